@@ -0,0 +1,89 @@
+package datasource
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Anchor is a URL fragment (without the leading '#') that identifies a
+// specific passage inside a larger document, such as a heading, paragraph,
+// PDF page, or video timestamp.
+//
+// Data sources that split long documents into several DataSourceData items
+// should attach an anchor to each item's SourceURL so citations deep-link to
+// the exact passage instead of the top of the document.
+type Anchor string
+
+// HeadingAnchor returns an anchor for a section heading using Markdown-style
+// slug rules: letters are lowercased, punctuation is removed, and runs of
+// whitespace, hyphens, or underscores collapse into a single hyphen. For
+// example "Getting Started!" becomes "getting-started".
+func HeadingAnchor(heading string) Anchor {
+	return Anchor(slugify(heading))
+}
+
+// ParagraphAnchor returns an anchor for the zero-based paragraph index within
+// a document, rendered as "p-<index>".
+func ParagraphAnchor(index int) Anchor {
+	if index < 0 {
+		index = 0
+	}
+	return Anchor("p-" + strconv.Itoa(index))
+}
+
+// PageAnchor returns an anchor for a one-based page number of a PDF document,
+// rendered as "page=<page>" which is understood by common PDF viewers.
+func PageAnchor(page int) Anchor {
+	if page < 1 {
+		page = 1
+	}
+	return Anchor("page=" + strconv.Itoa(page))
+}
+
+// TimestampAnchor returns a W3C media fragment for a position in an audio or
+// video resource, rendered as "t=<seconds>". Sub-second precision is
+// truncated.
+func TimestampAnchor(offset time.Duration) Anchor {
+	if offset < 0 {
+		offset = 0
+	}
+	return Anchor("t=" + strconv.FormatInt(int64(offset/time.Second), 10))
+}
+
+// WithAnchor returns sourceURL with its fragment replaced by the anchor.
+// Any existing fragment is discarded. An empty anchor removes the fragment.
+func WithAnchor(sourceURL string, a Anchor) string {
+	base, _, _ := strings.Cut(sourceURL, "#")
+	if a == "" {
+		return base
+	}
+	return base + "#" + string(a)
+}
+
+// Anchored returns a copy of d whose SourceURL deep-links to the anchor.
+func (d DataSourceData) Anchored(a Anchor) DataSourceData {
+	d.SourceURL = WithAnchor(d.SourceURL, a)
+	return d
+}
+
+// slugify converts heading text into a stable, URL-safe fragment.
+func slugify(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	pendingHyphen := false
+	for _, r := range strings.TrimSpace(s) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if pendingHyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			pendingHyphen = false
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsSpace(r) || r == '-' || r == '_':
+			pendingHyphen = true
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,46 @@
+package datasource_test
+
+import (
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestAnchors(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		a    datasource.Anchor
+		want string
+	}{
+		{"heading", "https://example.com/doc", datasource.HeadingAnchor("Getting Started!"), "https://example.com/doc#getting-started"},
+		{"heading collapses separators", "https://example.com/doc", datasource.HeadingAnchor("  Foo -- bar_baz  "), "https://example.com/doc#foo-bar-baz"},
+		{"heading unicode", "https://example.com/doc", datasource.HeadingAnchor("Über Größe"), "https://example.com/doc#über-größe"},
+		{"paragraph", "https://example.com/doc", datasource.ParagraphAnchor(3), "https://example.com/doc#p-3"},
+		{"page", "https://example.com/a.pdf", datasource.PageAnchor(12), "https://example.com/a.pdf#page=12"},
+		{"timestamp", "https://example.com/v.mp4", datasource.TimestampAnchor(90*time.Second + 500*time.Millisecond), "https://example.com/v.mp4#t=90"},
+		{"replaces fragment", "https://example.com/doc#old", datasource.ParagraphAnchor(0), "https://example.com/doc#p-0"},
+		{"empty removes fragment", "https://example.com/doc#old", "", "https://example.com/doc"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := datasource.WithAnchor(tt.url, tt.a); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDataSourceDataAnchored(t *testing.T) {
+	d := datasource.DataSourceData{SourceURL: "https://example.com/doc", AnswerID: 7}
+	got := d.Anchored(datasource.HeadingAnchor("Install"))
+
+	if got.SourceURL != "https://example.com/doc#install" {
+		t.Errorf("unexpected SourceURL %q", got.SourceURL)
+	}
+	if d.SourceURL != "https://example.com/doc" {
+		t.Error("Anchored must not modify the receiver")
+	}
+}
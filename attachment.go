@@ -0,0 +1,125 @@
+package datasource
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// AttachmentKind is the kind of an Attachment.
+type AttachmentKind string
+
+// Attachment kinds.
+const (
+	AttachmentCode  AttachmentKind = "code"  // a code snippet
+	AttachmentLog   AttachmentKind = "log"   // a log excerpt or stack trace
+	AttachmentImage AttachmentKind = "image" // a reference to an image, such as a screenshot
+)
+
+// maxErrorLineBytes bounds the lines ErrorLine returns.
+const maxErrorLineBytes = 200
+
+// Attachment is content the asker added to a question besides its text,
+// such as a pasted stack trace, which sources and preprocessors may search
+// on.
+type Attachment struct {
+	// Kind is the kind of attachment
+	Kind AttachmentKind `json:"kind"`
+
+	// Name is the attachment's file name
+	// Optional
+	Name string `json:"name,omitempty"`
+
+	// Language is the programming language of a code snippet (e.g., "go")
+	// Optional
+	Language string `json:"language,omitempty"`
+
+	// Text is the content of a code snippet or log excerpt, or the
+	// caption or extracted text of an image
+	// Optional for images
+	Text string `json:"text,omitempty"`
+
+	// URL references an image
+	// Optional - images only
+	URL string `json:"url,omitempty"`
+
+	// MediaType is the MIME type of an image (e.g., "image/png")
+	// Optional - images only
+	MediaType string `json:"media_type,omitempty"`
+}
+
+// ErrorLine returns the line of a's text that best states an error, as
+// ErrorLine does, for code snippets and log excerpts, and "" for images.
+func (a Attachment) ErrorLine() string {
+	if a.Kind == AttachmentImage {
+		return ""
+	}
+	return ErrorLine(a.Text)
+}
+
+// ErrorLines returns the error line of each of the input's attachments
+// that has one; see Attachment.ErrorLine.
+func (in NewQuestionInput) ErrorLines() []string {
+	var lines []string
+	for _, a := range in.Attachments {
+		if line := a.ErrorLine(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// SearchText returns the text a source searching by keyword should search
+// on: the question text followed by the error lines of its attachments
+// that it does not already quote. Sources that can take only a short
+// query should prefer QuestionText.
+func (in NewQuestionInput) SearchText() string {
+	text := strings.TrimSpace(in.QuestionText)
+	lower := strings.ToLower(text)
+	for _, line := range in.ErrorLines() {
+		if !strings.Contains(lower, strings.ToLower(line)) {
+			text = strings.TrimSpace(text + "\n" + line)
+		}
+	}
+	return text
+}
+
+var (
+	// errorPattern matches lines stating an error in common languages
+	// and log formats.
+	errorPattern = regexp.MustCompile(`(?i)error|exception|panic|fatal|fail|segmentation fault|undefined|cannot|unable to|denied|refused|timed? ?out`)
+
+	// framePattern matches the frames and headers of stack traces, which
+	// name where an error happened rather than what it is.
+	framePattern = regexp.MustCompile(`^(at |File "|goroutine \d|Traceback \(most recent call last\)|\.\.\. \d+ more|Caused by: \.\.\.)|^[\w./-]+\.\w+:\d+( \+0x[0-9a-f]+)?$`)
+
+	// logPrefix matches the timestamp and level that start log lines.
+	logPrefix = regexp.MustCompile(`^\[?\d{4}-\d{2}-\d{2}[T ][\d:.,]+(Z|[+-]\d{2}:?\d{2})?\]?\s*(\[?(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|FATAL|CRITICAL)\]?:?\s*)?`)
+)
+
+// ErrorLine returns the line of a log excerpt, stack trace, or compiler
+// output that best states the error, such as "panic: runtime error: index
+// out of range" or "ValueError: invalid literal for int()", without a
+// leading timestamp and log level and cut to 200 bytes. It returns the
+// first line mentioning an error that is not a stack frame, or "" if
+// there is none.
+func ErrorLine(text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || framePattern.MatchString(line) || !errorPattern.MatchString(line) {
+			continue
+		}
+		if rest := strings.TrimSpace(logPrefix.ReplaceAllString(line, "")); rest != "" {
+			line = rest
+		}
+		if len(line) > maxErrorLineBytes {
+			n := maxErrorLineBytes
+			for n > 0 && !utf8.RuneStart(line[n]) {
+				n--
+			}
+			line = line[:n]
+		}
+		return line
+	}
+	return ""
+}
@@ -0,0 +1,71 @@
+package datasource_test
+
+import (
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestErrorLine(t *testing.T) {
+	tests := []struct {
+		name, text, want string
+	}{
+		{
+			"go panic",
+			"goroutine 1 [running]:\npanic: runtime error: index out of range [3] with length 3\n\nmain.main()\n\t/app/main.go:12 +0x1d",
+			"panic: runtime error: index out of range [3] with length 3",
+		},
+		{
+			"python traceback",
+			"Traceback (most recent call last):\n  File \"app.py\", line 3, in <module>\n    int(\"x\")\nValueError: invalid literal for int() with base 10: 'x'",
+			"ValueError: invalid literal for int() with base 10: 'x'",
+		},
+		{
+			"java exception",
+			"Exception in thread \"main\" java.lang.NullPointerException: name\n\tat com.acme.App.main(App.java:5)",
+			"Exception in thread \"main\" java.lang.NullPointerException: name",
+		},
+		{
+			"log line",
+			"2026-03-01T12:00:00Z INFO starting\n2026-03-01T12:00:01.250Z [ERROR] dial tcp 10.0.0.1:5432: connect: connection refused",
+			"dial tcp 10.0.0.1:5432: connect: connection refused",
+		},
+		{
+			"no error",
+			"func main() {\n\tfmt.Println(\"hi\")\n}",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := datasource.ErrorLine(tt.text); got != tt.want {
+				t.Errorf("ErrorLine = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := datasource.ErrorLine("error: " + strings.Repeat("é", 200)); len(got) > 200 || !strings.HasPrefix(got, "error: ") {
+		t.Errorf("long line cut to %d bytes: %q", len(got), got)
+	}
+}
+
+func TestSearchText(t *testing.T) {
+	input := datasource.NewQuestionInput{
+		QuestionText: "Why does my server crash? It says panic: nil map",
+		Attachments: []datasource.Attachment{
+			{Kind: datasource.AttachmentLog, Text: "panic: nil map\ngoroutine 1 [running]:"},
+			{Kind: datasource.AttachmentCode, Language: "go", Text: "m[k] = v // error: assignment to entry in nil map"},
+			{Kind: datasource.AttachmentImage, URL: "https://example.com/error.png", Text: "fatal error"},
+		},
+	}
+	want := "Why does my server crash? It says panic: nil map\nm[k] = v // error: assignment to entry in nil map"
+	if got := input.SearchText(); got != want {
+		t.Errorf("SearchText = %q, want %q", got, want)
+	}
+
+	input = datasource.NewQuestionInput{Attachments: []datasource.Attachment{{Kind: datasource.AttachmentLog, Text: "E1203 failed to pull image"}}}
+	if got := input.SearchText(); got != "E1203 failed to pull image" {
+		t.Errorf("SearchText of an attachment-only question = %q", got)
+	}
+}
@@ -0,0 +1,32 @@
+package datasource
+
+import "sort"
+
+// blend returns a copy of items ordered by a weighted blend of relevance
+// and bonus, highest first. Relevance is score scaled by the highest score
+// in items or, if none is positive, 1/(position+1); bonus must be between 0
+// and 1. Ties keep the original order.
+func blend[T any](items []T, weight float64, score, bonus func(T) float64) []T {
+	maxScore := 0.0
+	for _, item := range items {
+		maxScore = max(maxScore, score(item))
+	}
+	type blended struct {
+		item  T
+		value float64
+	}
+	all := make([]blended, len(items))
+	for i, item := range items {
+		relevance := 1 / float64(i+1)
+		if maxScore > 0 {
+			relevance = score(item) / maxScore
+		}
+		all[i] = blended{item, (1-weight)*relevance + weight*bonus(item)}
+	}
+	sort.SliceStable(all, func(i, j int) bool { return all[i].value > all[j].value })
+	out := make([]T, len(all))
+	for i, b := range all {
+		out[i] = b.item
+	}
+	return out
+}
@@ -0,0 +1,79 @@
+package datasource
+
+import (
+	"context"
+	"time"
+)
+
+// Budget is the time the host allows a call, so that sources can shorten
+// their upstream timeouts and return partial results rather than
+// overrunning the host's query budget. The zero value allows unlimited
+// time.
+//
+//	ctx, cancel := input.Budget.Context(context.Background())
+//	defer cancel()
+//	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+type Budget struct {
+	// Deadline is when the host stops waiting for the result
+	// Optional - zero if the host sets no deadline
+	Deadline time.Time
+
+	// MaxLatency is how long the host waits from when the source receives
+	// the call; unlike Deadline it is unaffected by clock skew between
+	// hosts
+	// Optional - zero if the host sets no limit
+	MaxLatency time.Duration
+}
+
+// IsZero reports whether b allows unlimited time.
+func (b Budget) IsZero() bool {
+	return b.Deadline.IsZero() && b.MaxLatency <= 0
+}
+
+// Remaining returns the time left, measured from now as the start of the
+// call, and whether b limits it at all. The time left may be zero or
+// negative if the deadline has passed.
+func (b Budget) Remaining() (time.Duration, bool) {
+	end, ok := b.expires()
+	return time.Until(end), ok
+}
+
+// MinTimeout is the timeout Budget.Timeout returns once the time left has
+// run out, as a zero http.Client timeout would mean no timeout at all.
+const MinTimeout = time.Millisecond
+
+// Timeout returns the time left, at most max and at least MinTimeout, for
+// use as an upstream request timeout. It returns max if b is unlimited.
+func (b Budget) Timeout(max time.Duration) time.Duration {
+	if left, ok := b.Remaining(); ok && left < max {
+		if left < MinTimeout {
+			return MinTimeout
+		}
+		return left
+	}
+	return max
+}
+
+// Context returns a copy of parent that is canceled when the time left
+// runs out, and a function releasing its resources.
+func (b Budget) Context(parent context.Context) (context.Context, context.CancelFunc) {
+	if end, ok := b.expires(); ok {
+		return context.WithDeadline(parent, end)
+	}
+	return context.WithCancel(parent)
+}
+
+// expires returns when the time left runs out, measuring MaxLatency from
+// now.
+func (b Budget) expires() (time.Time, bool) {
+	if b.IsZero() {
+		return time.Time{}, false
+	}
+	end := b.Deadline
+	if b.MaxLatency > 0 {
+		if limit := time.Now().Add(b.MaxLatency); end.IsZero() || limit.Before(end) {
+			end = limit
+		}
+	}
+	return end, true
+}
@@ -0,0 +1,47 @@
+package datasource_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestBudget(t *testing.T) {
+	var zero datasource.Budget
+	if _, ok := zero.Remaining(); ok || zero.Timeout(time.Second) != time.Second {
+		t.Error("zero budget limits time")
+	}
+	ctx, cancel := zero.Context(context.Background())
+	cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("zero budget context has a deadline")
+	}
+
+	b := datasource.Budget{Deadline: time.Now().Add(time.Hour), MaxLatency: time.Second}
+	if left, ok := b.Remaining(); !ok || left > time.Second || left < 900*time.Millisecond {
+		t.Errorf("Remaining = %v, %v; want the shorter MaxLatency", left, ok)
+	}
+	if got := b.Timeout(100 * time.Millisecond); got != 100*time.Millisecond {
+		t.Errorf("Timeout = %v, want the smaller max", got)
+	}
+
+	b = datasource.Budget{Deadline: time.Now().Add(50 * time.Millisecond), MaxLatency: time.Hour}
+	if left, _ := b.Remaining(); left > 50*time.Millisecond || left <= 0 {
+		t.Errorf("Remaining = %v, want the time until the deadline", left)
+	}
+	ctx, cancel = b.Context(context.Background())
+	defer cancel()
+	if d, ok := ctx.Deadline(); !ok || d.After(b.Deadline) {
+		t.Errorf("context deadline = %v, %v; want by %v", d, ok, b.Deadline)
+	}
+
+	expired := datasource.Budget{Deadline: time.Now().Add(-time.Second)}
+	if left, ok := expired.Remaining(); !ok || left > 0 {
+		t.Errorf("Remaining = %v, %v; want none left", left, ok)
+	}
+	if got := expired.Timeout(time.Second); got != datasource.MinTimeout {
+		t.Errorf("Timeout of an expired budget = %v, want MinTimeout", got)
+	}
+}
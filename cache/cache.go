@@ -0,0 +1,200 @@
+// Package cache provides the in-memory store behind the caching middleware.
+//
+// A Cache holds fetched topics and data keyed by opaque strings, with a
+// per-entry time-to-live and least-recently-used eviction once MaxEntries is
+// reached. Hosts normally create one through middleware.Cache, but can build
+// a Cache directly to share it between sources or to inspect and purge it.
+//
+// Caches in different regions can share warmed results: set
+// Config.Publisher to write each change to a message bus, and pass events
+// received from the bus to Apply on every other replica. Replicas running
+// releases whose result types differ (see Schema) do not exchange entries
+// unless Config.Migrate converts them.
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// now is replaced in tests to control the clock.
+var now = time.Now
+
+// Default configuration values used when Config fields are zero.
+const (
+	DefaultTTL        = 5 * time.Minute
+	DefaultMaxEntries = 1000
+)
+
+// Config configures a Cache. Zero-valued fields use the package defaults.
+type Config struct {
+	// TTL is how long an entry stays valid after it is stored
+	TTL time.Duration
+
+	// MaxEntries is the maximum number of entries kept; the least recently
+	// used entry is evicted when the limit is exceeded
+	MaxEntries int
+
+	// Publisher, if set, receives an Event for every Set, Delete, Purge,
+	// and Invalidate so the change can be replicated to caches in other
+	// regions
+	Publisher Publisher
+
+	// ReplicaID identifies this cache in published events, so it can ignore
+	// its own events when they are delivered back by the bus
+	ReplicaID string
+
+	// Migrate, if set, converts an entry received from another replica
+	// whose Schema differs from this release's. It returns false to
+	// discard the entry. Without Migrate such entries are discarded
+	Migrate func(Entry) (Entry, bool)
+}
+
+// Entry is a cached FetchTopics or FetchData result.
+type Entry struct {
+	// Source is the name of the data source that produced the entry
+	Source string
+
+	// Topics holds a cached FetchTopics result
+	Topics []datasource.DataSourceTopic
+
+	// Data holds a cached FetchData result
+	Data []datasource.DataSourceData
+
+	// StoredAt is when the entry was stored
+	StoredAt time.Time
+
+	// ExpiresAt is when the entry stops being served
+	ExpiresAt time.Time
+
+	// Schema is the Schema of the release that stored the entry
+	Schema string
+}
+
+// Cache is a concurrency-safe LRU cache with per-entry expiry.
+type Cache struct {
+	cfg Config
+
+	mu       sync.Mutex
+	ll       *list.List // front is most recently used
+	items    map[string]*list.Element
+	removals []removal // replicated removals, in arrival order
+}
+
+type item struct {
+	key   string
+	entry Entry
+}
+
+// New creates an empty Cache.
+func New(cfg Config) *Cache {
+	if cfg.TTL <= 0 {
+		cfg.TTL = DefaultTTL
+	}
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+	return &Cache{cfg: cfg, ll: list.New(), items: make(map[string]*list.Element)}
+}
+
+// Get returns the entry stored under key if it exists and has not expired.
+// The returned slices are copies and may be modified by the caller.
+func (c *Cache) Get(key string) (Entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return Entry{}, false
+	}
+	it := el.Value.(*item)
+	if !now().Before(it.entry.ExpiresAt) {
+		c.removeElement(el)
+		return Entry{}, false
+	}
+	c.ll.MoveToFront(el)
+	return it.entry.clone(), true
+}
+
+// Set stores e under key, replacing any existing entry. StoredAt and
+// ExpiresAt are set from the current time and the configured TTL.
+func (c *Cache) Set(key string, e Entry) {
+	e = e.clone()
+	e.Schema = Schema
+	e.StoredAt = now()
+	e.ExpiresAt = e.StoredAt.Add(c.cfg.TTL)
+
+	c.mu.Lock()
+	c.store(key, e)
+	c.mu.Unlock()
+
+	if c.cfg.Publisher != nil {
+		published := e.clone()
+		c.publish(Event{Op: OpSet, Key: key, Entry: &published, At: e.StoredAt})
+	}
+}
+
+// Delete removes the entry stored under key, if any.
+func (c *Cache) Delete(key string) {
+	c.mu.Lock()
+	c.delete(key)
+	c.mu.Unlock()
+	c.publish(Event{Op: OpDelete, Key: key, At: now()})
+}
+
+// Purge removes every entry.
+func (c *Cache) Purge() {
+	c.mu.Lock()
+	c.purge()
+	c.mu.Unlock()
+	c.publish(Event{Op: OpPurge, At: now()})
+}
+
+// Len returns the number of stored entries, including expired entries that
+// have not been evicted yet.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *Cache) store(key string, e Entry) {
+	if el, ok := c.items[key]; ok {
+		el.Value.(*item).entry = e
+		c.ll.MoveToFront(el)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&item{key: key, entry: e})
+	for c.ll.Len() > c.cfg.MaxEntries {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *Cache) delete(key string) {
+	if el, ok := c.items[key]; ok {
+		c.removeElement(el)
+	}
+}
+
+func (c *Cache) purge() {
+	c.ll.Init()
+	c.items = make(map[string]*list.Element)
+}
+
+func (c *Cache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*item).key)
+}
+
+func (e Entry) clone() Entry {
+	if e.Topics != nil {
+		e.Topics = append([]datasource.DataSourceTopic{}, e.Topics...)
+	}
+	if e.Data != nil {
+		e.Data = append([]datasource.DataSourceData{}, e.Data...)
+	}
+	return e
+}
@@ -0,0 +1,215 @@
+package cache
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestCacheExpiry(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	c := New(Config{TTL: time.Minute})
+	c.Set("k", Entry{Topics: []datasource.DataSourceTopic{{TopicID: 1}}})
+
+	e, ok := c.Get("k")
+	if !ok || len(e.Topics) != 1 {
+		t.Fatalf("expected a hit, got %+v, %v", e, ok)
+	}
+	if !e.ExpiresAt.Equal(clock.Add(time.Minute)) {
+		t.Errorf("unexpected expiry %v", e.ExpiresAt)
+	}
+
+	clock = clock.Add(time.Minute)
+	if _, ok := c.Get("k"); ok {
+		t.Error("expected entry to expire after TTL")
+	}
+	if c.Len() != 0 {
+		t.Errorf("expected expired entry to be evicted, Len = %d", c.Len())
+	}
+}
+
+func TestCacheLRUEviction(t *testing.T) {
+	c := New(Config{MaxEntries: 2})
+	c.Set("a", Entry{})
+	c.Set("b", Entry{})
+	c.Get("a") // a becomes most recently used
+	c.Set("c", Entry{})
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("expected least recently used entry to be evicted")
+	}
+	for _, k := range []string{"a", "c"} {
+		if _, ok := c.Get(k); !ok {
+			t.Errorf("expected %q to be cached", k)
+		}
+	}
+}
+
+func TestCacheReturnsCopies(t *testing.T) {
+	c := New(Config{})
+	c.Set("k", Entry{Data: []datasource.DataSourceData{{DataText: "original"}}})
+
+	e, _ := c.Get("k")
+	e.Data[0].DataText = "mutated"
+
+	if e, _ := c.Get("k"); e.Data[0].DataText != "original" {
+		t.Error("mutating a returned entry must not change the cache")
+	}
+}
+
+func TestReplication(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	// A trivial bus delivering every event to every replica, including the
+	// sender.
+	var replicas []*Cache
+	bus := PublisherFunc(func(e Event) {
+		for _, r := range replicas {
+			r.Apply(e)
+		}
+	})
+	eu := New(Config{TTL: time.Minute, Publisher: bus, ReplicaID: "eu"})
+	us := New(Config{TTL: time.Hour, Publisher: bus, ReplicaID: "us"})
+	replicas = []*Cache{eu, us}
+
+	eu.Set("k", Entry{Topics: []datasource.DataSourceTopic{{TopicID: 1}}})
+	e, ok := us.Get("k")
+	if !ok || len(e.Topics) != 1 {
+		t.Fatalf("entry not replicated: %+v, %v", e, ok)
+	}
+	if !e.ExpiresAt.Equal(clock.Add(time.Minute)) {
+		t.Errorf("replica should keep the origin's expiry, got %v", e.ExpiresAt)
+	}
+
+	us.Delete("k")
+	if _, ok := eu.Get("k"); ok {
+		t.Error("delete not replicated")
+	}
+
+	us.Set("a", Entry{})
+	eu.Purge()
+	if us.Len() != 0 {
+		t.Errorf("purge not replicated, Len = %d", us.Len())
+	}
+}
+
+func TestApplyIgnoresStaleEvents(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	c := New(Config{ReplicaID: "eu"})
+	c.Set("k", Entry{Source: "local"})
+
+	older := Entry{Source: "old", Schema: Schema, StoredAt: clock.Add(-time.Second), ExpiresAt: clock.Add(time.Hour)}
+	c.Apply(Event{Op: OpSet, Origin: "us", Key: "k", Entry: &older})
+	expired := Entry{Source: "expired", Schema: Schema, StoredAt: clock, ExpiresAt: clock}
+	c.Apply(Event{Op: OpSet, Origin: "us", Key: "x", Entry: &expired})
+	c.Apply(Event{Op: OpDelete, Origin: "eu", Key: "k"})
+
+	if e, ok := c.Get("k"); !ok || e.Source != "local" {
+		t.Errorf("local entry overwritten or removed: %+v, %v", e, ok)
+	}
+	if _, ok := c.Get("x"); ok {
+		t.Error("expired entry applied")
+	}
+}
+
+func TestApplyOrdersRemovals(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	entry := func(source string, age time.Duration) *Entry {
+		return &Entry{Source: source, Schema: Schema, StoredAt: clock.Add(-age), ExpiresAt: clock.Add(time.Hour)}
+	}
+	c := New(Config{ReplicaID: "eu"})
+
+	// Removals delivered before the older sets they follow.
+	c.Apply(Event{Op: OpDelete, Origin: "us", Key: "k", At: clock.Add(-time.Second)})
+	c.Apply(Event{Op: OpSet, Origin: "us", Key: "k", Entry: entry("deleted", 2*time.Second)})
+	c.Apply(Event{Op: OpInvalidate, Origin: "us", Selector: &Selector{Source: "wiki"}, At: clock.Add(-time.Second)})
+	c.Apply(Event{Op: OpSet, Origin: "us", Key: "w", Entry: entry("wiki", 2*time.Second)})
+	if c.Len() != 0 {
+		t.Errorf("entries older than a removal were restored, Len = %d", c.Len())
+	}
+
+	// Removals delivered after newer sets.
+	c.Apply(Event{Op: OpSet, Origin: "us", Key: "k", Entry: entry("newer", 0)})
+	c.Apply(Event{Op: OpDelete, Origin: "us", Key: "k", At: clock.Add(-time.Minute)})
+	c.Apply(Event{Op: OpPurge, Origin: "us", At: clock.Add(-time.Minute)})
+	if e, ok := c.Get("k"); !ok || e.Source != "newer" {
+		t.Errorf("an older removal removed a newer entry: %+v, %v", e, ok)
+	}
+
+	c.Apply(Event{Op: OpPurge, Origin: "us", At: clock})
+	c.Apply(Event{Op: OpSet, Origin: "us", Key: "k", Entry: entry("purged", time.Second)})
+	if c.Len() != 0 {
+		t.Errorf("purge not applied in order, Len = %d", c.Len())
+	}
+}
+
+func TestApplyChecksSchema(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	legacy := Entry{Source: "old release", StoredAt: clock, ExpiresAt: clock.Add(time.Hour)}
+	current := legacy
+	current.Schema = Schema
+
+	c := New(Config{})
+	c.Apply(Event{Op: OpSet, Key: "legacy", Entry: &legacy})
+	c.Apply(Event{Op: OpSet, Key: "current", Entry: &current})
+	if _, ok := c.Get("legacy"); ok {
+		t.Error("entry from another schema applied")
+	}
+	if _, ok := c.Get("current"); !ok {
+		t.Error("entry from the current schema not applied")
+	}
+
+	migrated := New(Config{Migrate: func(e Entry) (Entry, bool) {
+		e.Source += " (migrated)"
+		return e, e.Source != "unmigratable (migrated)"
+	}})
+	unmigratable := legacy
+	unmigratable.Source = "unmigratable"
+	migrated.Apply(Event{Op: OpSet, Key: "legacy", Entry: &legacy})
+	migrated.Apply(Event{Op: OpSet, Key: "unmigratable", Entry: &unmigratable})
+	if e, ok := migrated.Get("legacy"); !ok || e.Source != "old release (migrated)" || e.Schema != Schema {
+		t.Errorf("migrated entry = %+v, %v", e, ok)
+	}
+	if _, ok := migrated.Get("unmigratable"); ok {
+		t.Error("entry rejected by Migrate applied")
+	}
+}
+
+func TestSchemaTracksFields(t *testing.T) {
+	type v1 struct {
+		Text string `json:"text"`
+	}
+	type v2 struct {
+		Text   string                 `json:"text"`
+		Author *struct{ Name string } `json:"author"`
+	}
+	type renamed struct {
+		Text string `json:"body"`
+	}
+	a, b, c := schemaOf(reflect.TypeOf(v1{})), schemaOf(reflect.TypeOf(v2{})), schemaOf(reflect.TypeOf(renamed{}))
+	if a == b || a == c {
+		t.Errorf("schemas should differ: %s, %s, %s", a, b, c)
+	}
+	if a != schemaOf(reflect.TypeOf(v1{})) {
+		t.Error("schema is not deterministic")
+	}
+	if Schema == "" {
+		t.Error("Schema is empty")
+	}
+}
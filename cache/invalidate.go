@@ -0,0 +1,85 @@
+package cache
+
+import (
+	"strings"
+	"time"
+)
+
+// Selector picks the entries removed by Invalidate. Set fields are
+// combined: an entry is selected only if it matches every one. A zero
+// Selector selects every entry.
+type Selector struct {
+	// Source selects the entries of the named data source (Entry.Source)
+	Source string `json:"source,omitempty"`
+
+	// Site selects entries with a topic or data item from the site,
+	// compared case-insensitively
+	Site string `json:"site,omitempty"`
+
+	// URLPrefix selects entries with a topic or data item whose SourceURL
+	// starts with the prefix
+	URLPrefix string `json:"url_prefix,omitempty"`
+
+	// OlderThan selects entries stored before the time
+	OlderThan time.Time `json:"older_than,omitempty"`
+}
+
+// IsZero reports whether s selects every entry.
+func (s Selector) IsZero() bool {
+	return s.Source == "" && s.Site == "" && s.URLPrefix == "" && s.OlderThan.IsZero()
+}
+
+// Match reports whether s selects e.
+func (s Selector) Match(e Entry) bool {
+	if s.Source != "" && e.Source != s.Source {
+		return false
+	}
+	if !s.OlderThan.IsZero() && !e.StoredAt.Before(s.OlderThan) {
+		return false
+	}
+	if s.Site == "" && s.URLPrefix == "" {
+		return true
+	}
+	for _, t := range e.Topics {
+		if s.matchItem(t.Site, t.SourceURL) {
+			return true
+		}
+	}
+	for _, d := range e.Data {
+		if s.matchItem(d.Site, d.SourceURL) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchItem reports whether a topic or data item with the site and
+// address matches the Site and URLPrefix of s.
+func (s Selector) matchItem(site, addr string) bool {
+	return (s.Site == "" || strings.EqualFold(site, s.Site)) &&
+		(s.URLPrefix == "" || strings.HasPrefix(addr, s.URLPrefix))
+}
+
+// Invalidate removes every entry sel selects, returning how many were
+// removed, so operators can purge the content of a compromised or
+// corrected site without flushing the whole cache.
+func (c *Cache) Invalidate(sel Selector) int {
+	c.mu.Lock()
+	n := c.invalidate(sel)
+	c.mu.Unlock()
+	c.publish(Event{Op: OpInvalidate, Selector: &sel, At: now()})
+	return n
+}
+
+func (c *Cache) invalidate(sel Selector) int {
+	n := 0
+	for el := c.ll.Front(); el != nil; {
+		next := el.Next()
+		if sel.Match(el.Value.(*item).entry) {
+			c.removeElement(el)
+			n++
+		}
+		el = next
+	}
+	return n
+}
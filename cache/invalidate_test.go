@@ -0,0 +1,75 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestInvalidate(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	fill := func(c *Cache) {
+		c.Set("wiki-old", Entry{Source: "wiki", Topics: []datasource.DataSourceTopic{{SourceURL: "https://wiki.example.com/a", Site: "Wiki"}}})
+		clock = clock.Add(time.Minute)
+		c.Set("wiki-new", Entry{Source: "wiki", Data: []datasource.DataSourceData{{SourceURL: "https://wiki.example.com/hr/b"}}})
+		c.Set("forum", Entry{Source: "forum", Topics: []datasource.DataSourceTopic{{SourceURL: "https://forum.example.com/t/1", Site: "forum"}}})
+		c.Set("empty", Entry{Source: "forum"})
+	}
+	tests := []struct {
+		name string
+		sel  Selector
+		kept []string
+	}{
+		{"source", Selector{Source: "wiki"}, []string{"forum", "empty"}},
+		{"site", Selector{Site: "wiki"}, []string{"wiki-new", "forum", "empty"}},
+		{"url prefix", Selector{URLPrefix: "https://wiki.example.com/hr/"}, []string{"wiki-old", "forum", "empty"}},
+		{"older than", Selector{OlderThan: clock.Add(30 * time.Second)}, []string{"wiki-new", "forum", "empty"}},
+		{"combined", Selector{Source: "forum", URLPrefix: "https://wiki.example.com/"}, []string{"wiki-old", "wiki-new", "forum", "empty"}},
+		{"everything", Selector{}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			c := New(Config{TTL: time.Hour})
+			fill(c)
+			if n := c.Invalidate(tt.sel); n != 4-len(tt.kept) {
+				t.Errorf("Invalidate = %d, want %d", n, 4-len(tt.kept))
+			}
+			if c.Len() != len(tt.kept) {
+				t.Errorf("Len = %d, want %d", c.Len(), len(tt.kept))
+			}
+			for _, k := range tt.kept {
+				if _, ok := c.Get(k); !ok {
+					t.Errorf("%q invalidated", k)
+				}
+			}
+		})
+	}
+}
+
+func TestInvalidateReplicates(t *testing.T) {
+	var replicas []*Cache
+	bus := PublisherFunc(func(e Event) {
+		for _, r := range replicas {
+			r.Apply(e)
+		}
+	})
+	eu := New(Config{Publisher: bus, ReplicaID: "eu"})
+	us := New(Config{Publisher: bus, ReplicaID: "us"})
+	replicas = []*Cache{eu, us}
+
+	eu.Set("a", Entry{Source: "wiki"})
+	eu.Set("b", Entry{Source: "forum"})
+	us.Invalidate(Selector{Source: "wiki"})
+
+	if _, ok := eu.Get("a"); ok {
+		t.Error("invalidation not replicated")
+	}
+	if _, ok := eu.Get("b"); !ok {
+		t.Error("unselected entry removed")
+	}
+}
@@ -0,0 +1,163 @@
+package cache
+
+import "time"
+
+// Op identifies the kind of change described by an Event.
+type Op string
+
+// Cache operations that are replicated.
+const (
+	OpSet        Op = "set"
+	OpDelete     Op = "delete"
+	OpPurge      Op = "purge"
+	OpInvalidate Op = "invalidate"
+)
+
+// Event describes a change made to a Cache, for replication to caches in
+// other regions. Events are JSON-serializable so they can be carried over
+// any message bus.
+type Event struct {
+	// Op is the operation performed
+	Op Op `json:"op"`
+
+	// Origin is the ReplicaID of the cache that made the change
+	Origin string `json:"origin,omitempty"`
+
+	// Key is the affected key (OpSet and OpDelete only)
+	Key string `json:"key,omitempty"`
+
+	// Entry is the stored entry, including its absolute expiry (OpSet only)
+	Entry *Entry `json:"entry,omitempty"`
+
+	// Selector picks the removed entries (OpInvalidate only)
+	Selector *Selector `json:"selector,omitempty"`
+
+	// At is when the change was made, by the origin's clock. Replicas
+	// use it to order removals (OpDelete, OpPurge and OpInvalidate)
+	// against entries; events without it are taken as made on arrival
+	At time.Time `json:"at,omitempty"`
+}
+
+// Publisher forwards cache changes to other replicas, typically by writing
+// them to a message bus. Publish is called synchronously after each change,
+// outside the cache's lock, and should not block for long.
+type Publisher interface {
+	Publish(Event)
+}
+
+// PublisherFunc adapts a function to the Publisher interface.
+type PublisherFunc func(Event)
+
+// Publish calls f(e).
+func (f PublisherFunc) Publish(e Event) { f(e) }
+
+func (c *Cache) publish(e Event) {
+	if c.cfg.Publisher != nil {
+		e.Origin = c.cfg.ReplicaID
+		c.cfg.Publisher.Publish(e)
+	}
+}
+
+// removal is a replicated Delete, Purge or Invalidate, remembered so that
+// an older entry delivered after it is not restored.
+type removal struct {
+	at  time.Time
+	key string    // removed key, if sel is nil
+	sel *Selector // removed entries; the zero Selector for a purge
+}
+
+// covers reports whether r removes e stored under key: e must have been
+// stored no later than r was made.
+func (r removal) covers(key string, e Entry) bool {
+	if e.StoredAt.After(r.at) {
+		return false
+	}
+	if r.sel == nil {
+		return key == r.key
+	}
+	return r.sel.Match(e)
+}
+
+// Apply applies an event received from another replica without publishing
+// it again. Events from this cache's own ReplicaID are ignored. An entry
+// is ignored if it has already expired, is older than the local entry for
+// the same key, or was stored before a removal already applied, and a
+// removal leaves entries stored after it was made. Removals are remembered
+// for the TTL, up to MaxEntries of them, so replicas sharing a TTL converge
+// regardless of delivery order, as far as their clocks agree. Entries
+// stored by a release with a different Schema are migrated with
+// Config.Migrate or ignored.
+func (c *Cache) Apply(e Event) {
+	if e.Origin != "" && e.Origin == c.cfg.ReplicaID {
+		return
+	}
+	if e.At.IsZero() {
+		e.At = now()
+	}
+	switch e.Op {
+	case OpSet:
+		if e.Entry == nil || !now().Before(e.Entry.ExpiresAt) {
+			return
+		}
+		entry, ok := c.admit(e.Entry.clone())
+		if !ok {
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		if el, ok := c.items[e.Key]; ok && el.Value.(*item).entry.StoredAt.After(entry.StoredAt) {
+			return
+		}
+		for _, r := range c.removals {
+			if r.covers(e.Key, entry) {
+				return
+			}
+		}
+		c.store(e.Key, entry)
+	case OpDelete:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.remove(removal{at: e.At, key: e.Key})
+	case OpPurge:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.remove(removal{at: e.At, sel: &Selector{}})
+	case OpInvalidate:
+		if e.Selector == nil {
+			return
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.remove(removal{at: e.At, sel: e.Selector})
+	}
+}
+
+// remove removes the entries r covers and remembers r, forgetting
+// removals older than the TTL and the oldest beyond MaxEntries.
+func (c *Cache) remove(r removal) {
+	if r.sel == nil {
+		if el, ok := c.items[r.key]; ok && r.covers(r.key, el.Value.(*item).entry) {
+			c.removeElement(el)
+		}
+	} else {
+		for el := c.ll.Front(); el != nil; {
+			next := el.Next()
+			if it := el.Value.(*item); r.covers(it.key, it.entry) {
+				c.removeElement(el)
+			}
+			el = next
+		}
+	}
+
+	cutoff := now().Add(-c.cfg.TTL)
+	kept := c.removals[:0]
+	for _, old := range c.removals {
+		if old.at.After(cutoff) {
+			kept = append(kept, old)
+		}
+	}
+	c.removals = append(kept, r)
+	if n := len(c.removals) - c.cfg.MaxEntries; n > 0 {
+		c.removals = append(c.removals[:0], c.removals[n:]...)
+	}
+}
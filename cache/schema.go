@@ -0,0 +1,99 @@
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Schema identifies the layout of the result types held in an Entry: a
+// digest of the JSON field names and types of DataSourceTopic and
+// DataSourceData, including nested types. It changes whenever a field is
+// added, removed, renamed, or retyped, so no release has to remember to
+// bump it.
+//
+// Entries are stamped with Schema when stored. Entries from another schema,
+// such as those replicated from a cache running an older release during a
+// rolling upgrade, would be missing fields or carry fields this release
+// drops, so they are passed to Config.Migrate or discarded.
+var Schema = schemaOf(reflect.TypeOf(datasource.DataSourceTopic{}), reflect.TypeOf(datasource.DataSourceData{}))
+
+var jsonMarshaler = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+// schemaOf returns a short digest of the JSON layout of types.
+func schemaOf(types ...reflect.Type) string {
+	var b strings.Builder
+	for _, t := range types {
+		describe(&b, t, make(map[reflect.Type]bool))
+		b.WriteByte(';')
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:6])
+}
+
+// describe writes the JSON layout of t. Types with their own JSON encoding
+// are described by name; seen guards against recursive types.
+func describe(b *strings.Builder, t reflect.Type, seen map[reflect.Type]bool) {
+	if t.Implements(jsonMarshaler) || reflect.PointerTo(t).Implements(jsonMarshaler) {
+		b.WriteString(t.String())
+		return
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		b.WriteByte('*')
+		describe(b, t.Elem(), seen)
+	case reflect.Slice, reflect.Array:
+		b.WriteString("[]")
+		describe(b, t.Elem(), seen)
+	case reflect.Map:
+		b.WriteString("map[")
+		describe(b, t.Key(), seen)
+		b.WriteByte(']')
+		describe(b, t.Elem(), seen)
+	case reflect.Struct:
+		if seen[t] {
+			b.WriteString(t.String())
+			return
+		}
+		seen[t] = true
+		b.WriteByte('{')
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			b.WriteString(name)
+			b.WriteByte(' ')
+			describe(b, f.Type, seen)
+			b.WriteByte(',')
+		}
+		b.WriteByte('}')
+	default:
+		b.WriteString(t.Kind().String())
+	}
+}
+
+// admit returns e ready to store if it has the current Schema, or was
+// migrated to it by Config.Migrate.
+func (c *Cache) admit(e Entry) (Entry, bool) {
+	if e.Schema == Schema {
+		return e, true
+	}
+	if c.cfg.Migrate == nil {
+		return Entry{}, false
+	}
+	e, ok := c.cfg.Migrate(e)
+	if !ok {
+		return Entry{}, false
+	}
+	e.Schema = Schema
+	return e, true
+}
@@ -0,0 +1,82 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// seedVersion is the version of the seed format written by WriteSeed.
+const seedVersion = 1
+
+// seed is the artifact WriteSeed writes.
+type seed struct {
+	Version int         `json:"version"`
+	Schema  string      `json:"schema"`
+	Written time.Time   `json:"written"`
+	Entries []seedEntry `json:"entries"`
+}
+
+type seedEntry struct {
+	Key   string `json:"key"`
+	Entry Entry  `json:"entry"`
+}
+
+// WriteSeed writes the unexpired entries to w as a seed artifact and
+// returns how many it wrote. A seed exported from one environment, such
+// as a CI job that warmed the cache with typical questions, is loaded
+// with ReadSeed by freshly deployed replicas so they do not start cold.
+func (c *Cache) WriteSeed(w io.Writer) (int, error) {
+	s := seed{Version: seedVersion, Schema: Schema, Written: now(), Entries: []seedEntry{}}
+	c.mu.Lock()
+	// Least recently used first, so ReadSeed restores the order.
+	for el := c.ll.Back(); el != nil; el = el.Prev() {
+		it := el.Value.(*item)
+		if s.Written.Before(it.entry.ExpiresAt) {
+			s.Entries = append(s.Entries, seedEntry{it.key, it.entry.clone()})
+		}
+	}
+	c.mu.Unlock()
+	if err := json.NewEncoder(w).Encode(s); err != nil {
+		return 0, fmt.Errorf("cache: write seed: %w", err)
+	}
+	return len(s.Entries), nil
+}
+
+// ReadSeed loads the entries of a seed written by WriteSeed and returns
+// how many it loaded. Loaded entries keep when they were stored but
+// expire a full TTL after loading, as a seed is usually older than the
+// TTL by the time it is deployed; entries stored more than maxAge ago are
+// skipped, unless maxAge is zero. Entries of another Schema are migrated
+// with Config.Migrate or skipped, and local entries stored later than the
+// seed's are kept. Loading is not published to other replicas.
+func (c *Cache) ReadSeed(r io.Reader, maxAge time.Duration) (int, error) {
+	var s seed
+	if err := json.NewDecoder(r).Decode(&s); err != nil {
+		return 0, fmt.Errorf("cache: read seed: %w", err)
+	}
+	if s.Version != seedVersion {
+		return 0, fmt.Errorf("cache: read seed: unsupported version %d", s.Version)
+	}
+	t := now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for _, se := range s.Entries {
+		if maxAge > 0 && t.Sub(se.Entry.StoredAt) > maxAge {
+			continue
+		}
+		e, ok := c.admit(se.Entry)
+		if !ok {
+			continue
+		}
+		if el, ok := c.items[se.Key]; ok && el.Value.(*item).entry.StoredAt.After(e.StoredAt) {
+			continue
+		}
+		e.ExpiresAt = t.Add(c.cfg.TTL)
+		c.store(se.Key, e)
+		n++
+	}
+	return n, nil
+}
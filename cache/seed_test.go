@@ -0,0 +1,59 @@
+package cache
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestSeedRoundTrip(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+
+	src := New(Config{TTL: time.Hour})
+	src.Set("old", Entry{Source: "kb", Topics: []datasource.DataSourceTopic{{TopicID: 1}}})
+	clock = clock.Add(30 * time.Minute)
+	src.Set("a", Entry{Source: "kb", Data: []datasource.DataSourceData{{DataText: "seeded"}}})
+	src.Set("b", Entry{Source: "kb"})
+	src.Get("a") // a becomes most recently used
+
+	var buf bytes.Buffer
+	if n, err := src.WriteSeed(&buf); err != nil || n != 3 {
+		t.Fatalf("WriteSeed = %d, %v", n, err)
+	}
+
+	// The seed is deployed a day later.
+	clock = clock.Add(24 * time.Hour)
+	dst := New(Config{TTL: time.Minute})
+	dst.Set("b", Entry{Source: "kb", Data: []datasource.DataSourceData{{DataText: "local"}}})
+	n, err := dst.ReadSeed(bytes.NewReader(buf.Bytes()), 0)
+	if err != nil || n != 2 {
+		t.Fatalf("ReadSeed = %d, %v; want the seed's a and old", n, err)
+	}
+	if e, ok := dst.Get("a"); !ok || e.Data[0].DataText != "seeded" || !e.ExpiresAt.Equal(clock.Add(time.Minute)) {
+		t.Errorf("seeded entry = %+v, %v; want it to expire a TTL after loading", e, ok)
+	}
+	if e, ok := dst.Get("b"); !ok || e.Data[0].DataText != "local" {
+		t.Errorf("local entry = %+v, %v; want it kept over the older seeded one", e, ok)
+	}
+
+	// Into a smaller cache, the least recently used entries are dropped.
+	dst = New(Config{MaxEntries: 1})
+	dst.ReadSeed(bytes.NewReader(buf.Bytes()), 0)
+	if _, ok := dst.Get("a"); !ok {
+		t.Error("most recently used entry not kept")
+	}
+
+	dst = New(Config{TTL: time.Minute})
+	if n, _ := dst.ReadSeed(bytes.NewReader(buf.Bytes()), 24*time.Hour); n != 2 {
+		t.Errorf("ReadSeed with maxAge loaded %d entries, want all but old", n)
+	}
+
+	if _, err := dst.ReadSeed(strings.NewReader(`{"version": 9}`), 0); err == nil {
+		t.Error("ReadSeed accepted an unknown version")
+	}
+}
@@ -0,0 +1,74 @@
+package datasource
+
+// Capabilities describes optional features a data source supports, so the
+// host can adapt how it builds queries for each source instead of probing
+// by trial and error. The zero value claims no optional features.
+type Capabilities struct {
+	// Embeddings means the source uses NewQuestionInput.Embedding for
+	// semantic search
+	Embeddings bool `json:"embeddings"`
+
+	// QueryEmbeddings names the entries of NewQuestionInput.Embeddings the
+	// source searches with, most preferred first, so the host computes
+	// them and a code search can use a code model while prose sources use
+	// a general one
+	// Optional - empty means the source uses NewQuestionInput.Embedding, if
+	// any
+	QueryEmbeddings []string `json:"query_embeddings,omitempty"`
+
+	// Pagination means the source can page through its upstream results,
+	// so large counts are honored rather than capped at one page
+	Pagination bool `json:"pagination"`
+
+	// TagFiltering means the source narrows results by NewQuestionInput.Tags
+	TagFiltering bool `json:"tag_filtering"`
+
+	// MultiSite means results come from several sites, distinguished by
+	// their Site fields
+	MultiSite bool `json:"multi_site"`
+
+	// ResultEmbeddings means the source returns precomputed Embedding
+	// vectors on its topics and data items
+	ResultEmbeddings bool `json:"result_embeddings"`
+
+	// EmbeddingModel names the model that produced the source's result
+	// embeddings (e.g., "text-embedding-3-small"), so the host reuses them
+	// only if it embeds with the same model
+	// Optional - empty if unknown or the source returns no embeddings
+	EmbeddingModel string `json:"embedding_model,omitempty"`
+
+	// Streaming means the source implements DataStreamer
+	Streaming bool `json:"streaming"`
+
+	// WriteBack means the source accepts questions posted to it; see
+	// Writable
+	WriteBack bool `json:"write_back"`
+
+	// PermissionSensitive means results depend on who is asking (for
+	// example, trimmed to the pages NewQuestionInput.AskedBy may see), so
+	// results must not be shared between requesters
+	PermissionSensitive bool `json:"permission_sensitive"`
+}
+
+// CapabilityReporter is an optional interface for data sources that report
+// their Capabilities.
+type CapabilityReporter interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities of ds, taken from the first
+// CapabilityReporter in its decorator chain (following Unwrap methods).
+// Streaming is also set if ds implements DataStreamer, as StreamData would
+// then stream. Sources that report nothing have the zero Capabilities.
+func CapabilitiesOf(ds DataSource) Capabilities {
+	var caps Capabilities
+	if _, ok := ds.(DataStreamer); ok {
+		caps.Streaming = true
+	}
+	if r, ok := find[CapabilityReporter](ds); ok {
+		c := r.Capabilities()
+		c.Streaming = c.Streaming || caps.Streaming
+		return c
+	}
+	return caps
+}
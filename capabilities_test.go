@@ -0,0 +1,44 @@
+package datasource_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+type capableSource struct {
+	ExampleDataSource
+	caps datasource.Capabilities
+}
+
+func (s *capableSource) Capabilities() datasource.Capabilities { return s.caps }
+
+type streamingLayer struct{ passthrough }
+
+func (streamingLayer) StreamData(context.Context, int, int64, func(datasource.DataSourceData) error) error {
+	return nil
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	semantic := &capableSource{caps: datasource.Capabilities{Embeddings: true, TagFiltering: true}}
+	tests := []struct {
+		name string
+		ds   datasource.DataSource
+		want datasource.Capabilities
+	}{
+		{"unreported", &ExampleDataSource{}, datasource.Capabilities{}},
+		{"reported", semantic, semantic.caps},
+		{"wrapped", passthrough{semantic}, semantic.caps},
+		{"streaming", streamingLayer{passthrough{semantic}}, datasource.Capabilities{Embeddings: true, TagFiltering: true, Streaming: true}},
+		{"streaming wrapped", passthrough{streamingLayer{passthrough{&ExampleDataSource{}}}}, datasource.Capabilities{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := datasource.CapabilitiesOf(tt.ds); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CapabilitiesOf = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
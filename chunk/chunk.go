@@ -0,0 +1,386 @@
+// Package chunk splits long data item text into bounded, overlapping
+// passages, as retrieval-augmented generation needs:
+//
+//	items = chunk.Splitter{MaxTokens: 200}.Data(items)
+//
+// Chunks end at paragraph boundaries where possible and otherwise at
+// sentence boundaries; only a single sentence longer than the budget is
+// cut between words. Each chunk carries an anchor deep-linking to the
+// passage it starts at. Convert HTML to Markdown or plain text (see the
+// content package) before splitting.
+package chunk
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Default Splitter values used when its fields are zero.
+const (
+	DefaultMaxTokens = 256
+	DefaultOverlap   = 32
+)
+
+// Metadata keys set on data items split by Splitter.Data.
+const (
+	MetadataIndex = "chunk_index" // the chunk's zero-based position
+	MetadataCount = "chunk_count" // the number of chunks in the item
+)
+
+// Chunk is a passage of a longer text.
+type Chunk struct {
+	// Text is the passage, trimmed of surrounding whitespace
+	Text string
+
+	// Start and End are the byte offsets of the passage in the original
+	// text, before trimming
+	Start, End int
+
+	// Tokens is the passage's size as measured by the Splitter
+	Tokens int
+
+	// Anchor identifies where the passage starts: the Markdown heading of
+	// its section if the text has one, else its first paragraph
+	Anchor datasource.Anchor
+}
+
+// Splitter splits text into chunks of at most MaxTokens tokens. Each chunk
+// after the first repeats up to Overlap tokens of whole sentences from the
+// end of the previous one, so passages cut mid-section keep their context.
+type Splitter struct {
+	// MaxTokens bounds the size of each chunk
+	// Defaults to DefaultMaxTokens
+	MaxTokens int
+
+	// Overlap bounds the text repeated between consecutive chunks; a
+	// negative Overlap disables it
+	// Defaults to DefaultOverlap
+	Overlap int
+
+	// Tokens counts the tokens in text, so budgets can match the
+	// tokenizer of the model the chunks are for
+	// Defaults to CountTokens
+	Tokens func(text string) int
+}
+
+func (s Splitter) withDefaults() Splitter {
+	if s.MaxTokens <= 0 {
+		s.MaxTokens = DefaultMaxTokens
+	}
+	if s.Overlap == 0 {
+		s.Overlap = DefaultOverlap
+	}
+	s.Overlap = min(max(s.Overlap, 0), s.MaxTokens/2)
+	if s.Tokens == nil {
+		s.Tokens = CountTokens
+	}
+	return s
+}
+
+// CountTokens approximates the number of tokens a subword tokenizer
+// produces for text: one per four letters or digits of each word, rounded
+// up, and one per punctuation mark or symbol.
+func CountTokens(text string) int {
+	n, word := 0, 0
+	for _, r := range text {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if word%4 == 0 {
+				n++
+			}
+			word++
+		case unicode.IsSpace(r):
+			word = 0
+		default:
+			n++
+			word = 0
+		}
+	}
+	return n
+}
+
+// Split returns text as chunks in order. Text within the budget is a
+// single chunk; empty text has none.
+func (s Splitter) Split(text string) []Chunk {
+	s = s.withDefaults()
+	pieces := s.pieces(text)
+	if len(pieces) == 0 {
+		return nil
+	}
+
+	var chunks []Chunk
+	var cur []piece
+	fresh := 0 // pieces in cur that are not overlap
+	emit := func() {
+		// Keep trailing headings with the section they introduce.
+		keep := len(cur)
+		for keep > len(cur)-fresh+1 && cur[keep-1].heading {
+			keep--
+		}
+		carry := append([]piece(nil), cur[keep:]...)
+		chunks = append(chunks, s.chunk(text, cur[:keep]))
+		if len(carry) > 0 {
+			cur, fresh = carry, len(carry)
+			return
+		}
+		cur, fresh = s.overlap(cur[:keep]), 0
+	}
+	for i, p := range pieces {
+		if fresh > 0 && p.paraStart {
+			// Start a paragraph in a new chunk if it fits there but not in
+			// this one.
+			para := s.paraTokens(pieces[i:])
+			if tokens(cur)+para > s.MaxTokens && para <= s.MaxTokens {
+				emit()
+			}
+		}
+		if fresh > 0 && tokens(cur)+p.tokens > s.MaxTokens {
+			emit()
+		}
+		for len(cur) > 0 && fresh < len(cur) && tokens(cur)+p.tokens > s.MaxTokens {
+			cur = cur[1:] // drop overlap that leaves no room
+		}
+		cur = append(cur, p)
+		fresh++
+	}
+	chunks = append(chunks, s.chunk(text, cur))
+	return chunks
+}
+
+// overlap returns the trailing sentences of a chunk to repeat in the next.
+func (s Splitter) overlap(prev []piece) []piece {
+	n, total := len(prev), 0
+	for n > 0 && total+prev[n-1].tokens <= s.Overlap && !prev[n-1].heading {
+		n--
+		total += prev[n].tokens
+	}
+	if n == 0 {
+		n = 1 // never repeat a whole chunk
+	}
+	return append([]piece(nil), prev[n:]...)
+}
+
+// paraTokens is the size of the paragraph starting at pieces[0].
+func (s Splitter) paraTokens(pieces []piece) int {
+	n := 0
+	for i, p := range pieces {
+		if i > 0 && p.paraStart {
+			break
+		}
+		n += p.tokens
+	}
+	return n
+}
+
+func (s Splitter) chunk(text string, pieces []piece) Chunk {
+	first, last := pieces[0], pieces[len(pieces)-1]
+	c := Chunk{
+		Text:   strings.TrimSpace(text[first.start:last.end]),
+		Start:  first.start,
+		End:    last.end,
+		Tokens: tokens(pieces),
+		Anchor: datasource.ParagraphAnchor(first.para),
+	}
+	if first.section != "" {
+		c.Anchor = datasource.HeadingAnchor(first.section)
+	}
+	return c
+}
+
+func tokens(pieces []piece) int {
+	n := 0
+	for _, p := range pieces {
+		n += p.tokens
+	}
+	return n
+}
+
+// Data splits each item whose DataText exceeds the budget into items that
+// differ only in DataText, a SourceURL anchored to the chunk (see
+// datasource.WithAnchor), and the MetadataIndex and MetadataCount
+// metadata. Other items are returned unchanged.
+func (s Splitter) Data(items []datasource.DataSourceData) []datasource.DataSourceData {
+	s = s.withDefaults()
+	out := make([]datasource.DataSourceData, 0, len(items))
+	for _, d := range items {
+		chunks := s.Split(d.DataText)
+		if len(chunks) <= 1 {
+			out = append(out, d)
+			continue
+		}
+		for i, c := range chunks {
+			item := d.Anchored(c.Anchor)
+			item.DataText = c.Text
+			item.Metadata = make(datasource.Metadata, len(d.Metadata)+2)
+			for k, v := range d.Metadata {
+				item.Metadata[k] = v
+			}
+			item.Metadata.Set(MetadataIndex, i)
+			item.Metadata.Set(MetadataCount, len(chunks))
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+// piece is a unit chunks are built from: a sentence, a line, or, for a
+// sentence over budget, a run of words.
+type piece struct {
+	start, end int
+	tokens     int
+	para       int    // zero-based paragraph index
+	paraStart  bool   // first piece of its paragraph
+	heading    bool   // the piece is a Markdown heading
+	section    string // the heading of the enclosing section
+}
+
+// pieces splits text into paragraphs, then sentences, then, for sentences
+// over budget, runs of words.
+func (s Splitter) pieces(text string) []piece {
+	var pieces []piece
+	section := ""
+	para := 0
+	for _, pr := range paragraphs(text) {
+		first := true
+		add := func(start, end int, isHeading bool) {
+			pieces = append(pieces, piece{
+				start: start, end: end, tokens: s.Tokens(text[start:end]),
+				para: para, paraStart: first, heading: isHeading, section: section,
+			})
+			first = false
+		}
+		for _, sp := range sentences(text, pr[0], pr[1]) {
+			if h, ok := heading(text[sp[0]:sp[1]]); ok {
+				section = h
+				add(sp[0], sp[1], true)
+				continue
+			}
+			if s.Tokens(text[sp[0]:sp[1]]) <= s.MaxTokens {
+				add(sp[0], sp[1], false)
+				continue
+			}
+			for _, wp := range s.words(text, sp[0], sp[1]) {
+				add(wp[0], wp[1], false)
+			}
+		}
+		para++
+	}
+	return pieces
+}
+
+// paragraphs returns the spans of text separated by blank lines.
+func paragraphs(text string) [][2]int {
+	var spans [][2]int
+	start := -1
+	for i := 0; i < len(text); {
+		end := strings.IndexByte(text[i:], '\n')
+		if end < 0 {
+			end = len(text)
+		} else {
+			end += i
+		}
+		blank := strings.TrimSpace(text[i:end]) == ""
+		switch {
+		case blank && start >= 0:
+			spans = append(spans, [2]int{start, i})
+			start = -1
+		case !blank && start < 0:
+			start = i
+		}
+		i = end + 1
+	}
+	if start >= 0 {
+		spans = append(spans, [2]int{start, len(text)})
+	}
+	return spans
+}
+
+// sentences returns the spans of sentences and lines in text[start:end].
+// A sentence ends at '.', '!', or '?' followed by whitespace and a
+// character that is not lowercase, which skips most abbreviations.
+func sentences(text string, start, end int) [][2]int {
+	var spans [][2]int
+	from := start
+	for i := start; i < end; {
+		r, size := utf8.DecodeRuneInString(text[i:end])
+		i += size
+		boundary := r == '\n'
+		if r == '.' || r == '!' || r == '?' || r == '。' {
+			j := i
+			for j < end && strings.ContainsRune(closers, rune(text[j])) {
+				j++
+			}
+			next := strings.TrimLeft(text[j:end], " \t")
+			n, _ := utf8.DecodeRuneInString(next)
+			switch {
+			case r == '。', j == end:
+				boundary = true
+			case len(next) < end-j:
+				boundary = next == "" || !unicode.IsLower(n)
+			}
+			if boundary {
+				i = j
+			}
+		}
+		if boundary {
+			if strings.TrimSpace(text[from:i]) != "" {
+				spans = append(spans, [2]int{from, i})
+			}
+			from = i
+		}
+	}
+	if strings.TrimSpace(text[from:end]) != "" {
+		spans = append(spans, [2]int{from, end})
+	}
+	return spans
+}
+
+// closers may follow the punctuation ending a sentence.
+const closers = ")]\"'"
+
+// words cuts text[start:end] into runs of whole words within the budget;
+// a single word over budget is a run of its own.
+func (s Splitter) words(text string, start, end int) [][2]int {
+	var spans [][2]int
+	from, budget := start, 0
+	for i := start; i < end; {
+		j := i
+		for j < end && isSpace(text[j]) {
+			j++
+		}
+		for j < end && !isSpace(text[j]) {
+			j++
+		}
+		n := s.Tokens(text[i:j])
+		if budget > 0 && budget+n > s.MaxTokens {
+			spans = append(spans, [2]int{from, i})
+			from, budget = i, 0
+		}
+		budget += n
+		i = j
+	}
+	if from < end {
+		spans = append(spans, [2]int{from, end})
+	}
+	return spans
+}
+
+// heading returns the text of a Markdown ATX heading.
+func heading(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level == len(line) || line[level] != ' ' {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimRight(line[level:], "# ")), true
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,141 @@
+package chunk_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/chunk"
+)
+
+// words counts whitespace-separated words, for predictable budgets.
+func words(s string) int { return len(strings.Fields(s)) }
+
+func texts(chunks []chunk.Chunk) []string {
+	var out []string
+	for _, c := range chunks {
+		out = append(out, c.Text)
+	}
+	return out
+}
+
+func TestCountTokens(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int
+	}{
+		{"", 0},
+		{"a cat", 2},
+		{"internationalization", 5},
+		{"Hello, world!", 6},
+	}
+	for _, tt := range tests {
+		if got := chunk.CountTokens(tt.in); got != tt.want {
+			t.Errorf("CountTokens(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestSplitShortText(t *testing.T) {
+	s := chunk.Splitter{}
+	if got := s.Split("  \n\n "); got != nil {
+		t.Errorf("Split(blank) = %v, want nil", got)
+	}
+	got := s.Split("One short paragraph.")
+	if len(got) != 1 || got[0].Text != "One short paragraph." || got[0].Anchor != "p-0" {
+		t.Errorf("Split = %+v", got)
+	}
+}
+
+func TestSplitKeepsParagraphsWhole(t *testing.T) {
+	text := "One two three. Four five.\n\nSix seven eight. Nine ten.\n\nEleven twelve."
+	s := chunk.Splitter{MaxTokens: 6, Overlap: -1, Tokens: words}
+	got := texts(s.Split(text))
+	want := []string{"One two three. Four five.", "Six seven eight. Nine ten.", "Eleven twelve."}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestSplitLongParagraphBySentence(t *testing.T) {
+	text := "A b c. D e f. G h i. J k l."
+	s := chunk.Splitter{MaxTokens: 6, Overlap: 3, Tokens: words}
+	chunks := s.Split(text)
+	want := []string{"A b c. D e f.", "D e f. G h i.", "G h i. J k l."}
+	if got := texts(chunks); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+	for _, c := range chunks {
+		if c.Tokens > 6 || !strings.Contains(text[c.Start:c.End], c.Text) {
+			t.Errorf("chunk %+v exceeds budget or does not match its offsets", c)
+		}
+	}
+}
+
+func TestSplitSentenceBoundaries(t *testing.T) {
+	text := "See e.g. the docs. Then run it! Really? Yes (done.) Next."
+	s := chunk.Splitter{MaxTokens: 5, Overlap: -1, Tokens: words}
+	want := []string{"See e.g. the docs.", "Then run it! Really?", "Yes (done.) Next."}
+	if got := texts(s.Split(text)); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestSplitLongSentenceByWords(t *testing.T) {
+	text := strings.Repeat("word ", 10)
+	s := chunk.Splitter{MaxTokens: 4, Overlap: -1, Tokens: words}
+	got := texts(s.Split(text))
+	want := []string{"word word word word", "word word word word", "word word"}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestSplitHeadingAnchors(t *testing.T) {
+	text := "Intro text here.\n\n## Getting Started\n\nInstall the tool first. Then configure it.\n\n## Usage\n\nRun it."
+	s := chunk.Splitter{MaxTokens: 10, Overlap: -1, Tokens: words}
+	chunks := s.Split(text)
+	var got []string
+	for _, c := range chunks {
+		got = append(got, string(c.Anchor)+": "+c.Text)
+	}
+	want := []string{
+		"p-0: Intro text here.",
+		"getting-started: ## Getting Started\n\nInstall the tool first. Then configure it.",
+		"usage: ## Usage\n\nRun it.",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("chunks = %q, want %q", got, want)
+	}
+}
+
+func TestData(t *testing.T) {
+	items := []datasource.DataSourceData{
+		{DataText: "Short.", SourceURL: "https://kb/a", AnswerID: 1},
+		{DataText: "First part.\n\nSecond part.", SourceURL: "https://kb/b#top", AnswerID: 2,
+			Metadata: datasource.Metadata{"lang": "en"}},
+	}
+	got := chunk.Splitter{MaxTokens: 2, Overlap: -1, Tokens: words}.Data(items)
+	if len(got) != 3 {
+		t.Fatalf("got %d items, want 3: %+v", len(got), got)
+	}
+	if got[0].DataText != "Short." || got[0].SourceURL != "https://kb/a" || got[0].Metadata != nil {
+		t.Errorf("short item changed: %+v", got[0])
+	}
+	for i, want := range []struct{ text, url string }{
+		{"First part.", "https://kb/b#p-0"},
+		{"Second part.", "https://kb/b#p-1"},
+	} {
+		d := got[i+1]
+		index, _ := d.Metadata.Int(chunk.MetadataIndex)
+		count, _ := d.Metadata.Int(chunk.MetadataCount)
+		lang, _ := d.Metadata.String("lang")
+		if d.DataText != want.text || d.SourceURL != want.url || d.AnswerID != 2 || index != int64(i) || count != 2 || lang != "en" {
+			t.Errorf("chunk %d = %+v", i, d)
+		}
+	}
+	if len(items[1].Metadata) != 1 {
+		t.Errorf("source metadata modified: %v", items[1].Metadata)
+	}
+}
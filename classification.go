@@ -0,0 +1,45 @@
+package datasource
+
+// Classification labels how sensitive a data item's content is, so hosts
+// can apply a display policy such as masking the text or requiring a
+// click-through. Sources may set it themselves; more often it is filled in
+// by an enrichment stage (see enrich.Classify).
+type Classification string
+
+// Classifications, from least to most restrictive. The empty
+// Classification means the item has not been classified; hosts decide
+// whether to treat that as safe.
+const (
+	ClassificationSafe      Classification = "safe"
+	ClassificationSensitive Classification = "sensitive"
+	ClassificationSecret    Classification = "secret"
+)
+
+// classificationRank orders classifications; unknown values rank with
+// secret so that a label from a newer source is never treated as safe.
+func classificationRank(c Classification) int {
+	switch c {
+	case "":
+		return 0
+	case ClassificationSafe:
+		return 1
+	case ClassificationSensitive:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// Stricter returns the more restrictive of c and o. Unknown
+// classifications are treated as secret.
+func (c Classification) Stricter(o Classification) Classification {
+	if classificationRank(o) > classificationRank(c) {
+		return o
+	}
+	return c
+}
+
+// AtLeast reports whether c is at least as restrictive as o.
+func (c Classification) AtLeast(o Classification) bool {
+	return classificationRank(c) >= classificationRank(o)
+}
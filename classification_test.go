@@ -0,0 +1,29 @@
+package datasource_test
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestClassificationStricter(t *testing.T) {
+	tests := []struct {
+		a, b, want datasource.Classification
+	}{
+		{"", datasource.ClassificationSafe, datasource.ClassificationSafe},
+		{datasource.ClassificationSafe, datasource.ClassificationSensitive, datasource.ClassificationSensitive},
+		{datasource.ClassificationSecret, datasource.ClassificationSensitive, datasource.ClassificationSecret},
+		{datasource.ClassificationSafe, "restricted", "restricted"},
+	}
+	for _, tt := range tests {
+		if got := tt.a.Stricter(tt.b); got != tt.want {
+			t.Errorf("%q.Stricter(%q) = %q, want %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if !datasource.Classification("restricted").AtLeast(datasource.ClassificationSecret) {
+		t.Error("unknown classifications should be treated as secret")
+	}
+	if datasource.ClassificationSafe.AtLeast(datasource.ClassificationSensitive) {
+		t.Error("safe should not be at least sensitive")
+	}
+}
@@ -0,0 +1,657 @@
+// Package cli implements locus-ds, a command-line tool for exercising data
+// sources by hand: running searches, fetching data, and exporting the
+// results for inspection or relevance labeling.
+//
+// Go programs cannot load data sources at run time, so integration authors
+// build their own locus-ds binary that links in the sources they want:
+//
+//	func main() {
+//	    os.Exit(cli.Main(map[string]datasource.DataSource{
+//	        "wikipedia": wikipedia.New(),
+//	    }))
+//	}
+//
+// Main also accepts configuration files (see package config) wherever a
+// source name is expected, so remote and plugin sources work without
+// rebuilding.
+//
+// Usage:
+//
+//	locus-ds topics [flags] QUESTION...
+//	locus-ds data [flags] TOPIC_ID
+//	locus-ds label [flags] --queries FILE --out DATASET
+//	locus-ds diff [flags] --a CONFIG --b CONFIG --queries FILE
+//	locus-ds plan [flags] CONFIG...
+//	locus-ds invalidate [flags] --admin URL
+//	locus-ds seed [flags] --queries FILE --out SEED
+//	locus-ds scaffold [flags] --name NAME
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/cache"
+	"github.com/locus-search/datasource-sdk/config"
+	"github.com/locus-search/datasource-sdk/eval"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/pipeline"
+	"github.com/locus-search/datasource-sdk/scaffold"
+)
+
+// Env is the environment a command runs in.
+type Env struct {
+	// Stdout receives command output
+	Stdout io.Writer
+
+	// Stderr receives usage and error messages
+	Stderr io.Writer
+
+	// Sources are the data sources available to the --source flag
+	Sources map[string]datasource.DataSource
+
+	// Open builds a data source from a configuration file, for commands
+	// such as diff that compare configurations. Nil disables loading
+	// configuration files; source names can still be used.
+	Open func(path string) (datasource.DataSource, error)
+}
+
+// Main runs locus-ds with the process arguments and standard streams and
+// returns the exit code.
+func Main(sources map[string]datasource.DataSource) int {
+	return Run(os.Args[1:], Env{Stdout: os.Stdout, Stderr: os.Stderr, Sources: sources, Open: config.Open})
+}
+
+// errUsage signals that usage has already been printed.
+var errUsage = errors.New("usage")
+
+type command struct {
+	name    string
+	summary string
+	run     func(args []string, env Env) error
+}
+
+func commands() []command {
+	return []command{
+		{"topics", "search a data source for topics", runTopics},
+		{"data", "fetch the data items of a topic", runData},
+		{"label", "serve a web UI for grading search results", runLabel},
+		{"diff", "compare ranked results of two sources or configurations", runDiff},
+		{"plan", "check configuration files and print the resolved pipeline", runPlan},
+		{"invalidate", "remove cached results from a running pipeline", runInvalidate},
+		{"seed", "warm a cache and export it, and any index, for new replicas", runSeed},
+		{"scaffold", "generate the skeleton of a new data source package", runScaffold},
+	}
+}
+
+// Run executes the locus-ds command line args (without the program name)
+// and returns the exit code: 0 on success, 1 on failure, 2 on bad usage.
+func Run(args []string, env Env) int {
+	if len(args) == 0 || args[0] == "-h" || args[0] == "--help" || args[0] == "help" {
+		printUsage(env.Stderr)
+		if len(args) == 0 {
+			return 2
+		}
+		return 0
+	}
+	for _, c := range commands() {
+		if c.name != args[0] {
+			continue
+		}
+		err := c.run(args[1:], env)
+		switch {
+		case err == nil:
+			return 0
+		case errors.Is(err, errUsage) || errors.Is(err, flag.ErrHelp):
+			return 2
+		default:
+			fmt.Fprintf(env.Stderr, "locus-ds %s: %v\n", c.name, err)
+			return 1
+		}
+	}
+	fmt.Fprintf(env.Stderr, "locus-ds: unknown command %q\n", args[0])
+	printUsage(env.Stderr)
+	return 2
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: locus-ds <command> [flags] [args]")
+	fmt.Fprintln(w, "\ncommands:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.summary)
+	}
+}
+
+// outputFlags are shared by commands that print results.
+type outputFlags struct {
+	source string
+	count  int
+	format string
+	fields string
+}
+
+func (o *outputFlags) register(fs *flag.FlagSet, defaultCount int) {
+	fs.StringVar(&o.source, "source", "", "data source name or configuration file (optional when only one source is available)")
+	fs.IntVar(&o.count, "count", defaultCount, "maximum number of results")
+	fs.StringVar(&o.format, "format", "text", "output format: text, csv, jsonl, or markdown")
+	fs.StringVar(&o.fields, "fields", "", "comma-separated fields to include (default all)")
+}
+
+func newFlagSet(name string, env Env) *flag.FlagSet {
+	fs := flag.NewFlagSet("locus-ds "+name, flag.ContinueOnError)
+	fs.SetOutput(env.Stderr)
+	return fs
+}
+
+// openSource resolves and initializes the source selected by name: a
+// registered source, or else a configuration file loaded with env.Open.
+func openSource(name string, env Env) (datasource.DataSource, error) {
+	if name == "" {
+		if len(env.Sources) != 1 {
+			return nil, fmt.Errorf("--source is required; available: %s", strings.Join(sourceNames(env), ", "))
+		}
+		for n := range env.Sources {
+			name = n
+		}
+	}
+	ds, ok := env.Sources[name]
+	if !ok {
+		return loadSource(name, env)
+	}
+	if err := ds.Init(); err != nil {
+		return nil, fmt.Errorf("init %s: %w", name, err)
+	}
+	return ds, nil
+}
+
+func sourceNames(env Env) []string {
+	names := make([]string, 0, len(env.Sources))
+	for n := range env.Sources {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func runTopics(args []string, env Env) error {
+	fs := newFlagSet("topics", env)
+	var out outputFlags
+	out.register(fs, 5)
+	tags := fs.String("tags", "", "comma-separated question tags")
+	sites := fs.String("sites", "", "comma-separated sites to restrict results to")
+	excludeTags := fs.String("exclude-tags", "", "comma-separated tags results must not have")
+	minScore := fs.Float64("min-score", 0, "lowest upstream score of results")
+	after := fs.String("after", "", "earliest publication date of results (YYYY-MM-DD)")
+	before := fs.String("before", "", "publication date results must precede (YYYY-MM-DD)")
+	sortOrder := fs.String("sort", "", "preferred order: relevance, recency, or votes")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(env.Stderr, "usage: locus-ds topics [flags] QUESTION...")
+		return errUsage
+	}
+	filters := datasource.Filters{
+		MinScore:    *minScore,
+		Sites:       splitList(*sites),
+		ExcludeTags: splitList(*excludeTags),
+		Sort:        datasource.SortOrder(*sortOrder),
+	}
+	switch filters.Sort {
+	case "", datasource.SortRelevance, datasource.SortRecency, datasource.SortVotes:
+	default:
+		return fmt.Errorf("invalid sort order %q", *sortOrder)
+	}
+	for _, d := range []struct {
+		flag string
+		dst  *time.Time
+	}{{*after, &filters.After}, {*before, &filters.Before}} {
+		if d.flag == "" {
+			continue
+		}
+		t, err := time.Parse(time.DateOnly, d.flag)
+		if err != nil {
+			return fmt.Errorf("invalid date %q; want YYYY-MM-DD", d.flag)
+		}
+		*d.dst = t
+	}
+
+	ds, err := openSource(out.source, env)
+	if err != nil {
+		return err
+	}
+	input := datasource.NewQuestionInput{QuestionText: strings.Join(fs.Args(), " "), Filters: filters}
+	if *tags != "" {
+		input.Tags = splitList(*tags)
+	}
+	topics, err := ds.FetchTopics(out.count, input)
+	if err != nil {
+		return err
+	}
+	return writeRecords(env.Stdout, out.format, splitList(out.fields), topics)
+}
+
+func runData(args []string, env Env) error {
+	fs := newFlagSet("data", env)
+	var out outputFlags
+	out.register(fs, 3)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fmt.Fprintln(env.Stderr, "usage: locus-ds data [flags] TOPIC_ID")
+		return errUsage
+	}
+	topicID, err := strconv.ParseInt(fs.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid topic ID %q", fs.Arg(0))
+	}
+
+	ds, err := openSource(out.source, env)
+	if err != nil {
+		return err
+	}
+	data, err := ds.FetchData(out.count, topicID)
+	if err != nil {
+		return err
+	}
+	return writeRecords(env.Stdout, out.format, splitList(out.fields), data)
+}
+
+func runLabel(args []string, env Env) error {
+	fs := newFlagSet("label", env)
+	source := fs.String("source", "", "data source name or configuration file (optional when only one source is available)")
+	count := fs.Int("count", 10, "number of topics to grade per question")
+	queries := fs.String("queries", "", "file with one question per line")
+	out := fs.String("out", "", "dataset file to read and update (JSON Lines)")
+	addr := fs.String("addr", "127.0.0.1:8089", "address to serve the labeling UI on")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *queries == "" || *out == "" {
+		fmt.Fprintln(env.Stderr, "usage: locus-ds label [flags] --queries FILE --out DATASET")
+		return errUsage
+	}
+
+	questions, err := readLines(*queries)
+	if err != nil {
+		return err
+	}
+	name := *source
+	if name == "" && len(env.Sources) == 1 {
+		name = sourceNames(env)[0]
+	}
+	ds, err := openSource(name, env)
+	if err != nil {
+		return err
+	}
+	labeler, err := eval.NewLabeler(ds, name, questions, *out, *count)
+	if err != nil {
+		return err
+	}
+
+	ln, err := net.Listen("tcp", *addr)
+	if err != nil {
+		return err
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	srv := &http.Server{Handler: labeler, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+	fmt.Fprintf(env.Stdout, "Labeling %d questions at http://%s/ (Ctrl-C to stop)\n", len(questions), ln.Addr())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+func runDiff(args []string, env Env) error {
+	fs := newFlagSet("diff", env)
+	a := fs.String("a", "", "baseline source name or configuration file")
+	b := fs.String("b", "", "candidate source name or configuration file")
+	queries := fs.String("queries", "", "file with one question per line")
+	count := fs.Int("count", 10, "number of topics to compare per question")
+	format := fs.String("format", "text", "output format: text or jsonl")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *a == "" || *b == "" || *queries == "" {
+		fmt.Fprintln(env.Stderr, "usage: locus-ds diff [flags] --a CONFIG --b CONFIG --queries FILE")
+		return errUsage
+	}
+
+	questions, err := readLines(*queries)
+	if err != nil {
+		return err
+	}
+	dsA, err := openSource(*a, env)
+	if err != nil {
+		return err
+	}
+	dsB, err := openSource(*b, env)
+	if err != nil {
+		return err
+	}
+
+	diffs := eval.Compare(dsA, dsB, questions, *count)
+	switch *format {
+	case "text", "":
+		writeDiffText(env.Stdout, diffs)
+		return nil
+	case "jsonl":
+		enc := json.NewEncoder(env.Stdout)
+		for _, d := range diffs {
+			if err := enc.Encode(d); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown format %q; use text or jsonl", *format)
+	}
+}
+
+func runPlan(args []string, env Env) error {
+	fs := newFlagSet("plan", env)
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		fmt.Fprintln(env.Stderr, "usage: locus-ds plan [flags] CONFIG...")
+		return errUsage
+	}
+
+	b := pipeline.NewBuilder()
+	for _, path := range fs.Args() {
+		b.WithConfigFile(path)
+	}
+	plan, err := b.Plan()
+	if err != nil {
+		return err
+	}
+	switch *format {
+	case "text", "":
+		if err := plan.WriteText(env.Stdout); err != nil {
+			return err
+		}
+	case "json":
+		enc := json.NewEncoder(env.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(plan); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown format %q; use text or json", *format)
+	}
+	if n := plan.Problems(); n > 0 {
+		return fmt.Errorf("configuration has %d problem(s)", n)
+	}
+	return nil
+}
+
+func runInvalidate(args []string, env Env) error {
+	fs := newFlagSet("invalidate", env)
+	admin := fs.String("admin", "", "base URL of the pipeline's admin endpoints")
+	source := fs.String("source", "", "remove the results of the named source")
+	site := fs.String("site", "", "remove results with a topic or data item from the site")
+	prefix := fs.String("url-prefix", "", "remove results with a topic or data item whose URL has the prefix")
+	olderThan := fs.Duration("older-than", 0, "remove results cached longer ago than the duration")
+	all := fs.Bool("all", false, "remove every cached result, when no other selector is set")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *admin == "" || fs.NArg() != 0 {
+		fmt.Fprintln(env.Stderr, "usage: locus-ds invalidate [flags] --admin URL")
+		return errUsage
+	}
+	sel := cache.Selector{Source: *source, Site: *site, URLPrefix: *prefix}
+	if *olderThan > 0 {
+		sel.OlderThan = time.Now().Add(-*olderThan)
+	}
+	if sel.IsZero() && !*all {
+		return errors.New("no selector set; use --source, --site, --url-prefix, --older-than, or --all")
+	}
+
+	body, err := json.Marshal(struct {
+		cache.Selector
+		All bool `json:"all,omitempty"`
+	}{sel, *all})
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Post(strings.TrimSuffix(*admin, "/")+pipeline.PathInvalidate, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<10))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var result struct {
+		Removed int `json:"removed"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	fmt.Fprintf(env.Stdout, "Removed %d cached results\n", result.Removed)
+	return nil
+}
+
+// seedTTL keeps results warmed by runSeed from expiring before they are
+// written; ReadSeed gives them a fresh TTL when loaded.
+const seedTTL = 24 * time.Hour
+
+func runSeed(args []string, env Env) error {
+	fs := newFlagSet("seed", env)
+	source := fs.String("source", "", "data source name or configuration file (optional when only one source is available)")
+	name := fs.String("name", "", "name of the source in the pipeline the seed is for, which cache entries are keyed by (default the --source value)")
+	count := fs.Int("count", 5, "count of the requests to warm, which cache entries are keyed by")
+	queries := fs.String("queries", "", "file with one question per line")
+	out := fs.String("out", "", "file to write the cache seed to")
+	snapshot := fs.String("snapshot", "", "file to write a snapshot of the source's index to, if it has one")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *queries == "" || *out == "" || fs.NArg() != 0 {
+		fmt.Fprintln(env.Stderr, "usage: locus-ds seed [flags] --queries FILE --out SEED")
+		return errUsage
+	}
+
+	questions, err := readLines(*queries)
+	if err != nil {
+		return err
+	}
+	if *source == "" && len(env.Sources) == 1 {
+		*source = sourceNames(env)[0]
+	}
+	if *name == "" {
+		*name = *source
+	}
+	ds, err := openSource(*source, env)
+	if err != nil {
+		return err
+	}
+	store := cache.New(cache.Config{TTL: seedTTL, MaxEntries: max(cache.DefaultMaxEntries, len(questions)*(*count+1))})
+	cached := middleware.Cache(ds, middleware.CacheConfig{Name: *name, Store: store})
+	var errs []error
+	for _, q := range questions {
+		topics, err := cached.FetchTopics(*count, datasource.NewQuestionInput{QuestionText: q})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%q: %w", q, err))
+			continue
+		}
+		for _, t := range topics {
+			if _, err := cached.FetchData(*count, t.TopicID); err != nil {
+				errs = append(errs, fmt.Errorf("%q: topic %d: %w", q, t.TopicID, err))
+			}
+		}
+	}
+	for _, err := range errs {
+		fmt.Fprintf(env.Stderr, "warning: %v\n", err)
+	}
+
+	n, err := writeFile(*out, func(w io.Writer) (int, error) { return store.WriteSeed(w) })
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "Wrote %d cached results for %d questions to %s\n", n, len(questions), *out)
+	if *snapshot == "" {
+		return nil
+	}
+	sn, ok := datasource.SnapshotterOf(ds)
+	if !ok {
+		return fmt.Errorf("%s has no index to snapshot", *source)
+	}
+	if _, err := writeFile(*snapshot, func(w io.Writer) (int, error) { return 0, sn.WriteSnapshot(w) }); err != nil {
+		return err
+	}
+	fmt.Fprintf(env.Stdout, "Wrote a snapshot of %s to %s\n", *source, *snapshot)
+	return nil
+}
+
+func runScaffold(args []string, env Env) error {
+	fs := newFlagSet("scaffold", env)
+	name := fs.String("name", "", "name of the package and of the source in config descriptors")
+	kind := fs.String("kind", string(scaffold.KindHTTPJSON), "kind of upstream: http-json, grpc, or local-index")
+	out := fs.String("out", "", "directory to write the package to (default ./NAME)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *name == "" || fs.NArg() != 0 {
+		fmt.Fprintln(env.Stderr, "usage: locus-ds scaffold [flags] --name NAME")
+		return errUsage
+	}
+	if *out == "" {
+		*out = *name
+	}
+	files, err := scaffold.Generate(scaffold.Options{Name: *name, Kind: scaffold.Kind(*kind)})
+	if err != nil {
+		return err
+	}
+	if err := scaffold.Write(*out, files); err != nil {
+		return err
+	}
+	for _, f := range files {
+		fmt.Fprintf(env.Stdout, "Wrote %s\n", filepath.Join(*out, f.Name))
+	}
+	fmt.Fprintln(env.Stdout, "Run go test on the package, then adapt the code marked TODO to your upstream")
+	return nil
+}
+
+// writeFile creates the file at path and writes it with write, removing
+// it if write fails.
+func writeFile(path string, write func(io.Writer) (int, error)) (int, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	n, err := write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return n, nil
+}
+
+// loadSource loads ref as a configuration file with env.Open and
+// initializes the source.
+func loadSource(ref string, env Env) (datasource.DataSource, error) {
+	if env.Open == nil {
+		return nil, fmt.Errorf("unknown source %q (available: %s) and this build cannot load configuration files",
+			ref, strings.Join(sourceNames(env), ", "))
+	}
+	ds, err := env.Open(ref)
+	if err != nil {
+		return nil, fmt.Errorf("load %s: %w", ref, err)
+	}
+	if err := ds.Init(); err != nil {
+		return nil, fmt.Errorf("init %s: %w", ref, err)
+	}
+	return ds, nil
+}
+
+func writeDiffText(w io.Writer, diffs []eval.QueryDiff) {
+	var changed int
+	var totalA, totalB time.Duration
+	for _, d := range diffs {
+		totalA += d.LatencyA
+		totalB += d.LatencyB
+		if !d.Changed() {
+			continue
+		}
+		changed++
+		fmt.Fprintf(w, "== %s\n", d.Question)
+		fmt.Fprintf(w, "   latency a=%s b=%s (%+dms)\n", d.LatencyA.Round(time.Millisecond), d.LatencyB.Round(time.Millisecond), (d.LatencyB - d.LatencyA).Milliseconds())
+		if d.ErrA != "" {
+			fmt.Fprintf(w, "   a error: %s\n", d.ErrA)
+		}
+		if d.ErrB != "" {
+			fmt.Fprintf(w, "   b error: %s\n", d.ErrB)
+		}
+		for _, c := range d.Added {
+			fmt.Fprintf(w, "   + [%d] %s <%s>\n", c.RankB, c.Topic, c.Key)
+		}
+		for _, c := range d.Removed {
+			fmt.Fprintf(w, "   - [%d] %s <%s>\n", c.RankA, c.Topic, c.Key)
+		}
+		for _, c := range d.Moved {
+			fmt.Fprintf(w, "   ~ [%d->%d] %s <%s>\n", c.RankA, c.RankB, c.Topic, c.Key)
+		}
+	}
+	fmt.Fprintf(w, "%d of %d queries changed", changed, len(diffs))
+	if n := len(diffs); n > 0 {
+		meanA, meanB := totalA/time.Duration(n), totalB/time.Duration(n)
+		fmt.Fprintf(w, "; mean latency a=%s b=%s (%+dms)", meanA.Round(time.Millisecond), meanB.Round(time.Millisecond), (meanB - meanA).Milliseconds())
+	}
+	fmt.Fprintln(w)
+}
+
+// readLines returns the non-empty lines of a file, skipping lines that
+// start with '#'.
+func readLines(path string) ([]string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, line := range strings.Split(string(b), "\n") {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	return lines, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
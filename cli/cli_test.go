@@ -0,0 +1,314 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/middleware"
+)
+
+type cannedSource struct {
+	topics []datasource.DataSourceTopic
+	data   []datasource.DataSourceData
+	last   datasource.NewQuestionInput
+}
+
+func (s *cannedSource) Init() error             { return nil }
+func (s *cannedSource) CheckAvailability() bool { return true }
+func (s *cannedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	s.last = input
+	return s.topics[:min(count, len(s.topics))], nil
+}
+func (s *cannedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	return s.data[:min(count, len(s.data))], nil
+}
+
+func newTestSource() *cannedSource {
+	return &cannedSource{
+		topics: []datasource.DataSourceTopic{
+			{Topic: "Reset a password", SourceURL: "https://kb.example.com/1", TopicID: 1},
+			{Topic: "Rotate keys, safely", SourceURL: "https://kb.example.com/2", Site: "sec", TopicID: 2},
+		},
+		data: []datasource.DataSourceData{
+			{DataText: "Line one\nline | two", SourceURL: "https://kb.example.com/1#a", AnswerID: 10},
+		},
+	}
+}
+
+func run(t *testing.T, src datasource.DataSource, args ...string) (string, string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run(args, Env{Stdout: &stdout, Stderr: &stderr, Sources: map[string]datasource.DataSource{"kb": src}})
+	return stdout.String(), stderr.String(), code
+}
+
+func TestTopicsFormats(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{
+			"csv with fields",
+			[]string{"topics", "--format", "csv", "--fields", "topic_id,topic", "reset", "password"},
+			"topic_id,topic\n1,Reset a password\n2,\"Rotate keys, safely\"\n",
+		},
+		{
+			"jsonl with fields",
+			[]string{"topics", "--format", "jsonl", "--fields", "topic,site", "q"},
+			"{\"topic\":\"Reset a password\",\"site\":null}\n{\"topic\":\"Rotate keys, safely\",\"site\":\"sec\"}\n",
+		},
+		{
+			"markdown",
+			[]string{"topics", "--format", "markdown", "--fields", "topic_id,source_url", "--count", "1", "q"},
+			"| topic_id | source_url |\n| --- | --- |\n| 1 | https://kb.example.com/1 |\n",
+		},
+		{
+			"unset times are empty",
+			[]string{"topics", "--format", "csv", "--fields", "topic_id,created_at", "--count", "1", "q"},
+			"topic_id,created_at\n1,\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stdout, stderr, code := run(t, newTestSource(), tt.args...)
+			if code != 0 {
+				t.Fatalf("exit code %d: %s", code, stderr)
+			}
+			if stdout != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", stdout, tt.want)
+			}
+		})
+	}
+}
+
+func TestTopicsPassesQuestionAndTags(t *testing.T) {
+	src := newTestSource()
+	if _, stderr, code := run(t, src, "topics", "--tags", "auth, sso", "how", "do", "I", "log", "in"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if src.last.QuestionText != "how do I log in" || len(src.last.Tags) != 2 || src.last.Tags[1] != "sso" {
+		t.Errorf("unexpected input %+v", src.last)
+	}
+}
+
+func TestTopicsPassesFilters(t *testing.T) {
+	src := newTestSource()
+	if _, stderr, code := run(t, src, "topics", "--sites", "so,su", "--after", "2024-01-02", "--sort", "recency", "q"); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	f := src.last.Filters
+	if len(f.Sites) != 2 || f.Sort != datasource.SortRecency || !f.After.Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected filters %+v", f)
+	}
+	if _, _, code := run(t, src, "topics", "--sort", "newest", "q"); code == 0 {
+		t.Error("invalid sort order accepted")
+	}
+}
+
+func TestDataMarkdownEscapes(t *testing.T) {
+	stdout, stderr, code := run(t, newTestSource(), "data", "--format", "markdown", "--fields", "answer_id,data_text", "1")
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, `| 10 | Line one<br>line \| two |`) {
+		t.Errorf("markdown cell not escaped:\n%s", stdout)
+	}
+}
+
+func TestTextFormatIsDefault(t *testing.T) {
+	stdout, _, code := run(t, newTestSource(), "topics", "q")
+	if code != 0 || !strings.HasPrefix(stdout, "TOPIC") || !strings.Contains(stdout, "Reset a password") {
+		t.Errorf("unexpected text output (code %d):\n%s", code, stdout)
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		msg  string
+	}{
+		{"unknown command", []string{"frobnicate"}, 2, "unknown command"},
+		{"unknown field", []string{"topics", "--fields", "votes", "q"}, 1, `unknown field "votes"`},
+		{"unknown format", []string{"topics", "--format", "xml", "q"}, 1, `unknown format "xml"`},
+		{"unknown source", []string{"topics", "--source", "nope", "q"}, 1, `unknown source "nope"`},
+		{"missing question", []string{"topics"}, 2, "usage: locus-ds topics"},
+		{"bad topic id", []string{"data", "abc"}, 1, "invalid topic ID"},
+		{"label without files", []string{"label"}, 2, "usage: locus-ds label"},
+		{"label missing queries", []string{"label", "--queries", "/nonexistent", "--out", "x"}, 1, "no such file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, stderr, code := run(t, newTestSource(), tt.args...)
+			if code != tt.code {
+				t.Errorf("exit code %d, want %d", code, tt.code)
+			}
+			if !strings.Contains(stderr, tt.msg) {
+				t.Errorf("stderr %q does not mention %q", stderr, tt.msg)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir := t.TempDir()
+	queries := dir + "/q.txt"
+	if err := os.WriteFile(queries, []byte("# comment\nreset password\n\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	a := newTestSource()
+	b := newTestSource()
+	b.topics = []datasource.DataSourceTopic{a.topics[1], {Topic: "New", SourceURL: "https://kb.example.com/9", TopicID: 9}}
+
+	var stdout, stderr bytes.Buffer
+	env := Env{
+		Stdout:  &stdout,
+		Stderr:  &stderr,
+		Sources: map[string]datasource.DataSource{"a": a},
+		Open: func(path string) (datasource.DataSource, error) {
+			if path != "b.json" {
+				t.Errorf("unexpected config path %q", path)
+			}
+			return b, nil
+		},
+	}
+	if code := Run([]string{"diff", "--a", "a", "--b", "b.json", "--queries", queries}, env); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr.String())
+	}
+
+	out := stdout.String()
+	for _, want := range []string{
+		"== reset password",
+		"+ [1] New <https://kb.example.com/9>",
+		"- [0] Reset a password <https://kb.example.com/1>",
+		"~ [1->0] Rotate keys, safely",
+		"1 of 1 queries changed",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestDiffWithoutConfigLoader(t *testing.T) {
+	queries := t.TempDir() + "/q.txt"
+	os.WriteFile(queries, []byte("q\n"), 0o644)
+
+	_, stderr, code := run(t, newTestSource(), "diff", "--a", "kb", "--b", "other.yaml", "--queries", queries)
+	if code != 1 || !strings.Contains(stderr, "cannot load configuration files") {
+		t.Errorf("unexpected result %d: %s", code, stderr)
+	}
+}
+
+func TestPlan(t *testing.T) {
+	dir := t.TempDir()
+	good := dir + "/good.json"
+	bad := dir + "/bad.json"
+	os.WriteFile(good, []byte(`{"sources": [{"name": "kb", "type": "remote", "settings": {"url": "https://kb"}}]}`), 0o644)
+	os.WriteFile(bad, []byte(`{"sources": [{"name": "kb", "type": "remote"}]}`), 0o644)
+
+	stdout, stderr, code := run(t, newTestSource(), "plan", good)
+	if code != 0 || !strings.Contains(stdout, `"timeout":"30s"`) || !strings.Contains(stdout, "No problems found.") {
+		t.Errorf("plan good (code %d): %s%s", code, stdout, stderr)
+	}
+
+	stdout, stderr, code = run(t, newTestSource(), "plan", "--format", "json", bad)
+	if code != 1 || !strings.Contains(stdout, `"problems"`) || !strings.Contains(stderr, "1 problem(s)") {
+		t.Errorf("plan bad (code %d): %s%s", code, stdout, stderr)
+	}
+}
+
+func TestInvalidate(t *testing.T) {
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/admin/cache/invalidate" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"removed":3}`))
+	}))
+	defer srv.Close()
+
+	stdout, stderr, code := run(t, newTestSource(), "invalidate", "--admin", srv.URL+"/admin/", "--site", "wiki", "--url-prefix", "https://wiki.example.com/")
+	if code != 0 || stdout != "Removed 3 cached results\n" {
+		t.Errorf("invalidate (code %d): %s%s", code, stdout, stderr)
+	}
+	if got["site"] != "wiki" || got["url_prefix"] != "https://wiki.example.com/" || got["all"] != nil {
+		t.Errorf("unexpected selector %v", got)
+	}
+
+	_, stderr, code = run(t, newTestSource(), "invalidate", "--admin", srv.URL)
+	if code != 1 || !strings.Contains(stderr, "no selector set") {
+		t.Errorf("empty selector (code %d): %s", code, stderr)
+	}
+}
+
+// countingSource counts the calls that reach it.
+type countingSource struct {
+	*cannedSource
+	calls int
+}
+
+func (s *countingSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	s.calls++
+	return s.cannedSource.FetchTopics(count, input)
+}
+
+func TestSeed(t *testing.T) {
+	dir := t.TempDir()
+	queries, seed := dir+"/queries.txt", dir+"/seed.json"
+	os.WriteFile(queries, []byte("reset password\n# comment\nrotate keys\n"), 0o644)
+
+	stdout, stderr, code := run(t, newTestSource(), "seed", "--queries", queries, "--out", seed)
+	if code != 0 || !strings.Contains(stdout, "Wrote 4 cached results for 2 questions") {
+		t.Fatalf("seed (code %d): %s%s", code, stdout, stderr)
+	}
+
+	// A replica's cache loads the seed at Init and answers without the source.
+	src := &countingSource{cannedSource: newTestSource()}
+	ds := middleware.Cache(src, middleware.CacheConfig{Name: "kb", Seed: seed})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "Rotate  keys"}); err != nil || len(topics) != 2 || src.calls != 0 {
+		t.Errorf("seeded FetchTopics = %v, %v with %d source calls", topics, err, src.calls)
+	}
+
+	_, stderr, code = run(t, newTestSource(), "seed", "--queries", queries, "--out", seed, "--snapshot", dir+"/index.json")
+	if code != 1 || !strings.Contains(stderr, "no index to snapshot") {
+		t.Errorf("snapshot of a source without an index (code %d): %s", code, stderr)
+	}
+}
+
+func TestScaffold(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "kb")
+	stdout, stderr, code := run(t, newTestSource(), "scaffold", "--name", "kb", "--kind", "local-index", "--out", out)
+	if code != 0 || !strings.Contains(stdout, filepath.Join(out, "kb_test.go")) {
+		t.Fatalf("scaffold (code %d): %s%s", code, stdout, stderr)
+	}
+	if b, err := os.ReadFile(filepath.Join(out, "kb.go")); err != nil || !strings.Contains(string(b), "package kb") {
+		t.Errorf("kb.go: %v", err)
+	}
+
+	_, stderr, code = run(t, newTestSource(), "scaffold", "--name", "kb", "--kind", "local-index", "--out", out)
+	if code != 1 || !strings.Contains(stderr, "exists") {
+		t.Errorf("scaffold over an existing package (code %d): %s", code, stderr)
+	}
+	_, stderr, code = run(t, newTestSource(), "scaffold", "--name", "kb", "--kind", "soap")
+	if code != 1 || !strings.Contains(stderr, "unknown kind") {
+		t.Errorf("scaffold of an unknown kind (code %d): %s", code, stderr)
+	}
+}
@@ -0,0 +1,188 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+// maxTextCell is the width at which text-format cells are truncated.
+const maxTextCell = 80
+
+// record is one result converted to JSON field values keyed by JSON name.
+type record map[string]json.RawMessage
+
+// jsonFields returns the JSON field names of struct type T in declaration
+// order.
+func jsonFields[T any]() []string {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	var names []string
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		switch name {
+		case "-":
+			continue
+		case "":
+			name = f.Name
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// selectFields validates the requested fields against the available ones.
+// An empty request selects every available field.
+func selectFields(available, requested []string) ([]string, error) {
+	if len(requested) == 0 {
+		return available, nil
+	}
+	for _, f := range requested {
+		found := false
+		for _, a := range available {
+			if a == f {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown field %q; available: %s", f, strings.Join(available, ", "))
+		}
+	}
+	return requested, nil
+}
+
+// writeRecords writes items in the named format, limited to fields.
+func writeRecords[T any](w io.Writer, format string, fields []string, items []T) error {
+	fields, err := selectFields(jsonFields[T](), fields)
+	if err != nil {
+		return err
+	}
+	records := make([]record, len(items))
+	for i, item := range items {
+		b, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &records[i]); err != nil {
+			return err
+		}
+	}
+
+	switch format {
+	case "text", "":
+		return writeText(w, fields, records)
+	case "csv":
+		return writeCSV(w, fields, records)
+	case "jsonl":
+		return writeJSONL(w, fields, records)
+	case "markdown", "md":
+		return writeMarkdown(w, fields, records)
+	default:
+		return fmt.Errorf("unknown format %q; use text, csv, jsonl, or markdown", format)
+	}
+}
+
+// cell renders a JSON value as plain text: strings unquoted, null and
+// missing values empty, everything else as compact JSON.
+func cell(raw json.RawMessage) string {
+	if len(raw) == 0 || string(raw) == "null" {
+		return ""
+	}
+	if raw[0] == '"' {
+		var s string
+		if json.Unmarshal(raw, &s) == nil {
+			return s
+		}
+	}
+	return string(raw)
+}
+
+func writeText(w io.Writer, fields []string, records []record) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(fields, "\t")))
+	for _, r := range records {
+		cells := make([]string, len(fields))
+		for i, f := range fields {
+			cells[i] = truncate(strings.Join(strings.Fields(cell(r[f])), " "), maxTextCell)
+		}
+		fmt.Fprintln(tw, strings.Join(cells, "\t"))
+	}
+	return tw.Flush()
+}
+
+func writeCSV(w io.Writer, fields []string, records []record) error {
+	cw := csv.NewWriter(w)
+	cw.Write(fields)
+	for _, r := range records {
+		row := make([]string, len(fields))
+		for i, f := range fields {
+			row[i] = cell(r[f])
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func writeJSONL(w io.Writer, fields []string, records []record) error {
+	var buf bytes.Buffer
+	for _, r := range records {
+		buf.Reset()
+		buf.WriteByte('{')
+		for i, f := range fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(f)
+			buf.Write(key)
+			buf.WriteByte(':')
+			if v, ok := r[f]; ok {
+				buf.Write(v)
+			} else {
+				buf.WriteString("null")
+			}
+		}
+		buf.WriteString("}\n")
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeMarkdown(w io.Writer, fields []string, records []record) error {
+	escape := strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>")
+	var b strings.Builder
+	b.WriteString("| " + strings.Join(fields, " | ") + " |\n|")
+	for range fields {
+		b.WriteString(" --- |")
+	}
+	b.WriteByte('\n')
+	for _, r := range records {
+		b.WriteByte('|')
+		for _, f := range fields {
+			b.WriteString(" " + escape.Replace(cell(r[f])) + " |")
+		}
+		b.WriteByte('\n')
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
@@ -0,0 +1,207 @@
+// Package composite provides a DataSource that federates several child
+// sources: FetchTopics fans out to every child concurrently and merges the
+// ranked results with a MergeStrategy, tolerating children that fail.
+//
+// Example:
+//
+//	ds := composite.New(composite.Weighted(2, 1), handbook, wiki)
+//
+// Unlike package router, which picks sources per question, a composite
+// always queries all of its children.
+package composite
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/topicmap"
+)
+
+// ambiguous marks in the ownership table a topic ID that more than one
+// child returned.
+const ambiguous = -1
+
+// Source is a DataSource merging the results of its children. Topics
+// returned by FetchTopics remember which child produced them so FetchData
+// can be sent to the same child; children should therefore use distinct
+// topic IDs, as FetchData fails for an ID more than one child returned. A
+// Source is safe for concurrent use.
+type Source struct {
+	children []datasource.DataSource
+	merge    MergeStrategy
+
+	// owners holds the index of the child that returned each topic, or
+	// ambiguous
+	owners topicmap.Map[int]
+}
+
+// New returns a Source over children, merging their topics with merge, or
+// RoundRobin if merge is nil.
+func New(merge MergeStrategy, children ...datasource.DataSource) *Source {
+	if merge == nil {
+		merge = RoundRobin()
+	}
+	return &Source{children: children, merge: merge}
+}
+
+// Children returns the child sources in the order given to New.
+func (s *Source) Children() []datasource.DataSource {
+	return append([]datasource.DataSource(nil), s.children...)
+}
+
+// Init initializes every child and returns all failures joined.
+func (s *Source) Init() error {
+	var errs []error
+	for i, ds := range s.children {
+		if err := ds.Init(); err != nil {
+			errs = append(errs, fmt.Errorf("composite: child %d: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckAvailability reports whether at least one child is available.
+func (s *Source) CheckAvailability() bool {
+	for _, ds := range s.children {
+		if ds.CheckAvailability() {
+			return true
+		}
+	}
+	return false
+}
+
+// HealthCheck reports each child as a component named by its index.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	components := make(map[string]datasource.HealthStatus, len(s.children))
+	for i, ds := range s.children {
+		components[fmt.Sprint(i)] = datasource.CheckHealth(ds)
+	}
+	return datasource.CombineHealth(components)
+}
+
+// FetchTopics queries every child concurrently and merges their results,
+// up to count topics. Failing children are skipped, as are children still
+// running when the question's Budget runs out; an error is returned only
+// if every child fails.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if len(s.children) == 0 || count <= 0 {
+		return []datasource.DataSourceTopic{}, nil
+	}
+
+	results := s.gather(count, input)
+
+	var errs []error
+	for _, r := range results {
+		if r.Err != nil {
+			errs = append(errs, fmt.Errorf("composite: child %d: %w", r.Child, r.Err))
+		}
+	}
+	if len(errs) == len(results) {
+		return nil, errors.Join(errs...)
+	}
+
+	picks := s.merge.Merge(count, input, results)
+	topics := make([]datasource.DataSourceTopic, 0, min(count, len(picks)))
+	for _, p := range picks {
+		if len(topics) == count {
+			break
+		}
+		r := results[p.Child]
+		if r.Err != nil || p.Rank < 0 || p.Rank >= len(r.Topics) {
+			continue
+		}
+		t := r.Topics[p.Rank]
+		topics = append(topics, t)
+		s.claim(t.TopicID, p.Child)
+	}
+	return topics, nil
+}
+
+// claim records child as the owner of topicID, unless another child
+// returned it too.
+func (s *Source) claim(topicID int64, child int) {
+	s.owners.Update(topicID, func(old int, ok bool) int {
+		if ok && old != child {
+			return ambiguous
+		}
+		return child
+	})
+}
+
+// gather calls FetchTopics on every child concurrently and collects the
+// results, giving up on children that have not answered when the input's
+// Budget runs out.
+func (s *Source) gather(count int, input datasource.NewQuestionInput) []Result {
+	done := make(chan Result, len(s.children))
+	for i, ds := range s.children {
+		go func(i int, ds datasource.DataSource) {
+			topics, err := ds.FetchTopics(count, input)
+			done <- Result{Child: i, Topics: topics, Err: err}
+		}(i, ds)
+	}
+	var expired <-chan time.Time
+	if left, ok := input.Budget.Remaining(); ok {
+		timer := time.NewTimer(left)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	results := make([]Result, len(s.children))
+	answered := make([]bool, len(s.children))
+	for range s.children {
+		select {
+		case r := <-done:
+			results[r.Child], answered[r.Child] = r, true
+		case <-expired:
+			for i := range results {
+				if !answered[i] {
+					results[i] = Result{Child: i, Err: fmt.Errorf("budget exhausted: %w: %w", datasource.ErrUnavailable, context.DeadlineExceeded)}
+				}
+			}
+			return results
+		}
+	}
+	return results
+}
+
+// FetchData fetches data from the child that returned topicID. If the
+// owner is unknown every child is tried and the result of the one child
+// with data is returned; an error is returned if every child fails, or if
+// more than one child returned topicID or has data for it.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if child, ok := s.owners.Get(topicID); ok {
+		if child == ambiguous {
+			return nil, fmt.Errorf("composite: topic %d was returned by more than one child: %w", topicID, datasource.ErrNotFound)
+		}
+		return s.children[child].FetchData(count, topicID)
+	}
+
+	var errs []error
+	found := -1
+	var result []datasource.DataSourceData
+	for i, ds := range s.children {
+		data, err := ds.FetchData(count, topicID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("composite: child %d: %w", i, err))
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if found >= 0 {
+			return nil, fmt.Errorf("composite: topic %d: children %d and %d both have data: %w", topicID, found, i, datasource.ErrNotFound)
+		}
+		found, result = i, data
+	}
+	if found >= 0 {
+		s.claim(topicID, found)
+		return result, nil
+	}
+	if len(s.children) > 0 && len(errs) == len(s.children) {
+		return nil, errors.Join(errs...)
+	}
+	return []datasource.DataSourceData{}, nil
+}
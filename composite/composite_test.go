@@ -0,0 +1,137 @@
+package composite_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/composite"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+)
+
+// child returns a source with n topics named prefix1..prefixN and IDs
+// base+1..base+n.
+func child(prefix string, base int64, n int) *datasourcetest.Fake {
+	f := &datasourcetest.Fake{Data: make(map[int64][]datasource.DataSourceData)}
+	for i := 1; i <= n; i++ {
+		id := base + int64(i)
+		f.Topics = append(f.Topics, datasource.DataSourceTopic{Topic: fmt.Sprint(prefix, i), TopicID: id})
+		f.Data[id] = []datasource.DataSourceData{{DataText: prefix, AnswerID: id}}
+	}
+	return f
+}
+
+func titles(topics []datasource.DataSourceTopic) string {
+	var s []string
+	for _, t := range topics {
+		s = append(s, t.Topic)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestMergeStrategies(t *testing.T) {
+	tests := []struct {
+		name  string
+		merge composite.MergeStrategy
+		count int
+		sizes [2]int
+		want  string
+	}{
+		{"round robin", composite.RoundRobin(), 5, [2]int{4, 2}, "a1,b1,a2,b2,a3"},
+		{"round robin uneven", nil, 10, [2]int{4, 2}, "a1,b1,a2,b2,a3,a4"},
+		{"weighted", composite.Weighted(1, 2), 6, [2]int{4, 4}, "b1,a1,b2,b3,a2,b4"},
+		{"weighted exhausted", composite.Weighted(1, 2), 6, [2]int{4, 1}, "b1,a1,a2,a3,a4"},
+		{"by score", composite.ByScore(func(t datasource.DataSourceTopic, _ composite.QueryContext) float64 {
+			return float64(t.TopicID % 10)
+		}), 3, [2]int{4, 2}, "a3,a2,b2"},
+		{"by source score", composite.BySourceScore(), 4, [2]int{3, 3}, "a1,b1,a3,b2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := child("a", 100, tt.sizes[0]), child("b", 200, tt.sizes[1])
+			// a scores like BM25 and ranks a3 above a2; b is unscored.
+			for i, score := range []float64{12, 3, 9} {
+				if i < len(a.Topics) {
+					a.Topics[i].Score = score
+				}
+			}
+			ds := composite.New(tt.merge, a, b)
+			topics, err := ds.FetchTopics(tt.count, datasource.NewQuestionInput{QuestionText: "q"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := titles(topics); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBySourceScoreBoost(t *testing.T) {
+	a, b := child("a", 100, 3), child("b", 200, 3)
+	for i, score := range []float64{12, 3, 9} {
+		a.Topics[i].Score = score
+	}
+	// The host prefers b's topics for its own tenant.
+	ds := composite.New(composite.BySourceScore(func(t datasource.DataSourceTopic, q composite.QueryContext) float64 {
+		if q.Input.TenantID == "acme" && q.Child == 1 {
+			return 1
+		}
+		return 0
+	}), a, b)
+
+	for tenant, want := range map[string]string{"": "a1,b1,a3,b2", "acme": "b1,b2,b3,a1"} {
+		topics, err := ds.FetchTopics(4, datasource.NewQuestionInput{QuestionText: "q", TenantID: tenant})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := titles(topics); got != want {
+			t.Errorf("tenant %q: got %s, want %s", tenant, got, want)
+		}
+	}
+}
+
+func TestPartialFailure(t *testing.T) {
+	broken := &datasourcetest.Fake{Errors: map[datasource.Method]error{datasource.MethodFetchTopics: datasource.ErrUnavailable}}
+	a := child("a", 100, 2)
+	ds := composite.New(nil, broken, a)
+
+	topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{})
+	if err != nil || titles(topics) != "a1,a2" {
+		t.Errorf("FetchTopics = %s, %v; want the healthy child's topics", titles(topics), err)
+	}
+	data, err := ds.FetchData(5, 101)
+	if err != nil || len(data) != 1 || len(broken.CallsTo(datasource.MethodFetchData)) != 0 {
+		t.Errorf("FetchData = %v, %v; want it sent to the owning child only", data, err)
+	}
+
+	allBroken := composite.New(nil, broken, broken)
+	if _, err := allBroken.FetchTopics(5, datasource.NewQuestionInput{}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchTopics with every child failing = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestCollidingTopicIDs(t *testing.T) {
+	a, b := child("a", 0, 2), child("b", 0, 2)
+	ds := composite.New(nil, a, b)
+	if data, err := ds.FetchData(5, 1); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData of an unknown topic both children have = %v, %v; want ErrNotFound", data, err)
+	}
+	if _, err := ds.FetchTopics(5, datasource.NewQuestionInput{}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := ds.FetchData(5, 2); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData of a topic both children returned = %v, %v; want ErrNotFound", data, err)
+	}
+
+	ds = composite.New(nil, a, child("c", 100, 2))
+	if data, err := ds.FetchData(5, 101); err != nil || len(data) != 1 || data[0].DataText != "c" {
+		t.Errorf("FetchData of an unknown topic one child has = %v, %v", data, err)
+	}
+}
+
+func TestCompositeConformance(t *testing.T) {
+	datasourcetest.RunConformance(t, composite.New(composite.Weighted(2, 1), child("a", 100, 3), child("b", 200, 3)))
+}
@@ -0,0 +1,175 @@
+package composite
+
+import (
+	"sort"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Result is one child's FetchTopics outcome, as passed to a MergeStrategy.
+type Result struct {
+	// Child is the child's index in the order given to New
+	Child int
+
+	// Topics are the child's ranked topics; nil if Err is set
+	Topics []datasource.DataSourceTopic
+
+	// Err is the child's error, if it failed
+	Err error
+}
+
+// Pick selects the topic at Rank in Child's result.
+type Pick struct {
+	Child int
+	Rank  int
+}
+
+// MergeStrategy combines children's ranked results for the question input
+// into one ranking. Merge returns picks in final order; the Source stops
+// after count topics and skips picks that do not refer to a returned
+// topic.
+type MergeStrategy interface {
+	Merge(count int, input datasource.NewQuestionInput, results []Result) []Pick
+}
+
+// MergeFunc adapts a function to the MergeStrategy interface.
+type MergeFunc func(count int, input datasource.NewQuestionInput, results []Result) []Pick
+
+// Merge calls f.
+func (f MergeFunc) Merge(count int, input datasource.NewQuestionInput, results []Result) []Pick {
+	return f(count, input, results)
+}
+
+// RoundRobin interleaves results by rank: every child's first topic, in
+// child order, then every child's second topic, and so on.
+func RoundRobin() MergeStrategy {
+	return MergeFunc(func(count int, _ datasource.NewQuestionInput, results []Result) []Pick {
+		var picks []Pick
+		for rank := 0; len(picks) < count; rank++ {
+			added := false
+			for _, r := range results {
+				if rank < len(r.Topics) && len(picks) < count {
+					picks = append(picks, Pick{Child: r.Child, Rank: rank})
+					added = true
+				}
+			}
+			if !added {
+				break
+			}
+		}
+		return picks
+	})
+}
+
+// QueryContext is what a ScoreFunc knows of the topic it scores besides
+// the topic itself.
+type QueryContext struct {
+	// Input is the question, including who asks it (AskedBy, TenantID)
+	Input datasource.NewQuestionInput
+
+	// Child is the index of the child that returned the topic
+	Child int
+
+	// Rank is the topic's zero-based rank in the child's result
+	Rank int
+}
+
+// ScoreFunc scores a topic for a question; higher is better. Hosts use it
+// to bring their own ranking signals, such as the asker's history or the
+// organization's priorities, to ByScore and BySourceScore.
+type ScoreFunc func(t datasource.DataSourceTopic, q QueryContext) float64
+
+// ByScore orders all topics by score, highest first. Ties keep child order
+// and then rank order.
+func ByScore(score ScoreFunc) MergeStrategy {
+	return MergeFunc(func(count int, input datasource.NewQuestionInput, results []Result) []Pick {
+		type scored struct {
+			Pick
+			score float64
+		}
+		var all []scored
+		for _, r := range results {
+			for rank, t := range r.Topics {
+				q := QueryContext{Input: input, Child: r.Child, Rank: rank}
+				all = append(all, scored{Pick{r.Child, rank}, score(t, q)})
+			}
+		}
+		sort.SliceStable(all, func(i, j int) bool { return all[i].score > all[j].score })
+		picks := make([]Pick, 0, min(count, len(all)))
+		for _, s := range all[:min(count, len(all))] {
+			picks = append(picks, s.Pick)
+		}
+		return picks
+	})
+}
+
+// BySourceScore orders all topics by the Score their source gave them,
+// scaled by the highest Score in the same child's result so that sources
+// scoring on different scales (BM25, cosine similarity, votes) compare.
+// Children that do not score their results are scored by reciprocal rank,
+// 1/(rank+1), which puts their first topic level with the others' best.
+//
+// The scores of boosts are added to that fused score, so hosts can adjust
+// the ranking without replacing it; a boost of 0.5 lifts a topic half of
+// the best topic's score.
+func BySourceScore(boosts ...ScoreFunc) MergeStrategy {
+	return MergeFunc(func(count int, input datasource.NewQuestionInput, results []Result) []Pick {
+		scale := make(map[int]float64, len(results))
+		for _, r := range results {
+			for _, t := range r.Topics {
+				scale[r.Child] = max(scale[r.Child], t.Score)
+			}
+		}
+		return ByScore(func(t datasource.DataSourceTopic, q QueryContext) float64 {
+			score := 1 / float64(q.Rank+1)
+			if s := scale[q.Child]; s > 0 {
+				score = t.Score / s
+			}
+			for _, boost := range boosts {
+				score += boost(t, q)
+			}
+			return score
+		}).Merge(count, input, results)
+	})
+}
+
+// Weighted interleaves results in proportion to weights: a child weighted
+// 2 contributes two topics for every one from a child weighted 1, each
+// child in its own rank order. Children without a weight, or with a
+// non-positive one, get 1. Children that run out of topics drop out.
+func Weighted(weights ...float64) MergeStrategy {
+	return MergeFunc(func(count int, _ datasource.NewQuestionInput, results []Result) []Pick {
+		w := make([]float64, len(results))
+		next := make([]int, len(results))
+		credit := make([]float64, len(results))
+		for i, r := range results {
+			w[i] = 1
+			if r.Child < len(weights) && weights[r.Child] > 0 {
+				w[i] = weights[r.Child]
+			}
+		}
+		var picks []Pick
+		for len(picks) < count {
+			// Smooth weighted round-robin: every child with topics left
+			// earns its weight, and the richest spends the total.
+			best, total := -1, 0.0
+			for i, r := range results {
+				if next[i] >= len(r.Topics) {
+					continue
+				}
+				credit[i] += w[i]
+				total += w[i]
+				if best < 0 || credit[i] > credit[best] {
+					best = i
+				}
+			}
+			if best < 0 {
+				break
+			}
+			credit[best] -= total
+			picks = append(picks, Pick{Child: results[best].Child, Rank: next[best]})
+			next[best]++
+		}
+		return picks
+	})
+}
@@ -0,0 +1,215 @@
+// Package config builds data sources from declarative descriptors, so
+// operators can add or retune a source by editing a file instead of
+// recompiling the host.
+//
+// A descriptor lists sources by name, each with a type, type-specific
+// settings, an optional credentials reference, and a middleware chain:
+//
+//	{
+//	  "sources": [
+//	    {
+//	      "name": "kb",
+//	      "type": "remote",
+//	      "settings": {"url": "https://kb-source.internal"},
+//	      "credentials": "env:KB_TOKEN",
+//	      "middleware": [
+//	        {"type": "logging"},
+//	        {"type": "cache", "ttl": "10m"},
+//	        {"type": "retry", "max_attempts": 4},
+//	        {"type": "rate_limit", "requests_per_second": 5, "burst": 10}
+//	      ]
+//	    }
+//	  ]
+//	}
+//
+// Middleware is listed from outermost to innermost, so in the example a
+// cache hit skips the retry and rate limit layers, as with
+// middleware.Chain. The built-in source types are "remote" (a
+// remote.Client) and "plugin" (a plugin launched with plugin.Launch);
+// hosts add their own with Loader.Types, and middleware types, configured
+// by their "settings", with Loader.Middleware.
+//
+// Descriptors are JSON. YAML is supported by setting Loader.Unmarshal to a
+// function that honors JSON field names, such as sigs.k8s.io/yaml.Unmarshal;
+// the SDK does not depend on a YAML library itself.
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// File is a parsed descriptor.
+type File struct {
+	Sources []Source `json:"sources"`
+}
+
+// Source describes one data source.
+type Source struct {
+	// Name is the configuration name the source is registered under
+	Name string `json:"name"`
+
+	// Type selects the constructor, e.g. "remote" or "plugin"
+	Type string `json:"type"`
+
+	// Settings are passed to the constructor for Type
+	Settings json.RawMessage `json:"settings,omitempty"`
+
+	// Credentials is a reference to a secret, resolved when the source is
+	// built: "env:NAME" reads an environment variable and "file:PATH" reads
+	// a file. Secrets themselves never appear in descriptors
+	Credentials string `json:"credentials,omitempty"`
+
+	// Middleware decorates the source, listed from outermost to innermost
+	Middleware []Middleware `json:"middleware,omitempty"`
+
+	// Timeouts bounds Init, fetches, and health checks separately (see
+	// middleware.Timeouts); fields left zero take Loader.Timeouts
+	// Optional
+	Timeouts *Timeouts `json:"timeouts,omitempty"`
+
+	// Snapshot is the path of a snapshot of the source's local state,
+	// such as an index, loaded when the source is built so that Init need
+	// not rebuild it (see datasource.Snapshotter); a missing file is
+	// ignored
+	// Optional
+	Snapshot string `json:"snapshot,omitempty"`
+
+	// DependsOn names sources that must be initialized before this one;
+	// the pipeline leaves the source out if any of them fails
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// Required means the pipeline is not ready to serve until the source
+	// is initialized and healthy
+	Required bool `json:"required,omitempty"`
+}
+
+// Middleware configures one decorator. Only the fields relevant to Type
+// are used; zero fields take the decorator's defaults.
+type Middleware struct {
+	// Type is "retry", "rate_limit", "cache", "logging", "sanitize"
+	// (HTML data sanitized with the default content.Policy),
+	// "size_limit", "filter_languages" (results in languages the asker
+	// does not accept dropped), "length_band", "dedup", "redact"
+	// (personal data and secrets redacted from questions and results with
+	// the default privacy.Redactor), "maintenance", or a type added with
+	// Loader.Middleware
+	Type string `json:"type"`
+
+	// Settings configures a type added with Loader.Middleware
+	// Optional
+	Settings json.RawMessage `json:"settings,omitempty"`
+
+	// MaxAttempts, InitialBackoff, and MaxBackoff configure "retry"
+	MaxAttempts    int      `json:"max_attempts,omitempty"`
+	InitialBackoff Duration `json:"initial_backoff,omitempty"`
+	MaxBackoff     Duration `json:"max_backoff,omitempty"`
+
+	// RequestsPerSecond and Burst configure "rate_limit"
+	RequestsPerSecond float64 `json:"requests_per_second,omitempty"`
+	Burst             int     `json:"burst,omitempty"`
+
+	// TTL, MaxEntries, Partition, Seed, and SeedMaxAge configure "cache";
+	// Partition keys results by requester for permission-sensitive
+	// sources, and Seed is the path of a seed artifact loaded at Init (see
+	// middleware.CacheConfig)
+	TTL        Duration `json:"ttl,omitempty"`
+	MaxEntries int      `json:"max_entries,omitempty"`
+	Partition  bool     `json:"partition,omitempty"`
+	Seed       string   `json:"seed,omitempty"`
+	SeedMaxAge Duration `json:"seed_max_age,omitempty"`
+
+	// MaxBytesPerCall, MaxBytesPerQuery, and DropOversized configure
+	// "size_limit" (see middleware.SizeLimits); at least one limit is
+	// required
+	MaxBytesPerCall  int  `json:"max_bytes_per_call,omitempty"`
+	MaxBytesPerQuery int  `json:"max_bytes_per_query,omitempty"`
+	DropOversized    bool `json:"drop_oversized,omitempty"`
+
+	// MinChars, MaxChars, and RequireLength configure "length_band" (see
+	// middleware.Band); at least one length is required
+	MinChars      int  `json:"min_chars,omitempty"`
+	MaxChars      int  `json:"max_chars,omitempty"`
+	RequireLength bool `json:"require_length,omitempty"`
+
+	// Threshold and URLOnly configure "dedup" (see middleware.DedupConfig)
+	Threshold float64 `json:"threshold,omitempty"`
+	URLOnly   bool    `json:"url_only,omitempty"`
+
+	// Windows and Timezone configure "maintenance" (see
+	// middleware.MaintenanceConfig): the source is treated as down for
+	// scheduled maintenance during the windows, read in the IANA time
+	// zone Timezone, or UTC if empty; at least one window is required
+	Windows  []MaintenanceWindow `json:"windows,omitempty"`
+	Timezone string              `json:"timezone,omitempty"`
+}
+
+// MaintenanceWindow is a recurring maintenance window of a source.
+type MaintenanceWindow struct {
+	// Start is a five-field cron expression of when the window opens,
+	// such as "0 2 * * *" for 02:00 daily
+	Start string `json:"start"`
+
+	// Duration is how long the window stays open
+	Duration Duration `json:"duration"`
+}
+
+// Timeouts bounds each kind of call to a source. A negative timeout
+// disables the bound.
+type Timeouts struct {
+	// Init bounds Init, which may load indexes
+	Init Duration `json:"init,omitempty"`
+
+	// Fetch bounds FetchTopics and FetchData
+	Fetch Duration `json:"fetch,omitempty"`
+
+	// Health bounds availability and health checks
+	Health Duration `json:"health,omitempty"`
+}
+
+// Duration is a time.Duration written as a string such as "1m30s".
+type Duration time.Duration
+
+// UnmarshalJSON accepts a duration string or a number of nanoseconds.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		var n int64
+		if err := json.Unmarshal(b, &n); err != nil {
+			return fmt.Errorf("invalid duration %s", b)
+		}
+		*d = Duration(n)
+		return nil
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Validate checks that names are present and unique and that every
+// source has a type.
+func (f *File) Validate() error {
+	seen := make(map[string]bool, len(f.Sources))
+	for i, s := range f.Sources {
+		switch {
+		case strings.TrimSpace(s.Name) == "":
+			return fmt.Errorf("config: source %d: missing name", i)
+		case seen[s.Name]:
+			return fmt.Errorf("config: source %q: defined more than once", s.Name)
+		case s.Type == "":
+			return fmt.Errorf("config: source %q: missing type", s.Name)
+		}
+		seen[s.Name] = true
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package config_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/config"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/remote"
+)
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name, doc, want string
+	}{
+		{"unknown field", `{"sources": [{"name": "a", "type": "remote", "ttl": "1m"}]}`, `unknown field "ttl"`},
+		{"missing name", `{"sources": [{"type": "remote"}]}`, "missing name"},
+		{"missing type", `{"sources": [{"name": "a"}]}`, "missing type"},
+		{"duplicate", `{"sources": [{"name": "a", "type": "x"}, {"name": "a", "type": "x"}]}`, "defined more than once"},
+		{"yaml without unmarshal", "sources:\n  - name: a\n", "no Loader.Unmarshal"},
+		{"bad duration", `{"sources": [{"name": "a", "type": "x", "middleware": [{"type": "cache", "ttl": "soon"}]}]}`, "invalid duration"},
+	}
+
+	var l config.Loader
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := l.Parse([]byte(tt.doc))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Parse error %v does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBuildAppliesMiddlewareOutermostFirst(t *testing.T) {
+	fake := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 1}}}
+	fake.FailNext(datasource.MethodFetchTopics, datasource.ErrUnavailable)
+	var gotSettings string
+	l := config.Loader{Types: map[string]config.Constructor{
+		"fake": func(settings json.RawMessage, creds string) (datasource.DataSource, error) {
+			gotSettings = string(settings)
+			return fake, nil
+		},
+	}}
+	f, err := l.Parse([]byte(`{"sources": [{
+		"name": "kb", "type": "fake", "settings": {"index": "docs"},
+		"middleware": [{"type": "cache", "ttl": "1m"}, {"type": "retry", "max_attempts": 2, "initial_backoff": "1ms"}]
+	}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := l.Build(f.Sources[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gotSettings != `{"index": "docs"}` {
+		t.Errorf("constructor got settings %s", gotSettings)
+	}
+
+	input := datasource.NewQuestionInput{QuestionText: "dns"}
+	for i := 0; i < 2; i++ {
+		if _, err := ds.FetchTopics(3, input); err != nil {
+			t.Fatalf("FetchTopics: %v", err)
+		}
+	}
+	// The retry absorbs the injected failure and the cache serves the
+	// second call.
+	if n := len(fake.CallsTo(datasource.MethodFetchTopics)); n != 2 {
+		t.Errorf("source called %d times, want 2", n)
+	}
+}
+
+func TestBuildWithHostMiddleware(t *testing.T) {
+	fake := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 1}}}
+	var calls []string
+	l := config.Loader{
+		Types: map[string]config.Constructor{
+			"fake": func(json.RawMessage, string) (datasource.DataSource, error) { return fake, nil },
+		},
+		Middleware: map[string]config.MiddlewareConstructor{
+			"count": func(settings json.RawMessage, source string) (middleware.Wrapper, error) {
+				var s struct{ Label string }
+				if err := json.Unmarshal(settings, &s); err != nil {
+					return nil, err
+				}
+				return middleware.UseHooks(source, datasource.Hooks{
+					OnRequestStart: func(_ context.Context, info datasource.RequestInfo) {
+						calls = append(calls, s.Label+":"+info.Source)
+					},
+				}), nil
+			},
+		},
+	}
+	f, err := l.Parse([]byte(`{"sources": [{
+		"name": "kb", "type": "fake",
+		"middleware": [{"type": "count", "settings": {"label": "outer"}}, {"type": "cache"}, {"type": "count", "settings": {"label": "inner"}}]
+	}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := l.Build(f.Sources[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		ds.FetchTopics(3, datasource.NewQuestionInput{QuestionText: "dns"})
+	}
+	if want := []string{"outer:kb", "inner:kb", "outer:kb"}; strings.Join(calls, " ") != strings.Join(want, " ") {
+		t.Errorf("calls = %v, want %v", calls, want)
+	}
+	if _, err := l.Resolve(f.Sources[0]); err != nil {
+		t.Errorf("Resolve with host middleware: %v", err)
+	}
+
+	f.Sources[0].Middleware[0].Settings = json.RawMessage(`[]`)
+	if _, err := l.Build(f.Sources[0]); err == nil || !strings.Contains(err.Error(), "middleware 0") {
+		t.Errorf("Build with bad middleware settings = %v", err)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	var l config.Loader
+	tests := []struct {
+		src  config.Source
+		want string
+	}{
+		{config.Source{Name: "a", Type: "ftp"}, `unknown type "ftp" (known: plugin, remote)`},
+		{config.Source{Name: "a", Type: "remote"}, "missing url"},
+		{config.Source{Name: "a", Type: "remote", Settings: json.RawMessage(`{"url": "x", "verbose": true}`)}, `unknown field "verbose"`},
+		{config.Source{Name: "a", Type: "remote", Settings: json.RawMessage(`{"url": "x"}`), Credentials: "env:LOCUS_TEST_UNSET"}, "LOCUS_TEST_UNSET is not set"},
+		{config.Source{Name: "a", Type: "plugin", Settings: json.RawMessage(`{"command": "/bin/true"}`)}, "credentials"},
+		{config.Source{Name: "a", Type: "remote", Settings: json.RawMessage(`{"url": "x"}`), Middleware: []config.Middleware{{Type: "gzip"}}}, `unknown middleware type "gzip"`},
+		{config.Source{Name: "a", Type: "remote", Settings: json.RawMessage(`{"url": "x"}`), Snapshot: "index.json"}, "does not support snapshots"},
+	}
+	for _, tt := range tests {
+		if _, err := l.Build(tt.src); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Build(%+v) error %v does not mention %q", tt.src, err, tt.want)
+		}
+	}
+}
+
+func TestRemoteSourceWithCredentials(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		remote.NewHandler(&datasourcetest.Fake{}).ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "token")
+	os.WriteFile(secret, []byte("s3cret\n"), 0o600)
+	path := filepath.Join(dir, "kb.json")
+	os.WriteFile(path, []byte(`{"sources": [{"name": "kb", "type": "remote",
+		"settings": {"url": "`+srv.URL+`", "timeout": "5s"}, "credentials": "file:`+secret+`"}]}`), 0o644)
+
+	ds, err := config.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if auth != "Bearer s3cret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+}
+
+func TestRegisterIsLazy(t *testing.T) {
+	built := 0
+	l := config.Loader{Types: map[string]config.Constructor{
+		"fake": func(json.RawMessage, string) (datasource.DataSource, error) {
+			built++
+			return &datasourcetest.Fake{}, nil
+		},
+	}}
+	f, _ := l.Parse([]byte(`{"sources": [{"name": "b", "type": "fake"}, {"name": "a", "type": "fake"}]}`))
+
+	reg := datasource.NewRegistry()
+	if err := l.Register(reg, f); err != nil {
+		t.Fatal(err)
+	}
+	if built != 0 || strings.Join(reg.List(), ",") != "a,b" {
+		t.Fatalf("built %d sources, registry has %v", built, reg.List())
+	}
+	if _, err := reg.Get("a"); err != nil || built != 1 {
+		t.Errorf("Get(a) = %v, built %d", err, built)
+	}
+	if err := l.Register(reg, f); err == nil {
+		t.Error("registering twice should fail")
+	}
+}
+
+func TestResolveCredentials(t *testing.T) {
+	t.Setenv("LOCUS_TEST_TOKEN", "abc")
+	if v, err := config.ResolveCredentials("env:LOCUS_TEST_TOKEN"); err != nil || v != "abc" {
+		t.Errorf("env ref = %q, %v", v, err)
+	}
+	for _, ref := range []string{"abc", "vault:x", "env:"} {
+		if _, err := config.ResolveCredentials(ref); err == nil {
+			t.Errorf("ResolveCredentials(%q) should fail", ref)
+		}
+	}
+	if _, err := config.Open(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Open(missing) = %v", err)
+	}
+}
+
+func TestResolveFillsDefaults(t *testing.T) {
+	var l config.Loader
+	s, err := l.Resolve(config.Source{
+		Name:       "kb",
+		Type:       "remote",
+		Settings:   json.RawMessage(`{"url": "https://kb"}`),
+		Middleware: []config.Middleware{{Type: "rate_limit", RequestsPerSecond: 2}, {Type: "cache"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(s.Settings) != `{"url":"https://kb","timeout":"30s"}` {
+		t.Errorf("settings %s", s.Settings)
+	}
+	if s.Middleware[0].Burst != 1 || s.Middleware[1].TTL == 0 || s.Middleware[1].MaxEntries == 0 {
+		t.Errorf("middleware defaults not filled: %+v", s.Middleware)
+	}
+
+	if _, err := l.Resolve(config.Source{Name: "p", Type: "plugin", Settings: json.RawMessage(`{"command": "x"}`)}); err == nil ||
+		!strings.Contains(err.Error(), "credentials") {
+		t.Errorf("Resolve plugin without credentials = %v", err)
+	}
+	if _, err := l.Resolve(config.Source{Name: "kb", Type: "remote", Settings: json.RawMessage(`{"url": "https://kb"}`),
+		Middleware: []config.Middleware{{Type: "size_limit"}}}); err == nil || !strings.Contains(err.Error(), "size_limit") {
+		t.Errorf("Resolve size_limit without limits = %v", err)
+	}
+	if _, err := l.Resolve(config.Source{Name: "kb", Type: "remote", Settings: json.RawMessage(`{"url": "https://kb"}`),
+		Middleware: []config.Middleware{{Type: "length_band", MinChars: 500, MaxChars: 200}}}); err == nil || !strings.Contains(err.Error(), "length_band") {
+		t.Errorf("Resolve length_band with min above max = %v", err)
+	}
+	if _, err := l.Resolve(config.Source{Name: "kb", Type: "remote", Settings: json.RawMessage(`{"url": "https://kb"}`),
+		Middleware: []config.Middleware{{Type: "dedup", Threshold: 1.5}}}); err == nil || !strings.Contains(err.Error(), "dedup") {
+		t.Errorf("Resolve dedup with threshold above 1 = %v", err)
+	}
+	if _, err := l.Resolve(config.Source{Name: "kb", Type: "remote", Settings: json.RawMessage(`{"url": "https://kb"}`),
+		Middleware: []config.Middleware{{Type: "maintenance", Windows: []config.MaintenanceWindow{{Start: "0 25 * * *", Duration: config.Duration(time.Hour)}}}}}); err == nil ||
+		!strings.Contains(err.Error(), "maintenance") {
+		t.Errorf("Resolve maintenance with an invalid window = %v", err)
+	}
+}
+
+// slowInit delays Init.
+type slowInit struct {
+	datasource.DataSource
+	delay time.Duration
+}
+
+func (s slowInit) Init() error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+func TestBuildAppliesTimeouts(t *testing.T) {
+	l := config.Loader{
+		Types: map[string]config.Constructor{
+			"slow": func(json.RawMessage, string) (datasource.DataSource, error) {
+				return slowInit{&datasourcetest.Fake{}, 50 * time.Millisecond}, nil
+			},
+		},
+		Timeouts: middleware.Timeouts{Init: time.Minute},
+	}
+	f, err := l.Parse([]byte(`{"sources": [
+		{"name": "bounded", "type": "slow", "timeouts": {"init": "10ms"}},
+		{"name": "default", "type": "slow"}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds, err := l.Build(f.Sources[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Init(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Init with a 10ms timeout = %v, want DeadlineExceeded", err)
+	}
+	ds, err = l.Build(f.Sources[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Init(); err != nil {
+		t.Errorf("Init with the loader's timeout = %v", err)
+	}
+
+	s, err := l.Resolve(config.Source{Name: "kb", Type: "remote", Settings: json.RawMessage(`{"url": "https://kb"}`),
+		Timeouts: &config.Timeouts{Health: config.Duration(time.Second)}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := config.Timeouts{Init: config.Duration(time.Minute), Fetch: config.Duration(middleware.DefaultFetchTimeout), Health: config.Duration(time.Second)}
+	if s.Timeouts == nil || *s.Timeouts != want {
+		t.Errorf("resolved timeouts %+v, want %+v", s.Timeouts, want)
+	}
+}
@@ -0,0 +1,323 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/privacy"
+)
+
+// Constructor builds a data source of one type from its settings and its
+// resolved credentials (empty if none were configured).
+type Constructor func(settings json.RawMessage, credentials string) (datasource.DataSource, error)
+
+// MiddlewareConstructor builds a Wrapper for a middleware type from the
+// middleware's Settings and the name of the source it wraps.
+type MiddlewareConstructor func(settings json.RawMessage, source string) (middleware.Wrapper, error)
+
+// Loader parses descriptors and builds the sources they describe. The zero
+// value reads JSON and supports the built-in source types.
+type Loader struct {
+	// Types adds source types, or replaces built-in ones, by name
+	Types map[string]Constructor
+
+	// Middleware adds middleware types, or replaces built-in ones, by
+	// name, such as a "metrics" type instrumenting sources
+	Middleware map[string]MiddlewareConstructor
+
+	// Unmarshal decodes descriptors that are not JSON, such as YAML files;
+	// it must honor JSON field names
+	Unmarshal func(data []byte, v any) error
+
+	// ResolveCredentials resolves Source.Credentials references
+	// Defaults to supporting "env:NAME" and "file:PATH"
+	ResolveCredentials func(ref string) (string, error)
+
+	// Timeouts are the timeouts of sources whose Source.Timeouts leave
+	// them zero; sources get no timeouts if both are zero
+	// Optional
+	Timeouts middleware.Timeouts
+}
+
+// Parse decodes and validates a descriptor. JSON descriptors must not
+// contain unknown fields, which catches misspelled options.
+func (l *Loader) Parse(data []byte) (*File, error) {
+	var f File
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&f); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	} else if l.Unmarshal != nil {
+		if err := l.Unmarshal(data, &f); err != nil {
+			return nil, fmt.Errorf("config: %w", err)
+		}
+	} else {
+		return nil, fmt.Errorf("config: descriptor is not JSON and no Loader.Unmarshal is set")
+	}
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+	return &f, nil
+}
+
+// LoadFile reads and parses the descriptor at path.
+func (l *Loader) LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("config: %w", err)
+	}
+	f, err := l.Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return f, nil
+}
+
+// Build constructs and decorates the source described by s. It does not
+// call Init.
+func (l *Loader) Build(s Source) (datasource.DataSource, error) {
+	ctor, ok := l.Types[s.Type]
+	if !ok {
+		ctor, ok = builtinTypes[s.Type]
+	}
+	if !ok {
+		return nil, fmt.Errorf("config: source %q: unknown type %q (known: %s)", s.Name, s.Type, strings.Join(l.typeNames(), ", "))
+	}
+	var creds string
+	if s.Credentials != "" {
+		resolve := l.ResolveCredentials
+		if resolve == nil {
+			resolve = ResolveCredentials
+		}
+		var err error
+		if creds, err = resolve(s.Credentials); err != nil {
+			return nil, fmt.Errorf("config: source %q: credentials: %w", s.Name, err)
+		}
+	}
+	wrappers, err := l.wrappers(s)
+	if err != nil {
+		return nil, err
+	}
+	ds, err := ctor(s.Settings, creds)
+	if err != nil {
+		return nil, fmt.Errorf("config: source %q: %w", s.Name, err)
+	}
+	if s.Snapshot != "" {
+		if err := readSnapshot(ds, s.Snapshot); err != nil {
+			return nil, fmt.Errorf("config: source %q: %w", s.Name, err)
+		}
+	}
+	return l.decorate(s, ds, wrappers), nil
+}
+
+// Decorate applies the timeouts and middleware of s to ds, as Build does
+// to the source it constructs, for a source constructed another way, such
+// as a replacement swapped in for s.
+func (l *Loader) Decorate(s Source, ds datasource.DataSource) (datasource.DataSource, error) {
+	wrappers, err := l.wrappers(s)
+	if err != nil {
+		return nil, err
+	}
+	return l.decorate(s, ds, wrappers), nil
+}
+
+// wrappers returns the middleware of s.
+func (l *Loader) wrappers(s Source) ([]middleware.Wrapper, error) {
+	wrappers := make([]middleware.Wrapper, len(s.Middleware))
+	for i, m := range s.Middleware {
+		var err error
+		if wrappers[i], err = l.Wrapper(s.Name, m); err != nil {
+			return nil, fmt.Errorf("config: source %q: middleware %d: %w", s.Name, i, err)
+		}
+	}
+	return wrappers, nil
+}
+
+func (l *Loader) decorate(s Source, ds datasource.DataSource, wrappers []middleware.Wrapper) datasource.DataSource {
+	if t := l.timeouts(s.Timeouts); !t.IsZero() {
+		// Innermost, so each retry attempt is bounded.
+		ds = middleware.Timeout(ds, t)
+	}
+	return middleware.Chain(ds, wrappers...)
+}
+
+// timeouts returns t with its zero fields taken from l.Timeouts.
+func (l *Loader) timeouts(t *Timeouts) middleware.Timeouts {
+	mt := l.Timeouts
+	if t == nil {
+		return mt
+	}
+	if t.Init != 0 {
+		mt.Init = time.Duration(t.Init)
+	}
+	if t.Fetch != 0 {
+		mt.Fetch = time.Duration(t.Fetch)
+	}
+	if t.Health != 0 {
+		mt.Health = time.Duration(t.Health)
+	}
+	return mt
+}
+
+// readSnapshot loads the snapshot at path into ds, if the file exists.
+func readSnapshot(ds datasource.DataSource, path string) error {
+	sn, ok := datasource.SnapshotterOf(ds)
+	if !ok {
+		return errors.New("snapshot: source does not support snapshots")
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("snapshot: %w", err)
+	}
+	defer f.Close()
+	if err := sn.ReadSnapshot(f); err != nil {
+		return fmt.Errorf("snapshot %s: %w", path, err)
+	}
+	return nil
+}
+
+// Register adds every source in f to reg. Sources are built and
+// initialized lazily, on their first Get.
+func (l *Loader) Register(reg *datasource.Registry, f *File) error {
+	for _, s := range f.Sources {
+		s := s
+		if err := reg.Register(s.Name, func() (datasource.DataSource, error) { return l.Build(s) }); err != nil {
+			return fmt.Errorf("config: %w", err)
+		}
+	}
+	return nil
+}
+
+func (l *Loader) typeNames() []string {
+	var names []string
+	for name := range builtinTypes {
+		if _, ok := l.Types[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	for name := range l.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Wrapper returns the Wrapper m describes, for the source with the name.
+func (l *Loader) Wrapper(source string, m Middleware) (middleware.Wrapper, error) {
+	if ctor, ok := l.Middleware[m.Type]; ok {
+		return ctor(m.Settings, source)
+	}
+	m, err := m.resolve()
+	if err != nil {
+		return nil, err
+	}
+	switch m.Type {
+	case "retry":
+		return middleware.UseRetry(middleware.RetryPolicy{
+			MaxAttempts:    m.MaxAttempts,
+			InitialBackoff: time.Duration(m.InitialBackoff),
+			MaxBackoff:     time.Duration(m.MaxBackoff),
+			Jitter:         middleware.DefaultRetryPolicy().Jitter,
+		}), nil
+	case "rate_limit":
+		return middleware.UseRateLimit(m.RequestsPerSecond, m.Burst), nil
+	case "cache":
+		return middleware.UseCache(middleware.CacheConfig{
+			TTL:        time.Duration(m.TTL),
+			MaxEntries: m.MaxEntries,
+			Name:       source,
+			Partition:  m.Partition,
+			Seed:       m.Seed,
+			SeedMaxAge: time.Duration(m.SeedMaxAge),
+		}), nil
+	case "sanitize":
+		return middleware.UseSanitize(content.Policy{}), nil
+	case "size_limit":
+		return middleware.UseSizeLimit(middleware.SizeLimits{
+			PerCall:  m.MaxBytesPerCall,
+			PerQuery: m.MaxBytesPerQuery,
+			Drop:     m.DropOversized,
+		}), nil
+	case "filter_languages":
+		return middleware.FilterLanguages, nil
+	case "length_band":
+		return middleware.UseLengthBand(middleware.Band{
+			MinChars: m.MinChars,
+			MaxChars: m.MaxChars,
+			Require:  m.RequireLength,
+		}), nil
+	case "dedup":
+		return middleware.UseDedup(middleware.DedupConfig{
+			Threshold: m.Threshold,
+			URLOnly:   m.URLOnly,
+		}), nil
+	case "maintenance":
+		cfg, err := m.maintenance()
+		if err != nil {
+			return nil, err
+		}
+		return middleware.UseMaintenance(cfg)
+	case "redact":
+		return func(ds datasource.DataSource) datasource.DataSource {
+			return privacy.Wrap(ds, privacy.Config{})
+		}, nil
+	default: // "logging"
+		return middleware.UseHooks(source, datasource.SlogHooks(nil)), nil
+	}
+}
+
+// ResolveCredentials resolves "env:NAME" and "file:PATH" references. File
+// contents are trimmed of surrounding whitespace.
+func ResolveCredentials(ref string) (string, error) {
+	kind, arg, ok := strings.Cut(ref, ":")
+	if !ok || arg == "" {
+		return "", fmt.Errorf("invalid reference %q; want env:NAME or file:PATH", ref)
+	}
+	switch kind {
+	case "env":
+		v, ok := os.LookupEnv(arg)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", arg)
+		}
+		return v, nil
+	case "file":
+		b, err := os.ReadFile(arg)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(b)), nil
+	default:
+		return "", fmt.Errorf("unsupported reference kind %q; want env or file", kind)
+	}
+}
+
+// Open loads the descriptor at path with the default Loader and builds its
+// only source, without calling Init. It is meant for tools, such as
+// locus-ds, that work with one source at a time.
+func Open(path string) (datasource.DataSource, error) {
+	var l Loader
+	f, err := l.LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if len(f.Sources) != 1 {
+		return nil, fmt.Errorf("config: %s defines %d sources, want 1", filepath.Base(path), len(f.Sources))
+	}
+	return l.Build(f.Sources[0])
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/locus-search/datasource-sdk/cache"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/plugin"
+	"github.com/locus-search/datasource-sdk/remote"
+)
+
+// builtinResolvers apply the defaults of built-in types to their settings
+// and report settings that would fail to build.
+var builtinResolvers = map[string]func(raw json.RawMessage, hasCreds bool) (any, error){
+	"remote": resolveRemote,
+	"plugin": resolvePlugin,
+}
+
+// Resolve returns s with every default made explicit: the settings of
+// built-in types and every middleware field. It checks everything Build
+// would, without constructing the source, launching plugins, or keeping
+// credential values, and returns the problems found joined. The returned
+// Source is usable even when the error is not nil.
+//
+// Settings of host-provided source and middleware types are returned as
+// written.
+func (l *Loader) Resolve(s Source) (Source, error) {
+	var errs []error
+	problem := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf("config: source %q: "+format, append([]any{s.Name}, args...)...))
+	}
+
+	if s.Credentials != "" {
+		resolve := l.ResolveCredentials
+		if resolve == nil {
+			resolve = ResolveCredentials
+		}
+		if _, err := resolve(s.Credentials); err != nil {
+			problem("credentials: %w", err)
+		}
+	}
+
+	_, custom := l.Types[s.Type]
+	resolveType, builtin := builtinResolvers[s.Type]
+	switch {
+	case custom:
+	case builtin:
+		settings, err := resolveType(s.Settings, s.Credentials != "")
+		if err != nil {
+			problem("%w", err)
+		} else if s.Settings, err = json.Marshal(settings); err != nil {
+			problem("%w", err)
+		}
+	default:
+		problem("unknown type %q", s.Type)
+	}
+
+	if t := l.timeouts(s.Timeouts); !t.IsZero() {
+		s.Timeouts = &Timeouts{Init: Duration(t.Init), Fetch: Duration(t.Fetch), Health: Duration(t.Health)}
+		for _, f := range []struct {
+			d   *Duration
+			def time.Duration
+		}{
+			{&s.Timeouts.Init, middleware.DefaultInitTimeout},
+			{&s.Timeouts.Fetch, middleware.DefaultFetchTimeout},
+			{&s.Timeouts.Health, middleware.DefaultHealthTimeout},
+		} {
+			if *f.d == 0 {
+				*f.d = Duration(f.def)
+			}
+		}
+	}
+	mw := make([]Middleware, len(s.Middleware))
+	for i, m := range s.Middleware {
+		if _, ok := l.Middleware[m.Type]; ok {
+			mw[i] = m
+			continue
+		}
+		var err error
+		if mw[i], err = m.resolve(); err != nil {
+			problem("middleware %d: %w", i, err)
+		}
+	}
+	s.Middleware = mw
+	return s, errors.Join(errs...)
+}
+
+// resolve fills in the decorator defaults for m's type.
+func (m Middleware) resolve() (Middleware, error) {
+	switch m.Type {
+	case "retry":
+		d := middleware.DefaultRetryPolicy()
+		if m.MaxAttempts <= 0 {
+			m.MaxAttempts = d.MaxAttempts
+		}
+		if m.InitialBackoff <= 0 {
+			m.InitialBackoff = Duration(d.InitialBackoff)
+		}
+		if m.MaxBackoff <= 0 {
+			m.MaxBackoff = Duration(d.MaxBackoff)
+		}
+	case "rate_limit":
+		if m.RequestsPerSecond <= 0 {
+			return m, fmt.Errorf("rate_limit: requests_per_second must be positive")
+		}
+		m.Burst = max(m.Burst, 1)
+	case "cache":
+		if m.TTL <= 0 {
+			m.TTL = Duration(cache.DefaultTTL)
+		}
+		if m.MaxEntries <= 0 {
+			m.MaxEntries = cache.DefaultMaxEntries
+		}
+	case "size_limit":
+		if m.MaxBytesPerCall <= 0 && m.MaxBytesPerQuery <= 0 {
+			return m, fmt.Errorf("size_limit: max_bytes_per_call or max_bytes_per_query must be positive")
+		}
+	case "length_band":
+		if m.MinChars <= 0 && m.MaxChars <= 0 {
+			return m, fmt.Errorf("length_band: min_chars or max_chars must be positive")
+		}
+		if m.MaxChars > 0 && m.MinChars > m.MaxChars {
+			return m, fmt.Errorf("length_band: min_chars %d exceeds max_chars %d", m.MinChars, m.MaxChars)
+		}
+	case "dedup":
+		if m.Threshold < 0 || m.Threshold > 1 {
+			return m, fmt.Errorf("dedup: threshold %g is not between 0 and 1", m.Threshold)
+		}
+		if m.Threshold == 0 {
+			m.Threshold = middleware.DefaultDedupThreshold
+		}
+	case "maintenance":
+		if len(m.Windows) == 0 {
+			return m, fmt.Errorf("maintenance: at least one window is required")
+		}
+		if _, err := m.maintenance(); err != nil {
+			return m, fmt.Errorf("maintenance: %w", err)
+		}
+	case "logging", "sanitize", "filter_languages", "redact":
+	default:
+		return m, fmt.Errorf("unknown middleware type %q", m.Type)
+	}
+	return m, nil
+}
+
+// maintenance returns the middleware.MaintenanceConfig m describes.
+func (m Middleware) maintenance() (middleware.MaintenanceConfig, error) {
+	var cfg middleware.MaintenanceConfig
+	if m.Timezone != "" {
+		loc, err := time.LoadLocation(m.Timezone)
+		if err != nil {
+			return cfg, err
+		}
+		cfg.Location = loc
+	}
+	for _, w := range m.Windows {
+		mw := middleware.MaintenanceWindow{Start: w.Start, Duration: time.Duration(w.Duration)}
+		if err := mw.Validate(); err != nil {
+			return cfg, err
+		}
+		cfg.Windows = append(cfg.Windows, mw)
+	}
+	return cfg, nil
+}
+
+func resolveRemote(raw json.RawMessage, _ bool) (any, error) {
+	var s RemoteSettings
+	if err := decodeSettings(raw, &s); err != nil {
+		return nil, err
+	}
+	if s.URL == "" {
+		return nil, fmt.Errorf("remote: missing url")
+	}
+	if s.Timeout <= 0 {
+		s.Timeout = Duration(remote.DefaultTimeout)
+	}
+	return s, nil
+}
+
+func resolvePlugin(raw json.RawMessage, hasCreds bool) (any, error) {
+	var s PluginSettings
+	if err := decodeSettings(raw, &s); err != nil {
+		return nil, err
+	}
+	if s.Command == "" {
+		return nil, fmt.Errorf("plugin: missing command")
+	}
+	if !hasCreds {
+		return nil, fmt.Errorf("plugin: credentials (the handshake secret) are required")
+	}
+	return pluginPlan{PluginSettings: s, StartTimeout: Duration(plugin.DefaultStartTimeout)}, nil
+}
+
+// pluginPlan adds the fixed start timeout to resolved plugin settings.
+type pluginPlan struct {
+	PluginSettings
+	StartTimeout Duration `json:"start_timeout"`
+}
@@ -0,0 +1,101 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/plugin"
+	"github.com/locus-search/datasource-sdk/remote"
+)
+
+var builtinTypes = map[string]Constructor{
+	"remote": newRemote,
+	"plugin": newPlugin,
+}
+
+// RemoteSettings configures the "remote" source type. Credentials, if set,
+// are sent as a bearer token.
+type RemoteSettings struct {
+	// URL is the service's base address
+	URL string `json:"url"`
+
+	// Timeout bounds each request
+	// Defaults to remote.DefaultTimeout
+	Timeout Duration `json:"timeout,omitempty"`
+}
+
+func newRemote(raw json.RawMessage, creds string) (datasource.DataSource, error) {
+	var s RemoteSettings
+	if err := decodeSettings(raw, &s); err != nil {
+		return nil, err
+	}
+	if s.URL == "" {
+		return nil, fmt.Errorf("remote: missing url")
+	}
+	c := remote.NewClient(s.URL)
+	if s.Timeout > 0 {
+		c.HTTPClient.Timeout = time.Duration(s.Timeout)
+	}
+	if creds != "" {
+		c.Header.Set("Authorization", "Bearer "+creds)
+	}
+	return c, nil
+}
+
+// PluginSettings configures the "plugin" source type. Credentials are the
+// handshake's shared secret and are required.
+type PluginSettings struct {
+	// Command is the plugin executable
+	Command string `json:"command"`
+
+	// Args are passed to the plugin
+	Args []string `json:"args,omitempty"`
+
+	// Checksum is the hex-encoded SHA-256 of Command, verified before launch
+	Checksum string `json:"checksum,omitempty"`
+
+	// CallTimeout, MemoryBytes, and CPUTime set plugin.Limits
+	CallTimeout Duration `json:"call_timeout,omitempty"`
+	MemoryBytes int64    `json:"memory_bytes,omitempty"`
+	CPUTime     Duration `json:"cpu_time,omitempty"`
+}
+
+func newPlugin(raw json.RawMessage, creds string) (datasource.DataSource, error) {
+	var s PluginSettings
+	if err := decodeSettings(raw, &s); err != nil {
+		return nil, err
+	}
+	if s.Command == "" {
+		return nil, fmt.Errorf("plugin: missing command")
+	}
+	if creds == "" {
+		return nil, fmt.Errorf("plugin: credentials (the handshake secret) are required")
+	}
+	return plugin.Launch(plugin.ClientConfig{
+		Cmd:       exec.Command(s.Command, s.Args...),
+		Handshake: plugin.Handshake{Secret: []byte(creds)},
+		Checksum:  s.Checksum,
+		Limits: plugin.Limits{
+			CallTimeout: time.Duration(s.CallTimeout),
+			MemoryBytes: s.MemoryBytes,
+			CPUTime:     time.Duration(s.CPUTime),
+		},
+	})
+}
+
+// decodeSettings decodes type settings, rejecting unknown fields.
+func decodeSettings(raw json.RawMessage, v any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("settings: %w", err)
+	}
+	return nil
+}
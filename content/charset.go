@@ -0,0 +1,149 @@
+package content
+
+import (
+	"bytes"
+	"mime"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// sniffBytes is how much of a body DecodeText searches for an HTML
+// charset declaration.
+const sniffBytes = 1024
+
+// DecodeText converts body to a UTF-8 string. The charset is taken from
+// contentType (a Content-Type header value, which may be empty), else
+// from a byte order mark, else from an HTML <meta> charset declaration in
+// the first 1024 bytes; without one, body is taken to be UTF-8.
+//
+// UTF-8, UTF-16, and the Windows-1252 family (windows-1252, iso-8859-1,
+// us-ascii, which web content labels interchangeably) are supported;
+// other labels are ignored. Bytes that are not valid UTF-8 in text taken
+// to be UTF-8 are decoded as Windows-1252, which recovers the Latin-1
+// text that legacy forums and wikis serve as UTF-8.
+func DecodeText(body []byte, contentType string) string {
+	charset := ""
+	if _, params, err := mime.ParseMediaType(contentType); err == nil {
+		charset = params["charset"]
+	}
+	switch {
+	case bytes.HasPrefix(body, []byte{0xEF, 0xBB, 0xBF}):
+		return decodeUTF8(body[3:])
+	case bytes.HasPrefix(body, []byte{0xFF, 0xFE}):
+		return decodeUTF16(body[2:], false)
+	case bytes.HasPrefix(body, []byte{0xFE, 0xFF}):
+		return decodeUTF16(body[2:], true)
+	}
+	if charset == "" {
+		charset = sniffCharset(body[:min(len(body), sniffBytes)])
+	}
+	switch strings.ToLower(strings.TrimSpace(charset)) {
+	case "utf-16be":
+		return decodeUTF16(body, true)
+	case "utf-16", "utf-16le":
+		return decodeUTF16(body, false)
+	case "windows-1252", "cp1252", "x-cp1252", "iso-8859-1", "iso8859-1", "latin1", "l1", "us-ascii", "ascii":
+		return decodeWindows1252(body)
+	default:
+		return decodeUTF8(body)
+	}
+}
+
+// sniffCharset returns the charset declared by an HTML <meta charset> or
+// <meta http-equiv="Content-Type"> element in head.
+func sniffCharset(head []byte) string {
+	lower := bytes.ToLower(head)
+	for i := 0; ; {
+		j := bytes.Index(lower[i:], []byte("<meta"))
+		if j < 0 {
+			return ""
+		}
+		i += j + len("<meta")
+		end := bytes.IndexByte(lower[i:], '>')
+		if end < 0 {
+			return ""
+		}
+		tag := lower[i : i+end]
+		if k := bytes.Index(tag, []byte("charset=")); k >= 0 {
+			v := bytes.TrimLeft(tag[k+len("charset="):], `"' `)
+			if n := bytes.IndexAny(v, `"' ;/`); n >= 0 {
+				v = v[:n]
+			}
+			return string(v)
+		}
+	}
+}
+
+// decodeUTF8 decodes b as UTF-8, decoding bytes that are not part of a
+// valid sequence as Windows-1252.
+func decodeUTF8(b []byte) string {
+	if utf8.Valid(b) {
+		return string(b)
+	}
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for len(b) > 0 {
+		r, size := utf8.DecodeRune(b)
+		if r == utf8.RuneError && size <= 1 {
+			r, size = windows1252(b[0]), 1
+		}
+		sb.WriteRune(r)
+		b = b[size:]
+	}
+	return sb.String()
+}
+
+func decodeWindows1252(b []byte) string {
+	var sb strings.Builder
+	sb.Grow(len(b))
+	for _, c := range b {
+		sb.WriteRune(windows1252(c))
+	}
+	return sb.String()
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		} else {
+			units[i] = uint16(b[2*i+1])<<8 | uint16(b[2*i])
+		}
+	}
+	s := string(utf16.Decode(units))
+	if len(b)%2 != 0 {
+		s += string(utf8.RuneError)
+	}
+	return s
+}
+
+// cp1252 holds the characters Windows-1252 assigns to bytes 0x80 through
+// 0x9F; the other bytes are the Unicode code points of the same value.
+// Unassigned bytes map to the C1 control characters, as in ISO-8859-1.
+var cp1252 = [32]rune{
+	'€', 0x81, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', 0x8D, 'Ž', 0x8F,
+	0x90, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', 0x9D, 'ž', 'Ÿ',
+}
+
+func windows1252(c byte) rune {
+	if c >= 0x80 && c < 0xA0 {
+		return cp1252[c-0x80]
+	}
+	return rune(c)
+}
+
+// windows1252Byte is the inverse of windows1252, also accepting the
+// ISO-8859-1 reading of bytes 0x80 through 0x9F, the C1 controls.
+func windows1252Byte(r rune) (byte, bool) {
+	if r < 0x100 {
+		return byte(r), true
+	}
+	for i, c := range cp1252 {
+		if c == r {
+			return byte(0x80 + i), true
+		}
+	}
+	return 0, false
+}
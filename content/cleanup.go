@@ -0,0 +1,214 @@
+package content
+
+import (
+	"html"
+	"strings"
+	"unicode"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Cleanup selects text cleanup passes for forum and chat content. Each
+// pass is also available as a function, for use with enrich.Text.
+type Cleanup struct {
+	// Entities decodes HTML entities left in text (DecodeEntities); it is
+	// skipped for HTML and code, where decoding would turn escaped markup
+	// into markup
+	Entities bool
+
+	// ZeroWidth removes invisible characters (StripZeroWidth)
+	ZeroWidth bool
+
+	// Emoji removes emoji (StripEmoji)
+	Emoji bool
+
+	// Whitespace collapses runs of whitespace (CollapseWhitespace); it is
+	// skipped for code, where whitespace is significant
+	Whitespace bool
+}
+
+// Clean applies the selected passes to text of content type t, in the
+// order the fields are declared.
+func (c Cleanup) Clean(text string, t datasource.ContentType) string {
+	if c.Entities && t != datasource.ContentHTML && t != datasource.ContentCode {
+		text = DecodeEntities(text)
+	}
+	if c.ZeroWidth {
+		text = StripZeroWidth(text)
+	}
+	if c.Emoji {
+		text = StripEmoji(text)
+	}
+	if c.Whitespace && t != datasource.ContentCode {
+		text = CollapseWhitespace(text)
+	}
+	return text
+}
+
+// maxEntityPasses bounds how many layers of escaping DecodeEntities
+// removes.
+const maxEntityPasses = 3
+
+// DecodeEntities decodes HTML character references such as "&amp;",
+// "&nbsp;", and "&#39;" in plain text or Markdown, including the doubly
+// escaped "&amp;quot;" that forums produce by escaping stored HTML again.
+// Do not apply it to HTML.
+func DecodeEntities(s string) string {
+	for i := 0; i < maxEntityPasses && strings.IndexByte(s, '&') >= 0; i++ {
+		decoded := html.UnescapeString(s)
+		if decoded == s {
+			break
+		}
+		s = decoded
+	}
+	return s
+}
+
+// StripZeroWidth removes invisible characters that break search and
+// comparisons: zero-width spaces, word joiners, byte order marks, soft
+// hyphens, and the Mongolian vowel separator. Zero-width joiners and
+// non-joiners are kept between letters and symbols, where they shape
+// scripts such as Persian and Devanagari and join emoji sequences.
+func StripZeroWidth(s string) string {
+	if !strings.ContainsFunc(s, zeroWidth) {
+		return s
+	}
+	runes := []rune(s)
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i, r := range runes {
+		switch r {
+		case '\u200b', '\u2060', '\ufeff', '\u00ad', '\u180e':
+			continue
+		case '\u200c', '\u200d':
+			if i == 0 || i == len(runes)-1 || !joinable(runes[i-1]) || !joinable(runes[i+1]) {
+				continue
+			}
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+func zeroWidth(r rune) bool {
+	switch r {
+	case '\u200b', '\u200c', '\u200d', '\u2060', '\ufeff', '\u00ad', '\u180e':
+		return true
+	}
+	return false
+}
+
+// joinable reports whether a joiner next to r may be meaningful.
+func joinable(r rune) bool {
+	return unicode.In(r, unicode.L, unicode.M, unicode.So) || r == '\ufe0f'
+}
+
+// StripEmoji removes emoji, including the skin tone modifiers, variation
+// selectors, joiners, and tags that combine them into sequences. Symbols
+// common in prose, such as ©, ™, and arrows, are kept.
+func StripEmoji(s string) string {
+	if !strings.ContainsFunc(s, emoji) {
+		return s
+	}
+	runes := []rune(s)
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i, r := range runes {
+		if emoji(r) {
+			continue
+		}
+		switch {
+		case r == '\u200d' && ((i > 0 && emoji(runes[i-1])) || (i < len(runes)-1 && emoji(runes[i+1]))):
+			continue // joins an emoji sequence
+		case r == '\ufe0f' || r == '\u20e3' || (r >= 0xE0020 && r <= 0xE007F):
+			continue // presentation selector, keycap, or tag
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// emojiRanges are the blocks and code points that are emoji by default.
+var emojiRanges = [][2]rune{
+	{0x1F000, 0x1FAFF}, // mahjong through symbols and pictographs extended-A
+	{0x2600, 0x27BF},   // miscellaneous symbols and dingbats
+	{0x231A, 0x231B},   // watch, hourglass
+	{0x23E9, 0x23F3},   // media controls, alarm clock
+	{0x23F8, 0x23FA},
+	{0x2B05, 0x2B07}, // heavy arrows
+	{0x2B1B, 0x2B1C}, // large squares
+	{0x2B50, 0x2B50}, // star
+	{0x2B55, 0x2B55}, // circle
+}
+
+func emoji(r rune) bool {
+	for _, rg := range emojiRanges {
+		if r >= rg[0] && r <= rg[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// CollapseWhitespace tidies the spacing of plain text or Markdown: runs of
+// spaces and tabs within a line, including non-breaking and other Unicode
+// spaces, become a single space; trailing spaces are removed; runs of
+// blank lines become one; and line endings become "\n". Indentation and
+// fenced code blocks are kept as they are, so Markdown structure survives.
+func CollapseWhitespace(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\r", "\n")
+	var b strings.Builder
+	b.Grow(len(s))
+	fenced, blank := false, true // blank: at start or after a blank line
+	for _, line := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			fenced = !fenced
+			b.WriteString(line)
+			b.WriteByte('\n')
+			blank = false
+			continue
+		}
+		if fenced {
+			b.WriteString(line)
+			b.WriteByte('\n')
+			continue
+		}
+		line = collapseLine(line)
+		if line == "" {
+			if !blank {
+				b.WriteByte('\n')
+			}
+			blank = true
+			continue
+		}
+		b.WriteString(line)
+		b.WriteByte('\n')
+		blank = false
+	}
+	return strings.Trim(b.String(), "\n")
+}
+
+// collapseLine collapses the runs of whitespace in line after its
+// indentation and trims its end.
+func collapseLine(line string) string {
+	indent := len(line) - len(strings.TrimLeft(line, " \t"))
+	var b strings.Builder
+	b.WriteString(line[:indent])
+	space := false
+	for _, r := range line[indent:] {
+		if unicode.IsSpace(r) {
+			space = true
+			continue
+		}
+		if space && b.Len() > indent {
+			b.WriteByte(' ')
+		}
+		space = false
+		b.WriteRune(r)
+	}
+	if b.Len() == indent {
+		return ""
+	}
+	return b.String()
+}
@@ -0,0 +1,59 @@
+package content_test
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+func TestCleanupForumPost(t *testing.T) {
+	// A post as scraped from a phpBB board: escaped twice, padded with
+	// non-breaking spaces, and decorated.
+	post := "Re: Can&amp;#39;t mount NFS share 😩\r\n\r\n\r\n" +
+		"Thanks&nbsp;&nbsp;for the help!!  🙏🏽 Try:\r\n" +
+		"    mount -t nfs -o vers=3 host:/export /mnt   \r\n" +
+		"Works&#8203; now ✅ &amp;quot;finally&amp;quot; \U0001f468\u200d\U0001f469\u200d\U0001f467\r\n"
+	c := content.Cleanup{Entities: true, ZeroWidth: true, Emoji: true, Whitespace: true}
+	want := "Re: Can't mount NFS share\n\n" +
+		"Thanks for the help!! Try:\n" +
+		"    mount -t nfs -o vers=3 host:/export /mnt\n" +
+		`Works now "finally"`
+	if got := c.Clean(post, datasource.ContentPlainText); got != want {
+		t.Errorf("Clean =\n%q\nwant\n%q", got, want)
+	}
+
+	html := "<p>a &lt;b&gt;  b</p>"
+	if got := c.Clean(html, datasource.ContentHTML); got != "<p>a &lt;b&gt; b</p>" {
+		t.Errorf("Clean(HTML) = %q, want entities kept", got)
+	}
+	code := "if a &&  b {\n\n\n}"
+	if got := c.Clean(code, datasource.ContentCode); got != code {
+		t.Errorf("Clean(code) = %q, want it unchanged", got)
+	}
+}
+
+func TestCleanupPasses(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(string) string
+		in   string
+		want string
+	}{
+		{"entities", content.DecodeEntities, "Tom &amp; Jerry &copy; &#x263A;", "Tom & Jerry © ☺"},
+		{"literal ampersand", content.DecodeEntities, "R&D", "R&D"},
+		{"zero width", content.StripZeroWidth, "pass\u200bword\ufeff co\u00adoperate", "password cooperate"},
+		{"joiners kept in words", content.StripZeroWidth, "\u200dمی\u200cخواهم\u200c", "می\u200cخواهم"},
+		{"emoji", content.StripEmoji, "ship it \U0001f680\U0001f680 \u2764\ufe0f 1\ufe0f\u20e3 \U0001f1e9\U0001f1ea!", "ship it   1 !"},
+		{"symbols kept", content.StripEmoji, "© 2024 → ™", "© 2024 → ™"},
+		{"whitespace", content.CollapseWhitespace, "\n\na  b \t c  \n \n\n\n- d\n  - e  ", "a b c\n\n- d\n  - e"},
+		{"fences kept", content.CollapseWhitespace, "x  y\n```\na    b\n\n\n```\n", "x y\n```\na    b\n\n\n```"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.fn(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
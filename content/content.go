@@ -0,0 +1,66 @@
+// Package content converts data item text between the formats declared by
+// datasource.ContentType. Sources that scrape or proxy HTML can hand the
+// host Markdown or plain text instead, and hosts can normalize text from
+// sources that did not declare a format:
+//
+//	item.DataText = content.HTMLToMarkdown(item.DataText)
+//	item.ContentType = datasource.ContentMarkdown
+//
+// The HTML parser is lenient and dependency-free; it handles the markup
+// found in answers, articles, and wiki pages rather than arbitrary
+// documents.
+package content
+
+import (
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Converter converts HTML to Markdown or plain text. The zero Converter is
+// the one HTMLToMarkdown and StripHTML use.
+type Converter struct {
+	// UnicodeMath renders math as Unicode approximations (see
+	// MathToUnicode) rather than keeping its TeX between $ delimiters
+	UnicodeMath bool
+}
+
+// knownTags are elements whose presence suggests text is HTML.
+var knownTags = map[string]bool{
+	"a": true, "b": true, "blockquote": true, "br": true, "code": true,
+	"div": true, "em": true, "h1": true, "h2": true, "h3": true, "h4": true,
+	"h5": true, "h6": true, "hr": true, "i": true, "img": true, "li": true,
+	"ol": true, "p": true, "pre": true, "span": true, "strong": true,
+	"table": true, "td": true, "tr": true, "ul": true,
+}
+
+// Detect guesses the format of text: HTML if it contains common HTML
+// elements, Markdown if it has Markdown block or link syntax, and plain
+// text otherwise. It never reports datasource.ContentCode, which sources
+// must declare.
+func Detect(text string) datasource.ContentType {
+	for _, t := range tokenize(text) {
+		if t.kind != textToken && knownTags[t.name] {
+			return datasource.ContentHTML
+		}
+	}
+	if looksLikeMarkdown(text) {
+		return datasource.ContentMarkdown
+	}
+	return datasource.ContentPlainText
+}
+
+func looksLikeMarkdown(text string) bool {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		for _, p := range []string{"# ", "## ", "### ", "- ", "* ", "> ", "```", "1. "} {
+			if strings.HasPrefix(line, p) {
+				return true
+			}
+		}
+	}
+	if i := strings.Index(text, "]("); i > 0 && strings.Contains(text[:i], "[") && strings.Contains(text[i:], ")") {
+		return true
+	}
+	return strings.Count(text, "**") >= 2 || strings.Count(text, "`") >= 2
+}
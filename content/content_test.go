@@ -0,0 +1,89 @@
+package content_test
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"heading and emphasis", "<h2>Reset a <em>password</em></h2><p>Then <strong>restart </strong>now.</p>",
+			"## Reset a _password_\n\nThen **restart** now."},
+		{"link and code", `<p>Run <code>passwd</code>, see <a href="https://example.com/a?x=1&amp;y=2">the docs</a>.</p>`,
+			"Run `passwd`, see [the docs](https://example.com/a?x=1&y=2)."},
+		{"link with spaces", `<a href="/a b">x</a>`, "[x](</a b>)"},
+		{"script link dropped", `<a href="javascript:alert(1)">x</a>`, "x"},
+		{"image", `<img src="d.png" alt="a [diagram]">`, `![a \[diagram\]](d.png)`},
+		{"nested lists", "<ul><li>One</li><li>Two<ol start=\"3\"><li>Three</li></ol></li></ul>",
+			"- One\n- Two\n  3. Three"},
+		{"paragraph in item", "<ul><li><p>One</p></li></ul>", "- One"},
+		{"blockquote", "<p>Intro</p><blockquote><p>One</p><p>Two</p></blockquote>",
+			"Intro\n\n> One\n>\n> Two"},
+		{"code block", "<pre><code class=\"language-go\">if a &lt; b {\n\n\treturn\n}\n</code></pre>",
+			"```go\nif a < b {\n\n\treturn\n}\n```"},
+		{"fence longer than content", "<pre>```</pre>", "````\n```\n````"},
+		{"inline backtick", "<code>a`b</code>", "``a`b``"},
+		{"table", "<table><tr><th>Name</th><th>Value</th></tr><tr><td>a|b</td><td>1</td></tr></table>",
+			"| Name | Value |\n| ---- | ----- |\n| a\\|b | 1     |"},
+		{"escaping", "<p>1. not a list, * and _ are literal</p><p># not a heading</p>",
+			"1\\. not a list, \\* and \\_ are literal\n\n\\# not a heading"},
+		{"line break", "a<br>b", "a  \nb"},
+		{"hidden", "<head><title>T</title></head><script>x()</script><style>p{}</style>text", "text"},
+		{"comments and doctype", "<!DOCTYPE html><!-- note -->text", "text"},
+		{"stray angle bracket", "<p>a < b</p>", `a \< b`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := content.HTMLToMarkdown(tt.in); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStripHTML(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"inline", "<p>Use <b>bold</b>  and\n<i>italic</i> &amp; more</p>", "Use bold and italic & more"},
+		{"paragraphs", "<h1>Title</h1><p>One</p><p>Two</p>", "Title\n\nOne\n\nTwo"},
+		{"lines", "<ul><li>a</li><li>b</li></ul>line<br>break", "a\nb\nline\nbreak"},
+		{"pre", "<pre>  keep\n    indent</pre>", "keep\n    indent"},
+		{"table", "<table><tr><td>a</td><td>b</td></tr><tr><td>c</td><td>d</td></tr></table>", "a\tb\nc\td"},
+		{"hidden", "<script>if (a < b) {}</script><style>p {}</style>text", "text"},
+		{"image alt", `see <img src="x.png" alt="the diagram">`, "see the diagram"},
+		{"unterminated tag", "text <b", "text"},
+		{"uppercase tags", "<P>One</P><P>Two</P>", "One\n\nTwo"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := content.StripHTML(tt.in); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		in   string
+		want datasource.ContentType
+	}{
+		{"<p>Hello <b>world</b></p>", datasource.ContentHTML},
+		{"Line one<br>Line two", datasource.ContentHTML},
+		{"# Title\n\nBody", datasource.ContentMarkdown},
+		{"See [the docs](https://example.com).", datasource.ContentMarkdown},
+		{"Run `make` first", datasource.ContentMarkdown},
+		{"if a < b and c > d", datasource.ContentPlainText},
+		{"Just a sentence.", datasource.ContentPlainText},
+	}
+	for _, tt := range tests {
+		if got := content.Detect(tt.in); got != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,507 @@
+package content
+
+import (
+	"html"
+	"strconv"
+	"strings"
+)
+
+// HTMLToMarkdown converts an HTML document to CommonMark. Headings,
+// paragraphs, emphasis, links, images, lists, block quotes, code, and
+// tables are converted; scripts and styles are dropped, and other elements
+// are reduced to their text. Characters that Markdown would interpret are
+// escaped in text. Tables become aligned pipe tables, as Table.Markdown
+// renders them, with inline Markdown kept in cells. Math, whether MathJax,
+// KaTeX, MathML, or TeX in the text, is kept as TeX in $...$ spans and
+// $$...$$ blocks, unescaped.
+func HTMLToMarkdown(s string) string {
+	return Converter{}.Markdown(s)
+}
+
+// Markdown converts an HTML document to CommonMark as HTMLToMarkdown does.
+func (cv Converter) Markdown(s string) string {
+	c := &mdConverter{opts: cv}
+	c.convert(mathTokens(tokenize(s)))
+	return c.w.String()
+}
+
+// cell renders the tokens of a table cell as a line of Markdown.
+func (c *mdConverter) cell(tokens []token) string {
+	cc := &mdConverter{opts: c.opts, inCell: true}
+	cc.convert(tokens)
+	return strings.Join(strings.Fields(cc.w.String()), " ")
+}
+
+type mdList struct {
+	ordered bool
+	n       int
+}
+
+type mdConverter struct {
+	w    mdWriter
+	opts Converter
+
+	hidden int
+	lists  []mdList
+	links  []string // href of each open <a>, "" if not rendered as a link
+
+	pre     *strings.Builder // collects <pre> text
+	preLang string
+	code    *strings.Builder // collects inline <code> text
+
+	inCell bool // converting a table cell, on a single line
+}
+
+func (c *mdConverter) convert(tokens []token) {
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind == startTagToken && t.name == "table" && !c.inCell && c.hidden == 0 && c.pre == nil && c.code == nil {
+			end := tableEnd(tokens, i)
+			c.table(parseTable(tokens[i+1:end], c.cell))
+			i = end
+			continue
+		}
+		c.token(t)
+	}
+}
+
+// table writes t, whose cells are already Markdown, as a block.
+func (c *mdConverter) table(t Table) {
+	c.block()
+	if t.Caption != "" {
+		c.w.literal(t.Caption)
+		c.block()
+	}
+	if md := t.markdown(); md != "" {
+		for i, line := range strings.Split(md, "\n") {
+			if i > 0 {
+				c.w.newline()
+			}
+			c.w.line(line)
+		}
+	}
+	c.block()
+}
+
+func (c *mdConverter) token(t token) {
+	switch t.kind {
+	case textToken:
+		c.text(html.UnescapeString(t.text))
+		return
+	case mathToken:
+		c.math(t)
+		return
+	}
+	start := t.kind == startTagToken
+	if hiddenTags[t.name] {
+		if start && !t.selfClosing {
+			c.hidden++
+		} else if !start && c.hidden > 0 {
+			c.hidden--
+		}
+		return
+	}
+	if c.hidden > 0 {
+		return
+	}
+	if c.pre != nil && t.name != "pre" {
+		if t.name == "code" && start && c.preLang == "" {
+			c.preLang = language(t.attrs["class"])
+		}
+		if t.name == "br" {
+			c.pre.WriteByte('\n')
+		}
+		return
+	}
+	if c.code != nil && t.name != "code" {
+		return
+	}
+	if start {
+		c.start(t)
+	} else {
+		c.end(t.name)
+	}
+}
+
+func (c *mdConverter) text(s string) {
+	switch {
+	case c.hidden > 0:
+	case c.pre != nil:
+		c.pre.WriteString(s)
+	case c.code != nil:
+		c.code.WriteString(s)
+	default:
+		c.w.text(s, true)
+	}
+}
+
+// math writes TeX as a $...$ span, or a $$...$$ block if it is displayed,
+// or as Unicode if the options ask for it.
+func (c *mdConverter) math(t token) {
+	tex, delim := t.text, "$"
+	if t.display {
+		delim = "$$"
+	}
+	if c.inCell {
+		tex = strings.ReplaceAll(tex, "|", `\|`) // or the table takes it as a column
+	}
+	switch {
+	case c.hidden > 0:
+	case c.pre != nil:
+		c.pre.WriteString(delim + tex + delim)
+	case c.code != nil:
+		c.code.WriteString(delim + tex + delim)
+	case t.display:
+		c.block()
+		if c.opts.UnicodeMath {
+			c.w.text(MathToUnicode(tex), true)
+		} else {
+			c.w.literal(delim + tex + delim)
+		}
+		c.block()
+	case c.opts.UnicodeMath:
+		c.w.text(MathToUnicode(tex), true)
+	default:
+		c.w.literal(delim + tex + delim)
+	}
+}
+
+func (c *mdConverter) start(t token) {
+	switch t.name {
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		c.block()
+		c.w.literal(strings.Repeat("#", int(t.name[1]-'0')) + " ")
+	case "p", "div", "section", "article", "header", "footer", "main", "nav", "aside", "figure", "dl":
+		c.block()
+	case "br":
+		if c.inCell {
+			c.w.text(" ", false)
+		} else {
+			c.w.literal("  ")
+			c.w.newline()
+		}
+	case "hr":
+		c.block()
+		c.w.literal("---")
+		c.block()
+	case "strong", "b":
+		c.w.literal("**")
+	case "em", "i":
+		c.w.literal("_")
+	case "del", "s", "strike":
+		c.w.literal("~~")
+	case "code":
+		c.code = &strings.Builder{}
+	case "pre":
+		c.block()
+		c.pre = &strings.Builder{}
+		c.preLang = language(t.attrs["class"])
+	case "a":
+		href := html.UnescapeString(t.attrs["href"])
+		if href == "" || strings.HasPrefix(strings.ToLower(strings.TrimSpace(href)), "javascript:") {
+			href = ""
+		} else {
+			c.w.literal("[")
+		}
+		c.links = append(c.links, href)
+	case "img":
+		src := html.UnescapeString(t.attrs["src"])
+		if src != "" {
+			c.w.literal("![" + escape(html.UnescapeString(t.attrs["alt"])) + "](" + destination(src) + ")")
+		}
+	case "ul", "ol":
+		if len(c.lists) > 0 {
+			c.w.newline()
+		} else {
+			c.block()
+		}
+		l := mdList{ordered: t.name == "ol", n: 1}
+		if n, err := strconv.Atoi(t.attrs["start"]); err == nil && l.ordered {
+			l.n = n
+		}
+		c.lists = append(c.lists, l)
+	case "li":
+		c.w.newline()
+		marker := "- "
+		if n := len(c.lists); n > 0 && c.lists[n-1].ordered {
+			marker = strconv.Itoa(c.lists[n-1].n) + ". "
+			c.lists[n-1].n++
+		}
+		c.w.literal(marker)
+		c.w.push(strings.Repeat(" ", len(marker)))
+		c.w.fresh = true
+	case "blockquote":
+		c.block()
+		c.w.breakLines()
+		c.w.push("> ")
+	case "dt":
+		c.w.newline()
+	case "dd":
+		c.w.newline()
+		c.w.literal(": ")
+	case "table", "caption", "tr", "td", "th":
+		c.block()
+	}
+}
+
+func (c *mdConverter) end(name string) {
+	switch name {
+	case "h1", "h2", "h3", "h4", "h5", "h6", "p", "div", "section", "article", "header", "footer", "main", "nav", "aside", "figure", "dl":
+		c.block()
+	case "strong", "b":
+		c.w.closing("**")
+	case "em", "i":
+		c.w.closing("_")
+	case "del", "s", "strike":
+		c.w.closing("~~")
+	case "code":
+		if c.code != nil {
+			code := c.code.String()
+			c.code = nil
+			c.w.literal(inlineCode(code))
+		}
+	case "pre":
+		if c.pre != nil {
+			code := strings.TrimRight(strings.TrimPrefix(c.pre.String(), "\n"), "\n ")
+			c.pre = nil
+			fence := "```"
+			for strings.Contains(code, fence) {
+				fence += "`"
+			}
+			c.w.literal(fence + c.preLang)
+			for _, line := range strings.Split(code, "\n") {
+				c.w.newline()
+				c.w.line(line)
+			}
+			c.w.newline()
+			c.w.literal(fence)
+			c.block()
+		}
+	case "a":
+		if n := len(c.links); n > 0 {
+			if href := c.links[n-1]; href != "" {
+				c.w.closing("](" + destination(href) + ")")
+			}
+			c.links = c.links[:n-1]
+		}
+	case "ul", "ol":
+		if n := len(c.lists); n > 0 {
+			c.lists = c.lists[:n-1]
+		}
+		if len(c.lists) == 0 {
+			c.block()
+		}
+	case "li":
+		c.w.pop()
+	case "blockquote":
+		c.w.pop()
+		c.block()
+	case "table", "caption", "tr", "td", "th":
+		c.block()
+	}
+}
+
+// block separates block elements with a blank line; within a table cell
+// it separates them with a space.
+func (c *mdConverter) block() {
+	if c.inCell {
+		c.w.text(" ", false)
+		return
+	}
+	c.w.blank()
+}
+
+// language returns the language of a code block from a class such as
+// "language-go" or "lang-go".
+func language(class string) string {
+	for _, f := range strings.Fields(class) {
+		for _, p := range []string{"language-", "lang-"} {
+			if strings.HasPrefix(f, p) {
+				return f[len(p):]
+			}
+		}
+	}
+	return ""
+}
+
+// inlineCode renders code as a code span, with a fence longer than any
+// run of backticks within it.
+func inlineCode(code string) string {
+	code = strings.Join(strings.Fields(code), " ")
+	if code == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		code = " " + code + " "
+	}
+	return fence + code + fence
+}
+
+// destination renders a link destination, enclosing it in angle brackets
+// if it contains spaces or parentheses.
+func destination(url string) string {
+	if strings.ContainsAny(url, " ()<>") {
+		return "<" + strings.NewReplacer("<", "%3C", ">", "%3E").Replace(url) + ">"
+	}
+	return url
+}
+
+// mdEscaper escapes characters with inline meaning in Markdown.
+var mdEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`, ">", `\>`, "|", `\|`,
+)
+
+func escape(s string) string {
+	return mdEscaper.Replace(s)
+}
+
+// mdWriter writes Markdown, prefixing every line with the markers of the
+// enclosing block quotes and list items.
+type mdWriter struct {
+	b        strings.Builder
+	prefixes []string
+
+	breaks    int  // pending line breaks (1, or 2 for a blank line)
+	written   int  // line breaks written since the last content
+	space     bool // pending space between words
+	lineStart bool // at the start of a line, before the prefix
+	fresh     bool // nothing written since a list marker
+}
+
+func (w *mdWriter) push(prefix string) { w.prefixes = append(w.prefixes, prefix) }
+
+func (w *mdWriter) pop() {
+	if n := len(w.prefixes); n > 0 {
+		w.prefixes = w.prefixes[:n-1]
+	}
+}
+
+func (w *mdWriter) newline() {
+	w.breaks = max(w.breaks, 1)
+	w.space = false
+}
+
+func (w *mdWriter) blank() {
+	if w.fresh {
+		return
+	}
+	w.breaks = 2
+	w.space = false
+}
+
+// breakLines writes pending line breaks, with blank lines carrying the
+// current prefixes.
+func (w *mdWriter) breakLines() {
+	if w.b.Len() == 0 {
+		w.breaks = 0
+		w.lineStart = true
+		return
+	}
+	for ; w.written < w.breaks; w.written++ {
+		if w.written > 0 {
+			w.b.WriteString(strings.TrimRight(strings.Join(w.prefixes, ""), " "))
+		}
+		w.b.WriteByte('\n')
+		w.lineStart = true
+	}
+	w.breaks = 0
+}
+
+// flush writes pending line breaks and the prefix of a new line.
+func (w *mdWriter) flush() {
+	w.breakLines()
+	if w.lineStart {
+		w.b.WriteString(strings.Join(w.prefixes, ""))
+		w.lineStart = false
+		w.space = false
+	}
+	w.written = 0
+	w.fresh = false
+}
+
+// literal writes Markdown syntax as is, after any pending space.
+func (w *mdWriter) literal(s string) {
+	if s == "" {
+		return
+	}
+	w.flush()
+	if w.space {
+		w.b.WriteByte(' ')
+		w.space = false
+	}
+	w.b.WriteString(s)
+}
+
+// closing writes Markdown syntax that closes a span, such as "**", before
+// any pending space.
+func (w *mdWriter) closing(s string) {
+	space := w.space
+	w.space = false
+	w.literal(s)
+	w.space = space
+}
+
+// line writes s as a line of its own, even if it is empty.
+func (w *mdWriter) line(s string) {
+	w.flush()
+	w.b.WriteString(s)
+}
+
+// text writes text with whitespace collapsed, escaped if escaped is set.
+func (w *mdWriter) text(s string, escaped bool) {
+	if s == "" {
+		return
+	}
+	if isSpace(s[0]) && w.b.Len() > 0 {
+		w.space = true
+	}
+	for i, word := range strings.Fields(s) {
+		atLine := w.b.Len() == 0 || w.breaks > w.written || w.lineStart
+		w.flush()
+		if (w.space || i > 0) && !atLine {
+			w.b.WriteByte(' ')
+		}
+		w.space = false
+		if escaped {
+			word = escape(word)
+			if atLine {
+				word = escapeLineStart(word)
+			}
+		}
+		w.b.WriteString(word)
+	}
+	if isSpace(s[len(s)-1]) {
+		w.space = true
+	}
+}
+
+// escapeLineStart escapes a word that would start a heading, list item,
+// or thematic break at the start of a line.
+func escapeLineStart(word string) string {
+	switch {
+	case word == "-" || word == "+" || word == "#" || strings.HasPrefix(word, "#"):
+		return `\` + word
+	case strings.HasPrefix(word, "---") || strings.HasPrefix(word, "==="):
+		return `\` + word
+	}
+	if i := strings.IndexAny(word, ".)"); i > 0 && i == len(word)-1 {
+		if _, err := strconv.Atoi(word[:i]); err == nil {
+			return word[:i] + `\` + word[i:]
+		}
+	}
+	return word
+}
+
+func (w *mdWriter) String() string {
+	lines := strings.Split(w.b.String(), "\n")
+	for i, l := range lines {
+		if !strings.HasSuffix(l, "  ") || strings.TrimSpace(l) == "" {
+			lines[i] = strings.TrimRight(l, " ")
+		}
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
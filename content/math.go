@@ -0,0 +1,451 @@
+package content
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// renderedMathClasses mark the typeset output of MathJax 2 and the
+// fallback images of MediaWiki, which duplicate the TeX kept elsewhere.
+var renderedMathClasses = map[string]bool{
+	"MathJax": true, "MathJax_Preview": true, "MathJax_Display": true,
+	"MathJax_SVG": true, "MathJax_SVG_Display": true, "MathJax_CHTML": true,
+	"MJX_Assistive_MathML": true, "mwe-math-fallback-image-inline": true,
+	"mwe-math-fallback-image-display": true,
+}
+
+// mathTokens replaces the math in tokens with math tokens holding its TeX:
+// MathJax <script type="math/tex"> elements, KaTeX and MathJax 3 output,
+// MathML <math> elements, and $...$, $$...$$, \(...\), and \[...\] spans
+// in text outside code. Typeset copies of the same math are dropped.
+func mathTokens(tokens []token) []token {
+	var out []token
+	code := 0 // depth of <pre> and <code>, whose text is literal
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		start := t.kind == startTagToken
+		switch {
+		case t.kind == textToken:
+			if code > 0 {
+				out = append(out, t)
+			} else {
+				out = append(out, splitMath(t.text)...)
+			}
+		case start && t.name == "script" && strings.HasPrefix(strings.ToLower(t.attrs["type"]), "math/tex"):
+			end := elementEnd(tokens, i)
+			var tex strings.Builder
+			for _, t := range tokens[i+1 : end] {
+				tex.WriteString(t.text)
+			}
+			out = append(out, texToken(tex.String(), strings.Contains(t.attrs["type"], "mode=display"))...)
+			i = end
+		case start && renderedMath(t):
+			i = elementEnd(tokens, i)
+		case start && (t.name == "math" || t.name == "mjx-container" || hasClass(t, "katex") || hasClass(t, "katex-display")):
+			end := elementEnd(tokens, i)
+			display := hasClass(t, "katex-display") || t.attrs["display"] == "block" || t.attrs["display"] == "true"
+			out = append(out, texToken(texOf(tokens[i:min(end+1, len(tokens))]), display)...)
+			i = end
+		default:
+			if t.name == "pre" || t.name == "code" {
+				if start && !t.selfClosing {
+					code++
+				} else if !start && code > 0 {
+					code--
+				}
+			}
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// texToken returns a math token for tex, or nothing if tex is blank.
+func texToken(tex string, display bool) []token {
+	tex = strings.TrimSpace(tex)
+	if inner, ok := strings.CutPrefix(tex, `{\displaystyle`); ok && strings.HasSuffix(inner, "}") {
+		tex = strings.TrimSpace(strings.TrimSuffix(inner, "}"))
+	}
+	if tex == "" {
+		return nil
+	}
+	return []token{{kind: mathToken, text: strings.Join(strings.Fields(tex), " "), display: display}}
+}
+
+func hasClass(t token, class string) bool {
+	for _, c := range strings.Fields(t.attrs["class"]) {
+		if c == class {
+			return true
+		}
+	}
+	return false
+}
+
+func renderedMath(t token) bool {
+	for _, c := range strings.Fields(t.attrs["class"]) {
+		if renderedMathClasses[c] {
+			return true
+		}
+	}
+	return false
+}
+
+// elementEnd returns the index of the end tag closing the element that
+// tokens[start] opens, or len(tokens) if it is unterminated. Void and
+// self-closing elements end where they start.
+func elementEnd(tokens []token, start int) int {
+	name := tokens[start].name
+	if tokens[start].selfClosing || voidTags[name] {
+		return start
+	}
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind == textToken || t.name != name {
+			continue
+		}
+		if t.kind == startTagToken && !t.selfClosing {
+			depth++
+		} else if t.kind == endTagToken {
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens)
+}
+
+// texOf returns the TeX source of rendered math: the alttext of its
+// <math> element, else its TeX annotation, else the text of the <math>
+// element itself.
+func texOf(tokens []token) string {
+	var text, annotation strings.Builder
+	inMath, inAnnotation := false, false
+	for _, t := range tokens {
+		switch {
+		case t.kind == textToken && inAnnotation:
+			annotation.WriteString(t.text)
+		case t.kind == textToken && inMath:
+			text.WriteString(t.text)
+		case t.name == "math" && t.kind == startTagToken:
+			if alt := t.attrs["alttext"]; alt != "" {
+				return html.UnescapeString(alt)
+			}
+			inMath = true
+		case t.name == "math":
+			inMath = false
+		case t.name == "annotation":
+			inAnnotation = t.kind == startTagToken && strings.Contains(t.attrs["encoding"], "tex")
+		}
+	}
+	if annotation.Len() > 0 {
+		return html.UnescapeString(annotation.String())
+	}
+	return html.UnescapeString(text.String())
+}
+
+// splitMath splits raw text into text and math tokens at TeX delimiters.
+// A single $ opens math only before a non-space and closes it only after a
+// non-space and before a non-digit, so amounts such as "$5 or $10" stay
+// text; \$ is never a delimiter.
+func splitMath(s string) []token {
+	var out []token
+	text := func(s string) {
+		if s != "" {
+			out = append(out, token{kind: textToken, text: s})
+		}
+	}
+	from := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && s[i+1] != '(' && s[i+1] != '[' {
+			i++ // an escape such as \$
+			continue
+		}
+		open, closer, display := "", "", false
+		switch {
+		case strings.HasPrefix(s[i:], "$$"):
+			open, closer, display = "$$", "$$", true
+		case strings.HasPrefix(s[i:], `\[`):
+			open, closer, display = `\[`, `\]`, true
+		case strings.HasPrefix(s[i:], `\(`):
+			open, closer = `\(`, `\)`
+		case s[i] == '$' && i+1 < len(s) && !isSpace(s[i+1]):
+			open, closer = "$", "$"
+		default:
+			continue
+		}
+		end := closeMath(s, i+len(open), closer)
+		if end < 0 {
+			i += len(open) - 1
+			continue
+		}
+		text(s[from:i])
+		out = append(out, texToken(html.UnescapeString(s[i+len(open):end]), display)...)
+		i = end + len(closer) - 1
+		from = i + 1
+	}
+	text(s[from:])
+	return out
+}
+
+// closeMath returns the index of the delimiter closing math that starts at
+// s[from:], or -1.
+func closeMath(s string, from int, closer string) int {
+	for i := from; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && !strings.HasPrefix(s[i:], closer):
+			i++
+		case !strings.HasPrefix(s[i:], closer):
+		case closer != "$":
+			return i
+		case i > from && !isSpace(s[i-1]) && (i+1 == len(s) || s[i+1] < '0' || s[i+1] > '9'):
+			return i
+		default:
+			return -1 // a $ that cannot close math ends it
+		}
+	}
+	return -1
+}
+
+// MathToUnicode approximates TeX math in Unicode for readers that do not
+// render it: Greek letters and common symbols become their characters,
+// simple superscripts and subscripts become ² and ₁, fractions become a/b,
+// and roots √x. Commands it does not know are kept as written.
+func MathToUnicode(tex string) string {
+	p := &texParser{s: tex}
+	return strings.Join(strings.Fields(p.seq(false)), " ")
+}
+
+type texParser struct {
+	s string
+	i int
+}
+
+// seq renders TeX up to the end of input or, within a group, the closing
+// brace, which it consumes.
+func (p *texParser) seq(group bool) string {
+	var b strings.Builder
+	for p.i < len(p.s) {
+		c := p.s[p.i]
+		switch c {
+		case '}':
+			p.i++
+			if group {
+				return b.String()
+			}
+		case '{':
+			p.i++
+			b.WriteString(p.seq(true))
+		case '^', '_':
+			p.i++
+			b.WriteString(script(p.arg(), c == '^'))
+		case '\\':
+			b.WriteString(p.command())
+		case '&', '~':
+			p.i++
+			b.WriteByte(' ')
+		default:
+			r, size := utf8.DecodeRuneInString(p.s[p.i:])
+			p.i += size
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// arg renders the argument of a command or script: a group, a command, or
+// a single character.
+func (p *texParser) arg() string {
+	for p.i < len(p.s) && isSpace(p.s[p.i]) {
+		p.i++
+	}
+	if p.i == len(p.s) {
+		return ""
+	}
+	switch p.s[p.i] {
+	case '{':
+		p.i++
+		return p.seq(true)
+	case '\\':
+		return p.command()
+	}
+	r, size := utf8.DecodeRuneInString(p.s[p.i:])
+	p.i += size
+	return string(r)
+}
+
+// command renders the command starting at the backslash at p.i.
+func (p *texParser) command() string {
+	p.i++
+	start := p.i
+	for p.i < len(p.s) && isASCIILetter(p.s[p.i]) {
+		p.i++
+	}
+	if p.i == start && p.i < len(p.s) {
+		p.i++ // a control symbol such as \, or \{
+	}
+	name := p.s[start:p.i]
+	if sym, ok := texSymbols[name]; ok {
+		return sym
+	}
+	switch name {
+	case "frac", "dfrac", "tfrac":
+		num, den := p.arg(), p.arg()
+		return parenthesize(num) + "/" + parenthesize(den)
+	case "sqrt":
+		index := ""
+		if p.i < len(p.s) && p.s[p.i] == '[' {
+			if end := strings.IndexByte(p.s[p.i:], ']'); end > 0 {
+				index = (&texParser{s: p.s[p.i+1 : p.i+end]}).seq(false)
+				p.i += end + 1
+			}
+		}
+		root := "√"
+		switch index {
+		case "":
+		case "3":
+			root = "∛"
+		case "4":
+			root = "∜"
+		default:
+			root = script(index, true) + "√"
+		}
+		return root + parenthesize(p.arg())
+	case "mathbb":
+		var b strings.Builder
+		for _, r := range p.arg() {
+			if d, ok := doubleStruck[r]; ok {
+				r = d
+			}
+			b.WriteRune(r)
+		}
+		return b.String()
+	case "text", "textrm", "textbf", "textit", "mathrm", "mathbf", "mathit", "mathsf", "mathtt", "mathcal", "operatorname", "mbox", "boldsymbol":
+		return p.arg()
+	case "begin", "end":
+		p.arg()
+		return " "
+	case "left", "right", "big", "Big", "bigg", "Bigg", "bigl", "bigr", "Bigl", "Bigr":
+		if p.i < len(p.s) && p.s[p.i] == '.' {
+			p.i++
+		}
+		return ""
+	case "displaystyle", "textstyle", "limits", "nolimits", "!":
+		return ""
+	case ",", ";", ":", " ", "quad", "qquad":
+		return " "
+	case "\\":
+		return "; "
+	case "{", "}", "$", "%", "&", "#", "_", "|":
+		return name
+	}
+	for _, fn := range texFunctions {
+		if name == fn {
+			return name
+		}
+	}
+	if p.i < len(p.s) && p.s[p.i] == '{' {
+		p.i++
+		return `\` + name + "{" + p.seq(true) + "}"
+	}
+	return `\` + name
+}
+
+// script renders a superscript or subscript, in Unicode if every
+// character has a form, and else after ^ or _.
+func script(s string, sup bool) string {
+	if s != "" && strings.Trim(s, "′") == "" {
+		return s
+	}
+	forms, mark := subscripts, "_"
+	if sup {
+		forms, mark = superscripts, "^"
+	}
+	var b strings.Builder
+	for _, r := range s {
+		f, ok := forms[r]
+		if !ok {
+			return mark + parenthesize(s)
+		}
+		b.WriteRune(f)
+	}
+	return b.String()
+}
+
+// parenthesize wraps s in parentheses unless it is a single term.
+func parenthesize(s string) string {
+	s = strings.TrimSpace(s)
+	if utf8.RuneCountInString(s) <= 1 || strings.IndexFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.'
+	}) < 0 {
+		return s
+	}
+	return "(" + s + ")"
+}
+
+// texFunctions are operator names rendered as their names.
+var texFunctions = []string{
+	"arccos", "arcsin", "arctan", "arg", "cos", "cosh", "cot", "csc", "deg",
+	"det", "dim", "exp", "gcd", "inf", "ker", "lg", "lim", "liminf",
+	"limsup", "ln", "log", "max", "min", "mod", "Pr", "sec", "sin", "sinh",
+	"sup", "tan", "tanh",
+}
+
+var texSymbols = map[string]string{
+	"alpha": "α", "beta": "β", "gamma": "γ", "delta": "δ", "epsilon": "ϵ",
+	"varepsilon": "ε", "zeta": "ζ", "eta": "η", "theta": "θ", "vartheta": "ϑ",
+	"iota": "ι", "kappa": "κ", "lambda": "λ", "mu": "μ", "nu": "ν", "xi": "ξ",
+	"pi": "π", "varpi": "ϖ", "rho": "ρ", "varrho": "ϱ", "sigma": "σ",
+	"varsigma": "ς", "tau": "τ", "upsilon": "υ", "phi": "ϕ", "varphi": "φ",
+	"chi": "χ", "psi": "ψ", "omega": "ω",
+	"Gamma": "Γ", "Delta": "Δ", "Theta": "Θ", "Lambda": "Λ", "Xi": "Ξ",
+	"Pi": "Π", "Sigma": "Σ", "Upsilon": "Υ", "Phi": "Φ", "Psi": "Ψ",
+	"Omega": "Ω",
+
+	"times": "×", "cdot": "·", "div": "÷", "pm": "±", "mp": "∓", "ast": "∗",
+	"circ": "∘", "bullet": "•", "oplus": "⊕", "otimes": "⊗",
+	"leq": "≤", "le": "≤", "geq": "≥", "ge": "≥", "neq": "≠", "ne": "≠",
+	"ll": "≪", "gg": "≫", "approx": "≈", "equiv": "≡", "sim": "∼",
+	"simeq": "≃", "cong": "≅", "propto": "∝", "mid": "∣", "parallel": "∥",
+	"perp": "⊥",
+	"in":   "∈", "notin": "∉", "ni": "∋", "subset": "⊂", "subseteq": "⊆",
+	"supset": "⊃", "supseteq": "⊇", "cup": "∪", "cap": "∩",
+	"setminus": "∖", "emptyset": "∅", "varnothing": "∅",
+	"forall": "∀", "exists": "∃", "nexists": "∄", "neg": "¬", "lnot": "¬",
+	"land": "∧", "wedge": "∧", "lor": "∨", "vee": "∨",
+	"to": "→", "rightarrow": "→", "leftarrow": "←", "gets": "←",
+	"leftrightarrow": "↔", "Rightarrow": "⇒", "implies": "⇒",
+	"Leftarrow": "⇐", "Leftrightarrow": "⇔", "iff": "⇔", "mapsto": "↦",
+	"uparrow": "↑", "downarrow": "↓",
+	"sum": "∑", "prod": "∏", "coprod": "∐", "int": "∫", "iint": "∬",
+	"oint": "∮", "partial": "∂", "nabla": "∇", "infty": "∞",
+	"ldots": "…", "dots": "…", "cdots": "⋯", "vdots": "⋮", "ddots": "⋱",
+	"prime": "′", "degree": "°", "angle": "∠", "triangle": "△",
+	"hbar": "ℏ", "ell": "ℓ", "Re": "ℜ", "Im": "ℑ", "aleph": "ℵ",
+	"langle": "⟨", "rangle": "⟩", "lfloor": "⌊", "rfloor": "⌋",
+	"lceil": "⌈", "rceil": "⌉", "lvert": "|", "rvert": "|", "vert": "|",
+	"Vert": "‖", "lbrace": "{", "rbrace": "}",
+}
+
+var superscripts = map[rune]rune{
+	'0': '⁰', '1': '¹', '2': '²', '3': '³', '4': '⁴', '5': '⁵', '6': '⁶',
+	'7': '⁷', '8': '⁸', '9': '⁹', '+': '⁺', '-': '⁻', '=': '⁼', '(': '⁽',
+	')': '⁾', 'a': 'ᵃ', 'b': 'ᵇ', 'c': 'ᶜ', 'd': 'ᵈ', 'e': 'ᵉ', 'f': 'ᶠ',
+	'g': 'ᵍ', 'h': 'ʰ', 'i': 'ⁱ', 'j': 'ʲ', 'k': 'ᵏ', 'l': 'ˡ', 'm': 'ᵐ',
+	'n': 'ⁿ', 'o': 'ᵒ', 'p': 'ᵖ', 'r': 'ʳ', 's': 'ˢ', 't': 'ᵗ', 'u': 'ᵘ',
+	'v': 'ᵛ', 'w': 'ʷ', 'x': 'ˣ', 'y': 'ʸ', 'z': 'ᶻ', 'T': 'ᵀ',
+}
+
+var subscripts = map[rune]rune{
+	'0': '₀', '1': '₁', '2': '₂', '3': '₃', '4': '₄', '5': '₅', '6': '₆',
+	'7': '₇', '8': '₈', '9': '₉', '+': '₊', '-': '₋', '=': '₌', '(': '₍',
+	')': '₎', 'a': 'ₐ', 'e': 'ₑ', 'h': 'ₕ', 'i': 'ᵢ', 'j': 'ⱼ', 'k': 'ₖ',
+	'l': 'ₗ', 'm': 'ₘ', 'n': 'ₙ', 'o': 'ₒ', 'p': 'ₚ', 'r': 'ᵣ', 's': 'ₛ',
+	't': 'ₜ', 'u': 'ᵤ', 'v': 'ᵥ', 'x': 'ₓ',
+}
+
+var doubleStruck = map[rune]rune{
+	'C': 'ℂ', 'H': 'ℍ', 'N': 'ℕ', 'P': 'ℙ', 'Q': 'ℚ', 'R': 'ℝ', 'Z': 'ℤ',
+}
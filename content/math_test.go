@@ -0,0 +1,73 @@
+package content_test
+
+import (
+	"testing"
+
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+func TestHTMLToMarkdownMath(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"dollar spans", `<p>Let $x_1 = \frac{a*b}{2}$ and</p><p>$$\sum_{i=1}^n i$$</p>`,
+			"Let $x_1 = \\frac{a*b}{2}$ and\n\n$$\\sum_{i=1}^n i$$"},
+		{"paren delimiters", `<p>where \(a &lt; b\) holds: \[ E = mc^2 \]</p>`,
+			"where $a < b$ holds:\n\n$$E = mc^2$$"},
+		{"amounts are not math", "<p>costs $5 or $10</p><p>not \\$x$ either</p>", "costs $5 or $10\n\nnot \\\\$x$ either"},
+		{"code is literal", "<p><code>echo $HOME$</code> and <pre>$a_b$</pre></p>", "`echo $HOME$` and\n\n```\n$a_b$\n```"},
+		{"mathjax script", `<p>Area <span class="MathJax_Preview">πr²</span><span class="MathJax" id="MathJax-1"><span>π</span></span>` +
+			`<script type="math/tex" id="MathJax-1">\pi r^2</script>.</p>` +
+			`<div class="MathJax_Display"><span>x</span></div><script type="math/tex; mode=display">x</script>`,
+			"Area $\\pi r^2$.\n\n$$x$$"},
+		{"katex", `<span class="katex-display"><span class="katex"><span class="katex-mathml"><math><semantics><mrow><mi>y</mi></mrow>` +
+			`<annotation encoding="application/x-tex">y = \sqrt{x}</annotation></semantics></math></span>` +
+			`<span class="katex-html" aria-hidden="true">y=√x</span></span></span>`,
+			"$$y = \\sqrt{x}$$"},
+		{"mathml", `<p>Wiki <span class="mwe-math-element"><math alttext="{\displaystyle a^{2}}"><mi>a</mi></math>` +
+			`<img class="mwe-math-fallback-image-inline" alt="{\displaystyle a^{2}}" src="a.svg"></span> and <math><mi>b</mi><mo>+</mo><mn>1</mn></math></p>`,
+			"Wiki $a^{2}$ and $b+1$"},
+		{"table cell", `<table><tr><th>Norm</th></tr><tr><td>$|x|$</td></tr></table>`,
+			"| Norm    |\n| ------- |\n| $\\|x\\|$ |"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := content.HTMLToMarkdown(tt.in); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConverterUnicodeMath(t *testing.T) {
+	in := `<p>If $x^2 \leq \frac{a+b}{2}$ then</p><script type="math/tex; mode=display">\alpha_1 \in \mathbb{R}</script>`
+	c := content.Converter{UnicodeMath: true}
+	if got, want := c.PlainText(in), "If x² ≤ (a+b)/2 then\n\nα₁ ∈ ℝ"; got != want {
+		t.Errorf("PlainText = %q, want %q", got, want)
+	}
+	if got, want := content.StripHTML(in), "If $x^2 \\leq \\frac{a+b}{2}$ then\n\n$$\\alpha_1 \\in \\mathbb{R}$$"; got != want {
+		t.Errorf("StripHTML = %q, want %q", got, want)
+	}
+	if got, want := c.Markdown(`<p>$a*b$</p>`), `a\*b`; got != want {
+		t.Errorf("Markdown = %q, want %q", got, want)
+	}
+}
+
+func TestMathToUnicode(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{`x^2 + y^{n+1}`, "x² + yⁿ⁺¹"},
+		{`a_{ij} \cdot b_k`, "aᵢⱼ · bₖ"},
+		{`\frac{1}{2} \times \frac{x+1}{y}`, "1/2 × (x+1)/y"},
+		{`\sqrt{2} + \sqrt[3]{x} + \sqrt{x+1}`, "√2 + ∛x + √(x+1)"},
+		{`\left( \sum_{i=0}^{\infty} \frac{1}{i!} \right)`, "( ∑ᵢ₌₀^∞ 1/(i!) )"},
+		{`\forall \epsilon > 0 \, \exists \delta`, "∀ ϵ > 0 ∃ δ"},
+		{`f'(x) = \lim_{h \to 0} g^{\prime}`, "f'(x) = lim_(h → 0) g′"},
+		{`\text{if } x \neq 0`, "if x ≠ 0"},
+		{`\unknown{x} y`, `\unknown{x} y`},
+	}
+	for _, tt := range tests {
+		if got := content.MathToUnicode(tt.in); got != tt.want {
+			t.Errorf("MathToUnicode(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package content
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// Normalize repairs the text corruption most common in upstream content:
+// it reverses mojibake (FixMojibake), removes control characters
+// (StripControl), and composes characters (NFC). Invalid UTF-8 becomes
+// U+FFFD. Decode bodies with DecodeText first.
+func Normalize(s string) string {
+	return NFC(StripControl(FixMojibake(s)))
+}
+
+// FixMojibake reverses the corruption of text that was encoded as UTF-8
+// and decoded as Windows-1252 or ISO-8859-1, as when a source mislabels
+// its charset: "cafÃ©" becomes "café" and "itâ€™s" becomes "it’s". Only
+// runs of characters that re-encode to a valid multibyte UTF-8 sequence
+// are replaced, so text that is correctly decoded is unchanged.
+func FixMojibake(s string) string {
+	if !strings.ContainsFunc(s, mojibakeLead) {
+		return s
+	}
+	runes := []rune(s)
+	var sb strings.Builder
+	sb.Grow(len(s))
+	for i := 0; i < len(runes); i++ {
+		if r, n := unmojibake(runes[i:]); n > 0 {
+			sb.WriteRune(r)
+			i += n - 1
+			continue
+		}
+		sb.WriteRune(runes[i])
+	}
+	return sb.String()
+}
+
+// mojibakeLead reports whether r is the Windows-1252 reading of a byte
+// that starts a multibyte UTF-8 sequence.
+func mojibakeLead(r rune) bool {
+	b, ok := windows1252Byte(r)
+	return ok && b >= 0xC2 && b <= 0xF4
+}
+
+// unmojibake decodes the UTF-8 sequence whose Windows-1252 reading starts
+// runes, returning the character and the number of runes it spans, or 0 if
+// runes does not start with such a sequence.
+func unmojibake(runes []rune) (rune, int) {
+	if !mojibakeLead(runes[0]) {
+		return 0, 0
+	}
+	var buf [utf8.UTFMax]byte
+	for n := 0; n < len(runes) && n < utf8.UTFMax; n++ {
+		b, ok := windows1252Byte(runes[n])
+		if !ok || (n > 0 && (b < 0x80 || b > 0xBF)) {
+			return 0, 0
+		}
+		buf[n] = b
+		if utf8.FullRune(buf[:n+1]) {
+			r, size := utf8.DecodeRune(buf[:n+1])
+			if r == utf8.RuneError || size != n+1 {
+				return 0, 0
+			}
+			return r, n + 1
+		}
+	}
+	return 0, 0
+}
+
+// StripControl removes control characters other than tab, newline, and
+// carriage return: the C0 and C1 controls and DEL, which upstream
+// content leaks from binary formats and broken encodings and which break
+// rendering and JSON consumers. Invalid UTF-8 becomes U+FFFD.
+func StripControl(s string) string {
+	return strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || (r >= 0x7F && r < 0xA0) {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// Hangul syllable composition constants, from the Unicode Standard,
+// section 3.12.
+const (
+	hangulS      = 0xAC00
+	hangulL      = 0x1100
+	hangulV      = 0x1161
+	hangulT      = 0x11A7
+	hangulLCount = 19
+	hangulVCount = 21
+	hangulTCount = 28
+	hangulSCount = hangulLCount * hangulVCount * hangulTCount
+)
+
+// NFC composes s as in Unicode Normalization Form C: each character
+// followed by a combining mark is replaced by the precomposed character
+// for the pair, if there is one, repeatedly, so "e" followed by U+0301
+// COMBINING ACUTE ACCENT becomes "é". This repairs the decomposed text
+// that macOS file systems and some input methods produce, which otherwise
+// fails to match composed text in search and comparisons.
+//
+// Unlike full normalization, NFC does not reorder combining marks, so a
+// mark is composed only with the character directly before it, and it
+// leaves singletons such as U+212B ANGSTROM SIGN as they are.
+func NFC(s string) string {
+	ascii := true
+	for i := 0; i < len(s) && ascii; i++ {
+		ascii = s[i] < utf8.RuneSelf
+	}
+	if ascii {
+		return s
+	}
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if n := len(out); n > 0 {
+			if c, ok := compose(out[n-1], r); ok {
+				out[n-1] = c
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// compose returns the primary composite of a and b.
+func compose(a, b rune) (rune, bool) {
+	switch {
+	case a >= hangulL && a < hangulL+hangulLCount && b >= hangulV && b < hangulV+hangulVCount:
+		return hangulS + ((a-hangulL)*hangulVCount+b-hangulV)*hangulTCount, true
+	case a >= hangulS && a < hangulS+hangulSCount && (a-hangulS)%hangulTCount == 0 &&
+		b > hangulT && b < hangulT+hangulTCount:
+		return a + b - hangulT, true
+	}
+	c, ok := compositions[[2]rune{a, b}]
+	return c, ok
+}
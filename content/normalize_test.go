@@ -0,0 +1,58 @@
+package content_test
+
+import (
+	"testing"
+
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+func TestDecodeText(t *testing.T) {
+	tests := []struct {
+		name        string
+		body        []byte
+		contentType string
+		want        string
+	}{
+		{"utf-8", []byte("caf\xc3\xa9"), "text/html; charset=utf-8", "café"},
+		{"declared latin-1", []byte("caf\xe9 \x93ok\x94"), "text/html; charset=ISO-8859-1", "café “ok”"},
+		{"undeclared latin-1", []byte("caf\xe9"), "", "café"},
+		{"mislabeled latin-1 mixed with utf-8", []byte("caf\xe9 na\xc3\xafve"), "text/plain; charset=utf-8", "café naïve"},
+		{"meta charset", []byte(`<html><head><meta charset="windows-1252"></head>caf` + "\xe9"), "", `<html><head><meta charset="windows-1252"></head>café`},
+		{"meta http-equiv", []byte(`<meta http-equiv="Content-Type" content="text/html; charset=latin1">` + "\xe9"), "text/html", `<meta http-equiv="Content-Type" content="text/html; charset=latin1">é`},
+		{"utf-8 bom", []byte("\xef\xbb\xbfhi"), "text/plain; charset=latin1", "hi"},
+		{"utf-16le bom", []byte{0xff, 0xfe, 'h', 0, 0xe9, 0}, "", "hé"},
+		{"utf-16be label", []byte{0, 'h', 0xd8, 0x3d, 0xde, 0x00}, "text/plain; charset=UTF-16BE", "h😀"},
+		{"unknown charset", []byte("caf\xc3\xa9"), "text/plain; charset=x-unknown", "café"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := content.DecodeText(tt.body, tt.contentType); got != tt.want {
+				t.Errorf("DecodeText = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"mojibake", "cafÃ© itâ€™s naÃ¯ve", "café it’s naïve"},
+		{"mojibake via C1 controls", "Ã\u0089tÃ©", "Été"},
+		{"correct text unchanged", "Â and é, “quoted”", "Â and é, “quoted”"},
+		{"control characters", "a\x00b\x1bc\u0085d\te\r\n", "abcd\te\r\n"},
+		{"invalid utf-8", "a\xffb", "a�b"},
+		{"decomposed", "cafe\u0301 nai\u0308ve", "café naïve"},
+		{"chained marks", "u\u0308\u0304", "\u01d6"},
+		{"no composite", "x\u0301", "x\u0301"},
+		{"hangul jamo", "\u1112\u1161\u11ab\u1100\u1173\u11af", "한글"},
+		{"ascii", "plain text", "plain text"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := content.Normalize(tt.in); got != tt.want {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
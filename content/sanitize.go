@@ -0,0 +1,279 @@
+package content
+
+import (
+	"html"
+	"net/url"
+	"strings"
+)
+
+// Default Policy values used when its fields are empty.
+var (
+	DefaultURLSchemes = []string{"http", "https", "mailto"}
+
+	DefaultTrackerHosts = []string{
+		"bat.bing.com", "doubleclick.net", "google-analytics.com",
+		"googletagmanager.com", "mc.yandex.ru", "pixel.wp.com",
+		"quantserve.com", "scorecardresearch.com", "stats.wp.com",
+	}
+)
+
+// DefaultElements returns the elements and attributes kept by the default
+// Policy: text formatting, headings, lists, tables, quotes, code, links,
+// and images. The map is a fresh copy that callers may extend.
+func DefaultElements() map[string][]string {
+	return map[string][]string{
+		"a": {"href", "title"}, "abbr": {"title"}, "b": nil,
+		"blockquote": {"cite"}, "br": nil, "caption": nil,
+		"code": {"class"}, "dd": nil, "del": nil, "details": nil,
+		"div": nil, "dl": nil, "dt": nil, "em": nil, "figcaption": nil,
+		"figure": nil, "h1": nil, "h2": nil, "h3": nil, "h4": nil,
+		"h5": nil, "h6": nil, "hr": nil, "i": nil,
+		"img": {"src", "alt", "title", "width", "height"}, "ins": nil,
+		"kbd": nil, "li": nil, "mark": nil, "ol": {"start"}, "p": nil,
+		"pre": {"class"}, "q": {"cite"}, "s": nil, "samp": nil,
+		"small": nil, "span": nil, "strong": nil, "sub": nil,
+		"summary": nil, "sup": nil, "table": nil, "tbody": nil,
+		"td": {"colspan", "rowspan"}, "tfoot": nil,
+		"th": {"colspan", "rowspan", "scope"}, "thead": nil, "tr": nil,
+		"u": nil, "ul": nil, "var": nil,
+	}
+}
+
+// droppedTags are elements removed together with their content, since
+// their content is code, metadata, or interactive rather than text.
+var droppedTags = map[string]bool{
+	"applet": true, "embed": true, "frame": true, "frameset": true,
+	"head": true, "iframe": true, "noscript": true, "object": true,
+	"script": true, "select": true, "style": true, "svg": true,
+	"template": true, "textarea": true, "title": true,
+}
+
+// voidTags are elements that have no content or end tag.
+var voidTags = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true,
+	"frame": true, "hr": true, "img": true, "input": true, "link": true,
+	"meta": true, "param": true, "source": true, "track": true,
+	"wbr": true,
+}
+
+// urlAttrs are attributes holding URLs, whose schemes are checked.
+var urlAttrs = map[string]bool{"cite": true, "href": true, "src": true}
+
+// trackingParams are query parameters that identify a click for analytics.
+var trackingParams = map[string]bool{
+	"fbclid": true, "gclid": true, "dclid": true, "mc_cid": true,
+	"mc_eid": true, "msclkid": true, "igshid": true, "_hsenc": true,
+}
+
+// Policy is an allowlist of the HTML that Sanitize keeps. The zero Policy
+// uses the defaults.
+type Policy struct {
+	// Elements maps each allowed element to its allowed attributes. Other
+	// elements are unwrapped, keeping their text, except scripts, styles,
+	// frames, and embedded objects, which are removed with their content
+	// Defaults to DefaultElements()
+	Elements map[string][]string
+
+	// URLSchemes are the schemes allowed in href, src, and cite
+	// attributes; relative URLs are always allowed
+	// Defaults to DefaultURLSchemes
+	URLSchemes []string
+
+	// TrackerHosts are hosts (including their subdomains) whose images
+	// are removed as tracking pixels. Images sized 1x1 or smaller are
+	// removed regardless of host
+	// Defaults to DefaultTrackerHosts
+	TrackerHosts []string
+
+	// KeepTrackingParams keeps utm_* and click-identifier query
+	// parameters in kept URLs instead of removing them
+	KeepTrackingParams bool
+}
+
+func (p Policy) withDefaults() Policy {
+	if p.Elements == nil {
+		p.Elements = DefaultElements()
+	}
+	if p.URLSchemes == nil {
+		p.URLSchemes = DefaultURLSchemes
+	}
+	if p.TrackerHosts == nil {
+		p.TrackerHosts = DefaultTrackerHosts
+	}
+	return p
+}
+
+// Sanitize returns s reduced to the HTML allowed by the default Policy.
+func Sanitize(s string) string {
+	return Policy{}.Sanitize(s)
+}
+
+// Sanitize returns s reduced to the HTML allowed by p: disallowed elements
+// and attributes are removed, event handler and style attributes always
+// are, URLs with disallowed schemes are dropped, tracking pixels are
+// removed, and the result is well-formed, with text re-escaped and every
+// element closed. Comments and doctypes are dropped.
+func (p Policy) Sanitize(s string) string {
+	p = p.withDefaults()
+	var b strings.Builder
+	var open []string
+	dropped := 0
+	for _, t := range tokenize(s) {
+		switch t.kind {
+		case textToken:
+			if dropped == 0 {
+				b.WriteString(html.EscapeString(html.UnescapeString(t.text)))
+			}
+		case startTagToken:
+			if droppedTags[t.name] {
+				if !t.selfClosing && !voidTags[t.name] {
+					dropped++
+				}
+				continue
+			}
+			if dropped > 0 {
+				continue
+			}
+			allowed, ok := p.Elements[t.name]
+			if !ok || (t.name == "img" && p.tracker(t.attrs)) {
+				continue
+			}
+			attrs := p.attrs(t.attrs, allowed)
+			if t.name == "img" && !hasAttr(attrs, "src") {
+				continue
+			}
+			b.WriteString("<" + t.name)
+			for _, a := range attrs {
+				b.WriteString(" " + a[0] + `="` + html.EscapeString(a[1]) + `"`)
+			}
+			b.WriteString(">")
+			if !voidTags[t.name] && !t.selfClosing {
+				open = append(open, t.name)
+			}
+		case endTagToken:
+			if droppedTags[t.name] {
+				if dropped > 0 {
+					dropped--
+				}
+				continue
+			}
+			if dropped > 0 {
+				continue
+			}
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] == t.name {
+					for j := len(open) - 1; j >= i; j-- {
+						b.WriteString("</" + open[j] + ">")
+					}
+					open = open[:i]
+					break
+				}
+			}
+		}
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+	return b.String()
+}
+
+// attrs returns the allowed attributes of an element in the order they are
+// listed in the policy, with URLs checked and cleaned.
+func (p Policy) attrs(attrs map[string]string, allowed []string) [][2]string {
+	var kept [][2]string
+	for _, name := range allowed {
+		name = strings.ToLower(name)
+		value, ok := attrs[name]
+		if !ok || strings.HasPrefix(name, "on") || name == "style" {
+			continue
+		}
+		value = html.UnescapeString(value)
+		if urlAttrs[name] {
+			var ok bool
+			if value, ok = p.url(value); !ok {
+				continue
+			}
+		}
+		kept = append(kept, [2]string{name, value})
+	}
+	return kept
+}
+
+// url checks that a URL has an allowed scheme and removes tracking
+// parameters from it.
+func (p Policy) url(raw string) (string, bool) {
+	// Browsers ignore control characters and whitespace in schemes, so
+	// "java\tscript:" must not slip past the check.
+	clean := strings.Map(func(r rune) rune {
+		if r <= ' ' || r == 0x7f {
+			return -1
+		}
+		return r
+	}, raw)
+	u, err := url.Parse(clean)
+	if err != nil {
+		return "", false
+	}
+	if u.Scheme != "" && !containsFold(p.URLSchemes, u.Scheme) {
+		return "", false
+	}
+	if p.KeepTrackingParams || u.RawQuery == "" {
+		return strings.TrimSpace(raw), true
+	}
+	q := u.Query()
+	changed := false
+	for k := range q {
+		if strings.HasPrefix(strings.ToLower(k), "utm_") || trackingParams[strings.ToLower(k)] {
+			q.Del(k)
+			changed = true
+		}
+	}
+	if !changed {
+		return strings.TrimSpace(raw), true
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), true
+}
+
+// tracker reports whether an image is a tracking pixel.
+func (p Policy) tracker(attrs map[string]string) bool {
+	if w, h := attrs["width"], attrs["height"]; tiny(w) && tiny(h) {
+		return true
+	}
+	u, err := url.Parse(strings.TrimSpace(html.UnescapeString(attrs["src"])))
+	if err != nil {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, t := range p.TrackerHosts {
+		t = strings.ToLower(t)
+		if host == t || strings.HasSuffix(host, "."+t) {
+			return true
+		}
+	}
+	return false
+}
+
+// tiny reports whether an image dimension is at most one pixel.
+func tiny(dim string) bool {
+	dim = strings.TrimSuffix(strings.TrimSpace(dim), "px")
+	return dim == "0" || dim == "1"
+}
+
+func hasAttr(attrs [][2]string, name string) bool {
+	for _, a := range attrs {
+		if a[0] == name {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package content_test
+
+import (
+	"testing"
+
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name, in, want string
+	}{
+		{"allowed markup kept", `<p>Use <b>bold</b> and <a href="https://example.com/a" title="A">links</a></p>`,
+			`<p>Use <b>bold</b> and <a href="https://example.com/a" title="A">links</a></p>`},
+		{"script removed", `<p>a<script>alert("x")</script>b</p>`, `<p>ab</p>`},
+		{"iframe and object removed", `<iframe src="https://evil.example"><p>fallback</p></iframe><object data="x">y</object>z`, `z`},
+		{"embed is void", `<embed src="x.swf">text`, `text`},
+		{"unknown element unwrapped", `<font color="red">red</font> <custom-tag>x</custom-tag>`, `red x`},
+		{"event handlers and style dropped", `<p onclick="x()" style="color:red">t</p>`, `<p>t</p>`},
+		{"javascript url dropped", `<a href="javascript:alert(1)">x</a>`, `<a>x</a>`},
+		{"obfuscated scheme dropped", `<a href="java&#09;script:alert(1)">x</a><a href=" JAVASCRIPT:x">y</a>`, `<a>x</a><a>y</a>`},
+		{"data image dropped", `<img src="data:image/png;base64,AAAA" alt="x">`, ``},
+		{"relative url kept", `<a href="/docs?q=1">x</a>`, `<a href="/docs?q=1">x</a>`},
+		{"tracking params removed", `<a href="https://example.com/p?id=3&utm_source=feed&fbclid=abc">x</a>`,
+			`<a href="https://example.com/p?id=3">x</a>`},
+		{"tracking pixel removed", `a<img src="https://example.com/p.gif" width="1" height="1">b`, `ab`},
+		{"tracker host removed", `<img src="https://stats.g.doubleclick.net/x.gif" alt="">`, ``},
+		{"image kept", `<img src="https://example.com/d.png" alt="diagram" onerror="x()">`,
+			`<img src="https://example.com/d.png" alt="diagram">`},
+		{"text re-escaped", `a < b &amp; c &gt; d`, `a &lt; b &amp; c &gt; d`},
+		{"attribute quotes escaped", `<a title='say "hi"' href="/x">x</a>`, `<a href="/x" title="say &#34;hi&#34;">x</a>`},
+		{"unclosed elements closed", `<ul><li><b>one`, `<ul><li><b>one</b></li></ul>`},
+		{"stray end tags dropped", `</div>text</b>`, `text`},
+		{"misnested closed in order", `<b><i>x</b>y</i>`, `<b><i>x</i></b>y`},
+		{"comments dropped", `<!-- secret -->text`, `text`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := content.Sanitize(tt.in); got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicy(t *testing.T) {
+	p := content.Policy{
+		Elements:           map[string][]string{"a": {"href"}, "video": {"src"}},
+		URLSchemes:         []string{"https"},
+		KeepTrackingParams: true,
+	}
+	in := `<p><a href="http://example.com">x</a> <a href="https://example.com/?utm_source=y">y</a></p><video src="https://example.com/v.mp4"></video>`
+	want := `<a>x</a> <a href="https://example.com/?utm_source=y">y</a><video src="https://example.com/v.mp4"></video>`
+	if got := p.Sanitize(in); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,210 @@
+package content
+
+import (
+	"html"
+	"strings"
+)
+
+// blockTags start and end a line of plain text; paragraphTags are also
+// set off by blank lines.
+var (
+	blockTags = map[string]bool{
+		"address": true, "article": true, "aside": true, "blockquote": true,
+		"br": true, "dd": true, "div": true, "dl": true, "dt": true,
+		"figcaption": true, "figure": true, "footer": true, "h1": true,
+		"h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+		"header": true, "hr": true, "li": true, "main": true, "nav": true,
+		"ol": true, "p": true, "pre": true, "section": true, "table": true,
+		"tr": true, "ul": true,
+	}
+	paragraphTags = map[string]bool{
+		"blockquote": true, "h1": true, "h2": true, "h3": true, "h4": true,
+		"h5": true, "h6": true, "p": true, "pre": true, "table": true,
+	}
+)
+
+// hiddenTags are elements whose content is never shown as text.
+var hiddenTags = map[string]bool{
+	"head": true, "script": true, "style": true, "template": true, "title": true,
+}
+
+// StripHTML returns the text of an HTML document without markup: entities
+// are decoded, scripts and styles dropped, block elements put on lines of
+// their own, paragraphs separated by blank lines, and other whitespace
+// collapsed except within <pre>. Tables are linearized as described by
+// Table.KeyValue, and math is kept as TeX between $ delimiters.
+func StripHTML(s string) string {
+	return Converter{}.PlainText(s)
+}
+
+// PlainText returns the text of an HTML document as StripHTML does.
+func (c Converter) PlainText(s string) string {
+	return c.strip(mathTokens(tokenize(s)))
+}
+
+// plainCell renders the tokens of a table cell as a line of plain text.
+func (c Converter) plainCell(tokens []token) string {
+	return strings.Join(strings.Fields(c.strip(tokens)), " ")
+}
+
+func (c Converter) strip(tokens []token) string {
+	var w textWriter
+	hidden, pre := 0, 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind == startTagToken && t.name == "table" && hidden == 0 && pre == 0 {
+			end := tableEnd(tokens, i)
+			w.paragraph()
+			w.raw(parseTable(tokens[i+1:end], c.plainCell).KeyValue())
+			w.paragraph()
+			i = end
+			continue
+		}
+		switch t.kind {
+		case mathToken:
+			if hidden > 0 {
+				continue
+			}
+			delim := "$"
+			if t.display {
+				delim = "$$"
+			}
+			tex := delim + t.text + delim
+			if c.UnicodeMath {
+				tex = MathToUnicode(t.text)
+			}
+			switch {
+			case pre > 0:
+				w.raw(tex)
+			case t.display:
+				w.paragraph()
+				w.text(tex)
+				w.paragraph()
+			default:
+				w.text(tex)
+			}
+		case textToken:
+			if hidden > 0 {
+				continue
+			}
+			text := html.UnescapeString(t.text)
+			if pre > 0 {
+				w.raw(text)
+			} else {
+				w.text(text)
+			}
+		case startTagToken, endTagToken:
+			start := t.kind == startTagToken
+			switch {
+			case hiddenTags[t.name]:
+				if start && !t.selfClosing {
+					hidden++
+				} else if !start && hidden > 0 {
+					hidden--
+				}
+			case t.name == "pre":
+				if start {
+					pre++
+				} else if pre > 0 {
+					pre--
+				}
+			}
+			switch {
+			case paragraphTags[t.name]:
+				w.paragraph()
+			case blockTags[t.name]:
+				w.line()
+			case t.name == "img" && start:
+				if alt := t.attrs["alt"]; alt != "" && hidden == 0 {
+					w.text(html.UnescapeString(alt))
+				}
+			}
+		}
+	}
+	return w.String()
+}
+
+// textWriter accumulates plain text, collapsing whitespace and the blank
+// lines requested by adjacent blocks.
+type textWriter struct {
+	b strings.Builder
+
+	breaks int  // pending line breaks (1 or 2)
+	space  bool // pending space between words
+}
+
+func (w *textWriter) text(s string) {
+	if s == "" {
+		return
+	}
+	if isSpace(s[0]) {
+		w.space = true
+	}
+	for i, word := range strings.Fields(s) {
+		w.flush()
+		if (w.space || i > 0) && !w.atBreak() {
+			w.b.WriteByte(' ')
+		}
+		w.b.WriteString(word)
+		w.space = false
+	}
+	if isSpace(s[len(s)-1]) {
+		w.space = true
+	}
+}
+
+// atBreak reports whether nothing, a line break, or a tab was written
+// last, so no space is needed.
+func (w *textWriter) atBreak() bool {
+	s := w.b.String()
+	return s == "" || s[len(s)-1] == '\n' || s[len(s)-1] == '\t'
+}
+
+func (w *textWriter) raw(s string) {
+	if s == "" {
+		return
+	}
+	w.flush()
+	w.b.WriteString(s)
+	w.space = false
+}
+
+// flush writes pending line breaks before new content.
+func (w *textWriter) flush() {
+	if w.breaks > 0 && w.b.Len() > 0 {
+		w.b.WriteString(strings.Repeat("\n", w.breaks))
+		w.space = false
+	}
+	w.breaks = 0
+}
+
+func (w *textWriter) line() {
+	w.breaks = max(w.breaks, 1)
+	w.space = false
+}
+
+func (w *textWriter) paragraph() {
+	w.breaks = 2
+	w.space = false
+}
+
+func (w *textWriter) String() string {
+	var lines []string
+	blank := 0
+	for _, l := range strings.Split(w.b.String(), "\n") {
+		l = strings.TrimRight(l, " \t")
+		if l == "" {
+			if blank++; blank > 1 {
+				continue
+			}
+		} else {
+			blank = 0
+		}
+		lines = append(lines, l)
+	}
+	return strings.TrimSpace(strings.Join(lines, "\n"))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,419 @@
+package content
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Table is an HTML table with each cell reduced to a single line of text.
+// Cells spanning several columns or rows are repeated in each, so every
+// row has as many cells as the widest.
+type Table struct {
+	// Caption is the text of the table's <caption>, if any
+	Caption string
+
+	// Header holds the column headings, from a <thead> or from leading
+	// rows of <th> cells; several heading rows are joined per column. It
+	// is nil if the table has no heading row
+	Header []string
+
+	// Rows holds the body cells, row by row
+	Rows [][]string
+
+	// RowHeaders reports whether the first cell of every row is a <th>,
+	// labelling the row, as in key-value spec tables
+	RowHeaders bool
+}
+
+// maxSpan bounds colspan and rowspan, which are otherwise up to the page.
+const maxSpan = 100
+
+// maxPad bounds the width, in characters, that Markdown table columns are
+// padded to; longer cells are left unpadded.
+const maxPad = 40
+
+// Tables returns the tables of an HTML document in order, with cells as
+// plain text. Tables nested in a cell are flattened into the cell's text.
+func Tables(s string) []Table {
+	var tables []Table
+	tokens := mathTokens(tokenize(s))
+	hidden := 0
+	for i := 0; i < len(tokens); i++ {
+		t := tokens[i]
+		switch {
+		case t.kind == textToken:
+		case hiddenTags[t.name]:
+			if t.kind == startTagToken && !t.selfClosing {
+				hidden++
+			} else if t.kind == endTagToken && hidden > 0 {
+				hidden--
+			}
+		case t.name == "table" && t.kind == startTagToken && hidden == 0:
+			end := tableEnd(tokens, i)
+			tables = append(tables, parseTable(tokens[i+1:end], Converter{}.plainCell))
+			i = end
+		}
+	}
+	return tables
+}
+
+// Markdown renders t as a pipe table with columns padded to line up,
+// preceded by its caption. A table without a header uses its first row as
+// one, as Markdown requires.
+func (t Table) Markdown() string {
+	esc := func(cells []string) []string {
+		if cells == nil {
+			return nil
+		}
+		out := make([]string, len(cells))
+		for i, c := range cells {
+			out[i] = escape(c)
+		}
+		return out
+	}
+	tt := Table{Header: esc(t.Header)}
+	for _, row := range t.Rows {
+		tt.Rows = append(tt.Rows, esc(row))
+	}
+	md := tt.markdown()
+	if words := strings.Fields(escape(t.Caption)); len(words) > 0 {
+		words[0] = escapeLineStart(words[0])
+		md = strings.Join(words, " ") + "\n\n" + md
+	}
+	return md
+}
+
+// markdown renders t as a pipe table without its caption, with cells
+// already in Markdown.
+func (t Table) markdown() string {
+	rows := t.Rows
+	header := t.Header
+	if header == nil {
+		if len(rows) == 0 {
+			return ""
+		}
+		header, rows = rows[0], rows[1:]
+	}
+	widths := make([]int, len(header))
+	for i := range widths {
+		widths[i] = 3 // the shortest delimiter row cell
+	}
+	for _, row := range append([][]string{header}, rows...) {
+		for i, c := range row {
+			if n := utf8.RuneCountInString(c); n <= maxPad && i < len(widths) {
+				widths[i] = max(widths[i], n)
+			}
+		}
+	}
+	var b strings.Builder
+	line := func(cells []string) {
+		b.WriteString("|")
+		for i, w := range widths {
+			c := ""
+			if i < len(cells) {
+				c = cells[i]
+			}
+			b.WriteString(" " + c + strings.Repeat(" ", max(w-utf8.RuneCountInString(c), 0)) + " |")
+		}
+	}
+	line(header)
+	b.WriteString("\n")
+	sep := make([]string, len(widths))
+	for i, w := range widths {
+		sep[i] = strings.Repeat("-", w)
+	}
+	line(sep)
+	for _, row := range rows {
+		b.WriteString("\n")
+		line(row)
+	}
+	return b.String()
+}
+
+// KeyValue renders t as plain text for readers that cannot see columns.
+// With a header, each row is a record of "Heading: value" lines, preceded
+// by its label if the table has row headers, and records are separated by
+// blank lines. Without one, rows with headers become "Label: values"
+// lines and other rows put tabs between cells. Empty cells are omitted.
+func (t Table) KeyValue() string {
+	var records []string
+	if t.Caption != "" {
+		records = append(records, t.Caption)
+	}
+	if t.Header == nil {
+		var lines []string
+		for _, row := range t.Rows {
+			cells := row
+			label := ""
+			if t.RowHeaders && len(row) > 0 {
+				label, cells = row[0], row[1:]
+			}
+			l := strings.Join(nonEmpty(cells), "\t")
+			if label != "" {
+				l = strings.TrimSuffix(label, ":") + ": " + l
+			}
+			if l = strings.TrimSpace(l); l != "" {
+				lines = append(lines, l)
+			}
+		}
+		if len(lines) > 0 {
+			records = append(records, strings.Join(lines, "\n"))
+		}
+		return strings.Join(records, "\n\n")
+	}
+	for _, row := range t.Rows {
+		var lines []string
+		for i, c := range row {
+			if c == "" {
+				continue
+			}
+			key := ""
+			if i < len(t.Header) {
+				key = t.Header[i]
+			}
+			switch {
+			case i == 0 && t.RowHeaders:
+				lines = append(lines, c)
+			case key == "":
+				lines = append(lines, c)
+			default:
+				lines = append(lines, strings.TrimSuffix(key, ":")+": "+c)
+			}
+		}
+		if len(lines) > 0 {
+			records = append(records, strings.Join(lines, "\n"))
+		}
+	}
+	return strings.Join(records, "\n\n")
+}
+
+func nonEmpty(cells []string) []string {
+	var out []string
+	for _, c := range cells {
+		if c != "" {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// tableEnd returns the index of the </table> closing the table that
+// tokens[start] opens, or len(tokens) if it is unterminated.
+func tableEnd(tokens []token, start int) int {
+	depth := 0
+	for i := start; i < len(tokens); i++ {
+		t := tokens[i]
+		if t.kind == textToken || t.name != "table" {
+			continue
+		}
+		if t.kind == startTagToken && !t.selfClosing {
+			depth++
+		} else if t.kind == endTagToken {
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(tokens)
+}
+
+type tableCell struct {
+	tokens  []token
+	header  bool
+	colspan int
+	rowspan int
+}
+
+type tableRow struct {
+	cells []tableCell
+	head  bool // in <thead>
+}
+
+// parseTable parses the tokens between <table> and </table>, rendering
+// each cell with text. Text outside cells and captions is dropped, and
+// tables nested in cells are left to text.
+func parseTable(tokens []token, text func([]token) string) Table {
+	var (
+		rows      []tableRow
+		row       *tableRow
+		cell      *tableCell
+		caption   []token
+		inCaption bool
+		head      bool
+		depth     int // of tables nested in the current cell
+	)
+	closeCell := func() {
+		if cell != nil {
+			row.cells = append(row.cells, *cell)
+			cell = nil
+		}
+	}
+	closeRow := func() {
+		closeCell()
+		if row != nil {
+			rows = append(rows, *row)
+			row = nil
+		}
+	}
+	for _, t := range tokens {
+		nested := t.kind != textToken && t.name == "table" && (cell != nil || inCaption)
+		if nested && t.kind == startTagToken && !t.selfClosing {
+			depth++
+		} else if nested && t.kind == endTagToken && depth > 0 {
+			depth--
+		}
+		if nested || depth > 0 || t.kind == textToken {
+			switch {
+			case inCaption:
+				caption = append(caption, t)
+			case cell != nil:
+				cell.tokens = append(cell.tokens, t)
+			}
+			continue
+		}
+		start := t.kind == startTagToken
+		switch t.name {
+		case "caption":
+			if start {
+				closeRow()
+			}
+			inCaption = start
+		case "thead", "tbody", "tfoot":
+			closeRow()
+			head = start && t.name == "thead"
+		case "tr":
+			closeRow()
+			if start {
+				row = &tableRow{head: head}
+			}
+		case "td", "th":
+			closeCell()
+			if !start {
+				continue
+			}
+			if row == nil {
+				row = &tableRow{head: head}
+			}
+			cell = &tableCell{header: t.name == "th", colspan: span(t.attrs["colspan"]), rowspan: span(t.attrs["rowspan"])}
+		default:
+			switch {
+			case inCaption:
+				caption = append(caption, t)
+			case cell != nil:
+				cell.tokens = append(cell.tokens, t)
+			}
+		}
+	}
+	closeRow()
+	return layout(rows, text(caption), text)
+}
+
+// span parses a colspan or rowspan attribute.
+func span(attr string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(attr))
+	if err != nil || n < 1 {
+		return 1
+	}
+	return min(n, maxSpan)
+}
+
+// layout places the cells of rows on a grid, repeating those that span
+// several columns or rows, and splits off the heading rows.
+func layout(rows []tableRow, caption string, text func([]token) string) Table {
+	type carry struct {
+		text   string
+		header bool
+		rows   int // further rows the cell spans
+	}
+	type gridCell struct {
+		text   string
+		header bool
+	}
+	type gridRow struct {
+		cells []gridCell
+		head  bool // in <thead> or all <th>
+	}
+	var (
+		grid    []gridRow
+		pending []carry // by column
+		width   int
+	)
+	for _, r := range rows {
+		var out []gridCell
+		fill := func() {
+			for len(out) < len(pending) && pending[len(out)].rows > 0 {
+				p := &pending[len(out)]
+				p.rows--
+				out = append(out, gridCell{p.text, p.header})
+			}
+		}
+		for _, c := range r.cells {
+			fill()
+			s := text(c.tokens)
+			for k := 0; k < c.colspan; k++ {
+				col := len(out)
+				out = append(out, gridCell{s, c.header})
+				for len(pending) <= col {
+					pending = append(pending, carry{})
+				}
+				pending[col] = carry{s, c.header, c.rowspan - 1}
+			}
+		}
+		for len(out) < len(pending) {
+			if fill(); len(out) < len(pending) {
+				out = append(out, gridCell{})
+			}
+		}
+		for len(out) > 0 && out[len(out)-1] == (gridCell{}) {
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			continue
+		}
+		head := r.head
+		if !head {
+			head = true
+			for _, c := range out {
+				head = head && c.header
+			}
+		}
+		grid = append(grid, gridRow{out, head})
+		width = max(width, len(out))
+	}
+
+	headerRows := 0
+	for headerRows < len(grid) && grid[headerRows].head {
+		headerRows++
+	}
+	if headerRows == len(grid) && headerRows > 1 {
+		headerRows = 1
+	}
+	t := Table{Caption: caption}
+	for i, r := range grid {
+		cells := make([]string, width)
+		for j, c := range r.cells {
+			cells[j] = c.text
+		}
+		if i >= headerRows {
+			t.Rows = append(t.Rows, cells)
+			continue
+		}
+		if t.Header == nil {
+			t.Header = cells
+			continue
+		}
+		for j, c := range cells {
+			if c != "" && !strings.HasSuffix(t.Header[j], c) {
+				t.Header[j] = strings.TrimSpace(t.Header[j] + " " + c)
+			}
+		}
+	}
+	t.RowHeaders = len(t.Rows) > 0
+	for _, r := range grid[headerRows:] {
+		if !r.cells[0].header {
+			t.RowHeaders = false
+		}
+	}
+	return t
+}
@@ -0,0 +1,115 @@
+package content_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+const specTable = `<table>
+<caption>Supported platforms</caption>
+<thead>
+  <tr><th rowspan="2">OS</th><th colspan="2">Architectures</th></tr>
+  <tr><th>64-bit</th><th>32-bit</th></tr>
+</thead>
+<tbody>
+  <tr><td rowspan="2">Linux</td><td>amd64</td><td>386</td></tr>
+  <tr><td>arm64</td><td>arm</td></tr>
+  <tr><td>macOS</td><td colspan="2"><a href="/apple">arm64</a> only</td></tr>
+</tbody>
+</table>`
+
+func TestTables(t *testing.T) {
+	tables := content.Tables("<p>intro</p>" + specTable + `<script>"<table>"</script>`)
+	want := []content.Table{{
+		Caption: "Supported platforms",
+		Header:  []string{"OS", "Architectures 64-bit", "Architectures 32-bit"},
+		Rows: [][]string{
+			{"Linux", "amd64", "386"},
+			{"Linux", "arm64", "arm"},
+			{"macOS", "arm64 only", "arm64 only"},
+		},
+	}}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("got %#v\nwant %#v", tables, want)
+	}
+}
+
+func TestTablesRowHeadersAndNesting(t *testing.T) {
+	tables := content.Tables(`<table>
+		<tr><th>Released</th><td>2009</td></tr>
+		<tr><th>License</th><td><table><tr><td>BSD</td><td>3-clause</td></tr></table></td></tr>
+		<tr></tr>
+	</table>`)
+	want := []content.Table{{
+		Rows:       [][]string{{"Released", "2009"}, {"License", "BSD 3-clause"}},
+		RowHeaders: true,
+	}}
+	if !reflect.DeepEqual(tables, want) {
+		t.Errorf("got %#v\nwant %#v", tables, want)
+	}
+	if got, want := tables[0].KeyValue(), "Released: 2009\nLicense: BSD 3-clause"; got != want {
+		t.Errorf("KeyValue = %q, want %q", got, want)
+	}
+}
+
+func TestTableMarkdown(t *testing.T) {
+	tables := content.Tables(specTable)
+	want := "Supported platforms\n\n" +
+		"| OS    | Architectures 64-bit | Architectures 32-bit |\n" +
+		"| ----- | -------------------- | -------------------- |\n" +
+		"| Linux | amd64                | 386                  |\n" +
+		"| Linux | arm64                | arm                  |\n" +
+		"| macOS | arm64 only           | arm64 only           |"
+	if got := tables[0].Markdown(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	escaped := content.Table{Rows: [][]string{{"a|b", "c"}, {"1", ""}}}.Markdown()
+	if want := "| a\\|b | c   |\n| ---- | --- |\n| 1    |     |"; escaped != want {
+		t.Errorf("got:\n%s\nwant:\n%s", escaped, want)
+	}
+}
+
+func TestTableKeyValue(t *testing.T) {
+	want := "Supported platforms\n\n" +
+		"OS: Linux\nArchitectures 64-bit: amd64\nArchitectures 32-bit: 386\n\n" +
+		"OS: Linux\nArchitectures 64-bit: arm64\nArchitectures 32-bit: arm\n\n" +
+		"OS: macOS\nArchitectures 64-bit: arm64 only\nArchitectures 32-bit: arm64 only"
+	if got := content.Tables(specTable)[0].KeyValue(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	comparison := content.Table{
+		Header:     []string{"", "Basic", "Pro"},
+		Rows:       [][]string{{"Price", "$0", "$10"}, {"Seats", "1", ""}},
+		RowHeaders: true,
+	}
+	want = "Price\nBasic: $0\nPro: $10\n\nSeats\nBasic: 1"
+	if got := comparison.KeyValue(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestHTMLToMarkdownTable(t *testing.T) {
+	in := "<ul><li>Limits:<table><tr><th>Plan</th><th>Requests</th></tr>" +
+		"<tr><td><b>Free</b></td><td>100<br>per day</td></tr></table></li></ul><p>After</p>"
+	want := "- Limits:\n\n" +
+		"  | Plan     | Requests    |\n" +
+		"  | -------- | ----------- |\n" +
+		"  | **Free** | 100 per day |\n\n" +
+		"After"
+	if got := content.HTMLToMarkdown(in); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestStripHTMLTable(t *testing.T) {
+	in := "<p>Plans</p><table><tr><th>Plan</th><th>Price</th></tr><tr><td>Free</td><td>$0</td></tr>" +
+		"<tr><td>Pro</td><td>$10</td></tr></table><p>Done</p>"
+	want := "Plans\n\nPlan: Free\nPrice: $0\n\nPlan: Pro\nPrice: $10\n\nDone"
+	if got := content.StripHTML(in); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,180 @@
+package content
+
+import (
+	"strings"
+)
+
+type tokenKind int
+
+const (
+	textToken tokenKind = iota
+	startTagToken
+	endTagToken
+	mathToken // TeX source, from mathTokens
+)
+
+// token is a piece of an HTML document. Text is raw, with entities still
+// escaped; tag names are lowercase.
+type token struct {
+	kind        tokenKind
+	text        string // textToken, mathToken
+	name        string // tags
+	attrs       map[string]string
+	selfClosing bool
+	display     bool // mathToken set off as a block
+}
+
+// rawTextElements hold text that is not parsed for tags.
+var rawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// tokenize splits an HTML document into text and tags. It is lenient, as
+// browsers are: a '<' that does not start a tag is text, and unterminated
+// tags run to the end of the input. Comments, doctypes, and processing
+// instructions are dropped.
+func tokenize(s string) []token {
+	var tokens []token
+	text := func(t string) {
+		if t == "" {
+			return
+		}
+		if n := len(tokens); n > 0 && tokens[n-1].kind == textToken {
+			tokens[n-1].text += t
+			return
+		}
+		tokens = append(tokens, token{kind: textToken, text: t})
+	}
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			text(s)
+			break
+		}
+		text(s[:i])
+		s = s[i:]
+		switch {
+		case strings.HasPrefix(s, "<!--"):
+			end := strings.Index(s[4:], "-->")
+			if end < 0 {
+				return tokens
+			}
+			s = s[4+end+3:]
+		case strings.HasPrefix(s, "<!") || strings.HasPrefix(s, "<?"):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return tokens
+			}
+			s = s[end+1:]
+		case len(s) > 2 && s[1] == '/' && isASCIILetter(s[2]):
+			end := strings.IndexByte(s, '>')
+			if end < 0 {
+				return tokens
+			}
+			name, _ := splitName(s[2:end])
+			tokens = append(tokens, token{kind: endTagToken, name: name})
+			s = s[end+1:]
+		case len(s) > 1 && isASCIILetter(s[1]):
+			t, rest := parseStartTag(s[1:])
+			tokens = append(tokens, t)
+			s = rest
+			if rawTextElements[t.name] && !t.selfClosing {
+				end := indexFold(s, "</"+t.name)
+				if end < 0 {
+					end = len(s)
+				}
+				text(s[:end])
+				s = s[end:]
+				if gt := strings.IndexByte(s, '>'); gt >= 0 {
+					tokens = append(tokens, token{kind: endTagToken, name: t.name})
+					s = s[gt+1:]
+				} else {
+					s = ""
+				}
+			}
+		default:
+			text("<")
+			s = s[1:]
+		}
+	}
+	return tokens
+}
+
+// parseStartTag parses a start tag after its '<' and returns the rest of
+// the input after its '>'.
+func parseStartTag(s string) (token, string) {
+	name, s := splitName(s)
+	t := token{kind: startTagToken, name: name}
+	for {
+		s = strings.TrimLeft(s, " \t\r\n\f")
+		switch {
+		case s == "":
+			return t, ""
+		case s[0] == '>':
+			return t, s[1:]
+		case strings.HasPrefix(s, "/>"):
+			t.selfClosing = true
+			return t, s[2:]
+		case s[0] == '/':
+			s = s[1:]
+			continue
+		}
+		end := strings.IndexAny(s, " \t\r\n\f/>=")
+		if end < 0 {
+			end = len(s)
+		}
+		if end == 0 {
+			// A stray '=' or similar; skip it.
+			s = s[1:]
+			continue
+		}
+		key := strings.ToLower(s[:end])
+		s = strings.TrimLeft(s[end:], " \t\r\n\f")
+		value := ""
+		if strings.HasPrefix(s, "=") {
+			s = strings.TrimLeft(s[1:], " \t\r\n\f")
+			if s != "" && (s[0] == '"' || s[0] == '\'') {
+				q := s[0]
+				end := strings.IndexByte(s[1:], q)
+				if end < 0 {
+					value, s = s[1:], ""
+				} else {
+					value, s = s[1:1+end], s[2+end:]
+				}
+			} else {
+				end := strings.IndexAny(s, " \t\r\n\f>")
+				if end < 0 {
+					end = len(s)
+				}
+				value, s = s[:end], s[end:]
+			}
+		}
+		if t.attrs == nil {
+			t.attrs = make(map[string]string)
+		}
+		if _, dup := t.attrs[key]; !dup {
+			t.attrs[key] = value
+		}
+	}
+}
+
+// splitName splits a lowercase tag name from the start of s.
+func splitName(s string) (name, rest string) {
+	end := strings.IndexAny(s, " \t\r\n\f/>")
+	if end < 0 {
+		end = len(s)
+	}
+	return strings.ToLower(s[:end]), s[end:]
+}
+
+// indexFold is strings.Index ignoring ASCII case in s.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isASCIILetter(c byte) bool {
+	return ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
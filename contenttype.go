@@ -0,0 +1,15 @@
+package datasource
+
+// ContentType declares the format of a data item's DataText, so consumers
+// do not have to guess whether to render, escape, or convert it. Package
+// content converts between formats.
+type ContentType string
+
+// Content types. The empty ContentType means the source did not declare
+// one; content.Detect can guess.
+const (
+	ContentHTML      ContentType = "html"
+	ContentMarkdown  ContentType = "markdown"
+	ContentPlainText ContentType = "plaintext"
+	ContentCode      ContentType = "code"
+)
@@ -7,6 +7,11 @@
 // or any custom knowledge base.
 package datasource
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // DataSource defines the contract for integrating external data sources.
 // Implementations should handle API communication, rate limiting, and error
 // handling internally.
@@ -50,12 +55,49 @@ type DataSource interface {
 	FetchData(count int, topicID int64) ([]DataSourceData, error)
 }
 
+// find returns the first layer of ds's decorator chain (following Unwrap
+// methods) that implements T, and false if there is none.
+func find[T any](ds DataSource) (T, bool) {
+	for ; ds != nil; ds = unwrap(ds) {
+		if t, ok := ds.(T); ok {
+			return t, true
+		}
+	}
+	var zero T
+	return zero, false
+}
+
+// unwrap returns the source ds decorates, or nil if it is not a decorator.
+func unwrap(ds DataSource) DataSource {
+	if u, ok := ds.(interface{ Unwrap() DataSource }); ok {
+		return u.Unwrap()
+	}
+	return nil
+}
+
 // DataSourceTopic represents a high-level item from an external source that
 // may contain relevant information (e.g., a question, article, or video).
 type DataSourceTopic struct {
-	// Topic is the title or main text of the topic
+	// Topic is the title or main text of the topic. Sources that set the
+	// structured fields below still set Topic, to the title, for hosts that
+	// read Topic alone; see Structured
 	Topic string `json:"topic"`
 
+	// Title is the topic's title alone, for hosts rendering it apart from
+	// the body and rankers weighting title matches
+	// Optional - if empty, Topic is the title; see DisplayTitle
+	Title string `json:"title,omitempty"`
+
+	// BodyExcerpt is plain text from the topic's body, such as a search
+	// snippet or description
+	// Optional
+	BodyExcerpt string `json:"body_excerpt,omitempty"`
+
+	// Path is the breadcrumb of the topic, outermost first, such as the
+	// space and parent pages of a wiki page, not including the topic
+	// Optional
+	Path []string `json:"path,omitempty"`
+
 	// SourceURL is the canonical URL where this topic can be viewed
 	SourceURL string `json:"source_url"`
 
@@ -67,6 +109,62 @@ type DataSourceTopic struct {
 	// TopicID is the unique identifier for this topic in the external system
 	// Used when calling FetchData to retrieve associated content
 	TopicID int64 `json:"topic_id"`
+
+	// Score is the source's relevance score for the topic (e.g., BM25,
+	// vector similarity, or upstream votes); higher is more relevant
+	// Optional - zero if the source does not score results. Scores are
+	// only comparable within one source's results
+	Score float64 `json:"score,omitempty"`
+
+	// Rank is the topic's 1-based position in the source's own ranking
+	// Optional - zero if unset; preserved when results are merged
+	Rank int `json:"rank,omitempty"`
+
+	// Metadata holds source-specific fields (e.g., view count, category)
+	// Optional - values must be JSON-serializable
+	Metadata Metadata `json:"metadata,omitempty"`
+
+	// Extra holds typed source-specific extensions (e.g., bounty details)
+	// Optional - see Extra
+	Extra Extra `json:"extra,omitempty"`
+
+	// Language is the BCP 47 tag of the topic's language (e.g., "en",
+	// "pt-BR")
+	// Optional - empty if unknown or the source is monolingual
+	Language string `json:"language,omitempty"`
+
+	// Embedding is the source's precomputed vector for the topic, from the
+	// model named by Capabilities.EmbeddingModel, so the host need not
+	// embed it again
+	// Optional - nil if the source does not index semantically
+	Embedding []float64 `json:"embedding,omitempty"`
+
+	// CreatedAt is when the topic was first published upstream
+	// Optional - zero if unknown
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt is when the topic was last modified upstream
+	// Optional - zero if unknown
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// MarshalJSON encodes t, leaving out CreatedAt and UpdatedAt when they are
+// zero rather than sending the zero time.
+func (t DataSourceTopic) MarshalJSON() ([]byte, error) {
+	type plain DataSourceTopic
+	return json.Marshal(struct {
+		plain
+		CreatedAt *time.Time `json:"created_at,omitempty"`
+		UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	}{plain(t), nonZeroTime(t.CreatedAt), nonZeroTime(t.UpdatedAt)})
+}
+
+// nonZeroTime returns a pointer to t, or nil if t is zero.
+func nonZeroTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
 }
 
 // DataSourceData represents a specific piece of content associated with a topic
@@ -75,6 +173,12 @@ type DataSourceData struct {
 	// DataText is the actual content text (may include HTML or markdown)
 	DataText string `json:"data_text"`
 
+	// ContentType declares the format of DataText (html, markdown,
+	// plaintext, or code)
+	// Optional - empty if undeclared; see the content package to detect
+	// or convert formats
+	ContentType ContentType `json:"content_type,omitempty"`
+
 	// SourceURL is the canonical URL where this specific data can be viewed
 	SourceURL string `json:"source_url"`
 
@@ -86,6 +190,104 @@ type DataSourceData struct {
 	// The name "AnswerID" is used for historical reasons but represents any
 	// data item identifier (answer, excerpt, etc.)
 	AnswerID int64 `json:"answer_id"`
+
+	// Score is the source's relevance score for the data item (e.g., vote
+	// count or passage similarity); higher is more relevant
+	// Optional - zero if the source does not score results
+	Score float64 `json:"score,omitempty"`
+
+	// Rank is the data item's 1-based position in the source's own ranking
+	// Optional - zero if unset
+	Rank int `json:"rank,omitempty"`
+
+	// Metadata holds source-specific fields (e.g., confidence, section)
+	// Optional - values must be JSON-serializable
+	Metadata Metadata `json:"metadata,omitempty"`
+
+	// Extra holds typed source-specific extensions (e.g., video duration)
+	// Optional - see Extra
+	Extra Extra `json:"extra,omitempty"`
+
+	// Language is the BCP 47 tag of DataText's language
+	// Optional - empty if unknown or the source is monolingual
+	Language string `json:"language,omitempty"`
+
+	// Embedding is the source's precomputed vector for DataText, from the
+	// model named by Capabilities.EmbeddingModel, so the host need not
+	// embed it again
+	// Optional - nil if the source does not index semantically
+	Embedding []float64 `json:"embedding,omitempty"`
+
+	// CreatedAt is when the data item was first published upstream
+	// Optional - zero if unknown
+	CreatedAt time.Time `json:"created_at"`
+
+	// UpdatedAt is when the data item was last modified upstream
+	// Optional - zero if unknown
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Author is who wrote the data item, for attribution and for weighting
+	// results by reputation
+	// Optional - nil if the source does not attribute content
+	Author *Author `json:"author,omitempty"`
+
+	// Classification labels how sensitive DataText is (safe, sensitive,
+	// secret) so the host can mask it or require a click-through
+	// Optional - typically populated by an enrichment stage (see
+	// enrich.Classify); empty if unclassified
+	Classification Classification `json:"classification,omitempty"`
+
+	// Entities lists canonical entities mentioned in DataText
+	// Optional - typically populated by an enrichment stage rather than the
+	// data source itself (see the enrich package)
+	Entities []Entity `json:"entities,omitempty"`
+}
+
+// MarshalJSON encodes d, leaving out CreatedAt and UpdatedAt when they are
+// zero rather than sending the zero time.
+func (d DataSourceData) MarshalJSON() ([]byte, error) {
+	type plain DataSourceData
+	return json.Marshal(struct {
+		plain
+		CreatedAt *time.Time `json:"created_at,omitempty"`
+		UpdatedAt *time.Time `json:"updated_at,omitempty"`
+	}{plain(d), nonZeroTime(d.CreatedAt), nonZeroTime(d.UpdatedAt)})
+}
+
+// Author identifies who wrote a data item.
+type Author struct {
+	// Name is the author's display name
+	Name string `json:"name"`
+
+	// ProfileURL optionally links to the author's profile page
+	ProfileURL string `json:"profile_url,omitempty"`
+
+	// Reputation is the author's standing on the source (e.g., Stack
+	// Exchange reputation, karma, or an internal expertise score); higher
+	// is more trusted
+	// Optional - zero if the source has no such measure. Reputations are
+	// only comparable within one source
+	Reputation float64 `json:"reputation,omitempty"`
+}
+
+// Entity is a canonical concept mentioned in a data item's text, such as an
+// internal glossary term or a Wikidata item. Linking mentions to entities
+// lets the host disambiguate terms and render knowledge panels.
+type Entity struct {
+	// ID is the canonical identifier (e.g., a glossary key or Wikidata "Q42")
+	ID string `json:"id"`
+
+	// Name is the canonical display name of the entity
+	Name string `json:"name"`
+
+	// URL optionally points to a page describing the entity
+	URL string `json:"url,omitempty"`
+
+	// Mention is the text as it appeared in DataText
+	Mention string `json:"mention"`
+
+	// Offset is the byte offset of Mention within DataText
+	Offset int `json:"offset"`
 }
 
 // NewQuestionInput provides context for searching topics in a data source.
@@ -98,12 +300,54 @@ type NewQuestionInput struct {
 	// Tags are optional topic tags that may help narrow the search
 	Tags []string
 
+	// Attachments optionally holds code snippets, log excerpts, and image
+	// references the asker added to the question
+	// Sources searching by keyword may search on their error lines too;
+	// see SearchText
+	Attachments []Attachment
+
 	// AskedBy is the optional user ID of who is asking the question
 	// May be nil if the query is anonymous
 	AskedBy *int64
 
+	// TenantID optionally identifies the organization the asker belongs to
+	// in multi-tenant hosts
+	TenantID string
+
+	// SessionID optionally identifies the conversation the question is
+	// part of, so multiplexers can favor the sources and topics that were
+	// useful earlier in it; see router.Affinity
+	SessionID string
+
+	// AcceptLanguages optionally lists the BCP 47 tags of the languages
+	// the asker reads, most preferred first (e.g., from Accept-Language)
+	// Multilingual sources should return only results in these languages;
+	// see AcceptsLanguage
+	AcceptLanguages []string
+
+	// Filters optionally constrains the results by date, score, site, and
+	// tags, and states the preferred sort order
+	// Sources should honor what they can; see Filters
+	Filters Filters
+
+	// Budget optionally limits how long the host waits for results
+	// Sources should bound their upstream calls by it and return the
+	// results they have when it runs out; see Budget
+	Budget Budget
+
+	// Trace optionally collects what decorators do with the question, such
+	// as answering it from a cache, for a QueryReport
+	// Set by multiplexers and never sent to remote sources; see Trace
+	Trace *Trace `json:"-"`
+
 	// Embedding is an optional precomputed vector representation of the question
 	// Advanced data sources can use this for semantic search or similarity matching
 	// If nil or empty, the data source should fall back to text-based search
 	Embedding []float64
+
+	// Embeddings optionally holds further vector representations of the
+	// question, keyed by the name of the model or view that produced them
+	// (e.g., "general", "code"), for sources that declare the ones they
+	// consume in Capabilities.QueryEmbeddings; see EmbeddingFor
+	Embeddings map[string][]float64
 }
@@ -0,0 +1,263 @@
+// Package datasourcetest provides utilities for testing DataSource
+// implementations.
+//
+// RunConformance checks an implementation against the documented DataSource
+// contract with a single call from a test:
+//
+//	func TestConformance(t *testing.T) {
+//	    datasourcetest.RunConformance(t, mysource.New(cfg))
+//	}
+package datasourcetest
+
+import (
+	"fmt"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// DefaultQueries are the questions RunConformance uses when Config.Queries
+// is empty.
+var DefaultQueries = []string{"how do I reset my password", "what is dns"}
+
+// Config configures the conformance suite.
+type Config struct {
+	// Queries are questions expected to return at least one topic
+	// Defaults to DefaultQueries
+	Queries []string
+
+	// NoMatchQuery is a question expected to match few or no topics
+	// Defaults to a random-looking string
+	NoMatchQuery string
+
+	// InvalidTopicID is a topic ID that does not exist upstream
+	// Defaults to -1
+	InvalidTopicID int64
+
+	// SkipInit skips calling Init, for data sources that are already
+	// initialized
+	SkipInit bool
+}
+
+func (c Config) withDefaults() Config {
+	if len(c.Queries) == 0 {
+		c.Queries = DefaultQueries
+	}
+	if c.NoMatchQuery == "" {
+		c.NoMatchQuery = "qzxv jkwp nonexistent-7f3a9c"
+	}
+	if c.InvalidTopicID == 0 {
+		c.InvalidTopicID = -1
+	}
+	return c
+}
+
+// RunConformance runs the conformance suite against ds with the default
+// Config. Each contract check runs as a subtest.
+func RunConformance(t *testing.T, ds datasource.DataSource) {
+	t.Helper()
+	Config{}.Run(t, ds)
+}
+
+// Run runs the conformance suite against ds. Each contract check runs as a
+// subtest; panics in ds are reported as failures of the check that caused
+// them.
+func (c Config) Run(t *testing.T, ds datasource.DataSource) {
+	t.Helper()
+	c = c.withDefaults()
+
+	if !c.SkipInit {
+		if err := safely(func() error { return ds.Init() }); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+	}
+	for _, chk := range checks {
+		chk := chk
+		t.Run(chk.name, func(t *testing.T) {
+			if err := safely(func() error { return chk.fn(ds, c) }); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+type check struct {
+	name string
+	fn   func(ds datasource.DataSource, c Config) error
+}
+
+var checks = []check{
+	{"CheckAvailability", checkAvailability},
+	{"FetchTopics/CountLimits", checkTopicCountLimits},
+	{"FetchTopics/Fields", checkTopicFields},
+	{"FetchTopics/NoMatch", checkNoMatch},
+	{"FetchTopics/InvalidInput", checkTopicsInvalidInput},
+	{"FetchData/StableIDs", checkStableIDs},
+	{"FetchData/CountLimits", checkDataCountLimits},
+	{"FetchData/InvalidTopic", checkInvalidTopic},
+}
+
+// safely calls fn, converting a panic into an error.
+func safely(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
+
+func checkAvailability(ds datasource.DataSource, _ Config) error {
+	if !ds.CheckAvailability() {
+		return fmt.Errorf("CheckAvailability returned false")
+	}
+	return nil
+}
+
+func fetchTopics(ds datasource.DataSource, count int, q string) ([]datasource.DataSourceTopic, error) {
+	topics, err := ds.FetchTopics(count, datasource.NewQuestionInput{QuestionText: q})
+	if err != nil {
+		return nil, fmt.Errorf("FetchTopics(%d, %q): %w", count, q, err)
+	}
+	if topics == nil {
+		return nil, fmt.Errorf("FetchTopics(%d, %q) returned a nil slice; return an empty slice for no results", count, q)
+	}
+	if len(topics) > count {
+		return nil, fmt.Errorf("FetchTopics(%d, %q) returned %d topics", count, q, len(topics))
+	}
+	return topics, nil
+}
+
+func fetchData(ds datasource.DataSource, count int, topicID int64) ([]datasource.DataSourceData, error) {
+	data, err := ds.FetchData(count, topicID)
+	if err != nil {
+		return nil, fmt.Errorf("FetchData(%d, %d): %w", count, topicID, err)
+	}
+	if data == nil {
+		return nil, fmt.Errorf("FetchData(%d, %d) returned a nil slice; return an empty slice for no data", count, topicID)
+	}
+	if len(data) > count {
+		return nil, fmt.Errorf("FetchData(%d, %d) returned %d items", count, topicID, len(data))
+	}
+	return data, nil
+}
+
+// sampleTopics returns the topics for the first configured query that has
+// any, so checks that need real topic IDs have something to work with.
+func sampleTopics(ds datasource.DataSource, c Config) ([]datasource.DataSourceTopic, error) {
+	for _, q := range c.Queries {
+		topics, err := fetchTopics(ds, 5, q)
+		if err != nil {
+			return nil, err
+		}
+		if len(topics) > 0 {
+			return topics, nil
+		}
+	}
+	return nil, fmt.Errorf("no query returned topics; set Config.Queries to questions the source can answer")
+}
+
+func checkTopicCountLimits(ds datasource.DataSource, c Config) error {
+	for _, q := range c.Queries {
+		for _, count := range []int{1, 3} {
+			if _, err := fetchTopics(ds, count, q); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func checkTopicFields(ds datasource.DataSource, c Config) error {
+	topics, err := sampleTopics(ds, c)
+	if err != nil {
+		return err
+	}
+	seen := make(map[int64]bool, len(topics))
+	for i, t := range topics {
+		if t.Topic == "" {
+			return fmt.Errorf("topic %d (ID %d) has an empty Topic", i, t.TopicID)
+		}
+		if seen[t.TopicID] {
+			return fmt.Errorf("topic ID %d returned more than once", t.TopicID)
+		}
+		seen[t.TopicID] = true
+	}
+	return nil
+}
+
+func checkNoMatch(ds datasource.DataSource, c Config) error {
+	_, err := fetchTopics(ds, 5, c.NoMatchQuery)
+	return err
+}
+
+func checkTopicsInvalidInput(ds datasource.DataSource, c Config) error {
+	// An empty question may be rejected or answered, but must not panic.
+	ds.FetchTopics(5, datasource.NewQuestionInput{})
+
+	for _, count := range []int{0, -1} {
+		topics, err := ds.FetchTopics(count, datasource.NewQuestionInput{QuestionText: c.Queries[0]})
+		if err == nil && len(topics) > 0 {
+			return fmt.Errorf("FetchTopics(%d, ...) returned %d topics; want an error or no results", count, len(topics))
+		}
+	}
+	return nil
+}
+
+func checkStableIDs(ds datasource.DataSource, c Config) error {
+	topics, err := sampleTopics(ds, c)
+	if err != nil {
+		return err
+	}
+	for _, t := range topics[:min(len(topics), 3)] {
+		first, err := fetchData(ds, 3, t.TopicID)
+		if err != nil {
+			return err
+		}
+		second, err := fetchData(ds, 3, t.TopicID)
+		if err != nil {
+			return err
+		}
+		if len(first) != len(second) {
+			return fmt.Errorf("FetchData(3, %d) returned %d items, then %d", t.TopicID, len(first), len(second))
+		}
+		seen := make(map[int64]bool, len(first))
+		for i := range first {
+			if first[i].AnswerID != second[i].AnswerID {
+				return fmt.Errorf("FetchData(3, %d) item %d has AnswerID %d, then %d",
+					t.TopicID, i, first[i].AnswerID, second[i].AnswerID)
+			}
+			if seen[first[i].AnswerID] {
+				return fmt.Errorf("FetchData(3, %d) returned AnswerID %d more than once", t.TopicID, first[i].AnswerID)
+			}
+			seen[first[i].AnswerID] = true
+		}
+	}
+	return nil
+}
+
+func checkDataCountLimits(ds datasource.DataSource, c Config) error {
+	topics, err := sampleTopics(ds, c)
+	if err != nil {
+		return err
+	}
+	if _, err := fetchData(ds, 1, topics[0].TopicID); err != nil {
+		return err
+	}
+	for _, count := range []int{0, -1} {
+		data, err := ds.FetchData(count, topics[0].TopicID)
+		if err == nil && len(data) > 0 {
+			return fmt.Errorf("FetchData(%d, %d) returned %d items; want an error or no data", count, topics[0].TopicID, len(data))
+		}
+	}
+	return nil
+}
+
+func checkInvalidTopic(ds datasource.DataSource, c Config) error {
+	data, err := ds.FetchData(3, c.InvalidTopicID)
+	if err == nil && len(data) > 0 {
+		return fmt.Errorf("FetchData(3, %d) returned %d items for a nonexistent topic; want an error or no data",
+			c.InvalidTopicID, len(data))
+	}
+	return nil
+}
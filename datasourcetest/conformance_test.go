@@ -0,0 +1,106 @@
+package datasourcetest
+
+import (
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// contractSource is a small DataSource whose behavior can be broken in
+// specific ways to exercise individual checks.
+type contractSource struct {
+	ignoreCount bool
+	nilEmpty    bool
+	dupTopics   bool
+	unstable    bool
+	panicData   bool
+	calls       int
+}
+
+func (s *contractSource) Init() error             { return nil }
+func (s *contractSource) CheckAvailability() bool { return true }
+
+func (s *contractSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if !strings.Contains(input.QuestionText, "dns") {
+		if s.nilEmpty {
+			return nil, nil
+		}
+		return []datasource.DataSourceTopic{}, nil
+	}
+	topics := []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 1}, {Topic: "Resolvers", TopicID: 2}}
+	if s.dupTopics {
+		topics[1].TopicID = 1
+	}
+	if !s.ignoreCount {
+		topics = topics[:max(0, min(count, len(topics)))]
+	}
+	return topics, nil
+}
+
+func (s *contractSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if s.panicData {
+		var m map[int64][]datasource.DataSourceData
+		m[topicID] = nil
+	}
+	if topicID <= 0 {
+		return nil, datasource.ErrNotFound
+	}
+	s.calls++
+	data := []datasource.DataSourceData{{AnswerID: topicID * 10}, {AnswerID: topicID*10 + 1}}
+	if s.unstable {
+		data[0].AnswerID += int64(s.calls)
+	}
+	return data[:max(0, min(count, len(data)))], nil
+}
+
+func TestChecks(t *testing.T) {
+	cfg := Config{Queries: []string{"what is dns"}}.withDefaults()
+
+	tests := []struct {
+		name  string
+		src   *contractSource
+		check func(datasource.DataSource, Config) error
+		want  string
+	}{
+		{"conforming counts", &contractSource{}, checkTopicCountLimits, ""},
+		{"ignores count", &contractSource{ignoreCount: true}, checkTopicCountLimits, "returned 2 topics"},
+		{"ignores zero count", &contractSource{ignoreCount: true}, checkTopicsInvalidInput, "want an error or no results"},
+		{"nil empty result", &contractSource{nilEmpty: true}, checkNoMatch, "nil slice"},
+		{"duplicate topic IDs", &contractSource{dupTopics: true}, checkTopicFields, "more than once"},
+		{"unstable answer IDs", &contractSource{unstable: true}, checkStableIDs, "has AnswerID"},
+		{"stable answer IDs", &contractSource{}, checkStableIDs, ""},
+		{"invalid topic", &contractSource{}, checkInvalidTopic, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.check(tt.src, cfg)
+			switch {
+			case tt.want == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case tt.want != "" && (err == nil || !strings.Contains(err.Error(), tt.want)):
+				t.Errorf("error %v does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSafelyRecoversPanics(t *testing.T) {
+	src := &contractSource{panicData: true}
+	err := safely(func() error { return checkInvalidTopic(src, Config{}.withDefaults()) })
+	if err == nil || !strings.Contains(err.Error(), "panic") {
+		t.Errorf("panic not reported: %v", err)
+	}
+}
+
+func TestRunConformance(t *testing.T) {
+	Config{Queries: []string{"what is dns"}}.Run(t, &contractSource{})
+}
+
+func TestNoAnswerableQueries(t *testing.T) {
+	_, err := sampleTopics(&contractSource{}, Config{Queries: []string{"reset my password"}}.withDefaults())
+	if err == nil || !strings.Contains(err.Error(), "Config.Queries") {
+		t.Errorf("expected a hint to set Config.Queries, got %v", err)
+	}
+}
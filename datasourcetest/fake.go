@@ -0,0 +1,149 @@
+package datasourcetest
+
+import (
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Call records one call made to a Fake.
+type Call struct {
+	// Method is the interface method called
+	Method datasource.Method
+
+	// Count is the requested count (FetchTopics and FetchData only)
+	Count int
+
+	// Input is the question (FetchTopics only)
+	Input datasource.NewQuestionInput
+
+	// TopicID is the requested topic (FetchData only)
+	TopicID int64
+}
+
+// Fake is a programmable DataSource for testing code that orchestrates data
+// sources. Configure its fields before use; its methods are safe for
+// concurrent use and record every call.
+//
+//	fake := &datasourcetest.Fake{
+//	    Topics: []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 1}},
+//	    Data:   map[int64][]datasource.DataSourceData{1: {{DataText: "..."}}},
+//	}
+//	fake.FailNext(datasource.MethodFetchTopics, datasource.ErrUnavailable)
+type Fake struct {
+	// Topics are returned by FetchTopics, truncated to count
+	Topics []datasource.DataSourceTopic
+
+	// Data maps topic IDs to the items FetchData returns, truncated to
+	// count; unknown topics have no data
+	Data map[int64][]datasource.DataSourceData
+
+	// TopicsFunc, if set, is called by FetchTopics instead of using Topics
+	TopicsFunc func(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error)
+
+	// DataFunc, if set, is called by FetchData instead of using Data
+	DataFunc func(count int, topicID int64) ([]datasource.DataSourceData, error)
+
+	// Errors makes every call to a method fail with the given error;
+	// an error for MethodCheckAvailability makes it return false
+	Errors map[datasource.Method]error
+
+	// Latency delays every call
+	Latency time.Duration
+
+	mu    sync.Mutex
+	calls []Call
+	next  map[datasource.Method][]error
+}
+
+var _ datasource.DataSource = (*Fake)(nil)
+
+// FailNext queues errors to be returned by the next calls to method, one per
+// call, before falling back to normal behavior.
+func (f *Fake) FailNext(method datasource.Method, errs ...error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.next == nil {
+		f.next = make(map[datasource.Method][]error)
+	}
+	f.next[method] = append(f.next[method], errs...)
+}
+
+// Calls returns the calls made so far, in order.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// CallsTo returns the calls made so far to method, in order.
+func (f *Fake) CallsTo(method datasource.Method) []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var calls []Call
+	for _, c := range f.calls {
+		if c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Reset clears recorded calls and queued errors.
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls = nil
+	f.next = nil
+}
+
+// begin records c, waits out Latency, and returns any injected error.
+func (f *Fake) begin(c Call) error {
+	f.mu.Lock()
+	f.calls = append(f.calls, c)
+	err := f.Errors[c.Method]
+	if q := f.next[c.Method]; len(q) > 0 {
+		err, f.next[c.Method] = q[0], q[1:]
+	}
+	f.mu.Unlock()
+
+	if f.Latency > 0 {
+		time.Sleep(f.Latency)
+	}
+	return err
+}
+
+func (f *Fake) Init() error {
+	return f.begin(Call{Method: datasource.MethodInit})
+}
+
+func (f *Fake) CheckAvailability() bool {
+	return f.begin(Call{Method: datasource.MethodCheckAvailability}) == nil
+}
+
+func (f *Fake) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if err := f.begin(Call{Method: datasource.MethodFetchTopics, Count: count, Input: input}); err != nil {
+		return nil, err
+	}
+	if f.TopicsFunc != nil {
+		return f.TopicsFunc(count, input)
+	}
+	return append([]datasource.DataSourceTopic{}, f.Topics[:clamp(count, len(f.Topics))]...), nil
+}
+
+func (f *Fake) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if err := f.begin(Call{Method: datasource.MethodFetchData, Count: count, TopicID: topicID}); err != nil {
+		return nil, err
+	}
+	if f.DataFunc != nil {
+		return f.DataFunc(count, topicID)
+	}
+	data := f.Data[topicID]
+	return append([]datasource.DataSourceData{}, data[:clamp(count, len(data))]...), nil
+}
+
+// clamp limits count to [0, n].
+func clamp(count, n int) int {
+	return max(0, min(count, n))
+}
@@ -0,0 +1,88 @@
+package datasourcetest_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+)
+
+func newFake() *datasourcetest.Fake {
+	return &datasourcetest.Fake{
+		Topics: []datasource.DataSourceTopic{
+			{Topic: "DNS", TopicID: 1},
+			{Topic: "Resolvers", TopicID: 2},
+		},
+		Data: map[int64][]datasource.DataSourceData{
+			1: {{DataText: "a", AnswerID: 10}, {DataText: "b", AnswerID: 11}},
+			2: {{DataText: "c", AnswerID: 20}},
+		},
+	}
+}
+
+func TestFakeConforms(t *testing.T) {
+	datasourcetest.RunConformance(t, newFake())
+}
+
+func TestFakeRecordsCalls(t *testing.T) {
+	fake := newFake()
+	fake.FetchTopics(1, datasource.NewQuestionInput{QuestionText: "what is dns"})
+	fake.FetchData(5, 2)
+
+	calls := fake.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 calls, got %d", len(calls))
+	}
+	if c := calls[0]; c.Method != datasource.MethodFetchTopics || c.Count != 1 || c.Input.QuestionText != "what is dns" {
+		t.Errorf("unexpected FetchTopics call %+v", c)
+	}
+	if c := fake.CallsTo(datasource.MethodFetchData); len(c) != 1 || c[0].TopicID != 2 {
+		t.Errorf("unexpected FetchData calls %+v", c)
+	}
+
+	fake.Reset()
+	if len(fake.Calls()) != 0 {
+		t.Error("Reset did not clear calls")
+	}
+}
+
+func TestFakeInjectsErrors(t *testing.T) {
+	fake := newFake()
+	boom := errors.New("boom")
+	fake.Errors = map[datasource.Method]error{datasource.MethodCheckAvailability: boom}
+	fake.FailNext(datasource.MethodFetchTopics, datasource.ErrUnavailable, boom)
+
+	input := datasource.NewQuestionInput{QuestionText: "q"}
+	if _, err := fake.FetchTopics(5, input); err != datasource.ErrUnavailable {
+		t.Errorf("first call: got %v", err)
+	}
+	if _, err := fake.FetchTopics(5, input); err != boom {
+		t.Errorf("second call: got %v", err)
+	}
+	if topics, err := fake.FetchTopics(5, input); err != nil || len(topics) != 2 {
+		t.Errorf("third call: got %v, %v", topics, err)
+	}
+	if fake.CheckAvailability() {
+		t.Error("CheckAvailability should report the injected error")
+	}
+}
+
+func TestFakeLatencyAndFuncs(t *testing.T) {
+	fake := &datasourcetest.Fake{
+		Latency: 20 * time.Millisecond,
+		DataFunc: func(count int, topicID int64) ([]datasource.DataSourceData, error) {
+			return []datasource.DataSourceData{{AnswerID: topicID}}, nil
+		},
+	}
+
+	start := time.Now()
+	data, err := fake.FetchData(1, 7)
+	if time.Since(start) < fake.Latency {
+		t.Error("latency not applied")
+	}
+	if err != nil || len(data) != 1 || data[0].AnswerID != 7 {
+		t.Errorf("DataFunc not used: %v, %v", data, err)
+	}
+}
@@ -0,0 +1,249 @@
+// Package transporttest checks that the SDK's transports carry a
+// DataSource faithfully: that a source served over HTTP (remote) or the
+// plugin protocol returns the same results and errors to its client as
+// it does in process.
+//
+//	func TestTransports(t *testing.T) {
+//	    transporttest.Run(t, mysource.New(cfg))
+//	}
+//
+// Results are compared by their JSON encoding, after the Topic filling
+// every transport applies (datasource.FillTopics), together with the match
+// estimate FetchTopics records on the Trace (datasource.FetchTopicsPage).
+// Errors are compared by
+// class, retryability, rate limit hint, and message. Transports of the
+// host's own, such as gRPC, are compared by adding them to
+// Config.Transports.
+package transporttest
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/plugin"
+	"github.com/locus-search/datasource-sdk/remote"
+)
+
+// Transport serves a data source and connects a client to it.
+type Transport struct {
+	// Name names the transport's subtest
+	Name string
+
+	// Connect serves ds and returns a client of it, released when the test
+	// ends
+	Connect func(t testing.TB, ds datasource.DataSource) datasource.DataSource
+}
+
+// HTTP serves a source with remote.NewHandler and connects a
+// remote.Client.
+var HTTP = Transport{Name: "HTTP", Connect: func(t testing.TB, ds datasource.DataSource) datasource.DataSource {
+	srv := httptest.NewServer(remote.NewHandler(ds))
+	t.Cleanup(srv.Close)
+	return remote.NewClient(srv.URL + "/")
+}}
+
+// Plugin serves a source with plugin.ServeConn over an in-memory
+// connection and connects a plugin.Client, as a launched plugin would be
+// reached.
+var Plugin = Transport{Name: "Plugin", Connect: func(t testing.TB, ds datasource.DataSource) datasource.DataSource {
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		t.Fatal(err)
+	}
+	hs := plugin.Handshake{Secret: secret}
+	host, guest := net.Pipe()
+	go plugin.ServeConn(ds, guest, hs)
+	c, err := plugin.NewClient(host, hs)
+	if err != nil {
+		t.Fatalf("plugin handshake: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}}
+
+// Config configures the suite.
+type Config struct {
+	// Inputs are the questions compared
+	// Defaults to datasourcetest.DefaultQueries
+	Inputs []datasource.NewQuestionInput
+
+	// Count is the count requested of FetchTopics and FetchData
+	// Defaults to 5
+	Count int
+
+	// TopicIDs are fetched in addition to the topics the Inputs return,
+	// such as topics whose fetch fails
+	// Defaults to -1, a topic expected not to exist
+	TopicIDs []int64
+
+	// Transports are the transports compared
+	// Defaults to HTTP and Plugin
+	Transports []Transport
+
+	// SkipInit skips calling Init, for data sources that are already
+	// initialized
+	SkipInit bool
+}
+
+func (c Config) withDefaults() Config {
+	if len(c.Inputs) == 0 {
+		for _, q := range datasourcetest.DefaultQueries {
+			c.Inputs = append(c.Inputs, datasource.NewQuestionInput{QuestionText: q})
+		}
+	}
+	if c.Count <= 0 {
+		c.Count = 5
+	}
+	if len(c.TopicIDs) == 0 {
+		c.TopicIDs = []int64{-1}
+	}
+	if len(c.Transports) == 0 {
+		c.Transports = []Transport{HTTP, Plugin}
+	}
+	return c
+}
+
+// Run runs the suite against ds with the default Config.
+func Run(t *testing.T, ds datasource.DataSource) {
+	t.Helper()
+	Config{}.Run(t, ds)
+}
+
+// Run compares ds with its client over each transport, each as a subtest.
+// Every difference is reported.
+func (c Config) Run(t *testing.T, ds datasource.DataSource) {
+	t.Helper()
+	c = c.withDefaults()
+	if !c.SkipInit {
+		if err := ds.Init(); err != nil {
+			t.Fatalf("Init: %v", err)
+		}
+	}
+	for _, tr := range c.Transports {
+		tr := tr
+		t.Run(tr.Name, func(t *testing.T) {
+			client := tr.Connect(t, ds)
+			if err := client.Init(); err != nil {
+				t.Fatalf("client Init: %v", err)
+			}
+			for _, err := range compare(ds, client, c) {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// compare calls direct and remote alike and returns their differences.
+func compare(direct, remote datasource.DataSource, c Config) []error {
+	var diffs []error
+	if d, r := direct.CheckAvailability(), remote.CheckAvailability(); d != r {
+		diffs = append(diffs, fmt.Errorf("CheckAvailability = %v, want %v", r, d))
+	}
+
+	topicIDs := append([]int64(nil), c.TopicIDs...)
+	for _, input := range c.Inputs {
+		call := fmt.Sprintf("FetchTopics(%d, %q)", c.Count, input.QuestionText)
+		want, wantErr := datasource.FetchTopicsPage(direct, c.Count, input)
+		got, gotErr := datasource.FetchTopicsPage(remote, c.Count, input)
+		if wantErr != nil || gotErr != nil {
+			if d := compareErrors(call, gotErr, wantErr); d != nil {
+				diffs = append(diffs, d)
+			}
+			continue
+		}
+		if d := compareResults(call, got.Topics, datasource.FillTopics(want.Topics)); d != nil {
+			diffs = append(diffs, d)
+		}
+		if got.TotalEstimate != want.TotalEstimate {
+			diffs = append(diffs, fmt.Errorf("%s over the transport estimated %d matches, want %d", call, got.TotalEstimate, want.TotalEstimate))
+		}
+		for _, t := range want.Topics {
+			topicIDs = append(topicIDs, t.TopicID)
+		}
+	}
+
+	seen := make(map[int64]bool)
+	for _, id := range topicIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		call := fmt.Sprintf("FetchData(%d, %d)", c.Count, id)
+		want, wantErr := direct.FetchData(c.Count, id)
+		got, gotErr := remote.FetchData(c.Count, id)
+		if wantErr != nil || gotErr != nil {
+			if d := compareErrors(call, gotErr, wantErr); d != nil {
+				diffs = append(diffs, d)
+			}
+			continue
+		}
+		if d := compareResults(call, got, want); d != nil {
+			diffs = append(diffs, d)
+		}
+	}
+	return diffs
+}
+
+// compareResults compares the results of calls that succeeded by their
+// JSON encoding.
+func compareResults[T any](call string, got, want []T) error {
+	if want == nil {
+		// Transports do not distinguish nil from empty results.
+		want = []T{}
+	}
+	g, err := json.Marshal(got)
+	if err != nil {
+		return fmt.Errorf("%s: encode result: %w", call, err)
+	}
+	w, err := json.Marshal(want)
+	if err != nil {
+		return fmt.Errorf("%s: encode result: %w", call, err)
+	}
+	if string(g) != string(w) {
+		return fmt.Errorf("%s over the transport differs:\n got: %s\nwant: %s", call, g, w)
+	}
+	return nil
+}
+
+// compareErrors reports how the transport failed to carry the error of a
+// call, or nil.
+func compareErrors(call string, got, want error) error {
+	switch {
+	case want == nil:
+		return fmt.Errorf("%s over the transport failed: %v", call, got)
+	case got == nil:
+		return fmt.Errorf("%s over the transport succeeded, want error %v", call, want)
+	}
+	var diffs []string
+	if g, w := datasource.ErrorClass(got), datasource.ErrorClass(want); g != w {
+		diffs = append(diffs, fmt.Sprintf("class %q, want %q", g, w))
+	}
+	if g, w := datasource.IsRetryable(got), datasource.IsRetryable(want); g != w {
+		diffs = append(diffs, fmt.Sprintf("retryable %v, want %v", g, w))
+	}
+	if g, w := retryAfter(got), retryAfter(want); g != w {
+		diffs = append(diffs, fmt.Sprintf("retry after %s, want %s", g, w))
+	}
+	if !strings.Contains(got.Error(), want.Error()) {
+		diffs = append(diffs, fmt.Sprintf("message %q, want %q", got, want))
+	}
+	if len(diffs) > 0 {
+		return fmt.Errorf("%s over the transport: error %s", call, strings.Join(diffs, ", "))
+	}
+	return nil
+}
+
+// retryAfter formats the rate limit hint of err, or "none".
+func retryAfter(err error) string {
+	if d, ok := datasource.RetryAfter(err); ok {
+		return d.String()
+	}
+	return "none"
+}
@@ -0,0 +1,122 @@
+package transporttest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+)
+
+// Topic IDs whose data fails.
+const (
+	rateLimitedTopic = 2
+	quotaTopic       = 3
+	timeoutTopic     = 4
+)
+
+func newFake() *datasourcetest.Fake {
+	created := time.Date(2026, 3, 4, 5, 6, 7, 0, time.FixedZone("CET", 3600))
+	var md datasource.Metadata
+	md.Set("views", int64(120))
+	md.Set("team", "IT")
+	topics := []datasource.DataSourceTopic{
+		datasource.DataSourceTopic{TopicID: 1, Score: 2.5, Rank: 1, Language: "en", CreatedAt: created, Metadata: md}.
+			Structured("DNS", "Name resolution.", "Networking"),
+		{Topic: "TLS", TopicID: rateLimitedTopic, Rank: 2},
+		{Topic: "Quota", TopicID: quotaTopic, Rank: 3},
+		{Topic: "Slow", TopicID: timeoutTopic, Rank: 4},
+	}
+	return &datasourcetest.Fake{
+		TopicsFunc: func(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+			input.Trace.Estimate(120)
+			return topics[:min(count, len(topics))], nil
+		},
+		DataFunc: func(count int, topicID int64) ([]datasource.DataSourceData, error) {
+			switch topicID {
+			case 1:
+				data := []datasource.DataSourceData{
+					{DataText: "Use **dig**.", ContentType: datasource.ContentMarkdown, AnswerID: 10, Rank: 1, Author: &datasource.Author{Name: "Ada"}},
+					{DataText: "Or nslookup.", AnswerID: 11, Rank: 2, Score: 0.5, UpdatedAt: created},
+				}
+				return data[:min(count, len(data))], nil
+			case rateLimitedTopic:
+				return nil, &datasource.ErrRateLimited{RetryAfter: 3 * time.Second, Err: fmt.Errorf("kb: slow down")}
+			case quotaTopic:
+				return nil, fmt.Errorf("kb: %w", datasource.ErrQuotaExceeded)
+			case timeoutTopic:
+				return nil, fmt.Errorf("kb: %w", context.DeadlineExceeded)
+			}
+			return nil, fmt.Errorf("kb: topic %d: %w", topicID, datasource.ErrNotFound)
+		},
+	}
+}
+
+func TestRun(t *testing.T) {
+	Config{Inputs: []datasource.NewQuestionInput{
+		{QuestionText: "what is dns", Tags: []string{"net"}},
+		{QuestionText: "nothing"},
+	}}.Run(t, newFake())
+}
+
+// alter is a transport changing what the source returns.
+type alter struct {
+	datasource.DataSource
+	data         func([]datasource.DataSourceData, error) ([]datasource.DataSourceData, error)
+	dropEstimate bool
+}
+
+func (a alter) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if a.dropEstimate {
+		input.Trace = nil
+	}
+	return a.DataSource.FetchTopics(count, input)
+}
+
+func (a alter) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	return a.data(a.DataSource.FetchData(count, topicID))
+}
+
+func TestCompareReportsDifferences(t *testing.T) {
+	faithful := func(d []datasource.DataSourceData, err error) ([]datasource.DataSourceData, error) { return d, err }
+	tests := []struct {
+		name         string
+		data         func([]datasource.DataSourceData, error) ([]datasource.DataSourceData, error)
+		dropEstimate bool
+		want         string
+	}{
+		{"faithful", faithful, false, ""},
+		{"dropped estimate", faithful, true, "estimated 0 matches, want 120"},
+		{"dropped field", func(d []datasource.DataSourceData, err error) ([]datasource.DataSourceData, error) {
+			for i := range d {
+				d[i].Author = nil
+			}
+			return d, err
+		}, false, "FetchData(5, 1) over the transport differs"},
+		{"lost class", func(d []datasource.DataSourceData, err error) ([]datasource.DataSourceData, error) {
+			if err != nil {
+				err = fmt.Errorf("%v", err)
+			}
+			return d, err
+		}, false, `class "other", want "rate_limited", retryable false, want true, retry after none, want 3s`},
+		{"swallowed error", func(d []datasource.DataSourceData, err error) ([]datasource.DataSourceData, error) {
+			return d, nil
+		}, false, "succeeded, want error"},
+	}
+	cfg := Config{TopicIDs: []int64{1, rateLimitedTopic}}.withDefaults()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newFake()
+			diffs := compare(fake, alter{DataSource: fake, data: tt.data, dropEstimate: tt.dropEstimate}, cfg)
+			switch {
+			case tt.want == "" && len(diffs) > 0:
+				t.Errorf("unexpected differences: %v", diffs)
+			case tt.want != "" && (len(diffs) == 0 || !strings.Contains(fmt.Sprint(diffs), tt.want)):
+				t.Errorf("differences %v do not mention %q", diffs, tt.want)
+			}
+		})
+	}
+}
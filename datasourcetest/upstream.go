@@ -0,0 +1,288 @@
+package datasourcetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Response is a canned response of an UpstreamServer.
+type Response struct {
+	// Status is the HTTP status
+	// Defaults to 200
+	Status int
+
+	// Header is added to the response's headers
+	// Optional
+	Header http.Header
+
+	// Body is the response body
+	Body string
+}
+
+// JSON returns a 200 response with v encoded as its JSON body. It panics
+// if v cannot be encoded.
+func JSON(v any) Response {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("datasourcetest: JSON: %v", err))
+	}
+	return Response{Header: http.Header{"Content-Type": {"application/json"}}, Body: string(b)}
+}
+
+// UpstreamRequest records one request made to an UpstreamServer.
+type UpstreamRequest struct {
+	// Method is the HTTP method
+	Method string
+
+	// URL is the request's path and query
+	URL *url.URL
+
+	// Header is the request's headers
+	Header http.Header
+
+	// Body is the request's body
+	Body string
+}
+
+// UpstreamServer is a fake HTTP API for testing data sources that call
+// one, without recording real traffic. Routes answer with canned
+// responses or handlers, and the server can paginate, require
+// credentials, and rate limit. Its methods are safe for concurrent use,
+// and it records every request.
+//
+//	up := datasourcetest.NewUpstreamServer(t)
+//	up.RequireHeader("Authorization", "Bearer s3cret")
+//	up.Route("GET /search", datasourcetest.JSON(map[string]any{"items": items}))
+//	up.Pages("/answers", "page", page1, page2)
+//	ds := mysource.New(mysource.Config{URL: up.URL, Token: "s3cret"})
+//
+// Requests matching no route are answered 404.
+type UpstreamServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string]http.HandlerFunc
+	auth     []credential
+	limit    int // requests left before rate limiting, if limited
+	limited  bool
+	retry    time.Duration
+	until    time.Time // when the rate limit resets
+	window   int
+	requests []UpstreamRequest
+}
+
+// credential is a header or query parameter every request must carry.
+type credential struct {
+	header, param, value string
+}
+
+// NewUpstreamServer starts an UpstreamServer with no routes, closed when
+// the test ends.
+func NewUpstreamServer(t testing.TB) *UpstreamServer {
+	s := &UpstreamServer{routes: make(map[string]http.HandlerFunc)}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// Route answers requests matching pattern with resp. A pattern is a path,
+// optionally preceded by a method and a space, as in "GET /search"; a path
+// ending in "/" matches every path under it. The pattern with the longest
+// matching path is used, preferring one with a method, and routing a
+// pattern again replaces its response.
+func (s *UpstreamServer) Route(pattern string, resp Response) {
+	s.RouteFunc(pattern, resp.write)
+}
+
+// RouteFunc answers requests matching pattern, as for Route, with h.
+func (s *UpstreamServer) RouteFunc(pattern string, h http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[pattern] = h
+}
+
+// Pages answers requests matching pattern with one of pages, selected by
+// the query parameter param: page 1, the first, when the parameter is
+// absent, and page n when it is n. Requests for a page past the last are
+// answered 400, to catch sources reading past the end.
+func (s *UpstreamServer) Pages(pattern, param string, pages ...Response) {
+	s.RouteFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		n := 1
+		if v := r.URL.Query().Get(param); v != "" {
+			var err error
+			if n, err = strconv.Atoi(v); err != nil {
+				http.Error(w, fmt.Sprintf("%s %q is not a page number", param, v), http.StatusBadRequest)
+				return
+			}
+		}
+		if n < 1 || n > len(pages) {
+			http.Error(w, fmt.Sprintf("%s %d is out of range", param, n), http.StatusBadRequest)
+			return
+		}
+		pages[n-1].write(w, r)
+	})
+}
+
+// RequireHeader makes requests without the header answer 401, and
+// requests with another value answer 403.
+func (s *UpstreamServer) RequireHeader(name, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth = append(s.auth, credential{header: name, value: value})
+}
+
+// RequireQuery makes requests without the query parameter, such as an API
+// key, answer 401, and requests with another value answer 403.
+func (s *UpstreamServer) RequireQuery(param, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth = append(s.auth, credential{param: param, value: value})
+}
+
+// RateLimit allows n more requests, then answers 429 with a Retry-After
+// header until retryAfter has passed, after which it allows n requests
+// again. With n of 0, every request is answered 429 until ClearRateLimit.
+func (s *UpstreamServer) RateLimit(n int, retryAfter time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limited, s.limit, s.window, s.retry, s.until = true, n, n, retryAfter, time.Time{}
+}
+
+// ClearRateLimit removes the rate limit.
+func (s *UpstreamServer) ClearRateLimit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limited = false
+}
+
+// Requests returns the requests made so far, in order.
+func (s *UpstreamServer) Requests() []UpstreamRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]UpstreamRequest(nil), s.requests...)
+}
+
+// RequestsTo returns the requests made so far to path, in order.
+func (s *UpstreamServer) RequestsTo(path string) []UpstreamRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var requests []UpstreamRequest
+	for _, r := range s.requests {
+		if r.URL.Path == path {
+			requests = append(requests, r)
+		}
+	}
+	return requests
+}
+
+// Reset clears recorded requests.
+func (s *UpstreamServer) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = nil
+}
+
+func (s *UpstreamServer) serve(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	s.mu.Lock()
+	s.requests = append(s.requests, UpstreamRequest{Method: r.Method, URL: r.URL, Header: r.Header.Clone(), Body: string(body)})
+	status, msg := s.admit(r)
+	h := s.match(r)
+	wait := time.Until(s.until)
+	s.mu.Unlock()
+
+	switch {
+	case status == http.StatusTooManyRequests:
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		http.Error(w, msg, status)
+	case status != 0:
+		http.Error(w, msg, status)
+	case h == nil:
+		http.Error(w, "no route for "+r.Method+" "+r.URL.Path, http.StatusNotFound)
+	default:
+		h(w, r)
+	}
+}
+
+// admit checks a request's credentials and the rate limit, returning the
+// status it is refused with, or 0. Callers hold s.mu.
+func (s *UpstreamServer) admit(r *http.Request) (int, string) {
+	for _, c := range s.auth {
+		v, name := r.Header.Get(c.header), c.header
+		if c.param != "" {
+			v, name = r.URL.Query().Get(c.param), c.param
+		}
+		switch {
+		case v == "":
+			return http.StatusUnauthorized, "missing " + name
+		case v != c.value:
+			return http.StatusForbidden, "invalid " + name
+		}
+	}
+	if !s.limited {
+		return 0, ""
+	}
+	if !s.until.IsZero() && !time.Now().Before(s.until) {
+		s.limit, s.until = s.window, time.Time{}
+	}
+	if s.limit > 0 {
+		s.limit--
+		return 0, ""
+	}
+	if s.until.IsZero() {
+		s.until = time.Now().Add(s.retry)
+	}
+	return http.StatusTooManyRequests, "rate limit exceeded"
+}
+
+// match returns the handler of the best pattern matching the request, or
+// nil. Callers hold s.mu.
+func (s *UpstreamServer) match(r *http.Request) http.HandlerFunc {
+	var best http.HandlerFunc
+	bestScore := -1
+	for pattern, h := range s.routes {
+		method, path, ok := strings.Cut(pattern, " ")
+		if !ok {
+			method, path = "", pattern
+		}
+		if method != "" && method != r.Method {
+			continue
+		}
+		if path != r.URL.Path && !(strings.HasSuffix(path, "/") && strings.HasPrefix(r.URL.Path, path)) {
+			continue
+		}
+		score := 2 * len(path)
+		if method != "" {
+			score++
+		}
+		if score > bestScore {
+			best, bestScore = h, score
+		}
+	}
+	return best
+}
+
+// write writes the response.
+func (resp Response) write(w http.ResponseWriter, _ *http.Request) {
+	for k, vs := range resp.Header {
+		for _, v := range vs {
+			w.Header().Add(k, v)
+		}
+	}
+	if resp.Status != 0 {
+		w.WriteHeader(resp.Status)
+	}
+	io.WriteString(w, resp.Body)
+}
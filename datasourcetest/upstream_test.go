@@ -0,0 +1,153 @@
+package datasourcetest_test
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// send makes a request to up and returns the response and its body.
+func send(t *testing.T, up *datasourcetest.UpstreamServer, method, path string, header http.Header) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, up.URL+path, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+	resp, err := up.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp, string(body)
+}
+
+func TestUpstreamRoutes(t *testing.T) {
+	up := datasourcetest.NewUpstreamServer(t)
+	up.Route("/search", datasourcetest.Response{Body: "any"})
+	up.Route("POST /search", datasourcetest.JSON(map[string]int{"n": 1}))
+	up.Route("/items/", datasourcetest.Response{Status: http.StatusGone, Body: "gone"})
+	up.RouteFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(w, r.Body)
+	})
+
+	tests := []struct {
+		method, path string
+		status       int
+		body         string
+	}{
+		{"GET", "/search?q=x", 200, "any"},
+		{"POST", "/search", 200, `{"n":1}`},
+		{"GET", "/items/12", 410, "gone"},
+		{"GET", "/echo", 200, "payload"},
+		{"GET", "/missing", 404, "no route for GET /missing\n"},
+	}
+	for _, tt := range tests {
+		resp, body := send(t, up, tt.method, tt.path, nil)
+		if resp.StatusCode != tt.status || body != tt.body {
+			t.Errorf("%s %s = %d %q, want %d %q", tt.method, tt.path, resp.StatusCode, body, tt.status, tt.body)
+		}
+	}
+	if resp, _ := send(t, up, "POST", "/search", nil); resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("JSON response Content-Type = %q", resp.Header.Get("Content-Type"))
+	}
+
+	if got := up.RequestsTo("/search"); len(got) != 3 || got[0].URL.Query().Get("q") != "x" || got[1].Body != "payload" {
+		t.Errorf("RequestsTo(/search) = %+v", got)
+	}
+	if n := len(up.Requests()); n != 6 {
+		t.Errorf("got %d requests, want 6", n)
+	}
+	up.Reset()
+	if n := len(up.Requests()); n != 0 {
+		t.Errorf("got %d requests after Reset", n)
+	}
+}
+
+func TestUpstreamPages(t *testing.T) {
+	up := datasourcetest.NewUpstreamServer(t)
+	up.Pages("/answers", "page", datasourcetest.Response{Body: "one"}, datasourcetest.Response{Body: "two"})
+	for path, want := range map[string]string{"/answers": "one", "/answers?page=1": "one", "/answers?page=2": "two"} {
+		if _, body := send(t, up, "GET", path, nil); body != want {
+			t.Errorf("%s = %q, want %q", path, body, want)
+		}
+	}
+	for _, path := range []string{"/answers?page=3", "/answers?page=x", "/answers?page=0"} {
+		if resp, _ := send(t, up, "GET", path, nil); resp.StatusCode != http.StatusBadRequest {
+			t.Errorf("%s = %d, want 400", path, resp.StatusCode)
+		}
+	}
+}
+
+func TestUpstreamAuth(t *testing.T) {
+	up := datasourcetest.NewUpstreamServer(t)
+	up.Route("/", datasourcetest.Response{Body: "ok"})
+	up.RequireHeader("Authorization", "Bearer s3cret")
+	up.RequireQuery("key", "k1")
+
+	tests := []struct {
+		path   string
+		auth   string
+		status int
+	}{
+		{"/?key=k1", "Bearer s3cret", 200},
+		{"/?key=k1", "", 401},
+		{"/?key=k1", "Bearer wrong", 403},
+		{"/", "Bearer s3cret", 401},
+		{"/?key=k2", "Bearer s3cret", 403},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.auth != "" {
+			header.Set("Authorization", tt.auth)
+		}
+		resp, _ := send(t, up, "GET", tt.path, header)
+		if resp.StatusCode != tt.status {
+			t.Errorf("%s with %q = %d, want %d", tt.path, tt.auth, resp.StatusCode, tt.status)
+		}
+	}
+}
+
+func TestUpstreamRateLimit(t *testing.T) {
+	up := datasourcetest.NewUpstreamServer(t)
+	up.Route("/", datasourcetest.Response{Body: "ok"})
+	up.RateLimit(2, 30*time.Second)
+	for i := 0; i < 2; i++ {
+		if resp, _ := send(t, up, "GET", "/", nil); resp.StatusCode != http.StatusOK {
+			t.Fatalf("request %d = %d, want 200", i, resp.StatusCode)
+		}
+	}
+	resp, _ := send(t, up, "GET", "/", nil)
+	err := httpx.StatusError(resp)
+	var rl *datasource.ErrRateLimited
+	if !errors.As(err, &rl) || rl.RetryAfter != 30*time.Second {
+		t.Errorf("third request = %v, want rate limited for 30s", err)
+	}
+
+	up.RateLimit(1, 10*time.Millisecond)
+	send(t, up, "GET", "/", nil)
+	if resp, _ := send(t, up, "GET", "/", nil); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("request over the new limit = %d, want 429", resp.StatusCode)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if resp, _ := send(t, up, "GET", "/", nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("request after Retry-After = %d, want 200", resp.StatusCode)
+	}
+
+	up.ClearRateLimit()
+	for i := 0; i < 3; i++ {
+		if resp, _ := send(t, up, "GET", "/", nil); resp.StatusCode != http.StatusOK {
+			t.Errorf("request %d after ClearRateLimit = %d", i, resp.StatusCode)
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package drift detects when an upstream API changes the shape of its
+// responses and a data source starts silently returning degraded results:
+// fields that no longer parse, pages that come back empty, IDs that turn
+// from numbers into strings.
+//
+// A Monitor samples a small fraction of traffic at two points: raw
+// upstream HTTP responses, through Transport, and the parsed results a
+// source returns, through Wrap. Each sample is reduced to features — the
+// JSON paths and value types present in a response body, or how many
+// returned items have each field set — and no values are kept: bodies are
+// stored only as a SHA-256 hash. The first WindowSize samples of each group
+// (an endpoint, or a DataSource method) set its watermark; each later
+// window of WindowSize samples is compared against it, and a feature whose
+// rate falls by Threshold or more raises an Alert.
+//
+//	m := drift.New(drift.Config{Alert: func(a drift.Alert) { log.Print(a) }})
+//	client := &http.Client{Transport: m.Transport(nil)}
+//	ds = drift.Wrap(newSource(client), m)
+//
+// After an intended upstream migration, Reset sets new watermarks.
+package drift
+
+import (
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// Default configuration values used when Config fields are zero.
+const (
+	DefaultRate       = 0.01
+	DefaultWindowSize = 20
+	DefaultThreshold  = 0.3
+	DefaultMaxSamples = 100
+	DefaultMaxBody    = 1 << 20
+)
+
+// Config configures a Monitor.
+type Config struct {
+	// Rate is the fraction of responses and results sampled, between 0
+	// and 1
+	// Defaults to DefaultRate
+	Rate float64
+
+	// WindowSize is the number of samples per group that set the
+	// watermark and that make up each window compared against it
+	// Defaults to DefaultWindowSize
+	WindowSize int
+
+	// Threshold is the drop in a feature's rate, between 0 and 1, that
+	// raises an alert
+	// Defaults to DefaultThreshold
+	Threshold float64
+
+	// MaxSamples bounds the number of raw response samples kept for
+	// inspection; the oldest are dropped first
+	// Defaults to DefaultMaxSamples
+	MaxSamples int
+
+	// MaxBody bounds the bytes of a response body read for a sample;
+	// longer bodies are hashed but their shape is not recorded
+	// Defaults to DefaultMaxBody
+	MaxBody int64
+
+	// Alert is called, without locks held, for every feature that
+	// drifted
+	// Defaults to logging a warning with slog.Default
+	Alert func(Alert)
+}
+
+func (c Config) withDefaults() Config {
+	if c.Rate <= 0 || c.Rate > 1 {
+		c.Rate = DefaultRate
+	}
+	if c.WindowSize <= 0 {
+		c.WindowSize = DefaultWindowSize
+	}
+	if c.Threshold <= 0 || c.Threshold > 1 {
+		c.Threshold = DefaultThreshold
+	}
+	if c.MaxSamples <= 0 {
+		c.MaxSamples = DefaultMaxSamples
+	}
+	if c.MaxBody <= 0 {
+		c.MaxBody = DefaultMaxBody
+	}
+	if c.Alert == nil {
+		c.Alert = func(a Alert) {
+			slog.Warn("drift: feature rate dropped", "group", a.Group, "feature", a.Feature,
+				"watermark", a.Watermark, "current", a.Current)
+		}
+	}
+	return c
+}
+
+// Alert reports a feature whose rate dropped from its watermark.
+type Alert struct {
+	// Group is the endpoint ("GET api.example.com/search") or
+	// DataSource method ("FetchTopics", "FetchTopics items") sampled
+	Group string
+
+	// Feature is what changed: a JSON path and type such as
+	// "$.items[].id:number", or a result field such as "source_url"
+	Feature string
+
+	// Watermark and Current are the feature's rates, between 0 and 1,
+	// in the watermark and in the window that raised the alert
+	Watermark float64
+	Current   float64
+
+	// At is when the window closed
+	At time.Time
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("drift: %s: %s dropped from %.2f to %.2f", a.Group, a.Feature, a.Watermark, a.Current)
+}
+
+// Monitor samples traffic and compares it against watermarks. It is safe
+// for concurrent use.
+type Monitor struct {
+	cfg Config
+
+	mu      sync.Mutex
+	groups  map[string]*group
+	samples []Sample // oldest first
+}
+
+// group accumulates one group's features.
+type group struct {
+	watermark *tally // nil until WindowSize samples were seen
+	window    tally
+}
+
+// tally sums feature values over n samples; features absent from a
+// sample count as zero.
+type tally struct {
+	n    int
+	sums map[string]float64
+}
+
+func (t *tally) add(features map[string]float64) {
+	if t.sums == nil {
+		t.sums = make(map[string]float64)
+	}
+	t.n++
+	for f, v := range features {
+		t.sums[f] += v
+	}
+}
+
+func (t *tally) rate(feature string) float64 {
+	if t.n == 0 {
+		return 0
+	}
+	return t.sums[feature] / float64(t.n)
+}
+
+// New returns a Monitor with no watermarks.
+func New(cfg Config) *Monitor {
+	return &Monitor{cfg: cfg.withDefaults(), groups: make(map[string]*group)}
+}
+
+// sampled reports whether to sample the next response or result.
+func (m *Monitor) sampled() bool {
+	return m.cfg.Rate >= 1 || rand.Float64() < m.cfg.Rate
+}
+
+// observe adds one sample's features to a group, and raises alerts when
+// it closes a window.
+func (m *Monitor) observe(name string, features map[string]float64) {
+	var alerts []Alert
+	m.mu.Lock()
+	g := m.groups[name]
+	if g == nil {
+		g = &group{}
+		m.groups[name] = g
+	}
+	g.window.add(features)
+	if g.window.n >= m.cfg.WindowSize {
+		if g.watermark == nil {
+			w := g.window
+			g.watermark = &w
+		} else {
+			alerts = m.compare(name, g)
+		}
+		g.window = tally{}
+	}
+	m.mu.Unlock()
+	for _, a := range alerts {
+		m.cfg.Alert(a)
+	}
+}
+
+// compare returns an alert for every feature of g's window whose rate
+// fell by Threshold from the watermark. The caller holds m.mu.
+func (m *Monitor) compare(name string, g *group) []Alert {
+	var alerts []Alert
+	at := now()
+	for f := range g.watermark.sums {
+		w, c := g.watermark.rate(f), g.window.rate(f)
+		if w-c >= m.cfg.Threshold-1e-9 {
+			alerts = append(alerts, Alert{Group: name, Feature: f, Watermark: w, Current: c, At: at})
+		}
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].Feature < alerts[j].Feature })
+	return alerts
+}
+
+// Reset discards every watermark and open window, so the next samples set
+// new watermarks. Stored samples are kept.
+func (m *Monitor) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.groups = make(map[string]*group)
+}
+
+// Samples returns the stored raw response samples, oldest first.
+func (m *Monitor) Samples() []Sample {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Sample(nil), m.samples...)
+}
+
+func (m *Monitor) store(s Sample) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.samples) >= m.cfg.MaxSamples {
+		m.samples = m.samples[len(m.samples)-m.cfg.MaxSamples+1:]
+	}
+	m.samples = append(m.samples, s)
+}
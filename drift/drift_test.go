@@ -0,0 +1,181 @@
+package drift_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/drift"
+)
+
+// recorder collects alerts.
+type recorder struct {
+	mu     sync.Mutex
+	alerts []drift.Alert
+}
+
+func (r *recorder) alert(a drift.Alert) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.alerts = append(r.alerts, a)
+}
+
+func (r *recorder) features() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var s []string
+	for _, a := range r.alerts {
+		s = append(s, a.Group+" "+a.Feature)
+	}
+	return s
+}
+
+func TestTransportDetectsShapeDrift(t *testing.T) {
+	var mu sync.Mutex
+	body := `{"items":[{"id":1,"title":"Reset a password"}],"has_more":false}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		io.WriteString(w, body)
+	}))
+	defer srv.Close()
+
+	rec := &recorder{}
+	m := drift.New(drift.Config{Rate: 1, WindowSize: 2, Alert: rec.alert})
+	client := &http.Client{Transport: m.Transport(nil)}
+	get := func() string {
+		t.Helper()
+		resp, err := client.Get(srv.URL + "/questions/12345/answers?page=2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	for i := 0; i < 4; i++ {
+		if got := get(); got != body {
+			t.Fatalf("body = %q, want it passed through unchanged", got)
+		}
+	}
+	if got := rec.features(); len(got) != 0 {
+		t.Fatalf("alerts without drift: %v", got)
+	}
+
+	mu.Lock()
+	body = `{"items":[{"id":"q1","title":"Reset a password"}],"has_more":false}`
+	mu.Unlock()
+	get()
+	get()
+	got := rec.features()
+	if len(got) != 1 || !strings.HasSuffix(got[0], "/questions/{id}/answers $.items[].id:number") {
+		t.Errorf("alerts = %v, want one for $.items[].id:number", got)
+	}
+
+	samples := m.Samples()
+	if len(samples) != 6 {
+		t.Fatalf("got %d samples, want 6", len(samples))
+	}
+	last := samples[len(samples)-1]
+	if len(last.BodyHash) != 64 || strings.Contains(strings.Join(last.Shape, " "), "Reset") {
+		t.Errorf("sample is not redacted: %+v", last)
+	}
+	if want := "$.items[].id:string"; !contains(last.Shape, want) {
+		t.Errorf("shape %v lacks %s", last.Shape, want)
+	}
+}
+
+func TestTransportNotJSON(t *testing.T) {
+	html := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if html {
+			io.WriteString(w, "<html>maintenance</html>")
+			return
+		}
+		io.WriteString(w, `{"ok":true}`)
+	}))
+	defer srv.Close()
+
+	rec := &recorder{}
+	m := drift.New(drift.Config{Rate: 1, WindowSize: 1, Alert: rec.alert})
+	client := &http.Client{Transport: m.Transport(nil)}
+	for _, h := range []bool{false, true} {
+		html = h
+		resp, err := client.Get(srv.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+	}
+	got := strings.Join(rec.features(), ",")
+	if !strings.Contains(got, " json") || !strings.Contains(got, " $.ok:bool") {
+		t.Errorf("alerts = %s, want json and $.ok:bool", got)
+	}
+}
+
+func TestWrapDetectsDegradedResults(t *testing.T) {
+	full := []datasource.DataSourceTopic{
+		{Topic: "a", SourceURL: "https://kb/1", TopicID: 1},
+		{Topic: "b", SourceURL: "https://kb/2", TopicID: 2},
+	}
+	fake := &datasourcetest.Fake{Topics: full}
+	rec := &recorder{}
+	m := drift.New(drift.Config{Rate: 1, WindowSize: 2, Alert: rec.alert})
+	ds := drift.Wrap(fake, m)
+	fetch := func() {
+		t.Helper()
+		if _, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	fetch()
+	fetch()
+	fake.Topics = []datasource.DataSourceTopic{{Topic: "a", TopicID: 1}, {Topic: "b", TopicID: 2}}
+	fetch()
+	fetch()
+	if got := rec.features(); len(got) != 1 || got[0] != "FetchTopics items source_url" {
+		t.Errorf("alerts = %v, want source_url dropped", got)
+	}
+
+	fake.Topics = nil
+	fetch()
+	fetch()
+	if got := rec.features(); len(got) != 2 || got[1] != "FetchTopics results" {
+		t.Errorf("alerts = %v, want results dropped", got)
+	}
+}
+
+func TestReset(t *testing.T) {
+	fake := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "a", SourceURL: "u", TopicID: 1}}}
+	rec := &recorder{}
+	m := drift.New(drift.Config{Rate: 1, WindowSize: 1, Alert: rec.alert})
+	ds := drift.Wrap(fake, m)
+
+	ds.FetchTopics(1, datasource.NewQuestionInput{})
+	m.Reset()
+	fake.Topics = []datasource.DataSourceTopic{{Topic: "a", TopicID: 1}}
+	ds.FetchTopics(1, datasource.NewQuestionInput{})
+	ds.FetchTopics(1, datasource.NewQuestionInput{})
+	if got := rec.features(); len(got) != 0 {
+		t.Errorf("alerts after Reset = %v", got)
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, e := range list {
+		if e == s {
+			return true
+		}
+	}
+	return false
+}
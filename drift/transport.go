@@ -0,0 +1,163 @@
+package drift
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// maxDepth bounds how deep response shapes are recorded.
+const maxDepth = 16
+
+// Sample is a redacted record of one upstream response: its endpoint,
+// status, a hash of its body, and the shape of the body's JSON, without
+// any values.
+type Sample struct {
+	// At is when the response was received
+	At time.Time
+
+	// Endpoint is the request method, host, and path, with numeric and
+	// hexadecimal path segments replaced by "{id}"; the query is dropped
+	Endpoint string
+
+	// Status is the HTTP status code
+	Status int
+
+	// BodyHash is the hex-encoded SHA-256 of the body, or of its first
+	// MaxBody bytes if it is longer
+	BodyHash string
+
+	// Shape lists the JSON paths and value types in the body, sorted;
+	// empty if the body is not JSON or is longer than MaxBody
+	Shape []string
+}
+
+// Transport returns an http.RoundTripper that samples successful
+// responses from base, or http.DefaultTransport if base is nil. The
+// sampled body is buffered and handed on unchanged.
+func (m *Monitor) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &transport{base: base, m: m}
+}
+
+type transport struct {
+	base http.RoundTripper
+	m    *Monitor
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode/100 != 2 || !t.m.sampled() {
+		return resp, err
+	}
+	head, err := io.ReadAll(io.LimitReader(resp.Body, t.m.cfg.MaxBody+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+	if err != nil {
+		return resp, nil // the caller sees the read error itself
+	}
+	t.m.sampleResponse(req, resp.StatusCode, head)
+	return resp, nil
+}
+
+func (m *Monitor) sampleResponse(req *http.Request, status int, body []byte) {
+	truncated := int64(len(body)) > m.cfg.MaxBody
+	if truncated {
+		body = body[:m.cfg.MaxBody]
+	}
+	sum := sha256.Sum256(body)
+	s := Sample{
+		At:       now(),
+		Endpoint: endpoint(req),
+		Status:   status,
+		BodyHash: hex.EncodeToString(sum[:]),
+	}
+	features := make(map[string]float64)
+	var v any
+	if !truncated && json.Unmarshal(body, &v) == nil {
+		paths := make(map[string]bool)
+		shape("$", v, 0, paths)
+		for p := range paths {
+			s.Shape = append(s.Shape, p)
+			features[p] = 1
+		}
+		sort.Strings(s.Shape)
+		features["json"] = 1
+	}
+	m.store(s)
+	if !truncated {
+		m.observe(s.Endpoint, features)
+	}
+}
+
+// shape records the path and type of v and of everything within it.
+// Array elements share the path of their array with "[]" appended.
+func shape(path string, v any, depth int, paths map[string]bool) {
+	var kind string
+	switch v := v.(type) {
+	case map[string]any:
+		kind = "object"
+		if depth < maxDepth {
+			for k, e := range v {
+				shape(path+"."+k, e, depth+1, paths)
+			}
+		}
+	case []any:
+		kind = "array"
+		if depth < maxDepth {
+			for _, e := range v {
+				shape(path+"[]", e, depth+1, paths)
+			}
+		}
+	case string:
+		kind = "string"
+	case float64:
+		kind = "number"
+	case bool:
+		kind = "bool"
+	default:
+		kind = "null"
+	}
+	paths[path+":"+kind] = true
+}
+
+// endpoint identifies the operation a request calls, so that responses
+// of different shapes are not compared with each other.
+func endpoint(req *http.Request) string {
+	segments := strings.Split(req.URL.Path, "/")
+	for i, s := range segments {
+		if isID(s) {
+			segments[i] = "{id}"
+		}
+	}
+	method := req.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+	return method + " " + req.URL.Host + strings.Join(segments, "/")
+}
+
+// isID reports whether a path segment looks like an identifier: all
+// digits, or at least 16 hexadecimal digits.
+func isID(s string) bool {
+	if s == "" {
+		return false
+	}
+	digits, hex := true, true
+	for _, r := range s {
+		digits = digits && unicode.IsDigit(r)
+		hex = hex && strings.ContainsRune("0123456789abcdefABCDEF-", r)
+	}
+	return digits || (hex && len(s) >= 16)
+}
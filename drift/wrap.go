@@ -0,0 +1,97 @@
+package drift
+
+import (
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Wrap returns a DataSource that samples the results of ds's FetchTopics
+// and FetchData calls into m. Each method has two groups: the method
+// itself, with the feature "results" set when a call returns any items,
+// and "<method> items", with the fraction of returned items that have
+// each field set. Errors are not sampled.
+func Wrap(ds datasource.DataSource, m *Monitor) datasource.DataSource {
+	return &driftSource{DataSource: ds, m: m}
+}
+
+type driftSource struct {
+	datasource.DataSource
+	m *Monitor
+}
+
+func (s *driftSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	topics, err := s.DataSource.FetchTopics(count, input)
+	if err == nil && s.m.sampled() {
+		fields := make([]map[string]bool, len(topics))
+		for i, t := range topics {
+			fields[i] = map[string]bool{
+				"topic":      t.Topic != "",
+				"source_url": t.SourceURL != "",
+				"site":       t.Site != "",
+				"topic_id":   t.TopicID != 0,
+				"score":      t.Score != 0,
+				"rank":       t.Rank != 0,
+				"metadata":   len(t.Metadata) > 0,
+				"extra":      len(t.Extra) > 0,
+				"language":   t.Language != "",
+				"embedding":  len(t.Embedding) > 0,
+				"created_at": !t.CreatedAt.IsZero(),
+				"updated_at": !t.UpdatedAt.IsZero(),
+			}
+		}
+		s.m.observeResults(string(datasource.MethodFetchTopics), fields)
+	}
+	return topics, err
+}
+
+func (s *driftSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	data, err := s.DataSource.FetchData(count, topicID)
+	if err == nil && s.m.sampled() {
+		fields := make([]map[string]bool, len(data))
+		for i, d := range data {
+			fields[i] = map[string]bool{
+				"data_text":      d.DataText != "",
+				"content_type":   d.ContentType != "",
+				"source_url":     d.SourceURL != "",
+				"site":           d.Site != "",
+				"answer_id":      d.AnswerID != 0,
+				"score":          d.Score != 0,
+				"rank":           d.Rank != 0,
+				"metadata":       len(d.Metadata) > 0,
+				"extra":          len(d.Extra) > 0,
+				"language":       d.Language != "",
+				"embedding":      len(d.Embedding) > 0,
+				"entities":       len(d.Entities) > 0,
+				"author":         d.Author != nil,
+				"classification": d.Classification != "",
+				"created_at":     !d.CreatedAt.IsZero(),
+				"updated_at":     !d.UpdatedAt.IsZero(),
+			}
+		}
+		s.m.observeResults(string(datasource.MethodFetchData), fields)
+	}
+	return data, err
+}
+
+// observeResults samples one call's result, given which fields each
+// returned item has set.
+func (m *Monitor) observeResults(method string, items []map[string]bool) {
+	if len(items) == 0 {
+		m.observe(method, nil)
+		return
+	}
+	m.observe(method, map[string]float64{"results": 1})
+	rates := make(map[string]float64)
+	for _, fields := range items {
+		for f, set := range fields {
+			if set {
+				rates[f] += 1 / float64(len(items))
+			}
+		}
+	}
+	m.observe(method+" items", rates)
+}
+
+// Unwrap returns the wrapped data source.
+func (s *driftSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
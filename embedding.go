@@ -0,0 +1,111 @@
+package datasource
+
+import (
+	"context"
+	"fmt"
+)
+
+// EmbeddingProvider computes vector embeddings of text. The host hands one
+// to data sources that embed queries or content (see EmbeddingReceiver),
+// so each source need not bundle its own model client. Embed returns one
+// vector per text, in order; the package embedding adds batching and
+// caching to any provider.
+type EmbeddingProvider interface {
+	Embed(ctx context.Context, texts []string) ([][]float64, error)
+}
+
+// EmbeddingProviderFunc adapts a function to the EmbeddingProvider
+// interface.
+type EmbeddingProviderFunc func(ctx context.Context, texts []string) ([][]float64, error)
+
+// Embed calls f(ctx, texts).
+func (f EmbeddingProviderFunc) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	return f(ctx, texts)
+}
+
+// EmbeddingReceiver is an optional interface for data sources that need
+// embeddings. UseEmbeddingProvider is called before Init with the host's
+// provider.
+type EmbeddingReceiver interface {
+	UseEmbeddingProvider(EmbeddingProvider)
+}
+
+// ProvideEmbeddings hands p to every layer of ds that implements
+// EmbeddingReceiver, following Unwrap methods down the decorator chain,
+// and reports whether any layer received it.
+func ProvideEmbeddings(ds DataSource, p EmbeddingProvider) bool {
+	received := false
+	for ; ds != nil; ds = unwrap(ds) {
+		if r, ok := ds.(EmbeddingReceiver); ok {
+			r.UseEmbeddingProvider(p)
+			received = true
+		}
+	}
+	return received
+}
+
+// EmbeddingFor returns the question embedding to search with for a source
+// consuming the named embeddings, most preferred first, as declared in
+// Capabilities.QueryEmbeddings: the first of them present in in.Embeddings,
+// or nil if none is. With no names it returns in.Embedding. It never falls
+// back from named embeddings to in.Embedding, whose vectors come from
+// another model and cannot be compared with the source's.
+func (in NewQuestionInput) EmbeddingFor(names ...string) []float64 {
+	if len(names) == 0 {
+		return in.Embedding
+	}
+	for _, name := range names {
+		if v := in.Embeddings[name]; len(v) > 0 {
+			return v
+		}
+	}
+	return nil
+}
+
+// QueryEmbeddingsOf returns the names in the Capabilities.QueryEmbeddings
+// of the given sources, each once, in the order first seen. These are the
+// embeddings a host should compute for questions sent to the sources.
+func QueryEmbeddingsOf(sources ...DataSource) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, ds := range sources {
+		for _, name := range CapabilitiesOf(ds).QueryEmbeddings {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// EmbedQuestion sets the named embeddings of in that are not already set,
+// embedding its QuestionText with the provider of the same name. Names
+// without a provider are skipped, so sources wanting them fall back to
+// text search.
+func EmbedQuestion(ctx context.Context, in *NewQuestionInput, providers map[string]EmbeddingProvider, names ...string) error {
+	copied := false
+	for _, name := range names {
+		p, ok := providers[name]
+		if !ok || len(in.Embeddings[name]) > 0 {
+			continue
+		}
+		vectors, err := p.Embed(ctx, []string{in.QuestionText})
+		if err != nil {
+			return fmt.Errorf("datasource: embed question with %q: %w", name, err)
+		}
+		if len(vectors) != 1 {
+			return fmt.Errorf("datasource: embed question with %q: got %d vectors, want 1", name, len(vectors))
+		}
+		if !copied {
+			// The map may be shared with copies of the input.
+			embeddings := make(map[string][]float64, len(in.Embeddings)+1)
+			for k, v := range in.Embeddings {
+				embeddings[k] = v
+			}
+			in.Embeddings, copied = embeddings, true
+		}
+		in.Embeddings[name] = vectors[0]
+	}
+	return nil
+}
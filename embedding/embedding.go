@@ -0,0 +1,154 @@
+// Package embedding adds batching and caching to any
+// datasource.EmbeddingProvider:
+//
+//	p = embedding.Cache(embedding.Batch(client, 64), embedding.CacheConfig{})
+//
+// Batch keeps requests within a model's batch limit, and Cache avoids
+// embedding the same text twice, which matters for repeated questions and
+// for content re-fetched across queries.
+//
+// Sources that embed a local corpus can re-embed it in the background when
+// the host's model changes, serving the old vectors until the new ones are
+// complete; see Migrate.
+package embedding
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"sync"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultBatchSize  = 64
+	DefaultMaxEntries = 10000
+)
+
+// Batch returns a provider that splits requests for more than size texts
+// into consecutive calls to p of at most size texts each. A size of zero
+// or less means DefaultBatchSize.
+func Batch(p datasource.EmbeddingProvider, size int) datasource.EmbeddingProvider {
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	return &batcher{p: p, size: size}
+}
+
+type batcher struct {
+	p    datasource.EmbeddingProvider
+	size int
+}
+
+func (b *batcher) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	out := make([][]float64, 0, len(texts))
+	for start := 0; start < len(texts); start += b.size {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch := texts[start:min(start+b.size, len(texts))]
+		vecs, err := embed(ctx, b.p, batch)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vecs...)
+	}
+	return out, nil
+}
+
+// embed calls p and checks that it returned one vector per text.
+func embed(ctx context.Context, p datasource.EmbeddingProvider, texts []string) ([][]float64, error) {
+	vecs, err := p.Embed(ctx, texts)
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != len(texts) {
+		return nil, fmt.Errorf("embedding: provider returned %d vectors for %d texts", len(vecs), len(texts))
+	}
+	return vecs, nil
+}
+
+// CacheConfig configures Cache.
+type CacheConfig struct {
+	// MaxEntries bounds the number of cached vectors; the least recently
+	// used is evicted beyond it
+	// Defaults to DefaultMaxEntries
+	MaxEntries int
+}
+
+// Cache returns a provider that remembers the vectors p returns. Each
+// request embeds only the texts that are not cached, once each however
+// often they repeat, in a single call to p. Vectors are shared between
+// callers and must not be modified.
+func Cache(p datasource.EmbeddingProvider, cfg CacheConfig) datasource.EmbeddingProvider {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = DefaultMaxEntries
+	}
+	return &cached{p: p, cfg: cfg, items: make(map[string]*list.Element), lru: list.New()}
+}
+
+type cached struct {
+	p   datasource.EmbeddingProvider
+	cfg CacheConfig
+
+	mu    sync.Mutex
+	items map[string]*list.Element // text -> element holding *entry
+	lru   *list.List               // front is most recently used
+}
+
+type entry struct {
+	text string
+	vec  []float64
+}
+
+func (c *cached) Embed(ctx context.Context, texts []string) ([][]float64, error) {
+	out := make([][]float64, len(texts))
+	var missing []string
+	pending := make(map[string][]int) // text -> positions in out
+	c.mu.Lock()
+	for i, text := range texts {
+		if el, ok := c.items[text]; ok {
+			c.lru.MoveToFront(el)
+			out[i] = el.Value.(*entry).vec
+			continue
+		}
+		if _, ok := pending[text]; !ok {
+			missing = append(missing, text)
+		}
+		pending[text] = append(pending[text], i)
+	}
+	c.mu.Unlock()
+	if len(missing) == 0 {
+		return out, nil
+	}
+
+	vecs, err := embed(ctx, c.p, missing)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for j, text := range missing {
+		for _, i := range pending[text] {
+			out[i] = vecs[j]
+		}
+		c.store(text, vecs[j])
+	}
+	return out, nil
+}
+
+func (c *cached) store(text string, vec []float64) {
+	if el, ok := c.items[text]; ok {
+		el.Value.(*entry).vec = vec
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.items[text] = c.lru.PushFront(&entry{text: text, vec: vec})
+	for c.lru.Len() > c.cfg.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.items, oldest.Value.(*entry).text)
+	}
+}
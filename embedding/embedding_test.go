@@ -0,0 +1,83 @@
+package embedding_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/embedding"
+)
+
+// lengths embeds each text as its length and records the batches it saw.
+type lengths struct{ batches [][]string }
+
+func (l *lengths) Embed(_ context.Context, texts []string) ([][]float64, error) {
+	l.batches = append(l.batches, append([]string(nil), texts...))
+	out := make([][]float64, len(texts))
+	for i, t := range texts {
+		out[i] = []float64{float64(len(t))}
+	}
+	return out, nil
+}
+
+func flat(vecs [][]float64) []float64 {
+	var out []float64
+	for _, v := range vecs {
+		out = append(out, v...)
+	}
+	return out
+}
+
+func TestBatch(t *testing.T) {
+	l := &lengths{}
+	vecs, err := embedding.Batch(l, 2).Embed(context.Background(), []string{"a", "bb", "ccc", "dddd", "e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := flat(vecs); len(got) != 5 || got[0] != 1 || got[3] != 4 || got[4] != 1 {
+		t.Errorf("vectors = %v", got)
+	}
+	if len(l.batches) != 3 || len(l.batches[0]) != 2 || len(l.batches[2]) != 1 {
+		t.Errorf("batches = %v, want sizes 2, 2, 1", l.batches)
+	}
+}
+
+func TestBatchChecksVectorCount(t *testing.T) {
+	short := datasource.EmbeddingProviderFunc(func(context.Context, []string) ([][]float64, error) {
+		return [][]float64{{1}}, nil
+	})
+	if _, err := embedding.Batch(short, 0).Embed(context.Background(), []string{"a", "b"}); err == nil ||
+		!strings.Contains(err.Error(), "1 vectors for 2 texts") {
+		t.Errorf("Embed = %v, want a vector count error", err)
+	}
+}
+
+func TestCache(t *testing.T) {
+	l := &lengths{}
+	p := embedding.Cache(l, embedding.CacheConfig{MaxEntries: 2})
+	ctx := context.Background()
+
+	vecs, err := p.Embed(ctx, []string{"a", "bb", "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := flat(vecs); len(got) != 3 || got[0] != 1 || got[1] != 2 || got[2] != 1 {
+		t.Errorf("vectors = %v", got)
+	}
+	if len(l.batches) != 1 || strings.Join(l.batches[0], ",") != "a,bb" {
+		t.Errorf("batches = %v, want one call embedding each text once", l.batches)
+	}
+
+	if _, err := p.Embed(ctx, []string{"bb", "ccc"}); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.batches) != 2 || strings.Join(l.batches[1], ",") != "ccc" {
+		t.Errorf("batches = %v, want only ccc embedded", l.batches)
+	}
+	// "a" was least recently used and evicted when "ccc" was stored.
+	p.Embed(ctx, []string{"a", "bb"})
+	if len(l.batches) != 3 || strings.Join(l.batches[2], ",") != "a" {
+		t.Errorf("batches = %v, want a re-embedded", l.batches)
+	}
+}
@@ -0,0 +1,297 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// DefaultMigrationRate is the number of texts a Migration embeds per
+// second when MigrationConfig.Rate is zero.
+const DefaultMigrationRate = 50
+
+// maxPasses bounds the passes a Migration makes over a corpus that keeps
+// changing while it is re-embedded.
+const maxPasses = 5
+
+// Document is a text of a Corpus.
+type Document struct {
+	// ID identifies the document in the corpus
+	ID int64
+
+	// Text is the text embedded
+	Text string
+}
+
+// Corpus is the embedded content of a local data source, such as an index
+// built at Init, that can be re-embedded with another model while it keeps
+// serving the vectors of the current one: a migration stages the new
+// vectors beside the current ones and cuts over once every document has
+// one. Implementations must be safe for concurrent use.
+type Corpus interface {
+	// Model returns the model of the vectors being served
+	Model() string
+
+	// Documents returns the documents of the corpus
+	Documents(ctx context.Context) ([]Document, error)
+
+	// Stage stores vectors computed with model, by document ID, beside
+	// the vectors being served. Vectors of documents removed meanwhile
+	// may be staged, and need not be kept
+	Stage(model string, vectors map[int64][]float64) error
+
+	// Cutover starts serving the vectors staged for model and drops the
+	// previous ones; questions must be embedded with model from then on
+	Cutover(model string) error
+
+	// Discard drops the vectors staged for model
+	Discard(model string)
+}
+
+// MigrationConfig configures a Migration.
+type MigrationConfig struct {
+	// Model names the model to migrate to
+	Model string
+
+	// Provider computes embeddings with Model
+	Provider datasource.EmbeddingProvider
+
+	// BatchSize is the number of texts embedded per request
+	// Defaults to DefaultBatchSize
+	BatchSize int
+
+	// Rate bounds the texts embedded per second, so that a migration does
+	// not starve questions of the provider's quota; a negative rate is
+	// unlimited
+	// Defaults to DefaultMigrationRate
+	Rate float64
+
+	// OnProgress, if set, is called with the status after each batch
+	OnProgress func(MigrationStatus)
+}
+
+// MigrationState is the state of a Migration.
+type MigrationState string
+
+// Migration states.
+const (
+	MigrationRunning  MigrationState = "running"
+	MigrationDone     MigrationState = "done"
+	MigrationFailed   MigrationState = "failed"
+	MigrationCanceled MigrationState = "canceled"
+)
+
+// MigrationStatus describes the progress of a Migration.
+type MigrationStatus struct {
+	// From is the model the corpus was served with when the migration
+	// started
+	From string `json:"from"`
+
+	// To is the model migrated to
+	To string `json:"to"`
+
+	// State is the state of the migration
+	State MigrationState `json:"state"`
+
+	// Embedded is the number of documents re-embedded so far
+	Embedded int `json:"embedded"`
+
+	// Total is the number of documents of the corpus, as of the last pass
+	Total int `json:"total"`
+
+	// Started is when the migration started
+	Started time.Time `json:"started"`
+
+	// Finished is when the migration ended, zero while it runs
+	Finished time.Time `json:"finished,omitempty"`
+
+	// Error is why the migration failed
+	Error string `json:"error,omitempty"`
+}
+
+// Migration re-embeds a Corpus with a new model in the background.
+type Migration struct {
+	corpus Corpus
+	cfg    MigrationConfig
+	cancel context.CancelFunc
+	done   chan struct{}
+	next   time.Time // when the next batch may start
+
+	mu     sync.Mutex
+	status MigrationStatus
+	err    error
+}
+
+// Migrate starts re-embedding corpus with cfg.Model in the background,
+// unless the corpus is already served with it. Documents are embedded in
+// batches at most cfg.Rate texts a second and staged; documents added or
+// changed meanwhile are embedded in further passes, and once every
+// document has a new vector the corpus cuts over to them. If the
+// migration fails or is canceled, the staged vectors are discarded and the
+// corpus keeps serving the current ones.
+func Migrate(corpus Corpus, cfg MigrationConfig) *Migration {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = DefaultBatchSize
+	}
+	if cfg.Rate == 0 {
+		cfg.Rate = DefaultMigrationRate
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &Migration{
+		corpus: corpus,
+		cfg:    cfg,
+		cancel: cancel,
+		done:   make(chan struct{}),
+		status: MigrationStatus{From: corpus.Model(), To: cfg.Model, State: MigrationRunning, Started: time.Now()},
+	}
+	go m.run(ctx)
+	return m
+}
+
+// Status returns the migration's progress.
+func (m *Migration) Status() MigrationStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.status
+}
+
+// Cancel stops the migration, discarding the vectors staged so far. It
+// does not wait for the migration to stop; see Wait.
+func (m *Migration) Cancel() {
+	m.cancel()
+}
+
+// Wait waits for the migration to end or ctx to be done, and returns why
+// the migration failed, if it did.
+func (m *Migration) Wait(ctx context.Context) error {
+	select {
+	case <-m.done:
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		return m.err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (m *Migration) run(ctx context.Context) {
+	defer close(m.done)
+	defer m.cancel()
+	err := m.migrate(ctx)
+	state := MigrationDone
+	switch {
+	case err != nil && ctx.Err() != nil:
+		state = MigrationCanceled
+	case err != nil:
+		state = MigrationFailed
+	}
+	if err != nil {
+		m.corpus.Discard(m.cfg.Model)
+	}
+	m.mu.Lock()
+	m.status.State, m.status.Finished, m.err = state, time.Now(), err
+	if err != nil {
+		m.status.Error = err.Error()
+	}
+	status := m.status
+	m.mu.Unlock()
+	if m.cfg.OnProgress != nil {
+		m.cfg.OnProgress(status)
+	}
+}
+
+// migrate embeds every document until a pass finds none left, then cuts
+// over.
+func (m *Migration) migrate(ctx context.Context) error {
+	if m.cfg.Model == "" || m.cfg.Provider == nil {
+		return errors.New("embedding: migration needs a model and a provider")
+	}
+	if m.corpus.Model() == m.cfg.Model {
+		return nil
+	}
+	staged := make(map[int64]string) // texts embedded, by document ID
+	for pass := 0; pass < maxPasses; pass++ {
+		docs, err := m.corpus.Documents(ctx)
+		if err != nil {
+			return fmt.Errorf("embedding: migration: %w", err)
+		}
+		var pending []Document
+		for _, d := range docs {
+			if text, ok := staged[d.ID]; !ok || text != d.Text {
+				pending = append(pending, d)
+			}
+		}
+		m.mu.Lock()
+		m.status.Total = len(docs)
+		m.mu.Unlock()
+		if len(pending) == 0 {
+			if err := m.corpus.Cutover(m.cfg.Model); err != nil {
+				return fmt.Errorf("embedding: migration: cutover: %w", err)
+			}
+			return nil
+		}
+		for start := 0; start < len(pending); start += m.cfg.BatchSize {
+			batch := pending[start:min(start+m.cfg.BatchSize, len(pending))]
+			if err := m.embed(ctx, batch); err != nil {
+				return err
+			}
+			for _, d := range batch {
+				staged[d.ID] = d.Text
+			}
+		}
+	}
+	return fmt.Errorf("embedding: migration: corpus still changing after %d passes", maxPasses)
+}
+
+// embed embeds and stages a batch of documents, once the rate allows.
+func (m *Migration) embed(ctx context.Context, batch []Document) error {
+	if err := m.pace(ctx, len(batch)); err != nil {
+		return err
+	}
+	texts := make([]string, len(batch))
+	for i, d := range batch {
+		texts[i] = d.Text
+	}
+	vecs, err := embed(ctx, m.cfg.Provider, texts)
+	if err != nil {
+		return fmt.Errorf("embedding: migration: %w", err)
+	}
+	vectors := make(map[int64][]float64, len(batch))
+	for i, d := range batch {
+		vectors[d.ID] = vecs[i]
+	}
+	if err := m.corpus.Stage(m.cfg.Model, vectors); err != nil {
+		return fmt.Errorf("embedding: migration: stage: %w", err)
+	}
+	m.mu.Lock()
+	m.status.Embedded += len(batch)
+	status := m.status
+	m.mu.Unlock()
+	if m.cfg.OnProgress != nil {
+		m.cfg.OnProgress(status)
+	}
+	return nil
+}
+
+// pace waits until a batch of n texts may start, and reserves the time it
+// takes at the configured rate.
+func (m *Migration) pace(ctx context.Context, n int) error {
+	if m.cfg.Rate < 0 {
+		return ctx.Err()
+	}
+	if wait := time.Until(m.next); wait > 0 {
+		t := time.NewTimer(wait)
+		defer t.Stop()
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	m.next = time.Now().Add(time.Duration(float64(n) / m.cfg.Rate * float64(time.Second)))
+	return ctx.Err()
+}
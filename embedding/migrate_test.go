@@ -0,0 +1,165 @@
+package embedding_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/embedding"
+)
+
+// corpus serves vectors of one model and stages those of others.
+type corpus struct {
+	mu        sync.Mutex
+	model     string
+	docs      map[int64]string
+	served    map[int64][]float64
+	staged    map[string]map[int64][]float64
+	discarded []string
+}
+
+func newCorpus(model string, texts ...string) *corpus {
+	c := &corpus{model: model, docs: make(map[int64]string), served: make(map[int64][]float64), staged: make(map[string]map[int64][]float64)}
+	for i, t := range texts {
+		c.docs[int64(i+1)] = t
+	}
+	return c
+}
+
+func (c *corpus) Model() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.model
+}
+
+func (c *corpus) Documents(context.Context) ([]embedding.Document, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var docs []embedding.Document
+	for id, text := range c.docs {
+		docs = append(docs, embedding.Document{ID: id, Text: text})
+	}
+	return docs, nil
+}
+
+func (c *corpus) add(id int64, text string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.docs[id] = text
+}
+
+func (c *corpus) Stage(model string, vectors map[int64][]float64) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.staged[model] == nil {
+		c.staged[model] = make(map[int64][]float64)
+	}
+	for id, v := range vectors {
+		c.staged[model][id] = v
+	}
+	return nil
+}
+
+func (c *corpus) Cutover(model string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id := range c.docs {
+		if c.staged[model][id] == nil {
+			return errors.New("incomplete")
+		}
+	}
+	c.model, c.served = model, c.staged[model]
+	delete(c.staged, model)
+	return nil
+}
+
+func (c *corpus) Discard(model string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.staged, model)
+	c.discarded = append(c.discarded, model)
+}
+
+func TestMigrate(t *testing.T) {
+	c := newCorpus("v1", "alpha", "beta", "gamma", "delta", "epsilon")
+	provider := &lengths{}
+	var progress []embedding.MigrationStatus
+	m := embedding.Migrate(c, embedding.MigrationConfig{
+		Model:     "v2",
+		Provider:  provider,
+		BatchSize: 2,
+		Rate:      -1,
+		OnProgress: func(s embedding.MigrationStatus) {
+			if len(progress) == 0 {
+				c.add(6, "zeta") // added while the migration runs
+				c.add(1, "alpha, revised")
+			}
+			progress = append(progress, s)
+		},
+	})
+	if err := m.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	s := m.Status()
+	if s.State != embedding.MigrationDone || s.From != "v1" || s.To != "v2" || s.Total != 6 || s.Embedded != 7 || s.Finished.IsZero() {
+		t.Errorf("status = %+v", s)
+	}
+	if c.Model() != "v2" || len(c.served) != 6 || c.served[1][0] != float64(len("alpha, revised")) {
+		t.Errorf("corpus serves %s with %v", c.model, c.served)
+	}
+	if last := progress[len(progress)-1]; last.State != embedding.MigrationDone {
+		t.Errorf("last progress = %+v", last)
+	}
+}
+
+func TestMigrateSameModel(t *testing.T) {
+	c := newCorpus("v1", "alpha")
+	provider := &lengths{}
+	m := embedding.Migrate(c, embedding.MigrationConfig{Model: "v1", Provider: provider})
+	if err := m.Wait(context.Background()); err != nil || m.Status().State != embedding.MigrationDone || len(provider.batches) != 0 {
+		t.Errorf("Wait = %v, status %+v, %d batches", err, m.Status(), len(provider.batches))
+	}
+}
+
+func TestMigrateFailureKeepsModel(t *testing.T) {
+	c := newCorpus("v1", "alpha", "beta")
+	failing := datasource.EmbeddingProviderFunc(func(context.Context, []string) ([][]float64, error) {
+		return nil, errors.New("quota exceeded")
+	})
+	m := embedding.Migrate(c, embedding.MigrationConfig{Model: "v2", Provider: failing, Rate: -1})
+	if err := m.Wait(context.Background()); err == nil {
+		t.Fatal("Wait succeeded")
+	}
+	if s := m.Status(); s.State != embedding.MigrationFailed || s.Error == "" {
+		t.Errorf("status = %+v", s)
+	}
+	if c.Model() != "v1" || len(c.discarded) != 1 || len(c.staged) != 0 {
+		t.Errorf("corpus = %s, discarded %v, staged %v", c.model, c.discarded, c.staged)
+	}
+}
+
+func TestMigrateRateAndCancel(t *testing.T) {
+	c := newCorpus("v1", "alpha", "beta", "gamma")
+	start := time.Now()
+	m := embedding.Migrate(c, embedding.MigrationConfig{Model: "v2", Provider: &lengths{}, BatchSize: 1, Rate: 20})
+	if err := m.Wait(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 90*time.Millisecond {
+		t.Errorf("3 texts at 20 per second took %v", d)
+	}
+
+	c = newCorpus("v1", "alpha", "beta", "gamma")
+	m = embedding.Migrate(c, embedding.MigrationConfig{Model: "v2", Provider: &lengths{}, BatchSize: 1, Rate: 1})
+	m.Cancel()
+	if err := m.Wait(context.Background()); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait = %v, want context.Canceled", err)
+	}
+	if m.Status().State != embedding.MigrationCanceled || c.Model() != "v1" {
+		t.Errorf("status = %+v, corpus model %s", m.Status(), c.Model())
+	}
+}
@@ -0,0 +1,87 @@
+package datasource_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+type embeddingSource struct {
+	ExampleDataSource
+	provider datasource.EmbeddingProvider
+}
+
+func (s *embeddingSource) UseEmbeddingProvider(p datasource.EmbeddingProvider) { s.provider = p }
+
+func TestProvideEmbeddings(t *testing.T) {
+	p := datasource.EmbeddingProviderFunc(func(_ context.Context, texts []string) ([][]float64, error) {
+		return make([][]float64, len(texts)), nil
+	})
+	src := &embeddingSource{}
+	if !datasource.ProvideEmbeddings(passthrough{src}, p) || src.provider == nil {
+		t.Error("expected the wrapped source to receive the provider")
+	}
+	if datasource.ProvideEmbeddings(&ExampleDataSource{}, p) {
+		t.Error("expected no receiver")
+	}
+}
+
+func TestEmbeddingFor(t *testing.T) {
+	in := datasource.NewQuestionInput{
+		Embedding:  []float64{1, 0},
+		Embeddings: map[string][]float64{"general": {1, 0}, "code": {0, 1, 0}},
+	}
+	for _, tt := range []struct {
+		names []string
+		want  []float64
+	}{
+		{nil, in.Embedding},
+		{[]string{"code", "general"}, in.Embeddings["code"]},
+		{[]string{"legal", "general"}, in.Embeddings["general"]},
+		{[]string{"legal"}, nil},
+	} {
+		if got := in.EmbeddingFor(tt.names...); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("EmbeddingFor(%q) = %v, want %v", tt.names, got, tt.want)
+		}
+	}
+}
+
+func TestEmbedQuestion(t *testing.T) {
+	code := &capableSource{caps: datasource.Capabilities{Embeddings: true, QueryEmbeddings: []string{"code", "general"}}}
+	prose := &capableSource{caps: datasource.Capabilities{Embeddings: true, QueryEmbeddings: []string{"general"}}}
+	names := datasource.QueryEmbeddingsOf(passthrough{code}, prose, &ExampleDataSource{})
+	if !reflect.DeepEqual(names, []string{"code", "general"}) {
+		t.Fatalf("QueryEmbeddingsOf = %q", names)
+	}
+
+	var embedded []string
+	provider := func(name string, v float64) datasource.EmbeddingProvider {
+		return datasource.EmbeddingProviderFunc(func(_ context.Context, texts []string) ([][]float64, error) {
+			embedded = append(embedded, name+":"+texts[0])
+			return [][]float64{{v}}, nil
+		})
+	}
+	shared := map[string][]float64{"general": {9}}
+	in := datasource.NewQuestionInput{QuestionText: "nil pointer in handler", Embeddings: shared}
+	providers := map[string]datasource.EmbeddingProvider{"code": provider("code", 1), "general": provider("general", 2)}
+	if err := datasource.EmbedQuestion(context.Background(), &in, providers, append(names, "legal")...); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(embedded, []string{"code:nil pointer in handler"}) {
+		t.Errorf("embedded %q; the general embedding was already set", embedded)
+	}
+	if !reflect.DeepEqual(in.Embeddings, map[string][]float64{"code": {1}, "general": {9}}) || len(shared) != 1 {
+		t.Errorf("Embeddings = %v; shared map %v", in.Embeddings, shared)
+	}
+
+	failing := datasource.EmbeddingProviderFunc(func(context.Context, []string) ([][]float64, error) {
+		return nil, datasource.ErrUnavailable
+	})
+	in = datasource.NewQuestionInput{QuestionText: "q"}
+	if err := datasource.EmbedQuestion(context.Background(), &in, map[string]datasource.EmbeddingProvider{"code": failing}, "code"); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("EmbedQuestion with a failing provider = %v", err)
+	}
+}
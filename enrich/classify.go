@@ -0,0 +1,70 @@
+package enrich
+
+import (
+	"regexp"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Classifier labels how sensitive a data item is. Implementations may
+// match patterns locally (see Rules) or call an external DLP service.
+type Classifier interface {
+	// Classify returns the item's classification.
+	Classify(item datasource.DataSourceData) (datasource.Classification, error)
+}
+
+// ClassifierFunc adapts an ordinary function to the Classifier interface.
+type ClassifierFunc func(item datasource.DataSourceData) (datasource.Classification, error)
+
+// Classify calls f(item).
+func (f ClassifierFunc) Classify(item datasource.DataSourceData) (datasource.Classification, error) {
+	return f(item)
+}
+
+// Classify returns a stage that sets the Classification of every data item
+// using c. A classification already set by the source is only ever made
+// stricter, never relaxed.
+func Classify(c Classifier) Stage {
+	return StageFunc(func(items []datasource.DataSourceData) error {
+		for i := range items {
+			class, err := c.Classify(items[i])
+			if err != nil {
+				return err
+			}
+			items[i].Classification = items[i].Classification.Stricter(class)
+		}
+		return nil
+	})
+}
+
+// Rule assigns Class to data items whose DataText matches Pattern.
+type Rule struct {
+	Class   datasource.Classification
+	Pattern *regexp.Regexp
+}
+
+// Rules is a Classifier that applies every rule to an item's text and
+// returns the strictest matching class, or Default if none match. A Rules
+// is safe for concurrent use.
+type Rules struct {
+	// Rules are the patterns to match
+	Rules []Rule
+
+	// Default is the class of items no rule matches
+	// Defaults to datasource.ClassificationSafe
+	Default datasource.Classification
+}
+
+// Classify implements Classifier.
+func (r *Rules) Classify(item datasource.DataSourceData) (datasource.Classification, error) {
+	class := r.Default
+	if class == "" {
+		class = datasource.ClassificationSafe
+	}
+	for _, rule := range r.Rules {
+		if rule.Pattern.MatchString(item.DataText) {
+			class = class.Stricter(rule.Class)
+		}
+	}
+	return class, nil
+}
@@ -0,0 +1,106 @@
+// Package enrich provides optional post-processing stages that add derived
+// information to data items after they are fetched from a data source.
+//
+// Stages run in order on the slice returned by FetchData and may modify the
+// items in place. Wrap attaches a set of stages to any DataSource:
+//
+//	ds = enrich.Wrap(ds, enrich.Entities(glossary), enrich.Classify(rules))
+package enrich
+
+import (
+	"fmt"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// Stage is a single enrichment step applied to fetched data items.
+type Stage interface {
+	// Enrich modifies items in place. Returning an error aborts the
+	// remaining stages and fails the FetchData call.
+	Enrich(items []datasource.DataSourceData) error
+}
+
+// StageFunc adapts an ordinary function to the Stage interface.
+type StageFunc func(items []datasource.DataSourceData) error
+
+// Enrich calls f(items).
+func (f StageFunc) Enrich(items []datasource.DataSourceData) error {
+	return f(items)
+}
+
+// Wrap returns a DataSource that runs the given stages, in order, on every
+// FetchData result of ds. All other methods are passed through unchanged.
+func Wrap(ds datasource.DataSource, stages ...Stage) datasource.DataSource {
+	return &enrichedSource{DataSource: ds, stages: stages}
+}
+
+type enrichedSource struct {
+	datasource.DataSource
+	stages []Stage
+}
+
+func (s *enrichedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	items, err := s.DataSource.FetchData(count, topicID)
+	if err != nil {
+		return items, err
+	}
+	for i, stage := range s.stages {
+		if err := stage.Enrich(items); err != nil {
+			return nil, fmt.Errorf("enrich: stage %d: %w", i, err)
+		}
+	}
+	return items, nil
+}
+
+// Unwrap returns the wrapped data source.
+func (s *enrichedSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
+
+// Text returns a stage that passes the DataText of every data item through
+// each of fns in order, such as content.Normalize:
+//
+//	ds = enrich.Wrap(ds, enrich.Text(content.Normalize))
+func Text(fns ...func(string) string) Stage {
+	return StageFunc(func(items []datasource.DataSourceData) error {
+		for i := range items {
+			for _, fn := range fns {
+				items[i].DataText = fn(items[i].DataText)
+			}
+		}
+		return nil
+	})
+}
+
+// Clean returns a stage that applies the cleanup passes c selects to the
+// DataText of every data item, according to its ContentType.
+func Clean(c content.Cleanup) Stage {
+	return StageFunc(func(items []datasource.DataSourceData) error {
+		for i := range items {
+			items[i].DataText = c.Clean(items[i].DataText, items[i].ContentType)
+		}
+		return nil
+	})
+}
+
+// ConvertHTML returns a stage that converts the DataText of HTML data items
+// to Markdown, or to plain text if to is datasource.ContentPlainText, with
+// tables linearized rather than run together. Items of undeclared type are
+// converted if content.Detect finds HTML; other items are left alone.
+func ConvertHTML(to datasource.ContentType) Stage {
+	convert, t := content.HTMLToMarkdown, datasource.ContentMarkdown
+	if to == datasource.ContentPlainText {
+		convert, t = content.StripHTML, datasource.ContentPlainText
+	}
+	return StageFunc(func(items []datasource.DataSourceData) error {
+		for i := range items {
+			d := &items[i]
+			if d.ContentType == datasource.ContentHTML || d.ContentType == "" && content.Detect(d.DataText) == datasource.ContentHTML {
+				d.DataText = convert(d.DataText)
+				d.ContentType = t
+			}
+		}
+		return nil
+	})
+}
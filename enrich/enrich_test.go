@@ -0,0 +1,146 @@
+package enrich_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+	"github.com/locus-search/datasource-sdk/enrich"
+)
+
+type staticSource struct {
+	data []datasource.DataSourceData
+}
+
+func (s *staticSource) Init() error             { return nil }
+func (s *staticSource) CheckAvailability() bool { return true }
+func (s *staticSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	return []datasource.DataSourceTopic{}, nil
+}
+func (s *staticSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	return s.data, nil
+}
+
+func TestGlossaryLink(t *testing.T) {
+	g := enrich.NewGlossary([]enrich.GlossaryEntry{
+		{ID: "k8s", Name: "Kubernetes", Aliases: []string{"k8s"}},
+		{ID: "go", Name: "Go", URL: "https://go.dev"},
+		{ID: "go-modules", Name: "Go modules"},
+	})
+
+	tests := []struct {
+		name string
+		text string
+		want []string // IDs in order
+	}{
+		{"single", "Deploy it on Kubernetes.", []string{"k8s"}},
+		{"alias case insensitive", "K8S and kubernetes", []string{"k8s", "k8s"}},
+		{"longest match wins", "Use Go modules with Go", []string{"go-modules", "go"}},
+		{"word boundaries", "Google and ago are not Go", []string{"go"}},
+		{"none", "nothing to see", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := g.Link(tt.text)
+			if err != nil {
+				t.Fatalf("Link failed: %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d entities %+v, want %v", len(got), got, tt.want)
+			}
+			for i, e := range got {
+				if e.ID != tt.want[i] {
+					t.Errorf("entity %d: got ID %q, want %q", i, e.ID, tt.want[i])
+				}
+				if tt.text[e.Offset:e.Offset+len(e.Mention)] != e.Mention {
+					t.Errorf("entity %d: offset %d does not point at mention %q", i, e.Offset, e.Mention)
+				}
+			}
+		})
+	}
+}
+
+func TestWrapRunsStages(t *testing.T) {
+	src := &staticSource{data: []datasource.DataSourceData{
+		{DataText: "Run go vet before pushing", AnswerID: 1},
+		{DataText: "No entities here", AnswerID: 2},
+	}}
+	g := enrich.NewGlossary([]enrich.GlossaryEntry{{ID: "go", Name: "Go"}})
+	ds := enrich.Wrap(src, enrich.Entities(g))
+
+	data, err := ds.FetchData(5, 1)
+	if err != nil {
+		t.Fatalf("FetchData failed: %v", err)
+	}
+	if len(data[0].Entities) != 1 || data[0].Entities[0].Mention != "go" {
+		t.Errorf("unexpected entities on first item: %+v", data[0].Entities)
+	}
+	if len(data[1].Entities) != 0 {
+		t.Errorf("unexpected entities on second item: %+v", data[1].Entities)
+	}
+}
+
+func TestWrapStageError(t *testing.T) {
+	src := &staticSource{data: []datasource.DataSourceData{{DataText: "x"}}}
+	boom := errors.New("boom")
+	ds := enrich.Wrap(src, enrich.StageFunc(func([]datasource.DataSourceData) error { return boom }))
+
+	if _, err := ds.FetchData(1, 1); !errors.Is(err, boom) {
+		t.Errorf("expected stage error, got %v", err)
+	}
+}
+
+func TestText(t *testing.T) {
+	src := &staticSource{data: []datasource.DataSourceData{{DataText: " a "}, {DataText: "b"}}}
+	ds := enrich.Wrap(src, enrich.Text(strings.TrimSpace, strings.ToUpper))
+
+	data, err := ds.FetchData(5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0].DataText != "A" || data[1].DataText != "B" {
+		t.Errorf("texts = %q, %q; want the functions applied in order", data[0].DataText, data[1].DataText)
+	}
+}
+
+func TestClean(t *testing.T) {
+	src := &staticSource{data: []datasource.DataSourceData{
+		{DataText: "a &amp;  b"},
+		{DataText: "a &amp;  b", ContentType: datasource.ContentCode},
+	}}
+	ds := enrich.Wrap(src, enrich.Clean(content.Cleanup{Entities: true, Whitespace: true}))
+
+	data, err := ds.FetchData(5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data[0].DataText != "a & b" || data[1].DataText != "a &amp;  b" {
+		t.Errorf("texts = %q, %q; want text cleaned and code kept", data[0].DataText, data[1].DataText)
+	}
+}
+
+func TestConvertHTML(t *testing.T) {
+	table := "<table><tr><th>OS</th><th>Arch</th></tr><tr><td>linux</td><td>arm64</td></tr></table>"
+	src := &staticSource{data: []datasource.DataSourceData{
+		{DataText: table, ContentType: datasource.ContentHTML},
+		{DataText: table},
+		{DataText: "<b>not html</b>", ContentType: datasource.ContentCode},
+	}}
+	ds := enrich.Wrap(src, enrich.ConvertHTML(datasource.ContentPlainText))
+
+	data, err := ds.FetchData(5, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range data[:2] {
+		if d.DataText != "OS: linux\nArch: arm64" || d.ContentType != datasource.ContentPlainText {
+			t.Errorf("converted = %q (%s), want key-value plain text", d.DataText, d.ContentType)
+		}
+	}
+	if data[2].DataText != "<b>not html</b>" {
+		t.Errorf("code = %q, want it unchanged", data[2].DataText)
+	}
+}
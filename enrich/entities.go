@@ -0,0 +1,132 @@
+package enrich
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Linker finds mentions of canonical entities in text. Implementations may
+// use a local glossary (see Glossary) or look mentions up in an external
+// knowledge base such as Wikidata.
+type Linker interface {
+	// Link returns the entities mentioned in text, ordered by Offset.
+	Link(text string) ([]datasource.Entity, error)
+}
+
+// Entities returns a stage that sets the Entities field of every data item
+// to the mentions found in its DataText by l. Existing entities are
+// replaced.
+func Entities(l Linker) Stage {
+	return StageFunc(func(items []datasource.DataSourceData) error {
+		for i := range items {
+			entities, err := l.Link(items[i].DataText)
+			if err != nil {
+				return err
+			}
+			items[i].Entities = entities
+		}
+		return nil
+	})
+}
+
+// GlossaryEntry describes one canonical entity known to a Glossary.
+type GlossaryEntry struct {
+	// ID is the canonical identifier reported in Entity.ID
+	ID string
+
+	// Name is the canonical display name; it is also matched as a mention
+	Name string
+
+	// URL optionally points to a page describing the entity
+	URL string
+
+	// Aliases are additional surface forms that refer to the entity
+	Aliases []string
+}
+
+// Glossary is a Linker backed by an in-memory list of terms. Matching is
+// case-insensitive, respects word boundaries, and prefers the longest term
+// when several overlap. A Glossary is safe for concurrent use.
+type Glossary struct {
+	terms []glossaryTerm
+}
+
+type glossaryTerm struct {
+	surface string
+	entry   *GlossaryEntry
+}
+
+// NewGlossary builds a Glossary from entries. Empty surface forms are
+// ignored.
+func NewGlossary(entries []GlossaryEntry) *Glossary {
+	g := &Glossary{}
+	for i := range entries {
+		e := &entries[i]
+		for _, s := range append([]string{e.Name}, e.Aliases...) {
+			if s = strings.TrimSpace(s); s != "" {
+				g.terms = append(g.terms, glossaryTerm{surface: s, entry: e})
+			}
+		}
+	}
+	sort.SliceStable(g.terms, func(i, j int) bool {
+		return len(g.terms[i].surface) > len(g.terms[j].surface)
+	})
+	return g
+}
+
+// Link implements Linker.
+func (g *Glossary) Link(text string) ([]datasource.Entity, error) {
+	var entities []datasource.Entity
+	for i := 0; i < len(text); {
+		if i > 0 && isWordRune(lastRune(text[:i])) {
+			_, size := utf8.DecodeRuneInString(text[i:])
+			i += size
+			continue
+		}
+		if t, ok := g.matchAt(text, i); ok {
+			entities = append(entities, datasource.Entity{
+				ID:      t.entry.ID,
+				Name:    t.entry.Name,
+				URL:     t.entry.URL,
+				Mention: text[i : i+len(t.surface)],
+				Offset:  i,
+			})
+			i += len(t.surface)
+			continue
+		}
+		_, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+	}
+	return entities, nil
+}
+
+// matchAt returns the longest term that matches text at byte offset i and
+// ends on a word boundary.
+func (g *Glossary) matchAt(text string, i int) (glossaryTerm, bool) {
+	for _, t := range g.terms {
+		end := i + len(t.surface)
+		if end > len(text) || !strings.EqualFold(text[i:end], t.surface) {
+			continue
+		}
+		if end < len(text) {
+			if r, _ := utf8.DecodeRuneInString(text[end:]); isWordRune(r) {
+				continue
+			}
+		}
+		return t, true
+	}
+	return glossaryTerm{}, false
+}
+
+func lastRune(s string) rune {
+	r, _ := utf8.DecodeLastRuneInString(s)
+	return r
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
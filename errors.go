@@ -0,0 +1,120 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Sentinel errors that data sources should wrap (with fmt.Errorf and %w) or
+// return directly so the host can react to the kind of failure instead of
+// treating every error the same way. Use errors.Is to test for them.
+var (
+	// ErrUnavailable indicates the external source is temporarily
+	// unreachable or returned a server-side failure (e.g., HTTP 5xx).
+	// Retrying later may succeed.
+	ErrUnavailable = errors.New("datasource: source unavailable")
+
+	// ErrUnauthorized indicates the credentials used by the data source
+	// were missing, invalid, or lack permission (e.g., HTTP 401/403).
+	ErrUnauthorized = errors.New("datasource: unauthorized")
+
+	// ErrNotFound indicates the requested topic or data does not exist in
+	// the external source (e.g., HTTP 404 or a deleted topic ID).
+	ErrNotFound = errors.New("datasource: not found")
+
+	// ErrQuotaExceeded indicates a long-term quota (daily or monthly) has
+	// been exhausted. Unlike ErrRateLimited it is not expected to clear
+	// within the lifetime of a single request.
+	ErrQuotaExceeded = errors.New("datasource: quota exceeded")
+)
+
+// ErrRateLimited indicates the external source throttled the request
+// (e.g., HTTP 429). RetryAfter carries the delay requested by the source,
+// if any; zero means the source gave no hint.
+//
+// Use errors.As to extract it:
+//
+//	var rl *datasource.ErrRateLimited
+//	if errors.As(err, &rl) {
+//	    time.Sleep(rl.RetryAfter)
+//	}
+type ErrRateLimited struct {
+	// RetryAfter is how long the source asked callers to wait
+	RetryAfter time.Duration
+
+	// Err is an optional underlying error with more detail
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ErrRateLimited) Error() string {
+	msg := "datasource: rate limited"
+	if e.RetryAfter > 0 {
+		msg += fmt.Sprintf(" (retry after %s)", e.RetryAfter)
+	}
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns the underlying error, if any.
+func (e *ErrRateLimited) Unwrap() error {
+	return e.Err
+}
+
+// IsRetryable reports whether err represents a transient failure that may
+// succeed if the same call is repeated later. Rate limiting and
+// unavailability are retryable; authorization failures, missing items, and
+// exhausted quotas are not. Unclassified errors are treated as not
+// retryable.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	var rl *ErrRateLimited
+	if errors.As(err, &rl) {
+		return true
+	}
+	return errors.Is(err, ErrUnavailable)
+}
+
+// RetryAfter returns the delay requested by the source if err is (or wraps)
+// an ErrRateLimited with a positive RetryAfter.
+func RetryAfter(err error) (time.Duration, bool) {
+	var rl *ErrRateLimited
+	if errors.As(err, &rl) && rl.RetryAfter > 0 {
+		return rl.RetryAfter, true
+	}
+	return 0, false
+}
+
+// ErrorClass returns a short, stable label for the kind of err, suitable
+// for metric labels and structured logs: "rate_limited", "unavailable",
+// "unauthorized", "not_found", "quota_exceeded", "canceled",
+// "deadline_exceeded", or "other". It returns "" for a nil error.
+func ErrorClass(err error) string {
+	var rl *ErrRateLimited
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &rl):
+		return "rate_limited"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrNotFound):
+		return "not_found"
+	case errors.Is(err, ErrQuotaExceeded):
+		return "quota_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	default:
+		return "other"
+	}
+}
@@ -0,0 +1,85 @@
+package datasource_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"nil", nil, false},
+		{"rate limited", &datasource.ErrRateLimited{RetryAfter: time.Second}, true},
+		{"wrapped rate limited", fmt.Errorf("search: %w", &datasource.ErrRateLimited{}), true},
+		{"unavailable", datasource.ErrUnavailable, true},
+		{"wrapped unavailable", fmt.Errorf("http 503: %w", datasource.ErrUnavailable), true},
+		{"unauthorized", datasource.ErrUnauthorized, false},
+		{"not found", datasource.ErrNotFound, false},
+		{"quota exceeded", datasource.ErrQuotaExceeded, false},
+		{"unclassified", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := datasource.IsRetryable(tt.err); got != tt.want {
+				t.Errorf("IsRetryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	err := fmt.Errorf("fetch: %w", &datasource.ErrRateLimited{RetryAfter: 3 * time.Second})
+	if d, ok := datasource.RetryAfter(err); !ok || d != 3*time.Second {
+		t.Errorf("RetryAfter = %v, %v; want 3s, true", d, ok)
+	}
+
+	if _, ok := datasource.RetryAfter(&datasource.ErrRateLimited{}); ok {
+		t.Error("expected no hint when RetryAfter is zero")
+	}
+	if _, ok := datasource.RetryAfter(datasource.ErrUnavailable); ok {
+		t.Error("expected no hint for non rate-limit errors")
+	}
+}
+
+func TestErrRateLimitedUnwrap(t *testing.T) {
+	cause := errors.New("HTTP 429")
+	err := &datasource.ErrRateLimited{RetryAfter: 2 * time.Second, Err: cause}
+
+	if !errors.Is(err, cause) {
+		t.Error("expected ErrRateLimited to unwrap to its cause")
+	}
+	if got, want := err.Error(), "datasource: rate limited (retry after 2s): HTTP 429"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
+
+func TestErrorClass(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{fmt.Errorf("x: %w", &datasource.ErrRateLimited{}), "rate_limited"},
+		{datasource.ErrUnavailable, "unavailable"},
+		{datasource.ErrUnauthorized, "unauthorized"},
+		{datasource.ErrNotFound, "not_found"},
+		{datasource.ErrQuotaExceeded, "quota_exceeded"},
+		{context.Canceled, "canceled"},
+		{fmt.Errorf("slow: %w", context.DeadlineExceeded), "deadline_exceeded"},
+		{errors.New("boom"), "other"},
+	}
+	for _, tt := range tests {
+		if got := datasource.ErrorClass(tt.err); got != tt.want {
+			t.Errorf("ErrorClass(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package datasource
+
+import "context"
+
+// MatchEstimator is an optional interface for data sources that can tell
+// how many of their topics match a question in all, beyond the top ones
+// FetchTopics returns, so the host can tell the asker how much relevant
+// material exists and decide whether to page deeper. The count may be
+// approximate, such as a search engine's hit count. Implementations must
+// stop promptly when ctx is done.
+//
+// Sources that learn the count from their search response should also
+// record it on the input's Trace with Trace.Estimate, so query reports
+// carry it without a second request.
+type MatchEstimator interface {
+	EstimateMatches(ctx context.Context, input NewQuestionInput) (int64, error)
+}
+
+// MatchEstimatorOf returns the first MatchEstimator in ds's decorator
+// chain (following Unwrap methods), and false if there is none.
+func MatchEstimatorOf(ds DataSource) (MatchEstimator, bool) {
+	return find[MatchEstimator](ds)
+}
+
+// TopicPage is the topics found for a question together with about how
+// many match it in all, so the host can tell the asker how much lies
+// beyond the page.
+type TopicPage struct {
+	Topics []DataSourceTopic `json:"topics"`
+
+	// TotalEstimate is about how many topics match the question in all
+	// Optional - zero if the source did not estimate
+	TotalEstimate int64 `json:"total_estimate,omitempty"`
+}
+
+// FetchTopicsPage calls ds.FetchTopics and returns the topics with the
+// estimate ds recorded on the input's Trace (see Trace.Estimate), giving
+// the input a Trace if it has none. Multiplexers such as router.Router
+// record the sum of their sources' estimates.
+func FetchTopicsPage(ds DataSource, count int, input NewQuestionInput) (TopicPage, error) {
+	if input.Trace == nil {
+		input.Trace = new(Trace)
+	}
+	topics, err := ds.FetchTopics(count, input)
+	if err != nil {
+		return TopicPage{}, err
+	}
+	var r SourceReport
+	input.Trace.Fill(&r)
+	return TopicPage{Topics: topics, TotalEstimate: r.TotalEstimate}, nil
+}
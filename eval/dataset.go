@@ -0,0 +1,148 @@
+// Package eval defines the relevance dataset format used to measure the
+// retrieval quality of data sources, along with tools to build datasets.
+//
+// A dataset is stored as JSON Lines: one Query per line, each carrying the
+// graded relevance judgments a reviewer assigned to the topics returned for
+// it. The format is append-friendly and diffs cleanly in version control.
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Grade is a graded relevance judgment.
+type Grade int
+
+// Relevance grades, from worst to best.
+const (
+	GradeIrrelevant Grade = 0
+	GradePartial    Grade = 1
+	GradeRelevant   Grade = 2
+	GradePerfect    Grade = 3
+)
+
+// Judgment is the relevance grade given to one topic returned for a query.
+type Judgment struct {
+	// TopicID identifies the topic in its data source
+	TopicID int64 `json:"topic_id"`
+
+	// SourceURL is the canonical URL of the topic, which stays meaningful if
+	// topic IDs change
+	SourceURL string `json:"source_url"`
+
+	// Topic is the topic title at labeling time, for human readers
+	Topic string `json:"topic,omitempty"`
+
+	// Grade is the assigned relevance
+	Grade Grade `json:"grade"`
+}
+
+// Query is a question with its relevance judgments.
+type Query struct {
+	// Question is the question text sent to the data source
+	Question string `json:"question"`
+
+	// Tags are optional question tags
+	Tags []string `json:"tags,omitempty"`
+
+	// Source is the name of the data source the judgments apply to
+	Source string `json:"source,omitempty"`
+
+	// Judgments are the graded topics
+	Judgments []Judgment `json:"judgments"`
+}
+
+// Dataset is an ordered collection of labeled queries.
+type Dataset struct {
+	Queries []Query
+}
+
+// Find returns the query with the given question and source, or nil.
+func (d *Dataset) Find(question, source string) *Query {
+	for i := range d.Queries {
+		if d.Queries[i].Question == question && d.Queries[i].Source == source {
+			return &d.Queries[i]
+		}
+	}
+	return nil
+}
+
+// Put replaces the query with the same question and source, or appends q.
+func (d *Dataset) Put(q Query) {
+	if existing := d.Find(q.Question, q.Source); existing != nil {
+		*existing = q
+		return
+	}
+	d.Queries = append(d.Queries, q)
+}
+
+// Read parses a dataset in JSON Lines format. Blank lines are ignored.
+func Read(r io.Reader) (*Dataset, error) {
+	d := &Dataset{}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		text := strings.TrimSpace(sc.Text())
+		if text == "" {
+			continue
+		}
+		var q Query
+		if err := json.Unmarshal([]byte(text), &q); err != nil {
+			return nil, fmt.Errorf("eval: line %d: %w", line, err)
+		}
+		d.Queries = append(d.Queries, q)
+	}
+	return d, sc.Err()
+}
+
+// Write encodes the dataset in JSON Lines format.
+func (d *Dataset) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	for _, q := range d.Queries {
+		if q.Judgments == nil {
+			q.Judgments = []Judgment{}
+		}
+		if err := enc.Encode(q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LoadFile reads a dataset from path. A missing file yields an empty
+// dataset.
+func LoadFile(path string) (*Dataset, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return &Dataset{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Read(f)
+}
+
+// SaveFile writes the dataset to path atomically by writing a temporary file
+// in the same directory and renaming it into place.
+func (d *Dataset) SaveFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".dataset-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := d.Write(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
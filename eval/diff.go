@@ -0,0 +1,115 @@
+package eval
+
+import (
+	"strconv"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// RankChange describes one topic whose presence or position differs between
+// two ranked result lists. Ranks are zero-based; -1 means absent.
+type RankChange struct {
+	// Key identifies the topic: its SourceURL, or its TopicID if no URL
+	Key string `json:"key"`
+
+	// Topic is the topic title
+	Topic string `json:"topic"`
+
+	// RankA is the position in the first list
+	RankA int `json:"rank_a"`
+
+	// RankB is the position in the second list
+	RankB int `json:"rank_b"`
+}
+
+// QueryDiff compares the results two data sources returned for a question.
+type QueryDiff struct {
+	Question string       `json:"question"`
+	Added    []RankChange `json:"added,omitempty"`
+	Removed  []RankChange `json:"removed,omitempty"`
+	Moved    []RankChange `json:"moved,omitempty"`
+
+	// LatencyA and LatencyB are the FetchTopics durations
+	LatencyA time.Duration `json:"latency_a"`
+	LatencyB time.Duration `json:"latency_b"`
+
+	// ErrA and ErrB hold FetchTopics error messages, if any
+	ErrA string `json:"error_a,omitempty"`
+	ErrB string `json:"error_b,omitempty"`
+}
+
+// Changed reports whether the two result lists differ in any way.
+func (d QueryDiff) Changed() bool {
+	return len(d.Added)+len(d.Removed)+len(d.Moved) > 0 || d.ErrA != d.ErrB
+}
+
+// topicKey identifies a topic across sources and configurations.
+func topicKey(t datasource.DataSourceTopic) string {
+	if t.SourceURL != "" {
+		return t.SourceURL
+	}
+	return strconv.FormatInt(t.TopicID, 10)
+}
+
+// DiffTopics compares two ranked topic lists. Topics only in b are added,
+// topics only in a are removed, and topics in both at different positions
+// are moved.
+func DiffTopics(a, b []datasource.DataSourceTopic) (added, removed, moved []RankChange) {
+	rankA := make(map[string]int, len(a))
+	for i, t := range a {
+		if _, dup := rankA[topicKey(t)]; !dup {
+			rankA[topicKey(t)] = i
+		}
+	}
+	rankB := make(map[string]int, len(b))
+	for i, t := range b {
+		k := topicKey(t)
+		if _, dup := rankB[k]; dup {
+			continue
+		}
+		rankB[k] = i
+		ra, ok := rankA[k]
+		switch {
+		case !ok:
+			added = append(added, RankChange{Key: k, Topic: t.Topic, RankA: -1, RankB: i})
+		case ra != i:
+			moved = append(moved, RankChange{Key: k, Topic: t.Topic, RankA: ra, RankB: i})
+		}
+	}
+	for i, t := range a {
+		k := topicKey(t)
+		if _, ok := rankB[k]; !ok && rankA[k] == i {
+			removed = append(removed, RankChange{Key: k, Topic: t.Topic, RankA: i, RankB: -1})
+		}
+	}
+	return added, removed, moved
+}
+
+// Compare runs every question through both data sources, fetching count
+// topics from each, and reports how the ranked results differ.
+func Compare(a, b datasource.DataSource, questions []string, count int) []QueryDiff {
+	diffs := make([]QueryDiff, len(questions))
+	for i, q := range questions {
+		input := datasource.NewQuestionInput{QuestionText: q}
+		d := QueryDiff{Question: q}
+
+		start := time.Now()
+		topicsA, errA := a.FetchTopics(count, input)
+		d.LatencyA = time.Since(start)
+
+		start = time.Now()
+		topicsB, errB := b.FetchTopics(count, input)
+		d.LatencyB = time.Since(start)
+
+		if errA != nil {
+			d.ErrA = errA.Error()
+		}
+		if errB != nil {
+			d.ErrB = errB.Error()
+		}
+		d.Added, d.Removed, d.Moved = DiffTopics(topicsA, topicsB)
+		diffs[i] = d
+	}
+	return diffs
+}
@@ -0,0 +1,147 @@
+package eval_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/eval"
+)
+
+type topicSource struct{ calls int }
+
+func (s *topicSource) Init() error             { return nil }
+func (s *topicSource) CheckAvailability() bool { return true }
+func (s *topicSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	s.calls++
+	return []datasource.DataSourceTopic{
+		{Topic: "<b>" + input.QuestionText + "</b>", SourceURL: "https://example.com/1", TopicID: 1},
+		{Topic: "Other", SourceURL: "https://example.com/2", TopicID: 2},
+	}, nil
+}
+func (s *topicSource) FetchData(int, int64) ([]datasource.DataSourceData, error) {
+	return []datasource.DataSourceData{}, nil
+}
+
+func TestDatasetRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ds.jsonl")
+	d := &eval.Dataset{}
+	d.Put(eval.Query{Question: "q1", Source: "wiki", Judgments: []eval.Judgment{{TopicID: 1, Grade: eval.GradePerfect}}})
+	d.Put(eval.Query{Question: "q2", Source: "wiki"})
+	d.Put(eval.Query{Question: "q1", Source: "wiki", Judgments: []eval.Judgment{{TopicID: 2, Grade: eval.GradePartial}}})
+	if err := d.SaveFile(path); err != nil {
+		t.Fatalf("SaveFile failed: %v", err)
+	}
+
+	got, err := eval.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	if len(got.Queries) != 2 {
+		t.Fatalf("expected 2 queries, got %d", len(got.Queries))
+	}
+	if j := got.Find("q1", "wiki").Judgments; len(j) != 1 || j[0].TopicID != 2 {
+		t.Errorf("expected Put to replace q1 judgments, got %+v", j)
+	}
+
+	if _, err := eval.Read(strings.NewReader("{not json}\n")); err == nil || !strings.Contains(err.Error(), "line 1") {
+		t.Errorf("expected a line-numbered parse error, got %v", err)
+	}
+	if empty, err := eval.LoadFile(filepath.Join(t.TempDir(), "missing.jsonl")); err != nil || len(empty.Queries) != 0 {
+		t.Errorf("expected an empty dataset for a missing file, got %v, %v", empty, err)
+	}
+}
+
+func TestLabelerFlow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ds.jsonl")
+	src := &topicSource{}
+	l, err := eval.NewLabeler(src, "wiki", []string{"reset password", "vpn setup"}, path, 5)
+	if err != nil {
+		t.Fatalf("NewLabeler failed: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/query?i=0", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), "&lt;b&gt;reset password&lt;/b&gt;") {
+		t.Fatalf("expected escaped topic in page, got %d:\n%s", rec.Code, rec.Body.String())
+	}
+
+	form := url.Values{"grade_1": {"3"}, "grade_2": {"0"}}
+	req := httptest.NewRequest(http.MethodPost, "/query?i=0", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, req)
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/query?i=1" {
+		t.Fatalf("expected redirect to next query, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	if src.calls != 1 {
+		t.Errorf("expected topics to be fetched once per query, got %d calls", src.calls)
+	}
+
+	d, err := eval.LoadFile(path)
+	if err != nil {
+		t.Fatalf("LoadFile failed: %v", err)
+	}
+	q := d.Find("reset password", "wiki")
+	if q == nil || len(q.Judgments) != 2 || q.Judgments[0].Grade != eval.GradePerfect || q.Judgments[0].SourceURL != "https://example.com/1" {
+		t.Errorf("unexpected saved query: %+v", q)
+	}
+
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/query?i=0", nil))
+	if !strings.Contains(rec.Body.String(), `name="grade_1" value="3" checked`) {
+		t.Error("expected saved grade to be preselected")
+	}
+
+	bad := httptest.NewRequest(http.MethodPost, "/query?i=1", strings.NewReader("grade_1=9"))
+	bad.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, bad)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an out-of-range grade, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/query?i=7", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown query, got %d", rec.Code)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Errorf("dataset file missing: %v", err)
+	}
+}
+
+func TestDiffTopics(t *testing.T) {
+	topic := func(url string) datasource.DataSourceTopic {
+		return datasource.DataSourceTopic{Topic: url, SourceURL: url}
+	}
+	a := []datasource.DataSourceTopic{topic("u1"), topic("u2"), topic("u3")}
+	b := []datasource.DataSourceTopic{topic("u2"), topic("u1"), topic("u4")}
+
+	added, removed, moved := eval.DiffTopics(a, b)
+	if len(added) != 1 || added[0].Key != "u4" || added[0].RankA != -1 || added[0].RankB != 2 {
+		t.Errorf("unexpected added %+v", added)
+	}
+	if len(removed) != 1 || removed[0].Key != "u3" || removed[0].RankB != -1 {
+		t.Errorf("unexpected removed %+v", removed)
+	}
+	if len(moved) != 2 || moved[0].Key != "u2" || moved[0].RankA != 1 || moved[0].RankB != 0 {
+		t.Errorf("unexpected moved %+v", moved)
+	}
+
+	if a, r, m := eval.DiffTopics(a, a); len(a)+len(r)+len(m) != 0 {
+		t.Error("identical lists must not differ")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	diffs := eval.Compare(&topicSource{}, &topicSource{}, []string{"q1", "q2"}, 5)
+	if len(diffs) != 2 || diffs[0].Changed() || diffs[1].Question != "q2" {
+		t.Errorf("unexpected diffs %+v", diffs)
+	}
+}
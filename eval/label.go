@@ -0,0 +1,226 @@
+package eval
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"sync"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Labeler is an http.Handler serving a small web UI for building a dataset:
+// it shows each query with the topics a data source returns for it and lets
+// a reviewer assign relevance grades, saving the dataset after every
+// submission. It is intended to be served on a loopback address.
+type Labeler struct {
+	source  datasource.DataSource
+	name    string
+	count   int
+	path    string
+	queries []string
+
+	mu      sync.Mutex
+	dataset *Dataset
+	results map[int][]datasource.DataSourceTopic
+}
+
+// NewLabeler creates a Labeler for the given questions. Judgments are read
+// from and saved to the dataset file at path, tagged with the source name;
+// count is the number of topics fetched per question.
+func NewLabeler(ds datasource.DataSource, name string, questions []string, path string, count int) (*Labeler, error) {
+	d, err := LoadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Labeler{
+		source:  ds,
+		name:    name,
+		count:   count,
+		path:    path,
+		queries: questions,
+		dataset: d,
+		results: make(map[int][]datasource.DataSourceTopic),
+	}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (l *Labeler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.URL.Path {
+	case "/":
+		l.serveIndex(w)
+	case "/query":
+		i, err := strconv.Atoi(r.URL.Query().Get("i"))
+		if err != nil || i < 0 || i >= len(l.queries) {
+			http.NotFound(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet:
+			l.serveQuery(w, i)
+		case http.MethodPost:
+			l.submit(w, r, i)
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		}
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+type indexRow struct {
+	Index    int
+	Question string
+	Labeled  int
+}
+
+func (l *Labeler) serveIndex(w http.ResponseWriter) {
+	l.mu.Lock()
+	rows := make([]indexRow, len(l.queries))
+	for i, q := range l.queries {
+		rows[i] = indexRow{Index: i, Question: q}
+		if existing := l.dataset.Find(q, l.name); existing != nil {
+			rows[i].Labeled = len(existing.Judgments)
+		}
+	}
+	l.mu.Unlock()
+	render(w, indexTemplate, struct {
+		Source string
+		Rows   []indexRow
+	}{l.name, rows})
+}
+
+type topicRow struct {
+	datasource.DataSourceTopic
+	Grade   Grade
+	Labeled bool
+}
+
+func (l *Labeler) serveQuery(w http.ResponseWriter, i int) {
+	topics, err := l.topics(i)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	l.mu.Lock()
+	grades := make(map[int64]Grade)
+	if existing := l.dataset.Find(l.queries[i], l.name); existing != nil {
+		for _, j := range existing.Judgments {
+			grades[j.TopicID] = j.Grade
+		}
+	}
+	l.mu.Unlock()
+
+	rows := make([]topicRow, len(topics))
+	for n, t := range topics {
+		g, labeled := grades[t.TopicID]
+		rows[n] = topicRow{DataSourceTopic: t, Grade: g, Labeled: labeled}
+	}
+	render(w, queryTemplate, struct {
+		Index    int
+		Question string
+		Topics   []topicRow
+		Grades   []Grade
+	}{i, l.queries[i], rows, []Grade{GradeIrrelevant, GradePartial, GradeRelevant, GradePerfect}})
+}
+
+func (l *Labeler) submit(w http.ResponseWriter, r *http.Request, i int) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	topics, err := l.topics(i)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+
+	q := Query{Question: l.queries[i], Source: l.name, Judgments: []Judgment{}}
+	for _, t := range topics {
+		v := r.PostForm.Get(fmt.Sprintf("grade_%d", t.TopicID))
+		if v == "" {
+			continue
+		}
+		g, err := strconv.Atoi(v)
+		if err != nil || g < int(GradeIrrelevant) || g > int(GradePerfect) {
+			http.Error(w, fmt.Sprintf("invalid grade %q", v), http.StatusBadRequest)
+			return
+		}
+		q.Judgments = append(q.Judgments, Judgment{TopicID: t.TopicID, SourceURL: t.SourceURL, Topic: t.Topic, Grade: Grade(g)})
+	}
+
+	l.mu.Lock()
+	l.dataset.Put(q)
+	err = l.dataset.SaveFile(l.path)
+	l.mu.Unlock()
+	if err != nil {
+		http.Error(w, "save dataset: "+err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	next := "/"
+	if i+1 < len(l.queries) {
+		next = fmt.Sprintf("/query?i=%d", i+1)
+	}
+	http.Redirect(w, r, next, http.StatusSeeOther)
+}
+
+// topics returns the topics for query i, fetching them on first use so a
+// reviewer sees a stable list while labeling.
+func (l *Labeler) topics(i int) ([]datasource.DataSourceTopic, error) {
+	l.mu.Lock()
+	topics, ok := l.results[i]
+	l.mu.Unlock()
+	if ok {
+		return topics, nil
+	}
+	topics, err := l.source.FetchTopics(l.count, datasource.NewQuestionInput{QuestionText: l.queries[i]})
+	if err != nil {
+		return nil, err
+	}
+	l.mu.Lock()
+	l.results[i] = topics
+	l.mu.Unlock()
+	return topics, nil
+}
+
+func render(w http.ResponseWriter, t *template.Template, data any) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := t.Execute(w, data); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+const pageStyle = `<style>
+body { font-family: sans-serif; max-width: 60rem; margin: 2rem auto; }
+td, th { padding: .3rem .6rem; text-align: left; vertical-align: top; }
+.grades label { margin-right: .8rem; }
+</style>`
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!doctype html>
+<title>locus-ds label</title>` + pageStyle + `
+<h1>Label queries for {{.Source}}</h1>
+<table>
+<tr><th>#</th><th>Question</th><th>Judgments</th></tr>
+{{range .Rows}}<tr><td>{{.Index}}</td><td><a href="/query?i={{.Index}}">{{.Question}}</a></td><td>{{.Labeled}}</td></tr>
+{{end}}</table>
+`))
+
+var queryTemplate = template.Must(template.New("query").Parse(`<!doctype html>
+<title>locus-ds label</title>` + pageStyle + `
+<p><a href="/">All queries</a></p>
+<h1>{{.Question}}</h1>
+<form method="post" action="/query?i={{.Index}}">
+<table>
+{{$grades := .Grades}}{{range .Topics}}{{$t := .}}<tr>
+<td><a href="{{.SourceURL}}" target="_blank" rel="noopener">{{.Topic}}</a>{{if .Site}} <small>({{.Site}})</small>{{end}}</td>
+<td class="grades">{{range $grades}}<label><input type="radio" name="grade_{{$t.TopicID}}" value="{{.}}"{{if and $t.Labeled (eq . $t.Grade)}} checked{{end}}> {{.}}</label>{{end}}</td>
+</tr>
+{{else}}<tr><td>No topics returned.</td></tr>
+{{end}}</table>
+<p>0 = irrelevant, 1 = partial, 2 = relevant, 3 = perfect</p>
+<button type="submit">Save and continue</button>
+</form>
+`))
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
 )
 
 // ExampleDataSource demonstrates a minimal implementation
@@ -27,6 +28,9 @@ func (ds *ExampleDataSource) FetchTopics(count int, input datasource.NewQuestion
 	if input.QuestionText == "" {
 		return nil, errors.New("question text is required")
 	}
+	if count <= 0 {
+		return []datasource.DataSourceTopic{}, nil
+	}
 
 	// Example: return a mock topic
 	return []datasource.DataSourceTopic{
@@ -42,6 +46,9 @@ func (ds *ExampleDataSource) FetchData(count int, topicID int64) ([]datasource.D
 	if topicID <= 0 {
 		return nil, errors.New("invalid topic ID")
 	}
+	if count <= 0 {
+		return []datasource.DataSourceData{}, nil
+	}
 
 	// Example: return mock data
 	return []datasource.DataSourceData{
@@ -114,3 +121,7 @@ func TestExampleDataSourceValidation(t *testing.T) {
 		t.Error("Expected error with invalid topic ID")
 	}
 }
+
+func TestExampleDataSourceConformance(t *testing.T) {
+	datasourcetest.RunConformance(t, &ExampleDataSource{Name: "test"})
+}
@@ -0,0 +1,59 @@
+package datasource
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Extra holds typed, source-specific extensions of a topic or data item,
+// such as Stack Exchange bounty details or a video's duration. Each
+// extension is a Go type with a name, stored as raw JSON, so it passes
+// through middleware and transports untouched and is decoded only by code
+// that knows its type:
+//
+//	type Bounty struct {
+//	    Amount    int       `json:"amount"`
+//	    ExpiresAt time.Time `json:"expires_at"`
+//	}
+//
+//	func (Bounty) ExtensionName() string { return "stackexchange.bounty" }
+//
+//	topic.Extra.Set(Bounty{Amount: 50})
+//	bounty, ok, err := datasource.GetExtra[Bounty](topic.Extra)
+//
+// For loosely typed scalar fields, Metadata is simpler.
+type Extra map[string]json.RawMessage
+
+// Extension is a type stored in Extra. ExtensionName must be constant for
+// the type and should be qualified by the source, as in
+// "stackexchange.bounty", to avoid collisions.
+type Extension interface {
+	ExtensionName() string
+}
+
+// Set stores v under its ExtensionName, allocating the map if needed.
+func (e *Extra) Set(v Extension) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("datasource: extension %s: %w", v.ExtensionName(), err)
+	}
+	if *e == nil {
+		*e = make(Extra)
+	}
+	(*e)[v.ExtensionName()] = b
+	return nil
+}
+
+// GetExtra decodes the extension of type T from e. It reports false if e
+// has no such extension, and an error if it cannot be decoded as T.
+func GetExtra[T Extension](e Extra) (T, bool, error) {
+	var v T
+	raw, ok := e[v.ExtensionName()]
+	if !ok {
+		return v, false, nil
+	}
+	if err := json.Unmarshal(raw, &v); err != nil {
+		return v, true, fmt.Errorf("datasource: extension %s: %w", v.ExtensionName(), err)
+	}
+	return v, true, nil
+}
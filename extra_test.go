@@ -0,0 +1,51 @@
+package datasource_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+type bounty struct {
+	Amount int `json:"amount"`
+}
+
+func (bounty) ExtensionName() string { return "stackexchange.bounty" }
+
+type duration struct {
+	Seconds int `json:"seconds"`
+}
+
+func (duration) ExtensionName() string { return "youtube.duration" }
+
+func TestExtraRoundTrip(t *testing.T) {
+	var topic datasource.DataSourceTopic
+	if err := topic.Extra.Set(bounty{Amount: 50}); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := json.Marshal(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded datasource.DataSourceTopic
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	got, ok, err := datasource.GetExtra[bounty](decoded.Extra)
+	if err != nil || !ok || got.Amount != 50 {
+		t.Errorf("GetExtra = %+v, %v, %v", got, ok, err)
+	}
+	if _, ok, err := datasource.GetExtra[duration](decoded.Extra); ok || err != nil {
+		t.Errorf("missing extension: ok = %v, err = %v", ok, err)
+	}
+}
+
+func TestExtraDecodeError(t *testing.T) {
+	extra := datasource.Extra{"stackexchange.bounty": json.RawMessage(`{"amount":"fifty"}`)}
+	if _, ok, err := datasource.GetExtra[bounty](extra); !ok || err == nil {
+		t.Errorf("ok = %v, err = %v; want a decode error", ok, err)
+	}
+}
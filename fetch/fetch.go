@@ -0,0 +1,121 @@
+// Package fetch provides helpers for fetching from data sources on behalf
+// of hosts.
+//
+// DataForTopics fetches the data of many topics at once, as a host does
+// after FetchTopics:
+//
+//	topics, err := ds.FetchTopics(5, input)
+//	...
+//	results, err := fetch.DataForTopics(ds, topics, 3, 4)
+//	for _, r := range results {
+//	    if r.Err != nil {
+//	        continue // also reported in err
+//	    }
+//	    render(r.Topic, r.Data)
+//	}
+package fetch
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// DefaultConcurrency is the number of concurrent calls DataForTopics makes
+// when its concurrency is zero or negative.
+const DefaultConcurrency = 4
+
+// Result is the data fetched for one topic.
+type Result struct {
+	Topic datasource.DataSourceTopic
+
+	// Data is the topic's data items, nil if the fetch failed
+	Data []datasource.DataSourceData
+
+	// Err is the fetch's error, or nil
+	Err error
+}
+
+// TopicError is the failure to fetch the data of one topic.
+type TopicError struct {
+	// Index is the topic's position in the topics fetched
+	Index int
+
+	TopicID int64
+	Err     error
+}
+
+func (e *TopicError) Error() string {
+	return fmt.Sprintf("topic %d: %v", e.TopicID, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *TopicError) Unwrap() error {
+	return e.Err
+}
+
+// Errors is the failures of a DataForTopics call, in topic order.
+// errors.Is and errors.As match the error of any topic.
+type Errors []*TopicError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("fetch: %d of the topics failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// Unwrap returns the errors of the topics.
+func (e Errors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// DataForTopics calls FetchData for each topic, with perTopicCount, making
+// up to concurrency calls at once (DefaultConcurrency if it is zero or
+// less). It returns a Result for every topic, in the order of topics, and,
+// if any failed, an Errors holding their failures; the data of the topics
+// that succeeded is returned either way.
+func DataForTopics(ds datasource.DataSource, topics []datasource.DataSourceTopic, perTopicCount, concurrency int) ([]Result, error) {
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	results := make([]Result, len(topics))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(concurrency, len(topics)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				data, err := ds.FetchData(perTopicCount, topics[i].TopicID)
+				if err != nil {
+					data = nil
+				}
+				results[i] = Result{Topic: topics[i], Data: data, Err: err}
+			}
+		}()
+	}
+	for i := range topics {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	var errs Errors
+	for i, r := range results {
+		if r.Err != nil {
+			errs = append(errs, &TopicError{Index: i, TopicID: r.Topic.TopicID, Err: r.Err})
+		}
+	}
+	if len(errs) > 0 {
+		return results, errs
+	}
+	return results, nil
+}
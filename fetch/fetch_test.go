@@ -0,0 +1,84 @@
+package fetch_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/fetch"
+)
+
+// slowSource answers FetchData after a delay, failing for odd topic IDs
+// over 100, and records the most calls in flight.
+type slowSource struct {
+	mu           sync.Mutex
+	active, most int
+}
+
+func (s *slowSource) Init() error             { return nil }
+func (s *slowSource) CheckAvailability() bool { return true }
+func (s *slowSource) FetchTopics(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	return nil, nil
+}
+
+func (s *slowSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	s.mu.Lock()
+	s.active++
+	s.most = max(s.most, s.active)
+	s.mu.Unlock()
+	// Later topics answer first, so order is not completion order.
+	time.Sleep(time.Duration(10-topicID%10) * time.Millisecond)
+	s.mu.Lock()
+	s.active--
+	s.mu.Unlock()
+	if topicID > 100 && topicID%2 == 1 {
+		return []datasource.DataSourceData{{DataText: "partial"}}, datasource.ErrNotFound
+	}
+	return []datasource.DataSourceData{{DataText: fmt.Sprint(topicID), AnswerID: int64(count)}}, nil
+}
+
+func topics(ids ...int64) []datasource.DataSourceTopic {
+	topics := make([]datasource.DataSourceTopic, len(ids))
+	for i, id := range ids {
+		topics[i] = datasource.DataSourceTopic{TopicID: id}
+	}
+	return topics
+}
+
+func TestDataForTopics(t *testing.T) {
+	src := &slowSource{}
+	results, err := fetch.DataForTopics(src, topics(1, 2, 3, 4, 5, 6, 7, 8), 3, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, r := range results {
+		if want := fmt.Sprint(i + 1); r.Topic.TopicID != int64(i+1) || len(r.Data) != 1 || r.Data[0].DataText != want || r.Data[0].AnswerID != 3 {
+			t.Errorf("result %d = %+v, want topic %s's data", i, r, want)
+		}
+	}
+	if src.most != 3 {
+		t.Errorf("%d calls in flight, want 3", src.most)
+	}
+
+	if results, err := fetch.DataForTopics(src, nil, 3, 0); err != nil || len(results) != 0 {
+		t.Errorf("DataForTopics without topics = %v, %v", results, err)
+	}
+}
+
+func TestDataForTopicsPartialFailure(t *testing.T) {
+	results, err := fetch.DataForTopics(&slowSource{}, topics(101, 102, 103), 3, 0)
+	var errs fetch.Errors
+	if !errors.As(err, &errs) || len(errs) != 2 || errs[0].Index != 0 || errs[1].TopicID != 103 {
+		t.Fatalf("err = %#v, want topics 101 and 103 failed", err)
+	}
+	if !errors.Is(err, datasource.ErrNotFound) || !strings.Contains(err.Error(), "2 of the topics failed") {
+		t.Errorf("err = %v", err)
+	}
+	if results[0].Err == nil || results[0].Data != nil || results[1].Err != nil || len(results[1].Data) != 1 {
+		t.Errorf("results = %+v", results)
+	}
+}
@@ -0,0 +1,142 @@
+package datasource
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SortOrder is the order in which the asker prefers results.
+type SortOrder string
+
+// Sort orders for Filters.Sort.
+const (
+	SortRelevance SortOrder = "relevance" // most relevant first, the default
+	SortRecency   SortOrder = "recency"   // most recently published first
+	SortVotes     SortOrder = "votes"     // most upvoted first
+)
+
+// Filters holds structured constraints on a search, in addition to the
+// question itself. The zero value constrains nothing. Sources should apply
+// what their upstream supports natively and may apply the rest to their
+// results with Topics and Data.
+type Filters struct {
+	// After and Before bound when results were published (CreatedAt, or
+	// UpdatedAt if that is unknown): at or after After and before Before;
+	// zero means unbounded
+	After, Before time.Time
+
+	// MinScore is the lowest upstream score, such as a vote count, a
+	// result may have; zero means no minimum
+	MinScore float64
+
+	// Sites restricts results to these sites (see DataSourceTopic.Site)
+	// Results without a site are kept
+	Sites []string
+
+	// ExcludeTags lists tags results must not have
+	ExcludeTags []string
+
+	// Sort is the preferred order of results
+	// Defaults to SortRelevance
+	Sort SortOrder
+}
+
+// IsZero reports whether f constrains nothing.
+func (f Filters) IsZero() bool {
+	return f.After.IsZero() && f.Before.IsZero() && f.MinScore == 0 &&
+		len(f.Sites) == 0 && len(f.ExcludeTags) == 0 && (f.Sort == "" || f.Sort == SortRelevance)
+}
+
+// key returns a normalized form of f for QueryKey.
+func (f Filters) key() string {
+	var parts []string
+	if !f.After.IsZero() {
+		parts = append(parts, "after="+f.After.UTC().Format(time.RFC3339Nano))
+	}
+	if !f.Before.IsZero() {
+		parts = append(parts, "before="+f.Before.UTC().Format(time.RFC3339Nano))
+	}
+	if f.MinScore != 0 {
+		parts = append(parts, "min_score="+strconv.FormatFloat(f.MinScore, 'g', -1, 64))
+	}
+	if sites := normalizeSet(f.Sites); sites != "" {
+		parts = append(parts, "sites="+sites)
+	}
+	if tags := normalizeSet(f.ExcludeTags); tags != "" {
+		parts = append(parts, "exclude_tags="+tags)
+	}
+	if f.Sort != "" && f.Sort != SortRelevance {
+		parts = append(parts, "sort="+string(f.Sort))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Topics returns the topics that pass the date, score, and site filters,
+// sorted by recency if Sort asks for it. ExcludeTags and SortVotes cannot
+// be checked on results and are left to the source.
+func (f Filters) Topics(topics []DataSourceTopic) []DataSourceTopic {
+	out := make([]DataSourceTopic, 0, len(topics))
+	for _, t := range topics {
+		if f.match(t.CreatedAt, t.UpdatedAt, t.Score, t.Site) {
+			out = append(out, t)
+		}
+	}
+	if f.Sort == SortRecency {
+		sort.SliceStable(out, func(i, j int) bool {
+			return published(out[i].CreatedAt, out[i].UpdatedAt).After(published(out[j].CreatedAt, out[j].UpdatedAt))
+		})
+	}
+	return out
+}
+
+// Data returns the data items that pass the filters, as Topics does.
+func (f Filters) Data(items []DataSourceData) []DataSourceData {
+	out := make([]DataSourceData, 0, len(items))
+	for _, d := range items {
+		if f.match(d.CreatedAt, d.UpdatedAt, d.Score, d.Site) {
+			out = append(out, d)
+		}
+	}
+	if f.Sort == SortRecency {
+		sort.SliceStable(out, func(i, j int) bool {
+			return published(out[i].CreatedAt, out[i].UpdatedAt).After(published(out[j].CreatedAt, out[j].UpdatedAt))
+		})
+	}
+	return out
+}
+
+// match reports whether a result passes the filters. Undated results
+// pass the date range.
+func (f Filters) match(created, updated time.Time, score float64, site string) bool {
+	if at := published(created, updated); !at.IsZero() {
+		if (!f.After.IsZero() && at.Before(f.After)) || (!f.Before.IsZero() && !at.Before(f.Before)) {
+			return false
+		}
+	}
+	if f.MinScore != 0 && score < f.MinScore {
+		return false
+	}
+	if site != "" && len(f.Sites) > 0 && !containsFold(f.Sites, site) {
+		return false
+	}
+	return true
+}
+
+// published is when a result was published: created, else updated.
+func published(created, updated time.Time) time.Time {
+	if created.IsZero() {
+		return updated
+	}
+	return created
+}
+
+func containsFold(list []string, s string) bool {
+	for _, v := range list {
+		if strings.EqualFold(strings.TrimSpace(v), s) {
+			return true
+		}
+	}
+	return false
+}
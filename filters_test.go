@@ -0,0 +1,56 @@
+package datasource_test
+
+import (
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestFiltersTopics(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2024, 3, d, 0, 0, 0, 0, time.UTC) }
+	topics := []datasource.DataSourceTopic{
+		{TopicID: 1, Score: 10, Site: "stackoverflow", CreatedAt: day(2)},
+		{TopicID: 2, Score: 1, Site: "stackoverflow", CreatedAt: day(5)},
+		{TopicID: 3, Score: 20, Site: "superuser", CreatedAt: day(6)},
+		{TopicID: 4, Score: 30, UpdatedAt: day(9)},
+		{TopicID: 5, Score: 40, Site: "ServerFault"},
+	}
+	tests := []struct {
+		name string
+		f    datasource.Filters
+		want []int64
+	}{
+		{"none", datasource.Filters{}, []int64{1, 2, 3, 4, 5}},
+		{"date range", datasource.Filters{After: day(3), Before: day(9)}, []int64{2, 3, 5}},
+		{"min score", datasource.Filters{MinScore: 10}, []int64{1, 3, 4, 5}},
+		{"sites", datasource.Filters{Sites: []string{"serverfault", "stackoverflow"}}, []int64{1, 2, 4, 5}},
+		{"recency", datasource.Filters{Sort: datasource.SortRecency}, []int64{4, 3, 2, 1, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.f.Topics(topics)
+			ids := make([]int64, len(got))
+			for i, t := range got {
+				ids[i] = t.TopicID
+			}
+			if len(ids) != len(tt.want) {
+				t.Fatalf("topics = %v, want %v", ids, tt.want)
+			}
+			for i := range ids {
+				if ids[i] != tt.want[i] {
+					t.Fatalf("topics = %v, want %v", ids, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestFiltersIsZero(t *testing.T) {
+	if !(datasource.Filters{Sort: datasource.SortRelevance}).IsZero() {
+		t.Error("relevance sort is not zero")
+	}
+	if (datasource.Filters{ExcludeTags: []string{"x"}}).IsZero() {
+		t.Error("excluded tags are zero")
+	}
+}
@@ -0,0 +1,128 @@
+// Package hashring maps questions to preferred replicas with consistent
+// hashing.
+//
+// Hosts running several replicas, each with its own in-memory cache, get
+// far better hit rates when equivalent questions land on the same replica.
+// A Ring assigns every normalized question (see datasource.QueryKey) to a
+// replica, and only about 1/N of questions move when a replica joins or
+// leaves. Load balancers that hash on a request header can instead use
+// SetHeader, which carries the same normalized hash in Header.
+package hashring
+
+import (
+	"hash/fnv"
+	"net/http"
+	"sort"
+	"strconv"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Header carries a question's affinity hash on HTTP requests.
+const Header = "X-Locus-Affinity"
+
+// DefaultVirtualNodes is the number of points each replica occupies on the
+// ring when New is given a non-positive count.
+const DefaultVirtualNodes = 128
+
+// Ring is an immutable consistent-hash ring of replicas. It is safe for
+// concurrent use; build a new Ring when membership changes.
+type Ring struct {
+	points   []point
+	replicas int
+}
+
+type point struct {
+	hash    uint64
+	replica string
+}
+
+// New builds a ring of the given replicas, each occupying vnodes points.
+// Duplicate and empty replica names are ignored.
+func New(replicas []string, vnodes int) *Ring {
+	if vnodes <= 0 {
+		vnodes = DefaultVirtualNodes
+	}
+	r := &Ring{}
+	seen := make(map[string]bool, len(replicas))
+	for _, name := range replicas {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		r.replicas++
+		for i := 0; i < vnodes; i++ {
+			r.points = append(r.points, point{hash: hash(name + "#" + strconv.Itoa(i)), replica: name})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash != r.points[j].hash {
+			return r.points[i].hash < r.points[j].hash
+		}
+		return r.points[i].replica < r.points[j].replica
+	})
+	return r
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return mix(h.Sum64())
+}
+
+// mix finalizes an FNV hash so that similar inputs spread evenly around the
+// ring (the MurmurHash3 64-bit finalizer).
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// Get returns the replica that owns key, or "" if the ring is empty.
+func (r *Ring) Get(key string) string {
+	if p := r.GetN(key, 1); len(p) > 0 {
+		return p[0]
+	}
+	return ""
+}
+
+// GetN returns up to n distinct replicas for key in order of preference,
+// so callers can fail over to the next replica when the first is down.
+func (r *Ring) GetN(key string, n int) []string {
+	if len(r.points) == 0 || n <= 0 {
+		return nil
+	}
+	n = min(n, r.replicas)
+	h := hash(key)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+
+	out := make([]string, 0, n)
+	seen := make(map[string]bool, n)
+	for j := 0; len(out) < n; j++ {
+		p := r.points[(i+j)%len(r.points)]
+		if !seen[p.replica] {
+			seen[p.replica] = true
+			out = append(out, p.replica)
+		}
+	}
+	return out
+}
+
+// Replica returns the replica that should answer input.
+func (r *Ring) Replica(input datasource.NewQuestionInput) string {
+	return r.Get(datasource.QueryKey(input))
+}
+
+// Key returns the affinity hash of input as a hex string. Equivalent
+// questions have the same key.
+func Key(input datasource.NewQuestionInput) string {
+	return strconv.FormatUint(hash(datasource.QueryKey(input)), 16)
+}
+
+// SetHeader sets Header on h to the affinity key of input.
+func SetHeader(h http.Header, input datasource.NewQuestionInput) {
+	h.Set(Header, Key(input))
+}
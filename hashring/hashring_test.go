@@ -0,0 +1,70 @@
+package hashring_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/hashring"
+)
+
+func TestEquivalentQuestionsShareReplica(t *testing.T) {
+	r := hashring.New([]string{"a", "b", "c"}, 0)
+	x := datasource.NewQuestionInput{QuestionText: "How do I  reset my Password?", Tags: []string{"Auth", "sso"}}
+	y := datasource.NewQuestionInput{QuestionText: "how do i reset my password?", Tags: []string{"sso", " auth"}}
+
+	if r.Replica(x) != r.Replica(y) {
+		t.Errorf("equivalent questions routed to %s and %s", r.Replica(x), r.Replica(y))
+	}
+	if hashring.Key(x) != hashring.Key(y) {
+		t.Errorf("equivalent questions have keys %s and %s", hashring.Key(x), hashring.Key(y))
+	}
+
+	h := http.Header{}
+	hashring.SetHeader(h, x)
+	if h.Get(hashring.Header) != hashring.Key(x) {
+		t.Errorf("header %q not set to key", h.Get(hashring.Header))
+	}
+}
+
+func TestMembershipChangesMoveFewKeys(t *testing.T) {
+	before := hashring.New([]string{"a", "b", "c", "d"}, 0)
+	after := hashring.New([]string{"a", "b", "c", "d", "e"}, 0)
+
+	const n = 10000
+	moved, counts := 0, map[string]int{}
+	for i := 0; i < n; i++ {
+		key := fmt.Sprintf("question %d", i)
+		b, a := before.Get(key), after.Get(key)
+		counts[b]++
+		if a != b {
+			moved++
+			if a != "e" {
+				t.Fatalf("key %q moved between existing replicas %s -> %s", key, b, a)
+			}
+		}
+	}
+	if moved < n/10 || moved > n/3 {
+		t.Errorf("%d of %d keys moved, want about 1/5", moved, n)
+	}
+	for replica, c := range counts {
+		if c < n/8 || c > n*3/8 {
+			t.Errorf("replica %s owns %d of %d keys, want about 1/4", replica, c, n)
+		}
+	}
+}
+
+func TestGetN(t *testing.T) {
+	r := hashring.New([]string{"a", "b", "a", ""}, 16)
+	got := r.GetN("k", 5)
+	if len(got) != 2 || got[0] == got[1] {
+		t.Errorf("GetN = %v, want both distinct replicas", got)
+	}
+	if got[0] != r.Get("k") {
+		t.Errorf("GetN[0] = %s, Get = %s", got[0], r.Get("k"))
+	}
+	if hashring.New(nil, 0).Get("k") != "" {
+		t.Error("empty ring should return no replica")
+	}
+}
@@ -0,0 +1,117 @@
+package datasource
+
+import (
+	"time"
+)
+
+// HealthState summarizes a health check.
+type HealthState string
+
+// Health states, from best to worst.
+const (
+	// Healthy sources are serving normally.
+	Healthy HealthState = "healthy"
+
+	// Degraded sources are serving, but slowly, partially, or with
+	// intermittent failures.
+	Degraded HealthState = "degraded"
+
+	// Unhealthy sources cannot serve requests.
+	Unhealthy HealthState = "unhealthy"
+)
+
+var healthRank = map[HealthState]int{Healthy: 0, Degraded: 1, Unhealthy: 2}
+
+// worse reports whether s is a worse state than o.
+func (s HealthState) worse(o HealthState) bool {
+	return healthRank[s] > healthRank[o]
+}
+
+// HealthStatus is a detailed health report, letting monitoring tell a slow
+// source from a down one and surface the reason.
+type HealthStatus struct {
+	// State is the overall state
+	State HealthState `json:"state"`
+
+	// Latency is how long the check took
+	Latency time.Duration `json:"latency"`
+
+	// Error describes the most recent failure, if any
+	Error string `json:"error,omitempty"`
+
+	// Components reports the health of parts of the source, such as
+	// upstream APIs, indexes, or child processes, by name
+	Components map[string]HealthStatus `json:"components,omitempty"`
+}
+
+// HealthChecker is an optional interface for data sources that can report
+// more than CheckAvailability's yes or no.
+type HealthChecker interface {
+	HealthCheck() HealthStatus
+}
+
+// CheckHealth reports the health of ds. It uses the HealthCheck method of
+// the first HealthChecker in ds's decorator chain (following Unwrap
+// methods), and falls back to CheckAvailability. Latency is filled in with
+// the measured duration of the check if the source does not report it.
+func CheckHealth(ds DataSource) HealthStatus {
+	start := time.Now()
+	var h HealthStatus
+	if c, ok := find[HealthChecker](ds); ok {
+		h = c.HealthCheck()
+	} else if ds.CheckAvailability() {
+		h = HealthStatus{State: Healthy}
+	} else {
+		h = HealthStatus{State: Unhealthy, Error: ErrUnavailable.Error()}
+	}
+	if h.Latency == 0 {
+		h.Latency = time.Since(start)
+	}
+	return h
+}
+
+// MaintenanceReporter is an optional interface for data sources and
+// decorators that know when a source is down for scheduled maintenance.
+// Maintenance reports whether it is, and until when. Multiplexers route
+// around sources in maintenance rather than recording their failures.
+type MaintenanceReporter interface {
+	Maintenance() (until time.Time, ok bool)
+}
+
+// InMaintenance reports whether ds is in scheduled maintenance, and until
+// when, asking the first MaintenanceReporter in its decorator chain
+// (following Unwrap methods).
+func InMaintenance(ds DataSource) (until time.Time, ok bool) {
+	if r, ok := find[MaintenanceReporter](ds); ok {
+		return r.Maintenance()
+	}
+	return time.Time{}, false
+}
+
+// CombineHealth summarizes component statuses: Healthy if all are healthy,
+// Unhealthy if all are unhealthy, and Degraded otherwise. With no
+// components the result is Unhealthy.
+func CombineHealth(components map[string]HealthStatus) HealthStatus {
+	h := HealthStatus{State: Unhealthy, Components: components}
+	if len(components) == 0 {
+		return h
+	}
+	best, worst := Unhealthy, Healthy
+	for _, c := range components {
+		if best.worse(c.State) {
+			best = c.State
+		}
+		if c.State.worse(worst) {
+			worst = c.State
+		}
+	}
+	switch {
+	case worst == Healthy:
+		h.State = Healthy
+	case best == Unhealthy:
+		h.State = Unhealthy
+	default:
+		h.State = Degraded
+	}
+	return h
+}
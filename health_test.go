@@ -0,0 +1,62 @@
+package datasource_test
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+type healthSource struct {
+	ExampleDataSource
+	status datasource.HealthStatus
+}
+
+func (s *healthSource) HealthCheck() datasource.HealthStatus { return s.status }
+
+type downSource struct{ ExampleDataSource }
+
+func (*downSource) CheckAvailability() bool { return false }
+
+func TestCheckHealth(t *testing.T) {
+	tests := []struct {
+		name  string
+		ds    datasource.DataSource
+		state datasource.HealthState
+	}{
+		{"available", &ExampleDataSource{}, datasource.Healthy},
+		{"unavailable", &downSource{}, datasource.Unhealthy},
+		{"checker", &healthSource{status: datasource.HealthStatus{State: datasource.Degraded}}, datasource.Degraded},
+		{"wrapped checker", passthrough{&healthSource{status: datasource.HealthStatus{State: datasource.Degraded}}}, datasource.Degraded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := datasource.CheckHealth(tt.ds).State; got != tt.state {
+				t.Errorf("State = %q, want %q", got, tt.state)
+			}
+		})
+	}
+}
+
+func TestCombineHealth(t *testing.T) {
+	healthy := datasource.HealthStatus{State: datasource.Healthy}
+	degraded := datasource.HealthStatus{State: datasource.Degraded}
+	unhealthy := datasource.HealthStatus{State: datasource.Unhealthy}
+	tests := []struct {
+		name       string
+		components map[string]datasource.HealthStatus
+		want       datasource.HealthState
+	}{
+		{"none", nil, datasource.Unhealthy},
+		{"all healthy", map[string]datasource.HealthStatus{"a": healthy, "b": healthy}, datasource.Healthy},
+		{"one degraded", map[string]datasource.HealthStatus{"a": healthy, "b": degraded}, datasource.Degraded},
+		{"one down", map[string]datasource.HealthStatus{"a": healthy, "b": unhealthy}, datasource.Degraded},
+		{"all down", map[string]datasource.HealthStatus{"a": unhealthy, "b": unhealthy}, datasource.Unhealthy},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := datasource.CombineHealth(tt.components).State; got != tt.want {
+				t.Errorf("State = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package datasource
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// RequestInfo describes a data source call for Hooks.
+type RequestInfo struct {
+	// Source is the name of the data source
+	Source string
+
+	// Method is the interface method being called
+	Method Method
+
+	// Count is the requested result count (FetchTopics and FetchData only)
+	Count int
+
+	// TopicID is the topic being fetched (FetchData only)
+	TopicID int64
+
+	// Query is the question text (FetchTopics only)
+	Query string
+
+	// Start is when the call began
+	Start time.Time
+}
+
+// RequestResult describes the outcome of a data source call.
+type RequestResult struct {
+	// Duration is how long the call took
+	Duration time.Duration
+
+	// Results is the number of topics or data items returned
+	Results int
+
+	// Err is the error returned by the call, if any
+	Err error
+}
+
+// Hooks receives notifications about data source calls so that logging,
+// auditing, and similar concerns can be handled uniformly across
+// integrations. Data sources and decorators fire hooks with the
+// RequestStart, RequestEnd, and Error methods, which skip nil fields.
+type Hooks struct {
+	// OnRequestStart is called before a call is made
+	OnRequestStart func(ctx context.Context, info RequestInfo)
+
+	// OnRequestEnd is called after every call, successful or not
+	OnRequestEnd func(ctx context.Context, info RequestInfo, result RequestResult)
+
+	// OnError is called after a call fails, before OnRequestEnd
+	OnError func(ctx context.Context, info RequestInfo, err error)
+
+	// OnQueryReport is called by multiplexers after answering a question
+	// from several sources; see QueryReport
+	OnQueryReport func(ctx context.Context, report QueryReport)
+}
+
+// RequestStart fires OnRequestStart if set.
+func (h Hooks) RequestStart(ctx context.Context, info RequestInfo) {
+	if h.OnRequestStart != nil {
+		h.OnRequestStart(ctx, info)
+	}
+}
+
+// RequestEnd fires OnError (when result.Err is set) and OnRequestEnd.
+func (h Hooks) RequestEnd(ctx context.Context, info RequestInfo, result RequestResult) {
+	if result.Err != nil {
+		h.Error(ctx, info, result.Err)
+	}
+	if h.OnRequestEnd != nil {
+		h.OnRequestEnd(ctx, info, result)
+	}
+}
+
+// Error fires OnError if set.
+func (h Hooks) Error(ctx context.Context, info RequestInfo, err error) {
+	if h.OnError != nil {
+		h.OnError(ctx, info, err)
+	}
+}
+
+// QueryReport fires OnQueryReport if set.
+func (h Hooks) QueryReport(ctx context.Context, report QueryReport) {
+	if h.OnQueryReport != nil {
+		h.OnQueryReport(ctx, report)
+	}
+}
+
+// CombineHooks returns Hooks that fire each of the given hooks in order.
+func CombineHooks(hooks ...Hooks) Hooks {
+	return Hooks{
+		OnRequestStart: func(ctx context.Context, info RequestInfo) {
+			for _, h := range hooks {
+				h.RequestStart(ctx, info)
+			}
+		},
+		OnRequestEnd: func(ctx context.Context, info RequestInfo, result RequestResult) {
+			for _, h := range hooks {
+				if h.OnRequestEnd != nil {
+					h.OnRequestEnd(ctx, info, result)
+				}
+			}
+		},
+		OnError: func(ctx context.Context, info RequestInfo, err error) {
+			for _, h := range hooks {
+				h.Error(ctx, info, err)
+			}
+		},
+		OnQueryReport: func(ctx context.Context, report QueryReport) {
+			for _, h := range hooks {
+				h.QueryReport(ctx, report)
+			}
+		},
+	}
+}
+
+// SlogHooks returns Hooks that write structured logs to logger: request
+// starts at debug level (including the question text), completions at info
+// level, failures at warn level with the error and its ErrorClass, and
+// query reports at info level with a group per source. A nil logger uses
+// slog.Default().
+func SlogHooks(logger *slog.Logger) Hooks {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	attrs := func(info RequestInfo) []slog.Attr {
+		a := []slog.Attr{slog.String("source", info.Source), slog.String("method", string(info.Method))}
+		switch info.Method {
+		case MethodFetchTopics:
+			a = append(a, slog.Int("count", info.Count))
+		case MethodFetchData:
+			a = append(a, slog.Int("count", info.Count), slog.Int64("topic_id", info.TopicID))
+		}
+		return a
+	}
+	return Hooks{
+		OnRequestStart: func(ctx context.Context, info RequestInfo) {
+			a := attrs(info)
+			if info.Query != "" {
+				a = append(a, slog.String("query", info.Query))
+			}
+			logger.LogAttrs(ctx, slog.LevelDebug, "datasource request started", a...)
+		},
+		OnRequestEnd: func(ctx context.Context, info RequestInfo, result RequestResult) {
+			if result.Err != nil {
+				return
+			}
+			a := append(attrs(info), slog.Duration("duration", result.Duration), slog.Int("results", result.Results))
+			logger.LogAttrs(ctx, slog.LevelInfo, "datasource request finished", a...)
+		},
+		OnError: func(ctx context.Context, info RequestInfo, err error) {
+			a := append(attrs(info),
+				slog.Duration("duration", time.Since(info.Start)),
+				slog.String("error", err.Error()),
+				slog.String("error_class", ErrorClass(err)),
+			)
+			logger.LogAttrs(ctx, slog.LevelWarn, "datasource request failed", a...)
+		},
+		OnQueryReport: func(ctx context.Context, report QueryReport) {
+			a := []slog.Attr{
+				slog.Int("count", report.Count),
+				slog.Int("returned", report.Returned),
+				slog.Duration("duration", report.Duration),
+			}
+			for _, s := range report.Sources {
+				g := []any{
+					slog.Duration("latency", s.Latency),
+					slog.Int("topics", s.Topics),
+					slog.Int("kept", s.Kept),
+				}
+				if s.Filtered > 0 {
+					g = append(g, slog.Int("filtered", s.Filtered))
+				}
+				if s.CacheHit {
+					g = append(g, slog.Bool("cache_hit", true))
+				}
+				if s.Error != "" {
+					g = append(g, slog.String("error", s.Error), slog.String("error_class", s.ErrorClass))
+				}
+				a = append(a, slog.Group(s.Source, g...))
+			}
+			logger.LogAttrs(ctx, slog.LevelInfo, "datasource query report", a...)
+		},
+	}
+}
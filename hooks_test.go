@@ -0,0 +1,97 @@
+package datasource_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestSlogHooks(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	hooks := datasource.SlogHooks(logger)
+	ctx := context.Background()
+
+	info := datasource.RequestInfo{Source: "kb", Method: datasource.MethodFetchData, Count: 3, TopicID: 42, Start: time.Now()}
+	hooks.RequestStart(ctx, info)
+	hooks.RequestEnd(ctx, info, datasource.RequestResult{Duration: time.Second, Results: 2})
+	hooks.RequestEnd(ctx, info, datasource.RequestResult{Err: datasource.ErrNotFound})
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 log lines, got %d:\n%s", len(lines), buf.String())
+	}
+	want := []map[string]any{
+		{"level": "DEBUG", "msg": "datasource request started", "source": "kb", "method": "FetchData", "topic_id": 42.0},
+		{"level": "INFO", "msg": "datasource request finished", "results": 2.0, "duration": float64(time.Second)},
+		{"level": "WARN", "msg": "datasource request failed", "error_class": "not_found", "count": 3.0},
+	}
+	for i, line := range lines {
+		var got map[string]any
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatal(err)
+		}
+		for k, v := range want[i] {
+			if got[k] != v {
+				t.Errorf("line %d: %s = %v, want %v", i, k, got[k], v)
+			}
+		}
+	}
+}
+
+func TestCombineHooks(t *testing.T) {
+	var calls []string
+	record := func(tag string) datasource.Hooks {
+		return datasource.Hooks{
+			OnRequestEnd: func(context.Context, datasource.RequestInfo, datasource.RequestResult) {
+				calls = append(calls, tag+":end")
+			},
+			OnError: func(context.Context, datasource.RequestInfo, error) { calls = append(calls, tag+":error") },
+		}
+	}
+	hooks := datasource.CombineHooks(record("a"), datasource.Hooks{}, record("b"))
+
+	hooks.RequestStart(context.Background(), datasource.RequestInfo{})
+	hooks.RequestEnd(context.Background(), datasource.RequestInfo{}, datasource.RequestResult{Err: datasource.ErrUnavailable})
+
+	if got := strings.Join(calls, ","); got != "a:error,b:error,a:end,b:end" {
+		t.Errorf("unexpected call order %s", got)
+	}
+}
+
+func TestSlogHooksQueryReport(t *testing.T) {
+	var buf bytes.Buffer
+	var fired int
+	hooks := datasource.CombineHooks(
+		datasource.SlogHooks(slog.New(slog.NewJSONHandler(&buf, nil))),
+		datasource.Hooks{OnQueryReport: func(context.Context, datasource.QueryReport) { fired++ }},
+	)
+	hooks.QueryReport(context.Background(), datasource.QueryReport{
+		Query: "dns", Count: 5, Returned: 1,
+		Sources: []datasource.SourceReport{{Source: "kb", Topics: 1, Kept: 1}, {Source: "wiki", Error: "down", ErrorClass: "unavailable"}},
+	})
+	if fired != 1 {
+		t.Errorf("OnQueryReport fired %d times, want 1", fired)
+	}
+
+	var got struct {
+		Msg      string
+		Returned int
+		KB       struct{ Kept int }
+		Wiki     struct {
+			ErrorClass string `json:"error_class"`
+		}
+	}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Msg != "datasource query report" || got.Returned != 1 || got.KB.Kept != 1 || got.Wiki.ErrorClass != "unavailable" {
+		t.Errorf("logged %s", buf.String())
+	}
+}
@@ -0,0 +1,246 @@
+package httpx
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrContentChanged is returned by a resumable body when the remote resource
+// changed between the original request and a resume attempt, so the bytes
+// already delivered cannot be continued.
+var ErrContentChanged = errors.New("httpx: resource changed during download")
+
+// ResumeOptions configures OpenResumable. Zero-valued fields use defaults.
+type ResumeOptions struct {
+	// MaxResumes is how many times an interrupted download is resumed
+	// before the error is returned (default 5)
+	MaxResumes int
+
+	// Backoff is the delay before each resume attempt (default 500ms)
+	Backoff time.Duration
+}
+
+// OpenResumable sends req, which must be a GET request without a body, and
+// returns the response body wrapped so that transient network failures are
+// recovered transparently: when a read fails mid-stream the request is
+// re-sent with a Range header starting at the first byte not yet delivered,
+// guarded by If-Range with the original ETag or Last-Modified value.
+//
+// If the server ignores the range but the resource is unchanged, the
+// already-delivered prefix is discarded from the new response. If the
+// resource changed, reads fail with ErrContentChanged.
+//
+// Non-2xx responses are reported with StatusError.
+func OpenResumable(client *http.Client, req *http.Request, opts ResumeOptions) (io.ReadCloser, error) {
+	if req.Method != "" && req.Method != http.MethodGet {
+		return nil, fmt.Errorf("httpx: resumable download requires GET, got %s", req.Method)
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if opts.MaxResumes <= 0 {
+		opts.MaxResumes = 5
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = 500 * time.Millisecond
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := StatusError(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	r := &resumableBody{client: client, req: req, opts: opts, body: resp.Body}
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		r.validator = etag
+	} else {
+		r.validator = resp.Header.Get("Last-Modified")
+	}
+	return r, nil
+}
+
+type resumableBody struct {
+	client    *http.Client
+	req       *http.Request
+	opts      ResumeOptions
+	validator string
+
+	body    io.ReadCloser
+	offset  int64
+	resumes int
+	err     error // sticky error after a failed resume
+}
+
+func (r *resumableBody) Read(p []byte) (int, error) {
+	if r.err != nil {
+		return 0, r.err
+	}
+	for {
+		n, err := r.body.Read(p)
+		r.offset += int64(n)
+		if err == nil || err == io.EOF {
+			return n, err
+		}
+		if ctxErr := r.req.Context().Err(); ctxErr != nil {
+			return n, ctxErr
+		}
+		if r.resumes >= r.opts.MaxResumes {
+			return n, err
+		}
+		r.resumes++
+		r.body.Close()
+		if rerr := r.resume(); rerr != nil {
+			r.body = http.NoBody
+			r.err = fmt.Errorf("httpx: resume after %w: %w", err, rerr)
+			return n, r.err
+		}
+		if n > 0 {
+			return n, nil
+		}
+	}
+}
+
+func (r *resumableBody) Close() error {
+	return r.body.Close()
+}
+
+func (r *resumableBody) resume() error {
+	ctx := r.req.Context()
+	t := time.NewTimer(r.opts.Backoff)
+	select {
+	case <-ctx.Done():
+		t.Stop()
+		return ctx.Err()
+	case <-t.C:
+	}
+
+	req := r.req.Clone(ctx)
+	req.Header.Set("Range", "bytes="+strconv.FormatInt(r.offset, 10)+"-")
+	if r.validator != "" {
+		req.Header.Set("If-Range", r.validator)
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != r.offset {
+			resp.Body.Close()
+			return fmt.Errorf("httpx: unexpected Content-Range %q for offset %d", resp.Header.Get("Content-Range"), r.offset)
+		}
+		r.body = resp.Body
+		return nil
+	case http.StatusOK:
+		if !r.unchanged(resp.Header) {
+			resp.Body.Close()
+			return ErrContentChanged
+		}
+		// The server does not support ranges: skip what was delivered.
+		br := bufio.NewReader(resp.Body)
+		if _, err := io.CopyN(io.Discard, br, r.offset); err != nil {
+			resp.Body.Close()
+			return err
+		}
+		r.body = struct {
+			io.Reader
+			io.Closer
+		}{br, resp.Body}
+		return nil
+	default:
+		resp.Body.Close()
+		if err := StatusError(resp); err != nil {
+			return err
+		}
+		return fmt.Errorf("httpx: unexpected status %s on resume", resp.Status)
+	}
+}
+
+// unchanged reports whether a full (200) response refers to the same
+// version of the resource as the original response.
+func (r *resumableBody) unchanged(h http.Header) bool {
+	if r.validator == "" {
+		return false
+	}
+	return h.Get("ETag") == r.validator || h.Get("Last-Modified") == r.validator
+}
+
+// contentRangeStart parses the first byte position of a Content-Range header
+// such as "bytes 100-199/1000".
+func contentRangeStart(v string) (int64, bool) {
+	v, ok := strings.CutPrefix(v, "bytes ")
+	if !ok {
+		return 0, false
+	}
+	start, _, ok := strings.Cut(v, "-")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(start, 10, 64)
+	return n, err == nil
+}
+
+// ReadChunks reads text from r, typically a resumable response body, and
+// calls fn with consecutive chunks of at most maxBytes bytes. Chunks end at
+// the last line break within the limit when there is one, so paragraphs are
+// kept intact where possible; otherwise they end before a multi-byte rune
+// that does not fit. It lets a DataStreamer emit data items while a large
+// payload is still downloading. Errors from fn stop reading and are
+// returned as-is.
+func ReadChunks(r io.Reader, maxBytes int, fn func(chunk string) error) error {
+	if maxBytes <= 0 {
+		return fmt.Errorf("httpx: invalid chunk size %d", maxBytes)
+	}
+	buf := make([]byte, 0, maxBytes)
+	tmp := make([]byte, maxBytes)
+	for {
+		n, err := r.Read(tmp[:maxBytes-len(buf)])
+		buf = append(buf, tmp[:n]...)
+		if len(buf) == maxBytes {
+			cut := bytes.LastIndexByte(buf, '\n') + 1
+			if cut == 0 {
+				cut = runeCut(buf)
+			}
+			if ferr := fn(string(buf[:cut])); ferr != nil {
+				return ferr
+			}
+			buf = append(buf[:0], buf[cut:]...)
+		}
+		if err == io.EOF {
+			if len(buf) > 0 {
+				return fn(string(buf))
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// runeCut returns where to cut a full buffer with no line break: its end,
+// or the start of a trailing rune the buffer holds only part of. A buffer
+// too small for a single rune is cut at its end.
+func runeCut(buf []byte) int {
+	for i := len(buf) - 1; i >= 0 && i >= len(buf)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(buf[i]) {
+			if i > 0 && !utf8.FullRune(buf[i:]) {
+				return i
+			}
+			break
+		}
+	}
+	return len(buf)
+}
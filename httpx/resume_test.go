@@ -0,0 +1,143 @@
+package httpx
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+// flakyServer serves content but aborts the first full response halfway.
+func flakyServer(t *testing.T, content []byte, etag string, supportRanges bool) (*httptest.Server, *int32) {
+	t.Helper()
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		w.Header().Set("ETag", etag)
+		if n == 1 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:len(content)/2])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		if !supportRanges {
+			w.Write(content)
+			return
+		}
+		http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &requests
+}
+
+func TestOpenResumableResumesWithRange(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 10000))
+	for _, ranges := range []bool{true, false} {
+		t.Run("ranges="+strconv.FormatBool(ranges), func(t *testing.T) {
+			srv, requests := flakyServer(t, content, `"v1"`, ranges)
+			req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+
+			body, err := OpenResumable(srv.Client(), req, ResumeOptions{Backoff: time.Millisecond})
+			if err != nil {
+				t.Fatalf("OpenResumable failed: %v", err)
+			}
+			defer body.Close()
+
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatalf("ReadAll failed: %v", err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("got %d bytes, want %d identical bytes", len(got), len(content))
+			}
+			if atomic.LoadInt32(requests) != 2 {
+				t.Errorf("expected 2 requests, got %d", *requests)
+			}
+		})
+	}
+}
+
+func TestOpenResumableDetectsChangedContent(t *testing.T) {
+	content := []byte(strings.Repeat("x", 4096))
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:100])
+			w.(http.Flusher).Flush()
+			panic(http.ErrAbortHandler)
+		}
+		w.Header().Set("ETag", `"v2"`)
+		w.Write(content)
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL, nil)
+	body, err := OpenResumable(srv.Client(), req, ResumeOptions{Backoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("OpenResumable failed: %v", err)
+	}
+	defer body.Close()
+
+	if _, err := io.ReadAll(body); !errors.Is(err, ErrContentChanged) {
+		t.Errorf("expected ErrContentChanged, got %v", err)
+	}
+}
+
+func TestOpenResumableRejectsNonGet(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "http://example.com", nil)
+	if _, err := OpenResumable(nil, req, ResumeOptions{}); err == nil {
+		t.Error("expected an error for POST requests")
+	}
+}
+
+func TestReadChunks(t *testing.T) {
+	text := "para one\npara two is longer\nshort\n" + strings.Repeat("y", 25)
+	var chunks []string
+	if err := ReadChunks(strings.NewReader(text), 20, func(c string) error {
+		chunks = append(chunks, c)
+		return nil
+	}); err != nil {
+		t.Fatalf("ReadChunks failed: %v", err)
+	}
+
+	if strings.Join(chunks, "") != text {
+		t.Errorf("chunks do not reassemble the input: %q", chunks)
+	}
+	if chunks[0] != "para one\n" {
+		t.Errorf("expected first chunk to end at a line break, got %q", chunks[0])
+	}
+	for _, c := range chunks {
+		if len(c) > 20 {
+			t.Errorf("chunk exceeds limit: %q", c)
+		}
+	}
+}
+
+func TestReadChunksKeepsRunesWhole(t *testing.T) {
+	text := strings.Repeat("日本語", 10) + "é"
+	var chunks []string
+	if err := ReadChunks(strings.NewReader(text), 8, func(c string) error {
+		chunks = append(chunks, c)
+		return nil
+	}); err != nil {
+		t.Fatalf("ReadChunks failed: %v", err)
+	}
+
+	if strings.Join(chunks, "") != text {
+		t.Errorf("chunks do not reassemble the input: %q", chunks)
+	}
+	for _, c := range chunks {
+		if len(c) > 8 || !utf8.ValidString(c) {
+			t.Errorf("chunk %q exceeds the limit or splits a rune", c)
+		}
+	}
+}
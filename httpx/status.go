@@ -0,0 +1,82 @@
+// Package httpx provides HTTP helpers for data source implementations:
+// mapping upstream status codes to the SDK's error taxonomy, downloading
+// large payloads with automatic range-based resumption, and reading
+// bodies in legacy charsets as clean UTF-8 text.
+package httpx
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// StatusError converts a non-2xx response into an error wrapping the
+// matching SDK error:
+//
+//   - 429 becomes *datasource.ErrRateLimited with RetryAfter from the
+//     Retry-After header
+//   - 401 and 403 wrap datasource.ErrUnauthorized
+//   - 404 and 410 wrap datasource.ErrNotFound
+//   - 5xx wrap datasource.ErrUnavailable
+//
+// It returns nil for 2xx responses. The response body is not read or closed.
+func StatusError(resp *http.Response) error {
+	code := resp.StatusCode
+	if code >= 200 && code < 300 {
+		return nil
+	}
+	status := fmt.Errorf("httpx: %s", resp.Status)
+	if req := resp.Request; req != nil {
+		status = fmt.Errorf("httpx: %s %s: %s", req.Method, redactURL(req), resp.Status)
+	}
+	switch {
+	case code == http.StatusTooManyRequests:
+		return &datasource.ErrRateLimited{RetryAfter: ParseRetryAfter(resp.Header.Get("Retry-After")), Err: status}
+	case code == http.StatusUnauthorized || code == http.StatusForbidden:
+		return fmt.Errorf("%w: %w", datasource.ErrUnauthorized, status)
+	case code == http.StatusNotFound || code == http.StatusGone:
+		return fmt.Errorf("%w: %w", datasource.ErrNotFound, status)
+	case code >= 500:
+		return fmt.Errorf("%w: %w", datasource.ErrUnavailable, status)
+	default:
+		return status
+	}
+}
+
+// ParseRetryAfter parses a Retry-After header value given either as a number
+// of seconds or as an HTTP date. It returns zero if the value is empty,
+// malformed, or in the past.
+func ParseRetryAfter(v string) time.Duration {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		if secs < 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// redactURL returns the request URL without its query string, which often
+// carries API keys.
+func redactURL(req *http.Request) string {
+	if req.URL == nil {
+		return ""
+	}
+	u := *req.URL
+	u.RawQuery = ""
+	u.User = nil
+	return u.String()
+}
@@ -0,0 +1,54 @@
+package httpx
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		code int
+		want error
+	}{
+		{http.StatusUnauthorized, datasource.ErrUnauthorized},
+		{http.StatusForbidden, datasource.ErrUnauthorized},
+		{http.StatusNotFound, datasource.ErrNotFound},
+		{http.StatusBadGateway, datasource.ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.code), func(t *testing.T) {
+			err := StatusError(&http.Response{StatusCode: tt.code, Status: http.StatusText(tt.code)})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("StatusError(%d) = %v, want %v", tt.code, err, tt.want)
+			}
+		})
+	}
+
+	if err := StatusError(&http.Response{StatusCode: http.StatusNoContent}); err != nil {
+		t.Errorf("expected nil for 2xx, got %v", err)
+	}
+
+	resp := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"12"}}}
+	if d, ok := datasource.RetryAfter(StatusError(resp)); !ok || d != 12*time.Second {
+		t.Errorf("expected RetryAfter 12s, got %v, %v", d, ok)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if got := ParseRetryAfter("30"); got != 30*time.Second {
+		t.Errorf("got %v, want 30s", got)
+	}
+	future := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	if got := ParseRetryAfter(future); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("got %v for HTTP date an hour ahead", got)
+	}
+	for _, v := range []string{"", "soon", "-5"} {
+		if got := ParseRetryAfter(v); got != 0 {
+			t.Errorf("ParseRetryAfter(%q) = %v, want 0", v, got)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package httpx
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// ReadText reads the body of resp as text: decoded to UTF-8 in the charset
+// its Content-Type header, byte order mark, or HTML declares (see
+// content.DecodeText), then repaired with content.Normalize, so that
+// Latin-1 forums and mislabeled wikis yield clean DataText. Bodies longer
+// than maxBytes fail; a maxBytes of zero or less means no limit. The body
+// is not closed.
+func ReadText(resp *http.Response, maxBytes int64) (string, error) {
+	r := io.Reader(resp.Body)
+	if maxBytes > 0 {
+		r = io.LimitReader(resp.Body, maxBytes+1)
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if maxBytes > 0 && int64(len(body)) > maxBytes {
+		return "", fmt.Errorf("httpx: response body exceeds %d bytes", maxBytes)
+	}
+	return content.Normalize(content.DecodeText(body, resp.Header.Get("Content-Type"))), nil
+}
@@ -0,0 +1,27 @@
+package httpx
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReadText(t *testing.T) {
+	resp := func(body, contentType string) *http.Response {
+		return &http.Response{
+			Header: http.Header{"Content-Type": {contentType}},
+			Body:   io.NopCloser(strings.NewReader(body)),
+		}
+	}
+	got, err := ReadText(resp("Caf\xe9 \x93cr\xe8me\x94\x00", "text/html; charset=iso-8859-1"), 0)
+	if err != nil || got != "Café “crème”" {
+		t.Errorf("ReadText = %q, %v", got, err)
+	}
+	if got, err := ReadText(resp("cafÃ©", "text/plain; charset=utf-8"), 7); err != nil || got != "café" {
+		t.Errorf("ReadText of mojibake = %q, %v", got, err)
+	}
+	if _, err := ReadText(resp("too long", "text/plain"), 4); err == nil {
+		t.Error("ReadText over the limit succeeded")
+	}
+}
@@ -0,0 +1,324 @@
+// Package fieldmap maps the fields of JSON results to the fields of topics
+// and data items, for the data sources configured with field mappings.
+package fieldmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// Default field specs used when fields of a Spec are empty.
+const (
+	DefaultID        = "id"
+	DefaultTitle     = "title"
+	DefaultExcerpt   = "excerpt"
+	DefaultText      = "text"
+	DefaultURL       = "url"
+	DefaultScore     = "score"
+	DefaultSite      = "site"
+	DefaultLanguage  = "language"
+	DefaultCreatedAt = "created_at"
+	DefaultUpdatedAt = "updated_at"
+	DefaultAuthor    = "author"
+)
+
+// Spec is the field mapping of a data source's configuration. Each field
+// is a JSONPath into a result or, if it contains "{{", a template
+// executed with the result. Sources declare their own documented type with
+// the same fields and convert it.
+type Spec struct {
+	Items                                      string
+	ID, Title, Excerpt, Text, URL, Score, Site string
+	Language, CreatedAt, UpdatedAt, Author     string
+	Metadata                                   map[string]string
+}
+
+func (s Spec) withDefaults() Spec {
+	for _, f := range []struct {
+		name *string
+		def  string
+	}{
+		{&s.ID, DefaultID},
+		{&s.Title, DefaultTitle},
+		{&s.Excerpt, DefaultExcerpt},
+		{&s.Text, DefaultText},
+		{&s.URL, DefaultURL},
+		{&s.Score, DefaultScore},
+		{&s.Site, DefaultSite},
+		{&s.Language, DefaultLanguage},
+		{&s.CreatedAt, DefaultCreatedAt},
+		{&s.UpdatedAt, DefaultUpdatedAt},
+		{&s.Author, DefaultAuthor},
+	} {
+		if *f.name == "" {
+			*f.name = f.def
+		}
+	}
+	return s
+}
+
+// Funcs are the functions available to templates: query and path (URL
+// escaping), join, and json.
+var Funcs = template.FuncMap{
+	"query": url.QueryEscape,
+	"path":  url.PathEscape,
+	"join":  strings.Join,
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// Execute executes t with data and returns its output.
+func Execute(t *template.Template, data any) (string, error) {
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// field is a compiled Spec field: a JSONPath, or a template executed
+// with the result.
+type field struct {
+	path jsonPath
+	tmpl *template.Template
+}
+
+func compileField(s string) (*field, error) {
+	if strings.Contains(s, "{{") {
+		t, err := template.New("field").Funcs(Funcs).Option("missingkey=error").Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		return &field{tmpl: t}, nil
+	}
+	p, err := compilePath(s)
+	if err != nil {
+		return nil, err
+	}
+	return &field{path: p}, nil
+}
+
+// value returns the field's value in the result: the first value its
+// path selects, or the output of its template. It returns nil if the
+// result has no such value or the template fails on the result.
+func (f *field) value(result any) any {
+	if f.tmpl == nil {
+		return f.path.first(result)
+	}
+	s, err := Execute(f.tmpl, result)
+	if err != nil {
+		return nil
+	}
+	return s
+}
+
+// text returns the field's value in the result as text.
+func (f *field) text(result any) string {
+	return text(f.value(result))
+}
+
+// results returns the results in a response: the elements of the array
+// the field selects, or the values it selects if there are several or
+// the one is not an array.
+func (f *field) results(resp any) []any {
+	if f == nil {
+		f = &field{}
+	}
+	values := f.path.get(resp)
+	if len(values) == 1 {
+		if a, ok := values[0].([]any); ok {
+			return a
+		}
+		if values[0] == nil {
+			return nil
+		}
+	}
+	return values
+}
+
+// Mapping is a compiled Spec.
+type Mapping struct {
+	items                                      *field
+	ID, Title, Excerpt, Text, URL, Score, Site *field
+	Language, CreatedAt, UpdatedAt, Author     *field
+	metadataKeys                               []string
+	metadataFields                             map[string]*field
+}
+
+// Compile compiles the spec, with the defaults of empty fields. Errors
+// name the field, prefixed with name.
+func Compile(name string, spec Spec) (*Mapping, error) {
+	spec = spec.withDefaults()
+	m := &Mapping{metadataFields: make(map[string]*field, len(spec.Metadata))}
+	var err error
+	if spec.Items != "" {
+		if m.items, err = compileField(spec.Items); err != nil {
+			return nil, fmt.Errorf("%s.Items: %w", name, err)
+		}
+		if m.items.tmpl != nil {
+			return nil, fmt.Errorf("%s.Items: must be a JSONPath", name)
+		}
+	}
+	for _, f := range []struct {
+		name string
+		spec string
+		into **field
+	}{
+		{"ID", spec.ID, &m.ID},
+		{"Title", spec.Title, &m.Title},
+		{"Excerpt", spec.Excerpt, &m.Excerpt},
+		{"Text", spec.Text, &m.Text},
+		{"URL", spec.URL, &m.URL},
+		{"Score", spec.Score, &m.Score},
+		{"Site", spec.Site, &m.Site},
+		{"Language", spec.Language, &m.Language},
+		{"CreatedAt", spec.CreatedAt, &m.CreatedAt},
+		{"UpdatedAt", spec.UpdatedAt, &m.UpdatedAt},
+		{"Author", spec.Author, &m.Author},
+	} {
+		if *f.into, err = compileField(f.spec); err != nil {
+			return nil, fmt.Errorf("%s.%s: %w", name, f.name, err)
+		}
+	}
+	for key, s := range spec.Metadata {
+		if m.metadataFields[key], err = compileField(s); err != nil {
+			return nil, fmt.Errorf("%s.Metadata[%q]: %w", name, key, err)
+		}
+		m.metadataKeys = append(m.metadataKeys, key)
+	}
+	sort.Strings(m.metadataKeys)
+	return m, nil
+}
+
+// Results returns the results in a response decoded with numbers as
+// json.Number: the values Items selects, or the elements of the one array
+// it selects.
+func (m *Mapping) Results(resp any) []any {
+	return m.items.results(resp)
+}
+
+// Topic maps a result to a topic. Topics need an integer ID, or numeric
+// text.
+func (m *Mapping) Topic(r any) (datasource.DataSourceTopic, error) {
+	id, ok := integer(m.ID.value(r))
+	if !ok {
+		return datasource.DataSourceTopic{}, fmt.Errorf("result without an integer ID")
+	}
+	t := datasource.DataSourceTopic{
+		SourceURL: m.URL.text(r),
+		Site:      m.Site.text(r),
+		TopicID:   id,
+		Score:     number(m.Score.value(r)),
+		Metadata:  m.metadata(r),
+		Language:  m.Language.text(r),
+		CreatedAt: timestamp(m.CreatedAt.value(r)),
+		UpdatedAt: timestamp(m.UpdatedAt.value(r)),
+	}
+	return t.Structured(m.Title.text(r), m.Excerpt.text(r)), nil
+}
+
+// Data maps the result at index i of a response to a data item, numbered
+// by i if it has no ID, with text of type ct, or detected with
+// content.Detect if ct is empty. The caller sets its Rank.
+func (m *Mapping) Data(r any, i int, ct datasource.ContentType) datasource.DataSourceData {
+	id, ok := integer(m.ID.value(r))
+	if !ok {
+		id = int64(i)
+	}
+	text := m.Text.text(r)
+	if ct == "" {
+		ct = content.Detect(text)
+	}
+	item := datasource.DataSourceData{
+		DataText:    text,
+		ContentType: ct,
+		SourceURL:   m.URL.text(r),
+		Site:        m.Site.text(r),
+		AnswerID:    id,
+		Score:       number(m.Score.value(r)),
+		Metadata:    m.metadata(r),
+		Language:    m.Language.text(r),
+		CreatedAt:   timestamp(m.CreatedAt.value(r)),
+		UpdatedAt:   timestamp(m.UpdatedAt.value(r)),
+	}
+	if author := m.Author.text(r); author != "" {
+		item.Author = &datasource.Author{Name: author}
+	}
+	return item
+}
+
+// metadata returns the result's metadata fields that it has.
+func (m *Mapping) metadata(result any) datasource.Metadata {
+	var md datasource.Metadata
+	for _, key := range m.metadataKeys {
+		switch v := m.metadataFields[key].value(result).(type) {
+		case nil:
+		case json.Number:
+			if n, err := v.Int64(); err == nil {
+				md.Set(key, n)
+			} else if f, err := v.Float64(); err == nil {
+				md.Set(key, f)
+			}
+		default:
+			md.Set(key, v)
+		}
+	}
+	return md
+}
+
+// integer returns v, a number or numeric text, as an integer.
+func integer(v any) (int64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		n, err := v.Int64()
+		return n, err == nil
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// number returns v, a number or numeric text, as a float, or 0.
+func number(v any) float64 {
+	var f float64
+	switch v := v.(type) {
+	case json.Number:
+		f, _ = v.Float64()
+	case string:
+		f, _ = strconv.ParseFloat(strings.TrimSpace(v), 64)
+	}
+	return f
+}
+
+// timeLayouts are the layouts of times given as text.
+var timeLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05", "2006-01-02"}
+
+// timestamp returns v, Unix seconds or text in one of timeLayouts, as a
+// time, or the zero time.
+func timestamp(v any) time.Time {
+	switch v := v.(type) {
+	case json.Number:
+		if f, err := v.Float64(); err == nil && f > 0 {
+			return time.Unix(0, int64(f*float64(time.Second))).UTC()
+		}
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
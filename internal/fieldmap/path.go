@@ -0,0 +1,141 @@
+package fieldmap
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// step is one segment of a JSONPath: a member name, an array index, or a
+// wildcard matching every member or element.
+type step struct {
+	key      string
+	index    int
+	isIndex  bool
+	wildcard bool
+}
+
+// jsonPath is a compiled JSONPath of the subset the package supports:
+// $, .name, ['name'], [index] (negative counts from the end), .* and [*].
+type jsonPath []step
+
+// compilePath compiles a JSONPath. Paths not starting with "$" are taken
+// relative to the root, so "title" is "$.title".
+func compilePath(s string) (jsonPath, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return nil, fmt.Errorf("empty path")
+	case strings.HasPrefix(s, "$"):
+		s = s[1:]
+	case !strings.HasPrefix(s, "["):
+		s = "." + s
+	}
+	var p jsonPath
+	for s != "" {
+		switch s[0] {
+		case '.':
+			end := strings.IndexAny(s[1:], ".[")
+			if end < 0 {
+				end = len(s) - 1
+			}
+			name := s[1 : end+1]
+			if name == "" {
+				return nil, fmt.Errorf("empty member name")
+			}
+			p = append(p, step{key: name, wildcard: name == "*"})
+			s = s[end+1:]
+		case '[':
+			end := strings.IndexByte(s, ']')
+			if q := s[1:min(2, len(s))]; q == "'" || q == `"` {
+				end = strings.Index(s[2:], q+"]")
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated member name in %q", s)
+				}
+				p = append(p, step{key: s[2 : end+2]})
+				s = s[end+4:]
+				continue
+			}
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated subscript in %q", s)
+			}
+			sub := strings.TrimSpace(s[1:end])
+			if sub == "*" {
+				p = append(p, step{wildcard: true})
+			} else {
+				i, err := strconv.Atoi(sub)
+				if err != nil {
+					return nil, fmt.Errorf("subscript %q is not an index, a quoted name, or *", sub)
+				}
+				p = append(p, step{index: i, isIndex: true})
+			}
+			s = s[end+1:]
+		default:
+			return nil, fmt.Errorf("unexpected %q", s)
+		}
+	}
+	return p, nil
+}
+
+// get returns the values the path selects in v, a value decoded from
+// JSON. Paths without wildcards select at most one value.
+func (p jsonPath) get(v any) []any {
+	values := []any{v}
+	for _, st := range p {
+		var next []any
+		for _, v := range values {
+			switch v := v.(type) {
+			case map[string]any:
+				if st.wildcard {
+					for _, x := range v {
+						next = append(next, x)
+					}
+				} else if x, ok := v[st.key]; ok && !st.isIndex {
+					next = append(next, x)
+				}
+			case []any:
+				switch {
+				case st.wildcard:
+					next = append(next, v...)
+				case st.isIndex:
+					i := st.index
+					if i < 0 {
+						i += len(v)
+					}
+					if i >= 0 && i < len(v) {
+						next = append(next, v[i])
+					}
+				}
+			}
+		}
+		values = next
+	}
+	return values
+}
+
+// first returns the first value the path selects in v, or nil.
+func (p jsonPath) first(v any) any {
+	if values := p.get(v); len(values) > 0 {
+		return values[0]
+	}
+	return nil
+}
+
+// text formats a value decoded from JSON as text: strings as they are,
+// numbers in their JSON form, null as "", and objects and arrays as JSON.
+func text(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		b, _ := json.Marshal(v)
+		return string(b)
+	}
+}
@@ -0,0 +1,53 @@
+package fieldmap
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestJSONPath(t *testing.T) {
+	dec := json.NewDecoder(strings.NewReader(`{
+		"data": {"hits": [{"id": 1, "tags": ["a", "b"]}, {"id": 2, "tags": []}]},
+		"odd key": {"x.y": "z"}
+	}`))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"$.data.hits[0].id", []string{"1"}},
+		{"data.hits[-1].id", []string{"2"}},
+		{"$.data.hits[*].id", []string{"1", "2"}},
+		{"$.data.hits[*].tags[0]", []string{"a"}},
+		{`$['odd key']["x.y"]`, []string{"z"}},
+		{"$.data.hits[5]", nil},
+		{"$.data.missing.id", nil},
+		{"$.data.hits.id", nil},
+	}
+	for _, tt := range tests {
+		p, err := compilePath(tt.path)
+		if err != nil {
+			t.Errorf("compilePath(%q): %v", tt.path, err)
+			continue
+		}
+		var got []string
+		for _, v := range p.get(doc) {
+			got = append(got, text(v))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "$.", "$[x]", "$['open", "$[1"} {
+		if _, err := compilePath(bad); err == nil {
+			t.Errorf("compilePath(%q) succeeded", bad)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+// Package topicmap provides a bounded table of values by topic ID, for
+// decorators and multiplexers that remember something about the topics
+// they return until the FetchData calls that follow.
+package topicmap
+
+import (
+	"sync"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Max bounds the topic IDs a Map remembers; the oldest are forgotten
+// first.
+const Max = 4096
+
+// Map remembers a value for each topic ID, forgetting the oldest beyond
+// Max. The zero value is ready to use, and a Map is safe for concurrent
+// use.
+type Map[V any] struct {
+	mu     sync.Mutex
+	values map[int64]V
+	order  []int64 // keys of values, oldest first
+}
+
+// Set records v for each of topics, replacing earlier values.
+func (m *Map[V]) Set(topics []datasource.DataSourceTopic, v V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, t := range topics {
+		m.store(t.TopicID, v)
+	}
+	m.trim()
+}
+
+// Put records v for topicID, replacing an earlier value.
+func (m *Map[V]) Put(topicID int64, v V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.store(topicID, v)
+	m.trim()
+}
+
+// Update records f of the value recorded for topicID, and whether there
+// was one, as a single step.
+func (m *Map[V]) Update(topicID int64, f func(old V, ok bool) V) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.values[topicID]
+	m.store(topicID, f(old, ok))
+	m.trim()
+}
+
+// Get returns the value recorded for topicID.
+func (m *Map[V]) Get(topicID int64) (V, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	v, ok := m.values[topicID]
+	return v, ok
+}
+
+func (m *Map[V]) store(topicID int64, v V) {
+	if m.values == nil {
+		m.values = make(map[int64]V)
+	}
+	if _, ok := m.values[topicID]; !ok {
+		m.order = append(m.order, topicID)
+	}
+	m.values[topicID] = v
+}
+
+// trim forgets the oldest topics beyond Max.
+func (m *Map[V]) trim() {
+	for len(m.order) > Max {
+		delete(m.values, m.order[0])
+		m.order = m.order[1:]
+	}
+}
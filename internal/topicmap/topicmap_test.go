@@ -0,0 +1,30 @@
+package topicmap
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestMapForgetsOldest(t *testing.T) {
+	var m Map[string]
+	m.Set([]datasource.DataSourceTopic{{TopicID: 1}, {TopicID: 2}}, "a")
+	m.Update(2, func(old string, ok bool) string {
+		if !ok || old != "a" {
+			t.Errorf("Update saw %q, %v", old, ok)
+		}
+		return "b"
+	})
+	if v, ok := m.Get(2); !ok || v != "b" {
+		t.Errorf("Get(2) = %q, %v; want b", v, ok)
+	}
+	for id := int64(3); id < Max+2; id++ {
+		m.Update(id, func(string, bool) string { return "c" })
+	}
+	if _, ok := m.Get(1); ok {
+		t.Error("oldest topic not forgotten")
+	}
+	if v, ok := m.Get(2); !ok || v != "b" {
+		t.Errorf("Get(2) = %q, %v; want it kept", v, ok)
+	}
+}
@@ -0,0 +1,78 @@
+// Package wire holds encodings shared by the SDK's transports.
+package wire
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Error carries a data source error across a process boundary, keeping its
+// class so the receiving side can still use errors.Is with the SDK's
+// sentinel errors, IsRetryable, and RetryAfter.
+type Error struct {
+	// Class is the datasource.ErrorClass of the original error
+	Class string `json:"class"`
+
+	// Message is the original error text
+	Message string `json:"message"`
+
+	// RetryAfterMillis is the rate limit hint, if any
+	RetryAfterMillis int64 `json:"retry_after_ms,omitempty"`
+}
+
+// EncodeError converts err for transmission. It returns nil for a nil err.
+func EncodeError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	e := &Error{Class: datasource.ErrorClass(err), Message: err.Error()}
+	if d, ok := datasource.RetryAfter(err); ok {
+		e.RetryAfterMillis = d.Milliseconds()
+	}
+	return e
+}
+
+// Err reconstructs the error. Its message is the original text and it
+// wraps the sentinel error matching Class. A nil *Error yields nil.
+func (e *Error) Err() error {
+	if e == nil {
+		return nil
+	}
+	msg := errors.New(e.Message)
+	var sentinel error
+	switch e.Class {
+	case "rate_limited":
+		return &remoteError{msg: e.Message, err: &datasource.ErrRateLimited{
+			RetryAfter: time.Duration(e.RetryAfterMillis) * time.Millisecond,
+			Err:        msg,
+		}}
+	case "unavailable":
+		sentinel = datasource.ErrUnavailable
+	case "unauthorized":
+		sentinel = datasource.ErrUnauthorized
+	case "not_found":
+		sentinel = datasource.ErrNotFound
+	case "quota_exceeded":
+		sentinel = datasource.ErrQuotaExceeded
+	case "canceled":
+		sentinel = context.Canceled
+	case "deadline_exceeded":
+		sentinel = context.DeadlineExceeded
+	default:
+		return msg
+	}
+	return &remoteError{msg: e.Message, err: sentinel}
+}
+
+// remoteError reports the original message while unwrapping to the
+// reconstructed class.
+type remoteError struct {
+	msg string
+	err error
+}
+
+func (e *remoteError) Error() string { return e.msg }
+func (e *remoteError) Unwrap() error { return e.err }
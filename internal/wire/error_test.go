@@ -0,0 +1,49 @@
+package wire
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestErrorRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		is   error
+	}{
+		{"not found", fmt.Errorf("topic 7: %w", datasource.ErrNotFound), datasource.ErrNotFound},
+		{"unavailable", datasource.ErrUnavailable, datasource.ErrUnavailable},
+		{"deadline", fmt.Errorf("search: %w", context.DeadlineExceeded), context.DeadlineExceeded},
+		{"other", errors.New("boom"), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EncodeError(tt.err).Err()
+			if got.Error() != tt.err.Error() {
+				t.Errorf("message %q, want %q", got, tt.err)
+			}
+			if tt.is != nil && !errors.Is(got, tt.is) {
+				t.Errorf("%v does not wrap %v", got, tt.is)
+			}
+			if datasource.ErrorClass(got) != datasource.ErrorClass(tt.err) {
+				t.Errorf("class %s, want %s", datasource.ErrorClass(got), datasource.ErrorClass(tt.err))
+			}
+		})
+	}
+}
+
+func TestRateLimitRoundTrip(t *testing.T) {
+	err := EncodeError(&datasource.ErrRateLimited{RetryAfter: 3 * time.Second}).Err()
+	if d, ok := datasource.RetryAfter(err); !ok || d != 3*time.Second {
+		t.Errorf("RetryAfter = %v, %v", d, ok)
+	}
+	if EncodeError(nil) != nil || (*Error)(nil).Err() != nil {
+		t.Error("nil errors should stay nil")
+	}
+}
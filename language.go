@@ -0,0 +1,31 @@
+package datasource
+
+import "strings"
+
+// AcceptsLanguage reports whether content in the language tagged lang may
+// be shown to an asker who reads the languages in accept (see
+// NewQuestionInput.AcceptLanguages). Tags match case-insensitively when
+// they are equal or one is a prefix of the other ending at a subtag, so
+// "en" accepts "en-GB" and "pt-BR" accepts "pt"; "*" accepts every
+// language. Content of unknown language, and askers who accept no
+// language in particular, always match.
+func AcceptsLanguage(accept []string, lang string) bool {
+	if lang == "" || len(accept) == 0 {
+		return true
+	}
+	for _, a := range accept {
+		if a = strings.TrimSpace(a); a == "*" || languagePrefix(a, lang) || languagePrefix(lang, a) {
+			return true
+		}
+	}
+	return false
+}
+
+// languagePrefix reports whether the tag prefix is tag or a prefix of it
+// ending at a subtag boundary.
+func languagePrefix(prefix, tag string) bool {
+	if prefix == "" || len(prefix) > len(tag) || !strings.EqualFold(prefix, tag[:len(prefix)]) {
+		return false
+	}
+	return len(prefix) == len(tag) || tag[len(prefix)] == '-'
+}
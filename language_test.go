@@ -0,0 +1,32 @@
+package datasource_test
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestAcceptsLanguage(t *testing.T) {
+	tests := []struct {
+		accept []string
+		lang   string
+		want   bool
+	}{
+		{nil, "de", true},
+		{[]string{"en"}, "", true},
+		{[]string{"en"}, "en", true},
+		{[]string{"en"}, "EN-gb", true},
+		{[]string{"pt-BR"}, "pt", true},
+		{[]string{"pt-BR"}, "pt-PT", false},
+		{[]string{"en"}, "eng", false},
+		{[]string{"zh-Hant"}, "zh-Hant-TW", true},
+		{[]string{"fr", "de"}, "de-CH", true},
+		{[]string{"fr", "de"}, "it", false},
+		{[]string{" * "}, "it", true},
+	}
+	for _, tt := range tests {
+		if got := datasource.AcceptsLanguage(tt.accept, tt.lang); got != tt.want {
+			t.Errorf("AcceptsLanguage(%q, %q) = %v, want %v", tt.accept, tt.lang, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Closer is an optional interface for data sources that hold connections,
+// goroutines, or file handles, and is the counterpart of Init. Close
+// releases them, giving up on graceful cleanup when ctx is done. Calls made
+// after Close should fail with ErrUnavailable.
+//
+// Decorators that implement Close must release only their own resources:
+// Shutdown closes the sources they wrap.
+type Closer interface {
+	Close(ctx context.Context) error
+}
+
+// Flusher is an optional interface for data sources and decorators that
+// buffer state worth keeping, such as cache snapshots, sync checkpoints, or
+// batched writes. Flush persists it without releasing any resources, giving
+// up when ctx is done.
+type Flusher interface {
+	Flush(ctx context.Context) error
+}
+
+// Snapshotter is an optional interface for data sources holding local
+// state that is slow to rebuild, such as a crawled or pretrained index.
+// WriteSnapshot exports the state, and ReadSnapshot, called before Init,
+// loads an export so that Init need not rebuild it: a snapshot built in
+// one environment, such as CI, can ship with the deployments of another
+// so new replicas do not start cold.
+type Snapshotter interface {
+	WriteSnapshot(w io.Writer) error
+	ReadSnapshot(r io.Reader) error
+}
+
+// SnapshotterOf returns the first Snapshotter in ds's decorator chain
+// (following Unwrap methods), and false if there is none.
+func SnapshotterOf(ds DataSource) (Snapshotter, bool) {
+	return find[Snapshotter](ds)
+}
+
+// Flush flushes ds and every source it wraps, outermost first, following
+// Unwrap methods down the decorator chain, and returns every error joined.
+func Flush(ctx context.Context, ds DataSource) error {
+	var errs []error
+	for ; ds != nil; ds = unwrap(ds) {
+		if f, ok := ds.(Flusher); ok {
+			errs = append(errs, f.Flush(ctx))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Shutdown closes ds and every source it wraps, outermost first, following
+// Unwrap methods down the decorator chain. Layers implementing Closer (or
+// io.Closer) are closed; a draining layer such as middleware.Drain waits for
+// in-flight calls before the sources beneath it release their resources.
+//
+// If ctx is done while a layer is closing, Shutdown still closes the layers
+// beneath it, and returns every error joined.
+func Shutdown(ctx context.Context, ds DataSource) error {
+	var errs []error
+	for ; ds != nil; ds = unwrap(ds) {
+		switch c := ds.(type) {
+		case Closer:
+			errs = append(errs, c.Close(ctx))
+		case io.Closer:
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,96 @@
+package datasource_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+type closingSource struct {
+	datasource.DataSource
+	name   string
+	err    error
+	closed *[]string
+}
+
+func (s *closingSource) Close(ctx context.Context) error {
+	*s.closed = append(*s.closed, s.name)
+	return s.err
+}
+
+func (s *closingSource) Unwrap() datasource.DataSource { return s.DataSource }
+
+// legacySource implements io.Closer rather than Closer.
+type legacySource struct {
+	ExampleDataSource
+	closed *[]string
+}
+
+func (s *legacySource) Close() error {
+	*s.closed = append(*s.closed, "legacy")
+	return nil
+}
+
+// passthrough is a decorator that holds no resources.
+type passthrough struct{ datasource.DataSource }
+
+func (p passthrough) Unwrap() datasource.DataSource { return p.DataSource }
+
+func TestShutdownClosesChainOutermostFirst(t *testing.T) {
+	var closed []string
+	boom := errors.New("boom")
+	ds := &closingSource{
+		name:   "outer",
+		err:    boom,
+		closed: &closed,
+		DataSource: passthrough{&closingSource{
+			name:       "inner",
+			closed:     &closed,
+			DataSource: &legacySource{closed: &closed},
+		}},
+	}
+
+	err := datasource.Shutdown(context.Background(), ds)
+	if !errors.Is(err, boom) {
+		t.Errorf("Shutdown = %v, want the outer error", err)
+	}
+	if len(closed) != 3 || closed[0] != "outer" || closed[1] != "inner" || closed[2] != "legacy" {
+		t.Errorf("closed %v, want outer, inner, legacy", closed)
+	}
+}
+
+func TestShutdownWithoutClosers(t *testing.T) {
+	if err := datasource.Shutdown(context.Background(), &ExampleDataSource{}); err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+}
+
+type flushingSource struct {
+	datasource.DataSource
+	name    string
+	flushed *[]string
+}
+
+func (s *flushingSource) Flush(ctx context.Context) error {
+	*s.flushed = append(*s.flushed, s.name)
+	return nil
+}
+
+func (s *flushingSource) Unwrap() datasource.DataSource { return s.DataSource }
+
+func TestFlushChainOutermostFirst(t *testing.T) {
+	var flushed []string
+	ds := &flushingSource{
+		name:       "outer",
+		flushed:    &flushed,
+		DataSource: passthrough{&flushingSource{name: "inner", flushed: &flushed, DataSource: &ExampleDataSource{}}},
+	}
+	if err := datasource.Flush(context.Background(), ds); err != nil {
+		t.Errorf("Flush = %v", err)
+	}
+	if len(flushed) != 2 || flushed[0] != "outer" || flushed[1] != "inner" {
+		t.Errorf("flushed %v, want outer, inner", flushed)
+	}
+}
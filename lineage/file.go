@@ -0,0 +1,165 @@
+package lineage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileStore is a Store persisting records to a file of JSON lines, one per
+// Put, so lineage survives restarts. Records are also held in memory for
+// lookups; Prune keeps the file, and memory, bounded.
+type FileStore struct {
+	path string
+
+	mu   sync.Mutex
+	f    *os.File
+	mem  *MemoryStore
+	dead int // lines in the file for replaced records
+}
+
+// OpenFile opens the FileStore at path, creating the file if needed and
+// loading the records in it.
+func OpenFile(path string) (*FileStore, error) {
+	s := &FileStore{path: path, mem: NewMemoryStore(0)}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("lineage: %w", err)
+	}
+	sc := bufio.NewScanner(f)
+	sc.Buffer(nil, 64<<20)
+	for line := 1; sc.Scan(); line++ {
+		if len(sc.Bytes()) == 0 {
+			continue
+		}
+		var r Record
+		if err := json.Unmarshal(sc.Bytes(), &r); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("lineage: %s:%d: %w", path, line, err)
+		}
+		if _, ok := s.mem.records[r.ID]; ok {
+			s.dead++
+		}
+		s.mem.put(r)
+	}
+	if err := sc.Err(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("lineage: %w", err)
+	}
+	s.f = f
+	return s, nil
+}
+
+// Put appends the record to the file.
+func (s *FileStore) Put(r Record) error {
+	b, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("lineage: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return errors.New("lineage: store closed")
+	}
+	if _, err := s.f.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("lineage: %w", err)
+	}
+	if s.has(r.ID) {
+		s.dead++
+	}
+	return s.mem.Put(r)
+}
+
+func (s *FileStore) has(id string) bool {
+	s.mem.mu.RLock()
+	defer s.mem.mu.RUnlock()
+	_, ok := s.mem.records[id]
+	return ok
+}
+
+// ByURL returns the records with an item from the address, newest first.
+func (s *FileStore) ByURL(addr string) ([]Record, error) {
+	return s.mem.ByURL(addr)
+}
+
+// ByTopic returns the records with an item of the topic, newest first.
+func (s *FileStore) ByTopic(topicID int64) ([]Record, error) {
+	return s.mem.ByTopic(topicID)
+}
+
+// Prune removes the records older than before, rewriting the file without
+// them and without the lines of replaced records.
+func (s *FileStore) Prune(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return 0, errors.New("lineage: store closed")
+	}
+	n, _ := s.mem.Prune(before)
+	if n == 0 && s.dead == 0 {
+		return 0, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return n, fmt.Errorf("lineage: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	w := bufio.NewWriter(tmp)
+	s.mem.mu.RLock()
+	records := s.mem.sorted(s.mem.records)
+	s.mem.mu.RUnlock()
+	for i := len(records) - 1; i >= 0; i-- {
+		b, err := json.Marshal(records[i])
+		if err != nil {
+			tmp.Close()
+			return n, fmt.Errorf("lineage: %w", err)
+		}
+		w.Write(append(b, '\n'))
+	}
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return n, fmt.Errorf("lineage: %w", err)
+	}
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return n, fmt.Errorf("lineage: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return n, fmt.Errorf("lineage: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return n, fmt.Errorf("lineage: %w", err)
+	}
+	f, err := os.OpenFile(s.path, os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		s.f.Close()
+		s.f = nil
+		return n, fmt.Errorf("lineage: reopen: %w", err)
+	}
+	s.f.Close()
+	s.f, s.dead = f, 0
+	return n, nil
+}
+
+// Len returns the number of records held.
+func (s *FileStore) Len() int {
+	return s.mem.Len()
+}
+
+// Close closes the file. The store cannot be used after.
+func (s *FileStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.f == nil {
+		return nil
+	}
+	err := s.f.Close()
+	s.f = nil
+	return err
+}
@@ -0,0 +1,158 @@
+// Package lineage records which topics and data items each answer was
+// built from, so that when upstream content is corrected or deleted, the
+// host can find every past answer that used it and refresh or retract it.
+//
+// The host records an answer once it is composed, and looks answers up by
+// address or topic ID when it learns of a change:
+//
+//	store, err := lineage.OpenFile("/var/lib/locus/lineage.jsonl")
+//	...
+//	store.Put(lineage.NewRecord(answerID, input, topics, data))
+//	...
+//	affected, err := store.ByURL("https://wiki.example.com/vpn")
+//
+// Items carry a hash of the content used, so hosts can tell answers built
+// from superseded content (see Record.Stale).
+package lineage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// Record is an answered question and the results it was built from.
+type Record struct {
+	// ID identifies the answer in the host, such as a message ID
+	ID string `json:"id"`
+
+	// Query is the question text
+	Query string `json:"query"`
+
+	// TenantID is the question's tenant
+	TenantID string `json:"tenant_id,omitempty"`
+
+	// AskedBy is the asker's ID, if known
+	AskedBy *int64 `json:"asked_by,omitempty"`
+
+	// Time is when the answer was given
+	Time time.Time `json:"time"`
+
+	// Items are the topics and data items used
+	Items []Item `json:"items"`
+}
+
+// Item is a topic or data item an answer used.
+type Item struct {
+	// Source names the data source of the item, when answers combine
+	// several
+	// Optional
+	Source string `json:"source,omitempty"`
+
+	// TopicID is the topic's ID, or for a data item, its topic's
+	TopicID int64 `json:"topic_id"`
+
+	// Data means the item is a data item rather than a topic
+	Data bool `json:"data,omitempty"`
+
+	// AnswerID is the data item's ID
+	AnswerID int64 `json:"answer_id,omitempty"`
+
+	// URL is the item's SourceURL
+	URL string `json:"url,omitempty"`
+
+	// ContentHash is the hex SHA-256 of the content used: a topic's title,
+	// or a data item's DataText (see Hash)
+	ContentHash string `json:"content_hash"`
+}
+
+// Hash returns the ContentHash of content.
+func Hash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewRecord returns the record of an answer to input built from topics and
+// data, the items fetched for each topic by topic ID, timestamped now. An
+// empty id is replaced by a random one.
+func NewRecord(id string, input datasource.NewQuestionInput, topics []datasource.DataSourceTopic, data map[int64][]datasource.DataSourceData) Record {
+	if id == "" {
+		id = newID()
+	}
+	r := Record{
+		ID:       id,
+		Query:    input.QuestionText,
+		TenantID: input.TenantID,
+		AskedBy:  input.AskedBy,
+		Time:     now().UTC(),
+	}
+	for _, t := range topics {
+		r.Items = append(r.Items, Item{TopicID: t.TopicID, URL: t.SourceURL, ContentHash: Hash(t.Topic)})
+		for _, d := range data[t.TopicID] {
+			r.Items = append(r.Items, Item{TopicID: t.TopicID, Data: true, AnswerID: d.AnswerID, URL: d.SourceURL, ContentHash: Hash(d.DataText)})
+		}
+	}
+	return r
+}
+
+// Stale returns the items of r from the address whose content differs
+// from the current content's hash, as from Hash. Addresses are compared
+// as by Store.ByURL.
+func (r Record) Stale(addr, currentHash string) []Item {
+	key := urlKey(addr)
+	var stale []Item
+	for _, it := range r.Items {
+		if urlKey(it.URL) == key && it.ContentHash != currentHash {
+			stale = append(stale, it)
+		}
+	}
+	return stale
+}
+
+// Store holds records. Implementations must be safe for concurrent use.
+type Store interface {
+	// Put adds a record, replacing any with the same ID
+	Put(r Record) error
+
+	// ByURL returns the records with an item from the address, newest
+	// first. Fragments are ignored, so the address of a page finds
+	// answers that cited its sections
+	ByURL(addr string) ([]Record, error)
+
+	// ByTopic returns the records with an item of the topic, newest first
+	ByTopic(topicID int64) ([]Record, error)
+
+	// Prune removes the records older than before, returning how many
+	// were removed
+	Prune(before time.Time) (int, error)
+}
+
+var (
+	_ Store = (*MemoryStore)(nil)
+	_ Store = (*FileStore)(nil)
+)
+
+// urlKey returns the address without its fragment, as records are
+// indexed by.
+func urlKey(addr string) string {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return addr
+	}
+	u.Fragment, u.RawFragment = "", ""
+	return u.String()
+}
+
+// newID returns a random record ID.
+func newID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
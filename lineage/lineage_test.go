@@ -0,0 +1,124 @@
+package lineage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// answer records an answer citing the VPN page, or its section, at
+// minute m.
+func answer(id string, m int, section bool) Record {
+	now = func() time.Time { return time.Date(2026, 5, 1, 12, m, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+	topics := []datasource.DataSourceTopic{
+		{Topic: "VPN setup", TopicID: 7, SourceURL: "https://wiki.example.com/vpn"},
+		{Topic: "Wi-Fi", TopicID: 8, SourceURL: "https://wiki.example.com/wifi"},
+	}
+	data := map[int64][]datasource.DataSourceData{
+		7: {{DataText: "Install the client.", AnswerID: 0, SourceURL: "https://wiki.example.com/vpn"}},
+	}
+	if section {
+		data[7][0].SourceURL += "#install"
+	}
+	return NewRecord(id, datasource.NewQuestionInput{QuestionText: "how do I set up the vpn", TenantID: "acme"}, topics, data)
+}
+
+func ids(records []Record) string {
+	var s []string
+	for _, r := range records {
+		s = append(s, r.ID)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestNewRecord(t *testing.T) {
+	r := answer("", 0, false)
+	if len(r.ID) != 32 || r.Query != "how do I set up the vpn" || r.TenantID != "acme" || len(r.Items) != 3 {
+		t.Fatalf("record = %+v", r)
+	}
+	if it := r.Items[1]; !it.Data || it.TopicID != 7 || it.ContentHash != Hash("Install the client.") {
+		t.Errorf("data item = %+v", it)
+	}
+	if stale := r.Stale("https://wiki.example.com/vpn#top", Hash("Install the client.")); len(stale) != 1 || stale[0].Data {
+		t.Errorf("stale = %+v, want the topic, whose title hash differs", stale)
+	}
+}
+
+func testStore(t *testing.T, s Store) {
+	t.Helper()
+	for i, id := range []string{"a", "b", "c"} {
+		if err := s.Put(answer(id, i, id == "b")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	unrelated := Record{ID: "d", Time: time.Date(2026, 5, 1, 13, 0, 0, 0, time.UTC), Items: []Item{{TopicID: 9, URL: "https://wiki.example.com/mail"}}}
+	s.Put(unrelated)
+
+	if got, _ := s.ByURL("https://wiki.example.com/vpn"); ids(got) != "c,b,a" {
+		t.Errorf("ByURL = %s, want newest first, sections included", ids(got))
+	}
+	if got, _ := s.ByTopic(9); ids(got) != "d" {
+		t.Errorf("ByTopic(9) = %s", ids(got))
+	}
+	// Replacing a record reindexes it.
+	unrelated.Items[0].TopicID = 10
+	s.Put(unrelated)
+	if got, _ := s.ByTopic(9); len(got) != 0 {
+		t.Errorf("ByTopic(9) after replacement = %s", ids(got))
+	}
+
+	n, err := s.Prune(time.Date(2026, 5, 1, 12, 1, 30, 0, time.UTC))
+	if err != nil || n != 2 {
+		t.Errorf("Prune = %d, %v; want 2 removed", n, err)
+	}
+	if got, _ := s.ByTopic(7); ids(got) != "c" {
+		t.Errorf("ByTopic(7) after Prune = %s", ids(got))
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	testStore(t, NewMemoryStore(0))
+
+	s := NewMemoryStore(2)
+	for i, id := range []string{"a", "b", "c"} {
+		s.Put(answer(id, i, false))
+	}
+	if got, _ := s.ByTopic(7); s.Len() != 2 || ids(got) != "c,b" {
+		t.Errorf("records = %s, want the oldest evicted", ids(got))
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lineage.jsonl")
+	s, err := OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testStore(t, s)
+	s.Put(answer("e", 5, false))
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got, _ := s.ByURL("https://wiki.example.com/vpn"); s.Len() != 3 || ids(got) != "e,c" {
+		t.Errorf("reopened: %d records, ByURL = %s", s.Len(), ids(got))
+	}
+	if got, _ := s.ByTopic(10); ids(got) != "d" {
+		t.Errorf("reopened: ByTopic(10) = %s, want the replacement", ids(got))
+	}
+
+	os.WriteFile(path, []byte("{not json\n"), 0o600)
+	if _, err := OpenFile(path); err == nil || !strings.Contains(err.Error(), ":1:") {
+		t.Errorf("OpenFile of a corrupt file = %v, want the line reported", err)
+	}
+}
@@ -0,0 +1,147 @@
+package lineage
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a Store keeping records in memory, up to a maximum; past
+// it, the oldest records are evicted.
+type MemoryStore struct {
+	max int
+
+	mu      sync.RWMutex
+	records map[string]Record
+	byURL   map[string]map[string]bool // record IDs by URL key
+	byTopic map[int64]map[string]bool  // record IDs by topic ID
+}
+
+// NewMemoryStore returns a MemoryStore holding at most maxRecords records,
+// or any number if maxRecords is zero or less.
+func NewMemoryStore(maxRecords int) *MemoryStore {
+	return &MemoryStore{
+		max:     maxRecords,
+		records: make(map[string]Record),
+		byURL:   make(map[string]map[string]bool),
+		byTopic: make(map[int64]map[string]bool),
+	}
+}
+
+// Put adds a record, evicting the oldest records past the maximum.
+func (s *MemoryStore) Put(r Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.put(r)
+	if s.max <= 0 || len(s.records) <= s.max {
+		return nil
+	}
+	for _, old := range s.sorted(s.records)[s.max:] {
+		s.remove(old.ID)
+	}
+	return nil
+}
+
+// put adds a copy of a record. The caller holds s.mu.
+func (s *MemoryStore) put(r Record) {
+	s.remove(r.ID)
+	r.Items = append([]Item(nil), r.Items...)
+	s.records[r.ID] = r
+	for _, it := range r.Items {
+		if it.URL != "" {
+			add(s.byURL, urlKey(it.URL), r.ID)
+		}
+		add(s.byTopic, it.TopicID, r.ID)
+	}
+}
+
+// remove removes the record with the ID, if any. The caller holds s.mu.
+func (s *MemoryStore) remove(id string) {
+	r, ok := s.records[id]
+	if !ok {
+		return
+	}
+	delete(s.records, id)
+	for _, it := range r.Items {
+		if it.URL != "" {
+			drop(s.byURL, urlKey(it.URL), id)
+		}
+		drop(s.byTopic, it.TopicID, id)
+	}
+}
+
+func add[K comparable](index map[K]map[string]bool, key K, id string) {
+	if index[key] == nil {
+		index[key] = make(map[string]bool)
+	}
+	index[key][id] = true
+}
+
+func drop[K comparable](index map[K]map[string]bool, key K, id string) {
+	delete(index[key], id)
+	if len(index[key]) == 0 {
+		delete(index, key)
+	}
+}
+
+// ByURL returns the records with an item from the address, newest first.
+func (s *MemoryStore) ByURL(addr string) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lookup(s.byURL[urlKey(addr)]), nil
+}
+
+// ByTopic returns the records with an item of the topic, newest first.
+func (s *MemoryStore) ByTopic(topicID int64) ([]Record, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lookup(s.byTopic[topicID]), nil
+}
+
+// lookup returns copies of the records with the IDs, newest first. The
+// caller holds s.mu.
+func (s *MemoryStore) lookup(ids map[string]bool) []Record {
+	found := make(map[string]Record, len(ids))
+	for id := range ids {
+		r := s.records[id]
+		r.Items = append([]Item(nil), r.Items...)
+		found[id] = r
+	}
+	return s.sorted(found)
+}
+
+// sorted returns the records, newest first.
+func (s *MemoryStore) sorted(records map[string]Record) []Record {
+	out := make([]Record, 0, len(records))
+	for _, r := range records {
+		out = append(out, r)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].Time.Equal(out[j].Time) {
+			return out[i].Time.After(out[j].Time)
+		}
+		return out[i].ID < out[j].ID
+	})
+	return out
+}
+
+// Prune removes the records older than before.
+func (s *MemoryStore) Prune(before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for id, r := range s.records {
+		if r.Time.Before(before) {
+			s.remove(id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// Len returns the number of records held.
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.records)
+}
@@ -0,0 +1,113 @@
+package datasource
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// Metadata holds source-specific fields of a topic or data item, such as
+// view counts, categories, or confidence, that have no field of their own.
+// Values must be JSON-serializable. Keys are chosen by the source; prefer
+// lower_snake_case, as in the struct tags.
+//
+// After a round trip through JSON (as across the remote and plugin
+// transports) numbers decode as float64 and lists as []any, so read values
+// with the typed getters rather than type assertions:
+//
+//	t.Metadata.Set("view_count", 1523)
+//	views, ok := t.Metadata.Int("view_count")
+//
+// For structured values with a Go type, use Extra.
+type Metadata map[string]any
+
+// Set stores v under key, allocating the map if needed.
+func (m *Metadata) Set(key string, v any) {
+	if *m == nil {
+		*m = make(Metadata)
+	}
+	(*m)[key] = v
+}
+
+// String returns the string stored under key.
+func (m Metadata) String(key string) (string, bool) {
+	s, ok := m[key].(string)
+	return s, ok
+}
+
+// Bool returns the bool stored under key.
+func (m Metadata) Bool(key string) (bool, bool) {
+	b, ok := m[key].(bool)
+	return b, ok
+}
+
+// Float returns the number stored under key, of any numeric type.
+func (m Metadata) Float(key string) (float64, bool) {
+	switch v := m[key].(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	}
+	if i, ok := m.Int(key); ok {
+		return float64(i), true
+	}
+	return 0, false
+}
+
+// Int returns the integer stored under key, of any integer type, or a
+// float64 with an integral value as produced by JSON decoding.
+func (m Metadata) Int(key string) (int64, bool) {
+	switch v := m[key].(type) {
+	case int:
+		return int64(v), true
+	case int8:
+		return int64(v), true
+	case int16:
+		return int64(v), true
+	case int32:
+		return int64(v), true
+	case int64:
+		return v, true
+	case uint:
+		return int64(v), v <= math.MaxInt64
+	case uint8:
+		return int64(v), true
+	case uint16:
+		return int64(v), true
+	case uint32:
+		return int64(v), true
+	case uint64:
+		return int64(v), v <= math.MaxInt64
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+			return int64(v), true
+		}
+	case json.Number:
+		i, err := v.Int64()
+		return i, err == nil
+	}
+	return 0, false
+}
+
+// Strings returns the list of strings stored under key, as a []string or
+// a []any holding only strings.
+func (m Metadata) Strings(key string) ([]string, bool) {
+	switch v := m[key].(type) {
+	case []string:
+		return v, true
+	case []any:
+		out := make([]string, len(v))
+		for i, e := range v {
+			s, ok := e.(string)
+			if !ok {
+				return nil, false
+			}
+			out[i] = s
+		}
+		return out, true
+	}
+	return nil, false
+}
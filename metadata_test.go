@@ -0,0 +1,78 @@
+package datasource_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestMetadataSurvivesJSON(t *testing.T) {
+	var topic datasource.DataSourceTopic
+	topic.Metadata.Set("view_count", 1523)
+	topic.Metadata.Set("ratio", 0.25)
+	topic.Metadata.Set("category", "networking")
+	topic.Metadata.Set("accepted", true)
+	topic.Metadata.Set("tags", []string{"dns", "tls"})
+
+	b, err := json.Marshal(topic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded datasource.DataSourceTopic
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range []datasource.Metadata{topic.Metadata, decoded.Metadata} {
+		if v, ok := m.Int("view_count"); !ok || v != 1523 {
+			t.Errorf("Int = %v, %v", v, ok)
+		}
+		if v, ok := m.Float("ratio"); !ok || v != 0.25 {
+			t.Errorf("Float = %v, %v", v, ok)
+		}
+		if v, ok := m.Float("view_count"); !ok || v != 1523 {
+			t.Errorf("Float of an integer = %v, %v", v, ok)
+		}
+		if v, ok := m.String("category"); !ok || v != "networking" {
+			t.Errorf("String = %q, %v", v, ok)
+		}
+		if v, ok := m.Bool("accepted"); !ok || !v {
+			t.Errorf("Bool = %v, %v", v, ok)
+		}
+		if v, ok := m.Strings("tags"); !ok || !reflect.DeepEqual(v, []string{"dns", "tls"}) {
+			t.Errorf("Strings = %v, %v", v, ok)
+		}
+	}
+}
+
+func TestMetadataTypeMismatch(t *testing.T) {
+	m := datasource.Metadata{"ratio": 0.25, "name": "x", "mixed": []any{"a", 1}}
+	if _, ok := m.Int("ratio"); ok {
+		t.Error("Int of a fractional number should fail")
+	}
+	if _, ok := m.Int("name"); ok {
+		t.Error("Int of a string should fail")
+	}
+	if _, ok := m.Strings("mixed"); ok {
+		t.Error("Strings of a mixed list should fail")
+	}
+	if _, ok := m.String("missing"); ok {
+		t.Error("String of a missing key should fail")
+	}
+	var empty datasource.Metadata
+	if _, ok := empty.Int("x"); ok {
+		t.Error("getters on a nil map should fail")
+	}
+}
+
+func TestMetadataOmittedWhenEmpty(t *testing.T) {
+	b, err := json.Marshal(datasource.DataSourceData{DataText: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"data_text":"x","source_url":"","answer_id":0}` {
+		t.Errorf("got %s", b)
+	}
+}
@@ -0,0 +1,13 @@
+package datasource
+
+// Method identifies one of the DataSource interface methods. Decorators and
+// instrumentation use it to configure or label behavior per method.
+type Method string
+
+// The DataSource interface methods.
+const (
+	MethodInit              Method = "Init"
+	MethodCheckAvailability Method = "CheckAvailability"
+	MethodFetchTopics       Method = "FetchTopics"
+	MethodFetchData         Method = "FetchData"
+)
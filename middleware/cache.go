@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/cache"
+)
+
+// CacheConfig configures the Cache decorator.
+type CacheConfig struct {
+	// TTL is how long results are served from memory
+	// Defaults to cache.DefaultTTL; ignored when Store is set
+	TTL time.Duration
+
+	// MaxEntries bounds the number of cached results
+	// Defaults to cache.DefaultMaxEntries; ignored when Store is set
+	MaxEntries int
+
+	// Name identifies the data source in cache keys and entries
+	// Required when several sources share one Store
+	Name string
+
+	// Store is an optional existing cache to use, allowing several sources
+	// to share one cache or the host to inspect and purge it
+	Store *cache.Cache
+
+	// Partition keys FetchTopics results by requester, as for sources
+	// reporting PermissionSensitive in their Capabilities. Set it for
+	// sources behind a transport whose capabilities may be unavailable
+	// when the first request arrives
+	Partition bool
+
+	// Seed is the path of a seed artifact written by cache.WriteSeed,
+	// loaded into the cache at Init so a new replica does not start cold;
+	// a missing file is ignored
+	// Optional
+	Seed string
+
+	// SeedMaxAge skips seeded results stored longer ago than it
+	// Optional - by default every seeded result is loaded
+	SeedMaxAge time.Duration
+}
+
+// Cache returns a DataSource that memoizes successful FetchTopics results,
+// keyed on the normalized question text, tags, and count, and FetchData
+// results, keyed on the topic ID and count. Errors are never cached.
+//
+// Question text is normalized by lowercasing and collapsing whitespace;
+// tags are lowercased and sorted, so equivalent questions share an entry.
+//
+// For permission-sensitive sources (see CacheConfig.Partition) the
+// FetchTopics key also includes the requester's TenantID and AskedBy, so
+// one user's permission-trimmed results are never served to another, and
+// FetchData, which does not identify the requester, is not cached.
+// Whether a source is permission-sensitive is read from its Capabilities
+// on the first call.
+func Cache(ds datasource.DataSource, cfg CacheConfig) datasource.DataSource {
+	store := cfg.Store
+	if store == nil {
+		store = cache.New(cache.Config{TTL: cfg.TTL, MaxEntries: cfg.MaxEntries})
+	}
+	return &cachedSource{DataSource: ds, name: cfg.Name, store: store, sensitive: cfg.Partition, seed: cfg.Seed, seedMaxAge: cfg.SeedMaxAge}
+}
+
+type cachedSource struct {
+	datasource.DataSource
+	name  string
+	store *cache.Cache
+
+	seed       string
+	seedMaxAge time.Duration
+
+	sensitiveOnce sync.Once
+	sensitive     bool
+}
+
+// partitioned reports whether results depend on the requester.
+func (c *cachedSource) partitioned() bool {
+	c.sensitiveOnce.Do(func() {
+		c.sensitive = c.sensitive || datasource.CapabilitiesOf(c.DataSource).PermissionSensitive
+	})
+	return c.sensitive
+}
+
+// Init initializes the wrapped source and then loads the seed, if any. A
+// seed that cannot be read fails Init.
+func (c *cachedSource) Init() error {
+	if err := c.DataSource.Init(); err != nil {
+		return err
+	}
+	if c.seed == "" {
+		return nil
+	}
+	f, err := os.Open(c.seed)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("cache seed: %w", err)
+	}
+	defer f.Close()
+	if _, err := c.store.ReadSeed(f, c.seedMaxAge); err != nil {
+		return fmt.Errorf("cache seed %s: %w", c.seed, err)
+	}
+	return nil
+}
+
+func (c *cachedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	key := c.topicsKey(count, input)
+	if e, ok := c.store.Get(key); ok {
+		input.Trace.CacheHit()
+		return e.Topics, nil
+	}
+	topics, err := c.DataSource.FetchTopics(count, input)
+	if err == nil {
+		c.store.Set(key, cache.Entry{Source: c.name, Topics: topics})
+	}
+	return topics, err
+}
+
+func (c *cachedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if c.partitioned() {
+		return c.DataSource.FetchData(count, topicID)
+	}
+	key := c.dataKey(count, topicID)
+	if e, ok := c.store.Get(key); ok {
+		return e.Data, nil
+	}
+	data, err := c.DataSource.FetchData(count, topicID)
+	if err == nil {
+		c.store.Set(key, cache.Entry{Source: c.name, Data: data})
+	}
+	return data, err
+}
+
+// Unwrap returns the wrapped data source.
+func (c *cachedSource) Unwrap() datasource.DataSource {
+	return c.DataSource
+}
+
+// CacheStore returns the store of the first Cache layer in ds's decorator
+// chain, and false if ds has no Cache layer.
+func CacheStore(ds datasource.DataSource) (*cache.Cache, bool) {
+	for ds != nil {
+		if c, ok := ds.(*cachedSource); ok {
+			return c.store, true
+		}
+		u, ok := ds.(interface{ Unwrap() datasource.DataSource })
+		if !ok {
+			break
+		}
+		ds = u.Unwrap()
+	}
+	return nil, false
+}
+
+func (c *cachedSource) topicsKey(count int, input datasource.NewQuestionInput) string {
+	parts := []string{c.name, "topics", strconv.Itoa(count), datasource.QueryKey(input)}
+	if c.partitioned() {
+		asker := "anonymous"
+		if input.AskedBy != nil {
+			asker = strconv.FormatInt(*input.AskedBy, 10)
+		}
+		parts = append(parts, "tenant="+input.TenantID, "user="+asker)
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func (c *cachedSource) dataKey(count int, topicID int64) string {
+	return strings.Join([]string{c.name, "data", strconv.Itoa(count), strconv.FormatInt(topicID, 10)}, "\x00")
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/cache"
+)
+
+func TestCacheMemoizesNormalizedQueries(t *testing.T) {
+	src := &stubSource{}
+	ds := Cache(src, CacheConfig{})
+
+	inputs := []datasource.NewQuestionInput{
+		{QuestionText: "How do I  reset my password?", Tags: []string{"Auth", "accounts"}},
+		{QuestionText: "how do i reset my password?", Tags: []string{"accounts", "auth "}},
+	}
+	for _, in := range inputs {
+		if _, err := ds.FetchTopics(5, in); err != nil {
+			t.Fatalf("FetchTopics failed: %v", err)
+		}
+	}
+	if src.topicCalls != 1 {
+		t.Errorf("expected equivalent questions to share an entry, got %d upstream calls", src.topicCalls)
+	}
+	trace := new(datasource.Trace)
+	ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: inputs[0].QuestionText, Tags: inputs[0].Tags, Trace: trace})
+	var report datasource.SourceReport
+	trace.Fill(&report)
+	if !report.CacheHit {
+		t.Error("expected the hit recorded on the question's trace")
+	}
+
+	ds.FetchTopics(10, inputs[0])
+	if src.topicCalls != 2 {
+		t.Error("expected a different count to miss the cache")
+	}
+
+	ds.FetchData(3, 42)
+	ds.FetchData(3, 42)
+	ds.FetchData(3, 43)
+	if src.dataCalls != 2 {
+		t.Errorf("expected 2 upstream FetchData calls, got %d", src.dataCalls)
+	}
+}
+
+func TestCacheHitOnEmptyResult(t *testing.T) {
+	src := &stubSource{topics: func(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+		return []datasource.DataSourceTopic{}, nil
+	}}
+	ds := Cache(src, CacheConfig{})
+
+	for i := 0; i < 2; i++ {
+		if topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q"}); err != nil || topics == nil {
+			t.Errorf("FetchTopics call %d = %v, %v; want an empty slice", i+1, topics, err)
+		}
+	}
+	if src.topicCalls != 1 {
+		t.Errorf("expected the empty result to be cached, got %d upstream calls", src.topicCalls)
+	}
+}
+
+func TestCacheDoesNotStoreErrors(t *testing.T) {
+	src := &stubSource{data: func(int, int64) ([]datasource.DataSourceData, error) {
+		return nil, datasource.ErrUnavailable
+	}}
+	store := cache.New(cache.Config{})
+	ds := Cache(src, CacheConfig{Store: store})
+
+	for i := 0; i < 2; i++ {
+		if _, err := ds.FetchData(1, 1); !errors.Is(err, datasource.ErrUnavailable) {
+			t.Fatalf("expected upstream error, got %v", err)
+		}
+	}
+	if src.dataCalls != 2 || store.Len() != 0 {
+		t.Errorf("errors must not be cached: %d calls, %d entries", src.dataCalls, store.Len())
+	}
+}
+
+func TestCacheSharedStoreSeparatesSources(t *testing.T) {
+	store := cache.New(cache.Config{})
+	a, b := &stubSource{}, &stubSource{}
+	dsA := Cache(a, CacheConfig{Name: "a", Store: store})
+	dsB := Cache(b, CacheConfig{Name: "b", Store: store})
+
+	dsA.FetchData(1, 1)
+	dsB.FetchData(1, 1)
+	if a.dataCalls != 1 || b.dataCalls != 1 || store.Len() != 2 {
+		t.Errorf("expected separate entries per source, got %d/%d calls and %d entries", a.dataCalls, b.dataCalls, store.Len())
+	}
+}
+
+func TestCacheStore(t *testing.T) {
+	store := cache.New(cache.Config{})
+	ds := Drain(Cache(&stubSource{}, CacheConfig{Store: store}))
+	if got, ok := CacheStore(ds); !ok || got != store {
+		t.Errorf("CacheStore = %p, %v; want %p", got, ok, store)
+	}
+	if _, ok := CacheStore(&stubSource{}); ok {
+		t.Error("CacheStore found a store in an undecorated source")
+	}
+}
+
+type sensitiveSource struct{ *stubSource }
+
+func (sensitiveSource) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{PermissionSensitive: true}
+}
+
+func TestCachePartitionsPermissionSensitiveSources(t *testing.T) {
+	alice, bob := int64(1), int64(2)
+	tests := []struct {
+		name   string
+		src    func(*stubSource) datasource.DataSource
+		cfg    CacheConfig
+		shared bool
+	}{
+		{"insensitive", func(s *stubSource) datasource.DataSource { return s }, CacheConfig{}, true},
+		{"capability", func(s *stubSource) datasource.DataSource { return sensitiveSource{s} }, CacheConfig{}, false},
+		{"forced", func(s *stubSource) datasource.DataSource { return s }, CacheConfig{Partition: true}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubSource{}
+			ds := Cache(tt.src(stub), tt.cfg)
+			for _, in := range []datasource.NewQuestionInput{
+				{QuestionText: "roadmap", AskedBy: &alice, TenantID: "acme"},
+				{QuestionText: "roadmap", AskedBy: &alice, TenantID: "acme"},
+				{QuestionText: "roadmap", AskedBy: &bob, TenantID: "acme"},
+				{QuestionText: "roadmap", AskedBy: &alice, TenantID: "globex"},
+				{QuestionText: "roadmap"},
+			} {
+				ds.FetchTopics(5, in)
+			}
+			ds.FetchData(1, 7)
+			ds.FetchData(1, 7)
+
+			wantTopics, wantData := 4, 2
+			if tt.shared {
+				wantTopics, wantData = 1, 1
+			}
+			if stub.topicCalls != wantTopics || stub.dataCalls != wantData {
+				t.Errorf("upstream calls: %d topics, %d data; want %d, %d", stub.topicCalls, stub.dataCalls, wantTopics, wantData)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package middleware
+
+import (
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// Wrapper decorates a data source. Drain and FilterLanguages are Wrappers
+// as they are; the Use functions return Wrappers for the decorators that
+// take options, and decorators of other packages adapt with a closure:
+//
+//	func(ds datasource.DataSource) datasource.DataSource {
+//	    return observability.Instrument(ds, "kb", reg)
+//	}
+type Wrapper func(datasource.DataSource) datasource.DataSource
+
+// Chain wraps ds with wrappers, listed from outermost to innermost, so
+// Chain(ds, a, b) is a(b(ds)):
+//
+//	ds = middleware.Chain(ds,
+//	    middleware.UseCache(middleware.CacheConfig{TTL: 10 * time.Minute}),
+//	    middleware.UseRetry(middleware.DefaultRetryPolicy()),
+//	    middleware.UseRateLimit(5, 10),
+//	)
+//
+// Nil wrappers are skipped, so optional layers can be left out in place.
+func Chain(ds datasource.DataSource, wrappers ...Wrapper) datasource.DataSource {
+	for i := len(wrappers) - 1; i >= 0; i-- {
+		if wrappers[i] != nil {
+			ds = wrappers[i](ds)
+		}
+	}
+	return ds
+}
+
+// UseRetry returns a Wrapper applying Retry with the policy.
+func UseRetry(policy RetryPolicy) Wrapper {
+	return func(ds datasource.DataSource) datasource.DataSource { return Retry(ds, policy) }
+}
+
+// UseRateLimit returns a Wrapper applying RateLimit with the limits.
+func UseRateLimit(requestsPerSecond float64, burst int, overrides ...MethodLimit) Wrapper {
+	return func(ds datasource.DataSource) datasource.DataSource {
+		return RateLimit(ds, requestsPerSecond, burst, overrides...)
+	}
+}
+
+// UseCache returns a Wrapper applying Cache with cfg. Unless cfg.Store is
+// set, each source it wraps gets its own cache.
+func UseCache(cfg CacheConfig) Wrapper {
+	return func(ds datasource.DataSource) datasource.DataSource { return Cache(ds, cfg) }
+}
+
+// UseSanitize returns a Wrapper applying Sanitize with the policy.
+func UseSanitize(policy content.Policy) Wrapper {
+	return func(ds datasource.DataSource) datasource.DataSource { return Sanitize(ds, policy) }
+}
+
+// UseSizeLimit returns a Wrapper applying SizeLimit with the limits.
+func UseSizeLimit(limits SizeLimits) Wrapper {
+	return func(ds datasource.DataSource) datasource.DataSource { return SizeLimit(ds, limits) }
+}
+
+// UseLengthBand returns a Wrapper applying LengthBand with the band.
+func UseLengthBand(band Band) Wrapper {
+	return func(ds datasource.DataSource) datasource.DataSource { return LengthBand(ds, band) }
+}
+
+// UseHooks returns a Wrapper applying WithHooks with the name and hooks.
+func UseHooks(name string, hooks datasource.Hooks) Wrapper {
+	return func(ds datasource.DataSource) datasource.DataSource { return WithHooks(ds, name, hooks) }
+}
+
+// UseDedup returns a Wrapper applying Dedup with cfg.
+func UseDedup(cfg DedupConfig) Wrapper {
+	return func(ds datasource.DataSource) datasource.DataSource { return Dedup(ds, cfg) }
+}
+
+// UseTimeout returns a Wrapper applying Timeout with the timeouts.
+func UseTimeout(timeouts Timeouts) Wrapper {
+	return func(ds datasource.DataSource) datasource.DataSource { return Timeout(ds, timeouts) }
+}
+
+// UseMaintenance returns a Wrapper applying Maintenance with cfg, or an
+// error if cfg fails Validate.
+func UseMaintenance(cfg MaintenanceConfig) (Wrapper, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return func(ds datasource.DataSource) datasource.DataSource { return MustMaintenance(ds, cfg) }, nil
+}
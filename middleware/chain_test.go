@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"fmt"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+func TestChain(t *testing.T) {
+	src := &stubSource{}
+	maintenance, err := UseMaintenance(MaintenanceConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := Chain(src,
+		UseHooks("kb", datasource.Hooks{}),
+		nil,
+		UseCache(CacheConfig{}),
+		UseRetry(DefaultRetryPolicy()),
+		UseRateLimit(100, 10),
+		UseSanitize(content.Policy{}),
+		UseSizeLimit(SizeLimits{PerCall: 1 << 20}),
+		UseLengthBand(Band{MaxChars: 1000}),
+		UseDedup(DedupConfig{}),
+		UseTimeout(Timeouts{}),
+		maintenance,
+		FilterLanguages,
+		Drain,
+	)
+
+	var layers []string
+	for cur := ds; cur != src; {
+		layers = append(layers, fmt.Sprintf("%T", cur))
+		u, ok := cur.(interface{ Unwrap() datasource.DataSource })
+		if !ok {
+			t.Fatalf("%T does not unwrap", cur)
+		}
+		cur = u.Unwrap()
+	}
+	want := []string{
+		"*middleware.hookedSource", "*middleware.cachedSource", "*middleware.retrySource",
+		"*middleware.rateLimitedSource", "*middleware.sanitizedSource", "*middleware.sizeLimitedSource",
+		"*middleware.bandedSource", "*middleware.dedupSource", "*middleware.timeoutSource",
+		"*middleware.maintenanceSource", "*middleware.languageSource", "*middleware.drainSource",
+	}
+	if fmt.Sprint(layers) != fmt.Sprint(want) {
+		t.Errorf("layers = %v, want %v", layers, want)
+	}
+
+	if _, err := ds.FetchTopics(3, datasource.NewQuestionInput{QuestionText: "dns"}); err != nil {
+		t.Fatal(err)
+	}
+	if Chain(src) != datasource.DataSource(src) {
+		t.Error("Chain without wrappers did not return the source")
+	}
+}
@@ -0,0 +1,190 @@
+package middleware
+
+import (
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/topicmap"
+	"github.com/locus-search/datasource-sdk/similarity"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultDedupThreshold = 0.85
+	DefaultDedupMinChars  = 40
+)
+
+// DedupConfig configures the Dedup decorator.
+type DedupConfig struct {
+	// Threshold is the estimated Jaccard similarity of two results' text,
+	// between 0 and 1, at which they are duplicates
+	// Defaults to DefaultDedupThreshold
+	Threshold float64
+
+	// MinChars is the length of text below which results are matched by
+	// URL only, as short texts are similar by chance
+	// Defaults to DefaultDedupMinChars
+	MinChars int
+
+	// URLOnly matches results by URL only
+	URLOnly bool
+
+	// OnDuplicate, if set, is called after a call had duplicates dropped,
+	// for logging and metrics
+	OnDuplicate func(method datasource.Method, dropped int)
+}
+
+func (c DedupConfig) withDefaults() DedupConfig {
+	if c.Threshold <= 0 {
+		c.Threshold = DefaultDedupThreshold
+	}
+	if c.MinChars <= 0 {
+		c.MinChars = DefaultDedupMinChars
+	}
+	return c
+}
+
+// Dedup returns a DataSource that drops duplicate results, such as the
+// same answer mirrored on several sites of a federated source. Results
+// are duplicates if their SourceURLs are the same once canonicalized
+// (scheme, "www.", default ports, fragments, tracking parameters, query
+// order, and trailing slashes ignored), or if the MinHash signatures of
+// their text are at least Threshold similar. The first of duplicates, the
+// best ranked, is kept, and the results after it are ranked again.
+//
+// Topics are matched by their title and excerpt. Data items are matched
+// across the FetchData calls for the topics of one FetchTopics call, so
+// an answer fetched for one topic is not returned again for another. Calls
+// for topics the decorator has not returned are deduplicated on their own.
+//
+// Dedup can wrap a single source or a composite.Source, to deduplicate
+// across its children. Dropped duplicates are not replaced, so calls may
+// return fewer results than requested.
+func Dedup(ds datasource.DataSource, cfg DedupConfig) datasource.DataSource {
+	return &dedupSource{DataSource: ds, cfg: cfg.withDefaults()}
+}
+
+type dedupSource struct {
+	datasource.DataSource
+	cfg     DedupConfig
+	queries topicmap.Map[*seenSet] // the data items returned for each topic's query
+}
+
+// seenSet is the results kept so far.
+type seenSet struct {
+	mu   sync.Mutex
+	urls map[string]bool
+	sigs []similarity.Signature
+}
+
+func (s *dedupSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	topics, err := s.DataSource.FetchTopics(count, input)
+	if len(topics) == 0 {
+		return topics, err
+	}
+	seen := &seenSet{}
+	kept := make([]datasource.DataSourceTopic, 0, len(topics))
+	for _, t := range topics {
+		if seen.add(s.cfg, t.SourceURL, t.DisplayTitle()+"\n"+t.BodyExcerpt) {
+			t.Rank = len(kept) + 1
+			kept = append(kept, t)
+		}
+	}
+	s.queries.Set(kept, &seenSet{})
+	s.report(datasource.MethodFetchTopics, len(topics)-len(kept))
+	return kept, err
+}
+
+func (s *dedupSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	data, err := s.DataSource.FetchData(count, topicID)
+	if len(data) == 0 {
+		return data, err
+	}
+	seen, ok := s.queries.Get(topicID)
+	if !ok {
+		seen = &seenSet{}
+	}
+	seen.mu.Lock()
+	defer seen.mu.Unlock()
+	kept := make([]datasource.DataSourceData, 0, len(data))
+	for _, d := range data {
+		if seen.add(s.cfg, d.SourceURL, d.DataText) {
+			d.Rank = len(kept) + 1
+			kept = append(kept, d)
+		}
+	}
+	s.report(datasource.MethodFetchData, len(data)-len(kept))
+	return kept, err
+}
+
+func (s *dedupSource) report(m datasource.Method, dropped int) {
+	if dropped > 0 && s.cfg.OnDuplicate != nil {
+		s.cfg.OnDuplicate(m, dropped)
+	}
+}
+
+// Unwrap returns the wrapped data source.
+func (s *dedupSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
+
+// add records a result unless it duplicates one already recorded, and
+// reports whether it did. Callers sharing a seenSet hold its mu.
+func (s *seenSet) add(cfg DedupConfig, rawURL, text string) bool {
+	u := canonicalURL(rawURL)
+	if u != "" && s.urls[u] {
+		return false
+	}
+	var sig similarity.Signature
+	if !cfg.URLOnly && utf8.RuneCountInString(text) >= cfg.MinChars {
+		sig = similarity.MinHash(text, 0)
+		for _, o := range s.sigs {
+			if sig.Jaccard(o) >= cfg.Threshold {
+				return false
+			}
+		}
+	}
+	if u != "" {
+		if s.urls == nil {
+			s.urls = make(map[string]bool)
+		}
+		s.urls[u] = true
+	}
+	if sig != nil {
+		s.sigs = append(s.sigs, sig)
+	}
+	return true
+}
+
+// trackingParams are query parameters that do not change what a URL
+// addresses.
+var trackingParams = map[string]bool{"fbclid": true, "gclid": true, "msclkid": true, "ref": true}
+
+// canonicalURL returns rawURL without the parts that vary between links
+// to the same page: the scheme, a "www." prefix, default ports, the
+// fragment, tracking parameters, the order of query parameters, and a
+// trailing slash. It returns "" for an empty or unparsable URL.
+func canonicalURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+	q := u.Query()
+	for k := range q {
+		if trackingParams[strings.ToLower(k)] || strings.HasPrefix(strings.ToLower(k), "utm_") {
+			q.Del(k)
+		}
+	}
+	canonical := host + strings.TrimSuffix(u.EscapedPath(), "/")
+	if enc := q.Encode(); enc != "" { // sorted by key
+		canonical += "?" + enc
+	}
+	return canonical
+}
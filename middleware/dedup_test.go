@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestCanonicalURL(t *testing.T) {
+	tests := []struct{ a, b string }{
+		{"https://www.example.com/q/1/", "http://example.com/q/1"},
+		{"https://example.com:443/q/1#answer-2", "https://EXAMPLE.com/q/1"},
+		{"https://example.com/q?b=2&a=1&utm_source=feed", "https://example.com/q?a=1&b=2"},
+		{"https://example.com/q?fbclid=x&ref=home", "https://example.com/q"},
+	}
+	for _, tt := range tests {
+		if a, b := canonicalURL(tt.a), canonicalURL(tt.b); a != b {
+			t.Errorf("canonicalURL(%q) = %q, canonicalURL(%q) = %q, want equal", tt.a, a, tt.b, b)
+		}
+	}
+	for _, u := range []string{"", "not a url", "/relative"} {
+		if got := canonicalURL(u); got != "" {
+			t.Errorf("canonicalURL(%q) = %q, want empty", u, got)
+		}
+	}
+	if canonicalURL("https://example.com/q?id=1") == canonicalURL("https://example.com/q?id=2") {
+		t.Error("URLs with different parameters are equal")
+	}
+}
+
+const answer = "Wrap the error with fmt.Errorf and the %w verb, then compare it with errors.Is against the sentinel value."
+
+func TestDedupTopics(t *testing.T) {
+	src := &stubSource{topics: func(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+		return []datasource.DataSourceTopic{
+			{TopicID: 1, Topic: "Compare wrapped errors", BodyExcerpt: answer, SourceURL: "https://stackoverflow.com/q/1", Rank: 1},
+			{TopicID: 2, Topic: "Compare wrapped errors", BodyExcerpt: answer + " ", SourceURL: "https://mirror.example/q/1", Rank: 2},
+			{TopicID: 3, Topic: "Other", BodyExcerpt: "short", SourceURL: "https://www.stackoverflow.com/q/1/?utm_medium=x", Rank: 3},
+			{TopicID: 4, Topic: "Parse durations", BodyExcerpt: "Use time.ParseDuration with a string such as 1m30s to get a time.Duration.", Rank: 4},
+		}, nil
+	}}
+	var dropped []int
+	ds := Dedup(src, DedupConfig{OnDuplicate: func(m datasource.Method, n int) {
+		if m != datasource.MethodFetchTopics {
+			t.Errorf("OnDuplicate method = %s", m)
+		}
+		dropped = append(dropped, n)
+	}})
+	got, err := ds.FetchTopics(4, datasource.NewQuestionInput{QuestionText: "errors"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].TopicID != 1 || got[1].TopicID != 4 || got[1].Rank != 2 {
+		t.Errorf("topics = %+v, want 1 and 4 ranked again", got)
+	}
+	if len(dropped) != 1 || dropped[0] != 2 {
+		t.Errorf("OnDuplicate calls = %v, want [2]", dropped)
+	}
+
+	got, _ = Dedup(src, DedupConfig{URLOnly: true}).FetchTopics(4, datasource.NewQuestionInput{QuestionText: "errors"})
+	if len(got) != 3 {
+		t.Errorf("URLOnly kept %d topics, want 3", len(got))
+	}
+}
+
+func TestDedupDataAcrossTopics(t *testing.T) {
+	src := &stubSource{
+		topics: func(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+			return []datasource.DataSourceTopic{{TopicID: 1, Topic: "a"}, {TopicID: 2, Topic: "b"}}, nil
+		},
+		data: func(_ int, topicID int64) ([]datasource.DataSourceData, error) {
+			return []datasource.DataSourceData{
+				{DataText: answer, AnswerID: topicID * 10},
+				{DataText: "+1", AnswerID: topicID*10 + 1},
+			}, nil
+		},
+	}
+	ds := Dedup(src, DedupConfig{})
+	if _, err := ds.FetchTopics(2, datasource.NewQuestionInput{QuestionText: "errors"}); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := ds.FetchData(5, 1)
+	second, _ := ds.FetchData(5, 2)
+	if len(first) != 2 {
+		t.Errorf("first topic's data = %+v, want both items", first)
+	}
+	// The answer was returned for topic 1; the short item is below MinChars.
+	if len(second) != 1 || second[0].AnswerID != 21 || second[0].Rank != 1 {
+		t.Errorf("second topic's data = %+v, want the short item alone", second)
+	}
+
+	// Topics of no query are deduplicated on their own.
+	if got, _ := ds.FetchData(5, 99); len(got) != 2 {
+		t.Errorf("unknown topic's data = %+v, want both items", got)
+	}
+}
@@ -0,0 +1,14 @@
+// Package middleware provides decorators that add cross-cutting behavior
+// (retries, rate limiting, caching) to any DataSource implementation.
+//
+// Each decorator wraps a DataSource and itself implements DataSource, so
+// decorators can be stacked:
+//
+//	ds = middleware.Retry(middleware.RateLimit(ds, 5, 10), middleware.DefaultRetryPolicy())
+//
+// Chain composes Wrappers in the order they are listed, outermost first:
+//
+//	ds = middleware.Chain(ds, middleware.UseRetry(middleware.DefaultRetryPolicy()), middleware.UseRateLimit(5, 10))
+//
+// Decorators expose the wrapped source through an Unwrap method.
+package middleware
@@ -0,0 +1,135 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Drain returns a DataSource that tracks calls in flight to ds so that it
+// can be shut down gracefully. Its Close method (called by
+// datasource.Shutdown) rejects new calls with ErrUnavailable and waits for
+// in-flight calls to finish, or for ctx to be done, whichever is first.
+//
+// Place Drain outermost so that calls are rejected before reaching any
+// other layer:
+//
+//	ds = middleware.Drain(middleware.Retry(ds, policy))
+//	...
+//	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+//	defer cancel()
+//	err := datasource.Shutdown(ctx, ds)
+func Drain(ds datasource.DataSource) datasource.DataSource {
+	return &drainSource{DataSource: ds}
+}
+
+type drainSource struct {
+	datasource.DataSource
+
+	mu       sync.Mutex
+	inflight int
+	closed   bool
+	idle     chan struct{} // closed when inflight drops to zero after Close
+}
+
+// enter registers a call, or fails if the source is closed.
+func (d *drainSource) enter(m datasource.Method) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return fmt.Errorf("middleware: %s: shutting down: %w", m, datasource.ErrUnavailable)
+	}
+	d.inflight++
+	return nil
+}
+
+func (d *drainSource) exit() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.inflight--
+	if d.closed && d.inflight == 0 {
+		close(d.idle)
+	}
+}
+
+func (d *drainSource) Init() error {
+	if err := d.enter(datasource.MethodInit); err != nil {
+		return err
+	}
+	defer d.exit()
+	return d.DataSource.Init()
+}
+
+func (d *drainSource) CheckAvailability() bool {
+	if d.enter(datasource.MethodCheckAvailability) != nil {
+		return false
+	}
+	defer d.exit()
+	return d.DataSource.CheckAvailability()
+}
+
+func (d *drainSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if err := d.enter(datasource.MethodFetchTopics); err != nil {
+		return nil, err
+	}
+	defer d.exit()
+	return d.DataSource.FetchTopics(count, input)
+}
+
+func (d *drainSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if err := d.enter(datasource.MethodFetchData); err != nil {
+		return nil, err
+	}
+	defer d.exit()
+	return d.DataSource.FetchData(count, topicID)
+}
+
+// Close stops accepting calls and waits for in-flight calls to finish. It
+// does not close the wrapped source; use datasource.Shutdown for that.
+func (d *drainSource) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		d.idle = make(chan struct{})
+		if d.inflight == 0 {
+			close(d.idle)
+		}
+	}
+	idle := d.idle
+	d.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		d.mu.Lock()
+		n := d.inflight
+		d.mu.Unlock()
+		return fmt.Errorf("middleware: drain: %d calls still in flight: %w", n, ctx.Err())
+	}
+}
+
+// InFlight returns the number of calls in flight through the first Drain
+// layer in ds's decorator chain, and false if ds has no Drain layer.
+func InFlight(ds datasource.DataSource) (int, bool) {
+	for ds != nil {
+		if d, ok := ds.(*drainSource); ok {
+			d.mu.Lock()
+			defer d.mu.Unlock()
+			return d.inflight, true
+		}
+		u, ok := ds.(interface{ Unwrap() datasource.DataSource })
+		if !ok {
+			break
+		}
+		ds = u.Unwrap()
+	}
+	return 0, false
+}
+
+// Unwrap returns the wrapped data source.
+func (d *drainSource) Unwrap() datasource.DataSource {
+	return d.DataSource
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestDrainWaitsForInFlightCalls(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	stub := &stubSource{topics: func(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+		close(started)
+		<-release
+		return []datasource.DataSourceTopic{{TopicID: 1}}, nil
+	}}
+	ds := Drain(stub)
+
+	result := make(chan error, 1)
+	go func() {
+		_, err := ds.FetchTopics(1, datasource.NewQuestionInput{})
+		result <- err
+	}()
+	<-started
+
+	closed := make(chan error, 1)
+	go func() { closed <- datasource.Shutdown(context.Background(), ds) }()
+
+	// Wait until Close has taken effect before checking rejection.
+	for ds.CheckAvailability() {
+		time.Sleep(time.Millisecond)
+	}
+	if _, err := ds.FetchData(1, 1); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchData during shutdown = %v, want ErrUnavailable", err)
+	}
+	select {
+	case err := <-closed:
+		t.Fatalf("Shutdown returned %v with a call in flight", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	if err := <-result; err != nil {
+		t.Errorf("in-flight call failed: %v", err)
+	}
+	if err := <-closed; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+}
+
+func TestDrainDeadline(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	started := make(chan struct{})
+	ds := Drain(&stubSource{data: func(int, int64) ([]datasource.DataSourceData, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}})
+	go ds.FetchData(1, 1)
+	<-started
+	if n, ok := InFlight(WithHooks(ds, "kb", datasource.Hooks{})); n != 1 || !ok {
+		t.Errorf("InFlight = %d, %v; want 1, true", n, ok)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := datasource.Shutdown(ctx, ds); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Shutdown = %v, want DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"context"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// WithHooks returns a DataSource that fires hooks around every call to ds,
+// identifying it as name. Use datasource.SlogHooks for uniform structured
+// logs, or datasource.CombineHooks to fire several hook sets.
+func WithHooks(ds datasource.DataSource, name string, hooks datasource.Hooks) datasource.DataSource {
+	return &hookedSource{DataSource: ds, name: name, hooks: hooks}
+}
+
+type hookedSource struct {
+	datasource.DataSource
+	name  string
+	hooks datasource.Hooks
+}
+
+func (s *hookedSource) start(info datasource.RequestInfo) datasource.RequestInfo {
+	info.Source = s.name
+	info.Start = now()
+	s.hooks.RequestStart(context.Background(), info)
+	return info
+}
+
+func (s *hookedSource) end(info datasource.RequestInfo, results int, err error) {
+	s.hooks.RequestEnd(context.Background(), info, datasource.RequestResult{
+		Duration: now().Sub(info.Start),
+		Results:  results,
+		Err:      err,
+	})
+}
+
+func (s *hookedSource) Init() error {
+	info := s.start(datasource.RequestInfo{Method: datasource.MethodInit})
+	err := s.DataSource.Init()
+	s.end(info, 0, err)
+	return err
+}
+
+func (s *hookedSource) CheckAvailability() bool {
+	info := s.start(datasource.RequestInfo{Method: datasource.MethodCheckAvailability})
+	ok := s.DataSource.CheckAvailability()
+	var err error
+	if !ok {
+		err = datasource.ErrUnavailable
+	}
+	s.end(info, 0, err)
+	return ok
+}
+
+func (s *hookedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	info := s.start(datasource.RequestInfo{Method: datasource.MethodFetchTopics, Count: count, Query: input.QuestionText})
+	topics, err := s.DataSource.FetchTopics(count, input)
+	s.end(info, len(topics), err)
+	return topics, err
+}
+
+func (s *hookedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	info := s.start(datasource.RequestInfo{Method: datasource.MethodFetchData, Count: count, TopicID: topicID})
+	data, err := s.DataSource.FetchData(count, topicID)
+	s.end(info, len(data), err)
+	return data, err
+}
+
+// Unwrap returns the wrapped data source.
+func (s *hookedSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
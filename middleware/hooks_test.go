@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestWithHooks(t *testing.T) {
+	var starts []datasource.RequestInfo
+	var results []datasource.RequestResult
+	var errs []error
+	hooks := datasource.Hooks{
+		OnRequestStart: func(_ context.Context, info datasource.RequestInfo) { starts = append(starts, info) },
+		OnRequestEnd: func(_ context.Context, _ datasource.RequestInfo, r datasource.RequestResult) {
+			results = append(results, r)
+		},
+		OnError: func(_ context.Context, _ datasource.RequestInfo, err error) { errs = append(errs, err) },
+	}
+
+	src := &stubSource{}
+	src.data = func(int, int64) ([]datasource.DataSourceData, error) { return nil, datasource.ErrNotFound }
+	ds := WithHooks(src, "kb", hooks)
+
+	ds.FetchTopics(4, datasource.NewQuestionInput{QuestionText: "what is dns"})
+	ds.FetchData(2, 7)
+
+	if len(starts) != 2 || len(results) != 2 {
+		t.Fatalf("expected 2 starts and ends, got %d and %d", len(starts), len(results))
+	}
+	if s := starts[0]; s.Source != "kb" || s.Method != datasource.MethodFetchTopics || s.Count != 4 || s.Query != "what is dns" {
+		t.Errorf("unexpected FetchTopics info %+v", s)
+	}
+	if s := starts[1]; s.Method != datasource.MethodFetchData || s.TopicID != 7 {
+		t.Errorf("unexpected FetchData info %+v", s)
+	}
+	if results[0].Results != 1 || results[0].Err != nil {
+		t.Errorf("unexpected FetchTopics result %+v", results[0])
+	}
+	if len(errs) != 1 || errs[0] != datasource.ErrNotFound || results[1].Err != datasource.ErrNotFound {
+		t.Errorf("error not reported: %v / %+v", errs, results[1])
+	}
+}
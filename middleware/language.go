@@ -0,0 +1,60 @@
+package middleware
+
+import (
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/topicmap"
+)
+
+// FilterLanguages returns a DataSource that drops results in languages the
+// asker does not read, for multilingual sources that cannot filter
+// upstream. FetchTopics keeps the topics datasource.AcceptsLanguage
+// accepts for the question's AcceptLanguages, and FetchData keeps the
+// items accepted for the question that returned the topic. Results of
+// unknown language are kept, as is everything for questions that accept
+// no language in particular.
+func FilterLanguages(ds datasource.DataSource) datasource.DataSource {
+	return &languageSource{DataSource: ds}
+}
+
+type languageSource struct {
+	datasource.DataSource
+	accept topicmap.Map[[]string] // the languages accepted by each topic's question
+}
+
+func (s *languageSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	topics, err := s.DataSource.FetchTopics(count, input)
+	if len(input.AcceptLanguages) == 0 {
+		// Forget the languages of earlier questions for these topics.
+		s.accept.Set(topics, nil)
+		return topics, err
+	}
+	kept := make([]datasource.DataSourceTopic, 0, len(topics))
+	for _, t := range topics {
+		if datasource.AcceptsLanguage(input.AcceptLanguages, t.Language) {
+			kept = append(kept, t)
+		}
+	}
+	input.Trace.Filtered(len(topics) - len(kept))
+	s.accept.Set(kept, input.AcceptLanguages)
+	return kept, err
+}
+
+func (s *languageSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	data, err := s.DataSource.FetchData(count, topicID)
+	accept, ok := s.accept.Get(topicID)
+	if !ok || len(data) == 0 {
+		return data, err
+	}
+	kept := make([]datasource.DataSourceData, 0, len(data))
+	for _, d := range data {
+		if datasource.AcceptsLanguage(accept, d.Language) {
+			kept = append(kept, d)
+		}
+	}
+	return kept, err
+}
+
+// Unwrap returns the wrapped data source.
+func (s *languageSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestFilterLanguages(t *testing.T) {
+	src := &stubSource{}
+	src.topics = func(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+		return []datasource.DataSourceTopic{
+			{TopicID: 1, Language: "en"},
+			{TopicID: 2, Language: "de"},
+			{TopicID: 3},
+		}, nil
+	}
+	src.data = func(int, int64) ([]datasource.DataSourceData, error) {
+		return []datasource.DataSourceData{{DataText: "hello", Language: "en-US"}, {DataText: "hallo", Language: "de"}}, nil
+	}
+	ds := FilterLanguages(src)
+
+	topics, _ := ds.FetchTopics(3, datasource.NewQuestionInput{QuestionText: "q", AcceptLanguages: []string{"en"}})
+	if len(topics) != 2 || topics[0].TopicID != 1 || topics[1].TopicID != 3 {
+		t.Errorf("topics = %+v, want 1 and the untagged 3", topics)
+	}
+	if data, _ := ds.FetchData(2, 3); len(data) != 1 || data[0].DataText != "hello" {
+		t.Errorf("data = %+v, want only the English item", data)
+	}
+
+	if topics, _ := ds.FetchTopics(3, datasource.NewQuestionInput{QuestionText: "q"}); len(topics) != 3 {
+		t.Errorf("without AcceptLanguages: %d topics, want all 3", len(topics))
+	}
+	if data, _ := ds.FetchData(2, 3); len(data) != 2 {
+		t.Errorf("data after a question accepting any language: %d items, want all 2", len(data))
+	}
+	if data, _ := ds.FetchData(2, 9); len(data) != 2 {
+		t.Errorf("data of an unknown topic: %d items, want all 2", len(data))
+	}
+}
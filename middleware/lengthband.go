@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"unicode/utf8"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/chunk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// Band configures the LengthBand decorator. Lengths count the characters
+// of DataText, of HTML items once converted to Markdown; zero means
+// unbounded.
+type Band struct {
+	// MinChars is the length below which an item, such as a one-line
+	// reply, is too short to be an answer
+	MinChars int
+
+	// MaxChars is the length above which an item is split into passages
+	// of at most MaxChars with chunk.Splitter; HTML items are converted to
+	// Markdown first
+	MaxChars int
+
+	// Require drops items shorter than MinChars. Otherwise they are kept,
+	// after the items within the band
+	Require bool
+}
+
+// LengthBand returns a DataSource that shapes FetchData results into
+// answer-sized passages, so that the host gets items of similar length
+// from sources as different as Q&A sites and document stores. Long items
+// are split, and short ones moved last or dropped; see Band. At most the
+// requested count of items is returned, in the source's order otherwise.
+//
+// Streaming sources are not streamed through LengthBand:
+// datasource.StreamData falls back to FetchData.
+func LengthBand(ds datasource.DataSource, band Band) datasource.DataSource {
+	return &bandedSource{DataSource: ds, band: band}
+}
+
+type bandedSource struct {
+	datasource.DataSource
+	band Band
+}
+
+func (s *bandedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	data, err := s.DataSource.FetchData(count, topicID)
+	if len(data) == 0 {
+		return data, err
+	}
+	banded := s.band.apply(data)
+	return banded[:min(len(banded), max(count, 0))], err
+}
+
+// apply splits long items and moves or drops short ones.
+func (b Band) apply(data []datasource.DataSourceData) []datasource.DataSourceData {
+	splitter := chunk.Splitter{MaxTokens: b.MaxChars, Overlap: b.MaxChars / 10, Tokens: utf8.RuneCountInString}
+	out := make([]datasource.DataSourceData, 0, len(data))
+	var short []datasource.DataSourceData
+	for _, d := range data {
+		n := utf8.RuneCountInString(d.DataText)
+		if d.ContentType == datasource.ContentHTML && (b.MinChars > 0 || b.MaxChars > 0 && n > b.MaxChars) {
+			md := content.HTMLToMarkdown(d.DataText)
+			n = utf8.RuneCountInString(md)
+			if b.MaxChars > 0 && n > b.MaxChars {
+				d.DataText, d.ContentType = md, datasource.ContentMarkdown
+			}
+		}
+		switch {
+		case n < b.MinChars:
+			if !b.Require {
+				short = append(short, d)
+			}
+		case b.MaxChars > 0 && n > b.MaxChars:
+			out = append(out, splitter.Data([]datasource.DataSourceData{d})...)
+		default:
+			out = append(out, d)
+		}
+	}
+	return append(out, short...)
+}
+
+// Unwrap returns the wrapped data source.
+func (s *bandedSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
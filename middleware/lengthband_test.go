@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/chunk"
+)
+
+func TestLengthBand(t *testing.T) {
+	long := strings.Repeat("This sentence is filler. ", 8) // 200 characters
+	items := []datasource.DataSourceData{
+		{DataText: "+1", AnswerID: 1},
+		{DataText: long, AnswerID: 2},
+		{DataText: "<p>Use <b>errors.Is</b> to compare wrapped errors.</p>", ContentType: datasource.ContentHTML, AnswerID: 3},
+		{DataText: "Thanks!", AnswerID: 4},
+	}
+	src := &stubSource{}
+	src.data = func(int, int64) ([]datasource.DataSourceData, error) { return items, nil }
+
+	ds := LengthBand(src, Band{MinChars: 20, MaxChars: 120})
+	got, err := ds.FetchData(10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, d := range got {
+		ids = append(ids, d.AnswerID)
+		if len([]rune(d.DataText)) > 120 {
+			t.Errorf("item %d has %d characters, want at most 120", d.AnswerID, len([]rune(d.DataText)))
+		}
+	}
+	// The long item is split in two; the short ones come last.
+	if want := []int64{2, 2, 3, 1, 4}; !equalInts(ids, want) {
+		t.Errorf("answer IDs = %v, want %v", ids, want)
+	}
+	if n, _ := got[1].Metadata.Int(chunk.MetadataCount); n != 2 {
+		t.Errorf("chunk count = %d, want 2", n)
+	}
+	if got[2].ContentType != datasource.ContentHTML {
+		t.Errorf("in-band HTML item converted to %s", got[2].ContentType)
+	}
+
+	ds = LengthBand(src, Band{MinChars: 20, Require: true})
+	got, _ = ds.FetchData(1, 1)
+	if len(got) != 1 || got[0].AnswerID != 2 || got[0].DataText != long {
+		t.Errorf("required band = %+v, want the long item alone and whole", got)
+	}
+}
+
+func equalInts(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,311 @@
+package middleware
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// MaintenanceWindow is a recurring period during which a source is down
+// for scheduled maintenance, such as an upstream system's nightly backup.
+type MaintenanceWindow struct {
+	// Start is when the window opens, as a cron expression of five fields:
+	// minute, hour, day of month, month, and day of week (0 or 7 is
+	// Sunday). Fields take *, numbers, ranges (1-5), lists (1,15), and
+	// steps (*/15); "0 2 * * *" opens at 02:00 daily and "30 1 * * 0" at
+	// 01:30 on Sundays
+	Start string
+
+	// Duration is how long the window stays open
+	Duration time.Duration
+}
+
+// Validate checks that the window's schedule parses and its duration is
+// positive.
+func (w MaintenanceWindow) Validate() error {
+	if _, err := parseCron(w.Start); err != nil {
+		return err
+	}
+	if w.Duration <= 0 {
+		return fmt.Errorf("middleware: maintenance window %q: duration must be positive", w.Start)
+	}
+	return nil
+}
+
+// MaintenanceConfig configures Maintenance.
+type MaintenanceConfig struct {
+	// Windows are the source's maintenance windows
+	Windows []MaintenanceWindow
+
+	// Location is the time zone the windows' schedules are read in
+	// Defaults to UTC
+	Location *time.Location
+
+	// Now returns the current time
+	// Defaults to time.Now
+	Now func() time.Time
+}
+
+// Validate checks every window with MaintenanceWindow.Validate.
+func (c MaintenanceConfig) Validate() error {
+	for _, w := range c.Windows {
+		if err := w.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c MaintenanceConfig) withDefaults() MaintenanceConfig {
+	if c.Location == nil {
+		c.Location = time.UTC
+	}
+	if c.Now == nil {
+		c.Now = time.Now
+	}
+	return c
+}
+
+// Maintenance returns a DataSource that treats ds as unavailable during its
+// scheduled maintenance windows, without calling it: CheckAvailability
+// reports false, health checks report it degraded with the time the
+// window closes, and fetches fail with an error wrapping ErrUnavailable.
+// It implements datasource.MaintenanceReporter, so multiplexers such as
+// router.Router skip the source rather than record its failures.
+//
+// Init, Close, and background work such as a source's refreshes and
+// datasource.SyncSource feeds are not affected. Maintenance returns an
+// error if cfg fails Validate.
+func Maintenance(ds datasource.DataSource, cfg MaintenanceConfig) (datasource.DataSource, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	cfg = cfg.withDefaults()
+	m := &maintenanceSource{DataSource: ds, loc: cfg.Location, now: cfg.Now}
+	for _, w := range cfg.Windows {
+		sched, _ := parseCron(w.Start)
+		m.windows = append(m.windows, window{sched, w.Duration})
+	}
+	return m, nil
+}
+
+// MustMaintenance is like Maintenance but panics if cfg fails Validate,
+// for windows fixed in code rather than read from operator input.
+func MustMaintenance(ds datasource.DataSource, cfg MaintenanceConfig) datasource.DataSource {
+	m, err := Maintenance(ds, cfg)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+type window struct {
+	start    *cronSchedule
+	duration time.Duration
+}
+
+type maintenanceSource struct {
+	datasource.DataSource
+	windows []window
+	loc     *time.Location
+	now     func() time.Time
+
+	mu sync.Mutex
+	// The state computed last holds from checked until changes, or for
+	// good if changes is zero.
+	checked, changes time.Time
+	open             bool
+	until            time.Time
+}
+
+// Maintenance implements datasource.MaintenanceReporter.
+func (m *maintenanceSource) Maintenance() (until time.Time, ok bool) {
+	now := m.now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.checked.IsZero() && !now.Before(m.checked) && (m.changes.IsZero() || now.Before(m.changes)) {
+		return m.until, m.open
+	}
+
+	m.checked, m.changes, m.open, m.until = now, time.Time{}, false, time.Time{}
+	next := time.Time{}
+	for _, w := range m.windows {
+		// A window containing now opened after now-duration.
+		s := w.start.next(now.Add(-w.duration + 1).In(m.loc))
+		switch {
+		case s.IsZero():
+		case !s.After(now):
+			if end := s.Add(w.duration); end.After(m.until) {
+				m.open, m.until = true, end
+			}
+		case next.IsZero() || s.Before(next):
+			next = s
+		}
+	}
+	if m.open {
+		// Windows overlapping this one are found once it closes.
+		m.changes = m.until
+	} else {
+		m.changes = next
+	}
+	return m.until, m.open
+}
+
+// unavailable returns the error for a call to m's method, if m is in
+// maintenance.
+func (m *maintenanceSource) unavailable(method datasource.Method) error {
+	if until, ok := m.Maintenance(); ok {
+		return fmt.Errorf("middleware: %s: in maintenance until %s: %w", method, until.Format(time.RFC3339), datasource.ErrUnavailable)
+	}
+	return nil
+}
+
+func (m *maintenanceSource) CheckAvailability() bool {
+	if _, ok := m.Maintenance(); ok {
+		return false
+	}
+	return m.DataSource.CheckAvailability()
+}
+
+// HealthCheck reports the source degraded during maintenance, and its own
+// health otherwise.
+func (m *maintenanceSource) HealthCheck() datasource.HealthStatus {
+	if until, ok := m.Maintenance(); ok {
+		return datasource.HealthStatus{State: datasource.Degraded, Error: "in maintenance until " + until.Format(time.RFC3339)}
+	}
+	return datasource.CheckHealth(m.DataSource)
+}
+
+func (m *maintenanceSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if err := m.unavailable(datasource.MethodFetchTopics); err != nil {
+		return nil, err
+	}
+	return m.DataSource.FetchTopics(count, input)
+}
+
+func (m *maintenanceSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if err := m.unavailable(datasource.MethodFetchData); err != nil {
+		return nil, err
+	}
+	return m.DataSource.FetchData(count, topicID)
+}
+
+// Unwrap returns the wrapped data source.
+func (m *maintenanceSource) Unwrap() datasource.DataSource { return m.DataSource }
+
+// cronSchedule is a parsed cron expression, each field a bit set of the
+// values it matches.
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+
+	// anyDay is set if the day of month or day of week is *, in which case
+	// a day must match both fields rather than either
+	anyDay bool
+}
+
+// parseCron parses a five-field cron expression.
+func parseCron(expr string) (*cronSchedule, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("middleware: maintenance window %q: want 5 fields, got %d", expr, len(fields))
+	}
+	var c cronSchedule
+	for i, f := range []struct {
+		bits   *uint64
+		lo, hi int
+	}{
+		{&c.minute, 0, 59},
+		{&c.hour, 0, 23},
+		{&c.dom, 1, 31},
+		{&c.month, 1, 12},
+		{&c.dow, 0, 7},
+	} {
+		bits, err := parseCronField(fields[i], f.lo, f.hi)
+		if err != nil {
+			return nil, fmt.Errorf("middleware: maintenance window %q: field %d: %w", expr, i+1, err)
+		}
+		*f.bits = bits
+	}
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	c.anyDay = fields[2] == "*" || fields[4] == "*"
+	return &c, nil
+}
+
+// parseCronField parses a field of values between lo and hi.
+func parseCronField(field string, lo, hi int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], n
+		}
+		first, last := lo, hi
+		if rng != "*" {
+			a, b, isRange := strings.Cut(rng, "-")
+			var err error
+			if first, err = strconv.Atoi(a); err != nil {
+				return 0, fmt.Errorf("invalid value %q", part)
+			}
+			switch {
+			case isRange:
+				if last, err = strconv.Atoi(b); err != nil {
+					return 0, fmt.Errorf("invalid value %q", part)
+				}
+			case step == 1:
+				last = first
+			}
+		}
+		if first < lo || last > hi || first > last {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, lo, hi)
+		}
+		for v := first; v <= last; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// day reports whether the schedule matches t's day.
+func (c *cronSchedule) day(t time.Time) bool {
+	dom := c.dom&(1<<t.Day()) != 0
+	dow := c.dow&(1<<t.Weekday()) != 0
+	if c.anyDay {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// next returns the first time at or after t that the schedule matches, in
+// t's location, or the zero time if there is none within five years.
+func (c *cronSchedule) next(t time.Time) time.Time {
+	if m := t.Truncate(time.Minute); m.Before(t) {
+		t = m.Add(time.Minute)
+	}
+	loc := t.Location()
+	for limit := t.AddDate(5, 0, 0); t.Before(limit); {
+		y, mo, d := t.Date()
+		switch {
+		case c.month&(1<<mo) == 0:
+			t = time.Date(y, mo+1, 1, 0, 0, 0, 0, loc)
+		case !c.day(t):
+			t = time.Date(y, mo, d+1, 0, 0, 0, 0, loc)
+		case c.hour&(1<<t.Hour()) == 0:
+			t = time.Date(y, mo, d, t.Hour()+1, 0, 0, 0, loc)
+		case c.minute&(1<<t.Minute()) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
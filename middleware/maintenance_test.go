@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestMaintenance(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip(err)
+	}
+	now := time.Date(2026, 3, 2, 1, 30, 0, 0, berlin) // a Monday
+	src := &stubSource{}
+	ds, err := Maintenance(src, MaintenanceConfig{
+		Windows: []MaintenanceWindow{
+			{Start: "0 2 * * 1-5", Duration: time.Hour},
+			{Start: "30 23 * * 0", Duration: 2 * time.Hour},
+		},
+		Location: berlin,
+		Now:      func() time.Time { return now },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := datasource.InMaintenance(ds); ok || !ds.CheckAvailability() {
+		t.Fatal("source in maintenance before its window")
+	}
+	if _, err := ds.FetchTopics(3, datasource.NewQuestionInput{QuestionText: "dns"}); err != nil || src.topicCalls != 1 {
+		t.Fatalf("FetchTopics outside the window = %v after %d calls", err, src.topicCalls)
+	}
+
+	now = now.Add(45 * time.Minute)
+	until, ok := datasource.InMaintenance(ds)
+	if want := time.Date(2026, 3, 2, 3, 0, 0, 0, berlin); !ok || !until.Equal(want) {
+		t.Errorf("InMaintenance = %s, %t; want until %s", until, ok, want)
+	}
+	if ds.CheckAvailability() {
+		t.Error("source available during its window")
+	}
+	if h := datasource.CheckHealth(ds); h.State != datasource.Degraded || !strings.Contains(h.Error, "maintenance") {
+		t.Errorf("health %+v, want degraded for maintenance", h)
+	}
+	if _, err := ds.FetchTopics(3, datasource.NewQuestionInput{QuestionText: "dns"}); !errors.Is(err, datasource.ErrUnavailable) || src.topicCalls != 1 {
+		t.Errorf("FetchTopics in the window = %v after %d calls; want ErrUnavailable without calling the source", err, src.topicCalls)
+	}
+	if _, err := ds.FetchData(3, 1); !errors.Is(err, datasource.ErrUnavailable) || src.dataCalls != 0 {
+		t.Errorf("FetchData in the window = %v", err)
+	}
+
+	now = time.Date(2026, 3, 2, 3, 0, 0, 0, berlin)
+	if _, ok := datasource.InMaintenance(ds); ok {
+		t.Error("source in maintenance once its window closed")
+	}
+	// The Sunday window spans midnight into Monday.
+	now = time.Date(2026, 3, 2, 0, 15, 0, 0, berlin)
+	if until, ok := datasource.InMaintenance(ds); !ok || until.Hour() != 1 || until.Minute() != 30 {
+		t.Errorf("InMaintenance after midnight = %s, %t", until, ok)
+	}
+	// Saturday has no window.
+	now = time.Date(2026, 3, 7, 2, 30, 0, 0, berlin)
+	if _, ok := datasource.InMaintenance(ds); ok {
+		t.Error("source in maintenance on a weekend")
+	}
+}
+
+func TestCronSchedule(t *testing.T) {
+	from := time.Date(2026, 1, 31, 12, 0, 30, 0, time.UTC) // a Saturday
+	for _, tc := range []struct {
+		expr, want string
+	}{
+		{"* * * * *", "2026-01-31T12:01:00Z"},
+		{"*/15 * * * *", "2026-01-31T12:15:00Z"},
+		{"5/20 9-17 * * *", "2026-01-31T12:05:00Z"},
+		{"0 2 * * 7", "2026-02-01T02:00:00Z"},
+		{"0 0 1,15 * *", "2026-02-01T00:00:00Z"},
+		{"0 0 29 2 *", "2028-02-29T00:00:00Z"},
+		{"0 0 13 * 5", "2026-02-06T00:00:00Z"}, // the 13th or a Friday
+		{"0 0 31 2 *", "0001-01-01T00:00:00Z"},
+	} {
+		c, err := parseCron(tc.expr)
+		if err != nil {
+			t.Errorf("parseCron(%q): %v", tc.expr, err)
+			continue
+		}
+		if got := c.next(from).Format(time.RFC3339); got != tc.want {
+			t.Errorf("next(%q) = %s, want %s", tc.expr, got, tc.want)
+		}
+	}
+
+	for _, w := range []MaintenanceWindow{
+		{Start: "0 2 * *", Duration: time.Hour},
+		{Start: "60 2 * * *", Duration: time.Hour},
+		{Start: "0 2 * * mon", Duration: time.Hour},
+		{Start: "*/0 2 * * *", Duration: time.Hour},
+		{Start: "0 5-2 * * *", Duration: time.Hour},
+		{Start: "0 2 * * *"},
+	} {
+		if err := w.Validate(); err == nil {
+			t.Errorf("Validate(%+v) succeeded", w)
+		}
+		if ds, err := Maintenance(&stubSource{}, MaintenanceConfig{Windows: []MaintenanceWindow{w}}); err == nil {
+			t.Errorf("Maintenance with window %+v = %v, want an error", w, ds)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// now is replaced in tests to control the clock.
+var now = time.Now
+
+// MethodLimit overrides the rate limit for a single DataSource method. The
+// method gets its own token bucket instead of sharing the default one.
+type MethodLimit struct {
+	// Method is the DataSource method the limit applies to
+	Method datasource.Method
+
+	// RequestsPerSecond is the sustained rate; zero or negative disables
+	// limiting for the method
+	RequestsPerSecond float64
+
+	// Burst is the number of calls that may be made back to back before
+	// throttling starts (minimum 1)
+	Burst int
+}
+
+// RateLimit returns a DataSource that throttles calls to ds using a token
+// bucket refilled at requestsPerSecond and holding up to burst tokens. All
+// interface methods share the bucket unless an override is given for them.
+// Calls that exceed the limit block until a token is available.
+//
+// A requestsPerSecond of zero or less disables the default limit.
+func RateLimit(ds datasource.DataSource, requestsPerSecond float64, burst int, overrides ...MethodLimit) datasource.DataSource {
+	r := &rateLimitedSource{
+		DataSource: ds,
+		fallback:   newTokenBucket(requestsPerSecond, burst),
+		methods:    make(map[datasource.Method]*tokenBucket),
+	}
+	for _, o := range overrides {
+		r.methods[o.Method] = newTokenBucket(o.RequestsPerSecond, o.Burst)
+	}
+	return r
+}
+
+type rateLimitedSource struct {
+	datasource.DataSource
+	fallback *tokenBucket
+	methods  map[datasource.Method]*tokenBucket
+}
+
+func (r *rateLimitedSource) wait(m datasource.Method) {
+	b, ok := r.methods[m]
+	if !ok {
+		b = r.fallback
+	}
+	if d := b.reserve(); d > 0 {
+		sleep(d)
+	}
+}
+
+func (r *rateLimitedSource) Init() error {
+	r.wait(datasource.MethodInit)
+	return r.DataSource.Init()
+}
+
+func (r *rateLimitedSource) CheckAvailability() bool {
+	r.wait(datasource.MethodCheckAvailability)
+	return r.DataSource.CheckAvailability()
+}
+
+func (r *rateLimitedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	r.wait(datasource.MethodFetchTopics)
+	return r.DataSource.FetchTopics(count, input)
+}
+
+func (r *rateLimitedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	r.wait(datasource.MethodFetchData)
+	return r.DataSource.FetchData(count, topicID)
+}
+
+// Unwrap returns the wrapped data source.
+func (r *rateLimitedSource) Unwrap() datasource.DataSource {
+	return r.DataSource
+}
+
+// tokenBucket is a reservation-based token bucket. Callers take a token
+// immediately and are told how long to wait for it, so concurrent callers
+// are served in arrival order without holding the lock while sleeping.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64 // tokens per second; <= 0 means unlimited
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64, burst int) *tokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now()}
+}
+
+// reserve takes one token and returns how long the caller must wait before
+// using it.
+func (b *tokenBucket) reserve() time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	t := now()
+	if elapsed := t.Sub(b.last).Seconds(); elapsed > 0 {
+		b.tokens = min(b.burst, b.tokens+elapsed*b.rate)
+		b.last = t
+	}
+	b.tokens--
+	if b.tokens >= 0 {
+		return 0
+	}
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
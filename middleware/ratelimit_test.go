@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// fakeClock pins the package clock for the duration of a test.
+func fakeClock(t *testing.T) *time.Time {
+	t.Helper()
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+	return &clock
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := fakeClock(t)
+	b := newTokenBucket(2, 2)
+
+	for i := 0; i < 2; i++ {
+		if d := b.reserve(); d != 0 {
+			t.Fatalf("burst call %d waited %v", i, d)
+		}
+	}
+	if d := b.reserve(); d != 500*time.Millisecond {
+		t.Errorf("third call waited %v, want 500ms", d)
+	}
+	if d := b.reserve(); d != time.Second {
+		t.Errorf("fourth call waited %v, want 1s", d)
+	}
+
+	*clock = clock.Add(10 * time.Second)
+	if d := b.reserve(); d != 0 {
+		t.Errorf("call after refill waited %v", d)
+	}
+}
+
+func TestRateLimitPerMethodOverride(t *testing.T) {
+	fakeClock(t)
+	slept := recordSleeps(t)
+
+	ds := RateLimit(&stubSource{}, 1, 1, MethodLimit{Method: datasource.MethodFetchData, RequestsPerSecond: 0})
+	input := datasource.NewQuestionInput{QuestionText: "q"}
+
+	ds.FetchTopics(1, input)
+	ds.FetchTopics(1, input)
+	for i := 0; i < 5; i++ {
+		ds.FetchData(1, 1)
+	}
+
+	if len(*slept) != 1 || (*slept)[0] != time.Second {
+		t.Errorf("slept %v, want a single 1s wait for FetchTopics", *slept)
+	}
+}
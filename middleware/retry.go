@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"fmt"
+	"math/rand"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// sleep is replaced in tests to avoid real delays.
+var sleep = time.Sleep
+
+// RetryPolicy configures the Retry decorator. Zero-valued fields, except
+// Jitter, take the corresponding value from DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of calls made, including the first
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the first retry
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the exponentially growing delay
+	MaxBackoff time.Duration
+
+	// Multiplier is the factor applied to the delay after each retry
+	Multiplier float64
+
+	// Jitter is the fraction (0 to 1) of each delay that is randomized to
+	// avoid synchronized retries from many callers
+	Jitter float64
+
+	// MaxRetryAfter bounds the RetryAfter hint of a rate-limited error;
+	// a longer hint ends the retries, returning the error, rather than
+	// holding the caller for that long
+	MaxRetryAfter time.Duration
+
+	// Retryable decides whether an error should be retried
+	// Defaults to datasource.IsRetryable
+	Retryable func(error) bool
+}
+
+// DefaultRetryPolicy returns a policy of 3 attempts with exponential backoff
+// starting at 200ms, doubling up to 5s, with 20% jitter, waiting at most
+// 30s for a RetryAfter hint.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+		Multiplier:     2,
+		Jitter:         0.2,
+		MaxRetryAfter:  30 * time.Second,
+		Retryable:      datasource.IsRetryable,
+	}
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	d := DefaultRetryPolicy()
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = d.MaxAttempts
+	}
+	if p.InitialBackoff <= 0 {
+		p.InitialBackoff = d.InitialBackoff
+	}
+	if p.MaxBackoff <= 0 {
+		p.MaxBackoff = d.MaxBackoff
+	}
+	if p.Multiplier < 1 {
+		p.Multiplier = d.Multiplier
+	}
+	if p.Jitter < 0 || p.Jitter > 1 {
+		p.Jitter = d.Jitter
+	}
+	if p.MaxRetryAfter <= 0 {
+		p.MaxRetryAfter = d.MaxRetryAfter
+	}
+	if p.Retryable == nil {
+		p.Retryable = d.Retryable
+	}
+	return p
+}
+
+// backoff returns the delay before retry number n (starting at 0), honoring
+// a RetryAfter hint carried by err when present. It reports false if the
+// hint exceeds p.MaxRetryAfter, so the call should not be retried.
+func (p RetryPolicy) backoff(n int, err error) (time.Duration, bool) {
+	if d, ok := datasource.RetryAfter(err); ok {
+		return d, d <= p.MaxRetryAfter
+	}
+	d := float64(p.InitialBackoff)
+	for i := 0; i < n; i++ {
+		d *= p.Multiplier
+		if d >= float64(p.MaxBackoff) {
+			d = float64(p.MaxBackoff)
+			break
+		}
+	}
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(d), true
+}
+
+// Retry returns a DataSource that retries FetchTopics and FetchData on
+// errors accepted by policy.Retryable, waiting with exponential backoff and
+// jitter between attempts. When the error carries an ErrRateLimited with a
+// RetryAfter hint, that delay is used instead of the computed backoff, or
+// the error is returned at once if the hint exceeds policy.MaxRetryAfter.
+//
+// The input's Budget covers all attempts of FetchTopics, which is not
+// retried when the budget would run out during the backoff. Init and
+// CheckAvailability are passed through without retries.
+func Retry(ds datasource.DataSource, policy RetryPolicy) datasource.DataSource {
+	return &retrySource{DataSource: ds, policy: policy.withDefaults()}
+}
+
+type retrySource struct {
+	datasource.DataSource
+	policy RetryPolicy
+}
+
+func (r *retrySource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	var topics []datasource.DataSourceTopic
+	attempts := 0
+	err := r.do(input.Budget, func(budget datasource.Budget) (err error) {
+		attempts++
+		input.Budget = budget
+		topics, err = r.DataSource.FetchTopics(count, input)
+		return err
+	})
+	if attempts > 1 {
+		input.Trace.Note(fmt.Sprintf("%d attempts", attempts))
+	}
+	return topics, err
+}
+
+func (r *retrySource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	var data []datasource.DataSourceData
+	err := r.do(datasource.Budget{}, func(datasource.Budget) (err error) {
+		data, err = r.DataSource.FetchData(count, topicID)
+		return err
+	})
+	return data, err
+}
+
+// do makes up to r.policy.MaxAttempts calls, stopping early when the
+// budget would run out before the next one. Each call is given what is
+// left of the budget, so retries do not extend it.
+func (r *retrySource) do(budget datasource.Budget, call func(datasource.Budget) error) error {
+	// Remaining measures MaxLatency from now, so fix the end once.
+	left, limited := budget.Remaining()
+	if limited {
+		budget = datasource.Budget{Deadline: time.Now().Add(left)}
+	}
+	end := budget.Deadline
+	var err error
+	for attempt := 0; attempt < r.policy.MaxAttempts; attempt++ {
+		if attempt > 0 {
+			d, ok := r.policy.backoff(attempt-1, err)
+			if !ok || limited && time.Until(end) < d {
+				return err
+			}
+			sleep(d)
+		}
+		if err = call(budget); err == nil || !r.policy.Retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// Unwrap returns the wrapped data source.
+func (r *retrySource) Unwrap() datasource.DataSource {
+	return r.DataSource
+}
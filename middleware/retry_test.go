@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// stubSource is a programmable DataSource shared by the middleware tests.
+type stubSource struct {
+	topics func(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error)
+	data   func(count int, topicID int64) ([]datasource.DataSourceData, error)
+
+	topicCalls int
+	dataCalls  int
+}
+
+func (s *stubSource) Init() error             { return nil }
+func (s *stubSource) CheckAvailability() bool { return true }
+
+func (s *stubSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	s.topicCalls++
+	if s.topics == nil {
+		return []datasource.DataSourceTopic{{Topic: input.QuestionText, TopicID: int64(s.topicCalls)}}, nil
+	}
+	return s.topics(count, input)
+}
+
+func (s *stubSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	s.dataCalls++
+	if s.data == nil {
+		return []datasource.DataSourceData{{DataText: "data", AnswerID: topicID}}, nil
+	}
+	return s.data(count, topicID)
+}
+
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var slept []time.Duration
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	t.Cleanup(func() { sleep = time.Sleep })
+	return &slept
+}
+
+func TestRetrySucceedsAfterTransientErrors(t *testing.T) {
+	slept := recordSleeps(t)
+	src := &stubSource{}
+	src.topics = func(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+		if src.topicCalls < 3 {
+			return nil, datasource.ErrUnavailable
+		}
+		return []datasource.DataSourceTopic{{TopicID: 1}}, nil
+	}
+
+	ds := Retry(src, RetryPolicy{MaxAttempts: 3, InitialBackoff: 100 * time.Millisecond, Jitter: 0})
+	topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q"})
+	if err != nil {
+		t.Fatalf("FetchTopics failed: %v", err)
+	}
+	if len(topics) != 1 || src.topicCalls != 3 {
+		t.Errorf("got %d topics after %d calls", len(topics), src.topicCalls)
+	}
+	want := []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}
+	if len(*slept) != len(want) || (*slept)[0] != want[0] || (*slept)[1] != want[1] {
+		t.Errorf("slept %v, want %v", *slept, want)
+	}
+}
+
+func TestRetryStopsOnPermanentError(t *testing.T) {
+	recordSleeps(t)
+	src := &stubSource{data: func(int, int64) ([]datasource.DataSourceData, error) {
+		return nil, datasource.ErrNotFound
+	}}
+
+	ds := Retry(src, DefaultRetryPolicy())
+	if _, err := ds.FetchData(3, 1); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if src.dataCalls != 1 {
+		t.Errorf("expected a single call, got %d", src.dataCalls)
+	}
+}
+
+func TestRetryHonorsRetryAfter(t *testing.T) {
+	slept := recordSleeps(t)
+	src := &stubSource{data: func(int, int64) ([]datasource.DataSourceData, error) {
+		return nil, &datasource.ErrRateLimited{RetryAfter: 7 * time.Second}
+	}}
+
+	ds := Retry(src, RetryPolicy{MaxAttempts: 2})
+	var rl *datasource.ErrRateLimited
+	if _, err := ds.FetchData(3, 1); !errors.As(err, &rl) {
+		t.Fatalf("expected ErrRateLimited after exhausting attempts, got %v", err)
+	}
+	if src.dataCalls != 2 {
+		t.Errorf("expected 2 calls, got %d", src.dataCalls)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 7*time.Second {
+		t.Errorf("slept %v, want [7s]", *slept)
+	}
+}
+
+func TestRetryGivesUpOnLongRetryAfter(t *testing.T) {
+	slept := recordSleeps(t)
+	src := &stubSource{data: func(int, int64) ([]datasource.DataSourceData, error) {
+		return nil, &datasource.ErrRateLimited{RetryAfter: time.Hour}
+	}}
+
+	ds := Retry(src, RetryPolicy{MaxAttempts: 3, MaxRetryAfter: time.Minute})
+	var rl *datasource.ErrRateLimited
+	if _, err := ds.FetchData(3, 1); !errors.As(err, &rl) || rl.RetryAfter != time.Hour {
+		t.Fatalf("expected the ErrRateLimited of the first call, got %v", err)
+	}
+	if src.dataCalls != 1 || len(*slept) != 0 {
+		t.Errorf("made %d calls and slept %v, want a single call without sleeping", src.dataCalls, *slept)
+	}
+}
+
+func TestRetryStopsWhenBudgetRunsOut(t *testing.T) {
+	slept := recordSleeps(t)
+	src := &stubSource{topics: func(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+		return nil, datasource.ErrUnavailable
+	}}
+
+	ds := Retry(src, RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Second, Jitter: 0})
+	input := datasource.NewQuestionInput{QuestionText: "q", Budget: datasource.Budget{MaxLatency: 500 * time.Millisecond}}
+	if _, err := ds.FetchTopics(5, input); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if src.topicCalls != 1 || len(*slept) != 0 {
+		t.Errorf("made %d calls and slept %v, want a single call without sleeping", src.topicCalls, *slept)
+	}
+
+	input.Budget.MaxLatency = time.Minute
+	if _, err := ds.FetchTopics(5, input); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Fatalf("expected ErrUnavailable, got %v", err)
+	}
+	if src.topicCalls != 4 {
+		t.Errorf("made %d calls with time to spare, want 3", src.topicCalls-1)
+	}
+}
+
+func TestRetryKeepsBudgetAcrossAttempts(t *testing.T) {
+	recordSleeps(t)
+	var second time.Duration
+	src := &stubSource{}
+	src.topics = func(_ int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+		if src.topicCalls == 1 {
+			time.Sleep(80 * time.Millisecond) // most of the budget
+			return nil, datasource.ErrUnavailable
+		}
+		second, _ = input.Budget.Remaining()
+		return []datasource.DataSourceTopic{{TopicID: 1}}, nil
+	}
+
+	ds := Retry(src, RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond, Jitter: 0})
+	input := datasource.NewQuestionInput{QuestionText: "q", Budget: datasource.Budget{MaxLatency: 100 * time.Millisecond}}
+	if _, err := ds.FetchTopics(5, input); err != nil {
+		t.Fatal(err)
+	}
+	if src.topicCalls != 2 || second > 20*time.Millisecond {
+		t.Errorf("second of %d attempts had %v left, want at most the 20ms the first left", src.topicCalls, second)
+	}
+}
+
+func TestRetryBackoffIsCapped(t *testing.T) {
+	p := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 3 * time.Second, Multiplier: 10}.withDefaults()
+	p.Jitter = 0
+	for n, want := range []time.Duration{time.Second, 3 * time.Second, 3 * time.Second} {
+		if got, _ := p.backoff(n, nil); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// Sanitize returns a DataSource that passes the DataText of every HTML data
+// item from FetchData through policy (see content.Policy), removing
+// scripts, trackers, and unsafe markup before the host renders it. Items
+// declared as HTML are sanitized, as are undeclared items that
+// content.Detect recognizes as HTML; sanitized items are then declared as
+// HTML. Markdown, plain text, and code are passed through unchanged.
+//
+// Streaming sources are not streamed through Sanitize: datasource.StreamData
+// falls back to FetchData, so every item is sanitized.
+func Sanitize(ds datasource.DataSource, policy content.Policy) datasource.DataSource {
+	return &sanitizedSource{DataSource: ds, policy: policy}
+}
+
+type sanitizedSource struct {
+	datasource.DataSource
+	policy content.Policy
+}
+
+func (s *sanitizedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	data, err := s.DataSource.FetchData(count, topicID)
+	// Copy so items shared with the source, such as cached results, keep
+	// their original text.
+	if data != nil {
+		data = append([]datasource.DataSourceData{}, data...)
+	}
+	for i := range data {
+		d := &data[i]
+		if d.ContentType == "" && content.Detect(d.DataText) == datasource.ContentHTML {
+			d.ContentType = datasource.ContentHTML
+		}
+		if d.ContentType == datasource.ContentHTML {
+			d.DataText = s.policy.Sanitize(d.DataText)
+		}
+	}
+	return data, err
+}
+
+// Unwrap returns the wrapped data source.
+func (s *sanitizedSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
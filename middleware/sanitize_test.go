@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+func TestSanitize(t *testing.T) {
+	items := []datasource.DataSourceData{
+		{DataText: `<p onclick="x()">a<script>steal()</script></p>`, ContentType: datasource.ContentHTML},
+		{DataText: `<b>b</b><img src="https://example.com/t.gif" width="1" height="1">`},
+		{DataText: "a <script> tag in **markdown**", ContentType: datasource.ContentMarkdown},
+		{DataText: "if a < b {}", ContentType: datasource.ContentCode},
+	}
+	src := &stubSource{}
+	src.data = func(int, int64) ([]datasource.DataSourceData, error) { return items, nil }
+	ds := Sanitize(src, content.Policy{})
+
+	got, err := ds.FetchData(10, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		text string
+		typ  datasource.ContentType
+	}{
+		{"<p>a</p>", datasource.ContentHTML},
+		{"<b>b</b>", datasource.ContentHTML},
+		{items[2].DataText, datasource.ContentMarkdown},
+		{items[3].DataText, datasource.ContentCode},
+	}
+	for i, w := range want {
+		if got[i].DataText != w.text || got[i].ContentType != w.typ {
+			t.Errorf("item %d = %q (%s), want %q (%s)", i, got[i].DataText, got[i].ContentType, w.text, w.typ)
+		}
+	}
+	if items[0].DataText != `<p onclick="x()">a<script>steal()</script></p>` || items[1].ContentType != "" {
+		t.Error("source items were modified")
+	}
+	items = []datasource.DataSourceData{}
+	if got, err := ds.FetchData(10, 1); err != nil || got == nil {
+		t.Errorf("FetchData of no items = %v, %v; want an empty slice", got, err)
+	}
+	if u, ok := ds.(interface{ Unwrap() datasource.DataSource }); !ok || u.Unwrap() != src {
+		t.Error("Unwrap does not return the wrapped source")
+	}
+}
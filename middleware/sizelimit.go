@@ -0,0 +1,144 @@
+package middleware
+
+import (
+	"sync"
+	"unicode/utf8"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/topicmap"
+)
+
+// MetadataTruncated is the metadata key SizeLimit sets to true on data
+// items whose DataText it cut short.
+const MetadataTruncated = "truncated"
+
+// SizeLimits configures the SizeLimit decorator. Limits count the bytes of
+// DataText; zero means unlimited.
+type SizeLimits struct {
+	// PerCall bounds the DataText returned by one FetchData call
+	PerCall int
+
+	// PerQuery bounds the DataText returned by the FetchData calls for
+	// the topics of one FetchTopics call. Calls for topics the decorator
+	// has not returned are bounded by PerCall only
+	PerQuery int
+
+	// Drop drops every item that does not fit, keeping later items that
+	// do. Otherwise the first item that does not fit is truncated, at a
+	// UTF-8 boundary, and marked with MetadataTruncated, and the items
+	// after it are dropped
+	Drop bool
+
+	// OnLimit, if set, is called after a FetchData call for topicID had
+	// items truncated or dropped, for logging and metrics
+	OnLimit func(topicID int64, truncated, dropped int)
+}
+
+// SizeLimit returns a DataSource that caps the text FetchData returns, so
+// a source that occasionally returns enormous documents cannot exhaust
+// the host's memory. Items are kept in order until a limit is reached;
+// see SizeLimits for what happens to the rest.
+//
+// Streaming sources are not streamed through SizeLimit:
+// datasource.StreamData falls back to FetchData, so every item is counted.
+func SizeLimit(ds datasource.DataSource, limits SizeLimits) datasource.DataSource {
+	return &sizeLimitedSource{DataSource: ds, limits: limits}
+}
+
+type sizeLimitedSource struct {
+	datasource.DataSource
+	limits  SizeLimits
+	queries topicmap.Map[*queryBudget] // the query each topic was returned for
+}
+
+// queryBudget is the DataText a query has used.
+type queryBudget struct {
+	mu   sync.Mutex
+	used int
+}
+
+func (s *sizeLimitedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	topics, err := s.DataSource.FetchTopics(count, input)
+	if s.limits.PerQuery > 0 && len(topics) > 0 {
+		s.queries.Set(topics, &queryBudget{})
+	}
+	return topics, err
+}
+
+func (s *sizeLimitedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	data, err := s.DataSource.FetchData(count, topicID)
+	if len(data) == 0 {
+		return data, err
+	}
+	limit := -1
+	if s.limits.PerCall > 0 {
+		limit = s.limits.PerCall
+	}
+	q, _ := s.queries.Get(topicID)
+	if q != nil {
+		// Hold the query's budget while cutting so that concurrent calls
+		// for its topics cannot overspend it together.
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		remaining := max(s.limits.PerQuery-q.used, 0)
+		if limit < 0 || remaining < limit {
+			limit = remaining
+		}
+	}
+	if limit < 0 {
+		return data, err
+	}
+
+	data, used, truncated, dropped := s.cut(data, limit)
+	if q != nil {
+		q.used += used
+	}
+	if (truncated > 0 || dropped > 0) && s.limits.OnLimit != nil {
+		s.limits.OnLimit(topicID, truncated, dropped)
+	}
+	return data, err
+}
+
+// cut returns the items of data that fit within limit bytes of DataText,
+// the bytes they use, and how many items were truncated and dropped.
+func (s *sizeLimitedSource) cut(data []datasource.DataSourceData, limit int) (kept []datasource.DataSourceData, used, truncated, dropped int) {
+	kept = make([]datasource.DataSourceData, 0, len(data))
+	for i, d := range data {
+		if used+len(d.DataText) <= limit {
+			kept = append(kept, d)
+			used += len(d.DataText)
+			continue
+		}
+		if s.limits.Drop {
+			dropped++
+			continue
+		}
+		n := limit - used
+		for n > 0 && !utf8.RuneStart(d.DataText[n]) {
+			n--
+		}
+		if n > 0 {
+			d.DataText = d.DataText[:n]
+			// Copy the metadata so that items shared with the source, such
+			// as cached results, are not marked.
+			meta := make(datasource.Metadata, len(d.Metadata)+1)
+			for k, v := range d.Metadata {
+				meta[k] = v
+			}
+			meta.Set(MetadataTruncated, true)
+			d.Metadata = meta
+			kept = append(kept, d)
+			used += n
+			truncated++
+			i++
+		}
+		dropped += len(data) - i
+		break
+	}
+	return kept, used, truncated, dropped
+}
+
+// Unwrap returns the wrapped data source.
+func (s *sizeLimitedSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
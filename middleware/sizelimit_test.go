@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func texts(items []datasource.DataSourceData) []string {
+	out := make([]string, len(items))
+	for i, d := range items {
+		out[i] = d.DataText
+	}
+	return out
+}
+
+func TestSizeLimitPerCall(t *testing.T) {
+	items := []datasource.DataSourceData{{DataText: "aaaa"}, {DataText: "héllo wörld"}, {DataText: "cc"}}
+	src := &stubSource{}
+	src.data = func(int, int64) ([]datasource.DataSourceData, error) { return items, nil }
+
+	var truncated, dropped int
+	ds := SizeLimit(src, SizeLimits{PerCall: 6, OnLimit: func(_ int64, t, d int) { truncated, dropped = t, d }})
+	got, _ := ds.FetchData(10, 1)
+	// The cut at byte 2 of "héllo" falls inside "é" and backs up to "h".
+	if want := []string{"aaaa", "h"}; !equalStrings(texts(got), want) {
+		t.Errorf("truncated = %q, want %q", texts(got), want)
+	}
+	if v, _ := got[1].Metadata.Bool(MetadataTruncated); !v || got[0].Metadata != nil {
+		t.Errorf("metadata = %v, %v; want only the cut item marked", got[0].Metadata, got[1].Metadata)
+	}
+	if truncated != 1 || dropped != 1 {
+		t.Errorf("OnLimit(%d, %d), want (1, 1)", truncated, dropped)
+	}
+	if items[1].Metadata != nil {
+		t.Error("source items were modified")
+	}
+
+	ds = SizeLimit(src, SizeLimits{PerCall: 6, Drop: true})
+	got, _ = ds.FetchData(10, 1)
+	if want := []string{"aaaa", "cc"}; !equalStrings(texts(got), want) {
+		t.Errorf("dropped = %q, want %q", texts(got), want)
+	}
+}
+
+func TestSizeLimitPerQuery(t *testing.T) {
+	src := &stubSource{}
+	src.topics = func(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+		return []datasource.DataSourceTopic{{TopicID: 1}, {TopicID: 2}}, nil
+	}
+	src.data = func(_ int, topicID int64) ([]datasource.DataSourceData, error) {
+		return []datasource.DataSourceData{{DataText: "0123456789"}}, nil
+	}
+	ds := SizeLimit(src, SizeLimits{PerQuery: 15, Drop: true})
+
+	if got, _ := ds.FetchData(1, 1); len(got) != 1 {
+		t.Fatalf("before FetchTopics: %d items, want the unlimited call's 1", len(got))
+	}
+	ds.FetchTopics(2, datasource.NewQuestionInput{QuestionText: "q"})
+	if got, _ := ds.FetchData(1, 1); len(got) != 1 {
+		t.Errorf("first topic: %d items, want 1", len(got))
+	}
+	if got, _ := ds.FetchData(1, 2); len(got) != 0 {
+		t.Errorf("second topic: %d items, want none within the query's budget", len(got))
+	}
+	if got, _ := ds.FetchData(1, 3); len(got) != 1 {
+		t.Errorf("topic of no query: %d items, want 1", len(got))
+	}
+
+	// A new query gets a new budget.
+	ds.FetchTopics(2, datasource.NewQuestionInput{QuestionText: "q"})
+	if got, _ := ds.FetchData(1, 2); len(got) != 1 {
+		t.Errorf("next query: %d items, want 1", len(got))
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Default timeouts used when Timeouts fields are zero.
+const (
+	DefaultInitTimeout   = 60 * time.Second
+	DefaultFetchTimeout  = 8 * time.Second
+	DefaultHealthTimeout = 2 * time.Second
+)
+
+// Timeouts bounds each kind of call to a data source. A negative timeout
+// disables the bound.
+type Timeouts struct {
+	// Init bounds Init, which may load indexes or models
+	// Defaults to DefaultInitTimeout
+	Init time.Duration
+
+	// Fetch bounds FetchTopics and FetchData
+	// Defaults to DefaultFetchTimeout
+	Fetch time.Duration
+
+	// Health bounds CheckAvailability and health checks
+	// Defaults to DefaultHealthTimeout
+	Health time.Duration
+}
+
+func (t Timeouts) withDefaults() Timeouts {
+	if t.Init == 0 {
+		t.Init = DefaultInitTimeout
+	}
+	if t.Fetch == 0 {
+		t.Fetch = DefaultFetchTimeout
+	}
+	if t.Health == 0 {
+		t.Health = DefaultHealthTimeout
+	}
+	return t
+}
+
+// IsZero reports whether t sets no timeout, leaving every one at its
+// default.
+func (t Timeouts) IsZero() bool {
+	return t == Timeouts{}
+}
+
+// Timeout returns a DataSource that bounds each call to ds by the timeout
+// of its kind. A call that overruns fails with an error wrapping
+// context.DeadlineExceeded, or, for CheckAvailability and health checks,
+// reports the source unavailable and unhealthy; the call itself is left
+// to finish in the background, as the interface cannot cancel it.
+// FetchTopics also shortens the question's Budget to the fetch timeout,
+// so sources honoring it give up in time.
+//
+// Place Timeout beneath Retry so that each attempt is bounded, or above
+// it to bound the attempts together.
+func Timeout(ds datasource.DataSource, timeouts Timeouts) datasource.DataSource {
+	return &timeoutSource{DataSource: ds, timeouts: timeouts.withDefaults()}
+}
+
+type timeoutSource struct {
+	datasource.DataSource
+	timeouts Timeouts
+}
+
+// bounded runs call, failing with an error once d has passed.
+func bounded[T any](m datasource.Method, d time.Duration, call func() (T, error)) (T, error) {
+	if d < 0 {
+		return call()
+	}
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := call()
+		done <- result{v, err}
+	}()
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-t.C:
+		var zero T
+		return zero, fmt.Errorf("middleware: %s timed out after %s: %w", m, d, context.DeadlineExceeded)
+	}
+}
+
+func (s *timeoutSource) Init() error {
+	_, err := bounded(datasource.MethodInit, s.timeouts.Init, func() (struct{}, error) {
+		return struct{}{}, s.DataSource.Init()
+	})
+	return err
+}
+
+func (s *timeoutSource) CheckAvailability() bool {
+	ok, err := bounded(datasource.MethodCheckAvailability, s.timeouts.Health, func() (bool, error) {
+		return s.DataSource.CheckAvailability(), nil
+	})
+	return ok && err == nil
+}
+
+// HealthCheck checks the wrapped source's health, reporting it unhealthy
+// if the check overruns the health timeout.
+func (s *timeoutSource) HealthCheck() datasource.HealthStatus {
+	h, err := bounded(datasource.MethodCheckAvailability, s.timeouts.Health, func() (datasource.HealthStatus, error) {
+		return datasource.CheckHealth(s.DataSource), nil
+	})
+	if err != nil {
+		return datasource.HealthStatus{State: datasource.Unhealthy, Latency: s.timeouts.Health, Error: err.Error()}
+	}
+	return h
+}
+
+func (s *timeoutSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if d := s.timeouts.Fetch; d > 0 && (input.Budget.MaxLatency <= 0 || input.Budget.MaxLatency > d) {
+		input.Budget.MaxLatency = d
+	}
+	return bounded(datasource.MethodFetchTopics, s.timeouts.Fetch, func() ([]datasource.DataSourceTopic, error) {
+		return s.DataSource.FetchTopics(count, input)
+	})
+}
+
+func (s *timeoutSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	return bounded(datasource.MethodFetchData, s.timeouts.Fetch, func() ([]datasource.DataSourceData, error) {
+		return s.DataSource.FetchData(count, topicID)
+	})
+}
+
+// Unwrap returns the wrapped data source.
+func (s *timeoutSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// slowInit delays Init and CheckAvailability.
+type slowInit struct {
+	*stubSource
+	delay time.Duration
+}
+
+func (s slowInit) Init() error {
+	time.Sleep(s.delay)
+	return nil
+}
+
+func (s slowInit) CheckAvailability() bool {
+	time.Sleep(s.delay)
+	return true
+}
+
+func TestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var budget datasource.Budget
+	src := &stubSource{
+		topics: func(_ int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+			budget = input.Budget
+			return []datasource.DataSourceTopic{{TopicID: 1}}, nil
+		},
+		data: func(int, int64) ([]datasource.DataSourceData, error) {
+			<-release
+			return nil, nil
+		},
+	}
+	ds := Timeout(slowInit{src, 50 * time.Millisecond}, Timeouts{Init: time.Second, Fetch: 20 * time.Millisecond, Health: 10 * time.Millisecond})
+
+	if err := ds.Init(); err != nil {
+		t.Errorf("Init within its timeout = %v", err)
+	}
+	if topics, err := ds.FetchTopics(1, datasource.NewQuestionInput{Budget: datasource.Budget{MaxLatency: time.Minute}}); err != nil || len(topics) != 1 {
+		t.Errorf("FetchTopics = %v, %v", topics, err)
+	}
+	if budget.MaxLatency != 20*time.Millisecond {
+		t.Errorf("source's budget = %v, want the fetch timeout", budget.MaxLatency)
+	}
+	_, err := ds.FetchData(1, 1)
+	if !errors.Is(err, context.DeadlineExceeded) || datasource.ErrorClass(err) != "deadline_exceeded" {
+		t.Errorf("FetchData past its timeout = %v", err)
+	}
+	if ds.CheckAvailability() {
+		t.Error("CheckAvailability past its timeout reported available")
+	}
+	if h := datasource.CheckHealth(ds); h.State != datasource.Unhealthy || h.Error == "" {
+		t.Errorf("health past its timeout = %+v", h)
+	}
+
+	ds = Timeout(slowInit{src, 30 * time.Millisecond}, Timeouts{Init: 10 * time.Millisecond, Health: -1})
+	if err := ds.Init(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Init past its timeout = %v", err)
+	}
+	if !ds.CheckAvailability() {
+		t.Error("CheckAvailability without a timeout failed")
+	}
+}
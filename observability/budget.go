@@ -0,0 +1,232 @@
+package observability
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// DefaultBudgetWindow is the window of a Budget whose Window is zero.
+const DefaultBudgetWindow = time.Hour
+
+// Resource is what a Budget limits.
+type Resource string
+
+// Resources limited by a Budget.
+const (
+	ResourceItems Resource = "items" // topics and data items returned
+	ResourceBytes Resource = "bytes" // the size of the results; see ResultBytes
+)
+
+// Level is the level of a budget.
+type Level string
+
+// Budget levels.
+const (
+	LevelSoft Level = "soft" // alerts
+	LevelHard Level = "hard" // alerts and throttles
+)
+
+// Budget bounds the results a data source may return per time window, so
+// that a misconfigured source pulling far more data than intended is
+// noticed, and stopped, before it exhausts the host or an upstream quota.
+// Limits are zero for none.
+type Budget struct {
+	// Window is the length of the fixed windows usage is counted in
+	// Defaults to DefaultBudgetWindow
+	Window time.Duration
+
+	// SoftItems and SoftBytes are the usage per window past which an
+	// alert is raised
+	SoftItems, SoftBytes int64
+
+	// HardItems and HardBytes are the usage per window past which an
+	// alert is raised and calls fail with datasource.ErrRateLimited until
+	// the window ends
+	HardItems, HardBytes int64
+
+	// OnAlert, if set, is called the first time in a window usage exceeds
+	// each limit
+	OnAlert func(Alert)
+}
+
+// Alert reports that a source exceeded a budget.
+type Alert struct {
+	// Source names the source
+	Source string
+
+	// Resource and Level identify the limit exceeded
+	Resource Resource
+	Level    Level
+
+	// Used is the usage in the window so far, and Limit the limit
+	Used, Limit int64
+
+	// Reset is when the window ends
+	Reset time.Time
+}
+
+func (a Alert) String() string {
+	return fmt.Sprintf("%s exceeded its %s budget of %d %s: %d used until %s",
+		a.Source, a.Level, a.Limit, a.Resource, a.Used, a.Reset.Format(time.RFC3339))
+}
+
+// Meter returns a DataSource that counts the items and bytes FetchTopics
+// and FetchData return against budget, in windows of budget.Window. Past a
+// soft limit it raises an alert; past a hard limit it also throttles:
+// calls fail with datasource.ErrRateLimited, without calling ds, until the
+// window ends. The call that crosses a hard limit still returns its
+// results. Exceeded limits are counted in reg, labeled with name, if reg is
+// not nil.
+func Meter(ds datasource.DataSource, name string, budget Budget, reg *Registry) datasource.DataSource {
+	if budget.Window <= 0 {
+		budget.Window = DefaultBudgetWindow
+	}
+	return &meteredSource{DataSource: ds, name: name, budget: budget, reg: reg}
+}
+
+type meteredSource struct {
+	datasource.DataSource
+	name   string
+	budget Budget
+	reg    *Registry
+
+	mu           sync.Mutex
+	start        time.Time // of the current window
+	items, bytes int64
+	alerted      map[Level]map[Resource]bool
+}
+
+func (s *meteredSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	topics, err := s.DataSource.FetchTopics(count, input)
+	s.add(len(topics), ResultBytes(topics, nil))
+	return topics, err
+}
+
+func (s *meteredSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	data, err := s.DataSource.FetchData(count, topicID)
+	s.add(len(data), ResultBytes(nil, data))
+	return data, err
+}
+
+// Unwrap returns the wrapped data source.
+func (s *meteredSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
+
+// roll starts a new window if the current one has ended. Callers hold s.mu.
+func (s *meteredSource) roll(t time.Time) {
+	if s.start.IsZero() || t.Sub(s.start) >= s.budget.Window {
+		s.start, s.items, s.bytes = t, 0, 0
+		s.alerted = map[Level]map[Resource]bool{LevelSoft: {}, LevelHard: {}}
+	}
+}
+
+// check fails with ErrRateLimited while a hard limit is exceeded.
+func (s *meteredSource) check() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := now()
+	s.roll(t)
+	for _, r := range []Resource{ResourceItems, ResourceBytes} {
+		if limit, used := s.limit(LevelHard, r), s.used(r); limit > 0 && used >= limit {
+			reset := s.start.Add(s.budget.Window)
+			return &datasource.ErrRateLimited{
+				RetryAfter: reset.Sub(t),
+				Err:        fmt.Errorf("observability: %s exceeded its hard budget of %d %s per %s", s.name, limit, r, s.budget.Window),
+			}
+		}
+	}
+	return nil
+}
+
+// add counts a call's results, and raises the alerts for the limits they
+// exceed first in the window.
+func (s *meteredSource) add(items int, bytes int64) {
+	s.mu.Lock()
+	s.roll(now())
+	s.items += int64(items)
+	s.bytes += bytes
+	var alerts []Alert
+	for _, level := range []Level{LevelSoft, LevelHard} {
+		for _, r := range []Resource{ResourceItems, ResourceBytes} {
+			limit, used := s.limit(level, r), s.used(r)
+			if limit <= 0 || used <= limit || s.alerted[level][r] {
+				continue
+			}
+			s.alerted[level][r] = true
+			alerts = append(alerts, Alert{Source: s.name, Resource: r, Level: level, Used: used, Limit: limit, Reset: s.start.Add(s.budget.Window)})
+		}
+	}
+	s.mu.Unlock()
+
+	for _, a := range alerts {
+		if s.reg != nil {
+			s.reg.ObserveBudget(s.name, a.Resource, a.Level)
+		}
+		if s.budget.OnAlert != nil {
+			s.budget.OnAlert(a)
+		}
+	}
+}
+
+func (s *meteredSource) limit(level Level, r Resource) int64 {
+	switch {
+	case level == LevelSoft && r == ResourceItems:
+		return s.budget.SoftItems
+	case level == LevelSoft:
+		return s.budget.SoftBytes
+	case r == ResourceItems:
+		return s.budget.HardItems
+	default:
+		return s.budget.HardBytes
+	}
+}
+
+func (s *meteredSource) used(r Resource) int64 {
+	if r == ResourceItems {
+		return s.items
+	}
+	return s.bytes
+}
+
+// ResultBytes returns the size of topics and data items, as the bytes of
+// their text, URLs, and metadata, and eight per embedding dimension. It
+// approximates what a source transfers for them, not their size on the
+// wire.
+func ResultBytes(topics []datasource.DataSourceTopic, data []datasource.DataSourceData) int64 {
+	var n int
+	for _, t := range topics {
+		n += len(t.Topic) + len(t.Title) + len(t.BodyExcerpt) + len(t.SourceURL) + 8*len(t.Embedding)
+		n += metadataBytes(t.Metadata)
+	}
+	for _, d := range data {
+		n += len(d.DataText) + len(d.SourceURL) + 8*len(d.Embedding)
+		n += metadataBytes(d.Metadata)
+	}
+	return int64(n)
+}
+
+func metadataBytes(m datasource.Metadata) int {
+	n := 0
+	for k, v := range m {
+		n += len(k)
+		if s, ok := v.(string); ok {
+			n += len(s)
+		} else {
+			n += 8
+		}
+	}
+	return n
+}
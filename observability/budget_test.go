@@ -0,0 +1,91 @@
+package observability
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// bulkySource returns count data items of 100 bytes each.
+type bulkySource struct{ calls int }
+
+func (s *bulkySource) Init() error             { return nil }
+func (s *bulkySource) CheckAvailability() bool { return true }
+func (s *bulkySource) FetchTopics(count int, _ datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	s.calls++
+	return []datasource.DataSourceTopic{{Topic: "t", TopicID: 1}}, nil
+}
+func (s *bulkySource) FetchData(count int, _ int64) ([]datasource.DataSourceData, error) {
+	s.calls++
+	data := make([]datasource.DataSourceData, count)
+	for i := range data {
+		data[i].DataText = strings.Repeat("x", 100)
+	}
+	return data, nil
+}
+
+func TestMeter(t *testing.T) {
+	clock := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	var alerts []Alert
+	reg := NewRegistry()
+	src := &bulkySource{}
+	ds := Meter(Instrument(src, "wiki", reg), "wiki", Budget{
+		Window:    time.Minute,
+		SoftItems: 15,
+		HardBytes: 2500,
+		OnAlert:   func(a Alert) { alerts = append(alerts, a) },
+	}, reg)
+
+	for i := 0; i < 2; i++ {
+		if _, err := ds.FetchData(10, 1); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if len(alerts) != 1 || alerts[0].Level != LevelSoft || alerts[0].Resource != ResourceItems || alerts[0].Used != 20 {
+		t.Fatalf("alerts after 20 items = %+v", alerts)
+	}
+
+	clock = clock.Add(10 * time.Second)
+	if _, err := ds.FetchData(10, 1); err != nil {
+		t.Fatal(err)
+	}
+	if len(alerts) != 2 || alerts[1].Level != LevelHard || alerts[1].Resource != ResourceBytes || alerts[1].Used != 3000 {
+		t.Fatalf("alerts after 3000 bytes = %+v", alerts)
+	}
+	calls := src.calls
+	_, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q"})
+	var limited *datasource.ErrRateLimited
+	if !errors.As(err, &limited) || limited.RetryAfter != 50*time.Second {
+		t.Fatalf("FetchTopics past the hard budget = %v, want ErrRateLimited for the rest of the window", err)
+	}
+	if src.calls != calls {
+		t.Error("throttled call reached the source")
+	}
+
+	clock = clock.Add(time.Minute)
+	if _, err := ds.FetchData(10, 1); err != nil {
+		t.Errorf("FetchData in a new window = %v", err)
+	}
+	if len(alerts) != 2 {
+		t.Errorf("alerts in a new window = %+v", alerts[2:])
+	}
+
+	var b strings.Builder
+	reg.WriteTo(&b)
+	for _, want := range []string{
+		`locus_datasource_items_total{source="wiki",method="FetchData"} 40`,
+		`locus_datasource_bytes_total{source="wiki",method="FetchData"} 4000`,
+		`locus_datasource_budget_exceeded_total{source="wiki",resource="bytes",level="hard"} 1`,
+		`locus_datasource_budget_exceeded_total{source="wiki",resource="items",level="soft"} 1`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("exposition missing %q\n%s", want, b.String())
+		}
+	}
+}
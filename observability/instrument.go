@@ -0,0 +1,67 @@
+package observability
+
+import (
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Instrument returns a DataSource that records every call to ds in reg,
+// labeled with name, the number and size of the results FetchTopics and
+// FetchData return, and the capabilities their successful calls exercise
+// (see Registry.Usage). A CheckAvailability result of false is counted as
+// an error of class "unavailable".
+func Instrument(ds datasource.DataSource, name string, reg *Registry) datasource.DataSource {
+	return &instrumentedSource{DataSource: ds, name: name, reg: reg}
+}
+
+type instrumentedSource struct {
+	datasource.DataSource
+	name string
+	reg  *Registry
+}
+
+func (s *instrumentedSource) Init() error {
+	start := time.Now()
+	err := s.DataSource.Init()
+	s.reg.Observe(s.name, datasource.MethodInit, time.Since(start), err)
+	return err
+}
+
+func (s *instrumentedSource) CheckAvailability() bool {
+	start := time.Now()
+	ok := s.DataSource.CheckAvailability()
+	var err error
+	if !ok {
+		err = datasource.ErrUnavailable
+	}
+	s.reg.Observe(s.name, datasource.MethodCheckAvailability, time.Since(start), err)
+	return ok
+}
+
+func (s *instrumentedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	start := time.Now()
+	topics, err := s.DataSource.FetchTopics(count, input)
+	s.reg.Observe(s.name, datasource.MethodFetchTopics, time.Since(start), err)
+	s.reg.ObserveTransfer(s.name, datasource.MethodFetchTopics, len(topics), ResultBytes(topics, nil))
+	if err == nil {
+		s.reg.ObserveUsage(s.name, datasource.CapabilitiesOf(s.DataSource), len(topics), topicsUsage(input, topics)...)
+	}
+	return topics, err
+}
+
+func (s *instrumentedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	start := time.Now()
+	data, err := s.DataSource.FetchData(count, topicID)
+	s.reg.Observe(s.name, datasource.MethodFetchData, time.Since(start), err)
+	s.reg.ObserveTransfer(s.name, datasource.MethodFetchData, len(data), ResultBytes(nil, data))
+	if err == nil {
+		s.reg.ObserveUsage(s.name, datasource.CapabilitiesOf(s.DataSource), len(data), dataUsage(data)...)
+	}
+	return data, err
+}
+
+// Unwrap returns the wrapped data source.
+func (s *instrumentedSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
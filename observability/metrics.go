@@ -0,0 +1,268 @@
+// Package observability instruments data sources with request, error,
+// latency, and transfer metrics, and meters them against budgets.
+//
+// Metrics are collected in a Registry that serves them in the Prometheus
+// text exposition format, so any Prometheus-compatible scraper can collect
+// them without adding the Prometheus client library (and its dependency
+// tree) to hosts:
+//
+//	reg := observability.NewRegistry()
+//	ds = observability.Instrument(ds, "wikipedia", reg)
+//	http.Handle("/metrics", reg)
+//
+// A Registry also summarizes which Capabilities of each source calls
+// actually exercise (Registry.Usage), so maintainers can prune
+// configuration nothing uses.
+package observability
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// DefaultBuckets are the latency histogram upper bounds in seconds.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Metric names exported by a Registry.
+const (
+	RequestsMetric = "locus_datasource_requests_total"
+	ErrorsMetric   = "locus_datasource_errors_total"
+	LatencyMetric  = "locus_datasource_request_duration_seconds"
+	ItemsMetric    = "locus_datasource_items_total"
+	BytesMetric    = "locus_datasource_bytes_total"
+	BudgetMetric   = "locus_datasource_budget_exceeded_total"
+)
+
+type seriesKey struct {
+	source string
+	method datasource.Method
+}
+
+type errorKey struct {
+	seriesKey
+	class string
+}
+
+type budgetKey struct {
+	source   string
+	resource Resource
+	level    Level
+}
+
+type histogram struct {
+	counts []uint64 // per bucket, non-cumulative; last is +Inf
+	sum    float64
+	count  uint64
+}
+
+// Registry collects metrics from instrumented data sources. It implements
+// http.Handler, serving the current values in the Prometheus text format.
+// A Registry is safe for concurrent use.
+type Registry struct {
+	buckets []float64
+
+	mu       sync.Mutex
+	requests map[seriesKey]uint64
+	errors   map[errorKey]uint64
+	latency  map[seriesKey]*histogram
+	items    map[seriesKey]uint64
+	bytes    map[seriesKey]uint64
+	budgets  map[budgetKey]uint64
+
+	usage      map[string]*usageCounts
+	usageSince time.Time
+}
+
+// NewRegistry creates an empty Registry using DefaultBuckets.
+func NewRegistry() *Registry {
+	return &Registry{
+		buckets:  DefaultBuckets,
+		requests: make(map[seriesKey]uint64),
+		errors:   make(map[errorKey]uint64),
+		latency:  make(map[seriesKey]*histogram),
+		items:    make(map[seriesKey]uint64),
+		bytes:    make(map[seriesKey]uint64),
+		budgets:  make(map[budgetKey]uint64),
+
+		usage:      make(map[string]*usageCounts),
+		usageSince: time.Now(),
+	}
+}
+
+// Observe records one call to method of the named source that took d and
+// failed with err (nil on success). Instrument calls it automatically;
+// hosts can call it directly for calls made outside an instrumented source.
+func (r *Registry) Observe(source string, method datasource.Method, d time.Duration, err error) {
+	k := seriesKey{source, method}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.requests[k]++
+	if err != nil {
+		r.errors[errorKey{k, datasource.ErrorClass(err)}]++
+	}
+	h := r.latency[k]
+	if h == nil {
+		h = &histogram{counts: make([]uint64, len(r.buckets)+1)}
+		r.latency[k] = h
+	}
+	secs := d.Seconds()
+	i := sort.SearchFloat64s(r.buckets, secs)
+	h.counts[i]++
+	h.sum += secs
+	h.count++
+}
+
+// ObserveTransfer records that a call to method of the named source
+// returned items results of the given size in bytes; see ResultBytes.
+// Instrument calls it automatically for FetchTopics and FetchData.
+func (r *Registry) ObserveTransfer(source string, method datasource.Method, items int, bytes int64) {
+	k := seriesKey{source, method}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.items[k] += uint64(items)
+	r.bytes[k] += uint64(bytes)
+}
+
+// ObserveBudget records that the named source exceeded a budget. Meter
+// calls it automatically.
+func (r *Registry) ObserveBudget(source string, resource Resource, level Level) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.budgets[budgetKey{source, resource, level}]++
+}
+
+// WriteTo writes all metrics to w in the Prometheus text exposition format.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cw := &countingWriter{w: bufio.NewWriter(w)}
+	fmt.Fprintf(cw, "# HELP %s Number of data source calls.\n# TYPE %s counter\n", RequestsMetric, RequestsMetric)
+	for _, k := range sortedSeries(r.requests) {
+		fmt.Fprintf(cw, "%s{%s} %d\n", RequestsMetric, k.labels(), r.requests[k])
+	}
+
+	fmt.Fprintf(cw, "# HELP %s Number of failed data source calls by error class.\n# TYPE %s counter\n", ErrorsMetric, ErrorsMetric)
+	errKeys := make([]errorKey, 0, len(r.errors))
+	for k := range r.errors {
+		errKeys = append(errKeys, k)
+	}
+	sort.Slice(errKeys, func(i, j int) bool {
+		if errKeys[i].seriesKey != errKeys[j].seriesKey {
+			return errKeys[i].seriesKey.less(errKeys[j].seriesKey)
+		}
+		return errKeys[i].class < errKeys[j].class
+	})
+	for _, k := range errKeys {
+		fmt.Fprintf(cw, "%s{%s,class=%q} %d\n", ErrorsMetric, k.labels(), k.class, r.errors[k])
+	}
+
+	fmt.Fprintf(cw, "# HELP %s Latency of data source calls.\n# TYPE %s histogram\n", LatencyMetric, LatencyMetric)
+	for _, k := range sortedSeries(r.latency) {
+		h := r.latency[k]
+		var cumulative uint64
+		for i, n := range h.counts {
+			cumulative += n
+			le := "+Inf"
+			if i < len(r.buckets) {
+				le = strconv.FormatFloat(r.buckets[i], 'g', -1, 64)
+			}
+			fmt.Fprintf(cw, "%s_bucket{%s,le=%q} %d\n", LatencyMetric, k.labels(), le, cumulative)
+		}
+		fmt.Fprintf(cw, "%s_sum{%s} %s\n", LatencyMetric, k.labels(), strconv.FormatFloat(h.sum, 'g', -1, 64))
+		fmt.Fprintf(cw, "%s_count{%s} %d\n", LatencyMetric, k.labels(), h.count)
+	}
+
+	fmt.Fprintf(cw, "# HELP %s Number of topics and data items returned by data source calls.\n# TYPE %s counter\n", ItemsMetric, ItemsMetric)
+	for _, k := range sortedSeries(r.items) {
+		fmt.Fprintf(cw, "%s{%s} %d\n", ItemsMetric, k.labels(), r.items[k])
+	}
+
+	fmt.Fprintf(cw, "# HELP %s Size of the results of data source calls.\n# TYPE %s counter\n", BytesMetric, BytesMetric)
+	for _, k := range sortedSeries(r.bytes) {
+		fmt.Fprintf(cw, "%s{%s} %d\n", BytesMetric, k.labels(), r.bytes[k])
+	}
+
+	fmt.Fprintf(cw, "# HELP %s Number of times a data source exceeded a budget.\n# TYPE %s counter\n", BudgetMetric, BudgetMetric)
+	budgetKeys := make([]budgetKey, 0, len(r.budgets))
+	for k := range r.budgets {
+		budgetKeys = append(budgetKeys, k)
+	}
+	sort.Slice(budgetKeys, func(i, j int) bool {
+		a, b := budgetKeys[i], budgetKeys[j]
+		if a.source != b.source {
+			return a.source < b.source
+		}
+		if a.resource != b.resource {
+			return a.resource < b.resource
+		}
+		return a.level < b.level
+	})
+	for _, k := range budgetKeys {
+		fmt.Fprintf(cw, "%s{source=%s,resource=%q,level=%q} %d\n", BudgetMetric, quoteLabel(k.source), string(k.resource), string(k.level), r.budgets[k])
+	}
+
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// ServeHTTP implements http.Handler.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	r.WriteTo(w)
+}
+
+func (k seriesKey) labels() string {
+	return fmt.Sprintf("source=%s,method=%q", quoteLabel(k.source), string(k.method))
+}
+
+func (k seriesKey) less(o seriesKey) bool {
+	if k.source != o.source {
+		return k.source < o.source
+	}
+	return k.method < o.method
+}
+
+func sortedSeries[V any](m map[seriesKey]V) []seriesKey {
+	keys := make([]seriesKey, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].less(keys[j]) })
+	return keys
+}
+
+// quoteLabel quotes a label value using the escaping rules of the text
+// exposition format (backslash, double quote, and newline only).
+func quoteLabel(v string) string {
+	v = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+	return `"` + v + `"`
+}
+
+type countingWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	c.err = err
+	return n, err
+}
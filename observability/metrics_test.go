@@ -0,0 +1,88 @@
+package observability_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/observability"
+)
+
+type flakySource struct{ fail bool }
+
+func (s *flakySource) Init() error             { return nil }
+func (s *flakySource) CheckAvailability() bool { return !s.fail }
+func (s *flakySource) FetchTopics(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if s.fail {
+		return nil, &datasource.ErrRateLimited{}
+	}
+	return []datasource.DataSourceTopic{}, nil
+}
+func (s *flakySource) FetchData(int, int64) ([]datasource.DataSourceData, error) {
+	return nil, datasource.ErrNotFound
+}
+
+func TestInstrumentExposition(t *testing.T) {
+	reg := observability.NewRegistry()
+	src := &flakySource{}
+	ds := observability.Instrument(src, "wiki", reg)
+
+	ds.FetchTopics(5, datasource.NewQuestionInput{})
+	src.fail = true
+	ds.FetchTopics(5, datasource.NewQuestionInput{})
+	ds.FetchData(1, 1)
+	ds.CheckAvailability()
+
+	rec := httptest.NewRecorder()
+	reg.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		`locus_datasource_requests_total{source="wiki",method="FetchTopics"} 2`,
+		`locus_datasource_errors_total{source="wiki",method="FetchTopics",class="rate_limited"} 1`,
+		`locus_datasource_errors_total{source="wiki",method="FetchData",class="not_found"} 1`,
+		`locus_datasource_errors_total{source="wiki",method="CheckAvailability",class="unavailable"} 1`,
+		`locus_datasource_request_duration_seconds_bucket{source="wiki",method="FetchTopics",le="+Inf"} 2`,
+		`locus_datasource_request_duration_seconds_count{source="wiki",method="FetchData"} 1`,
+		"# TYPE locus_datasource_request_duration_seconds histogram",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("exposition missing %q\n%s", want, body)
+		}
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+}
+
+func TestHistogramBuckets(t *testing.T) {
+	reg := observability.NewRegistry()
+	reg.Observe("s", datasource.MethodFetchData, 30*time.Millisecond, nil)
+	reg.Observe("s", datasource.MethodFetchData, 3*time.Second, nil)
+
+	var b strings.Builder
+	reg.WriteTo(&b)
+	for _, want := range []string{
+		`le="0.025"} 0`,
+		`le="0.05"} 1`,
+		`le="2.5"} 1`,
+		`le="5"} 2`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("exposition missing bucket %q\n%s", want, b.String())
+		}
+	}
+}
+
+func TestLabelEscaping(t *testing.T) {
+	reg := observability.NewRegistry()
+	reg.Observe("a\"b\\c", datasource.MethodInit, 0, nil)
+
+	var b strings.Builder
+	reg.WriteTo(&b)
+	if !strings.Contains(b.String(), `source="a\"b\\c"`) {
+		t.Errorf("label value not escaped:\n%s", b.String())
+	}
+}
@@ -0,0 +1,220 @@
+package observability
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Capability names used in usage summaries: the JSON names of the
+// datasource.Capabilities fields whose use calls reveal.
+const (
+	CapabilityEmbeddings          = "embeddings"
+	CapabilityPagination          = "pagination"
+	CapabilityTagFiltering        = "tag_filtering"
+	CapabilityMultiSite           = "multi_site"
+	CapabilityResultEmbeddings    = "result_embeddings"
+	CapabilityPermissionSensitive = "permission_sensitive"
+)
+
+// PaginationThreshold is the number of results above which a call is
+// taken to have paged through its upstream: the smallest page of the
+// built-in sources that paginate.
+const PaginationThreshold = 50
+
+// usageCounts is the usage of one source since the period began.
+type usageCounts struct {
+	declared   datasource.Capabilities
+	calls      uint64
+	maxResults int
+	exercised  map[string]uint64
+}
+
+// CapabilityUsage is how one capability of a source was used.
+type CapabilityUsage struct {
+	// Declared means the source reports the capability
+	Declared bool `json:"declared"`
+
+	// Calls is the number of calls that exercised it
+	Calls uint64 `json:"calls"`
+}
+
+// SourceUsage summarizes which capabilities of a source its calls
+// exercised.
+type SourceUsage struct {
+	Source string `json:"source"`
+
+	// Calls is the number of FetchTopics and FetchData calls
+	Calls uint64 `json:"calls"`
+
+	// MaxResults is the most results one call returned
+	MaxResults int `json:"max_results"`
+
+	// Capabilities is keyed by capability name
+	Capabilities map[string]CapabilityUsage `json:"capabilities"`
+
+	// Unused names the capabilities the source declares that no call
+	// exercised, candidates for pruning
+	Unused []string `json:"unused,omitempty"`
+
+	// Undeclared names the capabilities calls exercised that the source
+	// does not declare, such as tags sent to a source that ignores them
+	Undeclared []string `json:"undeclared,omitempty"`
+}
+
+// UsageSummary is the capability usage of every source over a period.
+type UsageSummary struct {
+	Since   time.Time     `json:"since"`
+	Until   time.Time     `json:"until"`
+	Sources []SourceUsage `json:"sources"`
+}
+
+// ObserveUsage records one FetchTopics or FetchData call of the named
+// source, which declares caps, that returned results items and exercised
+// the named capabilities. Instrument calls it automatically.
+func (r *Registry) ObserveUsage(source string, caps datasource.Capabilities, results int, exercised ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u := r.usage[source]
+	if u == nil {
+		u = &usageCounts{exercised: make(map[string]uint64)}
+		r.usage[source] = u
+	}
+	u.declared = caps
+	u.calls++
+	u.maxResults = max(u.maxResults, results)
+	for _, c := range exercised {
+		u.exercised[c]++
+	}
+}
+
+// Usage summarizes capability usage since the registry was created or
+// ResetUsage was last called, by source name.
+func (r *Registry) Usage() UsageSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.usageSummary()
+}
+
+// ResetUsage starts a new usage period and returns the summary of the one
+// it ends.
+func (r *Registry) ResetUsage() UsageSummary {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.usageSummary()
+	r.usage = make(map[string]*usageCounts)
+	r.usageSince = s.Until
+	return s
+}
+
+// SummarizeUsage calls report with the usage of each period of the given
+// length, resetting usage after each, until stop is called.
+func (r *Registry) SummarizeUsage(interval time.Duration, report func(UsageSummary)) (stop func()) {
+	t := time.NewTicker(interval)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-t.C:
+				report(r.ResetUsage())
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.Stop()
+			close(done)
+		})
+	}
+}
+
+// usageSummary builds the summary. Callers hold r.mu.
+func (r *Registry) usageSummary() UsageSummary {
+	s := UsageSummary{Since: r.usageSince, Until: time.Now(), Sources: []SourceUsage{}}
+	for name, u := range r.usage {
+		su := SourceUsage{Source: name, Calls: u.calls, MaxResults: u.maxResults, Capabilities: make(map[string]CapabilityUsage)}
+		for c, declared := range declaredCapabilities(u.declared) {
+			cu := CapabilityUsage{Declared: declared, Calls: u.exercised[c]}
+			su.Capabilities[c] = cu
+			switch {
+			case cu.Declared && cu.Calls == 0:
+				su.Unused = append(su.Unused, c)
+			case !cu.Declared && cu.Calls > 0:
+				su.Undeclared = append(su.Undeclared, c)
+			}
+		}
+		sort.Strings(su.Unused)
+		sort.Strings(su.Undeclared)
+		s.Sources = append(s.Sources, su)
+	}
+	sort.Slice(s.Sources, func(i, j int) bool { return s.Sources[i].Source < s.Sources[j].Source })
+	return s
+}
+
+// declaredCapabilities maps the name of each observed capability to
+// whether caps declares it.
+func declaredCapabilities(caps datasource.Capabilities) map[string]bool {
+	return map[string]bool{
+		CapabilityEmbeddings:          caps.Embeddings,
+		CapabilityPagination:          caps.Pagination,
+		CapabilityTagFiltering:        caps.TagFiltering,
+		CapabilityMultiSite:           caps.MultiSite,
+		CapabilityResultEmbeddings:    caps.ResultEmbeddings,
+		CapabilityPermissionSensitive: caps.PermissionSensitive,
+	}
+}
+
+// topicsUsage returns the capabilities a FetchTopics call exercised.
+func topicsUsage(input datasource.NewQuestionInput, topics []datasource.DataSourceTopic) []string {
+	var used []string
+	if len(input.Embedding) > 0 || len(input.Embeddings) > 0 {
+		used = append(used, CapabilityEmbeddings)
+	}
+	if len(input.Tags) > 0 {
+		used = append(used, CapabilityTagFiltering)
+	}
+	if input.AskedBy != nil {
+		used = append(used, CapabilityPermissionSensitive)
+	}
+	sites := make(map[string]bool)
+	embedded := false
+	for _, t := range topics {
+		if t.Site != "" {
+			sites[t.Site] = true
+		}
+		embedded = embedded || len(t.Embedding) > 0
+	}
+	return append(used, resultsUsage(len(topics), len(sites), embedded)...)
+}
+
+// dataUsage returns the capabilities a FetchData call exercised.
+func dataUsage(data []datasource.DataSourceData) []string {
+	sites := make(map[string]bool)
+	embedded := false
+	for _, d := range data {
+		if d.Site != "" {
+			sites[d.Site] = true
+		}
+		embedded = embedded || len(d.Embedding) > 0
+	}
+	return resultsUsage(len(data), len(sites), embedded)
+}
+
+func resultsUsage(results, sites int, embedded bool) []string {
+	var used []string
+	if results > PaginationThreshold {
+		used = append(used, CapabilityPagination)
+	}
+	if sites > 1 {
+		used = append(used, CapabilityMultiSite)
+	}
+	if embedded {
+		used = append(used, CapabilityResultEmbeddings)
+	}
+	return used
+}
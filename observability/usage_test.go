@@ -0,0 +1,76 @@
+package observability_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/observability"
+)
+
+// capableSource declares capabilities and returns results from two sites.
+type capableSource struct{ *flakySource }
+
+func (capableSource) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{Embeddings: true, Pagination: true, MultiSite: true}
+}
+
+func (capableSource) FetchTopics(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	return []datasource.DataSourceTopic{{TopicID: 1, Site: "a"}, {TopicID: 2, Site: "b"}}, nil
+}
+
+func TestUsage(t *testing.T) {
+	reg := observability.NewRegistry()
+	ds := observability.Instrument(capableSource{&flakySource{}}, "se", reg)
+	ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "dns", Tags: []string{"dns"}})
+	ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "dns"})
+	ds.FetchData(5, 1) // fails, so is not counted
+
+	s := reg.Usage()
+	if len(s.Sources) != 1 || s.Since.IsZero() || s.Until.Before(s.Since) {
+		t.Fatalf("Usage = %+v", s)
+	}
+	u := s.Sources[0]
+	if u.Source != "se" || u.Calls != 2 || u.MaxResults != 2 {
+		t.Errorf("usage = %+v", u)
+	}
+	if got := u.Capabilities[observability.CapabilityMultiSite]; !got.Declared || got.Calls != 2 {
+		t.Errorf("multi_site = %+v, want declared and used twice", got)
+	}
+	if fmt.Sprint(u.Unused) != "[embeddings pagination]" {
+		t.Errorf("Unused = %v", u.Unused)
+	}
+	if fmt.Sprint(u.Undeclared) != "[tag_filtering]" {
+		t.Errorf("Undeclared = %v", u.Undeclared)
+	}
+
+	if ended := reg.ResetUsage(); len(ended.Sources) != 1 {
+		t.Errorf("ResetUsage returned %+v", ended)
+	}
+	if s := reg.Usage(); len(s.Sources) != 0 {
+		t.Errorf("Usage after reset = %+v", s)
+	}
+}
+
+func TestSummarizeUsage(t *testing.T) {
+	reg := observability.NewRegistry()
+	reg.ObserveUsage("kb", datasource.Capabilities{}, 1)
+	reports := make(chan observability.UsageSummary, 1)
+	stop := reg.SummarizeUsage(time.Millisecond, func(s observability.UsageSummary) {
+		select {
+		case reports <- s:
+		default:
+		}
+	})
+	defer stop()
+	select {
+	case s := <-reports:
+		if len(s.Sources) != 1 || s.Sources[0].Source != "kb" {
+			t.Errorf("first report = %+v", s)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no report")
+	}
+	stop()
+}
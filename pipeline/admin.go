@@ -0,0 +1,166 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/cache"
+	"github.com/locus-search/datasource-sdk/middleware"
+)
+
+// Admin endpoint paths served by AdminHandler.
+const (
+	PathMetrics = "/metrics"
+	PathHealth  = "/healthz"
+	PathReady   = "/readyz"
+	PathStats   = "/stats"
+
+	PathInvalidate = "/cache/invalidate"
+)
+
+// HealthCheck reports the health of every configured source as a
+// component, using datasource.CheckHealth. Sources that failed to
+// initialize are unhealthy. The pipeline is healthy if every source is,
+// unhealthy if none is usable, and degraded otherwise.
+func (p *Pipeline) HealthCheck() datasource.HealthStatus {
+	p.mu.RLock()
+	active, failed := p.active, p.failed
+	p.mu.RUnlock()
+
+	components := make(map[string]datasource.HealthStatus, len(p.names))
+	for _, name := range p.names {
+		switch {
+		case active[name] != nil:
+			components[name] = datasource.CheckHealth(active[name])
+		case failed[name] != nil:
+			components[name] = datasource.HealthStatus{State: datasource.Unhealthy, Error: failed[name].Error()}
+		default:
+			components[name] = datasource.HealthStatus{State: datasource.Unhealthy, Error: "not initialized"}
+		}
+	}
+	return datasource.CombineHealth(components)
+}
+
+// Ready reports whether the pipeline can serve traffic: it is initialized,
+// every required source (see Builder.WithRequired) is initialized and not
+// unhealthy, and at least one source is usable. It returns nil when ready
+// and otherwise an error wrapping ErrUnavailable that says why, so hosts
+// can hold traffic back while required components start.
+func (p *Pipeline) Ready() error {
+	p.mu.RLock()
+	active, failed, initialized := p.active, p.failed, p.router != nil
+	p.mu.RUnlock()
+
+	if !initialized {
+		return fmt.Errorf("pipeline: not initialized: %w", datasource.ErrUnavailable)
+	}
+	usable := false
+	for _, name := range p.deps.order {
+		ds := active[name]
+		healthy := ds != nil && datasource.CheckHealth(ds).State != datasource.Unhealthy
+		usable = usable || healthy
+		if !p.deps.required[name] || healthy {
+			continue
+		}
+		if err := failed[name]; err != nil {
+			return fmt.Errorf("pipeline: required source %q is not initialized: %w", name, errors.Join(err, datasource.ErrUnavailable))
+		}
+		return fmt.Errorf("pipeline: required source %q is unhealthy: %w", name, datasource.ErrUnavailable)
+	}
+	if !usable {
+		return fmt.Errorf("pipeline: no source is usable: %w", datasource.ErrUnavailable)
+	}
+	return nil
+}
+
+// Invalidate removes the entries sel selects from the cache of every
+// source with a cache middleware, returning how many were removed. Caches
+// shared by several sources are invalidated once. Entries are named after
+// their source, so Selector.Source is a pipeline source name.
+func (p *Pipeline) Invalidate(sel cache.Selector) int {
+	p.mu.RLock()
+	active := p.active
+	p.mu.RUnlock()
+
+	seen := make(map[*cache.Cache]bool)
+	n := 0
+	for _, name := range p.names {
+		store, ok := middleware.CacheStore(active[name])
+		if !ok || seen[store] {
+			continue
+		}
+		seen[store] = true
+		n += store.Invalidate(sel)
+	}
+	return n
+}
+
+// AdminHandler returns an http.Handler serving Prometheus metrics at
+// PathMetrics, the HealthCheck report at PathHealth (503 when the pipeline
+// is unhealthy), the Ready result at PathReady (503 until the pipeline is
+// ready), and the capability usage of each source
+// (observability.Registry.Usage) at PathStats. POSTing a JSON
+// cache.Selector to PathInvalidate calls Invalidate and responds with the
+// number of entries removed; an empty selector is refused unless the
+// request sets "all", so that a mistyped request cannot flush every cache.
+// Mount it on an internal listener, stripping any prefix.
+func (p *Pipeline) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle(PathMetrics, p.metrics)
+	mux.HandleFunc(PathHealth, func(w http.ResponseWriter, r *http.Request) {
+		h := datasource.CheckHealth(p)
+		status := http.StatusOK
+		if h.State == datasource.Unhealthy {
+			status = http.StatusServiceUnavailable
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(h)
+	})
+	mux.HandleFunc(PathReady, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Ready bool   `json:"ready"`
+			Error string `json:"error,omitempty"`
+		}
+		status := http.StatusOK
+		if err := p.Ready(); err != nil {
+			status, body.Error = http.StatusServiceUnavailable, err.Error()
+		} else {
+			body.Ready = true
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(body)
+	})
+	mux.HandleFunc(PathStats, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(p.metrics.Usage())
+	})
+	mux.HandleFunc(PathInvalidate, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			cache.Selector
+			All bool `json:"all"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid selector: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if req.Selector.IsZero() && !req.All {
+			http.Error(w, "empty selector; set \"all\" to invalidate every entry", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(struct {
+			Removed int `json:"removed"`
+		}{p.Invalidate(req.Selector)})
+	})
+	return mux
+}
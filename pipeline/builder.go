@@ -0,0 +1,339 @@
+// Package pipeline assembles the pieces of the SDK a host application
+// needs — a source registry, declarative configuration, per-source
+// instrumentation, a multiplexer that queries many sources as one, and
+// admin endpoints — behind a builder with sensible defaults:
+//
+//	p, err := pipeline.NewBuilder().
+//	    WithConfigFile("sources.json").
+//	    WithSource("handbook", handbook.New()).
+//	    WithLogger(slog.Default()).
+//	    Build()
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	if err := p.Init(); err != nil {
+//	    log.Print(err) // sources that failed are left out
+//	}
+//	http.Handle("/admin/", http.StripPrefix("/admin", p.AdminHandler()))
+//
+//	topics, err := p.FetchTopics(5, input)
+//
+// Every source records metrics (see package observability) and, when a
+// logger is set, structured request logs. Without WithRouting, every
+// question is sent to every source.
+//
+// Sources can depend on one another (Builder.WithDependency, or
+// depends_on in descriptors): Init initializes dependencies first and
+// leaves out sources whose dependencies failed. Pipeline.Ready, served at
+// PathReady, holds readiness back until required sources are up.
+//
+// Builder.Plan resolves the same configuration without building anything
+// and reports the effective settings and any problems, for checking
+// descriptors before deploying them (see also locus-ds plan).
+package pipeline
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/config"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/observability"
+	"github.com/locus-search/datasource-sdk/router"
+)
+
+// Builder configures a Pipeline. Its methods return the Builder so calls
+// can be chained; configuration errors are reported by Build.
+type Builder struct {
+	loader     config.Loader
+	files      []string
+	inline     [][]byte
+	sources    []namedSource
+	metrics    *observability.Registry
+	logger     *slog.Logger
+	hooks      []datasource.Hooks
+	classifier router.Classifier
+	routes     map[router.Intent][]string
+	affinity   *router.Affinity
+	embedder   datasource.EmbeddingProvider
+	deps       map[string][]string
+	required   []string
+	errs       []error
+}
+
+type namedSource struct {
+	name string
+	ds   datasource.DataSource
+}
+
+// NewBuilder returns a Builder with default settings.
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// WithConfigFile adds the sources described by the descriptor at path.
+func (b *Builder) WithConfigFile(path string) *Builder {
+	b.files = append(b.files, path)
+	return b
+}
+
+// WithConfig adds the sources described by an in-memory descriptor.
+func (b *Builder) WithConfig(descriptor []byte) *Builder {
+	b.inline = append(b.inline, descriptor)
+	return b
+}
+
+// WithSource adds a source constructed by the host under name.
+func (b *Builder) WithSource(name string, ds datasource.DataSource) *Builder {
+	if ds == nil {
+		b.errs = append(b.errs, fmt.Errorf("pipeline: source %q is nil", name))
+	}
+	b.sources = append(b.sources, namedSource{name, ds})
+	return b
+}
+
+// WithDependency makes the source name depend on the sources in deps: they
+// are initialized first, and name is left out of the pipeline if any of
+// them fails, as for a reranker that needs an embedding source.
+// Descriptors can declare dependencies with depends_on instead.
+func (b *Builder) WithDependency(name string, deps ...string) *Builder {
+	if b.deps == nil {
+		b.deps = make(map[string][]string)
+	}
+	b.deps[name] = append(b.deps[name], deps...)
+	return b
+}
+
+// WithRequired marks sources the pipeline cannot serve without: Ready
+// fails until they are initialized and healthy. Descriptors can set
+// required instead.
+func (b *Builder) WithRequired(names ...string) *Builder {
+	b.required = append(b.required, names...)
+	return b
+}
+
+// WithEmbeddingProvider hands p to every source that implements
+// datasource.EmbeddingReceiver, before it is initialized.
+func (b *Builder) WithEmbeddingProvider(p datasource.EmbeddingProvider) *Builder {
+	b.embedder = p
+	return b
+}
+
+// WithType makes a source type available to descriptors, replacing any
+// built-in type of the same name.
+func (b *Builder) WithType(name string, ctor config.Constructor) *Builder {
+	if b.loader.Types == nil {
+		b.loader.Types = make(map[string]config.Constructor)
+	}
+	b.loader.Types[name] = ctor
+	return b
+}
+
+// WithUnmarshal sets the decoder for descriptors that are not JSON; see
+// config.Loader.Unmarshal.
+func (b *Builder) WithUnmarshal(unmarshal func(data []byte, v any) error) *Builder {
+	b.loader.Unmarshal = unmarshal
+	return b
+}
+
+// WithTimeouts bounds Init, fetches, and health checks of every source
+// separately (see middleware.Timeout). Descriptors can override them per
+// source with a timeouts object.
+func (b *Builder) WithTimeouts(t middleware.Timeouts) *Builder {
+	b.loader.Timeouts = t
+	return b
+}
+
+// WithMetrics records metrics in reg. By default the Pipeline creates its
+// own registry, served by AdminHandler.
+func (b *Builder) WithMetrics(reg *observability.Registry) *Builder {
+	b.metrics = reg
+	return b
+}
+
+// WithLogger logs every source request to logger using
+// datasource.SlogHooks.
+func (b *Builder) WithLogger(logger *slog.Logger) *Builder {
+	b.logger = logger
+	return b
+}
+
+// WithHooks fires hooks around every source request, and with a
+// datasource.QueryReport after every question the pipeline answers. Hooks
+// added by several calls, and the logger's, all fire.
+func (b *Builder) WithHooks(hooks datasource.Hooks) *Builder {
+	b.hooks = append(b.hooks, hooks)
+	return b
+}
+
+// WithRouting sends each question only to the sources named for its
+// intent, as classified by c. Questions whose intent has no route are sent
+// to every source.
+func (b *Builder) WithRouting(c router.Classifier, routes map[router.Intent][]string) *Builder {
+	if c == nil {
+		b.errs = append(b.errs, errors.New("pipeline: nil classifier"))
+	}
+	b.classifier, b.routes = c, routes
+	return b
+}
+
+// WithAffinity favors, within each session (NewQuestionInput.SessionID),
+// the sources and topics that were useful earlier in it; see
+// router.Affinity.
+func (b *Builder) WithAffinity(a *router.Affinity) *Builder {
+	b.affinity = a
+	return b
+}
+
+// Build checks the configuration, reads any descriptors, and returns a
+// Pipeline. Sources are not constructed until Pipeline.Init.
+func (b *Builder) Build() (*Pipeline, error) {
+	if err := errors.Join(b.errs...); err != nil {
+		return nil, err
+	}
+	p := &Pipeline{
+		registry:   datasource.NewRegistry(),
+		metrics:    b.metrics,
+		classifier: b.classifier,
+		routes:     b.routes,
+		affinity:   b.affinity,
+		embedder:   b.embedder,
+		wrap:       make(map[string]func(datasource.DataSource) (datasource.DataSource, error)),
+	}
+	if p.metrics == nil {
+		p.metrics = observability.NewRegistry()
+	}
+	hooks := b.hooks
+	if b.logger != nil {
+		hooks = append([]datasource.Hooks{datasource.SlogHooks(b.logger)}, hooks...)
+	}
+	if len(hooks) > 0 {
+		combined := datasource.CombineHooks(hooks...)
+		p.hooks = &combined
+	}
+	if p.classifier == nil {
+		p.classifier = router.ClassifierFunc(func(datasource.NewQuestionInput) router.Intent {
+			return router.IntentUnknown
+		})
+	}
+
+	sources, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	if p.deps, err = b.dependencies(sources); err != nil {
+		return nil, err
+	}
+	loader := b.loader
+	for _, cs := range sources {
+		s := cs.source
+		if err := p.register(s.Name, func() (datasource.DataSource, error) { return loader.Build(s) }); err != nil {
+			return nil, err
+		}
+		p.wrap[s.Name] = func(ds datasource.DataSource) (datasource.DataSource, error) { return loader.Decorate(s, ds) }
+	}
+	timeouts := b.loader.Timeouts
+	bound := func(ds datasource.DataSource) (datasource.DataSource, error) {
+		if timeouts.IsZero() {
+			return ds, nil
+		}
+		return middleware.Timeout(ds, timeouts), nil
+	}
+	for _, s := range b.sources {
+		ds, _ := bound(s.ds)
+		if err := p.register(s.name, func() (datasource.DataSource, error) { return ds, nil }); err != nil {
+			return nil, err
+		}
+		p.wrap[s.name] = bound
+	}
+	p.names = p.registry.List()
+	return p, nil
+}
+
+// configSource is a source read from a descriptor.
+type configSource struct {
+	origin string // the descriptor's path, or "inline"
+	source config.Source
+}
+
+// load reads every descriptor and checks that source names are unique and
+// that routes name known sources.
+func (b *Builder) load() ([]configSource, error) {
+	var sources []configSource
+	add := func(origin string, f *config.File) {
+		for _, s := range f.Sources {
+			sources = append(sources, configSource{origin, s})
+		}
+	}
+	for _, path := range b.files {
+		f, err := b.loader.LoadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		add(path, f)
+	}
+	for _, d := range b.inline {
+		f, err := b.loader.Parse(d)
+		if err != nil {
+			return nil, err
+		}
+		add("inline", f)
+	}
+
+	known := make(map[string]bool)
+	names := make([]string, 0, len(sources)+len(b.sources))
+	for _, cs := range sources {
+		names = append(names, cs.source.Name)
+	}
+	for _, s := range b.sources {
+		names = append(names, s.name)
+	}
+	for _, name := range names {
+		if known[name] {
+			return nil, fmt.Errorf("pipeline: source %q: already registered", name)
+		}
+		known[name] = true
+	}
+	if len(known) == 0 {
+		return nil, errors.New("pipeline: no sources configured")
+	}
+	for intent, list := range b.routes {
+		for _, name := range list {
+			if !known[name] {
+				return nil, fmt.Errorf("pipeline: route %q: unknown source %q", intent, name)
+			}
+		}
+	}
+	return sources, nil
+}
+
+// register adds a source to the registry, decorated so that Init is
+// observed too.
+func (p *Pipeline) register(name string, build datasource.Factory) error {
+	err := p.registry.Register(name, func() (datasource.DataSource, error) {
+		ds, err := build()
+		if err != nil {
+			return nil, err
+		}
+		return p.decorate(name, ds), nil
+	})
+	if err != nil {
+		return fmt.Errorf("pipeline: %w", err)
+	}
+	return nil
+}
+
+// decorate hands ds the embedding provider and wraps it in the pipeline's
+// hooks and instrumentation, and in middleware.Drain so that Shutdown and
+// Swap can wait for its calls.
+func (p *Pipeline) decorate(name string, ds datasource.DataSource) datasource.DataSource {
+	if p.embedder != nil {
+		datasource.ProvideEmbeddings(ds, p.embedder)
+	}
+	if p.hooks != nil {
+		ds = middleware.WithHooks(ds, name, *p.hooks)
+	}
+	return middleware.Drain(observability.Instrument(ds, name, p.metrics))
+}
@@ -0,0 +1,103 @@
+package pipeline
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// dependencies is the dependency graph of a pipeline's sources.
+type dependencies struct {
+	deps     map[string][]string // sorted, without duplicates
+	required map[string]bool
+	order    []string // initialization order
+}
+
+// dependencies collects the dependencies and required sources declared by
+// descriptors and the Builder, checks that they name known sources and
+// have no cycles, and orders the sources so that each comes after its
+// dependencies, breaking ties by name.
+func (b *Builder) dependencies(sources []configSource) (*dependencies, error) {
+	d := &dependencies{deps: make(map[string][]string), required: make(map[string]bool)}
+	known := make(map[string]bool)
+	add := func(name string, deps []string) {
+		d.deps[name] = append(d.deps[name], deps...)
+	}
+	for _, cs := range sources {
+		known[cs.source.Name] = true
+		add(cs.source.Name, cs.source.DependsOn)
+		if cs.source.Required {
+			d.required[cs.source.Name] = true
+		}
+	}
+	for _, s := range b.sources {
+		known[s.name] = true
+	}
+	for name, deps := range b.deps {
+		add(name, deps)
+	}
+	for _, name := range b.required {
+		if !known[name] {
+			return nil, fmt.Errorf("pipeline: required source %q is unknown", name)
+		}
+		d.required[name] = true
+	}
+	for name, deps := range d.deps {
+		if !known[name] {
+			return nil, fmt.Errorf("pipeline: dependencies of unknown source %q", name)
+		}
+		sort.Strings(deps)
+		uniq := deps[:0]
+		for i, dep := range deps {
+			if !known[dep] {
+				return nil, fmt.Errorf("pipeline: source %q: unknown dependency %q", name, dep)
+			}
+			if dep == name {
+				return nil, fmt.Errorf("pipeline: source %q depends on itself", name)
+			}
+			if i == 0 || dep != deps[i-1] {
+				uniq = append(uniq, dep)
+			}
+		}
+		d.deps[name] = uniq
+	}
+
+	names := make([]string, 0, len(known))
+	for name := range known {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	done := make(map[string]bool, len(names))
+	for len(d.order) < len(names) {
+		progress := false
+		for _, name := range names {
+			if done[name] || !d.ready(name, done) {
+				continue
+			}
+			d.order = append(d.order, name)
+			done[name] = true
+			progress = true
+			break
+		}
+		if !progress {
+			var cycle []string
+			for _, name := range names {
+				if !done[name] {
+					cycle = append(cycle, name)
+				}
+			}
+			return nil, fmt.Errorf("pipeline: dependency cycle among %s", strings.Join(cycle, ", "))
+		}
+	}
+	return d, nil
+}
+
+// ready reports whether every dependency of name is done.
+func (d *dependencies) ready(name string, done map[string]bool) bool {
+	for _, dep := range d.deps[name] {
+		if !done[dep] {
+			return false
+		}
+	}
+	return true
+}
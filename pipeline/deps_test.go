@@ -0,0 +1,145 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/pipeline"
+)
+
+// initRecorder records the order sources are initialized in.
+type initRecorder struct {
+	datasourcetest.Fake
+	name  string
+	order *[]string
+}
+
+func (r *initRecorder) Init() error {
+	*r.order = append(*r.order, r.name)
+	return r.Fake.Init()
+}
+
+func TestPipelineInitsDependenciesFirst(t *testing.T) {
+	var order []string
+	src := func(name string) *initRecorder { return &initRecorder{name: name, order: &order} }
+	embedder, reranker, web := src("embedder"), src("reranker"), src("web")
+	embedder.FailNext(datasource.MethodInit, errors.New("model not loaded"))
+	p, err := pipeline.NewBuilder().
+		WithSource("reranker", reranker).
+		WithSource("embedder", embedder).
+		WithSource("web", web).
+		WithDependency("reranker", "embedder").
+		WithRequired("reranker").
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := p.AdminHandler()
+	readyz := func() int {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pipeline.PathReady, nil))
+		return rec.Code
+	}
+	if err := p.Ready(); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("Ready before Init = %v, want ErrUnavailable", err)
+	}
+
+	err = p.Init()
+	if err == nil || !strings.Contains(err.Error(), `dependency "embedder" is not initialized`) {
+		t.Errorf("Init = %v, want the reranker left out", err)
+	}
+	if strings.Join(order, ",") != "embedder,web" {
+		t.Errorf("initialized %v, want embedder then web only", order)
+	}
+	if p.Source("reranker") != nil || p.Source("web") == nil {
+		t.Error("reranker should be left out and web usable")
+	}
+	if err := p.Ready(); err == nil || !strings.Contains(err.Error(), `required source "reranker"`) {
+		t.Errorf("Ready = %v, want the required reranker missing", err)
+	}
+	if code := readyz(); code != http.StatusServiceUnavailable {
+		t.Errorf("%s = %d, want 503", pipeline.PathReady, code)
+	}
+
+	order = nil
+	if err := p.Init(); err != nil {
+		t.Fatalf("second Init = %v", err)
+	}
+	if strings.Join(order, ",") != "embedder,reranker" {
+		t.Errorf("initialized %v, want embedder then reranker", order)
+	}
+	if err := p.Ready(); err != nil {
+		t.Errorf("Ready = %v", err)
+	}
+	if code := readyz(); code != http.StatusOK {
+		t.Errorf("%s = %d, want 200", pipeline.PathReady, code)
+	}
+}
+
+func TestPipelineReadyNeedsUsableSource(t *testing.T) {
+	src := &datasourcetest.Fake{Errors: map[datasource.Method]error{datasource.MethodCheckAvailability: datasource.ErrUnavailable}}
+	p, err := pipeline.NewBuilder().WithSource("a", src).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Init()
+	if err := p.Ready(); err == nil || !strings.Contains(err.Error(), "no source is usable") {
+		t.Errorf("Ready = %v, want no usable source", err)
+	}
+}
+
+func TestBuildChecksDependencies(t *testing.T) {
+	tests := []struct {
+		name string
+		b    *pipeline.Builder
+		want string
+	}{
+		{"unknown dependency", pipeline.NewBuilder().WithSource("a", &datasourcetest.Fake{}).WithDependency("a", "b"),
+			`source "a": unknown dependency "b"`},
+		{"unknown dependent", pipeline.NewBuilder().WithSource("a", &datasourcetest.Fake{}).WithDependency("b", "a"),
+			`dependencies of unknown source "b"`},
+		{"self", pipeline.NewBuilder().WithSource("a", &datasourcetest.Fake{}).WithDependency("a", "a"),
+			`source "a" depends on itself`},
+		{"cycle", pipeline.NewBuilder().
+			WithConfig([]byte(`{"sources": [{"name": "a", "type": "remote", "depends_on": ["b"]}]}`)).
+			WithSource("b", &datasourcetest.Fake{}).WithSource("c", &datasourcetest.Fake{}).
+			WithDependency("b", "a"),
+			"dependency cycle among a, b"},
+		{"unknown required", pipeline.NewBuilder().WithSource("a", &datasourcetest.Fake{}).WithRequired("z"),
+			`required source "z" is unknown`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.b.Build(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Build error %v does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanShowsDependencies(t *testing.T) {
+	plan, err := pipeline.NewBuilder().
+		WithConfig([]byte(`{"sources": [{"name": "rerank", "type": "remote", "settings": {"url": "https://r"},
+			"depends_on": ["embed"], "required": true}]}`)).
+		WithSource("embed", &datasourcetest.Fake{}).
+		Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(plan.InitOrder, ",") != "embed,rerank" {
+		t.Errorf("InitOrder = %v", plan.InitOrder)
+	}
+	var text bytes.Buffer
+	plan.WriteText(&text)
+	for _, want := range []string{"init order: embed, rerank", "depends on:  embed", "required:    yes"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text plan missing %q:\n%s", want, text.String())
+		}
+	}
+}
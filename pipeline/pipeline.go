@@ -0,0 +1,270 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/observability"
+	"github.com/locus-search/datasource-sdk/router"
+)
+
+// Pipeline is a DataSource that multiplexes every configured source. It is
+// created by Builder.Build and is safe for concurrent use.
+type Pipeline struct {
+	registry   *datasource.Registry
+	names      []string
+	metrics    *observability.Registry
+	hooks      *datasource.Hooks // nil without a logger or WithHooks
+	classifier router.Classifier
+	routes     map[router.Intent][]string
+	affinity   *router.Affinity // nil without WithAffinity
+	deps       *dependencies
+	embedder   datasource.EmbeddingProvider
+
+	// wrap applies the timeouts and middleware of each source, by name,
+	// to the source Swap installs in its place
+	wrap map[string]func(datasource.DataSource) (datasource.DataSource, error)
+
+	mu       sync.RWMutex
+	active   map[string]datasource.DataSource
+	failed   map[string]error
+	router   *router.Router
+	replaced map[string]datasource.DataSource // installed by Swap
+
+	swapMu sync.Mutex // serializes Swap
+}
+
+// Init constructs and initializes every source, each after the sources it
+// depends on (see Builder.WithDependency). Sources that fail, or whose
+// dependencies failed, are left out of the pipeline and their errors are
+// returned joined; calling Init again retries them. Init fails with
+// ErrUnavailable only if no source is usable.
+func (p *Pipeline) Init() error {
+	active := make(map[string]datasource.DataSource, len(p.names))
+	failed := make(map[string]error)
+	p.mu.RLock()
+	replaced := p.replaced
+	p.mu.RUnlock()
+	var errs []error
+	for _, name := range p.deps.order {
+		if dep := p.missingDependency(name, active); dep != "" {
+			err := fmt.Errorf("pipeline: source %q: dependency %q is not initialized: %w", name, dep, datasource.ErrUnavailable)
+			failed[name] = err
+			errs = append(errs, err)
+			continue
+		}
+		if ds := replaced[name]; ds != nil {
+			active[name] = ds
+			continue
+		}
+		ds, err := p.registry.Get(name)
+		if err != nil {
+			failed[name] = err
+			errs = append(errs, err)
+			continue
+		}
+		active[name] = ds
+	}
+
+	p.mu.Lock()
+	p.install(active, failed)
+	p.mu.Unlock()
+
+	if len(active) == 0 {
+		return fmt.Errorf("pipeline: no sources initialized: %w", errors.Join(append(errs, datasource.ErrUnavailable)...))
+	}
+	return errors.Join(errs...)
+}
+
+// install makes active the sources the pipeline routes to. It must be
+// called with p.mu held.
+func (p *Pipeline) install(active map[string]datasource.DataSource, failed map[string]error) {
+	all := make([]datasource.DataSource, 0, len(active))
+	for _, name := range p.names {
+		if ds, ok := active[name]; ok {
+			all = append(all, ds)
+		}
+	}
+	routes := make(map[router.Intent][]datasource.DataSource, len(p.routes))
+	for intent, names := range p.routes {
+		for _, name := range names {
+			if ds, ok := active[name]; ok {
+				routes[intent] = append(routes[intent], ds)
+			}
+		}
+	}
+	names := make(map[datasource.DataSource]string, len(active))
+	for name, ds := range active {
+		names[ds] = name
+	}
+	p.active, p.failed = active, failed
+	r := router.New(p.classifier, routes, all...).Named(names).WithAffinity(p.affinity)
+	if p.router != nil {
+		// Keep the owners of the topics already returned, which are named,
+		// so FetchData for them reaches the sources now installed.
+		r = r.WithOwners(p.router)
+	}
+	p.router = r
+}
+
+// missingDependency returns the first dependency of name that is not
+// active, or "".
+func (p *Pipeline) missingDependency(name string, active map[string]datasource.DataSource) string {
+	for _, dep := range p.deps.deps[name] {
+		if active[dep] == nil {
+			return dep
+		}
+	}
+	return ""
+}
+
+// multiplexer returns the router over the active sources, or an error if
+// none are usable.
+func (p *Pipeline) multiplexer() (*router.Router, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.router == nil {
+		return nil, fmt.Errorf("pipeline: not initialized: %w", datasource.ErrUnavailable)
+	}
+	if len(p.active) == 0 {
+		return nil, fmt.Errorf("pipeline: no sources initialized: %w", datasource.ErrUnavailable)
+	}
+	return p.router, nil
+}
+
+// CheckAvailability reports whether at least one source is available.
+func (p *Pipeline) CheckAvailability() bool {
+	r, err := p.multiplexer()
+	return err == nil && r.CheckAvailability()
+}
+
+// FetchTopics queries the sources routed for the question and interleaves
+// their results; see router.Router.FetchTopics.
+func (p *Pipeline) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	topics, _, err := p.FetchTopicsReport(count, input)
+	return topics, err
+}
+
+// FetchTopicsReport is FetchTopics, also returning a report of what each
+// source contributed, by name, for explaining an answer. The report is
+// also passed to the pipeline's hooks (see Builder.WithHooks); it is empty
+// if the pipeline has no usable source.
+func (p *Pipeline) FetchTopicsReport(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, datasource.QueryReport, error) {
+	r, err := p.multiplexer()
+	if err != nil {
+		return nil, datasource.QueryReport{Query: input.QuestionText, Start: time.Now(), Count: count}, err
+	}
+	topics, report, err := r.FetchTopicsReport(count, input)
+	if p.hooks != nil {
+		p.hooks.QueryReport(context.Background(), report)
+	}
+	return topics, report, err
+}
+
+// EstimateMatches estimates how many topics of the sources routed for the
+// question match it in all; see router.Router.EstimateMatches.
+func (p *Pipeline) EstimateMatches(ctx context.Context, input datasource.NewQuestionInput) (int64, error) {
+	r, err := p.multiplexer()
+	if err != nil {
+		return 0, err
+	}
+	return r.EstimateMatches(ctx, input)
+}
+
+// FetchData fetches data from the source that returned topicID.
+func (p *Pipeline) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	r, err := p.multiplexer()
+	if err != nil {
+		return nil, err
+	}
+	return r.FetchData(count, topicID)
+}
+
+// Only returns a view of the pipeline that queries only the named sources,
+// for scoping a single request, such as a user choosing to search internal
+// documents only:
+//
+//	ds, err := p.Only("handbook", "wiki")
+//	topics, err := ds.FetchTopics(5, input)
+//
+// Excluded sources are not called, so they spend no quota and record no
+// metrics for the request. Routing still applies within the allowed
+// sources. Unknown names are an error wrapping ErrNotFound; sources that
+// failed to initialize are skipped, and a view with no usable source fails
+// its calls with ErrUnavailable.
+func (p *Pipeline) Only(names ...string) (datasource.DataSource, error) {
+	r, err := p.multiplexer()
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]bool, len(p.names))
+	for _, name := range p.names {
+		known[name] = true
+	}
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	var allowed []datasource.DataSource
+	for _, name := range names {
+		if !known[name] {
+			return nil, fmt.Errorf("pipeline: source %q: %w", name, datasource.ErrNotFound)
+		}
+		if ds, ok := p.active[name]; ok {
+			allowed = append(allowed, ds)
+		}
+	}
+	if len(allowed) == 0 {
+		return unavailable{}, nil
+	}
+	return view{r.Only(allowed...)}, nil
+}
+
+// view is a scoped router whose sources the pipeline has already
+// initialized.
+type view struct{ *router.Router }
+
+func (view) Init() error { return nil }
+
+// unavailable is a view with no usable sources.
+type unavailable struct{}
+
+func (unavailable) Init() error             { return nil }
+func (unavailable) CheckAvailability() bool { return false }
+
+func (unavailable) FetchTopics(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	return nil, fmt.Errorf("pipeline: no selected source is initialized: %w", datasource.ErrUnavailable)
+}
+
+func (unavailable) FetchData(int, int64) ([]datasource.DataSourceData, error) {
+	return nil, fmt.Errorf("pipeline: no selected source is initialized: %w", datasource.ErrUnavailable)
+}
+
+// Names returns the names of all configured sources in sorted order,
+// including any that failed to initialize.
+func (p *Pipeline) Names() []string {
+	return append([]string(nil), p.names...)
+}
+
+// Source returns the initialized source registered under name, decorated
+// with the pipeline's instrumentation and draining, or nil if it is
+// unknown or failed to initialize.
+func (p *Pipeline) Source(name string) datasource.DataSource {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.active[name]
+}
+
+// Metrics returns the registry the pipeline records metrics in.
+func (p *Pipeline) Metrics() *observability.Registry {
+	return p.metrics
+}
+
+// Close shuts down the pipeline as Shutdown does, without the report.
+// Calls made after Close fail with ErrUnavailable.
+func (p *Pipeline) Close(ctx context.Context) error {
+	_, err := p.Shutdown(ctx)
+	return err
+}
@@ -0,0 +1,310 @@
+package pipeline_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/cache"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/observability"
+	"github.com/locus-search/datasource-sdk/pipeline"
+	"github.com/locus-search/datasource-sdk/router"
+)
+
+func TestPipelineMultiplexesSources(t *testing.T) {
+	wiki := &datasourcetest.Fake{
+		Topics: []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 1}},
+		Data:   map[int64][]datasource.DataSourceData{1: {{DataText: "Domain Name System", AnswerID: 10}}},
+	}
+	kb := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "Resolvers", TopicID: 2}}}
+	p, err := pipeline.NewBuilder().
+		WithType("kb", func(json.RawMessage, string) (datasource.DataSource, error) { return kb, nil }).
+		WithConfig([]byte(`{"sources": [{"name": "kb", "type": "kb"}]}`)).
+		WithSource("wiki", wiki).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.FetchTopics(5, datasource.NewQuestionInput{}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchTopics before Init = %v, want ErrUnavailable", err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if got := p.Names(); strings.Join(got, ",") != "kb,wiki" {
+		t.Errorf("Names = %v", got)
+	}
+
+	topics, err := p.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "dns"})
+	if err != nil || len(topics) != 2 {
+		t.Fatalf("FetchTopics = %v, %v; want a topic from each source", topics, err)
+	}
+	data, err := p.FetchData(5, 1)
+	if err != nil || len(data) != 1 || len(kb.CallsTo(datasource.MethodFetchData)) != 0 {
+		t.Errorf("FetchData = %v, %v; want it sent to the owning source only", data, err)
+	}
+
+	var metrics strings.Builder
+	p.Metrics().WriteTo(&metrics)
+	if !strings.Contains(metrics.String(), `source="wiki"`) {
+		t.Errorf("sources not instrumented:\n%s", metrics.String())
+	}
+
+	rec := httptest.NewRecorder()
+	p.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pipeline.PathStats, nil))
+	var stats observability.UsageSummary
+	if err := json.NewDecoder(rec.Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats.Sources) != 2 || stats.Sources[1].Source != "wiki" || stats.Sources[1].Calls != 2 {
+		t.Errorf("stats = %+v, want the calls of both sources", stats)
+	}
+}
+
+func TestPipelineRouting(t *testing.T) {
+	policy := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "Leave", TopicID: 1}}}
+	wiki := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 2}}}
+	p, err := pipeline.NewBuilder().
+		WithSource("policy", policy).
+		WithSource("wiki", wiki).
+		WithRouting(router.KeywordClassifier{}, map[router.Intent][]string{router.IntentPolicy: {"policy"}}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Init()
+
+	topics, err := p.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "what is the leave policy"})
+	if err != nil || len(topics) != 1 || len(wiki.CallsTo(datasource.MethodFetchTopics)) != 0 {
+		t.Errorf("FetchTopics = %v, %v; want only the policy source queried", topics, err)
+	}
+}
+
+func TestPipelineSkipsFailedSources(t *testing.T) {
+	broken := &datasourcetest.Fake{Errors: map[datasource.Method]error{datasource.MethodInit: datasource.ErrUnauthorized}}
+	ok := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 1}}}
+	p, err := pipeline.NewBuilder().WithSource("broken", broken).WithSource("ok", ok).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); !errors.Is(err, datasource.ErrUnauthorized) || errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("Init = %v, want the broken source's error only", err)
+	}
+	if p.Source("broken") != nil || p.Source("ok") == nil {
+		t.Error("Source should return initialized sources only")
+	}
+	if topics, err := p.FetchTopics(5, datasource.NewQuestionInput{}); err != nil || len(topics) != 1 {
+		t.Errorf("FetchTopics = %v, %v", topics, err)
+	}
+
+	rec := httptest.NewRecorder()
+	p.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pipeline.PathHealth, nil))
+	var h datasource.HealthStatus
+	if err := json.NewDecoder(rec.Body).Decode(&h); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK || h.State != datasource.Degraded ||
+		h.Components["broken"].Error == "" || h.Components["ok"].State != datasource.Healthy {
+		t.Errorf("health %d %+v", rec.Code, h)
+	}
+}
+
+func TestPipelineAllSourcesFailed(t *testing.T) {
+	broken := &datasourcetest.Fake{Errors: map[datasource.Method]error{datasource.MethodInit: datasource.ErrUnauthorized}}
+	p, err := pipeline.NewBuilder().WithSource("broken", broken).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("Init = %v, want ErrUnavailable", err)
+	}
+	rec := httptest.NewRecorder()
+	p.AdminHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, pipeline.PathHealth, nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("health status %d, want 503", rec.Code)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	fake := &datasourcetest.Fake{}
+	tests := []struct {
+		name string
+		b    *pipeline.Builder
+		want string
+	}{
+		{"no sources", pipeline.NewBuilder(), "no sources configured"},
+		{"duplicate", pipeline.NewBuilder().WithSource("a", fake).WithSource("a", fake), "already registered"},
+		{"nil source", pipeline.NewBuilder().WithSource("a", nil), "is nil"},
+		{"unknown route", pipeline.NewBuilder().WithSource("a", fake).
+			WithRouting(router.KeywordClassifier{}, map[router.Intent][]string{router.IntentPolicy: {"b"}}), `unknown source "b"`},
+		{"bad descriptor", pipeline.NewBuilder().WithConfig([]byte(`{"sources": [{"name": "a"}]}`)), "missing type"},
+		{"missing file", pipeline.NewBuilder().WithConfigFile("/nonexistent.json"), "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.b.Build()
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Build error %v does not mention %q", err, tt.want)
+			}
+		})
+	}
+}
+
+type closableFake struct {
+	datasourcetest.Fake
+	closed bool
+}
+
+func (f *closableFake) Close(context.Context) error {
+	f.closed = true
+	return nil
+}
+
+func TestPipelineClose(t *testing.T) {
+	src := &closableFake{}
+	p, err := pipeline.NewBuilder().WithSource("a", src).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Init()
+	if err := p.Close(context.Background()); err != nil || !src.closed {
+		t.Errorf("Close = %v, closed = %v", err, src.closed)
+	}
+	if _, err := p.FetchTopics(1, datasource.NewQuestionInput{}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchTopics after Close = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestPipelineOnly(t *testing.T) {
+	internal := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "Handbook", TopicID: 1}}}
+	web := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "Blog", TopicID: 2}}}
+	p, err := pipeline.NewBuilder().WithSource("internal", internal).WithSource("web", web).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Init()
+
+	ds, err := p.Only("internal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "leave"})
+	if err != nil || len(topics) != 1 || topics[0].Topic != "Handbook" || len(web.Calls()) != 1 {
+		t.Errorf("scoped FetchTopics = %v, %v; web calls %v", topics, err, web.Calls())
+	}
+
+	var metrics strings.Builder
+	p.Metrics().WriteTo(&metrics)
+	if strings.Contains(metrics.String(), `source="web",method="FetchTopics"`) {
+		t.Errorf("excluded source recorded metrics:\n%s", metrics.String())
+	}
+
+	if _, err := p.Only("intranet"); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("Only(unknown) = %v, want ErrNotFound", err)
+	}
+}
+
+type embeddingFake struct {
+	datasourcetest.Fake
+	provider datasource.EmbeddingProvider
+}
+
+func (f *embeddingFake) UseEmbeddingProvider(p datasource.EmbeddingProvider) { f.provider = p }
+
+func (f *embeddingFake) Init() error {
+	if f.provider == nil {
+		return errors.New("no embedding provider")
+	}
+	return nil
+}
+
+func TestPipelineProvidesEmbeddings(t *testing.T) {
+	src := &embeddingFake{}
+	provider := datasource.EmbeddingProviderFunc(func(_ context.Context, texts []string) ([][]float64, error) {
+		return make([][]float64, len(texts)), nil
+	})
+	p, err := pipeline.NewBuilder().WithSource("a", src).WithEmbeddingProvider(provider).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); err != nil {
+		t.Errorf("Init = %v, want the provider handed over first", err)
+	}
+}
+
+func TestPipelineReportsQueries(t *testing.T) {
+	var reports []datasource.QueryReport
+	p, err := pipeline.NewBuilder().
+		WithSource("kb", &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 1}}}).
+		WithSource("wiki", &datasourcetest.Fake{Errors: map[datasource.Method]error{datasource.MethodFetchTopics: datasource.ErrUnavailable}}).
+		WithHooks(datasource.Hooks{OnQueryReport: func(_ context.Context, r datasource.QueryReport) { reports = append(reports, r) }}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	_, report, err := p.FetchTopicsReport(5, datasource.NewQuestionInput{QuestionText: "dns"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].Returned != 1 {
+		t.Fatalf("hooks received %+v, want the report", reports)
+	}
+	var got []string
+	for _, s := range report.Sources {
+		got = append(got, fmt.Sprintf("%s:%d:%s", s.Source, s.Kept, s.ErrorClass))
+	}
+	if strings.Join(got, ",") != "kb:1:,wiki:0:unavailable" {
+		t.Errorf("sources = %v", got)
+	}
+}
+
+func TestPipelineInvalidate(t *testing.T) {
+	store := cache.New(cache.Config{})
+	wiki := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "VPN", SourceURL: "https://wiki.example.com/vpn", TopicID: 1}}}
+	forum := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "VPN", SourceURL: "https://forum.example.com/t/1", TopicID: 1}}}
+	p, err := pipeline.NewBuilder().
+		WithSource("wiki", middleware.Cache(wiki, middleware.CacheConfig{Name: "wiki", Store: store})).
+		WithSource("forum", middleware.Cache(forum, middleware.CacheConfig{Name: "forum", Store: store})).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+	p.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "vpn"})
+
+	admin := p.AdminHandler()
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		admin.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, pipeline.PathInvalidate, strings.NewReader(body)))
+		return rec
+	}
+	if rec := post(`{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("empty selector: status %d, want 400", rec.Code)
+	}
+	rec := post(`{"url_prefix":"https://wiki.example.com/"}`)
+	if rec.Code != http.StatusOK || strings.TrimSpace(rec.Body.String()) != `{"removed":1}` {
+		t.Errorf("invalidate: %d %s", rec.Code, rec.Body)
+	}
+
+	p.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "vpn"})
+	if n, m := len(wiki.CallsTo(datasource.MethodFetchTopics)), len(forum.CallsTo(datasource.MethodFetchTopics)); n != 2 || m != 1 {
+		t.Errorf("upstream calls wiki=%d forum=%d, want 2 and 1", n, m)
+	}
+	if n := p.Invalidate(cache.Selector{Source: "forum"}); n != 1 {
+		t.Errorf("Invalidate(forum) = %d, want 1", n)
+	}
+}
@@ -0,0 +1,252 @@
+package pipeline
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/locus-search/datasource-sdk/config"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/router"
+)
+
+// Plan describes the pipeline a Builder would assemble, with every default
+// made explicit. It is produced by Builder.Plan without constructing
+// sources, launching plugins, or contacting services, so misconfiguration
+// can be caught before deploying.
+type Plan struct {
+	Sources []SourcePlan `json:"sources"`
+
+	// Routing is "all sources" when every question goes to every source,
+	// or names the classifier
+	Routing string `json:"routing"`
+
+	// Routes lists the sources queried per intent; other intents go to
+	// every source
+	Routes map[router.Intent][]string `json:"routes,omitempty"`
+
+	// InitOrder lists the sources in the order Init initializes them,
+	// each after its dependencies
+	InitOrder []string `json:"init_order"`
+}
+
+// SourcePlan describes one source of a Plan.
+type SourcePlan struct {
+	Name string `json:"name"`
+
+	// Type is the descriptor type, or the Go type of a host source
+	Type string `json:"type"`
+
+	// Origin is the descriptor path, "inline", or "host"
+	Origin string `json:"origin"`
+
+	// Settings are the effective settings; those of host-provided types
+	// are shown as written
+	Settings json.RawMessage `json:"settings,omitempty"`
+
+	// Credentials is the credentials reference; values are never shown
+	Credentials string `json:"credentials,omitempty"`
+
+	// Middleware lists every decorator from outermost to innermost with
+	// effective settings, including the pipeline's own "metrics" and
+	// "logging" layers
+	Middleware []config.Middleware `json:"middleware"`
+
+	// Timeouts are the effective per-operation timeouts, if any
+	Timeouts *config.Timeouts `json:"timeouts,omitempty"`
+
+	// DependsOn lists the sources initialized before this one
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// Required means the pipeline is not ready without this source
+	Required bool `json:"required,omitempty"`
+
+	// Problems would make the source fail to build or initialize
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Problems returns the number of problems found across all sources.
+func (p *Plan) Problems() int {
+	n := 0
+	for _, s := range p.Sources {
+		n += len(s.Problems)
+	}
+	return n
+}
+
+// Plan resolves the pipeline Build would assemble. Errors that Build would
+// return are returned; problems that would only surface when a source is
+// built or initialized, such as invalid settings or unresolvable
+// credentials, are recorded in the plan.
+func (b *Builder) Plan() (*Plan, error) {
+	if err := errors.Join(b.errs...); err != nil {
+		return nil, err
+	}
+	sources, err := b.load()
+	if err != nil {
+		return nil, err
+	}
+	deps, err := b.dependencies(sources)
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []config.Middleware
+	layers = append(layers, config.Middleware{Type: "metrics"})
+	if b.logger != nil {
+		layers = append(layers, config.Middleware{Type: "logging"})
+	}
+	if len(b.hooks) > 0 {
+		layers = append(layers, config.Middleware{Type: "hooks"})
+	}
+
+	plan := &Plan{Routing: "all sources", Routes: b.routes, InitOrder: deps.order}
+	if b.classifier != nil {
+		plan.Routing = fmt.Sprintf("%T", b.classifier)
+	}
+	for _, cs := range sources {
+		resolved, err := b.loader.Resolve(cs.source)
+		sp := SourcePlan{
+			Name:        resolved.Name,
+			Type:        resolved.Type,
+			Origin:      cs.origin,
+			Settings:    resolved.Settings,
+			Credentials: resolved.Credentials,
+			Middleware:  append(append([]config.Middleware(nil), layers...), resolved.Middleware...),
+			Timeouts:    resolved.Timeouts,
+		}
+		for _, e := range flatten(err) {
+			sp.Problems = append(sp.Problems, e.Error())
+		}
+		plan.Sources = append(plan.Sources, sp)
+	}
+	for _, s := range b.sources {
+		plan.Sources = append(plan.Sources, SourcePlan{
+			Name:       s.name,
+			Type:       fmt.Sprintf("%T", s.ds),
+			Origin:     "host",
+			Middleware: layers,
+			Timeouts:   hostTimeouts(b.loader.Timeouts),
+		})
+	}
+	sort.Slice(plan.Sources, func(i, j int) bool { return plan.Sources[i].Name < plan.Sources[j].Name })
+	for i := range plan.Sources {
+		s := &plan.Sources[i]
+		s.DependsOn, s.Required = deps.deps[s.Name], deps.required[s.Name]
+	}
+	return plan, nil
+}
+
+// hostTimeouts returns the effective timeouts Build applies to host
+// sources, or nil if they have none.
+func hostTimeouts(t middleware.Timeouts) *config.Timeouts {
+	if t.IsZero() {
+		return nil
+	}
+	or := func(d, def time.Duration) config.Duration {
+		if d == 0 {
+			d = def
+		}
+		return config.Duration(d)
+	}
+	return &config.Timeouts{
+		Init:   or(t.Init, middleware.DefaultInitTimeout),
+		Fetch:  or(t.Fetch, middleware.DefaultFetchTimeout),
+		Health: or(t.Health, middleware.DefaultHealthTimeout),
+	}
+}
+
+// flatten splits an error made by errors.Join into its parts.
+func flatten(err error) []error {
+	if err == nil {
+		return nil
+	}
+	if j, ok := err.(interface{ Unwrap() []error }); ok {
+		return j.Unwrap()
+	}
+	return []error{err}
+}
+
+// WriteText writes the plan in a human-readable form.
+func (p *Plan) WriteText(w io.Writer) error {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%d sources, routing: %s\n", len(p.Sources), p.Routing)
+	intents := make([]string, 0, len(p.Routes))
+	for intent := range p.Routes {
+		intents = append(intents, string(intent))
+	}
+	sort.Strings(intents)
+	for _, intent := range intents {
+		fmt.Fprintf(&sb, "  %s -> %s\n", intent, strings.Join(p.Routes[router.Intent(intent)], ", "))
+	}
+	fmt.Fprintf(&sb, "init order: %s\n", strings.Join(p.InitOrder, ", "))
+
+	for _, s := range p.Sources {
+		fmt.Fprintf(&sb, "\n%s (%s, from %s)\n", s.Name, s.Type, s.Origin)
+		if len(s.Settings) > 0 {
+			fmt.Fprintf(&sb, "  settings:    %s\n", s.Settings)
+		}
+		if s.Credentials != "" {
+			fmt.Fprintf(&sb, "  credentials: %s\n", s.Credentials)
+		}
+		if len(s.DependsOn) > 0 {
+			fmt.Fprintf(&sb, "  depends on:  %s\n", strings.Join(s.DependsOn, ", "))
+		}
+		if s.Required {
+			sb.WriteString("  required:    yes\n")
+		}
+		if t := s.Timeouts; t != nil {
+			fmt.Fprintf(&sb, "  timeouts:    init=%s fetch=%s health=%s\n", time.Duration(t.Init), time.Duration(t.Fetch), time.Duration(t.Health))
+		}
+		sb.WriteString("  middleware (outermost first):\n")
+		for i, m := range s.Middleware {
+			fmt.Fprintf(&sb, "    %d. %s\n", i+1, strings.TrimSpace(fmt.Sprintf("%-10s %s", m.Type, describeMiddleware(m))))
+		}
+		if len(s.Problems) > 0 {
+			sb.WriteString("  problems:\n")
+			for _, problem := range s.Problems {
+				fmt.Fprintf(&sb, "    - %s\n", problem)
+			}
+		}
+	}
+
+	switch n := p.Problems(); n {
+	case 0:
+		sb.WriteString("\nNo problems found.\n")
+	case 1:
+		sb.WriteString("\n1 problem found.\n")
+	default:
+		fmt.Fprintf(&sb, "\n%d problems found.\n", n)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// describeMiddleware formats the settings relevant to m's type.
+func describeMiddleware(m config.Middleware) string {
+	dur := func(d config.Duration) string { return time.Duration(d).String() }
+	switch m.Type {
+	case "retry":
+		return fmt.Sprintf("max_attempts=%d initial_backoff=%s max_backoff=%s", m.MaxAttempts, dur(m.InitialBackoff), dur(m.MaxBackoff))
+	case "rate_limit":
+		return fmt.Sprintf("requests_per_second=%s burst=%d", strconv.FormatFloat(m.RequestsPerSecond, 'g', -1, 64), m.Burst)
+	case "cache":
+		return fmt.Sprintf("ttl=%s max_entries=%d partition=%t", dur(m.TTL), m.MaxEntries, m.Partition)
+	case "maintenance":
+		windows := make([]string, len(m.Windows))
+		for i, w := range m.Windows {
+			windows[i] = fmt.Sprintf("%q for %s", w.Start, dur(w.Duration))
+		}
+		tz := m.Timezone
+		if tz == "" {
+			tz = "UTC"
+		}
+		return fmt.Sprintf("windows=%s timezone=%s", strings.Join(windows, ","), tz)
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+package pipeline_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/pipeline"
+	"github.com/locus-search/datasource-sdk/router"
+)
+
+func TestPlanResolvesDefaults(t *testing.T) {
+	t.Setenv("KB_TOKEN", "secret-value")
+	plan, err := pipeline.NewBuilder().
+		WithConfig([]byte(`{"sources": [{
+			"name": "kb", "type": "remote", "settings": {"url": "https://kb.internal"}, "credentials": "env:KB_TOKEN",
+			"middleware": [{"type": "cache", "ttl": "10m"}, {"type": "retry", "max_attempts": 4}]
+		}]}`)).
+		WithSource("wiki", &datasourcetest.Fake{}).
+		WithRouting(router.KeywordClassifier{}, map[router.Intent][]string{router.IntentPolicy: {"kb"}}).
+		Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plan.Problems() != 0 || len(plan.Sources) != 2 {
+		t.Fatalf("unexpected plan %+v", plan)
+	}
+
+	kb := plan.Sources[0]
+	if string(kb.Settings) != `{"url":"https://kb.internal","timeout":"30s"}` {
+		t.Errorf("settings %s, want the default timeout filled in", kb.Settings)
+	}
+	var types []string
+	for _, m := range kb.Middleware {
+		types = append(types, m.Type)
+	}
+	if strings.Join(types, ",") != "metrics,cache,retry" {
+		t.Errorf("middleware %v", types)
+	}
+	if m := kb.Middleware[1]; m.MaxEntries != 1000 {
+		t.Errorf("cache max_entries %d, want the default", m.MaxEntries)
+	}
+	if m := kb.Middleware[2]; m.MaxAttempts != 4 || m.InitialBackoff == 0 {
+		t.Errorf("retry %+v", m)
+	}
+	if wiki := plan.Sources[1]; wiki.Origin != "host" || wiki.Type != "*datasourcetest.Fake" {
+		t.Errorf("host source %+v", wiki)
+	}
+
+	var text bytes.Buffer
+	plan.WriteText(&text)
+	out, _ := json.Marshal(plan)
+	for _, dump := range []string{text.String(), string(out)} {
+		if strings.Contains(dump, "secret-value") {
+			t.Errorf("plan reveals credentials:\n%s", dump)
+		}
+	}
+	for _, want := range []string{"routing: router.KeywordClassifier", "policy -> kb", "retry      max_attempts=4", "No problems found."} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text plan missing %q:\n%s", want, text.String())
+		}
+	}
+}
+
+func TestPlanReportsProblems(t *testing.T) {
+	plan, err := pipeline.NewBuilder().
+		WithType("custom", func(json.RawMessage, string) (datasource.DataSource, error) { return &datasourcetest.Fake{}, nil }).
+		WithConfig([]byte(`{"sources": [
+			{"name": "a", "type": "remote", "credentials": "env:LOCUS_TEST_UNSET_VARIABLE"},
+			{"name": "b", "type": "plugin", "settings": {"command": "x", "memory": 1}},
+			{"name": "c", "type": "nope", "middleware": [{"type": "rate_limit"}, {"type": "compress"}]},
+			{"name": "d", "type": "custom", "settings": {"anything": true}}
+		]}`)).
+		Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]string{
+		"a": {"credentials", "missing url"},
+		"b": {`unknown field "memory"`},
+		"c": {`unknown type "nope"`, "requests_per_second must be positive", `unknown middleware type "compress"`},
+	}
+	for _, s := range plan.Sources {
+		problems := strings.Join(s.Problems, "\n")
+		if len(want[s.Name]) != len(s.Problems) {
+			t.Errorf("%s: problems %q, want %d", s.Name, s.Problems, len(want[s.Name]))
+		}
+		for _, w := range want[s.Name] {
+			if !strings.Contains(problems, w) {
+				t.Errorf("%s: problems %q do not mention %q", s.Name, s.Problems, w)
+			}
+		}
+	}
+	if plan.Problems() != 6 {
+		t.Errorf("Problems() = %d, want 6", plan.Problems())
+	}
+}
+
+func TestPlanFailsLikeBuild(t *testing.T) {
+	_, err := pipeline.NewBuilder().WithSource("a", &datasourcetest.Fake{}).
+		WithRouting(router.KeywordClassifier{}, map[router.Intent][]string{router.IntentPolicy: {"b"}}).
+		Plan()
+	if err == nil || !strings.Contains(err.Error(), `unknown source "b"`) {
+		t.Errorf("Plan error %v, want the unknown route reported", err)
+	}
+}
+
+func TestPlanShowsTimeouts(t *testing.T) {
+	plan, err := pipeline.NewBuilder().
+		WithConfig([]byte(`{"sources": [{
+			"name": "kb", "type": "remote", "settings": {"url": "https://kb.internal"}, "timeouts": {"init": "2m"}
+		}]}`)).
+		WithSource("wiki", &datasourcetest.Fake{}).
+		WithTimeouts(middleware.Timeouts{Fetch: 5 * time.Second}).
+		Plan()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var text bytes.Buffer
+	plan.WriteText(&text)
+	for _, want := range []string{
+		"timeouts:    init=2m0s fetch=5s health=2s",
+		"timeouts:    init=1m0s fetch=5s health=2s",
+	} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("text plan missing %q:\n%s", want, text.String())
+		}
+	}
+}
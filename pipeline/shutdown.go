@@ -0,0 +1,126 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/middleware"
+)
+
+// ShutdownReport describes how the sources of a pipeline shut down.
+type ShutdownReport struct {
+	// Sources lists the initialized sources in the order they were closed
+	Sources []SourceShutdown
+}
+
+// SourceShutdown describes how one source shut down.
+type SourceShutdown struct {
+	// Name is the source's name
+	Name string
+
+	// Abandoned is the number of calls still in flight when the deadline
+	// passed; the source was flushed and closed beneath them
+	Abandoned int
+
+	// FlushErr is the error from flushing the source's layers, if any
+	FlushErr error
+
+	// CloseErr is the error from closing the source's layers, if any
+	CloseErr error
+}
+
+// CutShort reports whether the source did not shut down cleanly.
+func (s SourceShutdown) CutShort() bool {
+	return s.Abandoned > 0 || s.FlushErr != nil || s.CloseErr != nil
+}
+
+// CutShort returns the sources that did not shut down cleanly.
+func (r *ShutdownReport) CutShort() []SourceShutdown {
+	var cut []SourceShutdown
+	for _, s := range r.Sources {
+		if s.CutShort() {
+			cut = append(cut, s)
+		}
+	}
+	return cut
+}
+
+// Shutdown shuts the pipeline down gracefully, for rolling deploys:
+//
+//  1. New calls fail with ErrUnavailable, through the pipeline and through
+//     views returned by Only.
+//  2. Calls in flight are waited for, across all sources at once, until
+//     they finish or ctx is done.
+//  3. Every layer of every source implementing datasource.Flusher is
+//     flushed, persisting caches and checkpoints.
+//  4. Sources are closed with datasource.Shutdown, dependents before their
+//     dependencies: in the reverse of the order Init initializes them.
+//
+// Steps 3 and 4 run even if ctx is done, so that each source gets the
+// chance to release its resources. The report records what was cut short,
+// and the error joins everything that went wrong. Shutting down a pipeline
+// that is not initialized, or already shut down, does nothing.
+//
+//	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+//	defer cancel()
+//	report, err := p.Shutdown(ctx)
+//	for _, s := range report.CutShort() {
+//		log.Printf("%s: %d calls abandoned", s.Name, s.Abandoned)
+//	}
+func (p *Pipeline) Shutdown(ctx context.Context) (*ShutdownReport, error) {
+	p.mu.Lock()
+	active := p.active
+	p.active, p.router = nil, nil
+	p.mu.Unlock()
+
+	report := &ShutdownReport{}
+	for i := len(p.deps.order) - 1; i >= 0; i-- {
+		if name := p.deps.order[i]; active[name] != nil {
+			report.Sources = append(report.Sources, SourceShutdown{Name: name})
+		}
+	}
+
+	var wg sync.WaitGroup
+	for i := range report.Sources {
+		s := &report.Sources[i]
+		ds := active[s.Name]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if c, ok := ds.(datasource.Closer); ok && c.Close(ctx) != nil {
+				s.Abandoned, _ = middleware.InFlight(ds)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for i := range report.Sources {
+		s := &report.Sources[i]
+		inner := unwrap(active[s.Name])
+		s.FlushErr = datasource.Flush(ctx, inner)
+		s.CloseErr = datasource.Shutdown(ctx, inner)
+		if s.Abandoned > 0 {
+			errs = append(errs, fmt.Errorf("pipeline: shutdown %q: %d calls abandoned: %w", s.Name, s.Abandoned, ctx.Err()))
+		}
+		if s.FlushErr != nil {
+			errs = append(errs, fmt.Errorf("pipeline: flush %q: %w", s.Name, s.FlushErr))
+		}
+		if s.CloseErr != nil {
+			errs = append(errs, fmt.Errorf("pipeline: close %q: %w", s.Name, s.CloseErr))
+		}
+	}
+	return report, errors.Join(errs...)
+}
+
+// unwrap returns the source beneath the Drain layer added by register,
+// which Shutdown has already closed.
+func unwrap(ds datasource.DataSource) datasource.DataSource {
+	if u, ok := ds.(interface{ Unwrap() datasource.DataSource }); ok {
+		return u.Unwrap()
+	}
+	return ds
+}
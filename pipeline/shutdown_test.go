@@ -0,0 +1,113 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/pipeline"
+)
+
+// lifecycleFake records flushes and closes in a shared log.
+type lifecycleFake struct {
+	datasourcetest.Fake
+	name string
+
+	mu  *sync.Mutex
+	log *[]string
+}
+
+func (f *lifecycleFake) record(event string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	*f.log = append(*f.log, event+" "+f.name)
+}
+
+func (f *lifecycleFake) Flush(context.Context) error {
+	f.record("flush")
+	return nil
+}
+
+func (f *lifecycleFake) Close(context.Context) error {
+	f.record("close")
+	return nil
+}
+
+func TestPipelineShutdown(t *testing.T) {
+	var mu sync.Mutex
+	var log []string
+	release := make(chan struct{})
+	started := make(chan struct{})
+	slow := &lifecycleFake{name: "a", mu: &mu, log: &log}
+	slow.DataFunc = func(int, int64) ([]datasource.DataSourceData, error) {
+		close(started)
+		<-release
+		return nil, nil
+	}
+	fast := &lifecycleFake{name: "b", mu: &mu, log: &log}
+	p, err := pipeline.NewBuilder().WithSource("a", slow).WithSource("b", fast).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+	view, err := p.Only("b")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.Source("a").FetchData(1, 1)
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err := p.Shutdown(ctx)
+	if err == nil || !strings.Contains(err.Error(), `"a": 1 calls abandoned`) {
+		t.Errorf("Shutdown error = %v, want abandoned call in a", err)
+	}
+	if len(report.Sources) != 2 || report.Sources[0].Name != "b" || report.Sources[1].Name != "a" {
+		t.Fatalf("report sources = %+v, want b then a", report.Sources)
+	}
+	cut := report.CutShort()
+	if len(cut) != 1 || cut[0].Name != "a" || cut[0].Abandoned != 1 {
+		t.Errorf("CutShort = %+v, want a with 1 abandoned call", cut)
+	}
+	mu.Lock()
+	if got := strings.Join(log, ","); got != "flush b,close b,flush a,close a" {
+		t.Errorf("lifecycle = %s", got)
+	}
+	mu.Unlock()
+
+	if _, err := view.FetchTopics(1, datasource.NewQuestionInput{}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchTopics through view after Shutdown = %v, want ErrUnavailable", err)
+	}
+	close(release)
+	<-done
+
+	if report, err := p.Shutdown(context.Background()); err != nil || len(report.Sources) != 0 {
+		t.Errorf("second Shutdown = %+v, %v; want nothing to do", report, err)
+	}
+}
+
+func TestPipelineShutdownClean(t *testing.T) {
+	src := &datasourcetest.Fake{}
+	p, err := pipeline.NewBuilder().WithSource("a", src).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Init()
+	report, err := p.Shutdown(context.Background())
+	if err != nil || len(report.CutShort()) != 0 {
+		t.Errorf("Shutdown = %+v, %v; want a clean shutdown", report.CutShort(), err)
+	}
+}
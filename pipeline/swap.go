@@ -0,0 +1,249 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/middleware"
+)
+
+// DefaultSwapDrainTimeout bounds how long Swap waits for calls to the old
+// source when SwapOptions.DrainTimeout is zero.
+const DefaultSwapDrainTimeout = 30 * time.Second
+
+// shadowConcurrency bounds the shadow calls in flight during warming;
+// further live calls are not mirrored.
+const shadowConcurrency = 8
+
+// SwapOptions configures Swap.
+type SwapOptions struct {
+	// Shadow is how long live FetchTopics calls to the source are mirrored
+	// to the replacement before switching, to warm its caches and
+	// connections. The replacement's results are discarded. Zero switches
+	// as soon as the replacement is initialized
+	Shadow time.Duration
+
+	// DrainTimeout bounds the wait for calls in flight to the old source
+	// before it is closed beneath them
+	// Defaults to DefaultSwapDrainTimeout
+	DrainTimeout time.Duration
+}
+
+// SwapReport describes a completed Swap.
+type SwapReport struct {
+	// ShadowCalls and ShadowErrors count the mirrored calls made to the
+	// replacement while warming, and those that failed
+	ShadowCalls, ShadowErrors int
+
+	// Abandoned is the number of calls to the old source still in flight
+	// when it was closed
+	Abandoned int
+}
+
+// Swap replaces the source registered under name with ds without downtime,
+// for rotating credentials or upgrading a source:
+//
+//  1. ds is given the timeouts and middleware of the source it replaces,
+//     decorated like every pipeline source, and initialized while the old
+//     source keeps serving. If Init fails, the old source is kept and the
+//     error returned.
+//  2. If opts.Shadow is set, live FetchTopics calls are mirrored to ds for
+//     that long.
+//  3. Routing switches to ds atomically; calls already routed to the old
+//     source finish there, and later calls, including later Init calls,
+//     use ds.
+//  4. The old source is drained, flushed, and closed as by Shutdown.
+//
+// ctx bounds the whole swap; if it is done before the switch, the old
+// source is kept and ds is closed. Once switched, ds stays in service even
+// if closing the old source fails, which is reported in the error. Views
+// returned by Only before the switch keep the old source, so create views
+// per request. Swap fails with ErrNotFound for unknown names and
+// ErrUnavailable if the pipeline is not initialized.
+func (p *Pipeline) Swap(ctx context.Context, name string, ds datasource.DataSource, opts SwapOptions) (*SwapReport, error) {
+	if opts.DrainTimeout <= 0 {
+		opts.DrainTimeout = DefaultSwapDrainTimeout
+	}
+	p.swapMu.Lock()
+	defer p.swapMu.Unlock()
+	if err := p.swappable(name); err != nil {
+		return nil, err
+	}
+
+	ds, err := p.wrap[name](ds)
+	if err != nil {
+		return nil, fmt.Errorf("pipeline: swap %q: %w", name, err)
+	}
+	next := p.decorate(name, ds)
+	if err := next.Init(); err != nil {
+		return nil, fmt.Errorf("pipeline: swap %q: init replacement: %w", name, err)
+	}
+	report := &SwapReport{}
+	if opts.Shadow > 0 {
+		if err := p.warm(ctx, name, ds, opts.Shadow, report); err != nil {
+			datasource.Shutdown(context.Background(), next)
+			return report, fmt.Errorf("pipeline: swap %q: %w", name, err)
+		}
+	}
+
+	p.mu.Lock()
+	if p.router == nil {
+		p.mu.Unlock()
+		datasource.Shutdown(context.Background(), next)
+		return report, fmt.Errorf("pipeline: swap %q: shut down while swapping: %w", name, datasource.ErrUnavailable)
+	}
+	old := p.active[name]
+	active, failed, replaced := copyMap(p.active), copyMap(p.failed), copyMap(p.replaced)
+	active[name], replaced[name] = next, next
+	delete(failed, name)
+	p.install(active, failed)
+	p.replaced = replaced
+	p.mu.Unlock()
+
+	if old == nil {
+		return report, nil
+	}
+	drainCtx, cancel := context.WithTimeout(ctx, opts.DrainTimeout)
+	defer cancel()
+	var errs []error
+	if c, ok := old.(datasource.Closer); ok {
+		if err := c.Close(drainCtx); err != nil {
+			report.Abandoned, _ = middleware.InFlight(old)
+			errs = append(errs, err)
+		}
+	}
+	inner := unwrap(old)
+	errs = append(errs, datasource.Flush(drainCtx, inner), datasource.Shutdown(drainCtx, inner))
+	if err := errors.Join(errs...); err != nil {
+		return report, fmt.Errorf("pipeline: swap %q: close old source: %w", name, err)
+	}
+	return report, nil
+}
+
+// swappable checks that name can be swapped.
+func (p *Pipeline) swappable(name string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	known := false
+	for _, n := range p.names {
+		known = known || n == name
+	}
+	if !known {
+		return fmt.Errorf("pipeline: swap %q: %w", name, datasource.ErrNotFound)
+	}
+	if p.router == nil {
+		return fmt.Errorf("pipeline: swap %q: not initialized: %w", name, datasource.ErrUnavailable)
+	}
+	return nil
+}
+
+// warm mirrors live FetchTopics calls for name to ds for d, or until ctx
+// is done, which is an error.
+func (p *Pipeline) warm(ctx context.Context, name string, ds datasource.DataSource, d time.Duration, report *SwapReport) error {
+	p.mu.Lock()
+	serving := p.active[name]
+	if serving == nil {
+		// The old source failed to initialize, so there is no traffic to
+		// mirror.
+		p.mu.Unlock()
+		return nil
+	}
+	shadow := &shadowSource{DataSource: serving, shadow: ds, sem: make(chan struct{}, shadowConcurrency)}
+	active := copyMap(p.active)
+	active[name] = shadow
+	p.install(active, p.failed)
+	p.mu.Unlock()
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	p.mu.Lock()
+	if p.router != nil && p.active[name] == shadow {
+		active := copyMap(p.active)
+		active[name] = serving
+		p.install(active, p.failed)
+	}
+	p.mu.Unlock()
+	shadow.stop()
+	report.ShadowCalls, report.ShadowErrors = int(shadow.calls.Load()), int(shadow.errs.Load())
+	return err
+}
+
+// shadowSource serves calls from its DataSource and mirrors FetchTopics
+// calls to shadow in the background.
+type shadowSource struct {
+	datasource.DataSource
+	shadow datasource.DataSource
+	sem    chan struct{}
+
+	mu          sync.Mutex
+	stopped     bool
+	wg          sync.WaitGroup
+	calls, errs atomic.Int64
+}
+
+func (s *shadowSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	// The live source alone reports on the question.
+	mirrored := input
+	mirrored.Trace = nil
+	s.mirror(func() error {
+		_, err := s.shadow.FetchTopics(count, mirrored)
+		return err
+	})
+	return s.DataSource.FetchTopics(count, input)
+}
+
+// mirror runs call in the background unless warming has stopped or too
+// many mirrored calls are in flight.
+func (s *shadowSource) mirror(call func() error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	select {
+	case s.sem <- struct{}{}:
+	default:
+		return
+	}
+	s.wg.Add(1)
+	go func() {
+		defer func() { <-s.sem; s.wg.Done() }()
+		s.calls.Add(1)
+		if call() != nil {
+			s.errs.Add(1)
+		}
+	}()
+}
+
+// stop stops mirroring and waits for mirrored calls to finish.
+func (s *shadowSource) stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Unwrap returns the serving source.
+func (s *shadowSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
+
+func copyMap[V any](m map[string]V) map[string]V {
+	out := make(map[string]V, len(m)+1)
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,179 @@
+package pipeline_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/pipeline"
+)
+
+func TestPipelineSwap(t *testing.T) {
+	old := &closableFake{Fake: datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "old", TopicID: 1}}}}
+	next := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "new", TopicID: 1}}}
+	p, err := pipeline.NewBuilder().WithSource("kb", old).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Keep live traffic flowing while the replacement warms up; it must be
+	// served by the old source until the switch.
+	var served atomic.Value
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if topics, err := p.FetchTopics(1, datasource.NewQuestionInput{QuestionText: "q"}); err == nil && len(topics) == 1 {
+				served.Store(topics[0].Topic)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}()
+
+	report, err := p.Swap(context.Background(), "kb", next, pipeline.SwapOptions{Shadow: 30 * time.Millisecond})
+	close(stop)
+	<-done
+	if err != nil {
+		t.Fatalf("Swap = %v", err)
+	}
+	if report.ShadowCalls == 0 || report.ShadowErrors != 0 {
+		t.Errorf("report = %+v, want mirrored calls without errors", report)
+	}
+	if n := len(next.Calls()); n < report.ShadowCalls+1 {
+		t.Errorf("replacement saw %d calls, want Init plus %d shadow calls", n, report.ShadowCalls)
+	}
+	if !old.closed {
+		t.Error("old source was not closed")
+	}
+	for _, c := range next.CallsTo(datasource.MethodFetchTopics) {
+		if c.Input.Trace != nil {
+			t.Error("shadow call shared the live source's trace")
+			break
+		}
+	}
+	topics, err := p.FetchTopics(1, datasource.NewQuestionInput{})
+	if err != nil || topics[0].Topic != "new" {
+		t.Errorf("FetchTopics after Swap = %v, %v; want the replacement", topics, err)
+	}
+
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if topics, _ := p.FetchTopics(1, datasource.NewQuestionInput{}); topics[0].Topic != "new" {
+		t.Errorf("Init restored the old source: %v", topics)
+	}
+}
+
+func TestPipelineSwapKeepsOldSourceOnFailure(t *testing.T) {
+	old := &closableFake{Fake: datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "old", TopicID: 1}}}}
+	p, err := pipeline.NewBuilder().WithSource("kb", old).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if _, err := p.Swap(ctx, "kb", &datasourcetest.Fake{}, pipeline.SwapOptions{}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("Swap before Init = %v, want ErrUnavailable", err)
+	}
+	p.Init()
+	if _, err := p.Swap(ctx, "nope", &datasourcetest.Fake{}, pipeline.SwapOptions{}); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("Swap of unknown source = %v, want ErrNotFound", err)
+	}
+
+	broken := &datasourcetest.Fake{Errors: map[datasource.Method]error{datasource.MethodInit: datasource.ErrUnauthorized}}
+	if _, err := p.Swap(ctx, "kb", broken, pipeline.SwapOptions{}); !errors.Is(err, datasource.ErrUnauthorized) {
+		t.Errorf("Swap = %v, want the replacement's Init error", err)
+	}
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err := p.Swap(canceled, "kb", &datasourcetest.Fake{}, pipeline.SwapOptions{Shadow: time.Minute}); !errors.Is(err, context.Canceled) {
+		t.Errorf("Swap with canceled context = %v, want Canceled", err)
+	}
+	if topics, err := p.FetchTopics(1, datasource.NewQuestionInput{}); err != nil || topics[0].Topic != "old" || old.closed {
+		t.Errorf("FetchTopics = %v, %v; want the old source kept", topics, err)
+	}
+}
+
+// blockingFake is a source whose FetchTopics does not return until release
+// is closed.
+type blockingFake struct {
+	datasourcetest.Fake
+	release chan struct{}
+}
+
+func (f *blockingFake) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	<-f.release
+	return f.Fake.FetchTopics(count, input)
+}
+
+func TestPipelineSwapKeepsTimeouts(t *testing.T) {
+	p, err := pipeline.NewBuilder().
+		WithSource("kb", &datasourcetest.Fake{}).
+		WithTimeouts(middleware.Timeouts{Fetch: 20 * time.Millisecond}).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+	next := &blockingFake{release: make(chan struct{})}
+	t.Cleanup(func() { close(next.release) })
+	if _, err := p.Swap(context.Background(), "kb", next, pipeline.SwapOptions{}); err != nil {
+		t.Fatalf("Swap = %v", err)
+	}
+
+	errc := make(chan error, 1)
+	go func() {
+		_, err := p.FetchTopics(1, datasource.NewQuestionInput{QuestionText: "q"})
+		errc <- err
+	}()
+	select {
+	case err := <-errc:
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("FetchTopics = %v, want a timeout", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("FetchTopics of the swapped-in source did not time out")
+	}
+}
+
+func TestPipelineSwapKeepsTopicOwners(t *testing.T) {
+	kb := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "kb", TopicID: 1}}}
+	wiki := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{{Topic: "wiki", TopicID: 2}}}
+	p, err := pipeline.NewBuilder().WithSource("kb", kb).WithSource("wiki", wiki).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if topics, err := p.FetchTopics(2, datasource.NewQuestionInput{QuestionText: "q"}); err != nil || len(topics) != 2 {
+		t.Fatalf("FetchTopics = %v, %v", topics, err)
+	}
+
+	next := &datasourcetest.Fake{Data: map[int64][]datasource.DataSourceData{1: {{DataText: "new", AnswerID: 1}}}}
+	if _, err := p.Swap(context.Background(), "kb", next, pipeline.SwapOptions{}); err != nil {
+		t.Fatalf("Swap = %v", err)
+	}
+	data, err := p.FetchData(1, 1)
+	if err != nil || len(data) != 1 || data[0].DataText != "new" {
+		t.Errorf("FetchData = %v, %v; want the replacement's data", data, err)
+	}
+	if n := len(wiki.CallsTo(datasource.MethodFetchData)); n != 0 {
+		t.Errorf("FetchData of a topic returned before the swap probed other sources %d times", n)
+	}
+}
@@ -0,0 +1,287 @@
+package plugin
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// DefaultStartTimeout bounds how long Launch waits for a plugin to announce
+// its address when ClientConfig.StartTimeout is zero.
+const DefaultStartTimeout = 30 * time.Second
+
+// ClientConfig configures Launch.
+type ClientConfig struct {
+	// Cmd is the plugin command to run; it must not have been started
+	Cmd *exec.Cmd
+
+	// Handshake authenticates the plugin
+	Handshake Handshake
+
+	// Checksum, if set, is the hex-encoded SHA-256 of the plugin binary,
+	// verified with VerifyBinary before it is started
+	Checksum string
+
+	// StartTimeout bounds startup until the plugin announces its address
+	// Defaults to DefaultStartTimeout
+	StartTimeout time.Duration
+
+	// Limits bounds the plugin's memory, CPU, and call duration
+	Limits Limits
+}
+
+// Client is a DataSource served by a plugin. Calls fail with an error
+// wrapping datasource.ErrUnavailable once the connection is lost, or with a
+// *LimitError if the plugin exceeded a resource limit.
+type Client struct {
+	rpc         *rpc.Client
+	proc        *process
+	callTimeout time.Duration
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// process tracks a launched plugin process.
+type process struct {
+	cmd  *exec.Cmd
+	done chan struct{}
+	err  error // limit violation that ended the process; set before done closes
+}
+
+var _ datasource.DataSource = (*Client)(nil)
+
+// Launch starts the plugin process, verifies it, performs the handshake,
+// and returns a Client connected to it. The plugin's stderr is passed
+// through to Cmd.Stderr (os.Stderr if nil).
+func Launch(cfg ClientConfig) (*Client, error) {
+	cmd := cfg.Cmd
+	if cfg.Checksum != "" {
+		if err := VerifyBinary(cmd.Path, cfg.Checksum); err != nil {
+			return nil, err
+		}
+	}
+	if cmd.Env == nil {
+		cmd.Env = os.Environ()
+	}
+	cmd.Env = append(cmd.Env, EnvProtocol+"="+strconv.Itoa(ProtocolVersion))
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("plugin: %w", err)
+	}
+	sb, err := newSandbox(cmd, cfg.Limits)
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		sb.exited(nil)
+		return nil, fmt.Errorf("plugin: start %s: %w", cmd.Path, err)
+	}
+	proc := &process{cmd: cmd, done: make(chan struct{})}
+	exited := proc.done
+	go func() {
+		cmd.Wait()
+		proc.err = sb.exited(cmd.ProcessState)
+		close(proc.done)
+	}()
+
+	fail := func(err error) (*Client, error) {
+		cmd.Process.Kill()
+		<-exited
+		return nil, err
+	}
+	if err := sb.started(cmd.Process.Pid); err != nil {
+		return fail(err)
+	}
+
+	timeout := cfg.StartTimeout
+	if timeout <= 0 {
+		timeout = DefaultStartTimeout
+	}
+	addr := make(chan string, 1)
+	go func() {
+		r := bufio.NewReader(stdout)
+		line, _ := r.ReadString('\n')
+		addr <- strings.TrimSpace(line)
+		io.Copy(io.Discard, r) // keep later output from blocking the plugin
+	}()
+
+	var line string
+	select {
+	case line = <-addr:
+	case <-exited:
+		return fail(fmt.Errorf("plugin: %s exited during startup", cmd.Path))
+	case <-time.After(timeout):
+		return fail(fmt.Errorf("plugin: %s did not start within %v", cmd.Path, timeout))
+	}
+	network, address, err := parseAnnounce(line)
+	if err != nil {
+		return fail(err)
+	}
+	conn, err := net.DialTimeout(network, address, timeout)
+	if err != nil {
+		return fail(fmt.Errorf("plugin: dial %s: %w", address, err))
+	}
+	c, err := NewClient(conn, cfg.Handshake)
+	if err != nil {
+		return fail(err)
+	}
+	c.proc, c.callTimeout = proc, cfg.Limits.CallTimeout
+	return c, nil
+}
+
+func parseAnnounce(line string) (network, address string, err error) {
+	parts := strings.Split(line, "|")
+	if len(parts) != 4 || parts[0] != announcePrefix {
+		return "", "", fmt.Errorf("plugin: unexpected announcement %q", line)
+	}
+	if v, _ := strconv.Atoi(parts[1]); v != ProtocolVersion {
+		return "", "", fmt.Errorf("%w: plugin speaks protocol %s, want %d", ErrHandshake, parts[1], ProtocolVersion)
+	}
+	return parts[2], parts[3], nil
+}
+
+// NewClient performs the host side of the handshake on conn and returns a
+// Client using it. Closing the Client closes conn.
+func NewClient(conn net.Conn, hs Handshake) (*Client, error) {
+	c, err := hs.Client(conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Client{rpc: rpc.NewClientWithCodec(jsonrpc.NewClientCodec(c))}, nil
+}
+
+// Exited returns a channel closed when the plugin process exits, or nil for
+// clients created with NewClient.
+func (c *Client) Exited() <-chan struct{} {
+	if c.proc == nil {
+		return nil
+	}
+	return c.proc.done
+}
+
+// Close closes the connection and, for launched plugins, stops the process
+// and waits for it to exit.
+func (c *Client) Close() error {
+	c.closeOnce.Do(func() {
+		c.closeErr = c.rpc.Close()
+		if p := c.proc; p != nil {
+			select {
+			case <-p.done:
+			case <-time.After(2 * time.Second):
+				p.cmd.Process.Kill()
+				<-p.done
+			}
+		}
+	})
+	return c.closeErr
+}
+
+func (c *Client) call(method string, args, reply any) error {
+	var timeout <-chan time.Time
+	if c.callTimeout > 0 {
+		t := time.NewTimer(c.callTimeout)
+		defer t.Stop()
+		timeout = t.C
+	}
+	var err error
+	select {
+	case call := <-c.rpc.Go(ServiceName+"."+method, args, reply, make(chan *rpc.Call, 1)).Done:
+		err = call.Error
+	case <-timeout:
+		return &LimitError{Limit: LimitWallClock, Detail: fmt.Sprintf("%s did not return within %v", method, c.callTimeout)}
+	}
+	var serverErr rpc.ServerError
+	if err != nil && !errors.As(err, &serverErr) {
+		// Anything but an error reported by the plugin means the
+		// connection is broken.
+		if p := c.proc; p != nil {
+			// Give the exit watcher a moment to explain a crash.
+			select {
+			case <-p.done:
+				if p.err != nil {
+					return p.err
+				}
+			case <-time.After(time.Second):
+			}
+		}
+		return fmt.Errorf("plugin: %s: %v: %w", method, err, datasource.ErrUnavailable)
+	}
+	if err != nil {
+		return fmt.Errorf("plugin: %s: %w", method, err)
+	}
+	return nil
+}
+
+func (c *Client) Init() error {
+	var r InitResult
+	if err := c.call("Init", Empty{}, &r); err != nil {
+		return err
+	}
+	return r.Error.Err()
+}
+
+func (c *Client) CheckAvailability() bool {
+	var r AvailabilityResult
+	return c.call("CheckAvailability", Empty{}, &r) == nil && r.Available
+}
+
+// Capabilities returns the capabilities reported by the plugin, or none if
+// the call fails. Streaming is never reported, since the protocol delivers
+// data in one response.
+func (c *Client) Capabilities() datasource.Capabilities {
+	var r CapabilitiesResult
+	if c.call("Capabilities", Empty{}, &r) != nil {
+		return datasource.Capabilities{}
+	}
+	r.Capabilities.Streaming = false
+	return r.Capabilities
+}
+
+func (c *Client) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	var r FetchTopicsResult
+	if err := c.call("FetchTopics", FetchTopicsArgs{Count: count, Input: input}, &r); err != nil {
+		return nil, err
+	}
+	if err := r.Error.Err(); err != nil {
+		return nil, err
+	}
+	input.Trace.Estimate(r.TotalEstimate)
+	return nonNil(r.Topics), nil
+}
+
+func (c *Client) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	var r FetchDataResult
+	if err := c.call("FetchData", FetchDataArgs{Count: count, TopicID: topicID}, &r); err != nil {
+		return nil, err
+	}
+	if err := r.Error.Err(); err != nil {
+		return nil, err
+	}
+	return nonNil(r.Data), nil
+}
+
+// nonNil preserves the contract that empty results are non-nil slices,
+// which JSON encodes as null when the plugin returns nil.
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
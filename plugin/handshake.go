@@ -0,0 +1,306 @@
+// Package plugin runs data sources as separate processes and connects to
+// them from a host.
+//
+// Before any DataSource call crosses a plugin connection, host and plugin
+// perform a handshake that checks they speak the same protocol and
+// authenticates both ends, so only trusted plugin binaries can register as
+// data sources. Authentication uses a shared secret (a mutual HMAC
+// challenge-response), mutual TLS, or both. The shared secret only
+// authenticates: calls and results then cross the connection in plain
+// text, so use TLS as well when the connection may be observed, such as
+// over a network rather than between processes on one machine.
+//
+// A plugin binary serves its data source from main:
+//
+//	func main() {
+//	    hs := plugin.Handshake{Secret: []byte(os.Getenv("MY_PLUGIN_SECRET"))}
+//	    if err := plugin.Serve(mysource.New(), hs); err != nil {
+//	        log.Fatal(err)
+//	    }
+//	}
+//
+// and the host launches it and uses the returned Client as a DataSource:
+//
+//	ds, err := plugin.Launch(plugin.ClientConfig{
+//	    Cmd:       exec.Command("/opt/locus/plugins/mysource"),
+//	    Handshake: plugin.Handshake{Secret: secret},
+//	    Checksum:  "9f86d0...",
+//	})
+//
+// The wire protocol is JSON-RPC, so plugins can also be written in other
+// languages; see ServiceName for its description.
+package plugin
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// ProtocolVersion is the version of the plugin wire protocol.
+const ProtocolVersion = 1
+
+// ErrHandshake is returned, wrapped, when a handshake fails.
+var ErrHandshake = errors.New("plugin: handshake failed")
+
+// ProtocolChecksum identifies the DataSource method set served over the
+// protocol. Host and plugin must agree on it, so a plugin built against an
+// incompatible SDK is rejected before its first call.
+var ProtocolChecksum = protocolChecksum()
+
+func protocolChecksum() string {
+	t := reflect.TypeOf((*datasource.DataSource)(nil)).Elem()
+	var b strings.Builder
+	fmt.Fprintf(&b, "locus-datasource/%d\n", ProtocolVersion)
+	for i := 0; i < t.NumMethod(); i++ {
+		m := t.Method(i)
+		fmt.Fprintf(&b, "%s %s\n", m.Name, m.Type)
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// DefaultHandshakeTimeout bounds a handshake when Handshake.Timeout is zero.
+const DefaultHandshakeTimeout = 10 * time.Second
+
+// Handshake configures how host and plugin authenticate each other. At
+// least one of Secret and TLS must be set; setting both requires both.
+type Handshake struct {
+	// Secret is a shared secret provisioned to the host and to trusted
+	// plugins; each side proves it knows the secret without sending it.
+	// It does not encrypt the connection
+	Secret []byte
+
+	// TLS enables mutual TLS on the connection. The host side needs a
+	// client certificate and the plugin side must set ClientAuth to
+	// tls.RequireAndVerifyClientCert; a plugin side that would accept a
+	// host without a verified certificate fails the handshake
+	TLS *tls.Config
+
+	// Timeout bounds the whole handshake
+	// Defaults to DefaultHandshakeTimeout
+	Timeout time.Duration
+}
+
+type hello struct {
+	Protocol int    `json:"protocol"`
+	Checksum string `json:"checksum"`
+	Nonce    string `json:"nonce,omitempty"`
+	MAC      string `json:"mac,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Client performs the host side of the handshake on conn and returns the
+// connection to use for calls, which is conn wrapped in TLS when configured.
+func (h Handshake) Client(conn net.Conn) (net.Conn, error) {
+	return h.run(conn, true)
+}
+
+// Server performs the plugin side of the handshake on conn and returns the
+// connection to serve calls on.
+func (h Handshake) Server(conn net.Conn) (net.Conn, error) {
+	return h.run(conn, false)
+}
+
+func (h Handshake) run(conn net.Conn, host bool) (net.Conn, error) {
+	if len(h.Secret) == 0 && h.TLS == nil {
+		return nil, fmt.Errorf("%w: no secret or TLS configured", ErrHandshake)
+	}
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = DefaultHandshakeTimeout
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if h.TLS != nil {
+		var tc *tls.Conn
+		if host {
+			tc = tls.Client(conn, h.TLS)
+		} else {
+			if h.TLS.ClientAuth != tls.RequireAndVerifyClientCert && h.TLS.GetConfigForClient == nil {
+				return nil, fmt.Errorf("%w: tls: ClientAuth must be RequireAndVerifyClientCert", ErrHandshake)
+			}
+			tc = tls.Server(conn, h.TLS)
+		}
+		if err := tc.Handshake(); err != nil {
+			return nil, fmt.Errorf("%w: tls: %v", ErrHandshake, err)
+		}
+		if !host && len(tc.ConnectionState().VerifiedChains) == 0 {
+			return nil, fmt.Errorf("%w: tls: host presented no verified certificate", ErrHandshake)
+		}
+		conn = tc
+	}
+
+	var err error
+	if host {
+		err = h.host(conn)
+	} else {
+		err = h.plugin(conn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// host sends a hello with a nonce, checks the plugin's reply and proof, and
+// sends its own proof.
+func (h Handshake) host(rw io.ReadWriter) error {
+	hostNonce, err := nonce()
+	if err != nil {
+		return err
+	}
+	if err := writeHello(rw, hello{Protocol: ProtocolVersion, Checksum: ProtocolChecksum, Nonce: hostNonce}); err != nil {
+		return err
+	}
+	reply, err := readHello(rw)
+	if err != nil {
+		return err
+	}
+	if err := checkProtocol(reply); err != nil {
+		return err
+	}
+	if len(h.Secret) > 0 {
+		if !hmac.Equal([]byte(reply.MAC), []byte(h.mac("plugin", hostNonce, reply.Nonce))) {
+			return fmt.Errorf("%w: plugin failed authentication", ErrHandshake)
+		}
+	}
+	return writeHello(rw, hello{Protocol: ProtocolVersion, Checksum: ProtocolChecksum, MAC: h.mac("host", hostNonce, reply.Nonce)})
+}
+
+// plugin answers the host's hello with its own nonce and proof, then checks
+// the host's proof.
+func (h Handshake) plugin(rw io.ReadWriter) error {
+	greeting, err := readHello(rw)
+	if err != nil {
+		return err
+	}
+	if err := checkProtocol(greeting); err != nil {
+		writeHello(rw, hello{Protocol: ProtocolVersion, Checksum: ProtocolChecksum, Error: err.Error()})
+		return err
+	}
+	pluginNonce, err := nonce()
+	if err != nil {
+		return err
+	}
+	reply := hello{Protocol: ProtocolVersion, Checksum: ProtocolChecksum, Nonce: pluginNonce, MAC: h.mac("plugin", greeting.Nonce, pluginNonce)}
+	if err := writeHello(rw, reply); err != nil {
+		return err
+	}
+	final, err := readHello(rw)
+	if err != nil {
+		return err
+	}
+	if len(h.Secret) > 0 && !hmac.Equal([]byte(final.MAC), []byte(h.mac("host", greeting.Nonce, pluginNonce))) {
+		return fmt.Errorf("%w: host failed authentication", ErrHandshake)
+	}
+	return nil
+}
+
+func checkProtocol(m hello) error {
+	if m.Error != "" {
+		return fmt.Errorf("%w: peer: %s", ErrHandshake, m.Error)
+	}
+	if m.Protocol != ProtocolVersion {
+		return fmt.Errorf("%w: protocol version %d, want %d", ErrHandshake, m.Protocol, ProtocolVersion)
+	}
+	if m.Checksum != ProtocolChecksum {
+		return fmt.Errorf("%w: protocol checksum mismatch", ErrHandshake)
+	}
+	return nil
+}
+
+// mac proves knowledge of the secret for one side of the exchange. The role
+// label stops one side's proof being replayed as the other's.
+func (h Handshake) mac(role, hostNonce, pluginNonce string) string {
+	if len(h.Secret) == 0 {
+		return ""
+	}
+	m := hmac.New(sha256.New, h.Secret)
+	fmt.Fprintf(m, "%s\x00%s\x00%s\x00%s", role, hostNonce, pluginNonce, ProtocolChecksum)
+	return hex.EncodeToString(m.Sum(nil))
+}
+
+func nonce() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("plugin: nonce: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// maxHelloBytes bounds a handshake message.
+const maxHelloBytes = 4096
+
+func writeHello(w io.Writer, m hello) error {
+	b, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrHandshake, err)
+	}
+	return nil
+}
+
+// readHello reads one newline-terminated message a byte at a time, so no
+// bytes following the handshake are consumed.
+func readHello(r io.Reader) (hello, error) {
+	var line []byte
+	b := make([]byte, 1)
+	for {
+		if _, err := io.ReadFull(r, b); err != nil {
+			return hello{}, fmt.Errorf("%w: %v", ErrHandshake, err)
+		}
+		if b[0] == '\n' {
+			break
+		}
+		if len(line) >= maxHelloBytes {
+			return hello{}, fmt.Errorf("%w: message too long", ErrHandshake)
+		}
+		line = append(line, b[0])
+	}
+	var m hello
+	if err := json.Unmarshal(line, &m); err != nil {
+		return hello{}, fmt.Errorf("%w: %v", ErrHandshake, err)
+	}
+	return m, nil
+}
+
+// VerifyBinary checks that the file at path has the given hex-encoded
+// SHA-256 checksum. Hosts call it before launching a plugin binary so that
+// only known builds run.
+func VerifyBinary(path, checksum string) error {
+	want, err := hex.DecodeString(checksum)
+	if err != nil || len(want) != sha256.Size {
+		return fmt.Errorf("plugin: invalid checksum %q", checksum)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("plugin: %w", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return fmt.Errorf("plugin: %s: %w", path, err)
+	}
+	if !bytes.Equal(h.Sum(nil), want) {
+		return fmt.Errorf("plugin: %s: checksum mismatch", path)
+	}
+	return nil
+}
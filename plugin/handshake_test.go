@@ -0,0 +1,195 @@
+package plugin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// handshake runs both sides over a loopback connection and returns their
+// results.
+func handshake(t *testing.T, host, plugin Handshake) (hostConn net.Conn, hostErr, pluginErr error, pluginConn net.Conn) {
+	t.Helper()
+	a, b := loopback(t)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		pluginConn, pluginErr = plugin.Server(b)
+		if pluginErr != nil {
+			b.Close()
+		}
+	}()
+	hostConn, hostErr = host.Client(a)
+	if hostErr != nil {
+		a.Close()
+	}
+	<-done
+	return hostConn, hostErr, pluginErr, pluginConn
+}
+
+func loopback(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	a, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { a.Close(); b.Close() })
+	return a, b
+}
+
+func TestSharedSecretHandshake(t *testing.T) {
+	cfg := Handshake{Secret: []byte("s3cret")}
+	hc, herr, perr, pc := handshake(t, cfg, cfg)
+	if herr != nil || perr != nil {
+		t.Fatalf("handshake failed: host %v, plugin %v", herr, perr)
+	}
+
+	// Bytes written after the handshake must reach the other side intact.
+	go hc.Write([]byte("ping\n"))
+	buf := make([]byte, 5)
+	if _, err := io.ReadFull(pc, buf); err != nil || string(buf) != "ping\n" {
+		t.Errorf("post-handshake read = %q, %v", buf, err)
+	}
+}
+
+func TestWrongSecretRejected(t *testing.T) {
+	_, herr, _, _ := handshake(t, Handshake{Secret: []byte("host")}, Handshake{Secret: []byte("plugin")})
+	if !errors.Is(herr, ErrHandshake) || !strings.Contains(herr.Error(), "plugin failed authentication") {
+		t.Errorf("host error = %v", herr)
+	}
+}
+
+func TestNoAuthenticationConfigured(t *testing.T) {
+	a, _ := net.Pipe()
+	defer a.Close()
+	if _, err := (Handshake{}).Client(a); !errors.Is(err, ErrHandshake) {
+		t.Errorf("expected ErrHandshake, got %v", err)
+	}
+}
+
+func TestProtocolChecksumMismatch(t *testing.T) {
+	a, b := net.Pipe()
+	defer a.Close()
+	errc := make(chan error, 1)
+	go func() {
+		_, err := Handshake{Secret: []byte("s")}.Server(b)
+		errc <- err
+	}()
+
+	writeHello(a, hello{Protocol: ProtocolVersion, Checksum: "stale", Nonce: "n"})
+	reply, _ := readHello(a)
+	if err := <-errc; err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("plugin error = %v", err)
+	}
+	if !strings.Contains(reply.Error, "checksum mismatch") {
+		t.Errorf("host was not told why: %+v", reply)
+	}
+}
+
+func TestMutualTLSHandshake(t *testing.T) {
+	ca, caKey := newCert(t, nil, nil, "ca")
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	hostCert := tlsCert(t, ca, caKey, "host")
+	pluginCert := tlsCert(t, ca, caKey, "plugin")
+
+	host := Handshake{TLS: &tls.Config{Certificates: []tls.Certificate{hostCert}, RootCAs: pool, ServerName: "plugin"}}
+	plugin := Handshake{TLS: &tls.Config{Certificates: []tls.Certificate{pluginCert}, ClientCAs: pool, ClientAuth: tls.RequireAndVerifyClientCert}}
+	if _, herr, perr, _ := handshake(t, host, plugin); herr != nil || perr != nil {
+		t.Fatalf("handshake failed: host %v, plugin %v", herr, perr)
+	}
+
+	// A host without a client certificate is refused.
+	host.TLS = &tls.Config{RootCAs: pool, ServerName: "plugin"}
+	if _, _, perr, _ := handshake(t, host, plugin); !errors.Is(perr, ErrHandshake) {
+		t.Errorf("plugin accepted a host without a certificate: %v", perr)
+	}
+
+	// So is any host when the plugin side does not verify certificates,
+	// whether set directly or per connection.
+	weak := tls.Config{Certificates: []tls.Certificate{pluginCert}, ClientAuth: tls.RequestClientCert}
+	plugin.TLS = &weak
+	if _, _, perr, _ := handshake(t, host, plugin); !errors.Is(perr, ErrHandshake) || !strings.Contains(perr.Error(), "ClientAuth") {
+		t.Errorf("plugin without client verification = %v", perr)
+	}
+	plugin.TLS = &tls.Config{GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) { return &weak, nil }}
+	if _, _, perr, _ := handshake(t, host, plugin); !errors.Is(perr, ErrHandshake) || !strings.Contains(perr.Error(), "no verified certificate") {
+		t.Errorf("plugin without client verification per connection = %v", perr)
+	}
+}
+
+func newCert(t *testing.T, parent *x509.Certificate, parentKey *ecdsa.PrivateKey, name string) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	if parent == nil {
+		tmpl.IsCA, tmpl.BasicConstraintsValid = true, true
+		parent, parentKey = tmpl, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key
+}
+
+func tlsCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey, name string) tls.Certificate {
+	cert, key := newCert(t, ca, caKey, name)
+	return tls.Certificate{Certificate: [][]byte{cert.Raw}, PrivateKey: key, Leaf: cert}
+}
+
+func TestVerifyBinary(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plugin")
+	os.WriteFile(path, []byte("binary"), 0o755)
+	sum := sha256.Sum256([]byte("binary"))
+
+	if err := VerifyBinary(path, hex.EncodeToString(sum[:])); err != nil {
+		t.Errorf("VerifyBinary failed: %v", err)
+	}
+	other := sha256.Sum256([]byte("tampered"))
+	if err := VerifyBinary(path, hex.EncodeToString(other[:])); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+	if err := VerifyBinary(path, "xyz"); err == nil {
+		t.Error("expected invalid checksum error")
+	}
+}
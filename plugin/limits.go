@@ -0,0 +1,78 @@
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Limits bounds the resources a launched plugin may use. Zero fields are
+// unlimited. Memory and CPU limits are enforced on Linux only; Launch fails
+// on other platforms if they are set.
+type Limits struct {
+	// MemoryBytes caps the plugin's memory. With CgroupParent it limits
+	// resident memory (cgroup memory.max); otherwise it limits the address
+	// space (RLIMIT_AS)
+	MemoryBytes int64
+
+	// CPUs caps CPU usage as a number of CPUs, e.g. 0.5 for half a core
+	// Requires CgroupParent
+	CPUs float64
+
+	// CPUTime caps the plugin's total CPU time (RLIMIT_CPU); the process is
+	// killed when it is exceeded
+	CPUTime time.Duration
+
+	// CallTimeout bounds the wall-clock time of each call
+	CallTimeout time.Duration
+
+	// CgroupParent is a cgroup v2 directory delegated to the host process
+	// (e.g. /sys/fs/cgroup/locus-plugins). A child cgroup is created in it
+	// for each launched plugin and removed when the plugin exits
+	CgroupParent string
+}
+
+// Limit names a resource limit.
+type Limit string
+
+// Limits that can be exceeded.
+const (
+	LimitMemory    Limit = "memory"
+	LimitCPUTime   Limit = "cpu_time"
+	LimitWallClock Limit = "wall_clock"
+)
+
+// LimitError reports that a plugin exceeded a resource limit. It wraps
+// datasource.ErrUnavailable, since the plugin was stopped or the call
+// abandoned.
+type LimitError struct {
+	// Limit is the limit that was exceeded
+	Limit Limit
+
+	// Detail describes the violation
+	Detail string
+}
+
+// Error implements the error interface.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("plugin: %s limit exceeded: %s", e.Limit, e.Detail)
+}
+
+// Unwrap returns datasource.ErrUnavailable.
+func (e *LimitError) Unwrap() error {
+	return datasource.ErrUnavailable
+}
+
+// sandbox applies Limits to a plugin process. Implementations are platform
+// specific.
+type sandbox interface {
+	// started is called with the running process's PID.
+	started(pid int) error
+
+	// exited reports a limit violation that explains the process's exit,
+	// or nil, and releases any resources. state is nil if the process
+	// failed to start.
+	exited(state *os.ProcessState) error
+}
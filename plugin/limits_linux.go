@@ -0,0 +1,140 @@
+//go:build linux
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+type linuxSandbox struct {
+	limits Limits
+	cgroup string
+	fd     *os.File
+}
+
+// newSandbox prepares cmd to run under limits.
+func newSandbox(cmd *exec.Cmd, limits Limits) (sandbox, error) {
+	s := &linuxSandbox{limits: limits}
+	if limits.CPUs > 0 && limits.CgroupParent == "" {
+		return nil, fmt.Errorf("plugin: Limits.CPUs requires Limits.CgroupParent")
+	}
+	if limits.CgroupParent == "" {
+		return s, nil
+	}
+
+	dir, err := os.MkdirTemp(limits.CgroupParent, "plugin-")
+	if err != nil {
+		return nil, fmt.Errorf("plugin: create cgroup: %w", err)
+	}
+	s.cgroup = dir
+	if limits.MemoryBytes > 0 {
+		if err := s.write("memory.max", strconv.FormatInt(limits.MemoryBytes, 10)); err != nil {
+			return nil, s.cleanup(err)
+		}
+		// Without swap limits the memory limit would only push the plugin
+		// into swap.
+		s.write("memory.swap.max", "0")
+	}
+	if limits.CPUs > 0 {
+		const period = 100000
+		quota := int64(limits.CPUs * period)
+		if err := s.write("cpu.max", fmt.Sprintf("%d %d", max(quota, 1000), period)); err != nil {
+			return nil, s.cleanup(err)
+		}
+	}
+	if s.fd, err = os.Open(dir); err != nil {
+		return nil, s.cleanup(err)
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(s.fd.Fd())
+	return s, nil
+}
+
+func (s *linuxSandbox) write(file, value string) error {
+	if err := os.WriteFile(filepath.Join(s.cgroup, file), []byte(value), 0); err != nil {
+		return fmt.Errorf("plugin: set %s: %w", file, err)
+	}
+	return nil
+}
+
+func (s *linuxSandbox) cleanup(err error) error {
+	if s.fd != nil {
+		s.fd.Close()
+	}
+	if s.cgroup != "" {
+		os.Remove(s.cgroup)
+	}
+	return err
+}
+
+func (s *linuxSandbox) started(pid int) error {
+	if s.fd != nil {
+		s.fd.Close()
+		s.fd = nil
+	}
+	if s.limits.MemoryBytes > 0 && s.cgroup == "" {
+		if err := prlimit(pid, syscall.RLIMIT_AS, uint64(s.limits.MemoryBytes)); err != nil {
+			return err
+		}
+	}
+	if s.limits.CPUTime > 0 {
+		secs := uint64((s.limits.CPUTime + time.Second - 1) / time.Second)
+		if err := prlimit(pid, syscall.RLIMIT_CPU, secs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *linuxSandbox) exited(state *os.ProcessState) error {
+	if state == nil {
+		return s.cleanup(nil)
+	}
+	var violation error
+	cpu := state.UserTime() + state.SystemTime()
+	ws, _ := state.Sys().(syscall.WaitStatus)
+	killed := ws.Signaled() && ws.Signal() == syscall.SIGKILL
+	if s.cgroup != "" {
+		if b, err := os.ReadFile(filepath.Join(s.cgroup, "memory.events")); err == nil && oomKilled(string(b)) {
+			violation = &LimitError{Limit: LimitMemory, Detail: fmt.Sprintf("killed at memory.max=%d", s.limits.MemoryBytes)}
+		}
+	}
+	// Accounted CPU time can fall a tick short of the RLIMIT_CPU that
+	// triggered the kill, so allow some slack.
+	if violation == nil && killed && s.limits.CPUTime > 0 && cpu >= s.limits.CPUTime*9/10 {
+		violation = &LimitError{Limit: LimitCPUTime, Detail: fmt.Sprintf("killed after %v of CPU time", cpu.Round(time.Millisecond))}
+	}
+	s.cleanup(nil)
+	return violation
+}
+
+func oomKilled(events string) bool {
+	for _, line := range strings.Split(events, "\n") {
+		if f := strings.Fields(line); len(f) == 2 && f[0] == "oom_kill" && f[1] != "0" {
+			return true
+		}
+	}
+	return false
+}
+
+// prlimit sets both the soft and hard limit of resource for pid.
+func prlimit(pid, resource int, value uint64) error {
+	lim := syscall.Rlimit{Cur: value, Max: value}
+	_, _, errno := syscall.RawSyscall6(syscall.SYS_PRLIMIT64, uintptr(pid), uintptr(resource),
+		uintptr(unsafe.Pointer(&lim)), 0, 0, 0)
+	if errno != 0 {
+		return fmt.Errorf("plugin: prlimit: %w", errno)
+	}
+	return nil
+}
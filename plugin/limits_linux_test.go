@@ -0,0 +1,54 @@
+//go:build linux
+
+package plugin_test
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/locus-search/datasource-sdk/plugin"
+)
+
+func TestCPUTimeLimit(t *testing.T) {
+	if testing.Short() {
+		t.Skip("uses a second of CPU time")
+	}
+	c := launch(t, plugin.ClientConfig{
+		Handshake: plugin.Handshake{Secret: secret},
+		Limits:    plugin.Limits{CPUTime: time.Second},
+	})
+	_, err := c.FetchData(1, spinTopic)
+	var le *plugin.LimitError
+	if !errors.As(err, &le) || le.Limit != plugin.LimitCPUTime {
+		t.Errorf("FetchData = %v, want a CPU time LimitError", err)
+	}
+}
+
+// TestCgroupLimits needs a delegated cgroup v2 directory, named by
+// LOCUS_TEST_CGROUP, so it only runs where one is set up.
+func TestCgroupLimits(t *testing.T) {
+	parent := os.Getenv("LOCUS_TEST_CGROUP")
+	if parent == "" {
+		t.Skip("LOCUS_TEST_CGROUP not set")
+	}
+	c := launch(t, plugin.ClientConfig{
+		Handshake: plugin.Handshake{Secret: secret},
+		Limits:    plugin.Limits{MemoryBytes: 256 << 20, CPUs: 0.5, CgroupParent: parent},
+	})
+	if !c.CheckAvailability() {
+		t.Error("plugin not available inside the cgroup")
+	}
+}
+
+func TestCPUsRequiresCgroup(t *testing.T) {
+	_, err := plugin.Launch(plugin.ClientConfig{
+		Cmd:       helperCmd(),
+		Handshake: plugin.Handshake{Secret: secret},
+		Limits:    plugin.Limits{CPUs: 1},
+	})
+	if err == nil {
+		t.Error("expected an error for CPUs without CgroupParent")
+	}
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package plugin
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+)
+
+type noSandbox struct{}
+
+// newSandbox rejects memory and CPU limits, which are only enforced on
+// Linux.
+func newSandbox(_ *exec.Cmd, limits Limits) (sandbox, error) {
+	if limits.MemoryBytes > 0 || limits.CPUs > 0 || limits.CPUTime > 0 || limits.CgroupParent != "" {
+		return nil, fmt.Errorf("plugin: memory and CPU limits are only supported on Linux")
+	}
+	return noSandbox{}, nil
+}
+
+func (noSandbox) started(int) error             { return nil }
+func (noSandbox) exited(*os.ProcessState) error { return nil }
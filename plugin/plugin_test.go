@@ -0,0 +1,173 @@
+package plugin_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/plugin"
+)
+
+var secret = []byte("test-secret")
+
+// Topic IDs for which the helper plugin misbehaves.
+const (
+	spinTopic  = 1000
+	slowTopic  = 1001
+	crashTopic = 1002
+)
+
+// TestMain lets the test binary act as a plugin when relaunched by a test.
+func TestMain(m *testing.M) {
+	if os.Getenv("PLUGIN_TEST_HELPER") == "1" {
+		fake := &datasourcetest.Fake{
+			Topics: []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 1}, {Topic: "TLS", TopicID: 2}},
+			DataFunc: func(count int, topicID int64) ([]datasource.DataSourceData, error) {
+				switch {
+				case topicID <= 0:
+					return nil, fmt.Errorf("topic %d: %w", topicID, datasource.ErrNotFound)
+				case topicID == spinTopic:
+					for {
+					}
+				case topicID == slowTopic:
+					time.Sleep(time.Second)
+				case topicID == crashTopic:
+					os.Exit(3)
+				}
+				data := []datasource.DataSourceData{{DataText: "answer", AnswerID: topicID * 10}}
+				return data[:max(0, min(count, 1))], nil
+			},
+		}
+		if err := plugin.Serve(fake, plugin.Handshake{Secret: secret}); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func helperCmd() *exec.Cmd {
+	cmd := exec.Command(os.Args[0])
+	cmd.Env = append(os.Environ(), "PLUGIN_TEST_HELPER=1")
+	return cmd
+}
+
+func launch(t *testing.T, cfg plugin.ClientConfig) *plugin.Client {
+	t.Helper()
+	if cfg.Cmd == nil {
+		cfg.Cmd = helperCmd()
+	}
+	c, err := plugin.Launch(cfg)
+	if err != nil {
+		t.Fatalf("Launch: %v", err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func TestPluginConformance(t *testing.T) {
+	c := launch(t, plugin.ClientConfig{Handshake: plugin.Handshake{Secret: secret}})
+	datasourcetest.Config{Queries: []string{"what is dns"}}.Run(t, c)
+}
+
+func TestPluginPreservesErrorClass(t *testing.T) {
+	c := launch(t, plugin.ClientConfig{Handshake: plugin.Handshake{Secret: secret}})
+	_, err := c.FetchData(3, -5)
+	if !errors.Is(err, datasource.ErrNotFound) || err.Error() != "topic -5: datasource: not found" {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestPluginClose(t *testing.T) {
+	c := launch(t, plugin.ClientConfig{Handshake: plugin.Handshake{Secret: secret}})
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-c.Exited():
+	case <-time.After(5 * time.Second):
+		t.Fatal("plugin process did not exit")
+	}
+	if _, err := c.FetchTopics(1, datasource.NewQuestionInput{QuestionText: "q"}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("call after Close = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestLaunchRejectsUntrustedPlugins(t *testing.T) {
+	if _, err := plugin.Launch(plugin.ClientConfig{Cmd: helperCmd(), Handshake: plugin.Handshake{Secret: []byte("wrong")}}); !errors.Is(err, plugin.ErrHandshake) {
+		t.Errorf("wrong secret: %v", err)
+	}
+	sum := strings.Repeat("0", 64)
+	if _, err := plugin.Launch(plugin.ClientConfig{Cmd: helperCmd(), Handshake: plugin.Handshake{Secret: secret}, Checksum: sum}); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("bad checksum: %v", err)
+	}
+}
+
+func TestServeRequiresHost(t *testing.T) {
+	t.Setenv(plugin.EnvProtocol, "")
+	if err := plugin.Serve(&datasourcetest.Fake{}, plugin.Handshake{Secret: secret}); err == nil {
+		t.Error("Serve should refuse to run without a host")
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	c := launch(t, plugin.ClientConfig{
+		Handshake: plugin.Handshake{Secret: secret},
+		Limits:    plugin.Limits{CallTimeout: 50 * time.Millisecond},
+	})
+	_, err := c.FetchData(1, slowTopic)
+	var le *plugin.LimitError
+	if !errors.As(err, &le) || le.Limit != plugin.LimitWallClock || !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchData = %v, want a wall clock LimitError", err)
+	}
+}
+
+func TestSupervisorRestartsCrashedPlugin(t *testing.T) {
+	launches := 0
+	s := plugin.Supervise(plugin.SupervisorConfig{
+		Launch: func() (*plugin.Client, error) {
+			launches++
+			return plugin.Launch(plugin.ClientConfig{Cmd: helperCmd(), Handshake: plugin.Handshake{Secret: secret}})
+		},
+		InitialBackoff: 10 * time.Millisecond,
+		FlapThreshold:  2,
+	})
+	if err := s.Init(); err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for crash := 1; crash <= 2; crash++ {
+		if _, err := s.FetchData(1, crashTopic); !errors.Is(err, datasource.ErrUnavailable) {
+			t.Fatalf("crash %d: FetchData = %v", crash, err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for st := s.Status(); st.Restarts < crash || !st.Running; st = s.Status() {
+			if time.Now().After(deadline) {
+				t.Fatalf("plugin not restarted after crash %d: %+v", crash, s.Status())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	if data, err := s.FetchData(1, 1); err != nil || len(data) != 1 {
+		t.Errorf("FetchData after restart = %v, %v", data, err)
+	}
+	st := s.Status()
+	if launches != 3 || st.Restarts != 2 || !st.Flapping || st.LastError == nil {
+		t.Errorf("launches %d, status %+v", launches, st)
+	}
+	if s.CheckAvailability() {
+		t.Error("a flapping plugin should not report available")
+	}
+	if h := s.HealthCheck(); h.State != datasource.Unhealthy || h.Error == "" {
+		t.Errorf("HealthCheck = %+v, want unhealthy with the crash reason", h)
+	}
+}
@@ -0,0 +1,111 @@
+package plugin
+
+import (
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/wire"
+)
+
+// The plugin protocol is JSON-RPC 1.0 (as implemented by net/rpc/jsonrpc)
+// over the connection left after the handshake, so plugins can be written
+// in any language. The service is named ServiceName and mirrors the
+// DataSource interface; each method takes one params object and returns
+// one result object:
+//
+//	Plugin.Init              {}                              -> {"error"}
+//	Plugin.CheckAvailability {}                              -> {"available"}
+//	Plugin.Capabilities      {}                              -> {"capabilities"}
+//	Plugin.FetchTopics       {"count", "input"}              -> {"topics", "total_estimate", "error"}
+//	Plugin.FetchData         {"count", "topic_id"}           -> {"data", "error"}
+//
+// Data source errors are returned in the result's "error" field as
+// {"class", "message", "retry_after_ms"} so their class survives the
+// process boundary; the JSON-RPC error member is reserved for transport
+// failures.
+//
+// A plugin process announces where it is listening by printing one line to
+// stdout before anything else:
+//
+//	LOCUS_PLUGIN|<protocol version>|<network>|<address>
+
+// ServiceName is the JSON-RPC service name.
+const ServiceName = "Plugin"
+
+// Empty is the params object for methods without arguments.
+type Empty struct{}
+
+// InitResult is the result of Plugin.Init.
+type InitResult struct {
+	Error *wire.Error `json:"error,omitempty"`
+}
+
+// AvailabilityResult is the result of Plugin.CheckAvailability.
+type AvailabilityResult struct {
+	Available bool `json:"available"`
+}
+
+// CapabilitiesResult is the result of Plugin.Capabilities. Plugins built
+// before the method existed do not implement it; clients treat that as no
+// capabilities.
+type CapabilitiesResult struct {
+	Capabilities datasource.Capabilities `json:"capabilities"`
+}
+
+// FetchTopicsArgs are the params of Plugin.FetchTopics.
+type FetchTopicsArgs struct {
+	Count int                         `json:"count"`
+	Input datasource.NewQuestionInput `json:"input"`
+}
+
+// FetchTopicsResult is the result of Plugin.FetchTopics.
+type FetchTopicsResult struct {
+	Topics []datasource.DataSourceTopic `json:"topics"`
+
+	// TotalEstimate is the match estimate the source recorded on the
+	// input's Trace; zero if it gave none
+	TotalEstimate int64       `json:"total_estimate,omitempty"`
+	Error         *wire.Error `json:"error,omitempty"`
+}
+
+// FetchDataArgs are the params of Plugin.FetchData.
+type FetchDataArgs struct {
+	Count   int   `json:"count"`
+	TopicID int64 `json:"topic_id"`
+}
+
+// FetchDataResult is the result of Plugin.FetchData.
+type FetchDataResult struct {
+	Data  []datasource.DataSourceData `json:"data"`
+	Error *wire.Error                 `json:"error,omitempty"`
+}
+
+// service exposes a DataSource over net/rpc.
+type service struct {
+	ds datasource.DataSource
+}
+
+func (s *service) Init(_ Empty, r *InitResult) error {
+	r.Error = wire.EncodeError(s.ds.Init())
+	return nil
+}
+
+func (s *service) CheckAvailability(_ Empty, r *AvailabilityResult) error {
+	r.Available = s.ds.CheckAvailability()
+	return nil
+}
+
+func (s *service) Capabilities(_ Empty, r *CapabilitiesResult) error {
+	r.Capabilities = datasource.CapabilitiesOf(s.ds)
+	return nil
+}
+
+func (s *service) FetchTopics(args FetchTopicsArgs, r *FetchTopicsResult) error {
+	page, err := datasource.FetchTopicsPage(s.ds, args.Count, args.Input)
+	r.Topics, r.TotalEstimate, r.Error = datasource.FillTopics(page.Topics), page.TotalEstimate, wire.EncodeError(err)
+	return nil
+}
+
+func (s *service) FetchData(args FetchDataArgs, r *FetchDataResult) error {
+	data, err := s.ds.FetchData(args.Count, args.TopicID)
+	r.Data, r.Error = data, wire.EncodeError(err)
+	return nil
+}
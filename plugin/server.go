@@ -0,0 +1,68 @@
+package plugin
+
+import (
+	"fmt"
+	"net"
+	"net/rpc"
+	"net/rpc/jsonrpc"
+	"os"
+	"strconv"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// EnvProtocol is set by the host when it launches a plugin. Serve refuses
+// to run without it, so a plugin binary started by hand explains itself
+// instead of waiting for a connection.
+const EnvProtocol = "LOCUS_PLUGIN_PROTOCOL"
+
+// announcePrefix starts the line a plugin prints to tell the host where it
+// is listening.
+const announcePrefix = "LOCUS_PLUGIN"
+
+// Serve runs ds as a plugin. It is called from a plugin binary's main
+// function, listens on a loopback port, announces the address on stdout,
+// and serves the first connection that completes the handshake until the
+// host disconnects. It returns an error if the binary was not launched by a
+// host.
+func Serve(ds datasource.DataSource, hs Handshake) error {
+	if v := os.Getenv(EnvProtocol); v != strconv.Itoa(ProtocolVersion) {
+		return fmt.Errorf("plugin: this binary is a Locus data source plugin and must be launched by a host (%s=%q)", EnvProtocol, v)
+	}
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return fmt.Errorf("plugin: listen: %w", err)
+	}
+	defer ln.Close()
+
+	fmt.Fprintf(os.Stdout, "%s|%d|%s|%s\n", announcePrefix, ProtocolVersion, ln.Addr().Network(), ln.Addr())
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return fmt.Errorf("plugin: accept: %w", err)
+		}
+		if err := ServeConn(ds, conn, hs); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			continue
+		}
+		return nil
+	}
+}
+
+// ServeConn performs the plugin side of the handshake on conn and then
+// serves ds on it until the connection closes. It returns an error only if
+// the handshake fails.
+func ServeConn(ds datasource.DataSource, conn net.Conn, hs Handshake) error {
+	c, err := hs.Server(conn)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	srv := rpc.NewServer()
+	if err := srv.RegisterName(ServiceName, &service{ds: ds}); err != nil {
+		c.Close()
+		return err
+	}
+	srv.ServeCodec(jsonrpc.NewServerCodec(c))
+	return nil
+}
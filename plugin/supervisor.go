@@ -0,0 +1,289 @@
+package plugin
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// SupervisorConfig configures Supervise. Zero durations and counts take
+// the documented defaults.
+type SupervisorConfig struct {
+	// Launch starts a new plugin process. It is called for the first start
+	// and after every crash, so it must build a fresh exec.Cmd each time
+	Launch func() (*Client, error)
+
+	// InitialBackoff is the delay before the first restart attempt
+	// Defaults to 500ms
+	InitialBackoff time.Duration
+
+	// MaxBackoff caps the doubling delay between restart attempts
+	// Defaults to 30s
+	MaxBackoff time.Duration
+
+	// FlapThreshold is the number of crashes within FlapWindow after which
+	// the plugin is reported as flapping
+	// Defaults to 5
+	FlapThreshold int
+
+	// FlapWindow is the period over which crashes are counted; a plugin
+	// that stays up this long also resets its restart backoff
+	// Defaults to 5m
+	FlapWindow time.Duration
+}
+
+func (c SupervisorConfig) withDefaults() SupervisorConfig {
+	if c.InitialBackoff <= 0 {
+		c.InitialBackoff = 500 * time.Millisecond
+	}
+	if c.MaxBackoff <= 0 {
+		c.MaxBackoff = 30 * time.Second
+	}
+	if c.FlapThreshold <= 0 {
+		c.FlapThreshold = 5
+	}
+	if c.FlapWindow <= 0 {
+		c.FlapWindow = 5 * time.Minute
+	}
+	return c
+}
+
+// SupervisorStatus describes a supervised plugin.
+type SupervisorStatus struct {
+	// Running reports whether a plugin process is connected
+	Running bool
+
+	// Restarts is the number of restart attempts since Init
+	Restarts int
+
+	// RecentCrashes is the number of crashes within FlapWindow
+	RecentCrashes int
+
+	// Flapping reports whether the plugin crashed FlapThreshold times
+	// within FlapWindow
+	Flapping bool
+
+	// LastError is the most recent crash or failed restart, if any
+	LastError error
+}
+
+// Supervisor is a DataSource that keeps a plugin process running: it
+// restarts the plugin with exponential backoff when it exits, and replays
+// Init on each new process. While the plugin is down, calls fail with an
+// error wrapping datasource.ErrUnavailable, and CheckAvailability reports
+// false while the plugin is down or flapping.
+type Supervisor struct {
+	cfg SupervisorConfig
+
+	mu       sync.RWMutex
+	client   *Client
+	crashes  []time.Time
+	restarts int
+	lastErr  error
+	started  bool
+
+	closed    chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+var _ datasource.DataSource = (*Supervisor)(nil)
+
+// Supervise returns a Supervisor for the plugin started by cfg.Launch. The
+// plugin is launched by Init.
+func Supervise(cfg SupervisorConfig) *Supervisor {
+	return &Supervisor{cfg: cfg.withDefaults(), closed: make(chan struct{})}
+}
+
+// Init launches and initializes the plugin, then supervises it until
+// Close. Calling Init again after success is a no-op.
+func (s *Supervisor) Init() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return nil
+	}
+	c, err := s.start()
+	if err != nil {
+		return err
+	}
+	s.client, s.started = c, true
+	s.wg.Add(1)
+	go s.watch(c)
+	return nil
+}
+
+// start launches a plugin and replays Init on it.
+func (s *Supervisor) start() (*Client, error) {
+	c, err := s.cfg.Launch()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Init(); err != nil {
+		c.Close()
+		return nil, fmt.Errorf("plugin: init: %w", err)
+	}
+	return c, nil
+}
+
+// watch waits for the current plugin to exit and restarts it.
+func (s *Supervisor) watch(c *Client) {
+	defer s.wg.Done()
+	backoff := s.cfg.InitialBackoff
+	up := time.Now()
+	for {
+		select {
+		case <-c.Exited():
+		case <-s.closed:
+			return
+		}
+		crashed := time.Now()
+		if crashed.Sub(up) >= s.cfg.FlapWindow {
+			backoff = s.cfg.InitialBackoff
+		}
+		s.mu.Lock()
+		s.client = nil
+		s.lastErr = fmt.Errorf("plugin: process exited")
+		if c.proc != nil && c.proc.err != nil {
+			s.lastErr = c.proc.err
+		}
+		s.crashes = append(s.crashes, crashed)
+		s.mu.Unlock()
+		c.Close()
+
+		for {
+			select {
+			case <-time.After(backoff):
+			case <-s.closed:
+				return
+			}
+			backoff = min(2*backoff, s.cfg.MaxBackoff)
+
+			next, err := s.start()
+			s.mu.Lock()
+			s.restarts++
+			if err != nil {
+				s.lastErr = err
+				s.mu.Unlock()
+				continue
+			}
+			select {
+			case <-s.closed:
+				s.mu.Unlock()
+				next.Close()
+				return
+			default:
+			}
+			s.client = next
+			s.mu.Unlock()
+			c, up = next, time.Now()
+			break
+		}
+	}
+}
+
+// Status reports the plugin's current state.
+func (s *Supervisor) Status() SupervisorStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cutoff := time.Now().Add(-s.cfg.FlapWindow)
+	recent := s.crashes[:0]
+	for _, t := range s.crashes {
+		if t.After(cutoff) {
+			recent = append(recent, t)
+		}
+	}
+	s.crashes = recent
+	return SupervisorStatus{
+		Running:       s.client != nil,
+		Restarts:      s.restarts,
+		RecentCrashes: len(recent),
+		Flapping:      len(recent) >= s.cfg.FlapThreshold,
+		LastError:     s.lastErr,
+	}
+}
+
+// HealthCheck reports the plugin as unhealthy while it is down or
+// flapping, degraded while it has crashed within FlapWindow, and otherwise
+// by its own CheckAvailability.
+func (s *Supervisor) HealthCheck() datasource.HealthStatus {
+	st := s.Status()
+	var h datasource.HealthStatus
+	switch {
+	case !st.Running || st.Flapping:
+		h.State = datasource.Unhealthy
+	case st.RecentCrashes > 0:
+		h.State = datasource.Degraded
+	default:
+		h.State = datasource.Healthy
+	}
+	if st.LastError != nil {
+		h.Error = st.LastError.Error()
+	}
+	if h.State != datasource.Unhealthy && !s.CheckAvailability() {
+		h.State = datasource.Unhealthy
+		if h.Error == "" {
+			h.Error = datasource.ErrUnavailable.Error()
+		}
+	}
+	return h
+}
+
+// Close stops supervision and the plugin process.
+func (s *Supervisor) Close() error {
+	s.closeOnce.Do(func() { close(s.closed) })
+	s.wg.Wait()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.client == nil {
+		return nil
+	}
+	err := s.client.Close()
+	s.client = nil
+	return err
+}
+
+func (s *Supervisor) current() (*Client, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.client == nil {
+		return nil, fmt.Errorf("plugin: not running: %w", datasource.ErrUnavailable)
+	}
+	return s.client, nil
+}
+
+func (s *Supervisor) CheckAvailability() bool {
+	if s.Status().Flapping {
+		return false
+	}
+	c, err := s.current()
+	return err == nil && c.CheckAvailability()
+}
+
+// Capabilities returns the running plugin's capabilities, or none while it
+// is down.
+func (s *Supervisor) Capabilities() datasource.Capabilities {
+	c, err := s.current()
+	if err != nil {
+		return datasource.Capabilities{}
+	}
+	return c.Capabilities()
+}
+
+func (s *Supervisor) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	c, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchTopics(count, input)
+}
+
+func (s *Supervisor) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	c, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	return c.FetchData(count, topicID)
+}
@@ -0,0 +1,227 @@
+// Package privacy detects personal data and secrets in text — email
+// addresses, phone numbers, API keys, and credit card numbers — and
+// redacts them, for deployments that must not send such data to a data
+// source or pass it on from one.
+//
+// Detectors match a pattern and, where the format has one, verify a
+// checksum, as the Luhn check of card numbers, so that arbitrary digit
+// runs are not taken for them. Wrap redacts the questions a data source
+// receives and the results it returns:
+//
+//	ds = privacy.Wrap(ds, privacy.Config{})
+//
+// Detection is heuristic: it finds the common formats of each kind, not
+// every one, and is no substitute for keeping such data out of sources.
+package privacy
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Kind is a kind of sensitive data.
+type Kind string
+
+// Kinds detected by the built-in detectors.
+const (
+	KindEmail      Kind = "email"
+	KindPhone      Kind = "phone"
+	KindAPIKey     Kind = "api_key"
+	KindCreditCard Kind = "credit_card"
+)
+
+// Match is a span of text a Detector matched.
+type Match struct {
+	Kind Kind
+
+	// Start and End are the byte offsets of the span
+	Start, End int
+}
+
+// Detector finds sensitive data of one Kind: the matches of Pattern that
+// are not joined to letters or digits on either side and that Valid, if
+// set, accepts.
+type Detector struct {
+	Kind    Kind
+	Pattern *regexp.Regexp
+
+	// Valid checks a match, as with a checksum
+	// Optional
+	Valid func(match string) bool
+}
+
+// Detect returns the matches in text, in order.
+func (d Detector) Detect(text string) []Match {
+	var matches []Match
+	for _, loc := range d.Pattern.FindAllStringIndex(text, -1) {
+		if joined(text, loc[0], loc[1]) {
+			continue
+		}
+		if d.Valid != nil && !d.Valid(text[loc[0]:loc[1]]) {
+			continue
+		}
+		matches = append(matches, Match{Kind: d.Kind, Start: loc[0], End: loc[1]})
+	}
+	return matches
+}
+
+// joined reports whether text[start:end] continues a word or number.
+func joined(text string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	alnum := func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }
+	return (start > 0 && alnum(before)) || (end < len(text) && alnum(after))
+}
+
+// Email detects email addresses.
+var Email = Detector{
+	Kind:    KindEmail,
+	Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+}
+
+// Phone detects phone numbers written in international form, with a
+// leading "+", or in the North American form "(415) 555-0123" or
+// "415-555-0123", with 9 to 15 digits, so dates and version numbers are
+// not taken for them.
+var Phone = Detector{
+	Kind: KindPhone,
+	Pattern: regexp.MustCompile(`\+\d{1,3}(?:[ .-]?\(?\d{1,4}\)?){2,5}` +
+		`|\(\d{3}\)[ .-]?\d{3}[ .-]\d{4}` +
+		`|\d{3}[.-]\d{3}[.-]\d{4}`),
+	Valid: func(s string) bool {
+		n := digits(s)
+		return n >= 9 && n <= 15
+	},
+}
+
+// APIKey detects API keys and access tokens by the prefixes of common
+// providers: OpenAI and Stripe ("sk-", "sk_live_"), AWS ("AKIA"), GitHub
+// ("ghp_", "github_pat_"), Slack ("xox"), and Google ("AIza").
+var APIKey = Detector{
+	Kind: KindAPIKey,
+	Pattern: regexp.MustCompile(`sk-[A-Za-z0-9_-]{20,}` +
+		`|[sr]k_(?:live|test)_[A-Za-z0-9]{16,}` +
+		`|AKIA[0-9A-Z]{16}` +
+		`|gh[pousr]_[A-Za-z0-9]{36,}` +
+		`|github_pat_[A-Za-z0-9_]{22,}` +
+		`|xox[abposr]-[A-Za-z0-9-]{10,}` +
+		`|AIza[0-9A-Za-z_-]{35}`),
+}
+
+// CreditCard detects payment card numbers: 13 to 19 digits, unbroken or
+// in groups of four (four, six, and five for American Express) separated
+// by spaces or hyphens, that pass the Luhn check.
+var CreditCard = Detector{
+	Kind: KindCreditCard,
+	Pattern: regexp.MustCompile(`\d{4} \d{6} \d{5}` +
+		`|\d{4}(?: \d{4}){2} \d{1,7}` +
+		`|\d{4}(?:-\d{4}){2}-\d{1,7}` +
+		`|\d{13,19}`),
+	Valid: func(s string) bool {
+		n := digits(s)
+		return n >= 13 && n <= 19 && luhn(s)
+	},
+}
+
+// Default returns the built-in detectors.
+func Default() []Detector {
+	return []Detector{Email, Phone, APIKey, CreditCard}
+}
+
+func digits(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= '0' && r <= '9' {
+			n++
+		}
+	}
+	return n
+}
+
+// luhn reports whether the digits of s pass the Luhn check.
+func luhn(s string) bool {
+	sum, double := 0, false
+	for i := len(s) - 1; i >= 0; i-- {
+		if s[i] < '0' || s[i] > '9' {
+			continue
+		}
+		d := int(s[i] - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// Redactor replaces the sensitive data its detectors find. The zero
+// Redactor uses the built-in detectors. A Redactor is safe for concurrent
+// use.
+type Redactor struct {
+	// Detectors find the data to redact
+	// Defaults to Default()
+	Detectors []Detector
+
+	// Replacement returns the text replacing a match
+	// Defaults to "[redacted:KIND]", as in "[redacted:email]"
+	Replacement func(m Match) string
+}
+
+// Detect returns the matches of every detector in text, in order. Of
+// overlapping matches, the first and then longest is kept.
+func (r Redactor) Detect(text string) []Match {
+	detectors := r.Detectors
+	if detectors == nil {
+		detectors = Default()
+	}
+	var all []Match
+	for _, d := range detectors {
+		all = append(all, d.Detect(text)...)
+	}
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Start != all[j].Start {
+			return all[i].Start < all[j].Start
+		}
+		return all[i].End > all[j].End
+	})
+	matches := all[:0]
+	end := 0
+	for _, m := range all {
+		if m.Start >= end {
+			matches = append(matches, m)
+			end = m.End
+		}
+	}
+	return matches
+}
+
+// Redact returns text with every match replaced, and the matches.
+func (r Redactor) Redact(text string) (string, []Match) {
+	matches := r.Detect(text)
+	if len(matches) == 0 {
+		return text, nil
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		b.WriteString(text[last:m.Start])
+		b.WriteString(r.replacement(m))
+		last = m.End
+	}
+	b.WriteString(text[last:])
+	return b.String(), matches
+}
+
+func (r Redactor) replacement(m Match) string {
+	if r.Replacement != nil {
+		return r.Replacement(m)
+	}
+	return fmt.Sprintf("[redacted:%s]", m.Kind)
+}
@@ -0,0 +1,108 @@
+package privacy
+
+import (
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Config configures Wrap.
+type Config struct {
+	// Redactor finds and replaces sensitive data
+	// Defaults to the zero Redactor, with the built-in detectors
+	Redactor Redactor
+
+	// OnRedact, if set, is called after a call had data redacted, with
+	// the matches, for audit logs and metrics. It does not receive the
+	// redacted text
+	OnRedact func(method datasource.Method, matches []Match)
+}
+
+// Wrap returns a DataSource that redacts sensitive data from the
+// QuestionText, Tags, and the Name, Text, and URL of the Attachments of
+// the questions ds receives, and from the Topic, Title, and BodyExcerpt of
+// the topics and the DataText of the data items it returns. Place it beneath any cache, so that only redacted results
+// are cached.
+//
+// Streaming sources are not streamed through Wrap: datasource.StreamData
+// falls back to FetchData, so every item is redacted.
+func Wrap(ds datasource.DataSource, cfg Config) datasource.DataSource {
+	return &redactedSource{DataSource: ds, cfg: cfg}
+}
+
+type redactedSource struct {
+	datasource.DataSource
+	cfg Config
+}
+
+func (s *redactedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	var asked []Match
+	input.QuestionText = s.redact(input.QuestionText, &asked)
+	if len(input.Tags) > 0 {
+		tags := make([]string, len(input.Tags))
+		for i, t := range input.Tags {
+			tags[i] = s.redact(t, &asked)
+		}
+		input.Tags = tags
+	}
+	if len(input.Attachments) > 0 {
+		// Pasted logs and stack traces often carry addresses and keys.
+		attachments := make([]datasource.Attachment, len(input.Attachments))
+		for i, a := range input.Attachments {
+			a.Name = s.redact(a.Name, &asked)
+			a.Text = s.redact(a.Text, &asked)
+			a.URL = s.redact(a.URL, &asked)
+			attachments[i] = a
+		}
+		input.Attachments = attachments
+	}
+	s.report(datasource.MethodFetchTopics, asked)
+
+	topics, err := s.DataSource.FetchTopics(count, input)
+	// Copy so results shared with the source, such as cached results, keep
+	// their original text.
+	if topics != nil {
+		topics = append([]datasource.DataSourceTopic{}, topics...)
+	}
+	var found []Match
+	for i := range topics {
+		t := &topics[i]
+		t.Topic = s.redact(t.Topic, &found)
+		t.Title = s.redact(t.Title, &found)
+		t.BodyExcerpt = s.redact(t.BodyExcerpt, &found)
+	}
+	s.report(datasource.MethodFetchTopics, found)
+	return topics, err
+}
+
+func (s *redactedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	data, err := s.DataSource.FetchData(count, topicID)
+	if data != nil {
+		data = append([]datasource.DataSourceData{}, data...)
+	}
+	var found []Match
+	for i := range data {
+		data[i].DataText = s.redact(data[i].DataText, &found)
+	}
+	s.report(datasource.MethodFetchData, found)
+	return data, err
+}
+
+// redact redacts text, adding its matches to found.
+func (s *redactedSource) redact(text string, found *[]Match) string {
+	if text == "" {
+		return text
+	}
+	text, matches := s.cfg.Redactor.Redact(text)
+	*found = append(*found, matches...)
+	return text
+}
+
+func (s *redactedSource) report(m datasource.Method, matches []Match) {
+	if len(matches) > 0 && s.cfg.OnRedact != nil {
+		s.cfg.OnRedact(m, matches)
+	}
+}
+
+// Unwrap returns the wrapped data source.
+func (s *redactedSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
@@ -0,0 +1,154 @@
+package datasource
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+// Projection controls which heavy fields of data items are sent to callers
+// on constrained links (mobile, edge). It can be chosen per request or fixed
+// per adapter. The zero value keeps every field.
+type Projection struct {
+	// OmitText clears DataText entirely
+	OmitText bool `json:"omit_text,omitempty"`
+
+	// MaxTextBytes truncates DataText to at most this many bytes, cut on a
+	// UTF-8 boundary. Zero means no limit
+	MaxTextBytes int `json:"max_text_bytes,omitempty"`
+
+	// OmitEntities clears Entities
+	OmitEntities bool `json:"omit_entities,omitempty"`
+
+	// OmitMetadata clears Metadata and Extra
+	OmitMetadata bool `json:"omit_metadata,omitempty"`
+
+	// OmitEmbeddings clears Embedding
+	OmitEmbeddings bool `json:"omit_embeddings,omitempty"`
+}
+
+// LiteProjection returns a projection for low-bandwidth callers that keeps
+// a short text preview and drops enrichment data and embeddings. Callers
+// can fetch the full items later with HydrateData.
+func LiteProjection() Projection {
+	return Projection{MaxTextBytes: 280, OmitEntities: true, OmitEmbeddings: true}
+}
+
+// Apply returns a copy of items with the projection applied. The input
+// slice is not modified.
+func (p Projection) Apply(items []DataSourceData) []DataSourceData {
+	out := make([]DataSourceData, len(items))
+	for i, d := range items {
+		switch {
+		case p.OmitText:
+			d.DataText = ""
+		case p.MaxTextBytes > 0:
+			d.DataText = truncateUTF8(d.DataText, p.MaxTextBytes)
+		}
+		if p.OmitEntities {
+			d.Entities = nil
+		}
+		if p.OmitMetadata {
+			d.Metadata, d.Extra = nil, nil
+		}
+		if p.OmitEmbeddings {
+			d.Embedding = nil
+		}
+		out[i] = d
+	}
+	return out
+}
+
+// DataRef is a lightweight reference to a data item that can be sent in
+// place of the full item and resolved later with HydrateData.
+type DataRef struct {
+	// TopicID is the topic the data item belongs to
+	TopicID int64 `json:"topic_id"`
+
+	// AnswerID identifies the data item within the topic
+	AnswerID int64 `json:"answer_id"`
+
+	// Position is the zero-based index of the item in the FetchData result
+	// it was taken from
+	Position int `json:"position"`
+
+	// SourceURL is the canonical URL of the data item
+	SourceURL string `json:"source_url"`
+
+	// Site identifies the specific site or subsection if applicable
+	Site string `json:"site,omitempty"`
+
+	// Preview is an optional short prefix of DataText
+	Preview string `json:"preview,omitempty"`
+
+	// TextBytes is the length of the full DataText in bytes
+	TextBytes int `json:"text_bytes"`
+}
+
+// Refs converts data items fetched for topicID into references, keeping at
+// most previewBytes bytes of each item's text as a preview.
+func Refs(topicID int64, items []DataSourceData, previewBytes int) []DataRef {
+	refs := make([]DataRef, len(items))
+	for i, d := range items {
+		refs[i] = DataRef{
+			TopicID:   topicID,
+			AnswerID:  d.AnswerID,
+			Position:  i,
+			SourceURL: d.SourceURL,
+			Site:      d.Site,
+			Preview:   truncateUTF8(d.DataText, previewBytes),
+			TextBytes: len(d.DataText),
+		}
+	}
+	return refs
+}
+
+// HydrateData resolves references back into full data items by calling
+// FetchData on ds once per distinct topic. Items are returned in the order of
+// refs. An error wrapping ErrNotFound is returned if a referenced item is no
+// longer returned by the source.
+func HydrateData(ds DataSource, refs []DataRef) ([]DataSourceData, error) {
+	counts := make(map[int64]int)
+	var topics []int64
+	for _, r := range refs {
+		if _, ok := counts[r.TopicID]; !ok {
+			topics = append(topics, r.TopicID)
+		}
+		counts[r.TopicID] = max(counts[r.TopicID], r.Position+1)
+	}
+
+	type key struct{ topic, answer int64 }
+	found := make(map[key]DataSourceData)
+	for _, topicID := range topics {
+		items, err := ds.FetchData(counts[topicID], topicID)
+		if err != nil {
+			return nil, fmt.Errorf("hydrate topic %d: %w", topicID, err)
+		}
+		for _, d := range items {
+			found[key{topicID, d.AnswerID}] = d
+		}
+	}
+
+	out := make([]DataSourceData, 0, len(refs))
+	for _, r := range refs {
+		d, ok := found[key{r.TopicID, r.AnswerID}]
+		if !ok {
+			return nil, fmt.Errorf("hydrate answer %d of topic %d: %w", r.AnswerID, r.TopicID, ErrNotFound)
+		}
+		out = append(out, d)
+	}
+	return out, nil
+}
+
+// truncateUTF8 returns at most n bytes of s without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	if n <= 0 {
+		return ""
+	}
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
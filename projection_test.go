@@ -0,0 +1,83 @@
+package datasource_test
+
+import (
+	"errors"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+type answersSource struct {
+	ExampleDataSource
+	answers map[int64][]datasource.DataSourceData
+	calls   []int
+}
+
+func (s *answersSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	s.calls = append(s.calls, count)
+	items := s.answers[topicID]
+	if len(items) > count {
+		items = items[:count]
+	}
+	return items, nil
+}
+
+func TestProjectionApply(t *testing.T) {
+	items := []datasource.DataSourceData{{
+		DataText: "héllo world",
+		Entities: []datasource.Entity{{ID: "x"}},
+	}}
+
+	lite := datasource.Projection{MaxTextBytes: 2, OmitEntities: true}.Apply(items)
+	if lite[0].DataText != "h" {
+		t.Errorf("expected truncation on a rune boundary, got %q", lite[0].DataText)
+	}
+	if lite[0].Entities != nil {
+		t.Error("expected entities to be omitted")
+	}
+	if items[0].DataText != "héllo world" || len(items[0].Entities) != 1 {
+		t.Error("Apply must not modify its input")
+	}
+
+	if got := (datasource.Projection{OmitMetadata: true}).Apply([]datasource.DataSourceData{{Metadata: datasource.Metadata{"k": 1}}}); got[0].Metadata != nil {
+		t.Error("expected metadata to be omitted")
+	}
+	if got := datasource.LiteProjection().Apply([]datasource.DataSourceData{{Embedding: []float64{0.1}}}); got[0].Embedding != nil {
+		t.Error("expected LiteProjection to omit embeddings")
+	}
+	if got := (datasource.Projection{OmitText: true}).Apply(items); got[0].DataText != "" {
+		t.Errorf("expected text to be omitted, got %q", got[0].DataText)
+	}
+	if got := (datasource.Projection{}).Apply(items); got[0].DataText != items[0].DataText {
+		t.Error("zero projection must keep all fields")
+	}
+}
+
+func TestRefsAndHydrateData(t *testing.T) {
+	src := &answersSource{answers: map[int64][]datasource.DataSourceData{
+		1: {{DataText: "first", AnswerID: 10}, {DataText: "second", AnswerID: 11}, {DataText: "third", AnswerID: 12}},
+		2: {{DataText: "other", AnswerID: 20}},
+	}}
+
+	refs := append(datasource.Refs(1, src.answers[1], 3), datasource.Refs(2, src.answers[2], 3)...)
+	if refs[1].Preview != "sec" || refs[1].TextBytes != 6 || refs[1].Position != 1 {
+		t.Errorf("unexpected ref: %+v", refs[1])
+	}
+
+	// Hydrate a subset in a different order.
+	got, err := datasource.HydrateData(src, []datasource.DataRef{refs[3], refs[2]})
+	if err != nil {
+		t.Fatalf("HydrateData failed: %v", err)
+	}
+	if len(got) != 2 || got[0].AnswerID != 20 || got[1].DataText != "third" {
+		t.Errorf("unexpected hydrated items: %+v", got)
+	}
+	if src.calls[0] != 1 || src.calls[1] != 3 {
+		t.Errorf("expected FetchData counts [1 3], got %v", src.calls)
+	}
+
+	delete(src.answers, 2)
+	if _, err := datasource.HydrateData(src, refs[3:]); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a vanished item, got %v", err)
+	}
+}
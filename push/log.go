@@ -0,0 +1,83 @@
+package push
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// logBatchSize bounds the changes a Log returns from one Changes call.
+const logBatchSize = 1000
+
+// Log is an Ingestor that keeps the latest changes pushed and serves them
+// as a datasource.SyncSource, so the host indexes pushed changes as it
+// does a source's own change feed. Use one Log per source.
+//
+// A Log only knows what was pushed since it was created, and forgets the
+// oldest changes beyond its size: a cursor older than those it holds gets
+// datasource.ErrCursorExpired, and the zero Cursor starts at the oldest
+// change held rather than listing every topic. The host should build its
+// index from the source itself and use the Log to keep it current.
+type Log struct {
+	size int
+
+	mu      sync.Mutex
+	first   int64 // sequence number of changes[0]
+	changes []datasource.Change
+}
+
+// NewLog returns a Log holding up to size changes. If size is not
+// positive, DefaultLogSize is used.
+func NewLog(size int) *Log {
+	if size <= 0 {
+		size = DefaultLogSize
+	}
+	return &Log{size: size}
+}
+
+// Ingest appends changes to the log.
+func (l *Log) Ingest(_ context.Context, _ string, changes []datasource.Change) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.changes = append(l.changes, changes...)
+	if over := len(l.changes) - l.size; over > 0 {
+		l.changes = append([]datasource.Change(nil), l.changes[over:]...)
+		l.first += int64(over)
+	}
+	return nil
+}
+
+// Changes returns the changes pushed since the cursor. Cursors are
+// sequence numbers of pushed changes.
+func (l *Log) Changes(ctx context.Context, since datasource.Cursor) (datasource.ChangeBatch, error) {
+	if err := ctx.Err(); err != nil {
+		return datasource.ChangeBatch{}, err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	seq := l.first
+	if since != "" {
+		n, err := strconv.ParseInt(string(since), 10, 64)
+		if err != nil || n < 0 {
+			return datasource.ChangeBatch{}, fmt.Errorf("push: bad cursor %q", since)
+		}
+		seq = n
+	}
+	end := l.first + int64(len(l.changes))
+	switch {
+	case seq < l.first:
+		return datasource.ChangeBatch{}, fmt.Errorf("push: cursor %d: %w", seq, datasource.ErrCursorExpired)
+	case seq > end:
+		return datasource.ChangeBatch{}, fmt.Errorf("push: cursor %d is past the end of the log", seq)
+	}
+	held := l.changes[seq-l.first:]
+	n := min(len(held), logBatchSize)
+	return datasource.ChangeBatch{
+		Changes: append([]datasource.Change{}, held[:n]...),
+		Next:    datasource.Cursor(strconv.FormatInt(seq+int64(n), 10)),
+		More:    n < len(held),
+	}, nil
+}
@@ -0,0 +1,213 @@
+// Package push receives webhooks from upstream systems, such as "answer
+// posted" or "article updated" notifications, so their changes reach
+// Locus in near-real time rather than at the next sync or query.
+//
+// NewHandler returns an http.Handler that verifies each request's
+// signature, decodes its payload into datasource.Change values, and passes
+// them to an Ingestor:
+//
+//	feed := push.NewLog(0)
+//	h, err := push.NewHandler(push.Config{
+//	    Source:   "kb",
+//	    Verifier: &push.HMAC{Secrets: [][]byte{secret}, Header: "X-Hub-Signature-256", Prefix: "sha256="},
+//	    Ingestor: feed,
+//	})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	http.Handle("/hooks/kb", h)
+//
+// Log is an Ingestor that keeps the changes it receives and serves them
+// as a datasource.SyncSource, so pushed changes feed the host's index the
+// same way as a source's own change feed.
+package push
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultMaxBodyBytes    = 1 << 20
+	DefaultSignatureHeader = "X-Signature"
+	DefaultMaxAge          = 5 * time.Minute
+	DefaultLogSize         = 10000
+)
+
+// Ingestor takes in the changes pushed by a source. Ingest should return
+// promptly, as the sender waits for the response; errors wrapping
+// datasource.ErrUnavailable or *datasource.ErrRateLimited ask the sender
+// to retry later.
+type Ingestor interface {
+	Ingest(ctx context.Context, source string, changes []datasource.Change) error
+}
+
+// IngestorFunc adapts a function to an Ingestor.
+type IngestorFunc func(ctx context.Context, source string, changes []datasource.Change) error
+
+// Ingest calls f.
+func (f IngestorFunc) Ingest(ctx context.Context, source string, changes []datasource.Change) error {
+	return f(ctx, source, changes)
+}
+
+// Decoder decodes the body of a webhook request into changes.
+type Decoder func(r *http.Request, body []byte) ([]datasource.Change, error)
+
+// Config configures NewHandler.
+type Config struct {
+	// Source names the source whose webhooks the handler receives, as
+	// passed to the Ingestor
+	Source string
+
+	// Verifier checks each request's signature; it is required unless
+	// AllowUnsigned is set
+	Verifier Verifier
+
+	// AllowUnsigned lets the handler run without a Verifier, accepting
+	// unsigned requests, which is only safe on a trusted network
+	AllowUnsigned bool
+
+	// Decode converts a request's body to changes, for senders with their
+	// own payload format
+	// Defaults to DecodeChanges
+	Decode Decoder
+
+	// Ingestor takes in the changes
+	Ingestor Ingestor
+
+	// MaxBodyBytes bounds request bodies; larger requests are refused
+	// Defaults to DefaultMaxBodyBytes
+	MaxBodyBytes int64
+}
+
+func (c Config) withDefaults() Config {
+	if c.Decode == nil {
+		c.Decode = DecodeChanges
+	}
+	if c.MaxBodyBytes <= 0 {
+		c.MaxBodyBytes = DefaultMaxBodyBytes
+	}
+	return c
+}
+
+// DecodeChanges decodes a JSON datasource.ChangeBatch, or a single
+// datasource.Change, ignoring the batch's cursor. Changes without a kind
+// are upserts.
+func DecodeChanges(_ *http.Request, body []byte) ([]datasource.Change, error) {
+	var payload struct {
+		datasource.Change
+		Changes []datasource.Change `json:"changes"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil, err
+	}
+	changes := payload.Changes
+	if changes == nil {
+		changes = []datasource.Change{payload.Change}
+	}
+	for i := range changes {
+		switch c := &changes[i]; c.Kind {
+		case "":
+			c.Kind = datasource.ChangeUpsert
+		case datasource.ChangeUpsert, datasource.ChangeDelete:
+		default:
+			return nil, fmt.Errorf("change %d: unknown kind %q", i, c.Kind)
+		}
+		if changes[i].Topic.TopicID == 0 {
+			return nil, fmt.Errorf("change %d: missing topic ID", i)
+		}
+	}
+	return changes, nil
+}
+
+// NewHandler returns an http.Handler receiving the webhooks of cfg.Source
+// by POST. It answers 202 with the number of changes accepted, 401 if the
+// signature does not verify, 400 if the body does not decode, 413 if it is
+// too large, and 429, 503, or 500 if the Ingestor fails. It returns an
+// error if cfg has no Ingestor, or no Verifier without AllowUnsigned.
+func NewHandler(cfg Config) (http.Handler, error) {
+	if cfg.Ingestor == nil {
+		return nil, errors.New("push: Ingestor is required")
+	}
+	if cfg.Verifier == nil && !cfg.AllowUnsigned {
+		return nil, errors.New("push: Verifier is required unless AllowUnsigned is set")
+	}
+	return &handler{cfg: cfg.withDefaults()}, nil
+}
+
+type handler struct {
+	cfg Config
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodPost) {
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, h.cfg.MaxBodyBytes+1))
+	switch {
+	case err != nil:
+		http.Error(w, "read body: "+err.Error(), http.StatusBadRequest)
+		return
+	case int64(len(body)) > h.cfg.MaxBodyBytes:
+		http.Error(w, fmt.Sprintf("body exceeds %d bytes", h.cfg.MaxBodyBytes), http.StatusRequestEntityTooLarge)
+		return
+	}
+	if h.cfg.Verifier != nil {
+		if err := h.cfg.Verifier.Verify(r, body); err != nil {
+			// The reason is not revealed to a sender that may be forging.
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+	}
+	changes, err := h.cfg.Decode(r, body)
+	if err != nil {
+		http.Error(w, "decode: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.cfg.Ingestor.Ingest(r.Context(), h.cfg.Source, changes); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(struct {
+		Accepted int `json:"accepted"`
+	}{len(changes)})
+}
+
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+// writeError answers a failure of the Ingestor.
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	var rl *datasource.ErrRateLimited
+	switch {
+	case errors.As(err, &rl):
+		status = http.StatusTooManyRequests
+		if rl.RetryAfter > 0 {
+			secs := (rl.RetryAfter + time.Second - 1) / time.Second
+			w.Header().Set("Retry-After", strconv.FormatInt(int64(secs), 10))
+		}
+	case errors.Is(err, datasource.ErrQuotaExceeded):
+		status = http.StatusTooManyRequests
+	case errors.Is(err, datasource.ErrUnavailable):
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, "ingest: "+err.Error(), status)
+}
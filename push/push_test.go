@@ -0,0 +1,188 @@
+package push_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/push"
+)
+
+func post(t *testing.T, h http.Handler, v *push.HMAC, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodPost, "/hooks/kb", strings.NewReader(body))
+	if v != nil {
+		v.Sign(r, []byte(body))
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	log := push.NewLog(0)
+	signer := &push.HMAC{Secrets: [][]byte{[]byte("s3cret")}, Header: "X-Hub-Signature-256", Prefix: "sha256="}
+	h, err := push.NewHandler(push.Config{Source: "kb", Verifier: signer, Ingestor: log, MaxBodyBytes: 512})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := post(t, h, signer, `{"changes": [{"topic": {"topic": "Reset a password", "topic_id": 1}}, {"kind": "delete", "topic": {"topic_id": 2}}]}`)
+	if w.Code != http.StatusAccepted || !strings.Contains(w.Body.String(), `"accepted":2`) {
+		t.Fatalf("POST = %d %s", w.Code, w.Body)
+	}
+	if w := post(t, h, signer, `{"topic": {"topic": "What is DNS?", "topic_id": 3}}`); w.Code != http.StatusAccepted {
+		t.Fatalf("POST of one change = %d %s", w.Code, w.Body)
+	}
+
+	var got []datasource.Change
+	next, err := datasource.Sync(context.Background(), log, "", func(b datasource.ChangeBatch) error {
+		got = append(got, b.Changes...)
+		return nil
+	})
+	if err != nil || next != "3" || len(got) != 3 {
+		t.Fatalf("Sync = %q, %v; changes %+v", next, err, got)
+	}
+	if got[0].Kind != datasource.ChangeUpsert || got[1].Kind != datasource.ChangeDelete || got[2].Topic.TopicID != 3 {
+		t.Errorf("changes %+v", got)
+	}
+
+	forger := &push.HMAC{Secrets: [][]byte{[]byte("guess")}, Header: "X-Hub-Signature-256", Prefix: "sha256="}
+	for _, tc := range []struct {
+		name   string
+		signer *push.HMAC
+		body   string
+		want   int
+	}{
+		{"unsigned", nil, `{"topic": {"topic_id": 4}}`, http.StatusUnauthorized},
+		{"forged", forger, `{"topic": {"topic_id": 4}}`, http.StatusUnauthorized},
+		{"malformed", signer, `{"topic":`, http.StatusBadRequest},
+		{"no topic ID", signer, `{"topic": {"topic": "x"}}`, http.StatusBadRequest},
+		{"unknown kind", signer, `{"kind": "move", "topic": {"topic_id": 4}}`, http.StatusBadRequest},
+		{"too large", signer, `{"topic": {"topic_id": 4, "topic": "` + strings.Repeat("x", 512) + `"}}`, http.StatusRequestEntityTooLarge},
+	} {
+		if w := post(t, h, tc.signer, tc.body); w.Code != tc.want {
+			t.Errorf("%s: POST = %d %s, want %d", tc.name, w.Code, w.Body, tc.want)
+		}
+	}
+	if batch, _ := log.Changes(context.Background(), next); len(batch.Changes) != 0 {
+		t.Errorf("refused requests were ingested: %+v", batch.Changes)
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/hooks/kb", nil))
+	if w.Code != http.StatusMethodNotAllowed || w.Header().Get("Allow") != http.MethodPost {
+		t.Errorf("GET = %d, Allow %q", w.Code, w.Header().Get("Allow"))
+	}
+}
+
+func TestHandlerRequiresIngestor(t *testing.T) {
+	if h, err := push.NewHandler(push.Config{Source: "kb", AllowUnsigned: true}); err == nil {
+		t.Errorf("NewHandler without an Ingestor = %v, want an error", h)
+	}
+}
+
+func TestHandlerRequiresVerifier(t *testing.T) {
+	if h, err := push.NewHandler(push.Config{Source: "kb", Ingestor: push.NewLog(0)}); err == nil {
+		t.Errorf("NewHandler without a Verifier = %v, want an error", h)
+	}
+}
+
+func TestHandlerIngestErrors(t *testing.T) {
+	for _, tc := range []struct {
+		err        error
+		want       int
+		retryAfter string
+	}{
+		{&datasource.ErrRateLimited{RetryAfter: 1500 * time.Millisecond}, http.StatusTooManyRequests, "2"},
+		{datasource.ErrUnavailable, http.StatusServiceUnavailable, ""},
+		{errors.New("disk full"), http.StatusInternalServerError, ""},
+	} {
+		var source string
+		h, err := push.NewHandler(push.Config{Source: "kb", Ingestor: push.IngestorFunc(func(_ context.Context, s string, _ []datasource.Change) error {
+			source = s
+			return tc.err
+		}), AllowUnsigned: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w := post(t, h, nil, `{"topic": {"topic_id": 1}}`)
+		if w.Code != tc.want || w.Header().Get("Retry-After") != tc.retryAfter || source != "kb" {
+			t.Errorf("%v: POST = %d, Retry-After %q, source %q", tc.err, w.Code, w.Header().Get("Retry-After"), source)
+		}
+	}
+}
+
+func TestHMAC(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	old := &push.HMAC{Secrets: [][]byte{[]byte("old")}, TimestampHeader: "X-Timestamp", Now: func() time.Time { return now }}
+	v := &push.HMAC{Secrets: [][]byte{[]byte("new"), []byte("old")}, TimestampHeader: "X-Timestamp", Now: func() time.Time { return now }}
+	body := []byte(`{"topic": {"topic_id": 1}}`)
+
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	old.Sign(r, body)
+	if r.Header.Get(push.DefaultSignatureHeader) == "" || r.Header.Get("X-Timestamp") != "1700000000" {
+		t.Fatalf("Sign set headers %v", r.Header)
+	}
+	if err := v.Verify(r, body); err != nil {
+		t.Errorf("Verify with a rotated-out secret = %v", err)
+	}
+	if err := v.Verify(r, []byte(`{"topic": {"topic_id": 2}}`)); !errors.Is(err, push.ErrSignature) {
+		t.Errorf("Verify of a changed body = %v, want ErrSignature", err)
+	}
+
+	now = now.Add(push.DefaultMaxAge + time.Second)
+	if err := v.Verify(r, body); !errors.Is(err, push.ErrSignature) {
+		t.Errorf("Verify of a replayed request = %v, want ErrSignature", err)
+	}
+	r.Header.Set("X-Timestamp", "1700000301")
+	if err := v.Verify(r, body); !errors.Is(err, push.ErrSignature) {
+		t.Errorf("Verify with a moved timestamp = %v, want ErrSignature", err)
+	}
+}
+
+func TestHMACRefusesEmptySecrets(t *testing.T) {
+	body := []byte(`{"topic": {"topic_id": 1}}`)
+	for _, secrets := range [][][]byte{nil, {[]byte{}}, {[]byte("s3cret"), nil}} {
+		// A request signed under an empty secret is forgeable by anyone.
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		forged := hmac.New(sha256.New, nil)
+		forged.Write(body)
+		r.Header.Set(push.DefaultSignatureHeader, hex.EncodeToString(forged.Sum(nil)))
+		v := &push.HMAC{Secrets: secrets}
+		if err := v.Verify(r, body); !errors.Is(err, push.ErrSignature) {
+			t.Errorf("Verify with secrets %q = %v, want ErrSignature", secrets, err)
+		}
+	}
+}
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+	log := push.NewLog(2)
+	for id := int64(1); id <= 3; id++ {
+		log.Ingest(ctx, "kb", []datasource.Change{{Kind: datasource.ChangeUpsert, Topic: datasource.DataSourceTopic{TopicID: id}}})
+	}
+	batch, err := log.Changes(ctx, "")
+	if err != nil || len(batch.Changes) != 2 || batch.Changes[0].Topic.TopicID != 2 || batch.Next != "3" || batch.More {
+		t.Fatalf("Changes from the start = %+v, %v", batch, err)
+	}
+	if batch, err := log.Changes(ctx, batch.Next); err != nil || len(batch.Changes) != 0 || batch.Next != "3" {
+		t.Errorf("Changes at the end = %+v, %v", batch, err)
+	}
+	if _, err := log.Changes(ctx, "0"); !errors.Is(err, datasource.ErrCursorExpired) {
+		t.Errorf("Changes from a forgotten change = %v, want ErrCursorExpired", err)
+	}
+	for _, bad := range []datasource.Cursor{"4", "x"} {
+		if _, err := log.Changes(ctx, bad); err == nil || errors.Is(err, datasource.ErrCursorExpired) {
+			t.Errorf("Changes(%q) = %v", bad, err)
+		}
+	}
+}
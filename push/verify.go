@@ -0,0 +1,140 @@
+package push
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrSignature is returned by a Verifier for a request whose signature is
+// missing, malformed, or wrong.
+var ErrSignature = errors.New("push: invalid signature")
+
+// Verifier checks that a webhook request comes from its sender. It is
+// given the request and its body, which has already been read.
+type Verifier interface {
+	Verify(r *http.Request, body []byte) error
+}
+
+// HMAC verifies requests signed with HMAC-SHA256 under a shared secret,
+// the scheme most webhook senders use. The signature is hex encoded in a
+// header, after an optional prefix such as "sha256=".
+type HMAC struct {
+	// Secrets are the shared secrets accepted. Listing a new secret
+	// alongside the old one lets senders switch without refused requests;
+	// Sign uses the first.
+	Secrets [][]byte
+
+	// Header carries the signature
+	// Defaults to DefaultSignatureHeader
+	Header string
+
+	// Prefix precedes the hex digest in the header
+	// Optional
+	Prefix string
+
+	// TimestampHeader carries the time the request was signed, in Unix
+	// seconds. When set, the signature covers the timestamp, a ".", and
+	// the body, and requests signed more than MaxAge away from now are
+	// refused, so a captured request cannot be replayed later
+	// Optional
+	TimestampHeader string
+
+	// MaxAge bounds the age of timestamped requests
+	// Defaults to DefaultMaxAge
+	MaxAge time.Duration
+
+	// Now returns the current time
+	// Defaults to time.Now
+	Now func() time.Time
+}
+
+// Verify returns an error wrapping ErrSignature unless the request carries
+// a valid signature of body under one of v.Secrets. Every request is
+// refused if v has no secrets or an empty one, as anyone could sign under
+// an empty secret.
+func (v *HMAC) Verify(r *http.Request, body []byte) error {
+	if len(v.Secrets) == 0 {
+		return fmt.Errorf("%w: no secrets configured", ErrSignature)
+	}
+	for _, secret := range v.Secrets {
+		if len(secret) == 0 {
+			return fmt.Errorf("%w: empty secret configured", ErrSignature)
+		}
+	}
+	header := v.Header
+	if header == "" {
+		header = DefaultSignatureHeader
+	}
+	sig, ok := strings.CutPrefix(r.Header.Get(header), v.Prefix)
+	if !ok || sig == "" {
+		return fmt.Errorf("%w: no %s header", ErrSignature, header)
+	}
+	mac, err := hex.DecodeString(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSignature, err)
+	}
+	var ts string
+	if v.TimestampHeader != "" {
+		ts = r.Header.Get(v.TimestampHeader)
+		secs, err := strconv.ParseInt(ts, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%w: bad %s header %q", ErrSignature, v.TimestampHeader, ts)
+		}
+		maxAge := v.MaxAge
+		if maxAge <= 0 {
+			maxAge = DefaultMaxAge
+		}
+		if age := v.now().Sub(time.Unix(secs, 0)); age > maxAge || age < -maxAge {
+			return fmt.Errorf("%w: signed %s ago", ErrSignature, age.Round(time.Second))
+		}
+	}
+	for _, secret := range v.Secrets {
+		if hmac.Equal(mac, v.sum(secret, ts, body)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature mismatch", ErrSignature)
+}
+
+// Sign sets the signature headers of a request with the given body, as a
+// sender would, using the first of v.Secrets. It is meant for tests and
+// for services forwarding webhooks.
+func (v *HMAC) Sign(r *http.Request, body []byte) {
+	if len(v.Secrets) == 0 {
+		return
+	}
+	header := v.Header
+	if header == "" {
+		header = DefaultSignatureHeader
+	}
+	var ts string
+	if v.TimestampHeader != "" {
+		ts = strconv.FormatInt(v.now().Unix(), 10)
+		r.Header.Set(v.TimestampHeader, ts)
+	}
+	r.Header.Set(header, v.Prefix+hex.EncodeToString(v.sum(v.Secrets[0], ts, body)))
+}
+
+// sum returns the MAC of body, preceded by ts if there is a timestamp.
+func (v *HMAC) sum(secret []byte, ts string, body []byte) []byte {
+	h := hmac.New(sha256.New, secret)
+	if v.TimestampHeader != "" {
+		h.Write([]byte(ts + "."))
+	}
+	h.Write(body)
+	return h.Sum(nil)
+}
+
+func (v *HMAC) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
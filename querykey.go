@@ -0,0 +1,60 @@
+package datasource
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// QueryKey returns a normalized form of input's question text, tags,
+// accepted languages, filters, and attachments: the question lowercased
+// with runs of whitespace collapsed, and the tags, languages, and filter
+// lists lowercased, trimmed, and sorted. Attachments are keyed by a hash
+// of their content. Inputs that differ only in case, spacing, or tag order
+// have the same key, making it suitable for cache keys and for routing
+// equivalent questions to the same place.
+func QueryKey(input NewQuestionInput) string {
+	query := strings.ToLower(strings.Join(strings.Fields(input.QuestionText), " "))
+	key := query + "\x00" + normalizeSet(input.Tags)
+	langs, filters, attachments := normalizeSet(input.AcceptLanguages), input.Filters.key(), attachmentsKey(input.Attachments)
+	if langs != "" || filters != "" || attachments != "" {
+		key += "\x00" + langs
+	}
+	if filters != "" || attachments != "" {
+		key += "\x00" + filters
+	}
+	if attachments != "" {
+		key += "\x00" + attachments
+	}
+	return key
+}
+
+// attachmentsKey returns a hash of the attachments, in order, or "" if
+// there are none.
+func attachmentsKey(attachments []Attachment) string {
+	if len(attachments) == 0 {
+		return ""
+	}
+	h := fnv.New64a()
+	for _, a := range attachments {
+		for _, f := range []string{string(a.Kind), a.Name, a.Language, a.Text, a.URL, a.MediaType} {
+			h.Write([]byte(f))
+			h.Write([]byte{0})
+		}
+	}
+	return "attachments=" + strconv.FormatUint(h.Sum64(), 16)
+}
+
+// normalizeSet lowercases, trims, and sorts values, dropping empty ones,
+// and joins them with commas.
+func normalizeSet(values []string) string {
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if v = strings.ToLower(strings.TrimSpace(v)); v != "" {
+			out = append(out, v)
+		}
+	}
+	sort.Strings(out)
+	return strings.Join(out, ",")
+}
@@ -0,0 +1,72 @@
+package datasource_test
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestQueryKey(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b datasource.NewQuestionInput
+		same bool
+	}{
+		{
+			"case and spacing",
+			datasource.NewQuestionInput{QuestionText: "  What IS\tdns "},
+			datasource.NewQuestionInput{QuestionText: "what is dns"},
+			true,
+		},
+		{
+			"tag order and case",
+			datasource.NewQuestionInput{QuestionText: "q", Tags: []string{"B", "a", " "}},
+			datasource.NewQuestionInput{QuestionText: "q", Tags: []string{"a", "b"}},
+			true,
+		},
+		{
+			"tags are not part of the question",
+			datasource.NewQuestionInput{QuestionText: "q a"},
+			datasource.NewQuestionInput{QuestionText: "q", Tags: []string{"a"}},
+			false,
+		},
+		{
+			"language order and case",
+			datasource.NewQuestionInput{QuestionText: "q", AcceptLanguages: []string{"pt-BR", "en"}},
+			datasource.NewQuestionInput{QuestionText: "q", AcceptLanguages: []string{"EN", "pt-br"}},
+			true,
+		},
+		{
+			"filter lists are normalized",
+			datasource.NewQuestionInput{QuestionText: "q", Filters: datasource.Filters{Sites: []string{"b", "A"}, Sort: datasource.SortRelevance}},
+			datasource.NewQuestionInput{QuestionText: "q", Filters: datasource.Filters{Sites: []string{"a", "b"}}},
+			true,
+		},
+		{
+			"filters are part of the key",
+			datasource.NewQuestionInput{QuestionText: "q"},
+			datasource.NewQuestionInput{QuestionText: "q", Filters: datasource.Filters{MinScore: 5}},
+			false,
+		},
+		{
+			"attachments are part of the key",
+			datasource.NewQuestionInput{QuestionText: "q"},
+			datasource.NewQuestionInput{QuestionText: "q", Attachments: []datasource.Attachment{{Kind: datasource.AttachmentLog, Text: "panic: boom"}}},
+			false,
+		},
+		{
+			"languages are not tags",
+			datasource.NewQuestionInput{QuestionText: "q", Tags: []string{"en"}},
+			datasource.NewQuestionInput{QuestionText: "q", AcceptLanguages: []string{"en"}},
+			false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := datasource.QueryKey(tt.a) == datasource.QueryKey(tt.b); got != tt.same {
+				t.Errorf("QueryKey(%+v) == QueryKey(%+v) is %v, want %v", tt.a, tt.b, got, tt.same)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+// Package quota paces background work against an upstream request quota
+// that is shared with live traffic.
+//
+// A Budget tracks requests made against a quota of Limit requests per
+// Window. Live traffic is recorded with Track (or Budget.Live) and takes
+// priority: background jobs such as incremental refreshes ask Pace how long
+// to wait before each request, so their load is spread over the window in
+// proportion to the quota that live traffic is not expected to need, and
+// backs off entirely when live traffic is consuming the budget.
+//
+//	b := quota.NewBudget(10000, 24*time.Hour)
+//	ds = quota.Track(ds, b)
+//	for _, id := range stale {
+//	    time.Sleep(b.Pace())
+//	    b.Background(1)
+//	    refresh(id)
+//	}
+package quota
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// DefaultReserve is the fraction of each window's quota held back for live
+// traffic when Budget.Reserve is not set.
+const DefaultReserve = 0.2
+
+// Budget tracks usage of an upstream quota within fixed windows. It is safe
+// for concurrent use.
+type Budget struct {
+	limit   int
+	window  time.Duration
+	reserve float64
+
+	mu         sync.Mutex
+	start      time.Time
+	live       int
+	background int
+}
+
+// NewBudget returns a Budget for a quota of limit requests per window,
+// holding back DefaultReserve of it for live traffic.
+func NewBudget(limit int, window time.Duration) *Budget {
+	return &Budget{limit: limit, window: window, reserve: DefaultReserve}
+}
+
+// SetReserve sets the fraction (0 to 1) of each window's quota held back for
+// live traffic.
+func (b *Budget) SetReserve(fraction float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.reserve = math.Min(math.Max(fraction, 0), 1)
+}
+
+// roll starts a new window if the current one has ended. Callers hold b.mu.
+func (b *Budget) roll(t time.Time) {
+	if b.start.IsZero() || t.Sub(b.start) >= b.window {
+		b.start = t
+		b.live, b.background = 0, 0
+	}
+}
+
+// Live records n requests made while serving live traffic.
+func (b *Budget) Live(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now())
+	b.live += n
+}
+
+// Background records n requests made by background work.
+func (b *Budget) Background(n int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now())
+	b.background += n
+}
+
+// Remaining returns the number of requests left in the current window.
+func (b *Budget) Remaining() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.roll(now())
+	return max(b.limit-b.live-b.background, 0)
+}
+
+// Pace returns how long background work should wait before its next
+// request.
+//
+// Live demand for the rest of the window is projected from the live rate so
+// far and is never assumed to be below the reserve. Whatever quota remains
+// after that is the background share, and requests are spaced evenly so the
+// share would be used up exactly at the end of the window. With no share
+// left, Pace returns the time until the window resets.
+func (b *Budget) Pace() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	t := now()
+	b.roll(t)
+
+	elapsed := t.Sub(b.start)
+	left := b.window - elapsed
+
+	projected := 0.0
+	if elapsed > 0 {
+		projected = float64(b.live) / elapsed.Seconds() * left.Seconds()
+	}
+	held := math.Max(projected, b.reserve*float64(b.limit)-float64(b.live))
+	share := float64(b.limit-b.live-b.background) - held
+	if share < 1 {
+		return left
+	}
+	return time.Duration(float64(left) / share)
+}
@@ -0,0 +1,84 @@
+package quota
+
+import (
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func fakeClock(t *testing.T) *time.Time {
+	t.Helper()
+	clock := time.Unix(1700000000, 0)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+	return &clock
+}
+
+func TestPace(t *testing.T) {
+	tests := []struct {
+		name       string
+		elapsed    time.Duration
+		live       int
+		background int
+		want       time.Duration
+	}{
+		{"fresh window spreads the unreserved share", 0, 0, 0, 1250 * time.Millisecond},
+		{"background usage slows pacing", 500 * time.Second, 0, 400, 1250 * time.Millisecond},
+		{"live usage within the reserve", 500 * time.Second, 100, 0, 625 * time.Millisecond},
+		{"projected live demand consumes the budget", 500 * time.Second, 500, 0, 500 * time.Second},
+		{"exhausted quota waits for the next window", 900 * time.Second, 0, 1000, 100 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := fakeClock(t)
+			b := NewBudget(1000, 1000*time.Second)
+			b.Live(0)
+			*clock = clock.Add(tt.elapsed)
+			b.Live(tt.live)
+			b.Background(tt.background)
+
+			if got := b.Pace(); got != tt.want {
+				t.Errorf("Pace() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWindowResets(t *testing.T) {
+	clock := fakeClock(t)
+	b := NewBudget(10, time.Minute)
+	b.Background(10)
+	if r := b.Remaining(); r != 0 {
+		t.Fatalf("Remaining() = %d, want 0", r)
+	}
+	*clock = clock.Add(time.Minute)
+	if r := b.Remaining(); r != 10 {
+		t.Errorf("Remaining() after reset = %d, want 10", r)
+	}
+}
+
+type nopSource struct{}
+
+func (nopSource) Init() error             { return nil }
+func (nopSource) CheckAvailability() bool { return true }
+func (nopSource) FetchTopics(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	return []datasource.DataSourceTopic{}, nil
+}
+func (nopSource) FetchData(int, int64) ([]datasource.DataSourceData, error) {
+	return []datasource.DataSourceData{}, nil
+}
+
+func TestTrackRecordsLiveUsage(t *testing.T) {
+	fakeClock(t)
+	b := NewBudget(100, time.Hour)
+	ds := Track(nopSource{}, b)
+	ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q"})
+	ds.FetchData(5, 1)
+	ds.CheckAvailability()
+
+	if r := b.Remaining(); r != 98 {
+		t.Errorf("Remaining() = %d, want 98", r)
+	}
+}
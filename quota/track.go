@@ -0,0 +1,30 @@
+package quota
+
+import datasource "github.com/locus-search/datasource-sdk"
+
+// Track returns a DataSource that records each FetchTopics and FetchData
+// call to ds as live usage of b, so background work paced by b yields to
+// user-facing traffic.
+func Track(ds datasource.DataSource, b *Budget) datasource.DataSource {
+	return &trackedSource{DataSource: ds, budget: b}
+}
+
+type trackedSource struct {
+	datasource.DataSource
+	budget *Budget
+}
+
+func (s *trackedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	s.budget.Live(1)
+	return s.DataSource.FetchTopics(count, input)
+}
+
+func (s *trackedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	s.budget.Live(1)
+	return s.DataSource.FetchData(count, topicID)
+}
+
+// Unwrap returns the wrapped data source.
+func (s *trackedSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
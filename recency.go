@@ -0,0 +1,79 @@
+package datasource
+
+import (
+	"math"
+	"time"
+)
+
+// Default Recency values used when its fields are zero.
+const (
+	DefaultRecencyHalfLife = 30 * 24 * time.Hour
+	DefaultRecencyWeight   = 0.3
+)
+
+// Recency reranks results to prefer fresh content. Each result's
+// relevance, taken from its Score scaled by the highest Score in the list
+// or, if the list is unscored, from its position as 1/(position+1), is
+// blended with its freshness, which halves every HalfLife since it was
+// last updated (UpdatedAt, else CreatedAt). Undated results have no
+// freshness.
+//
+//	topics = datasource.Recency{HalfLife: 7 * 24 * time.Hour}.Topics(topics)
+type Recency struct {
+	// HalfLife is the age at which freshness halves
+	// Defaults to DefaultRecencyHalfLife
+	HalfLife time.Duration
+
+	// Weight is the share of freshness in the blended score, between 0
+	// and 1
+	// Defaults to DefaultRecencyWeight
+	Weight float64
+
+	// Now is the time ages are measured from
+	// Defaults to the current time
+	Now time.Time
+}
+
+func (r Recency) withDefaults() Recency {
+	if r.HalfLife <= 0 {
+		r.HalfLife = DefaultRecencyHalfLife
+	}
+	if r.Weight <= 0 || r.Weight > 1 {
+		r.Weight = DefaultRecencyWeight
+	}
+	if r.Now.IsZero() {
+		r.Now = time.Now()
+	}
+	return r
+}
+
+// Topics returns a copy of topics reordered by blended relevance and
+// freshness. Scores and ranks are not modified.
+func (r Recency) Topics(topics []DataSourceTopic) []DataSourceTopic {
+	r = r.withDefaults()
+	return blend(topics, r.Weight,
+		func(t DataSourceTopic) float64 { return t.Score },
+		func(t DataSourceTopic) float64 { return r.freshness(t.CreatedAt, t.UpdatedAt) })
+}
+
+// Data returns a copy of items reordered by blended relevance and
+// freshness. Scores and ranks are not modified.
+func (r Recency) Data(items []DataSourceData) []DataSourceData {
+	r = r.withDefaults()
+	return blend(items, r.Weight,
+		func(d DataSourceData) float64 { return d.Score },
+		func(d DataSourceData) float64 { return r.freshness(d.CreatedAt, d.UpdatedAt) })
+}
+
+// freshness is 1 for content updated now, halving every HalfLife.
+func (r Recency) freshness(created, updated time.Time) float64 {
+	t := updated
+	if t.IsZero() {
+		t = created
+	}
+	if t.IsZero() {
+		return 0
+	}
+	age := max(r.Now.Sub(t), 0)
+	return math.Exp2(-float64(age) / float64(r.HalfLife))
+}
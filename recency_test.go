@@ -0,0 +1,58 @@
+package datasource_test
+
+import (
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestRecencyTopics(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	topics := []datasource.DataSourceTopic{
+		{Topic: "old", CreatedAt: now.Add(-365 * day)},
+		{Topic: "fresh", CreatedAt: now.Add(-400 * day), UpdatedAt: now.Add(-day)},
+		{Topic: "undated"},
+	}
+
+	tests := []struct {
+		name string
+		r    datasource.Recency
+		want []string
+	}{
+		// Position relevance 1, 0.5, 0.33 blended with freshness ~0, ~1, 0.
+		{"default weight", datasource.Recency{Now: now}, []string{"old", "fresh", "undated"}},
+		{"heavy weight", datasource.Recency{Now: now, Weight: 0.8}, []string{"fresh", "old", "undated"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.r.Topics(topics)
+			for i, w := range tt.want {
+				if got[i].Topic != w {
+					t.Fatalf("order = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+	if topics[0].Topic != "old" {
+		t.Error("Topics modified its input")
+	}
+}
+
+func TestRecencyDataUsesScores(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	items := []datasource.DataSourceData{
+		{AnswerID: 1, Score: 100, CreatedAt: now.Add(-1000 * 24 * time.Hour)},
+		{AnswerID: 2, Score: 90, CreatedAt: now},
+		{AnswerID: 3, Score: 10, CreatedAt: now},
+	}
+	got := datasource.Recency{Now: now, HalfLife: 7 * 24 * time.Hour}.Data(items)
+	// Blended: 0.7*1 + 0 = 0.7, 0.7*0.9 + 0.3 = 0.93, 0.7*0.1 + 0.3 = 0.37.
+	if got[0].AnswerID != 2 || got[1].AnswerID != 1 || got[2].AnswerID != 3 {
+		t.Errorf("order = %d, %d, %d; want 2, 1, 3", got[0].AnswerID, got[1].AnswerID, got[2].AnswerID)
+	}
+	if got[0].Score != 90 {
+		t.Error("scores must not be modified")
+	}
+}
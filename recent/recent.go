@@ -0,0 +1,211 @@
+// Package recent detects questions that nearly duplicate recently answered
+// ones, so a host can serve the earlier results without querying any data
+// source. "How do I reset my password?" and "how to reset my password"
+// are the same question for search purposes.
+//
+// Questions are compared by the cosine similarity of their embeddings when
+// both have one, and otherwise by the estimated Jaccard similarity of their
+// MinHash signatures (see package similarity).
+//
+// Example:
+//
+//	history := recent.New(recent.Config{Window: 5 * time.Minute, Threshold: 0.85})
+//	ds = recent.Wrap(ds, history)
+package recent
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/similarity"
+)
+
+// now is replaced in tests.
+var now = time.Now
+
+// Default configuration values used when Config fields are zero.
+const (
+	DefaultWindow     = 10 * time.Minute
+	DefaultThreshold  = 0.9
+	DefaultMaxEntries = 1000
+)
+
+// Config configures a History.
+type Config struct {
+	// Window is how long an answered question can be matched
+	// Defaults to DefaultWindow
+	Window time.Duration
+
+	// Threshold is the minimum similarity, between 0 and 1, for two
+	// questions to be considered duplicates
+	// Defaults to DefaultThreshold
+	Threshold float64
+
+	// MaxEntries bounds the number of questions remembered; the oldest are
+	// forgotten first
+	// Defaults to DefaultMaxEntries
+	MaxEntries int
+
+	// PerUser restricts matches to questions asked by the same user
+	// (NewQuestionInput.AskedBy), as needed for permission-sensitive
+	// sources. Matches always require the same TenantID, tags,
+	// AcceptLanguages, and Filters
+	PerUser bool
+}
+
+func (c Config) withDefaults() Config {
+	if c.Window <= 0 {
+		c.Window = DefaultWindow
+	}
+	if c.Threshold <= 0 || c.Threshold > 1 {
+		c.Threshold = DefaultThreshold
+	}
+	if c.MaxEntries <= 0 {
+		c.MaxEntries = DefaultMaxEntries
+	}
+	return c
+}
+
+// Match is a recently answered question similar to the one looked up.
+type Match struct {
+	// Question is the earlier question's text
+	Question string
+
+	// Topics are the results recorded for it
+	Topics []datasource.DataSourceTopic
+
+	// Similarity is how similar the two questions are, between 0 and 1
+	Similarity float64
+
+	// At is when the earlier question was recorded
+	At time.Time
+}
+
+type entry struct {
+	input     datasource.NewQuestionInput
+	signature similarity.Signature
+	count     int
+	topics    []datasource.DataSourceTopic
+	at        time.Time
+}
+
+// History remembers recently answered questions. It is safe for concurrent
+// use.
+type History struct {
+	cfg Config
+
+	mu      sync.Mutex
+	entries []*entry // oldest first
+}
+
+// New returns an empty History.
+func New(cfg Config) *History {
+	return &History{cfg: cfg.withDefaults()}
+}
+
+// Record remembers the topics returned for input when count topics were
+// requested.
+func (h *History) Record(input datasource.NewQuestionInput, count int, topics []datasource.DataSourceTopic) {
+	e := &entry{
+		input:     input,
+		signature: similarity.MinHash(input.QuestionText, 0),
+		count:     count,
+		topics:    append([]datasource.DataSourceTopic{}, topics...),
+		at:        now(),
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expire()
+	if len(h.entries) >= h.cfg.MaxEntries {
+		h.entries = h.entries[len(h.entries)-h.cfg.MaxEntries+1:]
+	}
+	h.entries = append(h.entries, e)
+}
+
+// Lookup returns the most similar recorded question within the window that
+// meets the threshold and whose results can answer a request for count
+// topics: it asked for at least count, or got fewer than it asked for.
+// Among equally similar questions the most recent wins.
+func (h *History) Lookup(input datasource.NewQuestionInput, count int) (Match, bool) {
+	return h.lookup(input, count, h.cfg.PerUser)
+}
+
+func (h *History) lookup(input datasource.NewQuestionInput, count int, perUser bool) (Match, bool) {
+	sig := similarity.MinHash(input.QuestionText, 0)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expire()
+
+	var best *entry
+	bestSim := 0.0
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		e := h.entries[i]
+		if !compatible(e.input, input, perUser) || (e.count < count && len(e.topics) >= e.count) {
+			continue
+		}
+		if s := h.similarity(e, input, sig); s >= h.cfg.Threshold && s > bestSim {
+			best, bestSim = e, s
+		}
+	}
+	if best == nil {
+		return Match{}, false
+	}
+	return Match{
+		Question:   best.input.QuestionText,
+		Topics:     append([]datasource.DataSourceTopic{}, best.topics[:min(count, len(best.topics))]...),
+		Similarity: bestSim,
+		At:         best.at,
+	}, true
+}
+
+// Len returns the number of questions remembered within the window.
+func (h *History) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.expire()
+	return len(h.entries)
+}
+
+// expire drops entries older than the window. The caller holds h.mu.
+func (h *History) expire() {
+	cutoff := now().Add(-h.cfg.Window)
+	i := 0
+	for i < len(h.entries) && !h.entries[i].at.After(cutoff) {
+		i++
+	}
+	h.entries = h.entries[i:]
+}
+
+// compatible reports whether a question's results may answer another:
+// both must have the same tags, accepted languages, filters, and tenant,
+// and the same asker if perUser.
+func compatible(a, b datasource.NewQuestionInput, perUser bool) bool {
+	if a.TenantID != b.TenantID || scopeKey(a) != scopeKey(b) {
+		return false
+	}
+	if !perUser {
+		return true
+	}
+	return (a.AskedBy == nil && b.AskedBy == nil) ||
+		(a.AskedBy != nil && b.AskedBy != nil && *a.AskedBy == *b.AskedBy)
+}
+
+// scopeKey returns the normalized tags, accepted languages, and filters of
+// input.
+func scopeKey(input datasource.NewQuestionInput) string {
+	key := datasource.QueryKey(datasource.NewQuestionInput{
+		Tags:            input.Tags,
+		AcceptLanguages: input.AcceptLanguages,
+		Filters:         input.Filters,
+	})
+	return key[strings.IndexByte(key, 0)+1:]
+}
+
+func (h *History) similarity(e *entry, input datasource.NewQuestionInput, sig similarity.Signature) float64 {
+	if len(e.input.Embedding) > 0 && len(e.input.Embedding) == len(input.Embedding) {
+		return similarity.Cosine(e.input.Embedding, input.Embedding)
+	}
+	return e.signature.Jaccard(sig)
+}
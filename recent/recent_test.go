@@ -0,0 +1,192 @@
+package recent
+
+import (
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+)
+
+func stubNow(t *testing.T) *time.Time {
+	t.Helper()
+	clock := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	t.Cleanup(func() { now = time.Now })
+	return &clock
+}
+
+func question(text string) datasource.NewQuestionInput {
+	return datasource.NewQuestionInput{QuestionText: text}
+}
+
+var topics = []datasource.DataSourceTopic{
+	{Topic: "Resetting your password", TopicID: 1},
+	{Topic: "Account recovery", TopicID: 2},
+	{Topic: "Two-factor authentication", TopicID: 3},
+}
+
+func TestLookup(t *testing.T) {
+	stubNow(t)
+	h := New(Config{Threshold: 0.6})
+	h.Record(question("How do I reset my password?"), 3, topics)
+
+	m, ok := h.Lookup(question("how do I reset my password"), 2)
+	if !ok {
+		t.Fatal("near duplicate not matched")
+	}
+	if m.Question != "How do I reset my password?" || len(m.Topics) != 2 || m.Similarity < 0.6 {
+		t.Errorf("match = %+v", m)
+	}
+	if _, ok := h.Lookup(question("Which regions support GPU instances?"), 2); ok {
+		t.Error("unrelated question matched")
+	}
+}
+
+func TestLookupExpires(t *testing.T) {
+	clock := stubNow(t)
+	h := New(Config{Window: time.Minute})
+	h.Record(question("How do I reset my password?"), 3, topics)
+
+	*clock = clock.Add(59 * time.Second)
+	if _, ok := h.Lookup(question("How do I reset my password?"), 3); !ok {
+		t.Fatal("not matched within window")
+	}
+	*clock = clock.Add(time.Second)
+	if _, ok := h.Lookup(question("How do I reset my password?"), 3); ok {
+		t.Error("matched after window")
+	}
+	if h.Len() != 0 {
+		t.Errorf("Len = %d after expiry, want 0", h.Len())
+	}
+}
+
+func TestLookupCount(t *testing.T) {
+	stubNow(t)
+	h := New(Config{})
+	h.Record(question("reset password"), 2, topics[:2])
+	h.Record(question("account recovery"), 5, topics)
+
+	if _, ok := h.Lookup(question("reset password"), 3); ok {
+		t.Error("a full result for 2 topics answered a request for 3")
+	}
+	m, ok := h.Lookup(question("account recovery"), 4)
+	if !ok || len(m.Topics) != 3 {
+		t.Errorf("a short result for 5 topics should answer 4: ok=%v, topics=%d", ok, len(m.Topics))
+	}
+}
+
+func TestLookupScope(t *testing.T) {
+	stubNow(t)
+	alice, bob := int64(1), int64(2)
+	base := datasource.NewQuestionInput{
+		QuestionText: "How do I reset my password?",
+		Tags:         []string{"accounts", "security"},
+		TenantID:     "acme",
+		AskedBy:      &alice,
+	}
+	with := func(f func(*datasource.NewQuestionInput)) datasource.NewQuestionInput {
+		in := base
+		f(&in)
+		return in
+	}
+	tests := []struct {
+		name    string
+		perUser bool
+		input   datasource.NewQuestionInput
+		want    bool
+	}{
+		{"same", false, base, true},
+		{"tag order", false, with(func(in *datasource.NewQuestionInput) { in.Tags = []string{"security", "accounts"} }), true},
+		{"other tags", false, with(func(in *datasource.NewQuestionInput) { in.Tags = []string{"billing"} }), false},
+		{"other tenant", false, with(func(in *datasource.NewQuestionInput) { in.TenantID = "globex" }), false},
+		{"other user", false, with(func(in *datasource.NewQuestionInput) { in.AskedBy = &bob }), true},
+		{"other user per user", true, with(func(in *datasource.NewQuestionInput) { in.AskedBy = &bob }), false},
+		{"anonymous per user", true, with(func(in *datasource.NewQuestionInput) { in.AskedBy = nil }), false},
+		{"same user per user", true, base, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := New(Config{PerUser: tt.perUser})
+			h.Record(base, 3, topics)
+			if _, ok := h.Lookup(tt.input, 3); ok != tt.want {
+				t.Errorf("matched = %v, want %v", ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupEmbedding(t *testing.T) {
+	stubNow(t)
+	h := New(Config{Threshold: 0.95})
+	h.Record(datasource.NewQuestionInput{QuestionText: "reset password", Embedding: []float64{1, 0.1}}, 3, topics)
+
+	// Embeddings take precedence over the text when both have one.
+	if _, ok := h.Lookup(datasource.NewQuestionInput{QuestionText: "forgot my login", Embedding: []float64{1, 0.12}}, 3); !ok {
+		t.Error("similar embeddings not matched")
+	}
+	if _, ok := h.Lookup(datasource.NewQuestionInput{QuestionText: "reset password", Embedding: []float64{0, 1}}, 3); ok {
+		t.Error("dissimilar embeddings matched")
+	}
+}
+
+func TestMaxEntries(t *testing.T) {
+	stubNow(t)
+	h := New(Config{MaxEntries: 2})
+	h.Record(question("reset password"), 3, topics)
+	h.Record(question("account recovery"), 3, topics)
+	h.Record(question("gpu regions"), 3, topics)
+
+	if h.Len() != 2 {
+		t.Errorf("Len = %d, want 2", h.Len())
+	}
+	if _, ok := h.Lookup(question("reset password"), 3); ok {
+		t.Error("oldest entry not forgotten")
+	}
+}
+
+func TestWrap(t *testing.T) {
+	stubNow(t)
+	fake := &datasourcetest.Fake{Topics: topics}
+	ds := Wrap(fake, New(Config{Threshold: 0.6}))
+
+	if _, err := ds.FetchTopics(3, question("How do I reset my password?")); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ds.FetchTopics(3, question("how do i reset my password"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 3 {
+		t.Errorf("got %d topics, want 3", len(got))
+	}
+	if n := len(fake.Calls()); n != 1 {
+		t.Errorf("source called %d times, want 1", n)
+	}
+
+	fake.FailNext(datasource.MethodFetchTopics, datasource.ErrUnavailable)
+	if _, err := ds.FetchTopics(3, question("gpu regions")); err == nil {
+		t.Fatal("error not returned")
+	}
+	if _, err := ds.FetchTopics(3, question("gpu regions")); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Calls()); n != 3 {
+		t.Errorf("failed result was recorded: source called %d times, want 3", n)
+	}
+}
+
+func TestWrapReplaysEmptyResult(t *testing.T) {
+	stubNow(t)
+	fake := &datasourcetest.Fake{}
+	ds := Wrap(fake, New(Config{Threshold: 0.6}))
+
+	for _, q := range []string{"How do I reset my password?", "how do i reset my password"} {
+		if got, err := ds.FetchTopics(3, question(q)); err != nil || got == nil {
+			t.Errorf("FetchTopics(%q) = %v, %v; want an empty slice", q, got, err)
+		}
+	}
+	if n := len(fake.Calls()); n != 1 {
+		t.Errorf("source called %d times, want 1", n)
+	}
+}
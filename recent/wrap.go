@@ -0,0 +1,43 @@
+package recent
+
+import (
+	"sync"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Wrap returns a DataSource that answers FetchTopics from h when a similar
+// question was answered recently, and records every successful result in
+// h. FetchData is passed through. Sources reporting PermissionSensitive in
+// their Capabilities (read on the first call) are matched per user even if
+// h is not configured so.
+func Wrap(ds datasource.DataSource, h *History) datasource.DataSource {
+	return &recentSource{DataSource: ds, history: h}
+}
+
+type recentSource struct {
+	datasource.DataSource
+	history *History
+
+	perUserOnce sync.Once
+	perUser     bool
+}
+
+func (s *recentSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	s.perUserOnce.Do(func() {
+		s.perUser = s.history.cfg.PerUser || datasource.CapabilitiesOf(s.DataSource).PermissionSensitive
+	})
+	if m, ok := s.history.lookup(input, count, s.perUser); ok {
+		return m.Topics, nil
+	}
+	topics, err := s.DataSource.FetchTopics(count, input)
+	if err == nil {
+		s.history.Record(input, count, topics)
+	}
+	return topics, err
+}
+
+// Unwrap returns the wrapped data source.
+func (s *recentSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
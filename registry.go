@@ -0,0 +1,93 @@
+package datasource
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Factory constructs a DataSource. It should be cheap; heavy work belongs in
+// Init, which the Registry calls after construction.
+type Factory func() (DataSource, error)
+
+// Registry maps configuration names to data sources, so hosts can look
+// sources up by name instead of wiring them by hand. Sources are constructed
+// and initialized lazily on first Get. The zero value is an empty registry
+// ready to use, and all methods are safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*registryEntry
+}
+
+type registryEntry struct {
+	factory Factory
+
+	mu sync.Mutex // serializes construction so Init runs once
+	ds DataSource
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// Register adds a data source under name. It returns an error if name is
+// empty or already registered.
+func (r *Registry) Register(name string, factory Factory) error {
+	if name == "" {
+		return fmt.Errorf("datasource: register: empty name")
+	}
+	if factory == nil {
+		return fmt.Errorf("datasource: register %q: nil factory", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, dup := r.entries[name]; dup {
+		return fmt.Errorf("datasource: register %q: already registered", name)
+	}
+	if r.entries == nil {
+		r.entries = make(map[string]*registryEntry)
+	}
+	r.entries[name] = &registryEntry{factory: factory}
+	return nil
+}
+
+// Get returns the data source registered under name, constructing it and
+// calling Init on first use. If construction or Init fails, the error is
+// returned and the next Get tries again. Unknown names return an error
+// wrapping ErrNotFound.
+func (r *Registry) Get(name string) (DataSource, error) {
+	r.mu.RLock()
+	e, ok := r.entries[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("datasource: source %q: %w", name, ErrNotFound)
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.ds != nil {
+		return e.ds, nil
+	}
+	ds, err := e.factory()
+	if err != nil {
+		return nil, fmt.Errorf("datasource: source %q: %w", name, err)
+	}
+	if err := ds.Init(); err != nil {
+		return nil, fmt.Errorf("datasource: source %q: init: %w", name, err)
+	}
+	e.ds = ds
+	return ds, nil
+}
+
+// List returns the registered names in sorted order.
+func (r *Registry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.entries))
+	for name := range r.entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,85 @@
+package datasource_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+type countingSource struct {
+	ExampleDataSource
+	inits int
+}
+
+func (s *countingSource) Init() error {
+	s.inits++
+	return s.ExampleDataSource.Init()
+}
+
+func TestRegistryLazyInit(t *testing.T) {
+	var r datasource.Registry
+	src := &countingSource{ExampleDataSource: ExampleDataSource{Name: "wiki"}}
+	constructed := 0
+	if err := r.Register("wiki", func() (datasource.DataSource, error) {
+		constructed++
+		return src, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if constructed != 0 {
+		t.Fatal("factory called before Get")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if ds, err := r.Get("wiki"); err != nil || ds != src {
+				t.Errorf("Get = %v, %v", ds, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if constructed != 1 || src.inits != 1 {
+		t.Errorf("constructed %d times, initialized %d times; want once each", constructed, src.inits)
+	}
+}
+
+func TestRegistryRetriesFailedInit(t *testing.T) {
+	r := datasource.NewRegistry()
+	src := &countingSource{}
+	r.Register("kb", func() (datasource.DataSource, error) { return src, nil })
+
+	if _, err := r.Get("kb"); err == nil || !strings.Contains(err.Error(), "name is required") {
+		t.Fatalf("expected Init error, got %v", err)
+	}
+	src.Name = "kb"
+	if _, err := r.Get("kb"); err != nil {
+		t.Errorf("second Get failed: %v", err)
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	r := datasource.NewRegistry()
+	factory := func() (datasource.DataSource, error) { return &ExampleDataSource{Name: "x"}, nil }
+
+	if err := r.Register("", factory); err == nil {
+		t.Error("expected error for empty name")
+	}
+	r.Register("b", factory)
+	r.Register("a", factory)
+	if err := r.Register("a", factory); err == nil {
+		t.Error("expected error for duplicate name")
+	}
+	if _, err := r.Get("missing"); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("Get(missing) = %v, want ErrNotFound", err)
+	}
+	if got := strings.Join(r.List(), ","); got != "a,b" {
+		t.Errorf("List() = %s", got)
+	}
+}
@@ -0,0 +1,205 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/hashring"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// DefaultTimeout bounds each request made by a Client created with
+// NewClient.
+const DefaultTimeout = 30 * time.Second
+
+// maxResponseBytes bounds response bodies read by the client.
+const maxResponseBytes = 32 << 20
+
+// Client is a DataSource backed by a service speaking the remote protocol.
+type Client struct {
+	// BaseURL is the service address, without the /v1 prefix
+	BaseURL string
+
+	// HTTPClient makes the requests
+	HTTPClient *http.Client
+
+	// Header is added to every request, e.g. for authentication
+	Header http.Header
+
+	// StreamWindow is the number of data items StreamData lets the server
+	// read ahead of the consumer
+	// Defaults to DefaultStreamWindow
+	StreamWindow int
+
+	// Projection asks the server to leave heavy fields out of the items
+	// returned by FetchData and StreamData, e.g. datasource.LiteProjection
+	// on a constrained link; Hydrate fetches the full items later
+	// Optional - the zero value fetches full data items
+	Projection datasource.Projection
+}
+
+var _ datasource.DataSource = (*Client)(nil)
+
+// NewClient returns a Client for the service at baseURL.
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: DefaultTimeout},
+		Header:     make(http.Header),
+	}
+}
+
+// errNotServed marks a 404 from a server that does not serve the
+// endpoint, as opposed to a protocol error for a missing resource.
+var errNotServed = errors.New("endpoint not served")
+
+// do sends a request and decodes a successful JSON response into out. Error
+// responses are converted back into SDK errors.
+func (c *Client) do(method, path string, body any, header http.Header, out any) error {
+	return c.doContext(context.Background(), method, path, body, header, out)
+}
+
+func (c *Client) doContext(ctx context.Context, method, path string, body any, header http.Header, out any) error {
+	var rd io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		rd = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, rd)
+	if err != nil {
+		return fmt.Errorf("remote: %w", err)
+	}
+	for k, v := range c.Header {
+		req.Header[k] = v
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("remote: %w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	r := io.LimitReader(resp.Body, maxResponseBytes)
+
+	if resp.StatusCode >= 300 {
+		var e errorResponse
+		if json.NewDecoder(r).Decode(&e) == nil && e.Error != nil {
+			return e.Error.Err()
+		}
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("remote: %w: %w", errNotServed, httpx.StatusError(resp))
+		}
+		return httpx.StatusError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(r).Decode(out); err != nil {
+		return fmt.Errorf("remote: decode %s response: %w", path, err)
+	}
+	return nil
+}
+
+func (c *Client) Init() error {
+	return c.do(http.MethodPost, PathInit, nil, nil, nil)
+}
+
+func (c *Client) CheckAvailability() bool {
+	var r healthResponse
+	return c.do(http.MethodGet, PathHealth, nil, nil, &r) == nil && r.Available
+}
+
+// HealthCheck returns the health reported by the server, which is
+// unhealthy if the server cannot be reached. Latency is the round trip.
+func (c *Client) HealthCheck() datasource.HealthStatus {
+	start := time.Now()
+	var r healthResponse
+	err := c.do(http.MethodGet, PathHealth, nil, nil, &r)
+	h := datasource.HealthStatus{State: datasource.Healthy}
+	switch {
+	case err != nil:
+		h = datasource.HealthStatus{State: datasource.Unhealthy, Error: err.Error()}
+	case r.Health != nil:
+		h = *r.Health
+	case !r.Available:
+		h = datasource.HealthStatus{State: datasource.Unhealthy, Error: datasource.ErrUnavailable.Error()}
+	}
+	h.Latency = time.Since(start)
+	return h
+}
+
+// Capabilities returns the capabilities reported by the server, or none if
+// it cannot be reached. Streaming is always reported, since the Client
+// streams data (see StreamData) whether or not the served source does.
+func (c *Client) Capabilities() datasource.Capabilities {
+	var caps datasource.Capabilities
+	if c.do(http.MethodGet, PathCapabilities, nil, nil, &caps) != nil {
+		return datasource.Capabilities{Streaming: true}
+	}
+	caps.Streaming = true
+	return caps
+}
+
+// FetchTopics searches the remote source. A Budget on input bounds the
+// request and is sent as the time left, so the server's deadline does not
+// depend on its clock agreeing with the client's. The source's match
+// estimate is recorded on the input's Trace.
+func (c *Client) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	header := make(http.Header)
+	hashring.SetHeader(header, input)
+	ctx, cancel := input.Budget.Context(context.Background())
+	defer cancel()
+	if left, ok := input.Budget.Remaining(); ok {
+		input.Budget = datasource.Budget{MaxLatency: max(left, time.Millisecond)}
+	}
+	var r topicsResponse
+	if err := c.doContext(ctx, http.MethodPost, PathTopics, topicsRequest{Count: count, Input: input}, header, &r); err != nil {
+		return nil, err
+	}
+	if r.Topics == nil {
+		r.Topics = []datasource.DataSourceTopic{}
+	}
+	input.Trace.Estimate(r.TotalEstimate)
+	return r.Topics, nil
+}
+
+func (c *Client) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	query := url.Values{"count": {strconv.Itoa(count)}}
+	encodeProjection(query, c.Projection)
+	path := PathTopics + "/" + strconv.FormatInt(topicID, 10) + "/data?" + query.Encode()
+	var r dataResponse
+	if err := c.do(http.MethodGet, path, nil, nil, &r); err != nil {
+		return nil, err
+	}
+	if r.Data == nil {
+		r.Data = []datasource.DataSourceData{}
+	}
+	return r.Data, nil
+}
+
+// Hydrate resolves references taken from projected items back into full
+// data items with datasource.HydrateData, ignoring c.Projection. A
+// projection fixed by the server's HandlerConfig still applies.
+func (c *Client) Hydrate(refs []datasource.DataRef) ([]datasource.DataSourceData, error) {
+	full := *c
+	full.Projection = datasource.Projection{}
+	return datasource.HydrateData(&full, refs)
+}
@@ -0,0 +1,187 @@
+package remote
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/wire"
+)
+
+// maxRequestBytes bounds request bodies; questions with embeddings are the
+// largest expected payloads.
+const maxRequestBytes = 1 << 20
+
+// HandlerConfig configures a handler created with NewHandlerConfig.
+type HandlerConfig struct {
+	// Projection is applied to the data served to every caller, on top of
+	// any projection a request asks for, e.g. datasource.LiteProjection
+	// for an adapter serving mobile clients
+	// Optional - the zero value serves full data items
+	Projection datasource.Projection
+
+	// MaxStreams caps the streams open at once; opening another fails
+	// with 429 Too Many Requests until one is done or closed
+	// Defaults to DefaultMaxStreams
+	MaxStreams int
+}
+
+func (c HandlerConfig) withDefaults() HandlerConfig {
+	if c.MaxStreams <= 0 {
+		c.MaxStreams = DefaultMaxStreams
+	}
+	return c
+}
+
+// NewHandler returns an http.Handler serving ds with the remote protocol.
+// Mount it at the root of a server or strip any prefix before it. Streams
+// are held by the handler, so serve every request with the same one.
+func NewHandler(ds datasource.DataSource) http.Handler {
+	return NewHandlerConfig(ds, HandlerConfig{})
+}
+
+// NewHandlerConfig is like NewHandler with the given configuration.
+func NewHandlerConfig(ds datasource.DataSource, cfg HandlerConfig) http.Handler {
+	return &handler{ds: ds, cfg: cfg.withDefaults(), streams: make(map[string]*stream)}
+}
+
+type handler struct {
+	ds  datasource.DataSource
+	cfg HandlerConfig
+
+	mu      sync.Mutex
+	streams map[string]*stream
+}
+
+var errBadRequest = errors.New("bad request")
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	switch {
+	case path == PathInit:
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		if err := h.ds.Init(); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	case path == PathHealth:
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		health := datasource.CheckHealth(h.ds)
+		writeJSON(w, http.StatusOK, healthResponse{Available: health.State != datasource.Unhealthy, Health: &health})
+
+	case path == PathCapabilities:
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		writeJSON(w, http.StatusOK, datasource.CapabilitiesOf(h.ds))
+
+	case path == PathTopics:
+		if !allow(w, r, http.MethodPost) {
+			return
+		}
+		var req topicsRequest
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+			writeError(w, fmt.Errorf("%w: %v", errBadRequest, err))
+			return
+		}
+		page, err := datasource.FetchTopicsPage(h.ds, req.Count, req.Input)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, topicsResponse{Topics: datasource.FillTopics(page.Topics), TotalEstimate: page.TotalEstimate})
+
+	case strings.HasPrefix(path, PathTopics+"/") && strings.HasSuffix(path, "/data"):
+		if !allow(w, r, http.MethodGet) {
+			return
+		}
+		id, err := strconv.ParseInt(strings.TrimSuffix(strings.TrimPrefix(path, PathTopics+"/"), "/data"), 10, 64)
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: invalid topic ID", errBadRequest))
+			return
+		}
+		count, err := strconv.Atoi(r.URL.Query().Get("count"))
+		if err != nil {
+			writeError(w, fmt.Errorf("%w: invalid count", errBadRequest))
+			return
+		}
+		p, err := decodeProjection(r.URL.Query())
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		data, err := h.ds.FetchData(count, id)
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusOK, dataResponse{Data: h.project(p, data)})
+
+	case path == PathStreams || strings.HasPrefix(path, PathStreams+"/"):
+		h.serveStreams(w, r)
+
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// project applies the handler's projection and then the request's p.
+func (h *handler) project(p datasource.Projection, items []datasource.DataSourceData) []datasource.DataSourceData {
+	for _, p := range []datasource.Projection{h.cfg.Projection, p} {
+		if p != (datasource.Projection{}) {
+			items = p.Apply(items)
+		}
+	}
+	return items
+}
+
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	e := wire.EncodeError(err)
+	status := http.StatusInternalServerError
+	switch e.Class {
+	case "not_found":
+		status = http.StatusNotFound
+	case "unauthorized":
+		status = http.StatusUnauthorized
+	case "rate_limited", "quota_exceeded":
+		status = http.StatusTooManyRequests
+	case "unavailable":
+		status = http.StatusServiceUnavailable
+	default:
+		if errors.Is(err, errBadRequest) {
+			status = http.StatusBadRequest
+		}
+	}
+	if e.RetryAfterMillis > 0 {
+		secs := (time.Duration(e.RetryAfterMillis)*time.Millisecond + time.Second - 1) / time.Second
+		w.Header().Set("Retry-After", strconv.FormatInt(int64(secs), 10))
+	}
+	writeJSON(w, status, errorResponse{Error: e})
+}
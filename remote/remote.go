@@ -0,0 +1,137 @@
+// Package remote serves data sources over HTTP and connects to them, so a
+// data source can be deployed as an independent service.
+//
+// The protocol uses JSON bodies under a versioned path prefix:
+//
+//	POST /v1/init                    -> 204 No Content
+//	GET  /v1/health                  -> {"available": true, "health": {"state": "healthy", ...}}
+//	GET  /v1/capabilities            -> {"embeddings": true, ...}
+//	POST /v1/topics                  {"count": 5, "input": {...}}
+//	                                 -> {"topics": [...], "total_estimate": 120}
+//	GET  /v1/topics/{id}/data?count=N&omit=entities,embeddings&max_text_bytes=280
+//	                                 -> {"data": [...]}
+//	POST /v1/streams                 {"topic_id": 1, "count": 500, "window": 64, "projection": {...}}
+//	                                 -> 201 {"stream": "...", "window": 64}
+//	GET  /v1/streams/{stream}?max=N  -> {"data": [...], "done": false}
+//	DELETE /v1/streams/{stream}      -> 204 No Content
+//
+// "input" is a datasource.NewQuestionInput and the results are
+// datasource.DataSourceTopic and DataSourceData values, in their JSON
+// encodings; "total_estimate" is the match estimate the source recorded on
+// its Trace, if any. Failures use a status code matching the error class (404 not
+// found, 401 unauthorized, 429 rate limited or over quota with Retry-After,
+// 503 unavailable, 400 for malformed requests, 500 otherwise) and a body of
+//
+//	{"error": {"class": "not_found", "message": "...", "retry_after_ms": 0}}
+//
+// so clients can rebuild errors that work with errors.Is and IsRetryable.
+// Data requests may carry a datasource.Projection to leave out heavy
+// fields: the omit query parameter lists any of text, entities, metadata
+// and embeddings, and max_text_bytes truncates the text; streams take the
+// projection's JSON encoding. A projection set on the handler with
+// HandlerConfig applies on top of the request's.
+// FetchTopics requests carry the hashring.Header affinity key, letting a
+// load balancer send equivalent questions to the same replica.
+//
+// Streams deliver large FetchData results with pull-based flow control: the
+// client pulls batches of at most the window, and the server reads no more
+// than one window ahead of the last pull. A pull waits briefly for the
+// first item and returns an empty batch if none is ready; the final batch
+// has "done" set and, if the source failed, an "error". Streams that are
+// not pulled for StreamIdleTimeout are cancelled, and a handler keeps at
+// most HandlerConfig.MaxStreams open, refusing more with 429.
+package remote
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/wire"
+)
+
+// Paths of the protocol endpoints.
+const (
+	PathInit         = "/v1/init"
+	PathHealth       = "/v1/health"
+	PathCapabilities = "/v1/capabilities"
+	PathTopics       = "/v1/topics"
+	PathStreams      = "/v1/streams"
+)
+
+type healthResponse struct {
+	Available bool                     `json:"available"`
+	Health    *datasource.HealthStatus `json:"health,omitempty"`
+}
+
+type topicsRequest struct {
+	Count int                         `json:"count"`
+	Input datasource.NewQuestionInput `json:"input"`
+}
+
+type topicsResponse struct {
+	Topics        []datasource.DataSourceTopic `json:"topics"`
+	TotalEstimate int64                        `json:"total_estimate,omitempty"`
+}
+
+type dataResponse struct {
+	Data []datasource.DataSourceData `json:"data"`
+}
+
+// omitFields names the fields of the omit query parameter.
+var omitFields = []struct {
+	name  string
+	field func(*datasource.Projection) *bool
+}{
+	{"text", func(p *datasource.Projection) *bool { return &p.OmitText }},
+	{"entities", func(p *datasource.Projection) *bool { return &p.OmitEntities }},
+	{"metadata", func(p *datasource.Projection) *bool { return &p.OmitMetadata }},
+	{"embeddings", func(p *datasource.Projection) *bool { return &p.OmitEmbeddings }},
+}
+
+// encodeProjection adds p to the query parameters of a data request.
+func encodeProjection(q url.Values, p datasource.Projection) {
+	var omit []string
+	for _, f := range omitFields {
+		if *f.field(&p) {
+			omit = append(omit, f.name)
+		}
+	}
+	if len(omit) > 0 {
+		q.Set("omit", strings.Join(omit, ","))
+	}
+	if p.MaxTextBytes > 0 {
+		q.Set("max_text_bytes", strconv.Itoa(p.MaxTextBytes))
+	}
+}
+
+// decodeProjection reads the projection of a data request.
+func decodeProjection(q url.Values) (datasource.Projection, error) {
+	var p datasource.Projection
+	if v := q.Get("omit"); v != "" {
+	names:
+		for _, name := range strings.Split(v, ",") {
+			for _, f := range omitFields {
+				if f.name == name {
+					*f.field(&p) = true
+					continue names
+				}
+			}
+			return p, fmt.Errorf("%w: invalid omit field %q", errBadRequest, name)
+		}
+	}
+	if v := q.Get("max_text_bytes"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return p, fmt.Errorf("%w: invalid max_text_bytes", errBadRequest)
+		}
+		p.MaxTextBytes = n
+	}
+	return p, nil
+}
+
+type errorResponse struct {
+	Error *wire.Error `json:"error"`
+}
@@ -0,0 +1,261 @@
+package remote_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/hashring"
+	"github.com/locus-search/datasource-sdk/remote"
+)
+
+func newFake() *datasourcetest.Fake {
+	return &datasourcetest.Fake{
+		Topics: []datasource.DataSourceTopic{{Topic: "DNS", SourceURL: "https://kb/1", TopicID: 1}},
+		Data: map[int64][]datasource.DataSourceData{
+			1: {{DataText: "answer", AnswerID: 10}},
+		},
+	}
+}
+
+func serve(t *testing.T, h http.Handler) *remote.Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return remote.NewClient(srv.URL + "/")
+}
+
+func TestRemoteConformance(t *testing.T) {
+	datasourcetest.RunConformance(t, serve(t, remote.NewHandler(newFake())))
+}
+
+func TestRemoteRoundTrip(t *testing.T) {
+	fake := newFake()
+	var affinity, auth string
+	c := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == remote.PathTopics {
+			affinity, auth = r.Header.Get(hashring.Header), r.Header.Get("Authorization")
+		}
+		remote.NewHandler(fake).ServeHTTP(w, r)
+	}))
+	c.Header.Set("Authorization", "Bearer t")
+
+	input := datasource.NewQuestionInput{QuestionText: "what is dns", Tags: []string{"net"}}
+	topics, err := c.FetchTopics(5, input)
+	if err != nil || len(topics) != 1 || topics[0].SourceURL != "https://kb/1" {
+		t.Fatalf("FetchTopics = %+v, %v", topics, err)
+	}
+	if got := fake.CallsTo(datasource.MethodFetchTopics)[0]; got.Count != 5 || got.Input.Tags[0] != "net" {
+		t.Errorf("server received %+v", got)
+	}
+	if affinity != hashring.Key(input) || auth != "Bearer t" {
+		t.Errorf("headers: affinity %q, auth %q", affinity, auth)
+	}
+
+	data, err := c.FetchData(2, 1)
+	if err != nil || len(data) != 1 || data[0].AnswerID != 10 {
+		t.Errorf("FetchData = %+v, %v", data, err)
+	}
+	if got := fake.CallsTo(datasource.MethodFetchData)[0]; got.Count != 2 || got.TopicID != 1 {
+		t.Errorf("server received %+v", got)
+	}
+}
+
+func TestRemoteProjection(t *testing.T) {
+	fake := &datasourcetest.Fake{
+		Topics: []datasource.DataSourceTopic{{Topic: "DNS", TopicID: 1}},
+		Data: map[int64][]datasource.DataSourceData{
+			1: {{DataText: "a long answer", AnswerID: 10, Embedding: []float64{0.1}, Entities: []datasource.Entity{{ID: "dns"}}}},
+		},
+	}
+	c := serve(t, remote.NewHandlerConfig(fake, remote.HandlerConfig{Projection: datasource.Projection{OmitEntities: true}}))
+	c.Projection = datasource.Projection{MaxTextBytes: 6, OmitEmbeddings: true}
+
+	data, err := c.FetchData(1, 1)
+	if err != nil || len(data) != 1 || data[0].DataText != "a long" || data[0].Embedding != nil || data[0].Entities != nil {
+		t.Fatalf("FetchData = %+v, %v", data, err)
+	}
+	var streamed []datasource.DataSourceData
+	err = c.StreamData(context.Background(), 1, 1, func(d datasource.DataSourceData) error {
+		streamed = append(streamed, d)
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(streamed, data) {
+		t.Errorf("StreamData = %+v, %v, want %+v", streamed, err, data)
+	}
+
+	full, err := c.Hydrate(datasource.Refs(1, data, 0))
+	if err != nil || len(full) != 1 || full[0].DataText != "a long answer" || full[0].Embedding == nil {
+		t.Fatalf("Hydrate = %+v, %v", full, err)
+	}
+	if full[0].Entities != nil {
+		t.Error("the handler's projection should apply to hydrated items")
+	}
+	if c.Projection.MaxTextBytes != 6 {
+		t.Error("Hydrate should not change the client's projection")
+	}
+}
+
+func TestRemoteDoesNotSendTrace(t *testing.T) {
+	var input map[string]json.RawMessage
+	c := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Input map[string]json.RawMessage `json:"input"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &req)
+		input = req.Input
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		remote.NewHandler(newFake()).ServeHTTP(w, r)
+	}))
+
+	trace := new(datasource.Trace)
+	trace.Note("seen")
+	if _, err := c.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q", Trace: trace}); err != nil {
+		t.Fatal(err)
+	}
+	if input == nil {
+		t.Fatal("server received no input")
+	}
+	for key := range input {
+		if strings.EqualFold(key, "trace") {
+			t.Errorf("request input has key %q", key)
+		}
+	}
+}
+
+func TestHandlerFillsTopic(t *testing.T) {
+	fake := &datasourcetest.Fake{Topics: []datasource.DataSourceTopic{
+		{Title: "DNS", BodyExcerpt: "Name resolution.", Path: []string{"Networking"}, TopicID: 1},
+	}}
+	topics, err := serve(t, remote.NewHandler(fake)).FetchTopics(5, datasource.NewQuestionInput{QuestionText: "dns"})
+	if err != nil || len(topics) != 1 {
+		t.Fatalf("FetchTopics = %+v, %v", topics, err)
+	}
+	if got := topics[0]; got.Topic != "DNS" || got.Title != "DNS" || got.BodyExcerpt != "Name resolution." || got.Path[0] != "Networking" {
+		t.Errorf("topic = %+v", got)
+	}
+}
+
+func TestRemoteSendsBudgetAsTimeLeft(t *testing.T) {
+	fake := newFake()
+	c := serve(t, remote.NewHandler(fake))
+
+	deadline := time.Now().Add(time.Minute)
+	if _, err := c.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q", Budget: datasource.Budget{Deadline: deadline}}); err != nil {
+		t.Fatal(err)
+	}
+	got := fake.CallsTo(datasource.MethodFetchTopics)[0].Input.Budget
+	if !got.Deadline.IsZero() || got.MaxLatency <= 0 || got.MaxLatency > time.Minute {
+		t.Errorf("server received budget %+v, want the time left as MaxLatency", got)
+	}
+
+	spent := datasource.Budget{Deadline: time.Now().Add(-time.Second)}
+	if _, err := c.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q", Budget: spent}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchTopics with a spent budget = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestRemoteErrors(t *testing.T) {
+	fake := newFake()
+	c := serve(t, remote.NewHandler(fake))
+
+	fake.FailNext(datasource.MethodFetchTopics, &datasource.ErrRateLimited{RetryAfter: 1500 * time.Millisecond})
+	_, err := c.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q"})
+	if d, ok := datasource.RetryAfter(err); !ok || d != 1500*time.Millisecond {
+		t.Errorf("rate limit not preserved: %v (%v, %v)", err, d, ok)
+	}
+
+	fake.FailNext(datasource.MethodInit, datasource.ErrUnauthorized)
+	if err := c.Init(); !errors.Is(err, datasource.ErrUnauthorized) {
+		t.Errorf("Init = %v", err)
+	}
+
+	fake.Errors = map[datasource.Method]error{datasource.MethodCheckAvailability: datasource.ErrUnavailable}
+	if c.CheckAvailability() {
+		t.Error("CheckAvailability should be false")
+	}
+}
+
+func TestHandlerRejectsBadRequests(t *testing.T) {
+	h := remote.NewHandler(newFake())
+	tests := []struct {
+		method, path, body string
+		status             int
+	}{
+		{"GET", remote.PathTopics, "", http.StatusMethodNotAllowed},
+		{"POST", remote.PathTopics, "{not json", http.StatusBadRequest},
+		{"GET", "/v1/topics/abc/data?count=1", "", http.StatusBadRequest},
+		{"GET", "/v1/topics/1/data", "", http.StatusBadRequest},
+		{"GET", "/v1/topics/1/data?count=1&omit=text,body", "", http.StatusBadRequest},
+		{"GET", "/v1/topics/1/data?count=1&max_text_bytes=-1", "", http.StatusBadRequest},
+		{"GET", "/v2/health", "", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body)))
+		if rec.Code != tt.status {
+			t.Errorf("%s %s = %d, want %d", tt.method, tt.path, rec.Code, tt.status)
+		}
+	}
+}
+
+func TestClientHandlesNonProtocolErrors(t *testing.T) {
+	c := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "<html>bad gateway</html>", http.StatusBadGateway)
+	}))
+	if _, err := c.FetchData(1, 1); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchData = %v, want ErrUnavailable", err)
+	}
+
+	c = remote.NewClient("http://127.0.0.1:1")
+	if _, err := c.FetchData(1, 1); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("unreachable service = %v, want ErrUnavailable", err)
+	}
+}
+
+type degradedSource struct{ *datasourcetest.Fake }
+
+func (degradedSource) HealthCheck() datasource.HealthStatus {
+	return datasource.HealthStatus{State: datasource.Degraded, Error: "index lagging"}
+}
+
+func TestRemoteHealthCheck(t *testing.T) {
+	c := serve(t, remote.NewHandler(degradedSource{newFake()}))
+	if h := c.HealthCheck(); h.State != datasource.Degraded || h.Error != "index lagging" {
+		t.Errorf("HealthCheck = %+v", h)
+	}
+	if !c.CheckAvailability() {
+		t.Error("a degraded source should report available")
+	}
+
+	down := remote.NewClient("http://127.0.0.1:1/")
+	if h := down.HealthCheck(); h.State != datasource.Unhealthy || h.Error == "" {
+		t.Errorf("unreachable HealthCheck = %+v", h)
+	}
+}
+
+type capableSource struct{ *datasourcetest.Fake }
+
+func (capableSource) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{Embeddings: true, QueryEmbeddings: []string{"code"}, Pagination: true, Streaming: true}
+}
+
+func TestRemoteCapabilities(t *testing.T) {
+	c := serve(t, remote.NewHandler(capableSource{newFake()}))
+	// The client streams data itself, so Streaming is always reported.
+	want := datasource.Capabilities{Embeddings: true, QueryEmbeddings: []string{"code"}, Pagination: true, Streaming: true}
+	if got := datasource.CapabilitiesOf(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("Capabilities = %+v, want %+v", got, want)
+	}
+}
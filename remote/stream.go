@@ -0,0 +1,282 @@
+package remote
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/wire"
+)
+
+// Streaming flow control. The server reads ahead at most the window of
+// items the client asked for; while the window is full the source's
+// StreamData callback blocks, so a slow client slows the source down
+// instead of growing buffers.
+const (
+	// DefaultStreamWindow is the window used when Client.StreamWindow is
+	// zero
+	DefaultStreamWindow = 64
+
+	// MaxStreamWindow caps the window a client may request
+	MaxStreamWindow = 1024
+
+	// DefaultMaxStreams is the number of streams a handler keeps open at
+	// once when HandlerConfig.MaxStreams is zero
+	DefaultMaxStreams = 64
+
+	// streamsRetryAfter is the wait suggested to clients refused a stream
+	streamsRetryAfter = time.Second
+
+	// StreamIdleTimeout is how long the server keeps a stream that is not
+	// being pulled before cancelling it
+	StreamIdleTimeout = time.Minute
+
+	// streamPollWait bounds how long a pull waits for the first item; it
+	// is below DefaultTimeout so idle pulls return before clients give up
+	streamPollWait = 20 * time.Second
+)
+
+type streamRequest struct {
+	TopicID int64 `json:"topic_id"`
+	Count   int   `json:"count"`
+	Window  int   `json:"window"`
+
+	Projection datasource.Projection `json:"projection"`
+}
+
+type streamResponse struct {
+	Stream string `json:"stream"`
+	Window int    `json:"window"`
+}
+
+type pullResponse struct {
+	Data  []datasource.DataSourceData `json:"data"`
+	Done  bool                        `json:"done"`
+	Error *wire.Error                 `json:"error,omitempty"`
+}
+
+// stream is a server-side stream being read ahead into items.
+type stream struct {
+	items  chan datasource.DataSourceData // closed when the source is done
+	err    error                          // the source's error; set before items is closed
+	window int
+	cancel context.CancelFunc
+	idle   *time.Timer
+}
+
+func (h *handler) serveStreams(w http.ResponseWriter, r *http.Request) {
+	id := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, PathStreams), "/")
+	switch {
+	case id == "":
+		if allow(w, r, http.MethodPost) {
+			h.openStream(w, r)
+		}
+	case r.Method == http.MethodGet:
+		h.pullStream(w, r, id)
+	case r.Method == http.MethodDelete:
+		h.closeStream(id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *handler) openStream(w http.ResponseWriter, r *http.Request) {
+	var req streamRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&req); err != nil {
+		writeError(w, fmt.Errorf("%w: %v", errBadRequest, err))
+		return
+	}
+	window := req.Window
+	if window <= 0 {
+		window = DefaultStreamWindow
+	}
+	window = min(window, MaxStreamWindow)
+
+	id, err := newStreamID()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	h.mu.Lock()
+	if len(h.streams) >= h.cfg.MaxStreams {
+		h.mu.Unlock()
+		writeError(w, &datasource.ErrRateLimited{RetryAfter: streamsRetryAfter, Err: fmt.Errorf("remote: %d streams open", h.cfg.MaxStreams)})
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &stream{items: make(chan datasource.DataSourceData, window), window: window, cancel: cancel}
+	s.idle = time.AfterFunc(StreamIdleTimeout, func() { h.closeStream(id) })
+	h.streams[id] = s
+	h.mu.Unlock()
+
+	project := h.cfg.Projection != (datasource.Projection{}) || req.Projection != (datasource.Projection{})
+	go func() {
+		s.err = datasource.StreamData(ctx, h.ds, req.Count, req.TopicID, func(d datasource.DataSourceData) error {
+			if project {
+				d = h.project(req.Projection, []datasource.DataSourceData{d})[0]
+			}
+			select {
+			case s.items <- d:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+		close(s.items)
+	}()
+	writeJSON(w, http.StatusCreated, streamResponse{Stream: id, Window: window})
+}
+
+// pullStream returns the items read ahead so far, up to the max query
+// parameter, waiting up to streamPollWait for the first one.
+func (h *handler) pullStream(w http.ResponseWriter, r *http.Request, id string) {
+	h.mu.Lock()
+	s := h.streams[id]
+	h.mu.Unlock()
+	if s == nil {
+		writeError(w, fmt.Errorf("remote: stream %s: %w", id, datasource.ErrNotFound))
+		return
+	}
+	max := s.window
+	if v := r.URL.Query().Get("max"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, fmt.Errorf("%w: invalid max", errBadRequest))
+			return
+		}
+		max = min(n, s.window)
+	}
+	s.idle.Stop()
+	defer s.idle.Reset(StreamIdleTimeout)
+
+	resp := pullResponse{Data: []datasource.DataSourceData{}}
+	wait := time.NewTimer(streamPollWait)
+	defer wait.Stop()
+	select {
+	case d, ok := <-s.items:
+		if ok {
+			resp.Data = append(resp.Data, d)
+		} else {
+			resp.Done = true
+		}
+	case <-wait.C:
+	case <-r.Context().Done():
+		return
+	}
+	for !resp.Done && len(resp.Data) < max {
+		select {
+		case d, ok := <-s.items:
+			if ok {
+				resp.Data = append(resp.Data, d)
+			} else {
+				resp.Done = true
+			}
+			continue
+		default:
+		}
+		break
+	}
+	if resp.Done {
+		resp.Error = wire.EncodeError(s.err)
+		h.closeStream(id)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// closeStream cancels a stream and forgets it.
+func (h *handler) closeStream(id string) {
+	h.mu.Lock()
+	s := h.streams[id]
+	delete(h.streams, id)
+	h.mu.Unlock()
+	if s != nil {
+		s.idle.Stop()
+		s.cancel()
+	}
+}
+
+func newStreamID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("remote: stream ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+var _ datasource.DataStreamer = (*Client)(nil)
+
+// StreamData implements datasource.DataStreamer. It pulls items from the
+// server in batches of at most StreamWindow, and the server reads ahead no
+// further than that, so memory on both sides stays bounded however slowly
+// fn consumes items. Servers that predate streaming are read with
+// FetchData instead.
+func (c *Client) StreamData(ctx context.Context, count int, topicID int64, fn func(datasource.DataSourceData) error) error {
+	window := c.StreamWindow
+	if window <= 0 {
+		window = DefaultStreamWindow
+	}
+	var opened streamResponse
+	err := c.doContext(ctx, http.MethodPost, PathStreams, streamRequest{TopicID: topicID, Count: count, Window: window, Projection: c.Projection}, nil, &opened)
+	if errors.Is(err, errNotServed) {
+		return c.fetchEach(ctx, count, topicID, fn)
+	}
+	if err != nil {
+		return err
+	}
+
+	path := PathStreams + "/" + url.PathEscape(opened.Stream)
+	done := false
+	defer func() {
+		if !done {
+			// Release the server's read-ahead; the stream would otherwise
+			// linger until StreamIdleTimeout.
+			c.do(http.MethodDelete, path, nil, nil, nil)
+		}
+	}()
+	query := "?" + url.Values{"max": {strconv.Itoa(opened.Window)}}.Encode()
+	for {
+		var r pullResponse
+		if err := c.doContext(ctx, http.MethodGet, path+query, nil, nil, &r); err != nil {
+			return err
+		}
+		done = r.Done
+		for _, d := range r.Data {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if err := fn(d); err != nil {
+				return err
+			}
+		}
+		if r.Done {
+			return r.Error.Err()
+		}
+	}
+}
+
+func (c *Client) fetchEach(ctx context.Context, count int, topicID int64, fn func(datasource.DataSourceData) error) error {
+	items, err := c.FetchData(count, topicID)
+	if err != nil {
+		return err
+	}
+	for _, d := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
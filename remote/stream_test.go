@@ -0,0 +1,195 @@
+package remote_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/datasourcetest"
+	"github.com/locus-search/datasource-sdk/remote"
+)
+
+// counterSource streams n items, counting how many it produced, and fails
+// with failAfter's error once that many were produced.
+type counterSource struct {
+	*datasourcetest.Fake
+	n         int
+	failAfter int
+	produced  atomic.Int64
+	cancelled chan struct{}
+}
+
+func newCounterSource(n int) *counterSource {
+	return &counterSource{Fake: &datasourcetest.Fake{}, n: n, cancelled: make(chan struct{})}
+}
+
+func (s *counterSource) StreamData(ctx context.Context, count int, topicID int64, fn func(datasource.DataSourceData) error) error {
+	for i := 0; i < min(count, s.n); i++ {
+		if s.failAfter > 0 && i == s.failAfter {
+			return fmt.Errorf("topic %d: %w", topicID, datasource.ErrNotFound)
+		}
+		if err := ctx.Err(); err != nil {
+			close(s.cancelled)
+			return err
+		}
+		s.produced.Add(1)
+		if err := fn(datasource.DataSourceData{AnswerID: int64(i + 1)}); err != nil {
+			if ctx.Err() != nil {
+				close(s.cancelled)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStreamData(t *testing.T) {
+	c := serve(t, remote.NewHandler(newCounterSource(200)))
+	c.StreamWindow = 8
+
+	var got []int64
+	err := datasource.StreamData(context.Background(), c, 150, 1, func(d datasource.DataSourceData) error {
+		got = append(got, d.AnswerID)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 150 || got[0] != 1 || got[149] != 150 {
+		t.Errorf("got %d items (%v...), want 150 in order", len(got), got[:min(3, len(got))])
+	}
+}
+
+func TestStreamDataBackpressure(t *testing.T) {
+	src := newCounterSource(1000)
+	c := serve(t, remote.NewHandler(src))
+	c.StreamWindow = 4
+
+	err := datasource.StreamData(context.Background(), c, 1000, 1, func(d datasource.DataSourceData) error {
+		// A slow consumer: give the server time to read ahead as far as
+		// it will.
+		time.Sleep(50 * time.Millisecond)
+		return datasource.ErrStopStream
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// One pulled batch, one buffered window, and one item blocked in the
+	// callback.
+	if n := src.produced.Load(); n > 2*4+1 {
+		t.Errorf("source produced %d items for a stalled consumer, want at most %d", n, 2*4+1)
+	}
+	select {
+	case <-src.cancelled:
+	case <-time.After(2 * time.Second):
+		t.Error("stopping the stream did not cancel the source")
+	}
+}
+
+func TestStreamDataError(t *testing.T) {
+	src := newCounterSource(10)
+	src.failAfter = 3
+	c := serve(t, remote.NewHandler(src))
+
+	n := 0
+	err := datasource.StreamData(context.Background(), c, 10, 7, func(datasource.DataSourceData) error {
+		n++
+		return nil
+	})
+	if !errors.Is(err, datasource.ErrNotFound) || n != 3 {
+		t.Errorf("got %d items and %v, want 3 items then ErrNotFound", n, err)
+	}
+}
+
+func TestStreamDataContext(t *testing.T) {
+	src := newCounterSource(1000)
+	c := serve(t, remote.NewHandler(src))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	err := datasource.StreamData(ctx, c, 1000, 1, func(datasource.DataSourceData) error {
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("StreamData = %v, want context.Canceled", err)
+	}
+}
+
+func TestStreamDataFallsBackToFetchData(t *testing.T) {
+	fake := newFake()
+	h := remote.NewHandler(fake)
+	c := serve(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, remote.PathStreams) {
+			http.NotFound(w, r) // a server that predates streaming
+			return
+		}
+		h.ServeHTTP(w, r)
+	}))
+
+	var got []datasource.DataSourceData
+	err := c.StreamData(context.Background(), 5, 1, func(d datasource.DataSourceData) error {
+		got = append(got, d)
+		return nil
+	})
+	if err != nil || len(got) != 1 || got[0].DataText != "answer" {
+		t.Errorf("StreamData = %v, %v; want the FetchData result", got, err)
+	}
+}
+
+func TestPullUnknownStream(t *testing.T) {
+	c := serve(t, remote.NewHandler(newFake()))
+	resp, err := http.Get(c.BaseURL + remote.PathStreams + "/nope")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestStreamsAreCapped(t *testing.T) {
+	c := serve(t, remote.NewHandlerConfig(newCounterSource(1000), remote.HandlerConfig{MaxStreams: 1}))
+	open := func() *http.Response {
+		t.Helper()
+		resp, err := http.Post(c.BaseURL+remote.PathStreams, "application/json", strings.NewReader(`{"topic_id": 1, "count": 1000}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+
+	first := open()
+	var opened struct{ Stream string }
+	json.NewDecoder(first.Body).Decode(&opened)
+	first.Body.Close()
+	if first.StatusCode != http.StatusCreated {
+		t.Fatalf("first stream: status %d", first.StatusCode)
+	}
+	resp := open()
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Errorf("stream past the cap: status %d, Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	err := c.StreamData(context.Background(), 1, 1, func(datasource.DataSourceData) error { return nil })
+	if _, ok := datasource.RetryAfter(err); !ok {
+		t.Errorf("client stream past the cap = %v, want a rate limit", err)
+	}
+
+	req, _ := http.NewRequest(http.MethodDelete, c.BaseURL+remote.PathStreams+"/"+opened.Stream, nil)
+	if resp, err := http.DefaultClient.Do(req); err == nil {
+		resp.Body.Close()
+	}
+	resp = open()
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("stream after closing one: status %d", resp.StatusCode)
+	}
+}
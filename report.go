@@ -0,0 +1,192 @@
+package datasource
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// QueryReport explains how a multiplexer answered one question: for each
+// source it queried, how long the source took, what it returned, how much
+// of that made it into the answer, and what went wrong. It is the artifact
+// to look at when an asker reports a bad answer. Multiplexers such as
+// router.Router return it on request and fire it through
+// Hooks.OnQueryReport.
+type QueryReport struct {
+	// Query is the question text
+	Query string `json:"query"`
+
+	// Start is when the question was received
+	Start time.Time `json:"start"`
+
+	// Duration is how long answering took
+	Duration time.Duration `json:"duration"`
+
+	// Count is the number of topics requested
+	Count int `json:"count"`
+
+	// Returned is the number of topics in the answer
+	Returned int `json:"returned"`
+
+	// TotalEstimate is the sum of the sources' TotalEstimate
+	TotalEstimate int64 `json:"total_estimate,omitempty"`
+
+	// Sources has an entry for every source queried, in query order
+	Sources []SourceReport `json:"sources"`
+}
+
+// SourceReport is a source's part in a QueryReport.
+type SourceReport struct {
+	// Source names the source
+	Source string `json:"source"`
+
+	// Latency is how long the source took to answer, or until it was
+	// given up on
+	Latency time.Duration `json:"latency"`
+
+	// Topics is the number of topics the source returned
+	Topics int `json:"topics"`
+
+	// Filtered is the number of topics decorators of the source dropped,
+	// such as results in languages the asker does not read
+	Filtered int `json:"filtered,omitempty"`
+
+	// Kept is the number of the source's topics in the answer; the others
+	// were outranked or beyond the requested count
+	Kept int `json:"kept"`
+
+	// TotalEstimate is about how many of the source's topics match the
+	// question in all, as it recorded with Trace.Estimate; zero if it gave
+	// no estimate
+	TotalEstimate int64 `json:"total_estimate,omitempty"`
+
+	// CacheHit means a cache answered for the source
+	CacheHit bool `json:"cache_hit,omitempty"`
+
+	// Error is the source's error, if it failed
+	Error string `json:"error,omitempty"`
+
+	// ErrorClass is the ErrorClass of the source's error
+	ErrorClass string `json:"error_class,omitempty"`
+
+	// Notes are remarks decorators recorded, such as retries
+	Notes []string `json:"notes,omitempty"`
+}
+
+// Coverage returns the share of the matches the sources estimated that
+// the answer holds, between 0 and 1, and false if no source gave an
+// estimate. Sources without an estimate are left out, so a low coverage
+// means deeper pages have more to offer.
+func (r QueryReport) Coverage() (float64, bool) {
+	var kept, total int64
+	for _, s := range r.Sources {
+		if s.TotalEstimate > 0 {
+			kept += min(int64(s.Kept), s.TotalEstimate)
+			total += s.TotalEstimate
+		}
+	}
+	if total == 0 {
+		return 0, false
+	}
+	return float64(kept) / float64(total), true
+}
+
+// String formats the report as a table, one line per source.
+func (r QueryReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "query %q: %d of %d topics in %s", r.Query, r.Returned, r.Count, r.Duration.Round(time.Millisecond))
+	if r.TotalEstimate > 0 {
+		fmt.Fprintf(&b, ", about %d matching", r.TotalEstimate)
+	}
+	b.WriteByte('\n')
+	for _, s := range r.Sources {
+		fmt.Fprintf(&b, "  %s: %s, %d topics, %d kept", s.Source, s.Latency.Round(time.Millisecond), s.Topics, s.Kept)
+		if s.TotalEstimate > 0 {
+			fmt.Fprintf(&b, ", about %d matching", s.TotalEstimate)
+		}
+		if s.Filtered > 0 {
+			fmt.Fprintf(&b, ", %d filtered", s.Filtered)
+		}
+		if s.CacheHit {
+			b.WriteString(", cache hit")
+		}
+		if s.Error != "" {
+			fmt.Fprintf(&b, ", error (%s): %s", s.ErrorClass, s.Error)
+		}
+		for _, n := range s.Notes {
+			b.WriteString(", " + n)
+		}
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// Trace collects what happens to a question on its way to one source, for
+// the source's SourceReport. Multiplexers building a QueryReport give each
+// source a NewQuestionInput with its own Trace; decorators record on it.
+// Its methods do nothing on a nil Trace, so decorators call them
+// unconditionally. A Trace is safe for concurrent use.
+type Trace struct {
+	mu       sync.Mutex
+	cacheHit bool
+	filtered int
+	estimate int64
+	notes    []string
+}
+
+// CacheHit records that a cache answered.
+func (t *Trace) CacheHit() {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cacheHit = true
+}
+
+// Filtered records that n results were dropped.
+func (t *Trace) Filtered(n int) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.filtered += n
+}
+
+// Estimate records that about n topics match the question in all, as a
+// source learned from its search response; see MatchEstimator.
+func (t *Trace) Estimate(n int64) {
+	if t == nil || n <= 0 {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.estimate = n
+}
+
+// Note records a remark for the report.
+func (t *Trace) Note(note string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.notes = append(t.notes, note)
+}
+
+// Fill copies what t recorded into r.
+func (t *Trace) Fill(r *SourceReport) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	r.CacheHit = r.CacheHit || t.cacheHit
+	r.Filtered += t.filtered
+	if t.estimate > 0 {
+		r.TotalEstimate = t.estimate
+	}
+	r.Notes = append(r.Notes, t.notes...)
+}
@@ -0,0 +1,65 @@
+package datasource_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestTrace(t *testing.T) {
+	var none *datasource.Trace
+	none.CacheHit()
+	none.Filtered(2)
+	none.Note("ignored")
+	var r datasource.SourceReport
+	none.Fill(&r)
+	if r.CacheHit || r.Filtered != 0 || len(r.Notes) != 0 {
+		t.Errorf("nil trace filled %+v", r)
+	}
+
+	tr := new(datasource.Trace)
+	tr.CacheHit()
+	tr.Filtered(2)
+	tr.Filtered(-1)
+	tr.Filtered(1)
+	tr.Note("2 attempts")
+	tr.Estimate(0)
+	tr.Estimate(1200)
+	tr.Fill(&r)
+	if !r.CacheHit || r.Filtered != 3 || strings.Join(r.Notes, ",") != "2 attempts" || r.TotalEstimate != 1200 {
+		t.Errorf("filled %+v", r)
+	}
+}
+
+func TestQueryReportString(t *testing.T) {
+	report := datasource.QueryReport{
+		Query: "reset password", Count: 5, Returned: 2, TotalEstimate: 40, Duration: 40 * time.Millisecond,
+		Sources: []datasource.SourceReport{
+			{Source: "kb", Latency: 12 * time.Millisecond, Topics: 3, Kept: 2, TotalEstimate: 40, Filtered: 1, CacheHit: true},
+			{Source: "wiki", Latency: 40 * time.Millisecond, Error: "timeout", ErrorClass: "unavailable", Notes: []string{"3 attempts"}},
+		},
+	}
+	want := `query "reset password": 2 of 5 topics in 40ms, about 40 matching
+  kb: 12ms, 3 topics, 2 kept, about 40 matching, 1 filtered, cache hit
+  wiki: 40ms, 0 topics, 0 kept, error (unavailable): timeout, 3 attempts
+`
+	if got := report.String(); got != want {
+		t.Errorf("String() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestQueryReportCoverage(t *testing.T) {
+	report := datasource.QueryReport{Sources: []datasource.SourceReport{
+		{Source: "kb", Kept: 3, TotalEstimate: 12},
+		{Source: "forum", Kept: 2, TotalEstimate: 1},
+		{Source: "wiki", Kept: 4},
+	}}
+	if got, ok := report.Coverage(); !ok || got != 4.0/13 {
+		t.Errorf("Coverage() = %v, %v; want 4/13", got, ok)
+	}
+	if _, ok := (datasource.QueryReport{Sources: report.Sources[2:]}).Coverage(); ok {
+		t.Error("Coverage() without estimates reported one")
+	}
+}
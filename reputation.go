@@ -0,0 +1,48 @@
+package datasource
+
+import "math"
+
+// DefaultReputationWeight is the Reputation weight used when it is zero.
+const DefaultReputationWeight = 0.3
+
+// Reputation reranks data items to prefer those by reputable authors. Each
+// item's relevance, as in Recency, is blended with its author's
+// reputation on a log scale relative to the most reputable author in the
+// list, so 10,000 against 100,000 counts for more than 10 against 100.
+// Items without an author or reputation get no boost.
+//
+//	items = datasource.Reputation{Weight: 0.2}.Data(items)
+type Reputation struct {
+	// Weight is the share of reputation in the blended score, between 0
+	// and 1
+	// Defaults to DefaultReputationWeight
+	Weight float64
+}
+
+// Data returns a copy of items reordered by blended relevance and author
+// reputation. Scores and ranks are not modified.
+func (r Reputation) Data(items []DataSourceData) []DataSourceData {
+	if r.Weight <= 0 || r.Weight > 1 {
+		r.Weight = DefaultReputationWeight
+	}
+	top := 0.0
+	for _, d := range items {
+		top = max(top, reputation(d))
+	}
+	return blend(items, r.Weight,
+		func(d DataSourceData) float64 { return d.Score },
+		func(d DataSourceData) float64 {
+			if top == 0 {
+				return 0
+			}
+			return reputation(d) / top
+		})
+}
+
+// reputation returns the log-scaled reputation of d's author.
+func reputation(d DataSourceData) float64 {
+	if d.Author == nil || d.Author.Reputation <= 0 {
+		return 0
+	}
+	return math.Log1p(d.Author.Reputation)
+}
@@ -0,0 +1,40 @@
+package datasource_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestReputationData(t *testing.T) {
+	items := []datasource.DataSourceData{
+		{AnswerID: 1, Score: 10, Author: &datasource.Author{Name: "new", Reputation: 1}},
+		{AnswerID: 2, Score: 9, Author: &datasource.Author{Name: "expert", Reputation: 100000}},
+		{AnswerID: 3, Score: 9},
+	}
+	got := datasource.Reputation{Weight: 0.5}.Data(items)
+	// Blended: 0.5*1 + 0.5*0.06 = 0.53, 0.5*0.9 + 0.5 = 0.95, 0.5*0.9 = 0.45.
+	if got[0].AnswerID != 2 || got[1].AnswerID != 1 || got[2].AnswerID != 3 {
+		t.Errorf("order = %d, %d, %d; want 2, 1, 3", got[0].AnswerID, got[1].AnswerID, got[2].AnswerID)
+	}
+
+	unattributed := []datasource.DataSourceData{{AnswerID: 1}, {AnswerID: 2}}
+	if got := (datasource.Reputation{}).Data(unattributed); got[0].AnswerID != 1 {
+		t.Error("items without authors should keep their order")
+	}
+}
+
+func TestAuthorJSON(t *testing.T) {
+	b, err := json.Marshal(datasource.DataSourceData{Author: &datasource.Author{Name: "Ada", ProfileURL: "https://example.com/u/1", Reputation: 42}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d datasource.DataSourceData
+	if err := json.Unmarshal(b, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Author == nil || *d.Author != (datasource.Author{Name: "Ada", ProfileURL: "https://example.com/u/1", Reputation: 42}) {
+		t.Errorf("Author = %+v", d.Author)
+	}
+}
@@ -0,0 +1,100 @@
+// Package rerank orders results by the cosine similarity of their
+// embeddings to the question's, the last step of most semantic sources:
+//
+//	topics = rerank.Reranker{Fusion: 0.3}.Topics(input.Embedding, topics)
+//
+// Results carry their vectors in their Embedding fields; the question's
+// is NewQuestionInput.Embedding. Fusion blends similarity with the
+// upstream ordering, so a lexical engine's ranking is refined rather than
+// discarded.
+package rerank
+
+import (
+	"sort"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/similarity"
+)
+
+// Reranker reorders results by similarity to a query vector. Negative
+// similarities count as zero, as do results without an embedding or with
+// one of a different dimension than the query's.
+type Reranker struct {
+	// Fusion is the share of upstream relevance in the fused score,
+	// between 0 and 1. Upstream relevance is a result's Score scaled by
+	// the highest Score in the list or, if the list is unscored, its
+	// position as 1/(position+1)
+	// Defaults to 0: results are ordered by similarity alone
+	Fusion float64
+
+	// SetScores replaces each result's Score with its fused score and its
+	// Rank with its new 1-based position
+	// Defaults to false: scores and ranks are not modified
+	SetScores bool
+}
+
+// Topics returns a copy of topics reordered by fused similarity to query,
+// highest first; ties keep the upstream order. Without a query vector, or
+// if no topic has an embedding, the copy keeps the upstream order.
+func (r Reranker) Topics(query []float64, topics []datasource.DataSourceTopic) []datasource.DataSourceTopic {
+	return rerank(r, query, topics,
+		func(t *datasource.DataSourceTopic) ([]float64, float64) { return t.Embedding, t.Score },
+		func(t *datasource.DataSourceTopic, score float64, rank int) { t.Score, t.Rank = score, rank })
+}
+
+// Data returns a copy of items reordered by fused similarity to query, as
+// Topics does.
+func (r Reranker) Data(query []float64, items []datasource.DataSourceData) []datasource.DataSourceData {
+	return rerank(r, query, items,
+		func(d *datasource.DataSourceData) ([]float64, float64) { return d.Embedding, d.Score },
+		func(d *datasource.DataSourceData, score float64, rank int) { d.Score, d.Rank = score, rank })
+}
+
+// rerank implements Topics and Data. get returns an item's embedding and
+// upstream score; set records its fused score and rank.
+func rerank[T any](r Reranker, query []float64, items []T, get func(*T) ([]float64, float64), set func(*T, float64, int)) []T {
+	out := append([]T(nil), items...)
+	if len(query) == 0 || !embedded(out, get) {
+		return out
+	}
+	fusion := min(max(r.Fusion, 0), 1)
+	maxScore := 0.0
+	for i := range out {
+		_, score := get(&out[i])
+		maxScore = max(maxScore, score)
+	}
+	fused := make([]float64, len(out))
+	for i := range out {
+		vec, score := get(&out[i])
+		relevance := 1 / float64(i+1)
+		if maxScore > 0 {
+			relevance = max(score, 0) / maxScore
+		}
+		sim := max(similarity.Cosine(query, vec), 0)
+		fused[i] = (1-fusion)*sim + fusion*relevance
+	}
+
+	order := make([]int, len(out))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return fused[order[a]] > fused[order[b]] })
+	sorted := make([]T, len(out))
+	for pos, i := range order {
+		sorted[pos] = out[i]
+		if r.SetScores {
+			set(&sorted[pos], fused[i], pos+1)
+		}
+	}
+	return sorted
+}
+
+// embedded reports whether any item has an embedding.
+func embedded[T any](items []T, get func(*T) ([]float64, float64)) bool {
+	for i := range items {
+		if vec, _ := get(&items[i]); len(vec) > 0 {
+			return true
+		}
+	}
+	return false
+}
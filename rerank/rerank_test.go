@@ -0,0 +1,96 @@
+package rerank
+
+import (
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func topicIDs(topics []datasource.DataSourceTopic) []int64 {
+	ids := make([]int64, len(topics))
+	for i, t := range topics {
+		ids[i] = t.TopicID
+	}
+	return ids
+}
+
+func equal(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestTopics(t *testing.T) {
+	query := []float64{1, 0}
+	topics := []datasource.DataSourceTopic{
+		{TopicID: 1, Score: 9, Embedding: []float64{0, 1}},    // orthogonal
+		{TopicID: 2, Score: 8},                                // no embedding
+		{TopicID: 3, Score: 7, Embedding: []float64{1, 1}},    // 45 degrees
+		{TopicID: 4, Score: 1, Embedding: []float64{2, 0}},    // parallel
+		{TopicID: 5, Score: 5, Embedding: []float64{-1, 0}},   // opposite
+		{TopicID: 6, Score: 6, Embedding: []float64{1, 0, 0}}, // wrong dimension
+	}
+	tests := []struct {
+		name string
+		r    Reranker
+		want []int64
+	}{
+		{"similarity only", Reranker{}, []int64{4, 3, 1, 2, 5, 6}},
+		{"fused", Reranker{Fusion: 0.5}, []int64{3, 4, 1, 2, 6, 5}},
+		{"upstream only", Reranker{Fusion: 1}, []int64{1, 2, 3, 6, 5, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.r.Topics(query, topics)
+			if ids := topicIDs(got); !equal(ids, tt.want) {
+				t.Errorf("order = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+	if ids := topicIDs(topics); !equal(ids, []int64{1, 2, 3, 4, 5, 6}) || topics[0].Rank != 0 {
+		t.Errorf("input modified: %v", ids)
+	}
+}
+
+func TestTopicsUnranked(t *testing.T) {
+	topics := []datasource.DataSourceTopic{
+		{TopicID: 1, Embedding: []float64{0, 1}},
+		{TopicID: 2, Embedding: []float64{1, 0}},
+	}
+	if ids := topicIDs(Reranker{}.Topics(nil, topics)); !equal(ids, []int64{1, 2}) {
+		t.Errorf("without query: %v, want upstream order", ids)
+	}
+	if ids := topicIDs(Reranker{}.Topics([]float64{1, 0}, []datasource.DataSourceTopic{{TopicID: 1}, {TopicID: 2}})); !equal(ids, []int64{1, 2}) {
+		t.Errorf("without embeddings: %v, want upstream order", ids)
+	}
+	// Unscored lists fuse by position: 1 has relevance 1, 2 has 0.5.
+	if ids := topicIDs(Reranker{Fusion: 0.4}.Topics([]float64{1, 0}, topics)); !equal(ids, []int64{2, 1}) {
+		t.Errorf("fused by position: %v, want [2 1]", ids)
+	}
+	if ids := topicIDs(Reranker{Fusion: 0.8}.Topics([]float64{1, 0}, topics)); !equal(ids, []int64{1, 2}) {
+		t.Errorf("fused by position: %v, want [1 2]", ids)
+	}
+}
+
+func TestDataSetScores(t *testing.T) {
+	items := []datasource.DataSourceData{
+		{DataText: "far", Score: 4, Rank: 1, Embedding: []float64{0, 1}},
+		{DataText: "near", Score: 2, Rank: 2, Embedding: []float64{1, 0}},
+	}
+	got := Reranker{Fusion: 0.5, SetScores: true}.Data([]float64{1, 0}, items)
+	if got[0].DataText != "near" || got[0].Rank != 1 || got[0].Score != 0.75 {
+		t.Errorf("first = %+v, want near with rank 1 and score 0.75", got[0])
+	}
+	if got[1].DataText != "far" || got[1].Rank != 2 || got[1].Score != 0.5 {
+		t.Errorf("second = %+v, want far with rank 2 and score 0.5", got[1])
+	}
+	if items[0].Score != 4 || items[0].Rank != 1 {
+		t.Errorf("input modified: %+v", items[0])
+	}
+}
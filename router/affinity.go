@@ -0,0 +1,161 @@
+package router
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/topicmap"
+)
+
+// DefaultAffinityTTL is how long an Affinity remembers an idle session if
+// its TTL is zero.
+const DefaultAffinityTTL = 30 * time.Minute
+
+// now is replaced in tests.
+var now = time.Now
+
+// Affinity remembers, per session, which sources and topics contributed
+// useful results, so that a Router given it with WithAffinity favors them
+// for the follow-up questions of the session (NewQuestionInput.SessionID).
+// Within a session, the results of sources that were useful are interleaved
+// first, most useful first, and topics that were useful before lead their
+// source's results.
+//
+// A topic counts as useful when the host fetches its data through the
+// Router, or reports it with Useful. An Affinity is safe for concurrent
+// use, and may be shared by several Routers.
+type Affinity struct {
+	ttl time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	shown    topicmap.Map[shownTopic] // the session and source of topics returned
+	swept    time.Time
+}
+
+// session is what an Affinity remembers of one session.
+type session struct {
+	seen    time.Time
+	sources map[datasource.DataSource]int // useful topics per source
+	topics  map[int64]bool                // useful topics
+}
+
+// shownTopic is the session a topic was last returned in, and the source
+// that returned it.
+type shownTopic struct {
+	session string
+	source  datasource.DataSource
+}
+
+// NewAffinity returns an Affinity that forgets sessions idle for ttl, or
+// DefaultAffinityTTL if ttl is not positive.
+func NewAffinity(ttl time.Duration) *Affinity {
+	if ttl <= 0 {
+		ttl = DefaultAffinityTTL
+	}
+	return &Affinity{
+		ttl:      ttl,
+		sessions: make(map[string]*session),
+	}
+}
+
+// Useful records that the topic, returned in the session, contributed to
+// an answer. Topics the Affinity has not seen returned in the session are
+// ignored.
+func (a *Affinity) Useful(sessionID string, topicID int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s, ok := a.shown.Get(topicID); ok && s.session == sessionID {
+		a.useful(s, topicID)
+	}
+}
+
+// fetched records that the data of a topic was fetched, which makes it
+// useful in the session it was last returned in.
+func (a *Affinity) fetched(topicID int64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if s, ok := a.shown.Get(topicID); ok {
+		a.useful(s, topicID)
+	}
+}
+
+// useful records a useful topic. Callers hold a.mu.
+func (a *Affinity) useful(s shownTopic, topicID int64) {
+	sess := a.session(s.session)
+	if !sess.topics[topicID] {
+		sess.topics[topicID] = true
+		sess.sources[s.source]++
+	}
+}
+
+// session returns the session with the ID, creating it if needed, and
+// forgets idle sessions. Callers hold a.mu.
+func (a *Affinity) session(id string) *session {
+	t := now()
+	if t.Sub(a.swept) >= a.ttl {
+		for k, s := range a.sessions {
+			if t.Sub(s.seen) >= a.ttl {
+				delete(a.sessions, k)
+			}
+		}
+		a.swept = t
+	}
+	s, ok := a.sessions[id]
+	if !ok || t.Sub(s.seen) >= a.ttl {
+		s = &session{sources: make(map[datasource.DataSource]int), topics: make(map[int64]bool)}
+		a.sessions[id] = s
+	}
+	s.seen = t
+	return s
+}
+
+// order sorts sources by their useful topics in the session, most first,
+// keeping the route's order otherwise, and returns the permutation
+// applied.
+func (a *Affinity) order(sessionID string, sources []datasource.DataSource) []int {
+	a.mu.Lock()
+	sess := a.session(sessionID)
+	useful := make([]int, len(sources))
+	for i, ds := range sources {
+		useful[i] = sess.sources[ds]
+	}
+	a.mu.Unlock()
+
+	perm := make([]int, len(sources))
+	for i := range perm {
+		perm[i] = i
+	}
+	sort.SliceStable(perm, func(i, j int) bool { return useful[perm[i]] > useful[perm[j]] })
+	return perm
+}
+
+// boost moves the topics that were useful in the session to the front of
+// topics, keeping their order otherwise.
+func (a *Affinity) boost(sessionID string, topics []datasource.DataSourceTopic) []datasource.DataSourceTopic {
+	a.mu.Lock()
+	sess := a.session(sessionID)
+	var front, rest []datasource.DataSourceTopic
+	for _, t := range topics {
+		if sess.topics[t.TopicID] {
+			front = append(front, t)
+		} else {
+			rest = append(rest, t)
+		}
+	}
+	a.mu.Unlock()
+	if len(front) == 0 {
+		return topics
+	}
+	return append(front, rest...)
+}
+
+// returned records the topics returned in the session and the sources
+// that returned them.
+func (a *Affinity) returned(sessionID string, topics []datasource.DataSourceTopic, sources []datasource.DataSource) {
+	for i, t := range topics {
+		a.shown.Put(t.TopicID, shownTopic{session: sessionID, source: sources[i]})
+	}
+}
@@ -0,0 +1,87 @@
+package router
+
+import (
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Intent is the kind of question being asked.
+type Intent string
+
+// Intents recognized by KeywordClassifier. Custom classifiers may return any
+// other Intent value as long as the Router is configured with routes for it.
+const (
+	IntentUnknown         Intent = "unknown"
+	IntentHowTo           Intent = "how-to"
+	IntentDefinition      Intent = "definition"
+	IntentTroubleshooting Intent = "troubleshooting"
+	IntentCodeExample     Intent = "code-example"
+	IntentPolicy          Intent = "policy"
+)
+
+// Classifier determines the intent of a question.
+type Classifier interface {
+	Classify(input datasource.NewQuestionInput) Intent
+}
+
+// ClassifierFunc adapts an ordinary function to the Classifier interface.
+type ClassifierFunc func(input datasource.NewQuestionInput) Intent
+
+// Classify calls f(input).
+func (f ClassifierFunc) Classify(input datasource.NewQuestionInput) Intent {
+	return f(input)
+}
+
+// KeywordClassifier is a lightweight heuristic Classifier based on phrases
+// in the question text and tags. It needs no model or network access and is
+// a reasonable default; hosts with an ML classifier can supply their own.
+type KeywordClassifier struct{}
+
+// keywordRules are evaluated in order; the first intent with a matching
+// phrase wins. More specific intents come first.
+var keywordRules = []struct {
+	intent   Intent
+	prefixes []string
+	phrases  []string
+}{
+	{
+		intent:  IntentPolicy,
+		phrases: []string{"policy", "policies", "allowed to", "permitted", "compliance", "guideline", "approval", "expense"},
+	},
+	{
+		intent:  IntentTroubleshooting,
+		phrases: []string{"error", "exception", "fails", "failing", "failed", "not working", "doesn't work", "crash", "broken", "panic", "stack trace", "timeout", "fix "},
+	},
+	{
+		intent:  IntentCodeExample,
+		phrases: []string{"example", "snippet", "sample code", "code for", "code to"},
+	},
+	{
+		intent:   IntentHowTo,
+		prefixes: []string{"how do", "how to", "how can", "how should", "steps to"},
+	},
+	{
+		intent:   IntentDefinition,
+		prefixes: []string{"what is", "what are", "what does", "define", "meaning of", "who is"},
+	},
+}
+
+// Classify implements Classifier.
+func (KeywordClassifier) Classify(input datasource.NewQuestionInput) Intent {
+	text := strings.ToLower(strings.Join(strings.Fields(input.QuestionText), " "))
+	tags := strings.ToLower(strings.Join(input.Tags, " "))
+	for _, rule := range keywordRules {
+		for _, p := range rule.prefixes {
+			if strings.HasPrefix(text, p) {
+				return rule.intent
+			}
+		}
+		for _, p := range rule.phrases {
+			if strings.Contains(text, p) || strings.Contains(tags, strings.TrimSpace(p)) {
+				return rule.intent
+			}
+		}
+	}
+	return IntentUnknown
+}
@@ -0,0 +1,457 @@
+// Package router provides a DataSource that classifies each question and
+// queries only the data sources configured for its intent, reducing latency
+// and quota spend on sources that are unlikely to help.
+//
+// Example:
+//
+//	r := router.New(router.KeywordClassifier{}, map[router.Intent][]datasource.DataSource{
+//	    router.IntentPolicy:          {handbook},
+//	    router.IntentTroubleshooting: {stackOverflow, issueTracker},
+//	}, wiki)
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/internal/topicmap"
+)
+
+// Router is a DataSource that routes questions to sources by intent.
+//
+// Topics returned by FetchTopics remember which source produced them so that
+// FetchData can be sent to the same source; sources should therefore use
+// distinct topic IDs, as FetchData fails for an ID more than one source
+// returned. A Router is safe for concurrent use.
+type Router struct {
+	classifier Classifier
+	routes     map[Intent][]datasource.DataSource
+	fallback   []datasource.DataSource
+
+	// allowed restricts the sources queried; nil allows all
+	allowed map[datasource.DataSource]bool
+
+	// names names sources in query reports
+	names map[datasource.DataSource]string
+
+	// affinity favors the sources and topics useful earlier in a session
+	affinity *Affinity
+
+	// owners remembers the source that returned each topic; it is shared
+	// by a Router and its views
+	owners *topicmap.Map[owner]
+}
+
+// owner is the source that returned a topic, identified by name if it has
+// one, so that ownership carries over to a source replacing it (see
+// WithOwners). The zero owner marks a topic more than one source returned.
+type owner struct {
+	ds   datasource.DataSource
+	name string
+}
+
+// New creates a Router. Questions whose intent has no entry in routes are
+// sent to the fallback sources.
+func New(c Classifier, routes map[Intent][]datasource.DataSource, fallback ...datasource.DataSource) *Router {
+	return &Router{
+		classifier: c,
+		routes:     routes,
+		fallback:   fallback,
+		owners:     new(topicmap.Map[owner]),
+	}
+}
+
+// Only returns a view of r that queries only the given sources, for
+// requests the host scopes to a subset, such as a user searching internal
+// documents only. Routes are intersected with the allowed sources, and a
+// question whose routed sources are all excluded goes to the allowed
+// fallback sources. The view shares r's topic ownership table, so FetchData
+// works for topics returned by either. Sources outside r are ignored.
+func (r *Router) Only(sources ...datasource.DataSource) *Router {
+	v := *r
+	v.allowed = make(map[datasource.DataSource]bool, len(sources))
+	for _, ds := range sources {
+		if r.allowed == nil || r.allowed[ds] {
+			v.allowed[ds] = true
+		}
+	}
+	return &v
+}
+
+// allow filters list down to the allowed sources.
+func (r *Router) allow(list []datasource.DataSource) []datasource.DataSource {
+	if r.allowed == nil {
+		return list
+	}
+	var out []datasource.DataSource
+	for _, ds := range list {
+		if r.allowed[ds] {
+			out = append(out, ds)
+		}
+	}
+	return out
+}
+
+// Route returns the classified intent of input and the sources that will be
+// queried for it. Sources in scheduled maintenance (see
+// datasource.InMaintenance) are left out.
+func (r *Router) Route(input datasource.NewQuestionInput) (Intent, []datasource.DataSource) {
+	intent := r.classifier.Classify(input)
+	if sources := r.allow(r.routes[intent]); len(sources) > 0 {
+		return intent, serving(sources)
+	}
+	return intent, serving(r.allow(r.fallback))
+}
+
+// serving filters list down to the sources not in maintenance.
+func serving(list []datasource.DataSource) []datasource.DataSource {
+	out := list[:0:0]
+	for _, ds := range list {
+		if _, ok := datasource.InMaintenance(ds); !ok {
+			out = append(out, ds)
+		}
+	}
+	return out
+}
+
+// sources returns every distinct source known to the router in a stable
+// order.
+func (r *Router) sources() []datasource.DataSource {
+	seen := make(map[datasource.DataSource]bool)
+	var all []datasource.DataSource
+	add := func(list []datasource.DataSource) {
+		for _, ds := range list {
+			if !seen[ds] {
+				seen[ds] = true
+				all = append(all, ds)
+			}
+		}
+	}
+	intents := make([]string, 0, len(r.routes))
+	for intent := range r.routes {
+		intents = append(intents, string(intent))
+	}
+	sort.Strings(intents)
+	for _, intent := range intents {
+		add(r.routes[Intent(intent)])
+	}
+	add(r.fallback)
+	return r.allow(all)
+}
+
+// Init initializes every configured source and returns all failures joined.
+func (r *Router) Init() error {
+	var errs []error
+	for _, ds := range r.sources() {
+		if err := ds.Init(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckAvailability reports whether at least one configured source is
+// available.
+func (r *Router) CheckAvailability() bool {
+	for _, ds := range r.sources() {
+		if ds.CheckAvailability() {
+			return true
+		}
+	}
+	return false
+}
+
+// FetchTopics queries the sources routed for the question's intent
+// concurrently and interleaves their results, up to count topics. Failing
+// sources are skipped, as are sources still running when the question's
+// Budget runs out; an error is returned only if every source fails. The
+// sum of the sources' match estimates is recorded on the input's Trace;
+// see datasource.FetchTopicsPage.
+func (r *Router) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	topics, _, err := r.FetchTopicsReport(count, input)
+	return topics, err
+}
+
+// FetchTopicsReport is FetchTopics, also returning a report of what each
+// routed source contributed. Sources are named as given to Named, or by
+// their index in the route.
+func (r *Router) FetchTopicsReport(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, datasource.QueryReport, error) {
+	report := datasource.QueryReport{Query: input.QuestionText, Start: time.Now(), Count: count}
+	_, sources := r.Route(input)
+	if len(sources) == 0 || count <= 0 {
+		report.Duration = time.Since(report.Start)
+		return []datasource.DataSourceTopic{}, report, nil
+	}
+
+	answers := gather(sources, count, input)
+	report.Sources = make([]datasource.SourceReport, len(sources))
+	var errs []error
+	for i, a := range answers {
+		sr := datasource.SourceReport{Source: r.name(i, sources[i]), Latency: a.latency, Topics: len(a.topics)}
+		if a.err != nil {
+			errs = append(errs, a.err)
+			sr.Error, sr.ErrorClass = a.err.Error(), datasource.ErrorClass(a.err)
+		}
+		a.trace.Fill(&sr)
+		report.Sources[i] = sr
+		report.TotalEstimate += sr.TotalEstimate
+	}
+	if len(errs) == len(sources) {
+		report.Duration = time.Since(report.Start)
+		return nil, report, fmt.Errorf("router: all sources failed: %w", errors.Join(errs...))
+	}
+
+	order := make([]int, len(answers))
+	for i := range order {
+		order[i] = i
+	}
+	affinity := r.affinity
+	if input.SessionID == "" {
+		affinity = nil
+	}
+	if affinity != nil {
+		order = affinity.order(input.SessionID, sources)
+		for i := range answers {
+			answers[i].topics = affinity.boost(input.SessionID, answers[i].topics)
+		}
+	}
+
+	topics := make([]datasource.DataSourceTopic, 0, count)
+	returnedBy := make([]datasource.DataSource, 0, count)
+	for rank := 0; len(topics) < count; rank++ {
+		added := false
+		for _, i := range order {
+			if a := answers[i]; rank < len(a.topics) && len(topics) < count {
+				topics = append(topics, a.topics[rank])
+				returnedBy = append(returnedBy, sources[i])
+				r.claim(a.topics[rank].TopicID, sources[i])
+				report.Sources[i].Kept++
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	if affinity != nil {
+		affinity.returned(input.SessionID, topics, returnedBy)
+	}
+	report.Returned = len(topics)
+	report.Duration = time.Since(report.Start)
+	input.Trace.Estimate(report.TotalEstimate)
+	return topics, report, nil
+}
+
+// EstimateMatches sums the match estimates of the sources routed for the
+// question that are datasource.MatchEstimators, asked concurrently.
+// Sources that cannot estimate are left out, as are failing ones unless
+// all fail. It fails with errors.ErrUnsupported if no routed source can
+// estimate.
+func (r *Router) EstimateMatches(ctx context.Context, input datasource.NewQuestionInput) (int64, error) {
+	type result struct {
+		n   int64
+		err error
+	}
+	_, sources := r.Route(input)
+	done := make(chan result, len(sources))
+	asked := 0
+	for _, ds := range sources {
+		e, ok := datasource.MatchEstimatorOf(ds)
+		if !ok {
+			continue
+		}
+		asked++
+		go func(e datasource.MatchEstimator) {
+			n, err := e.EstimateMatches(ctx, input)
+			done <- result{n, err}
+		}(e)
+	}
+	if asked == 0 {
+		return 0, fmt.Errorf("router: %w: no routed source estimates matches", errors.ErrUnsupported)
+	}
+	var total int64
+	var errs []error
+	for i := 0; i < asked; i++ {
+		res := <-done
+		if res.err != nil {
+			errs = append(errs, res.err)
+			continue
+		}
+		total += res.n
+	}
+	if len(errs) == asked {
+		return 0, fmt.Errorf("router: all estimates failed: %w", errors.Join(errs...))
+	}
+	return total, nil
+}
+
+// WithAffinity returns a view of r that favors, within each session, the
+// sources and topics that were useful earlier in it; see Affinity. The
+// view shares r's topic ownership table.
+func (r *Router) WithAffinity(a *Affinity) *Router {
+	v := *r
+	v.affinity = a
+	return &v
+}
+
+// WithOwners returns a view of r that uses prev's topic ownership table,
+// for a router replacing prev, so that FetchData reaches the sources that
+// returned topics before the replacement, matching them by name (see
+// Named).
+func (r *Router) WithOwners(prev *Router) *Router {
+	v := *r
+	v.owners = prev.owners
+	return &v
+}
+
+// Named returns a view of r that names sources in query reports, and in
+// its topic ownership table, by names. The view shares r's topic
+// ownership table.
+func (r *Router) Named(names map[datasource.DataSource]string) *Router {
+	v := *r
+	v.names = names
+	return &v
+}
+
+// name returns the name of ds, the source at index i of a route.
+func (r *Router) name(i int, ds datasource.DataSource) string {
+	if name, ok := r.names[ds]; ok {
+		return name
+	}
+	return fmt.Sprint(i)
+}
+
+// FetchData fetches data from the source that returned topicID. If the
+// owner is unknown, or excluded by Only, every source not in maintenance
+// is tried and the result of the one source with data is returned; an
+// error is returned if every source fails, or if more than one source
+// returned topicID or has data for it.
+func (r *Router) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	o, known := r.owners.Get(topicID)
+	if known && o == (owner{}) {
+		return nil, fmt.Errorf("router: topic %d was returned by more than one source: %w", topicID, datasource.ErrNotFound)
+	}
+	if ds := r.resolve(o); known && ds != nil && (r.allowed == nil || r.allowed[ds]) {
+		data, err := ds.FetchData(count, topicID)
+		if err == nil && len(data) > 0 && r.affinity != nil {
+			r.affinity.fetched(topicID)
+		}
+		return data, err
+	}
+
+	sources := serving(r.sources())
+	var errs []error
+	var found datasource.DataSource
+	var result []datasource.DataSourceData
+	for _, ds := range sources {
+		data, err := ds.FetchData(count, topicID)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if len(data) == 0 {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("router: topic %d: more than one source has data: %w", topicID, datasource.ErrNotFound)
+		}
+		found, result = ds, data
+	}
+	if found != nil {
+		r.claim(topicID, found)
+		if r.affinity != nil {
+			r.affinity.fetched(topicID)
+		}
+		return result, nil
+	}
+	if len(sources) > 0 && len(errs) == len(sources) {
+		return nil, fmt.Errorf("router: topic %d: %w", topicID, errors.Join(errs...))
+	}
+	return []datasource.DataSourceData{}, nil
+}
+
+// claim records ds as the owner of topicID, unless another source
+// returned it too.
+func (r *Router) claim(topicID int64, ds datasource.DataSource) {
+	o := owner{ds: ds, name: r.names[ds]}
+	r.owners.Update(topicID, func(old owner, ok bool) owner {
+		if ok && old != o && (o.name == "" || old.name != o.name) {
+			return owner{}
+		}
+		return o
+	})
+}
+
+// resolve returns the source o identifies among r's sources: the one
+// with o's name, if o is named, or else o's source. It returns nil if
+// there is none.
+func (r *Router) resolve(o owner) datasource.DataSource {
+	if o.name == "" {
+		return o.ds
+	}
+	for _, ds := range r.sources() {
+		if r.names[ds] == o.name {
+			return ds
+		}
+	}
+	return nil
+}
+
+// answer is a source's FetchTopics outcome, as collected by gather.
+type answer struct {
+	topics  []datasource.DataSourceTopic
+	err     error
+	latency time.Duration
+	trace   *datasource.Trace
+}
+
+// gather calls FetchTopics on every source concurrently, each with its own
+// Trace, and collects the answers, giving up on sources that have not
+// answered when the input's Budget runs out.
+func gather(sources []datasource.DataSource, count int, input datasource.NewQuestionInput) []answer {
+	type result struct {
+		i      int
+		topics []datasource.DataSourceTopic
+		err    error
+	}
+	start := time.Now()
+	answers := make([]answer, len(sources))
+	done := make(chan result, len(sources))
+	for i, ds := range sources {
+		in := input
+		in.Trace = new(datasource.Trace)
+		answers[i].trace = in.Trace
+		go func(i int, ds datasource.DataSource) {
+			topics, err := ds.FetchTopics(count, in)
+			done <- result{i, topics, err}
+		}(i, ds)
+	}
+	var expired <-chan time.Time
+	if left, ok := input.Budget.Remaining(); ok {
+		timer := time.NewTimer(left)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	answered := make([]bool, len(sources))
+	for range sources {
+		select {
+		case r := <-done:
+			answers[r.i].topics, answers[r.i].err, answers[r.i].latency = r.topics, r.err, time.Since(start)
+			answered[r.i] = true
+		case <-expired:
+			for i := range sources {
+				if !answered[i] {
+					answers[i].err = fmt.Errorf("router: source %d: budget exhausted: %w: %w", i, datasource.ErrUnavailable, context.DeadlineExceeded)
+					answers[i].latency = time.Since(start)
+				}
+			}
+			return answers
+		}
+	}
+	return answers
+}
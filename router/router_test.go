@@ -0,0 +1,357 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/middleware"
+	"github.com/locus-search/datasource-sdk/router"
+)
+
+type namedSource struct {
+	name    string
+	baseID  int64
+	fail    bool
+	block   chan struct{} // if set, FetchTopics waits for it to close
+	mu      sync.Mutex
+	queries int
+}
+
+func (s *namedSource) Init() error             { return nil }
+func (s *namedSource) CheckAvailability() bool { return !s.fail }
+
+func (s *namedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	s.mu.Lock()
+	s.queries++
+	s.mu.Unlock()
+	if s.block != nil {
+		<-s.block
+	}
+	if s.fail {
+		return nil, datasource.ErrUnavailable
+	}
+	return []datasource.DataSourceTopic{
+		{Topic: s.name + " 1", TopicID: s.baseID + 1},
+		{Topic: s.name + " 2", TopicID: s.baseID + 2},
+	}, nil
+}
+
+func (s *namedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if topicID <= s.baseID || topicID > s.baseID+2 {
+		return []datasource.DataSourceData{}, nil
+	}
+	return []datasource.DataSourceData{{DataText: s.name, AnswerID: topicID}}, nil
+}
+
+func TestKeywordClassifier(t *testing.T) {
+	tests := []struct {
+		question string
+		tags     []string
+		want     router.Intent
+	}{
+		{"How do I rotate my API key?", nil, router.IntentHowTo},
+		{"What is a service mesh?", nil, router.IntentDefinition},
+		{"Build fails with exit code 137", nil, router.IntentTroubleshooting},
+		{"Show me an example of a Go HTTP client", nil, router.IntentCodeExample},
+		{"Are contractors allowed to expense travel?", nil, router.IntentPolicy},
+		{"kubernetes", []string{"error"}, router.IntentTroubleshooting},
+		{"quarterly roadmap", nil, router.IntentUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.question, func(t *testing.T) {
+			got := router.KeywordClassifier{}.Classify(datasource.NewQuestionInput{QuestionText: tt.question, Tags: tt.tags})
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouterQueriesOnlyRoutedSources(t *testing.T) {
+	handbook := &namedSource{name: "handbook", baseID: 100}
+	forum := &namedSource{name: "forum", baseID: 200}
+	tracker := &namedSource{name: "tracker", baseID: 300}
+	wiki := &namedSource{name: "wiki", baseID: 400}
+
+	r := router.New(router.KeywordClassifier{}, map[router.Intent][]datasource.DataSource{
+		router.IntentPolicy:          {handbook},
+		router.IntentTroubleshooting: {forum, tracker},
+	}, wiki)
+
+	topics, err := r.FetchTopics(3, datasource.NewQuestionInput{QuestionText: "deploy fails with timeout"})
+	if err != nil {
+		t.Fatalf("FetchTopics failed: %v", err)
+	}
+	want := []int64{201, 301, 202}
+	if len(topics) != len(want) {
+		t.Fatalf("got %d topics, want %d", len(topics), len(want))
+	}
+	for i, id := range want {
+		if topics[i].TopicID != id {
+			t.Errorf("topic %d: got ID %d, want %d", i, topics[i].TopicID, id)
+		}
+	}
+	if handbook.queries != 0 || wiki.queries != 0 {
+		t.Error("sources for other intents must not be queried")
+	}
+
+	data, err := r.FetchData(1, 301)
+	if err != nil || len(data) != 1 || data[0].DataText != "tracker" {
+		t.Errorf("FetchData routed incorrectly: %+v, %v", data, err)
+	}
+
+	// Unknown intents go to the fallback source.
+	if _, err := r.FetchTopics(2, datasource.NewQuestionInput{QuestionText: "quarterly roadmap"}); err != nil {
+		t.Fatalf("FetchTopics failed: %v", err)
+	}
+	if wiki.queries != 1 {
+		t.Errorf("expected fallback to be queried once, got %d", wiki.queries)
+	}
+}
+
+func TestRouterPartialFailure(t *testing.T) {
+	ok := &namedSource{name: "ok", baseID: 10}
+	down := &namedSource{name: "down", baseID: 20, fail: true}
+	r := router.New(router.KeywordClassifier{}, nil, ok, down)
+
+	topics, err := r.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "anything"})
+	if err != nil || len(topics) != 2 {
+		t.Errorf("expected partial results, got %d topics and %v", len(topics), err)
+	}
+
+	all := router.New(router.KeywordClassifier{}, nil, down)
+	if _, err := all.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "anything"}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("expected ErrUnavailable when every source fails, got %v", err)
+	}
+}
+
+func TestRouterSkipsSourcesInMaintenance(t *testing.T) {
+	up := &namedSource{name: "up", baseID: 10}
+	nightly := &namedSource{name: "nightly", baseID: 20}
+	r := router.New(router.KeywordClassifier{}, nil, up, middleware.MustMaintenance(nightly, middleware.MaintenanceConfig{
+		Windows: []middleware.MaintenanceWindow{{Start: "* * * * *", Duration: time.Hour}},
+	}))
+
+	topics, report, err := r.FetchTopicsReport(5, datasource.NewQuestionInput{QuestionText: "anything"})
+	if err != nil || len(topics) != 2 || len(report.Sources) != 1 {
+		t.Errorf("got %d topics from %d sources and %v, want only the source up", len(topics), len(report.Sources), err)
+	}
+	if nightly.queries != 0 {
+		t.Errorf("source in maintenance queried %d times", nightly.queries)
+	}
+	if data, err := r.FetchData(1, 21); err != nil || len(data) != 0 {
+		t.Errorf("probe = %+v, %v; want the source in maintenance skipped", data, err)
+	}
+}
+
+func TestRouterBudget(t *testing.T) {
+	fast := &namedSource{name: "fast", baseID: 10}
+	slow := &namedSource{name: "slow", baseID: 20, block: make(chan struct{})}
+	defer close(slow.block)
+	r := router.New(router.KeywordClassifier{}, nil, fast, slow)
+
+	input := datasource.NewQuestionInput{QuestionText: "anything", Budget: datasource.Budget{MaxLatency: 20 * time.Millisecond}}
+	topics, err := r.FetchTopics(5, input)
+	if err != nil || len(topics) != 2 || topics[0].Topic != "fast 1" {
+		t.Errorf("expected the fast source's results, got %v and %v", topics, err)
+	}
+
+	input.Budget = datasource.Budget{Deadline: time.Now().Add(-time.Second)}
+	if _, err := router.New(router.KeywordClassifier{}, nil, slow).FetchTopics(5, input); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected DeadlineExceeded when the budget is spent, got %v", err)
+	}
+}
+
+func TestRouterFetchDataProbesUnknownTopics(t *testing.T) {
+	a := &namedSource{name: "a", baseID: 10}
+	b := &namedSource{name: "b", baseID: 20}
+	r := router.New(router.KeywordClassifier{}, nil, a, b)
+
+	data, err := r.FetchData(1, 22)
+	if err != nil || len(data) != 1 || data[0].DataText != "b" {
+		t.Errorf("expected probe to find topic in b, got %+v, %v", data, err)
+	}
+}
+
+func TestRouterCollidingTopicIDs(t *testing.T) {
+	a := &namedSource{name: "a", baseID: 10}
+	b := &namedSource{name: "b", baseID: 10}
+	r := router.New(router.KeywordClassifier{}, nil, a, b)
+
+	if data, err := r.FetchData(1, 11); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("probe both sources answer = %+v, %v; want ErrNotFound", data, err)
+	}
+	if _, err := r.FetchTopics(4, datasource.NewQuestionInput{QuestionText: "anything"}); err != nil {
+		t.Fatal(err)
+	}
+	if data, err := r.FetchData(1, 12); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData of a topic both sources returned = %+v, %v; want ErrNotFound", data, err)
+	}
+}
+
+func TestRouterOnly(t *testing.T) {
+	docs := &namedSource{name: "docs", baseID: 100}
+	forum := &namedSource{name: "forum", baseID: 200}
+	r := router.New(router.KeywordClassifier{}, map[router.Intent][]datasource.DataSource{
+		router.IntentPolicy: {forum},
+	}, docs, forum)
+
+	topics, _ := r.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "kubernetes"})
+	if len(topics) != 4 {
+		t.Fatalf("unscoped FetchTopics returned %d topics", len(topics))
+	}
+
+	only := r.Only(docs)
+	forum.queries = 0
+	topics, err := only.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "what is the travel policy"})
+	if err != nil || len(topics) != 2 || topics[0].Topic != "docs 1" || forum.queries != 0 {
+		t.Errorf("scoped FetchTopics = %+v, %v (forum queried %d times)", topics, err, forum.queries)
+	}
+
+	// Topics remembered by the parent are shared, but excluded owners are
+	// not called.
+	if data, err := only.FetchData(1, 101); err != nil || len(data) != 1 {
+		t.Errorf("scoped FetchData(101) = %v, %v", data, err)
+	}
+	if data, err := only.FetchData(1, 201); err != nil || len(data) != 0 {
+		t.Errorf("scoped FetchData(201) = %v, %v; want no data from an excluded source", data, err)
+	}
+	if data, _ := r.FetchData(1, 201); len(data) != 1 {
+		t.Error("parent router lost topic ownership")
+	}
+}
+
+func TestRouterReport(t *testing.T) {
+	ok := &namedSource{name: "ok", baseID: 10}
+	cached := middleware.Cache(&namedSource{name: "cached", baseID: 20}, middleware.CacheConfig{})
+	down := &namedSource{name: "down", baseID: 30, fail: true}
+	r := router.New(router.KeywordClassifier{}, nil, ok, cached, down).
+		Named(map[datasource.DataSource]string{ok: "ok", cached: "cached"})
+
+	input := datasource.NewQuestionInput{QuestionText: "anything"}
+	r.FetchTopics(3, input)
+	topics, report, err := r.FetchTopicsReport(3, input)
+	if err != nil || len(topics) != 3 {
+		t.Fatalf("FetchTopicsReport = %d topics, %v", len(topics), err)
+	}
+	if report.Query != "anything" || report.Count != 3 || report.Returned != 3 || len(report.Sources) != 3 {
+		t.Fatalf("report = %+v", report)
+	}
+	if s := report.Sources[0]; s.Source != "ok" || s.Topics != 2 || s.Kept != 2 || s.CacheHit || s.Error != "" {
+		t.Errorf("ok source = %+v", s)
+	}
+	if s := report.Sources[1]; s.Source != "cached" || s.Topics != 2 || s.Kept != 1 || !s.CacheHit {
+		t.Errorf("cached source = %+v, want a cache hit with one topic kept", s)
+	}
+	if s := report.Sources[2]; s.Source != "2" || s.Kept != 0 || s.ErrorClass != "unavailable" || s.Error == "" {
+		t.Errorf("failing source = %+v, want its error and index as name", s)
+	}
+
+	// The named view shares the original's topic ownership.
+	if data, err := r.FetchData(1, 21); err != nil || len(data) != 1 || data[0].DataText != "cached" {
+		t.Errorf("FetchData = %v, %v", data, err)
+	}
+}
+
+// estimatingSource knows how many of its topics match, and records it on
+// the trace of FetchTopics.
+type estimatingSource struct {
+	*namedSource
+	matches int64
+	err     error
+}
+
+func (s *estimatingSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	input.Trace.Estimate(s.matches)
+	return s.namedSource.FetchTopics(count, input)
+}
+
+func (s *estimatingSource) EstimateMatches(context.Context, datasource.NewQuestionInput) (int64, error) {
+	return s.matches, s.err
+}
+
+func TestRouterEstimateMatches(t *testing.T) {
+	kb := &estimatingSource{namedSource: &namedSource{name: "kb", baseID: 10}, matches: 40}
+	forum := &estimatingSource{namedSource: &namedSource{name: "forum", baseID: 20}, matches: 8}
+	wiki := &namedSource{name: "wiki", baseID: 30}
+	r := router.New(router.KeywordClassifier{}, nil, kb, forum, wiki)
+	input := datasource.NewQuestionInput{QuestionText: "anything"}
+
+	_, report, err := r.FetchTopicsReport(4, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.TotalEstimate != 48 || report.Sources[0].TotalEstimate != 40 || report.Sources[2].TotalEstimate != 0 {
+		t.Errorf("report = %+v", report)
+	}
+	if coverage, ok := report.Coverage(); !ok || coverage != 3.0/48 {
+		t.Errorf("Coverage() = %v, %v", coverage, ok)
+	}
+
+	page, err := datasource.FetchTopicsPage(r, 4, input)
+	if err != nil || len(page.Topics) != 4 || page.TotalEstimate != 48 {
+		t.Errorf("FetchTopicsPage = %+v, %v; want 4 topics of about 48", page, err)
+	}
+
+	if n, err := r.EstimateMatches(context.Background(), input); err != nil || n != 48 {
+		t.Errorf("EstimateMatches = %d, %v; want 48", n, err)
+	}
+	forum.err = datasource.ErrUnavailable
+	if n, err := r.EstimateMatches(context.Background(), input); err != nil || n != 40 {
+		t.Errorf("EstimateMatches with a failing source = %d, %v; want 40", n, err)
+	}
+	kb.err = datasource.ErrUnavailable
+	if _, err := r.EstimateMatches(context.Background(), input); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("EstimateMatches with every source failing = %v", err)
+	}
+	if _, err := router.New(router.KeywordClassifier{}, nil, wiki).EstimateMatches(context.Background(), input); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("EstimateMatches without estimators = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestRouterAffinity(t *testing.T) {
+	handbook := &namedSource{name: "handbook", baseID: 100}
+	wiki := &namedSource{name: "wiki", baseID: 200}
+	affinity := router.NewAffinity(0)
+	r := router.New(router.KeywordClassifier{}, nil, handbook, wiki).WithAffinity(affinity)
+	ids := func(session string) []int64 {
+		topics, err := r.FetchTopics(4, datasource.NewQuestionInput{QuestionText: "q", SessionID: session})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var out []int64
+		for _, topic := range topics {
+			out = append(out, topic.TopicID)
+		}
+		return out
+	}
+	plain := []int64{101, 201, 102, 202}
+
+	if got := ids("s1"); !reflect.DeepEqual(got, plain) {
+		t.Fatalf("first question = %v, want %v", got, plain)
+	}
+	if data, err := r.FetchData(1, 202); err != nil || len(data) != 1 {
+		t.Fatalf("FetchData = %v, %v", data, err)
+	}
+	if got, want := ids("s1"), []int64{202, 101, 201, 102}; !reflect.DeepEqual(got, want) {
+		t.Errorf("follow-up = %v, want the wiki and its useful topic first: %v", got, want)
+	}
+	if got := ids("s2"); !reflect.DeepEqual(got, plain) {
+		t.Errorf("other session = %v, want %v", got, plain)
+	}
+	if got := ids(""); !reflect.DeepEqual(got, plain) {
+		t.Errorf("no session = %v, want %v", got, plain)
+	}
+
+	affinity.Useful("s1", 102) // last returned in s2
+	affinity.Useful("s2", 102)
+	if got, want := ids("s2"), []int64{102, 201, 101, 202}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after reporting a useful topic = %v, want %v", got, want)
+	}
+}
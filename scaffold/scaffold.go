@@ -0,0 +1,131 @@
+// Package scaffold generates the skeleton of a new data source package, as
+// run by locus-ds scaffold, so that integration authors start from code
+// that builds and passes its tests rather than from a blank file:
+//
+//	files, err := scaffold.Generate(scaffold.Options{Name: "mysource", Kind: scaffold.KindHTTPJSON})
+//	if err != nil { ... }
+//	err = scaffold.Write("./mysource", files)
+//
+// The package has a Config with JSON settings and its defaults, a
+// constructor for config descriptors, a Register function adding the
+// source to a datasource.Registry, the client wiring for its kind of
+// upstream, and tests running datasourcetest.RunConformance against a
+// fake upstream. TODO comments mark what to adapt.
+package scaffold
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"text/template"
+)
+
+// Kind is the kind of upstream a generated source reads from.
+type Kind string
+
+// Kinds of upstream.
+const (
+	// KindHTTPJSON is a JSON API over HTTP
+	KindHTTPJSON Kind = "http-json"
+
+	// KindGRPC is a gRPC service, called through an interface the author
+	// implements with the stubs protoc generates
+	KindGRPC Kind = "grpc"
+
+	// KindLocalIndex is files under a directory, indexed in memory
+	KindLocalIndex Kind = "local-index"
+)
+
+// Kinds lists every Kind.
+var Kinds = []Kind{KindHTTPJSON, KindGRPC, KindLocalIndex}
+
+//go:embed templates
+var templates embed.FS
+
+// namePattern matches valid package names.
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// Options configures Generate.
+type Options struct {
+	// Name is the name of the package, and of the source in config
+	// descriptors: lowercase letters and digits, starting with a letter
+	Name string
+
+	// Kind is the kind of upstream the source reads from
+	Kind Kind
+}
+
+// File is a generated file.
+type File struct {
+	// Name is the file's name within the package directory
+	Name string
+
+	Content []byte
+}
+
+// Generate returns the files of a source package for opts, formatted with
+// gofmt.
+func Generate(opts Options) ([]File, error) {
+	if !namePattern.MatchString(opts.Name) {
+		return nil, fmt.Errorf("scaffold: invalid name %q: use lowercase letters and digits, starting with a letter", opts.Name)
+	}
+	known := false
+	for _, k := range Kinds {
+		known = known || opts.Kind == k
+	}
+	if !known {
+		return nil, fmt.Errorf("scaffold: unknown kind %q; want one of %v", opts.Kind, Kinds)
+	}
+	dir := "templates/" + string(opts.Kind)
+	entries, err := fs.ReadDir(templates, dir)
+	if err != nil {
+		return nil, fmt.Errorf("scaffold: %w", err)
+	}
+	var files []File
+	for _, e := range entries {
+		// Go composite literals can open with {{, so actions use [[ ]].
+		tmpl, err := template.New(e.Name()).Delims("[[", "]]").ParseFS(templates, dir+"/"+e.Name())
+		if err != nil {
+			return nil, fmt.Errorf("scaffold: %w", err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, opts); err != nil {
+			return nil, fmt.Errorf("scaffold: %s: %w", e.Name(), err)
+		}
+		src, err := format.Source(buf.Bytes())
+		if err != nil {
+			return nil, fmt.Errorf("scaffold: %s: %w", e.Name(), err)
+		}
+		// source.go.tmpl becomes NAME.go, and source_test.go.tmpl NAME_test.go.
+		name := opts.Name + e.Name()[len("source"):len(e.Name())-len(".tmpl")]
+		files = append(files, File{Name: name, Content: src})
+	}
+	return files, nil
+}
+
+// Write writes files to dir, creating it if needed. It writes nothing if
+// any of the files already exists.
+func Write(dir string, files []File) error {
+	for _, f := range files {
+		if _, err := os.Stat(filepath.Join(dir, f.Name)); err == nil {
+			return fmt.Errorf("scaffold: %s: %w", filepath.Join(dir, f.Name), fs.ErrExist)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("scaffold: %w", err)
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("scaffold: %w", err)
+	}
+	for _, f := range files {
+		if err := os.WriteFile(filepath.Join(dir, f.Name), f.Content, 0o644); err != nil {
+			return fmt.Errorf("scaffold: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package scaffold_test
+
+import (
+	"errors"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/locus-search/datasource-sdk/scaffold"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, kind := range scaffold.Kinds {
+		files, err := scaffold.Generate(scaffold.Options{Name: "mysource", Kind: kind})
+		if err != nil {
+			t.Fatalf("%s: %v", kind, err)
+		}
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name)
+			file, err := parser.ParseFile(token.NewFileSet(), f.Name, f.Content, parser.ParseComments)
+			if err != nil {
+				t.Errorf("%s: %v", kind, err)
+				continue
+			}
+			if file.Name.Name != "mysource" {
+				t.Errorf("%s: %s is in package %s", kind, f.Name, file.Name.Name)
+			}
+			for _, want := range []string{"[[", "]]", "{{.Name}}"} {
+				if strings.Contains(string(f.Content), want) {
+					t.Errorf("%s: %s has an unexpanded %q", kind, f.Name, want)
+				}
+			}
+		}
+		if strings.Join(names, ",") != "mysource.go,mysource_test.go" {
+			t.Errorf("%s: files %v", kind, names)
+		}
+		test := string(files[1].Content)
+		for _, want := range []string{"RunConformance", `Register(r, "mysource"`} {
+			if !strings.Contains(test, want) {
+				t.Errorf("%s: test lacks %s", kind, want)
+			}
+		}
+	}
+
+	for _, opts := range []scaffold.Options{
+		{Name: "MySource", Kind: scaffold.KindHTTPJSON},
+		{Name: "my-source", Kind: scaffold.KindHTTPJSON},
+		{Name: "mysource", Kind: "soap"},
+		{Name: "mysource", Kind: "../templates"},
+	} {
+		if _, err := scaffold.Generate(opts); err == nil {
+			t.Errorf("Generate(%+v) succeeded", opts)
+		}
+	}
+}
+
+func TestWrite(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mysource")
+	files, err := scaffold.Generate(scaffold.Options{Name: "mysource", Kind: scaffold.KindGRPC})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := scaffold.Write(dir, files); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "mysource.go")); err != nil || string(b) != string(files[0].Content) {
+		t.Errorf("mysource.go not written: %v", err)
+	}
+
+	os.Remove(filepath.Join(dir, "mysource.go"))
+	if err := scaffold.Write(dir, files); !errors.Is(err, fs.ErrExist) {
+		t.Errorf("Write over an existing file = %v, want ErrExist", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "mysource.go")); err == nil {
+		t.Error("Write wrote files although one existed")
+	}
+}
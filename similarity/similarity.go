@@ -0,0 +1,115 @@
+// Package similarity provides the text and vector similarity measures used
+// to detect near-duplicate questions and results: MinHash signatures, which
+// estimate the Jaccard similarity of two texts' character shingles in
+// constant space, and cosine similarity of embeddings.
+package similarity
+
+import (
+	"hash/fnv"
+	"math"
+	"strings"
+	"unicode"
+)
+
+// DefaultSignatureSize is the number of hash functions in a MinHash
+// signature when MinHash is given a non-positive size. The standard error
+// of the Jaccard estimate is about 1/sqrt(size), here 0.125.
+const DefaultSignatureSize = 64
+
+// shingleSize is the length, in runes, of the character shingles hashed by
+// MinHash. Character shingles tolerate typos and inflections that word
+// shingles do not.
+const shingleSize = 3
+
+// Signature is a MinHash signature. Signatures are comparable only if they
+// have the same size.
+type Signature []uint64
+
+// MinHash returns the MinHash signature of text with size hash functions.
+// Text is normalized first: lowercased, with punctuation removed and runs
+// of whitespace collapsed. Empty text has a signature of all ones, similar
+// only to other empty text.
+func MinHash(text string, size int) Signature {
+	if size <= 0 {
+		size = DefaultSignatureSize
+	}
+	sig := make(Signature, size)
+	for i := range sig {
+		sig[i] = math.MaxUint64
+	}
+	runes := []rune(Normalize(text))
+	if len(runes) == 0 {
+		return sig
+	}
+	for start := 0; start == 0 || start+shingleSize <= len(runes); start++ {
+		h := hash(string(runes[start:min(start+shingleSize, len(runes))]))
+		for i := range sig {
+			if v := mix(h ^ seed(i)); v < sig[i] {
+				sig[i] = v
+			}
+		}
+	}
+	return sig
+}
+
+// Jaccard estimates the Jaccard similarity of the texts s and o were
+// computed from, between 0 and 1. It returns 0 if the sizes differ.
+func (s Signature) Jaccard(o Signature) float64 {
+	if len(s) != len(o) || len(s) == 0 {
+		return 0
+	}
+	same := 0
+	for i := range s {
+		if s[i] == o[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(s))
+}
+
+// Cosine returns the cosine similarity of two vectors, between -1 and 1.
+// It returns 0 if the lengths differ or either vector is zero.
+func Cosine(a, b []float64) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += a[i] * b[i]
+		na += a[i] * a[i]
+		nb += b[i] * b[i]
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// Normalize lowercases text, replaces punctuation with spaces, and
+// collapses runs of whitespace.
+func Normalize(text string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}), " ")
+}
+
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
+
+// seed derives the i'th hash function's seed.
+func seed(i int) uint64 {
+	return mix(uint64(i) + 0x9e3779b97f4a7c15)
+}
+
+// mix is the MurmurHash3 64-bit finalizer.
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
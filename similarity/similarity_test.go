@@ -0,0 +1,63 @@
+package similarity
+
+import (
+	"math"
+	"testing"
+)
+
+func TestMinHashJaccard(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     string
+		min, max float64
+	}{
+		{"identical", "How do I reset my password?", "How do I reset my password?", 1, 1},
+		{"normalized", "How do I reset my password?", "how do i reset  my PASSWORD", 1, 1},
+		{"near duplicate", "How do I reset my password?", "how do I reset my password quickly", 0.6, 1},
+		{"unrelated", "How do I reset my password?", "Which regions support GPU instances", 0, 0.2},
+		{"empty", "", "", 1, 1},
+		{"empty and text", "", "password", 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MinHash(tt.a, 0).Jaccard(MinHash(tt.b, 0))
+			if got < tt.min || got > tt.max {
+				t.Errorf("Jaccard = %v, want in [%v, %v]", got, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestMinHashSize(t *testing.T) {
+	if got := len(MinHash("text", 0)); got != DefaultSignatureSize {
+		t.Errorf("default size = %d, want %d", got, DefaultSignatureSize)
+	}
+	if got := MinHash("text", 16).Jaccard(MinHash("text", 32)); got != 0 {
+		t.Errorf("Jaccard of different sizes = %v, want 0", got)
+	}
+}
+
+func TestCosine(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want float64
+	}{
+		{"same direction", []float64{1, 2}, []float64{2, 4}, 1},
+		{"orthogonal", []float64{1, 0}, []float64{0, 1}, 0},
+		{"opposite", []float64{1, 0}, []float64{-1, 0}, -1},
+		{"zero", []float64{0, 0}, []float64{1, 0}, 0},
+		{"length mismatch", []float64{1}, []float64{1, 0}, 0},
+	}
+	for _, tt := range tests {
+		if got := Cosine(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%s: Cosine = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	if got, want := Normalize("  What's   the TTL?\n"), "what s the ttl"; got != want {
+		t.Errorf("Normalize = %q, want %q", got, want)
+	}
+}
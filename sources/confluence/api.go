@@ -0,0 +1,108 @@
+package confluence
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// maxResponseBytes bounds response bodies read from the API.
+const maxResponseBytes = 16 << 20
+
+// page is a page as the content API returns it, with the expansions
+// the source requests.
+type page struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Title string `json:"title"`
+	Space struct {
+		Key  string `json:"key"`
+		Name string `json:"name"`
+	} `json:"space"`
+	Ancestors []struct {
+		Title string `json:"title"`
+	} `json:"ancestors"`
+	History struct {
+		CreatedDate time.Time `json:"createdDate"`
+		CreatedBy   user      `json:"createdBy"`
+	} `json:"history"`
+	Version struct {
+		When   time.Time `json:"when"`
+		Number int       `json:"number"`
+		By     user      `json:"by"`
+	} `json:"version"`
+	Metadata struct {
+		Labels struct {
+			Results []struct {
+				Name string `json:"name"`
+			} `json:"results"`
+		} `json:"labels"`
+	} `json:"metadata"`
+	Body struct {
+		Storage struct {
+			Value string `json:"value"`
+		} `json:"storage"`
+	} `json:"body"`
+	Links links `json:"_links"`
+}
+
+type user struct {
+	DisplayName string `json:"displayName"`
+}
+
+type links struct {
+	Base  string `json:"base"`
+	WebUI string `json:"webui"`
+}
+
+// searchResponse is a page of content search results.
+type searchResponse struct {
+	Results []page `json:"results"`
+	Links   links  `json:"_links"`
+}
+
+// do sends a GET request to path, with its query, under the REST API of
+// the site, and decodes the JSON response into into, if not nil.
+func (s *Source) do(ctx context.Context, path string, into any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(s.cfg.URL, "/")+"/rest/api"+path, nil)
+	if err != nil {
+		return fmt.Errorf("confluence: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case s.cfg.Token != "":
+		req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	case s.cfg.Email != "":
+		req.SetBasicAuth(s.cfg.Email, s.cfg.APIToken)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("confluence: %w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Message string `json:"message"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&e)
+		if e.Message != "" {
+			return fmt.Errorf("confluence: %w: %s", httpx.StatusError(resp), e.Message)
+		}
+		return fmt.Errorf("confluence: %w", httpx.StatusError(resp))
+	}
+	if into == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(into); err != nil {
+		return fmt.Errorf("confluence: decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,366 @@
+// Package confluence is a data source backed by the REST API of a
+// Confluence Cloud site, or of Confluence Data Center. Topics are pages
+// found with a CQL text search, and data items are the sections of a
+// page's body, sanitized HTML deep-linking to their headings:
+//
+//	ds := confluence.New(confluence.Config{
+//	    URL:      "https://example.atlassian.net/wiki",
+//	    Email:    "bot@example.com",
+//	    APIToken: os.Getenv("CONFLUENCE_API_TOKEN"),
+//	    Spaces:   []string{"ENG", "OPS"},
+//	})
+//
+// Results are those the account of the token may see. Space keys are the
+// Site of results, and Filters.Sites narrows the configured Spaces;
+// NewQuestionInput.Tags and Filters.ExcludeTags match page labels.
+package confluence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultTimeout = 15 * time.Second
+)
+
+// Metadata keys set on topics and data items.
+const (
+	MetadataSpace   = "space"   // the name of the page's space
+	MetadataLabels  = "labels"  // the page's labels
+	MetadataVersion = "version" // the page's version number
+	MetadataSection = "section" // the heading of the section, unset for the lead
+)
+
+// maxLimit is the most search results the API returns in one response.
+const maxLimit = 100
+
+// expand lists the expansions requested with pages.
+const expand = "space,ancestors,history,version,metadata.labels"
+
+// Config configures a Source.
+type Config struct {
+	// URL is the address of the site's wiki, such as
+	// "https://example.atlassian.net/wiki" for Confluence Cloud
+	URL string
+
+	// Email and APIToken authenticate to Confluence Cloud with an
+	// Atlassian account's API token
+	// Optional
+	Email, APIToken string
+
+	// Token is a personal access token (Data Center) or OAuth access
+	// token, sent as a bearer token instead of Email and APIToken
+	// Optional
+	Token string
+
+	// Spaces are the keys of the spaces searched
+	// Defaults to every space the account may see
+	Spaces []string
+
+	// CQL is a clause further restricting the pages searched, such as
+	// `ancestor = 123456`
+	// Optional
+	CQL string
+
+	// Timeout bounds each request, in addition to NewQuestionInput.Budget
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// HTTPClient makes the requests
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+// Source is a DataSource searching the pages of a Confluence site. A
+// Source is safe for concurrent use.
+type Source struct {
+	cfg Config
+}
+
+var (
+	_ datasource.DataSource         = (*Source)(nil)
+	_ datasource.CapabilityReporter = (*Source)(nil)
+	_ datasource.HealthChecker      = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults()}
+}
+
+// Init checks that the site can be reached with the credentials, and that
+// the configured spaces exist.
+func (s *Source) Init() error {
+	if s.cfg.URL == "" {
+		return errors.New("confluence: no URL configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	if err := s.do(ctx, "/user/current", nil); err != nil {
+		return err
+	}
+	for _, key := range s.cfg.Spaces {
+		if err := s.do(ctx, "/space/"+url.PathEscape(key), nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck reports whether the site can be reached with the
+// credentials.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := s.do(ctx, "/user/current", nil)
+	h := datasource.HealthStatus{State: datasource.Healthy, Latency: time.Since(start)}
+	if err != nil {
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	}
+	return h
+}
+
+// Capabilities reports label filtering, and several sites unless a single
+// space is configured.
+func (s *Source) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{
+		TagFiltering: true,
+		MultiSite:    len(s.cfg.Spaces) != 1,
+	}
+}
+
+// FetchTopics searches the pages for the question, best match first.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if count <= 0 || strings.TrimSpace(input.QuestionText) == "" {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	spaces := s.spaces(input.Filters.Sites)
+	if spaces != nil && len(spaces) == 0 {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	ctx, cancel := input.Budget.Context(context.Background())
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	query := url.Values{
+		"cql":    {s.cql(input, spaces)},
+		"limit":  {strconv.Itoa(min(count, maxLimit))},
+		"expand": {expand},
+	}
+	var resp searchResponse
+	if err := s.do(ctx, "/content/search?"+query.Encode(), &resp); err != nil {
+		return nil, err
+	}
+	topics := make([]datasource.DataSourceTopic, 0, len(resp.Results))
+	for _, c := range resp.Results {
+		id, err := strconv.ParseInt(c.ID, 10, 64)
+		if err != nil {
+			continue
+		}
+		t := datasource.DataSourceTopic{
+			SourceURL: s.pageURL(c, resp.Links.Base),
+			Site:      c.Space.Key,
+			TopicID:   id,
+			Metadata:  metadata(c),
+			CreatedAt: c.History.CreatedDate,
+			UpdatedAt: c.Version.When,
+		}
+		topics = append(topics, t.Structured(c.Title, "", breadcrumb(c)...))
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// FetchData returns the sections of the page with the topic ID, lead
+// first. An item's AnswerID is the index of its section.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if count <= 0 {
+		return []datasource.DataSourceData{}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	var c page
+	path := "/content/" + strconv.FormatInt(topicID, 10) + "?" + url.Values{"expand": {"body.storage," + expand}}.Encode()
+	if err := s.do(ctx, path, &c); err != nil {
+		return nil, err
+	}
+	if s.cfg.Spaces != nil && !contains(s.cfg.Spaces, c.Space.Key) {
+		return nil, fmt.Errorf("confluence: page %d: %w", topicID, datasource.ErrNotFound)
+	}
+	pageURL := s.pageURL(c, c.Links.Base)
+	var author *datasource.Author
+	if name := c.History.CreatedBy.DisplayName; name != "" {
+		author = &datasource.Author{Name: name}
+	}
+	var items []datasource.DataSourceData
+	for i, sec := range sections(storageHTML(c.Body.Storage.Value)) {
+		if len(items) >= count {
+			break
+		}
+		item := datasource.DataSourceData{
+			DataText:    sec.html,
+			ContentType: datasource.ContentHTML,
+			SourceURL:   pageURL,
+			Site:        c.Space.Key,
+			AnswerID:    int64(i),
+			Rank:        i + 1,
+			Metadata:    metadata(c),
+			Author:      author,
+			CreatedAt:   c.History.CreatedDate,
+			UpdatedAt:   c.Version.When,
+		}
+		if sec.heading != "" {
+			item.Metadata.Set(MetadataSection, sec.heading)
+			item = item.Anchored(sec.anchor)
+		}
+		items = append(items, item)
+	}
+	if items == nil {
+		items = []datasource.DataSourceData{}
+	}
+	return items, nil
+}
+
+// spaces returns the spaces to search given the Sites filter: the
+// configured spaces it allows, or, without configured spaces, the filter
+// itself. It returns nil if every space may be searched.
+func (s *Source) spaces(sites []string) []string {
+	if len(sites) == 0 {
+		return s.cfg.Spaces
+	}
+	if s.cfg.Spaces == nil {
+		return sites
+	}
+	out := []string{}
+	for _, key := range s.cfg.Spaces {
+		if contains(sites, key) {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// cql returns the CQL query finding pages for the question in the spaces,
+// with the labels and dates of the input's tags and filters.
+func (s *Source) cql(input datasource.NewQuestionInput, spaces []string) string {
+	clauses := []string{"type = page", "text ~ " + quote(input.QuestionText)}
+	if len(spaces) > 0 {
+		clauses = append(clauses, "space in ("+quoteAll(spaces)+")")
+	}
+	if len(input.Tags) > 0 {
+		clauses = append(clauses, "label in ("+quoteAll(input.Tags)+")")
+	}
+	f := input.Filters
+	if len(f.ExcludeTags) > 0 {
+		clauses = append(clauses, "label not in ("+quoteAll(f.ExcludeTags)+")")
+	}
+	if !f.After.IsZero() {
+		clauses = append(clauses, "created >= "+quote(f.After.UTC().Format("2006-01-02 15:04")))
+	}
+	if !f.Before.IsZero() {
+		clauses = append(clauses, "created < "+quote(f.Before.UTC().Format("2006-01-02 15:04")))
+	}
+	if s.cfg.CQL != "" {
+		clauses = append(clauses, "("+s.cfg.CQL+")")
+	}
+	q := strings.Join(clauses, " AND ")
+	if f.Sort == datasource.SortRecency {
+		q += " ORDER BY created DESC"
+	}
+	return q
+}
+
+// quote returns s as a CQL string literal.
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// pageURL returns the address of a page in the web interface.
+func (s *Source) pageURL(c page, base string) string {
+	if base == "" {
+		base = s.cfg.URL
+	}
+	if c.Links.WebUI == "" {
+		return ""
+	}
+	return strings.TrimSuffix(base, "/") + c.Links.WebUI
+}
+
+// breadcrumb returns the name of a page's space and the titles of its
+// ancestors, outermost first.
+func breadcrumb(c page) []string {
+	var path []string
+	if c.Space.Name != "" {
+		path = append(path, c.Space.Name)
+	}
+	for _, a := range c.Ancestors {
+		path = append(path, a.Title)
+	}
+	return path
+}
+
+// metadata returns the metadata of a page.
+func metadata(c page) datasource.Metadata {
+	var m datasource.Metadata
+	if c.Space.Name != "" {
+		m.Set(MetadataSpace, c.Space.Name)
+	}
+	if n := c.Version.Number; n > 0 {
+		m.Set(MetadataVersion, n)
+	}
+	if results := c.Metadata.Labels.Results; len(results) > 0 {
+		labels := make([]string, len(results))
+		for i, l := range results {
+			labels[i] = l.Name
+		}
+		m.Set(MetadataLabels, labels)
+	}
+	return m
+}
+
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if strings.EqualFold(x, v) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,173 @@
+package confluence_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/confluence"
+)
+
+const body = `<p>Connect to the <strong>VPN</strong> before deploying.</p>` +
+	`<ac:structured-macro ac:name="toc" ac:schema-version="1"/>` +
+	`<h2>Install the client</h2>` +
+	`<ac:structured-macro ac:name="info"><ac:parameter ac:name="title">Note</ac:parameter>` +
+	`<ac:rich-text-body><p>See <ac:link><ri:page ri:content-title="Laptop setup"/></ac:link>.</p></ac:rich-text-body></ac:structured-macro>` +
+	`<ac:structured-macro ac:name="code"><ac:parameter ac:name="language">bash</ac:parameter>` +
+	`<ac:plain-text-body><![CDATA[sudo apt install vpn && echo "<ok>"]]></ac:plain-text-body></ac:structured-macro>` +
+	`<h2>Troubleshooting</h2><script>alert(1)</script>` +
+	`<ac:task-list><ac:task><ac:task-id>1</ac:task-id><ac:task-status>incomplete</ac:task-status><ac:task-body>Restart</ac:task-body></ac:task></ac:task-list>` +
+	`<h3>Empty</h3>`
+
+var page = map[string]any{
+	"id": "123", "type": "page", "title": "VPN guide",
+	"space":     map[string]any{"key": "ENG", "name": "Engineering"},
+	"ancestors": []any{map[string]any{"id": "100", "title": "Runbooks"}},
+	"history":   map[string]any{"createdDate": "2026-01-02T03:04:05.000Z", "createdBy": map[string]any{"displayName": "Ada"}},
+	"version":   map[string]any{"when": "2026-02-03T04:05:06.000Z", "number": 7},
+	"metadata":  map[string]any{"labels": map[string]any{"results": []any{map[string]any{"name": "network"}}}},
+	"body":      map[string]any{"storage": map[string]any{"value": body}},
+	"_links":    map[string]any{"webui": "/spaces/ENG/pages/123/VPN+guide", "base": "https://example.atlassian.net/wiki"},
+}
+
+// site is a fake Confluence site with one page.
+type site struct {
+	mu   sync.Mutex
+	cql  []string
+	auth string
+}
+
+func (s *site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.auth = r.Header.Get("Authorization")
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/wiki/rest/api/user/current", "/wiki/rest/api/space/ENG", "/wiki/rest/api/space/OPS":
+		io.WriteString(w, `{}`)
+	case "/wiki/rest/api/content/search":
+		s.cql = append(s.cql, r.URL.Query().Get("cql"))
+		result := make(map[string]any)
+		for k, v := range page {
+			if k != "body" && k != "_links" {
+				result[k] = v
+			}
+		}
+		result["_links"] = map[string]any{"webui": "/spaces/ENG/pages/123/VPN+guide"}
+		json.NewEncoder(w).Encode(map[string]any{
+			"results": []any{result, map[string]any{"id": "att1", "title": "not a page id"}},
+			"_links":  map[string]any{"base": "https://example.atlassian.net/wiki"},
+		})
+	case "/wiki/rest/api/content/123":
+		json.NewEncoder(w).Encode(page)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"statusCode":404,"message":"No space with key : NOPE"}`)
+	}
+}
+
+func newSource(t *testing.T, cfg confluence.Config) (*confluence.Source, *site) {
+	t.Helper()
+	fake := &site{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	cfg.URL = srv.URL + "/wiki"
+	ds := confluence.New(cfg)
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return ds, fake
+}
+
+func TestFetchTopics(t *testing.T) {
+	ds, fake := newSource(t, confluence.Config{Email: "bot@example.com", APIToken: "secret", Spaces: []string{"ENG", "OPS"}})
+	topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{
+		QuestionText: `vpn "setup"`,
+		Tags:         []string{"network"},
+		Filters:      datasource.Filters{Sites: []string{"ENG"}, ExcludeTags: []string{"archived"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 1 {
+		t.Fatalf("got %d topics, want 1: %+v", len(topics), topics)
+	}
+	got := topics[0]
+	if got.Topic != "VPN guide" || got.TopicID != 123 || got.Site != "ENG" || got.Rank != 1 ||
+		got.SourceURL != "https://example.atlassian.net/wiki/spaces/ENG/pages/123/VPN+guide" ||
+		got.CreatedAt.Day() != 2 || got.UpdatedAt.Month() != 2 {
+		t.Errorf("topic = %+v", got)
+	}
+	if got.Title != "VPN guide" || !reflect.DeepEqual(got.Path, []string{"Engineering", "Runbooks"}) {
+		t.Errorf("title %q, path %q", got.Title, got.Path)
+	}
+	if v, _ := got.Metadata.String(confluence.MetadataSpace); v != "Engineering" {
+		t.Errorf("space = %q", v)
+	}
+	want := `type = page AND text ~ "vpn \"setup\"" AND space in ("ENG") AND label in ("network") AND label not in ("archived")`
+	if fake.cql[0] != want {
+		t.Errorf("cql = %s\nwant %s", fake.cql[0], want)
+	}
+	if !strings.HasPrefix(fake.auth, "Basic ") {
+		t.Errorf("Authorization = %q", fake.auth)
+	}
+
+	topics, err = ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "vpn", Filters: datasource.Filters{Sites: []string{"HR"}}})
+	if err != nil || len(topics) != 0 || len(fake.cql) != 1 {
+		t.Errorf("FetchTopics(other space) = %+v, %v", topics, err)
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	ds, fake := newSource(t, confluence.Config{Token: "pat"})
+	data, err := ds.FetchData(10, 123)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.auth != "Bearer pat" {
+		t.Errorf("Authorization = %q", fake.auth)
+	}
+	want := []struct{ url, section, text string }{
+		{"https://example.atlassian.net/wiki/spaces/ENG/pages/123/VPN+guide", "",
+			`<p>Connect to the <strong>VPN</strong> before deploying.</p>`},
+		{"https://example.atlassian.net/wiki/spaces/ENG/pages/123/VPN+guide#Install-the-client", "Install the client",
+			`<blockquote><p><strong>Note</strong></p><p>See Laptop setup.</p></blockquote>` +
+				`<pre><code class="language-bash">sudo apt install vpn &amp;&amp; echo &#34;&lt;ok&gt;&#34;</code></pre>`},
+		{"https://example.atlassian.net/wiki/spaces/ENG/pages/123/VPN+guide#Troubleshooting", "Troubleshooting",
+			`<ul><li>Restart</li></ul>`},
+	}
+	if len(data) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(data), len(want), data)
+	}
+	for i, w := range want {
+		d := data[i]
+		section, _ := d.Metadata.String(confluence.MetadataSection)
+		if d.SourceURL != w.url || section != w.section || d.DataText != w.text || d.AnswerID != int64(i) ||
+			d.ContentType != datasource.ContentHTML || d.Author == nil || d.Author.Name != "Ada" {
+			t.Errorf("item %d = %s %q %q, want %s %q %q", i, d.SourceURL, section, d.DataText, w.url, w.section, w.text)
+		}
+	}
+
+	if _, err := ds.FetchData(10, 456); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	if err := confluence.New(confluence.Config{}).Init(); err == nil {
+		t.Error("Init without a URL succeeded")
+	}
+	srv := httptest.NewServer(&site{})
+	defer srv.Close()
+	err := confluence.New(confluence.Config{URL: srv.URL + "/wiki", Spaces: []string{"NOPE"}}).Init()
+	if !errors.Is(err, datasource.ErrNotFound) || !strings.Contains(err.Error(), "No space with key") {
+		t.Errorf("Init(missing space) = %v", err)
+	}
+}
@@ -0,0 +1,269 @@
+package confluence
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// calloutMacros are the macros rendered as quoted panels.
+var calloutMacros = map[string]bool{
+	"info": true, "note": true, "panel": true, "tip": true, "warning": true,
+}
+
+// droppedElements are the storage format elements removed with their
+// content: macro parameters, media, and task metadata.
+var droppedElements = []string{
+	"ac:parameter", "ac:image", "ac:emoticon", "ac:placeholder",
+	"ac:task-id", "ac:task-status", "ac:inline-comment-marker-ref",
+}
+
+// renamedElements are the storage format elements converted to HTML ones.
+var renamedElements = map[string]string{
+	"ac:task-list": "ul",
+	"ac:task":      "li",
+}
+
+var attrPattern = regexp.MustCompile(`([\w:-]+)\s*=\s*"([^"]*)"`)
+
+// storageHTML converts a page body in Confluence storage format, XHTML
+// with ac: and ri: elements, to sanitized HTML: code macros become code
+// blocks, callout macros quotes, links their text, and other macros their
+// rich text body, if any.
+func storageHTML(s string) string {
+	return content.Sanitize(convert(s))
+}
+
+func convert(s string) string {
+	var b strings.Builder
+	for len(s) > 0 {
+		i := strings.IndexByte(s, '<')
+		if i < 0 {
+			b.WriteString(s)
+			break
+		}
+		b.WriteString(s[:i])
+		s = s[i:]
+		if strings.HasPrefix(s, "<![CDATA[") {
+			text, rest, _ := strings.Cut(s[len("<![CDATA["):], "]]>")
+			b.WriteString(html.EscapeString(text))
+			s = rest
+			continue
+		}
+		name, closing := tagName(s)
+		switch {
+		case name == "ac:structured-macro" && !closing:
+			attrs, inner, rest := element(s, name)
+			b.WriteString(macro(attrs["ac:name"], inner))
+			s = rest
+		case name == "ac:link" && !closing:
+			_, inner, rest := element(s, name)
+			b.WriteString(link(inner))
+			s = rest
+		case dropped(name) && !closing:
+			_, _, rest := element(s, name)
+			s = rest
+		case renamedElements[name] != "":
+			end := tagEnd(s)
+			if closing {
+				b.WriteString("</" + renamedElements[name] + ">")
+			} else {
+				b.WriteString("<" + renamedElements[name] + ">")
+			}
+			s = s[end:]
+		default:
+			b.WriteByte('<')
+			s = s[1:]
+		}
+	}
+	return b.String()
+}
+
+// macro renders a structured macro with the name and content.
+func macro(name, inner string) string {
+	params := parameters(inner)
+	switch {
+	case name == "code" || name == "noformat":
+		_, body, _ := element(find(inner, "ac:plain-text-body"), "ac:plain-text-body")
+		class := ""
+		if lang := params["language"]; lang != "" {
+			class = ` class="language-` + html.EscapeString(lang) + `"`
+		}
+		return "<pre><code" + class + ">" + convert(body) + "</code></pre>"
+	case calloutMacros[name]:
+		_, body, _ := element(find(inner, "ac:rich-text-body"), "ac:rich-text-body")
+		title := ""
+		if t := params["title"]; t != "" {
+			title = "<p><strong>" + html.EscapeString(t) + "</strong></p>"
+		}
+		return "<blockquote>" + title + convert(body) + "</blockquote>"
+	}
+	if body := find(inner, "ac:rich-text-body"); body != "" {
+		_, body, _ = element(body, "ac:rich-text-body")
+		return "<div>" + convert(body) + "</div>"
+	}
+	return ""
+}
+
+// parameters returns the values of a macro's parameters by name.
+func parameters(inner string) map[string]string {
+	params := make(map[string]string)
+	for p := find(inner, "ac:parameter"); p != ""; p = find(p, "ac:parameter") {
+		attrs, value, rest := element(p, "ac:parameter")
+		if _, ok := params[attrs["ac:name"]]; !ok {
+			params[attrs["ac:name"]] = html.UnescapeString(strings.TrimSpace(value))
+		}
+		p = rest
+	}
+	return params
+}
+
+// link renders a link's body, or else the title of the page, the name of
+// the attachment, or the key of the space it points to.
+func link(inner string) string {
+	for _, body := range []string{"ac:link-body", "ac:plain-text-link-body"} {
+		if el := find(inner, body); el != "" {
+			_, text, _ := element(el, body)
+			return convert(text)
+		}
+	}
+	for _, m := range attrPattern.FindAllStringSubmatch(inner, -1) {
+		switch m[1] {
+		case "ri:content-title", "ri:filename", "ri:space-key":
+			return m[2]
+		}
+	}
+	return ""
+}
+
+func dropped(name string) bool {
+	for _, d := range droppedElements {
+		if name == d {
+			return true
+		}
+	}
+	return false
+}
+
+// tagName returns the lowercased name of the tag s starts with, and
+// whether it is an end tag.
+func tagName(s string) (string, bool) {
+	s = s[1:]
+	closing := strings.HasPrefix(s, "/")
+	if closing {
+		s = s[1:]
+	}
+	end := strings.IndexAny(s, " \t\r\n/>")
+	if end < 0 {
+		return "", false
+	}
+	return strings.ToLower(s[:end]), closing
+}
+
+// tagEnd returns the index after the tag s starts with.
+func tagEnd(s string) int {
+	if i := strings.IndexByte(s, '>'); i >= 0 {
+		return i + 1
+	}
+	return len(s)
+}
+
+// find returns s from the first start tag of the element on, or "".
+func find(s, name string) string {
+	for i := 0; ; {
+		j := strings.Index(s[i:], "<"+name)
+		if j < 0 {
+			return ""
+		}
+		i += j
+		if n, closing := tagName(s[i:]); n == name && !closing {
+			return s[i:]
+		}
+		i++
+	}
+}
+
+// element reads the element of the name s starts with, and returns its
+// attributes, its content, and s after its end tag.
+func element(s, name string) (attrs map[string]string, inner, rest string) {
+	if s == "" {
+		return nil, "", ""
+	}
+	end := tagEnd(s)
+	attrs = make(map[string]string)
+	for _, m := range attrPattern.FindAllStringSubmatch(s[:end], -1) {
+		attrs[m[1]] = html.UnescapeString(m[2])
+	}
+	if strings.HasSuffix(s[:end], "/>") {
+		return attrs, "", s[end:]
+	}
+	depth := 1
+	for i := end; i < len(s); {
+		j := strings.IndexByte(s[i:], '<')
+		if j < 0 {
+			break
+		}
+		i += j
+		if strings.HasPrefix(s[i:], "<![CDATA[") {
+			k := strings.Index(s[i:], "]]>")
+			if k < 0 {
+				break
+			}
+			i += k + len("]]>")
+			continue
+		}
+		n, closing := tagName(s[i:])
+		k := tagEnd(s[i:])
+		switch {
+		case n != name:
+		case closing:
+			depth--
+		case !strings.HasSuffix(s[i:i+k], "/>"):
+			depth++
+		}
+		if depth == 0 {
+			return attrs, s[end:i], s[i+k:]
+		}
+		i += k
+	}
+	return attrs, s[end:], ""
+}
+
+// section is a part of a page: the lead before the first heading, or a
+// level 1 to 3 heading and the content up to the next.
+type section struct {
+	heading string // empty for the lead
+	anchor  datasource.Anchor
+	html    string
+}
+
+var headingPattern = regexp.MustCompile(`(?s)<h([1-3])>(.*?)</h[1-3]>`)
+
+// sections splits sanitized HTML at its level 1 to 3 headings, leaving out
+// sections without text.
+func sections(body string) []section {
+	var out []section
+	add := func(heading, html string) {
+		html = strings.TrimSpace(html)
+		if strings.TrimSpace(content.StripHTML(html)) != "" {
+			out = append(out, section{heading: heading, anchor: headingAnchor(heading), html: html})
+		}
+	}
+	heading, start := "", 0
+	for _, m := range headingPattern.FindAllStringSubmatchIndex(body, -1) {
+		add(heading, body[start:m[0]])
+		heading = strings.TrimSpace(content.StripHTML(body[m[4]:m[5]]))
+		start = m[1]
+	}
+	add(heading, body[start:])
+	return out
+}
+
+// headingAnchor returns the anchor Confluence gives a heading: its words
+// joined by hyphens, in their case.
+func headingAnchor(heading string) datasource.Anchor {
+	return datasource.Anchor(strings.Join(strings.Fields(heading), "-"))
+}
@@ -0,0 +1,105 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// maxResponseBytes bounds response bodies read from the cluster.
+const maxResponseBytes = 32 << 20
+
+// apiError is the error object of a failed request.
+type apiError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// searchResponse is the part of a _search response the source reads.
+type searchResponse struct {
+	TimedOut bool `json:"timed_out"`
+	Hits     struct {
+		// Total is the number of matching documents, or a lower bound of
+		// it if the cluster stopped counting
+		Total *struct {
+			Value int64 `json:"value"`
+		} `json:"total"`
+		Hits []hit `json:"hits"`
+	} `json:"hits"`
+}
+
+type hit struct {
+	Index  string          `json:"_index"`
+	ID     string          `json:"_id"`
+	Score  float64         `json:"_score"`
+	Source json.RawMessage `json:"_source"`
+}
+
+// do sends a request with a JSON body, if not nil, to path under the
+// cluster's address, and decodes the JSON response into into, if not nil.
+func (s *Source) do(ctx context.Context, method, path string, body, into any) error {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("elasticsearch: %w", err)
+		}
+		r = bytes.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(s.cfg.URL, "/")+path, r)
+	if err != nil {
+		return fmt.Errorf("elasticsearch: %w", err)
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	switch {
+	case s.cfg.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+s.cfg.APIKey)
+	case s.cfg.Username != "":
+		req.SetBasicAuth(s.cfg.Username, s.cfg.Password)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("elasticsearch: %w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var e struct {
+			Error json.RawMessage `json:"error"`
+		}
+		json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&e)
+		if reason := errorReason(e.Error); reason != "" {
+			return fmt.Errorf("elasticsearch: %w: %s", httpx.StatusError(resp), reason)
+		}
+		return fmt.Errorf("elasticsearch: %w", httpx.StatusError(resp))
+	}
+	if into == nil {
+		return nil
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(into); err != nil {
+		return fmt.Errorf("elasticsearch: decode response: %w", err)
+	}
+	return nil
+}
+
+// errorReason describes the error of a failed request, which is an object
+// or, from old clusters and proxies, a string.
+func errorReason(raw json.RawMessage) string {
+	var e apiError
+	if err := json.Unmarshal(raw, &e); err == nil && e.Type != "" {
+		return e.Type + ": " + e.Reason
+	}
+	var s string
+	json.Unmarshal(raw, &s)
+	return s
+}
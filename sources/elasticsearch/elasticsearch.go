@@ -0,0 +1,594 @@
+// Package elasticsearch is a data source searching an existing
+// Elasticsearch or OpenSearch index. Each document of the index is a
+// topic, whose data item is the document's text; Config.Fields maps
+// document fields to the topic and data item fields:
+//
+//	ds := elasticsearch.New(elasticsearch.Config{
+//	    URL:    "https://search.internal:9200",
+//	    Index:  "kb-articles",
+//	    APIKey: os.Getenv("ES_API_KEY"),
+//	    Fields: elasticsearch.Fields{
+//	        Topic:     "headline",
+//	        DataText:  "body_html",
+//	        SourceURL: "permalink",
+//	        Vector:    "body_embedding",
+//	    },
+//	})
+//
+// Questions are answered with a BM25 query on Fields.Search, or, if they
+// carry an embedding and Fields.Vector is set, with a kNN query on the
+// vector field; set Config.Hybrid to combine both. The query syntax of
+// Elasticsearch 8 or OpenSearch is used, as Init detects. The cluster's
+// hit count is recorded as the estimate of matches in query reports, and
+// EstimateMatches counts them on request.
+package elasticsearch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"maps"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultURL       = "http://localhost:9200"
+	DefaultTopic     = "title"
+	DefaultDataText  = "content"
+	DefaultSourceURL = "url"
+	DefaultTimeout   = 10 * time.Second
+)
+
+// Metadata keys set on topics and data items, besides Fields.Metadata.
+const (
+	MetadataIndex = "index" // the index the document is in
+	MetadataTags  = "tags"  // the document's tags, if Fields.Tags is set
+)
+
+// Fields maps the fields of the index's documents to those of topics and
+// data items. Names may be paths of nested objects, such as
+// "author.name".
+type Fields struct {
+	// Topic is the field holding the topic's title
+	// Defaults to DefaultTopic
+	Topic string
+
+	// DataText is the field holding the text of the data item, a string
+	// or an array of strings each becoming an item
+	// Defaults to DefaultDataText
+	DataText string
+
+	// SourceURL is the field holding the document's address
+	// Defaults to DefaultSourceURL
+	SourceURL string
+
+	// Search lists the fields BM25 queries match, with optional boosts
+	// such as "title^2"
+	// Defaults to Topic boosted twice, and DataText
+	Search []string
+
+	// Vector is a dense_vector (Elasticsearch) or knn_vector (OpenSearch)
+	// field, for kNN queries with the question's embedding
+	// Optional
+	Vector string
+
+	// ID is a numeric field used as the topic ID. Without it, numeric
+	// document IDs are used, and other IDs are hashed, which FetchData
+	// resolves only for topics this Source returned since it started
+	// Optional
+	ID string
+
+	// Site, Language, Tags, CreatedAt, UpdatedAt, and Author are the
+	// fields filling the like-named topic and data item fields; filters on
+	// them are sent with queries
+	// Optional
+	Site, Language, Tags, CreatedAt, UpdatedAt, Author string
+
+	// Metadata lists fields copied into the Metadata of topics and data
+	// items, under their names
+	// Optional
+	Metadata []string
+}
+
+func (f Fields) withDefaults() Fields {
+	if f.Topic == "" {
+		f.Topic = DefaultTopic
+	}
+	if f.DataText == "" {
+		f.DataText = DefaultDataText
+	}
+	if f.SourceURL == "" {
+		f.SourceURL = DefaultSourceURL
+	}
+	if len(f.Search) == 0 {
+		f.Search = []string{f.Topic + "^2", f.DataText}
+	}
+	return f
+}
+
+// Config configures a Source.
+type Config struct {
+	// URL is the address of the cluster
+	// Defaults to DefaultURL
+	URL string
+
+	// Index is the index, alias, or comma-separated index patterns to
+	// search
+	Index string
+
+	// Fields maps document fields to topic and data item fields
+	Fields Fields
+
+	// Hybrid combines BM25 and kNN scores for questions with an embedding,
+	// instead of answering them with kNN alone
+	Hybrid bool
+
+	// EmbeddingModel names the model of the vectors in Fields.Vector; if
+	// set, the vectors are returned as the Embedding of results and the
+	// source reports the model in its Capabilities
+	// Optional
+	EmbeddingModel string
+
+	// QueryEmbedding names the entry of NewQuestionInput.Embeddings that is
+	// matched against Fields.Vector, for an index embedded with another
+	// model than the host's default (e.g., "code"); the source declares it
+	// in its Capabilities
+	// Optional - empty uses NewQuestionInput.Embedding
+	QueryEmbedding string
+
+	// APIKey is an encoded Elasticsearch API key; Username and Password
+	// are used for basic authentication instead if it is empty
+	// Optional
+	APIKey             string
+	Username, Password string
+
+	// ContentType is the format of the DataText field
+	// Defaults to detecting it with content.Detect
+	ContentType datasource.ContentType
+
+	// Timeout bounds each request, in addition to NewQuestionInput.Budget
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// HTTPClient makes the requests
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func (c Config) withDefaults() Config {
+	if c.URL == "" {
+		c.URL = DefaultURL
+	}
+	c.Fields = c.Fields.withDefaults()
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+// engine is the search engine of a cluster, which decides the kNN query
+// syntax.
+type engine int
+
+const (
+	engineElasticsearch engine = iota
+	engineOpenSearch
+)
+
+// maxHashedIDs bounds the document IDs remembered for hashed topic IDs,
+// per generation.
+const maxHashedIDs = 50000
+
+// Source is a DataSource searching an Elasticsearch or OpenSearch index.
+// A Source is safe for concurrent use.
+type Source struct {
+	cfg    Config
+	engine engine // set by Init
+
+	// ids maps hashed topic IDs to document IDs, in two generations: when
+	// the current one is full it replaces the previous one
+	mu       sync.Mutex
+	ids, old map[int64]string
+}
+
+var (
+	_ datasource.DataSource         = (*Source)(nil)
+	_ datasource.CapabilityReporter = (*Source)(nil)
+	_ datasource.HealthChecker      = (*Source)(nil)
+	_ datasource.MatchEstimator     = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults(), ids: make(map[int64]string)}
+}
+
+// Init detects whether the cluster runs Elasticsearch or OpenSearch, and
+// checks that the index exists.
+func (s *Source) Init() error {
+	if s.cfg.Index == "" {
+		return errors.New("elasticsearch: no index configured")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	var info struct {
+		Version struct {
+			Distribution string `json:"distribution"`
+		} `json:"version"`
+	}
+	if err := s.do(ctx, http.MethodGet, "/", nil, &info); err != nil {
+		return err
+	}
+	if info.Version.Distribution == "opensearch" {
+		s.engine = engineOpenSearch
+	}
+	return s.do(ctx, http.MethodGet, "/"+url.PathEscape(s.cfg.Index)+"/_count", nil, nil)
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck reports the cluster health: unhealthy if the cluster cannot
+// be reached or its status is red, and degraded if it is yellow.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	var health struct {
+		Status string `json:"status"`
+	}
+	err := s.do(ctx, http.MethodGet, "/_cluster/health/"+url.PathEscape(s.cfg.Index), nil, &health)
+	h := datasource.HealthStatus{State: datasource.Healthy, Latency: time.Since(start)}
+	switch {
+	case err != nil:
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	case health.Status == "red":
+		h.State, h.Error = datasource.Unhealthy, "elasticsearch: cluster status red"
+	case health.Status == "yellow":
+		h.State, h.Error = datasource.Degraded, "elasticsearch: cluster status yellow"
+	}
+	return h
+}
+
+// Capabilities reports semantic search if a vector field is mapped, tag
+// filtering if a tags field is, and several sites if a site field is.
+func (s *Source) Capabilities() datasource.Capabilities {
+	f := s.cfg.Fields
+	return datasource.Capabilities{
+		Embeddings:       f.Vector != "",
+		QueryEmbeddings:  s.queryEmbeddings(),
+		TagFiltering:     f.Tags != "",
+		MultiSite:        f.Site != "",
+		ResultEmbeddings: s.vectorField() != "",
+		EmbeddingModel:   s.cfg.EmbeddingModel,
+	}
+}
+
+// FetchTopics searches the index for the question; see searchBody. A
+// topic's Score is the document's _score.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if count <= 0 || strings.TrimSpace(input.QuestionText) == "" && len(s.queryVector(input)) == 0 {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	ctx, cancel := input.Budget.Context(context.Background())
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	var resp searchResponse
+	if err := s.do(ctx, http.MethodPost, "/"+url.PathEscape(s.cfg.Index)+"/_search", s.searchBody(count, input), &resp); err != nil {
+		return nil, err
+	}
+	// The total of a kNN query alone is its k, not a count of matches.
+	if t := resp.Hits.Total; t != nil && (len(s.queryVector(input)) == 0 || s.cfg.Hybrid) {
+		input.Trace.Estimate(t.Value)
+	}
+	topics := make([]datasource.DataSourceTopic, 0, len(resp.Hits.Hits))
+	for _, h := range resp.Hits.Hits {
+		d, err := s.document(h)
+		if err != nil {
+			return nil, err
+		}
+		if datasource.AcceptsLanguage(input.AcceptLanguages, d.language) {
+			topics = append(topics, d.topic(h))
+		}
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// EstimateMatches counts the documents matching the question's text and
+// filters with the _count API. Embeddings are ignored, as a kNN query
+// matches every document; the count is of keyword matches.
+func (s *Source) EstimateMatches(ctx context.Context, input datasource.NewQuestionInput) (int64, error) {
+	if strings.TrimSpace(input.QuestionText) == "" {
+		return 0, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	input.Embedding, input.Embeddings = nil, nil
+	var resp struct {
+		Count int64 `json:"count"`
+	}
+	body := object{"query": s.searchBody(0, input)["query"]}
+	if err := s.do(ctx, http.MethodPost, "/"+url.PathEscape(s.cfg.Index)+"/_count", body, &resp); err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
+}
+
+// FetchData returns the text of the document with the topic ID: one item,
+// or one per string if the DataText field holds an array.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	var resp searchResponse
+	body := object{"size": 1, "_source": s.sourceFields(), "query": s.idQuery(topicID)}
+	if err := s.do(ctx, http.MethodPost, "/"+url.PathEscape(s.cfg.Index)+"/_search", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Hits.Hits) == 0 {
+		return nil, fmt.Errorf("elasticsearch: document %d: %w", topicID, datasource.ErrNotFound)
+	}
+	h := resp.Hits.Hits[0]
+	d, err := s.document(h)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]datasource.DataSourceData, 0, min(max(count, 0), len(d.texts)))
+	for i, text := range d.texts {
+		if len(items) >= count {
+			break
+		}
+		items = append(items, d.data(h, i, text, s.cfg.ContentType))
+	}
+	return items, nil
+}
+
+// idQuery returns the query finding the document with the topic ID.
+func (s *Source) idQuery(topicID int64) object {
+	if s.cfg.Fields.ID != "" {
+		return object{"term": object{s.cfg.Fields.ID: topicID}}
+	}
+	s.mu.Lock()
+	id, ok := s.ids[topicID]
+	if !ok {
+		id, ok = s.old[topicID]
+	}
+	s.mu.Unlock()
+	if !ok {
+		id = strconv.FormatInt(topicID, 10)
+	}
+	return object{"ids": object{"values": []string{id}}}
+}
+
+// topicID returns the topic ID of the document hit, remembering hashed
+// IDs for FetchData.
+func (s *Source) topicID(h hit, src map[string]any) (int64, error) {
+	if s.cfg.Fields.ID != "" {
+		v, ok := lookup(src, s.cfg.Fields.ID)
+		if !ok {
+			return 0, fmt.Errorf("elasticsearch: document %s has no %s field", h.ID, s.cfg.Fields.ID)
+		}
+		n, err := strconv.ParseInt(fmt.Sprint(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("elasticsearch: document %s: %s is not an integer", h.ID, s.cfg.Fields.ID)
+		}
+		return n, nil
+	}
+	if n, err := strconv.ParseInt(h.ID, 10, 64); err == nil && n > 0 {
+		return n, nil
+	}
+	hash := fnv.New64a()
+	hash.Write([]byte(h.ID))
+	id := int64(hash.Sum64()>>1) | 1
+	s.mu.Lock()
+	if len(s.ids) >= maxHashedIDs {
+		s.old, s.ids = s.ids, make(map[int64]string)
+	}
+	s.ids[id] = h.ID
+	s.mu.Unlock()
+	return id, nil
+}
+
+// document is a search hit read through the field mapping.
+type document struct {
+	id               int64
+	title, url       string
+	site, language   string
+	tags             []string
+	created, updated time.Time
+	author           string
+	texts            []string
+	embedding        []float64
+	metadata         datasource.Metadata
+}
+
+func (s *Source) document(h hit) (document, error) {
+	var src map[string]any
+	if err := json.Unmarshal(h.Source, &src); err != nil {
+		return document{}, fmt.Errorf("elasticsearch: decode document %s: %w", h.ID, err)
+	}
+	id, err := s.topicID(h, src)
+	if err != nil {
+		return document{}, err
+	}
+	f := s.cfg.Fields
+	d := document{
+		id:       id,
+		title:    str(src, f.Topic),
+		url:      str(src, f.SourceURL),
+		site:     str(src, f.Site),
+		language: str(src, f.Language),
+		tags:     strs(src, f.Tags),
+		created:  date(src, f.CreatedAt),
+		updated:  date(src, f.UpdatedAt),
+		author:   str(src, f.Author),
+		texts:    strs(src, f.DataText),
+	}
+	if v := s.vectorField(); v != "" {
+		if raw, ok := lookup(src, v); ok {
+			for _, x := range asSlice(raw) {
+				if n, ok := x.(float64); ok {
+					d.embedding = append(d.embedding, n)
+				}
+			}
+		}
+	}
+	d.metadata.Set(MetadataIndex, h.Index)
+	if len(d.tags) > 0 {
+		d.metadata.Set(MetadataTags, d.tags)
+	}
+	for _, m := range f.Metadata {
+		if v, ok := lookup(src, m); ok {
+			d.metadata.Set(m, v)
+		}
+	}
+	return d, nil
+}
+
+func (d document) topic(h hit) datasource.DataSourceTopic {
+	return datasource.DataSourceTopic{
+		Topic:     d.title,
+		SourceURL: d.url,
+		Site:      d.site,
+		TopicID:   d.id,
+		Score:     h.Score,
+		Metadata:  maps.Clone(d.metadata),
+		Language:  d.language,
+		Embedding: d.embedding,
+		CreatedAt: d.created,
+		UpdatedAt: d.updated,
+	}
+}
+
+func (d document) data(h hit, i int, text string, ct datasource.ContentType) datasource.DataSourceData {
+	if ct == "" {
+		ct = content.Detect(text)
+	}
+	item := datasource.DataSourceData{
+		DataText:    text,
+		ContentType: ct,
+		SourceURL:   d.url,
+		Site:        d.site,
+		AnswerID:    int64(i),
+		Rank:        i + 1,
+		Metadata:    maps.Clone(d.metadata),
+		Language:    d.language,
+		CreatedAt:   d.created,
+		UpdatedAt:   d.updated,
+	}
+	if len(d.texts) == 1 {
+		item.Embedding = d.embedding
+	}
+	if d.author != "" {
+		item.Author = &datasource.Author{Name: d.author}
+	}
+	return item
+}
+
+// lookup returns the value at the dotted path in a document's source,
+// which may name nested objects or a field whose name has dots.
+func lookup(src map[string]any, path string) (any, bool) {
+	if path == "" {
+		return nil, false
+	}
+	if v, ok := src[path]; ok {
+		return v, true
+	}
+	for i := strings.IndexByte(path, '.'); i >= 0; i = next(path, i) {
+		if obj, ok := src[path[:i]].(map[string]any); ok {
+			if v, ok := lookup(obj, path[i+1:]); ok {
+				return v, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// next returns the index of the dot in path after the one at i, or -1.
+func next(path string, i int) int {
+	j := strings.IndexByte(path[i+1:], '.')
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+func asSlice(v any) []any {
+	if s, ok := v.([]any); ok {
+		return s
+	}
+	return []any{v}
+}
+
+// str returns the string at path, or the first of an array.
+func str(src map[string]any, path string) string {
+	if s := strs(src, path); len(s) > 0 {
+		return s[0]
+	}
+	return ""
+}
+
+// strs returns the non-empty strings at path, a string or an array.
+func strs(src map[string]any, path string) []string {
+	v, ok := lookup(src, path)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, x := range asSlice(v) {
+		switch x := x.(type) {
+		case string:
+			if strings.TrimSpace(x) != "" {
+				out = append(out, x)
+			}
+		case float64, bool:
+			out = append(out, fmt.Sprint(x))
+		}
+	}
+	return out
+}
+
+// date returns the date at path: an RFC 3339 string, a date without a
+// time, or milliseconds since the epoch, as Elasticsearch's default date
+// format accepts.
+func date(src map[string]any, path string) time.Time {
+	v, ok := lookup(src, path)
+	if !ok {
+		return time.Time{}
+	}
+	switch v := v.(type) {
+	case float64:
+		return time.UnixMilli(int64(v)).UTC()
+	case string:
+		for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+			if t, err := time.Parse(layout, v); err == nil {
+				return t.UTC()
+			}
+		}
+		if ms, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.UnixMilli(ms).UTC()
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,315 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/elasticsearch"
+)
+
+var docs = []map[string]any{
+	{
+		"_index": "kb-2026", "_id": "reset-password", "_score": 7.5,
+		"_source": map[string]any{
+			"headline":  "Resetting your password",
+			"body":      []any{"<p>Open <b>Settings</b>.</p>", "Choose Reset."},
+			"permalink": "https://kb.example.com/reset",
+			"lang":      "en",
+			"tags":      []any{"accounts", "security"},
+			"meta":      map[string]any{"published": "2026-03-01T10:00:00Z", "author": map[string]any{"name": "Ada"}},
+			"views":     120,
+			"vec":       []any{0.1, 0.2},
+		},
+	},
+	{
+		"_index": "kb-2026", "_id": "42", "_score": 3.25,
+		"_source": map[string]any{
+			"headline":  "Two-factor authentication",
+			"body":      "Enable 2FA in Settings.",
+			"permalink": "https://kb.example.com/2fa",
+			"meta":      map[string]any{"published": 1767225600000.0},
+		},
+	},
+}
+
+// cluster is a fake Elasticsearch or OpenSearch cluster serving docs.
+type cluster struct {
+	distribution string
+	status       string
+
+	mu       sync.Mutex
+	searches []map[string]any
+	auth     string
+}
+
+func (c *cluster) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.auth = r.Header.Get("Authorization")
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.URL.Path == "/":
+		json.NewEncoder(w).Encode(map[string]any{"version": map[string]any{"distribution": c.distribution, "number": "8.13.0"}})
+	case r.URL.Path == "/missing/_count":
+		w.WriteHeader(http.StatusNotFound)
+		io.WriteString(w, `{"error":{"type":"index_not_found_exception","reason":"no such index [missing]"},"status":404}`)
+	case strings.HasSuffix(r.URL.Path, "/_count"):
+		var body map[string]any
+		if json.NewDecoder(r.Body).Decode(&body) == nil {
+			c.searches = append(c.searches, body)
+			io.WriteString(w, `{"count":1250}`)
+			return
+		}
+		io.WriteString(w, `{"count":2}`)
+	case strings.HasPrefix(r.URL.Path, "/_cluster/health/"):
+		json.NewEncoder(w).Encode(map[string]any{"status": c.status})
+	case strings.HasSuffix(r.URL.Path, "/_search"):
+		var body map[string]any
+		json.NewDecoder(r.Body).Decode(&body)
+		c.searches = append(c.searches, body)
+		hits := docs
+		if q, ok := body["query"].(map[string]any); ok && q["ids"] != nil {
+			id := q["ids"].(map[string]any)["values"].([]any)[0]
+			hits = nil
+			for _, d := range docs {
+				if d["_id"] == id {
+					hits = append(hits, d)
+				}
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]any{"hits": map[string]any{"total": map[string]any{"value": 1250, "relation": "eq"}, "hits": hits}})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (c *cluster) lastSearch() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	b, _ := json.Marshal(c.searches[len(c.searches)-1])
+	return string(b)
+}
+
+func newSource(t *testing.T, c *cluster, cfg elasticsearch.Config) *elasticsearch.Source {
+	t.Helper()
+	srv := httptest.NewServer(c)
+	t.Cleanup(srv.Close)
+	cfg.URL = srv.URL
+	if cfg.Index == "" {
+		cfg.Index = "kb-*"
+	}
+	cfg.Fields = elasticsearch.Fields{
+		Topic: "headline", DataText: "body", SourceURL: "permalink", Vector: "vec",
+		Language: "lang", Tags: "tags", CreatedAt: "meta.published", Author: "meta.author.name",
+		Metadata: []string{"views"},
+	}
+	ds := elasticsearch.New(cfg)
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func TestFetchTopics(t *testing.T) {
+	c := &cluster{}
+	ds := newSource(t, c, elasticsearch.Config{APIKey: "secret"})
+
+	topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{
+		QuestionText:    "reset password",
+		Tags:            []string{"security"},
+		AcceptLanguages: []string{"en"},
+		Filters:         datasource.Filters{ExcludeTags: []string{"billing"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 {
+		t.Fatalf("topics = %+v", topics)
+	}
+	first := topics[0]
+	if first.Topic != "Resetting your password" || first.SourceURL != "https://kb.example.com/reset" || first.Score != 7.5 ||
+		first.Rank != 1 || first.Language != "en" || !first.CreatedAt.Equal(time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("first topic = %+v", first)
+	}
+	if views, _ := first.Metadata.Int("views"); views != 120 || first.Embedding != nil {
+		t.Errorf("metadata = %v, embedding = %v", first.Metadata, first.Embedding)
+	}
+	if topics[1].TopicID != 42 || !topics[1].CreatedAt.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("second topic = %+v, want the numeric document ID and an epoch date", topics[1])
+	}
+	if c.auth != "ApiKey secret" {
+		t.Errorf("Authorization = %q", c.auth)
+	}
+
+	body := c.lastSearch()
+	for _, want := range []string{
+		`"multi_match":{"fields":["headline^2","body"],"query":"reset password","type":"best_fields"}`,
+		`"should":{"terms":{"tags":["security"]}}`,
+		`"must_not":[{"terms":{"tags":["billing"]}}]`,
+		`{"term":{"lang":"en"}}`,
+		`"size":5`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("search body lacks %s:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, "knn") {
+		t.Errorf("kNN query sent without an embedding:\n%s", body)
+	}
+}
+
+func TestKNN(t *testing.T) {
+	c := &cluster{}
+	ds := newSource(t, c, elasticsearch.Config{})
+	input := datasource.NewQuestionInput{QuestionText: "reset", Embedding: []float64{0.5, 0.5}}
+	if _, err := ds.FetchTopics(3, input); err != nil {
+		t.Fatal(err)
+	}
+	body := c.lastSearch()
+	if !strings.Contains(body, `"knn":{"field":"vec","k":3,"num_candidates":100,"query_vector":[0.5,0.5]}`) || strings.Contains(body, "multi_match") {
+		t.Errorf("Elasticsearch kNN body:\n%s", body)
+	}
+
+	hybrid := newSource(t, c, elasticsearch.Config{Hybrid: true})
+	hybrid.FetchTopics(3, input)
+	if body := c.lastSearch(); !strings.Contains(body, `"knn":{`) || !strings.Contains(body, "multi_match") {
+		t.Errorf("hybrid body:\n%s", body)
+	}
+
+	c = &cluster{distribution: "opensearch"}
+	opensearch := newSource(t, c, elasticsearch.Config{EmbeddingModel: "minilm"})
+	topics, err := opensearch.FetchTopics(3, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body := c.lastSearch(); !strings.Contains(body, `"must":{"knn":{"vec":{"k":3,"vector":[0.5,0.5]}}}`) {
+		t.Errorf("OpenSearch kNN body:\n%s", body)
+	}
+	if len(topics[0].Embedding) != 2 {
+		t.Errorf("embedding = %v, want the document's vector", topics[0].Embedding)
+	}
+	if caps := opensearch.Capabilities(); !caps.Embeddings || !caps.ResultEmbeddings || caps.EmbeddingModel != "minilm" || !caps.TagFiltering {
+		t.Errorf("capabilities = %+v", caps)
+	}
+}
+
+func TestKNNQueryEmbedding(t *testing.T) {
+	c := &cluster{}
+	ds := newSource(t, c, elasticsearch.Config{QueryEmbedding: "code"})
+	if caps := ds.Capabilities(); len(caps.QueryEmbeddings) != 1 || caps.QueryEmbeddings[0] != "code" {
+		t.Errorf("QueryEmbeddings = %q", caps.QueryEmbeddings)
+	}
+	input := datasource.NewQuestionInput{
+		QuestionText: "nil map write",
+		Embedding:    []float64{0.5, 0.5},
+		Embeddings:   map[string][]float64{"code": {0.25, 0.75}},
+	}
+	if _, err := ds.FetchTopics(3, input); err != nil {
+		t.Fatal(err)
+	}
+	if body := c.lastSearch(); !strings.Contains(body, `"query_vector":[0.25,0.75]`) {
+		t.Errorf("kNN body does not use the code embedding:\n%s", body)
+	}
+
+	// Without the code embedding the default one, from another model, is
+	// not searched against the code vectors.
+	input.Embeddings = nil
+	if _, err := ds.FetchTopics(3, input); err != nil {
+		t.Fatal(err)
+	}
+	if body := c.lastSearch(); strings.Contains(body, "knn") || !strings.Contains(body, "multi_match") {
+		t.Errorf("body without the code embedding:\n%s", body)
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	c := &cluster{}
+	ds := newSource(t, c, elasticsearch.Config{})
+	topics, _ := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "password"})
+
+	items, err := ds.FetchData(5, topics[0].TopicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(c.lastSearch(), `"ids":{"values":["reset-password"]}`) {
+		t.Errorf("hashed topic ID not resolved:\n%s", c.lastSearch())
+	}
+	if len(items) != 2 || items[0].ContentType != datasource.ContentHTML || items[1].AnswerID != 1 ||
+		items[0].Author == nil || items[0].Author.Name != "Ada" || items[0].SourceURL != "https://kb.example.com/reset" {
+		t.Errorf("items = %+v, want one per text", items)
+	}
+	if items, _ := ds.FetchData(1, topics[0].TopicID); len(items) != 1 {
+		t.Errorf("FetchData(1) = %d items", len(items))
+	}
+
+	items, err = ds.FetchData(5, 42)
+	if err != nil || len(items) != 1 || items[0].DataText != "Enable 2FA in Settings." {
+		t.Errorf("FetchData(42) = %+v, %v", items, err)
+	}
+	if _, err := ds.FetchData(5, 7); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("unknown document: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestInitAndHealth(t *testing.T) {
+	srv := httptest.NewServer(&cluster{status: "yellow"})
+	defer srv.Close()
+	ds := elasticsearch.New(elasticsearch.Config{URL: srv.URL, Index: "missing"})
+	if err := ds.Init(); !errors.Is(err, datasource.ErrNotFound) || !strings.Contains(err.Error(), "index_not_found_exception") {
+		t.Errorf("Init() = %v, want the missing index reported", err)
+	}
+	if err := elasticsearch.New(elasticsearch.Config{URL: srv.URL}).Init(); err == nil {
+		t.Error("Init() without an index succeeded")
+	}
+
+	ds = elasticsearch.New(elasticsearch.Config{URL: srv.URL, Index: "kb"})
+	if h := ds.HealthCheck(); h.State != datasource.Degraded {
+		t.Errorf("health = %+v, want degraded for a yellow cluster", h)
+	}
+	srv.Close()
+	if ds.CheckAvailability() {
+		t.Error("available with the cluster down")
+	}
+}
+
+func TestEstimateMatches(t *testing.T) {
+	c := &cluster{}
+	ds := newSource(t, c, elasticsearch.Config{})
+	input := datasource.NewQuestionInput{QuestionText: "reset password", Filters: datasource.Filters{ExcludeTags: []string{"billing"}}}
+	n, err := ds.EstimateMatches(context.Background(), input)
+	if err != nil || n != 1250 {
+		t.Fatalf("EstimateMatches = %d, %v", n, err)
+	}
+	if body := c.lastSearch(); !strings.HasPrefix(body, `{"query":{"bool":`) || !strings.Contains(body, "multi_match") || !strings.Contains(body, "billing") {
+		t.Errorf("_count body:\n%s", body)
+	}
+
+	input.Trace = new(datasource.Trace)
+	ds.FetchTopics(5, input)
+	var report datasource.SourceReport
+	input.Trace.Fill(&report)
+	if report.TotalEstimate != 1250 {
+		t.Errorf("traced estimate = %d, want the hit count", report.TotalEstimate)
+	}
+
+	// A kNN query's hit count is its k, not an estimate.
+	input.Trace, input.Embedding = new(datasource.Trace), []float64{0.5, 0.5}
+	ds.FetchTopics(5, input)
+	report = datasource.SourceReport{}
+	input.Trace.Fill(&report)
+	if report.TotalEstimate != 0 {
+		t.Errorf("traced estimate of a kNN query = %d", report.TotalEstimate)
+	}
+	if n, err := ds.EstimateMatches(context.Background(), input); err != nil || n != 1250 || strings.Contains(c.lastSearch(), "knn") {
+		t.Errorf("EstimateMatches with an embedding = %d, %v; body %s", n, err, c.lastSearch())
+	}
+}
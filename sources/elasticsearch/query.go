@@ -0,0 +1,152 @@
+package elasticsearch
+
+import (
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// object is a JSON object of the query DSL.
+type object = map[string]any
+
+// minCandidates is the least number of candidates a kNN search considers
+// per shard; more candidates find closer neighbors at a higher cost.
+const minCandidates = 100
+
+// searchBody returns the _search request answering the question: a BM25
+// multi_match query on Fields.Search, a kNN query on Fields.Vector if the
+// question has an embedding, or both, their scores added, if
+// Config.Hybrid is set. Results are narrowed by the input's filters on the
+// mapped fields, and documents tagged with the input's Tags rank higher.
+func (s *Source) searchBody(count int, input datasource.NewQuestionInput) object {
+	f := s.cfg.Fields
+	filter, mustNot := s.filters(input)
+	vector := s.queryVector(input)
+	knn := len(vector) > 0
+
+	var must []any
+	if !knn || s.cfg.Hybrid {
+		must = append(must, object{"multi_match": object{
+			"query":  input.QuestionText,
+			"fields": f.Search,
+			"type":   "best_fields",
+		}})
+	}
+	body := object{"size": count, "_source": s.sourceFields()}
+	switch {
+	case knn && s.engine == engineOpenSearch:
+		must = append(must, object{"knn": object{f.Vector: object{"vector": vector, "k": count}}})
+	case knn:
+		k := object{
+			"field":          f.Vector,
+			"query_vector":   vector,
+			"k":              count,
+			"num_candidates": max(minCandidates, 10*count),
+		}
+		if len(filter) > 0 || len(mustNot) > 0 {
+			k["filter"] = object{"bool": object{"filter": filter, "must_not": mustNot}}
+		}
+		body["knn"] = k
+	}
+	if len(must) > 0 {
+		q := object{"filter": filter, "must_not": mustNot, "must": must[0]}
+		if len(must) > 1 {
+			q["must"] = object{"bool": object{"should": must}}
+		}
+		if f.Tags != "" && len(input.Tags) > 0 {
+			q["should"] = object{"terms": object{f.Tags: input.Tags}}
+		}
+		body["query"] = object{"bool": q}
+	}
+	if input.Filters.Sort == datasource.SortRecency && f.CreatedAt != "" {
+		body["sort"] = []any{object{f.CreatedAt: object{"order": "desc", "unmapped_type": "date"}}, "_score"}
+	}
+	return body
+}
+
+// filters returns the clauses of the input's filters that apply to mapped
+// fields. Documents without a language are kept whatever the accepted
+// languages; those without a site or date are kept too, as Filters.Topics
+// would keep them.
+func (s *Source) filters(input datasource.NewQuestionInput) (filter, mustNot []any) {
+	f := s.cfg.Fields
+	filter, mustNot = []any{}, []any{}
+	if f.Site != "" && len(input.Filters.Sites) > 0 {
+		filter = append(filter, orMissing(f.Site, object{"terms": object{f.Site: input.Filters.Sites}}))
+	}
+	if f.Language != "" && len(input.AcceptLanguages) > 0 {
+		var langs []any
+		for _, l := range input.AcceptLanguages {
+			// Accepting "en" accepts "en-GB" too.
+			primary := strings.ToLower(strings.SplitN(l, "-", 2)[0])
+			langs = append(langs, object{"term": object{f.Language: l}}, object{"prefix": object{f.Language: primary}})
+		}
+		filter = append(filter, orMissing(f.Language, object{"bool": object{"should": langs}}))
+	}
+	if f.CreatedAt != "" && (!input.Filters.After.IsZero() || !input.Filters.Before.IsZero()) {
+		r := object{}
+		if !input.Filters.After.IsZero() {
+			r["gte"] = input.Filters.After.UTC().Format(time.RFC3339Nano)
+		}
+		if !input.Filters.Before.IsZero() {
+			r["lt"] = input.Filters.Before.UTC().Format(time.RFC3339Nano)
+		}
+		filter = append(filter, orMissing(f.CreatedAt, object{"range": object{f.CreatedAt: r}}))
+	}
+	if f.Tags != "" && len(input.Filters.ExcludeTags) > 0 {
+		mustNot = append(mustNot, object{"terms": object{f.Tags: input.Filters.ExcludeTags}})
+	}
+	return filter, mustNot
+}
+
+// orMissing returns a clause matching documents matching clause or
+// without field.
+func orMissing(field string, clause object) object {
+	return object{"bool": object{"should": []any{
+		clause,
+		object{"bool": object{"must_not": object{"exists": object{"field": field}}}},
+	}}}
+}
+
+// sourceFields returns the document fields the source reads.
+func (s *Source) sourceFields() []string {
+	f := s.cfg.Fields
+	var fields []string
+	for _, name := range append([]string{
+		f.Topic, f.DataText, f.SourceURL, f.ID, f.Site, f.Language, f.Tags,
+		f.CreatedAt, f.UpdatedAt, f.Author, s.vectorField(),
+	}, f.Metadata...) {
+		if name != "" {
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// vectorField returns the vector field, if its vectors are returned with
+// results: only when Config.EmbeddingModel names their model.
+func (s *Source) vectorField() string {
+	if s.cfg.EmbeddingModel == "" {
+		return ""
+	}
+	return s.cfg.Fields.Vector
+}
+
+// queryEmbeddings returns the names of the question embeddings the source
+// consumes: Config.QueryEmbedding, if a vector field is mapped.
+func (s *Source) queryEmbeddings() []string {
+	if s.cfg.Fields.Vector == "" || s.cfg.QueryEmbedding == "" {
+		return nil
+	}
+	return []string{s.cfg.QueryEmbedding}
+}
+
+// queryVector returns the question's embedding to match against
+// Fields.Vector, or nil if there is none or no vector field is mapped.
+func (s *Source) queryVector(input datasource.NewQuestionInput) []float64 {
+	if s.cfg.Fields.Vector == "" {
+		return nil
+	}
+	return input.EmbeddingFor(s.queryEmbeddings()...)
+}
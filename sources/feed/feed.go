@@ -0,0 +1,621 @@
+// Package feed is a data source answering from RSS and Atom feeds, such as
+// blogs, changelogs, and status pages. Init fetches every feed and keeps
+// refreshing them in the background; topics are the indexed items whose
+// titles and summaries match the question's keywords, and each topic's
+// data is the item's content:
+//
+//	ds := feed.New(feed.Config{
+//	    Feeds: []string{
+//	        "https://go.dev/blog/feed.atom",
+//	        "https://www.githubstatus.com/history.rss",
+//	    },
+//	})
+//	if err := ds.Init(); err != nil { ... }
+//	defer datasource.Shutdown(ctx, ds)
+//
+// Items are kept in a MemoryStore unless Config.Store provides another
+// Store, such as one persisting them so that items which dropped out of a
+// feed stay searchable across restarts.
+//
+// The source also implements datasource.SyncSource, so a host can index
+// new and updated items ahead of questions instead of searching live.
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+	"github.com/locus-search/datasource-sdk/httpx"
+	"github.com/locus-search/datasource-sdk/similarity"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultRefreshInterval = 15 * time.Minute
+	DefaultMaxItems        = 5000
+	DefaultUserAgent       = "locus-datasource-sdk (https://github.com/locus-search/datasource-sdk)"
+	DefaultTimeout         = 30 * time.Second
+)
+
+// Metadata keys set on topics and data items.
+const (
+	MetadataFeed       = "feed"       // the title of the item's feed
+	MetadataSummary    = "summary"    // the item's summary, as plain text
+	MetadataCategories = "categories" // the item's categories
+)
+
+// maxFeedBytes bounds the documents read from feeds.
+const maxFeedBytes = 10 << 20
+
+// maxSummaryChars bounds MetadataSummary and untitled items' topics.
+const maxSummaryChars = 300
+
+// Config configures a Source.
+type Config struct {
+	// Feeds lists the addresses of the RSS or Atom feeds to index
+	Feeds []string
+
+	// RefreshInterval is how often feeds are fetched again after Init; a
+	// negative interval fetches them only in Init and Refresh
+	// Defaults to DefaultRefreshInterval
+	RefreshInterval time.Duration
+
+	// Store holds the indexed items
+	// Defaults to a MemoryStore of MaxItems
+	Store Store
+
+	// MaxItems bounds the default MemoryStore
+	// Defaults to DefaultMaxItems
+	MaxItems int
+
+	// UserAgent identifies the client to the feeds' servers
+	// Defaults to DefaultUserAgent
+	UserAgent string
+
+	// Timeout bounds the fetch of each feed
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// HTTPClient makes the requests
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func (c Config) withDefaults() Config {
+	if c.RefreshInterval == 0 {
+		c.RefreshInterval = DefaultRefreshInterval
+	}
+	if c.MaxItems <= 0 {
+		c.MaxItems = DefaultMaxItems
+	}
+	if c.Store == nil {
+		c.Store = NewMemoryStore(c.MaxItems)
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = DefaultUserAgent
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+// feedState is what a Source knows of a feed between refreshes.
+type feedState struct {
+	etag, lastModified string
+	err                error // of the last refresh
+}
+
+// Source is a DataSource indexing feeds. Topic IDs are item IDs (see
+// Item.ID). A Source is safe for concurrent use.
+type Source struct {
+	cfg Config
+
+	mu      sync.Mutex
+	feeds   map[string]*feedState
+	started bool
+	closed  bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+var (
+	_ datasource.DataSource         = (*Source)(nil)
+	_ datasource.CapabilityReporter = (*Source)(nil)
+	_ datasource.HealthChecker      = (*Source)(nil)
+	_ datasource.Closer             = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults(), feeds: make(map[string]*feedState)}
+}
+
+// Init fetches every feed, failing only if none can be read, and starts
+// refreshing them every RefreshInterval until Close.
+func (s *Source) Init() error {
+	if len(s.cfg.Feeds) == 0 {
+		return errors.New("feed: no feeds configured")
+	}
+	for _, f := range s.cfg.Feeds {
+		if u, err := url.Parse(f); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("feed: invalid feed address %q", f)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	if s.started || s.closed {
+		s.mu.Unlock()
+		cancel()
+		return nil
+	}
+	s.started, s.cancel = true, cancel
+	s.mu.Unlock()
+
+	if err := s.Refresh(ctx); err != nil && s.healthy() == 0 {
+		s.mu.Lock()
+		s.started = false
+		s.mu.Unlock()
+		cancel()
+		return err
+	}
+	if s.cfg.RefreshInterval > 0 {
+		s.wg.Add(1)
+		go s.run(ctx)
+	}
+	return nil
+}
+
+// run refreshes the feeds every RefreshInterval until ctx is done.
+func (s *Source) run(ctx context.Context) {
+	defer s.wg.Done()
+	t := time.NewTicker(s.cfg.RefreshInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.Refresh(ctx)
+		}
+	}
+}
+
+// Close stops refreshing the feeds, waiting for a refresh in progress to
+// stop or ctx to be done.
+func (s *Source) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Refresh fetches every feed now, concurrently, and stores their items.
+// It returns the failures joined.
+func (s *Source) Refresh(ctx context.Context) error {
+	errs := make([]error, len(s.cfg.Feeds))
+	var wg sync.WaitGroup
+	for i, f := range s.cfg.Feeds {
+		wg.Add(1)
+		go func(i int, f string) {
+			defer wg.Done()
+			if err := s.refresh(ctx, f); err != nil {
+				errs[i] = fmt.Errorf("feed: %s: %w", f, err)
+			}
+		}(i, f)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// refresh fetches the feed at addr, unless unchanged since the last
+// refresh, and stores its items.
+func (s *Source) refresh(ctx context.Context, addr string) (err error) {
+	s.mu.Lock()
+	st := s.feeds[addr]
+	if st == nil {
+		st = &feedState{}
+		s.feeds[addr] = st
+	}
+	etag, lastModified := st.etag, st.lastModified
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		st.err = err
+		s.mu.Unlock()
+	}()
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, addr, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", s.cfg.UserAgent)
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, text/xml;q=0.8, */*;q=0.1")
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil
+	case resp.StatusCode >= 300:
+		return httpx.StatusError(resp)
+	}
+	doc, err := io.ReadAll(io.LimitReader(resp.Body, maxFeedBytes))
+	if err != nil {
+		return fmt.Errorf("%w: %w", datasource.ErrUnavailable, err)
+	}
+	p, err := parse(doc)
+	if err != nil {
+		return err
+	}
+	for i := range p.items {
+		it := &p.items[i]
+		it.Feed, it.FeedTitle = addr, p.title
+		it.ID = itemID(addr, first(it.GUID, it.Link, it.Title))
+		if it.Language == "" {
+			it.Language = p.language
+		}
+	}
+	if err := s.cfg.Store.Put(p.items); err != nil {
+		return fmt.Errorf("store items: %w", err)
+	}
+	s.mu.Lock()
+	st.etag, st.lastModified = resp.Header.Get("ETag"), resp.Header.Get("Last-Modified")
+	s.mu.Unlock()
+	return nil
+}
+
+// itemID derives a positive topic ID from the feed's address and the
+// item's key.
+func itemID(feed, key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(feed))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return int64(h.Sum64()>>1) | 1
+}
+
+// healthy returns the number of feeds whose last refresh succeeded.
+func (s *Source) healthy() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for _, st := range s.feeds {
+		if st.err == nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck reports the outcome of the last refresh without fetching
+// the feeds: unhealthy if no feed could be read, and degraded if some
+// could not. Items of failing feeds are still served.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []string
+	for _, f := range s.cfg.Feeds {
+		if st := s.feeds[f]; st != nil && st.err != nil {
+			errs = append(errs, st.err.Error())
+		}
+	}
+	h := datasource.HealthStatus{State: datasource.Healthy}
+	switch {
+	case s.closed:
+		h.State, h.Error = datasource.Unhealthy, "feed: closed"
+	case !s.started:
+		h.State, h.Error = datasource.Unhealthy, "feed: not initialized"
+	case len(errs) == len(s.cfg.Feeds):
+		h.State, h.Error = datasource.Unhealthy, strings.Join(errs, "; ")
+	case len(errs) > 0:
+		h.State, h.Error = datasource.Degraded, strings.Join(errs, "; ")
+	}
+	return h
+}
+
+// Capabilities reports tag filtering, on item categories, and several
+// sites if more than one feed is configured.
+func (s *Source) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{
+		TagFiltering: true,
+		MultiSite:    len(s.cfg.Feeds) > 1,
+	}
+}
+
+func (s *Source) checkOpen() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return fmt.Errorf("feed: %w: source closed", datasource.ErrUnavailable)
+	}
+	return nil
+}
+
+// FetchTopics returns the items matching the question's keywords, best
+// match first and then most recent. A keyword found in an item's title
+// counts twice as much as one found only in its summary, and keywords of
+// four or more letters also match longer words they begin, so "deploy"
+// finds "deployment". Items in a category among the input's Tags rank
+// higher, and those in an excluded tag are left out. A topic's Score is
+// the share of the question matched, between 0 and 1.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	terms := keywords(input.QuestionText)
+	if count <= 0 || len(terms) == 0 {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	items, err := s.cfg.Store.Items()
+	if err != nil {
+		return nil, fmt.Errorf("feed: %w", err)
+	}
+
+	type match struct {
+		item  Item
+		score float64
+	}
+	var matches []match
+	for _, it := range items {
+		if !datasource.AcceptsLanguage(input.AcceptLanguages, it.Language) || hasAny(it.Categories, input.Filters.ExcludeTags) {
+			continue
+		}
+		if score := relevance(terms, input.Tags, it); score > 0 {
+			matches = append(matches, match{it, score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].item.date().After(matches[j].item.date())
+	})
+
+	topics := make([]datasource.DataSourceTopic, 0, len(matches))
+	for _, m := range matches {
+		topics = append(topics, topic(m.item, m.score))
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// keywords returns the distinct words of the question, lowercased, less
+// single letters.
+func keywords(question string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, w := range strings.Fields(similarity.Normalize(question)) {
+		if utf8.RuneCountInString(w) > 1 && !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// relevance scores how well the item matches the terms and tags.
+func relevance(terms, tags []string, it Item) float64 {
+	title := strings.Fields(similarity.Normalize(it.Title))
+	summary := strings.Fields(similarity.Normalize(content.StripHTML(it.Summary)))
+	score := 0.0
+	for _, t := range terms {
+		switch {
+		case contains(title, t):
+			score += 2
+		case contains(summary, t):
+			score++
+		}
+	}
+	if score == 0 {
+		return 0
+	}
+	matched := 0
+	for _, tag := range tags {
+		if hasAny(it.Categories, []string{tag}) {
+			matched++
+		}
+	}
+	return (score + float64(matched)) / float64(2*len(terms)+len(tags))
+}
+
+// contains reports whether words has term, or, for terms of four or more
+// letters, a word beginning with it.
+func contains(words []string, term string) bool {
+	prefix := utf8.RuneCountInString(term) >= 4
+	for _, w := range words {
+		if w == term || prefix && strings.HasPrefix(w, term) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAny(categories, tags []string) bool {
+	for _, c := range categories {
+		for _, t := range tags {
+			if strings.EqualFold(c, t) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// site returns the host of the feed's address.
+func site(feed string) string {
+	if u, err := url.Parse(feed); err == nil {
+		return u.Host
+	}
+	return feed
+}
+
+func topic(it Item, score float64) datasource.DataSourceTopic {
+	t := datasource.DataSourceTopic{
+		Topic:     it.Title,
+		SourceURL: it.Link,
+		Site:      site(it.Feed),
+		TopicID:   it.ID,
+		Score:     score,
+		Language:  it.Language,
+		CreatedAt: it.Published,
+		UpdatedAt: it.Updated,
+	}
+	summary := truncate(strings.TrimSpace(content.StripHTML(it.Summary)), maxSummaryChars)
+	t = t.Structured(it.Title, summary)
+	if t.Topic == "" {
+		t.Topic = summary
+	}
+	if it.FeedTitle != "" {
+		t.Path = []string{it.FeedTitle}
+		t.Metadata.Set(MetadataFeed, it.FeedTitle)
+	}
+	if summary != "" {
+		t.Metadata.Set(MetadataSummary, summary)
+	}
+	if len(it.Categories) > 0 {
+		t.Metadata.Set(MetadataCategories, it.Categories)
+	}
+	return t
+}
+
+// truncate shortens s to at most n characters, ending in an ellipsis.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
+
+// FetchData returns the item's content, or its summary if the feed gives
+// no content, as HTML.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if err := s.checkOpen(); err != nil {
+		return nil, err
+	}
+	it, ok, err := s.cfg.Store.Get(topicID)
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("feed: %w", err)
+	case !ok:
+		return nil, fmt.Errorf("feed: item %d: %w", topicID, datasource.ErrNotFound)
+	case count <= 0:
+		return []datasource.DataSourceData{}, nil
+	}
+	return []datasource.DataSourceData{data(it)}, nil
+}
+
+// data returns the item's only data item.
+func data(it Item) datasource.DataSourceData {
+	d := datasource.DataSourceData{
+		DataText:    first(it.Content, it.Summary, it.Title),
+		ContentType: datasource.ContentHTML,
+		SourceURL:   it.Link,
+		Site:        site(it.Feed),
+		AnswerID:    it.ID,
+		Rank:        1,
+		Language:    it.Language,
+		CreatedAt:   it.Published,
+		UpdatedAt:   it.Updated,
+	}
+	if it.Author != "" {
+		d.Author = &datasource.Author{Name: it.Author, ProfileURL: it.AuthorURL}
+	}
+	if it.FeedTitle != "" {
+		d.Metadata.Set(MetadataFeed, it.FeedTitle)
+	}
+	return d
+}
+
+// Changes implements datasource.SyncSource, returning as upserts the items
+// published or updated after the cursor, oldest first, with their data.
+// Cursors are item dates, so an item whose feed backdates it past the
+// cursor is not reported; items evicted from the store are not reported as
+// deletions.
+func (s *Source) Changes(ctx context.Context, since datasource.Cursor) (datasource.ChangeBatch, error) {
+	if err := s.checkOpen(); err != nil {
+		return datasource.ChangeBatch{}, err
+	}
+	var after time.Time
+	if since != "" {
+		var err error
+		if after, err = time.Parse(time.RFC3339Nano, string(since)); err != nil {
+			return datasource.ChangeBatch{}, fmt.Errorf("feed: cursor %q: %w", since, datasource.ErrCursorExpired)
+		}
+	}
+	items, err := s.cfg.Store.Items()
+	if err != nil {
+		return datasource.ChangeBatch{}, fmt.Errorf("feed: %w", err)
+	}
+	if err := ctx.Err(); err != nil {
+		return datasource.ChangeBatch{}, err
+	}
+
+	var changed []Item
+	for _, it := range items {
+		if it.date().After(after) {
+			changed = append(changed, it)
+		}
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].date().Before(changed[j].date()) })
+	batch := datasource.ChangeBatch{Changes: make([]datasource.Change, 0, len(changed)), Next: since}
+	for _, it := range changed {
+		batch.Changes = append(batch.Changes, datasource.Change{
+			Kind:      datasource.ChangeUpsert,
+			Topic:     topic(it, 0),
+			Data:      []datasource.DataSourceData{data(it)},
+			ChangedAt: it.date(),
+		})
+	}
+	if n := len(changed); n > 0 {
+		batch.Next = datasource.Cursor(changed[n-1].date().UTC().Format(time.RFC3339Nano))
+	}
+	return batch, nil
+}
@@ -0,0 +1,322 @@
+package feed_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/feed"
+)
+
+const rssDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:atom="http://www.w3.org/2005/Atom">
+<channel>
+  <title>Release notes</title>
+  <language>en-us</language>
+  <atom:link href="https://example.com/feed.xml" rel="self"/>
+  <item>
+    <title>Version 2.0 released</title>
+    <atom:link href="https://example.com/x" rel="related"/>
+    <link>https://example.com/v2</link>
+    <guid>v2</guid>
+    <description><![CDATA[<p>Faster deployments and a new CLI.</p>]]></description>
+    <content:encoded><![CDATA[<p>Full notes for 2.0.</p>]]></content:encoded>
+    <dc:creator>Ada</dc:creator>
+    <category>release</category>
+    <pubDate>Mon, 02 Mar 2026 10:00:00 +0000</pubDate>
+  </item>
+  <item>
+    <title>Maintenance window</title>
+    <link>https://example.com/maint</link>
+    <description>Deployment of the database upgrade &amp; reboot.</description>
+    <category>ops</category>
+    <pubDate>Tue, 3 Mar 2026 10:00:00 GMT</pubDate>
+  </item>
+</channel>
+</rss>`
+
+const atomDoc = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom" xml:lang="de">
+  <title type="html">Status &lt;b&gt;page&lt;/b&gt;</title>
+  <entry>
+    <id>tag:status,2026:1</id>
+    <title>Deployment delayed</title>
+    <link rel="alternate" href="https://status.example.org/1"/>
+    <updated>2026-03-04T08:00:00Z</updated>
+    <summary>Rollout paused.</summary>
+    <content type="xhtml"><div xmlns="http://www.w3.org/1999/xhtml"><p>Rollout <b>paused</b>.</p></div></content>
+    <author><name>Ops</name><uri>https://status.example.org/ops</uri></author>
+    <category term="incident" label="Incident"/>
+  </entry>
+</feed>`
+
+// server serves feeds by path, answering conditional requests for
+// unchanged documents with 304.
+type server struct {
+	mu      sync.Mutex
+	docs    map[string]string
+	version int
+	gets    map[string]int
+	fresh   map[string]int // 200 responses
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	doc, ok := s.docs[r.URL.Path]
+	s.gets[r.URL.Path]++
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	etag := fmt.Sprintf(`"%d"`, s.version)
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	s.fresh[r.URL.Path]++
+	w.Header().Set("ETag", etag)
+	w.Write([]byte(doc))
+}
+
+func (s *server) set(path, doc string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.docs[path] = doc
+	s.version++
+}
+
+func newSource(t *testing.T, cfg feed.Config, paths ...string) (*feed.Source, *server) {
+	t.Helper()
+	s := &server{
+		docs:  map[string]string{"/rss": rssDoc, "/atom": atomDoc},
+		gets:  make(map[string]int),
+		fresh: make(map[string]int),
+	}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	for _, p := range paths {
+		cfg.Feeds = append(cfg.Feeds, srv.URL+p)
+	}
+	if cfg.RefreshInterval == 0 {
+		cfg.RefreshInterval = -1
+	}
+	ds := feed.New(cfg)
+	t.Cleanup(func() { ds.Close(context.Background()) })
+	return ds, s
+}
+
+func titles(topics []datasource.DataSourceTopic) string {
+	var s []string
+	for _, t := range topics {
+		s = append(s, t.Topic)
+	}
+	return strings.Join(s, "|")
+}
+
+func TestFetchTopics(t *testing.T) {
+	ds, _ := newSource(t, feed.Config{}, "/rss", "/atom")
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	topics, err := ds.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "deployment status"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Title matches beat summary matches; ties go to the most recent.
+	if got, want := titles(topics), "Deployment delayed|Maintenance window|Version 2.0 released"; got != want {
+		t.Fatalf("topics = %s, want %s", got, want)
+	}
+	first := topics[0]
+	if first.Score != 0.5 || first.Rank != 1 || first.Language != "de" || first.SourceURL != "https://status.example.org/1" ||
+		!first.CreatedAt.Equal(time.Date(2026, 3, 4, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("first topic = %+v", first)
+	}
+	if f, _ := first.Metadata.String(feed.MetadataFeed); f != "Status page" {
+		t.Errorf("feed title = %q, want markup stripped", f)
+	}
+
+	topics, _ = ds.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "deployment", AcceptLanguages: []string{"en"}, Tags: []string{"ops"}})
+	if got, want := titles(topics), "Maintenance window|Version 2.0 released"; got != want {
+		t.Errorf("with language and tags: topics = %s, want %s", got, want)
+	}
+	topics, _ = ds.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "deployment", Filters: datasource.Filters{ExcludeTags: []string{"Incident", "ops"}}})
+	if got, want := titles(topics), "Version 2.0 released"; got != want {
+		t.Errorf("excluding tags: topics = %s, want %s", got, want)
+	}
+	if topics, _ := ds.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "a ?"}); len(topics) != 0 {
+		t.Errorf("topics for no keywords = %s", titles(topics))
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	ds, _ := newSource(t, feed.Config{}, "/rss", "/atom")
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	topics, _ := ds.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "version deployment rollout"})
+	byTitle := make(map[string]int64)
+	for _, tp := range topics {
+		byTitle[tp.Topic] = tp.TopicID
+	}
+
+	items, err := ds.FetchData(5, byTitle["Version 2.0 released"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := items[0]
+	if len(items) != 1 || d.DataText != "<p>Full notes for 2.0.</p>" || d.ContentType != datasource.ContentHTML ||
+		d.SourceURL != "https://example.com/v2" || d.Author == nil || d.Author.Name != "Ada" || d.Language != "en-us" {
+		t.Errorf("items = %+v", items)
+	}
+	items, _ = ds.FetchData(5, byTitle["Maintenance window"])
+	if items[0].DataText != "Deployment of the database upgrade & reboot." {
+		t.Errorf("summary item = %q", items[0].DataText)
+	}
+	items, _ = ds.FetchData(5, byTitle["Deployment delayed"])
+	if d := items[0]; !strings.Contains(d.DataText, "<b>paused</b>") || d.Author.ProfileURL != "https://status.example.org/ops" {
+		t.Errorf("atom item = %+v, want the xhtml content", d)
+	}
+
+	if _, err := ds.FetchData(5, 12345); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("unknown item: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ds, srv := newSource(t, feed.Config{}, "/rss")
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if srv.gets["/rss"] != 2 || srv.fresh["/rss"] != 1 {
+		t.Errorf("%d requests, %d full responses; want the unchanged feed not sent again", srv.gets["/rss"], srv.fresh["/rss"])
+	}
+
+	srv.set("/rss", strings.Replace(rssDoc, "<item>", `<item><title>Security advisory</title><guid>sa-1</guid></item><item>`, 1))
+	if err := ds.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	topics, _ := ds.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "advisory"})
+	if titles(topics) != "Security advisory" {
+		t.Errorf("topics = %s, want the new item", titles(topics))
+	}
+	// Items that drop out of the feed stay searchable.
+	srv.set("/rss", `<rss><channel><title>Empty</title></channel></rss>`)
+	if err := ds.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if topics, _ := ds.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "advisory"}); len(topics) != 1 {
+		t.Errorf("topics = %s, want items kept", titles(topics))
+	}
+}
+
+func TestChanges(t *testing.T) {
+	ds, srv := newSource(t, feed.Config{}, "/rss")
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	batch, err := ds.Changes(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, c := range batch.Changes {
+		got = append(got, c.Topic.Topic)
+	}
+	if strings.Join(got, ", ") != "Version 2.0 released, Maintenance window" || batch.More {
+		t.Errorf("changes %v, want every item oldest first", got)
+	}
+	if c := batch.Changes[0]; c.Kind != datasource.ChangeUpsert || len(c.Data) != 1 || c.Data[0].DataText != "<p>Full notes for 2.0.</p>" {
+		t.Errorf("change %+v, want an upsert with the item's content", c)
+	}
+	if batch.Next != "2026-03-03T10:00:00Z" {
+		t.Errorf("next cursor %q", batch.Next)
+	}
+
+	srv.set("/rss", strings.Replace(rssDoc, "<item>",
+		`<item><title>Security advisory</title><guid>sa-1</guid><pubDate>Wed, 4 Mar 2026 09:00:00 GMT</pubDate></item><item>`, 1))
+	if err := ds.Refresh(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	batch, err = ds.Changes(context.Background(), batch.Next)
+	if err != nil || len(batch.Changes) != 1 || batch.Changes[0].Topic.Topic != "Security advisory" {
+		t.Errorf("Changes = %+v, %v; want only the new item", batch, err)
+	}
+	if _, err := ds.Changes(context.Background(), "yesterday"); !errors.Is(err, datasource.ErrCursorExpired) {
+		t.Errorf("invalid cursor: err = %v, want ErrCursorExpired", err)
+	}
+}
+
+func TestRefreshInBackground(t *testing.T) {
+	ds, srv := newSource(t, feed.Config{RefreshInterval: 10 * time.Millisecond}, "/rss")
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		srv.mu.Lock()
+		n := srv.gets["/rss"]
+		srv.mu.Unlock()
+		if n >= 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d requests, want periodic refreshes", n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := ds.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "version"}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("after Close: err = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestInitAndHealth(t *testing.T) {
+	ds, _ := newSource(t, feed.Config{}, "/missing")
+	if err := ds.Init(); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("Init() = %v, want the feed's 404", err)
+	}
+	if err := feed.New(feed.Config{}).Init(); err == nil {
+		t.Error("Init() without feeds succeeded")
+	}
+	if err := feed.New(feed.Config{Feeds: []string{"file:///etc/passwd"}}).Init(); err == nil {
+		t.Error("Init() with a file address succeeded")
+	}
+
+	partial, _ := newSource(t, feed.Config{}, "/rss", "/missing")
+	if err := partial.Init(); err != nil {
+		t.Fatalf("Init() = %v, want success with one feed readable", err)
+	}
+	if h := partial.HealthCheck(); h.State != datasource.Degraded || !strings.Contains(h.Error, "/missing") {
+		t.Errorf("health = %+v, want degraded naming the failing feed", h)
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	s := feed.NewMemoryStore(2)
+	day := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	s.Put([]feed.Item{{ID: 1, Published: day}, {ID: 2, Published: day.AddDate(0, 0, 2)}})
+	s.Put([]feed.Item{{ID: 3, Published: day.AddDate(0, 0, 1)}, {ID: 2, Title: "updated", Published: day.AddDate(0, 0, 2)}})
+	if s.Len() != 2 {
+		t.Fatalf("Len() = %d, want 2", s.Len())
+	}
+	if _, ok, _ := s.Get(1); ok {
+		t.Error("oldest item kept past the maximum")
+	}
+	if it, ok, _ := s.Get(2); !ok || it.Title != "updated" {
+		t.Errorf("Get(2) = %+v, %v; want the replaced item", it, ok)
+	}
+}
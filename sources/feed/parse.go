@@ -0,0 +1,265 @@
+package feed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// parsed is a feed as read from its document.
+type parsed struct {
+	title    string
+	language string
+	items    []Item
+}
+
+// nsAtom is the namespace of Atom elements.
+const nsAtom = "http://www.w3.org/2005/Atom"
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	GUID        string   `xml:"guid"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	Categories  []string `xml:"category"`
+	Subjects    []string `xml:"http://purl.org/dc/elements/1.1/ subject"`
+	Links       []struct {
+		Body string `xml:",chardata"`
+	} `xml:"link"` // atom:link elements are empty
+}
+
+// rss is an RSS 2.0 document, or an RSS 1.0 (RDF) one, whose items are
+// siblings of the channel.
+type rss struct {
+	Channel struct {
+		Title    string    `xml:"title"`
+		Language string    `xml:"language"`
+		Items    []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+// text is an Atom text construct.
+type text struct {
+	Type  string `xml:"type,attr"`
+	Body  string `xml:",chardata"`
+	Inner string `xml:",innerxml"`
+}
+
+// html returns the construct as HTML.
+func (t text) html() string {
+	switch t.Type {
+	case "html":
+		return strings.TrimSpace(t.Body)
+	case "xhtml":
+		return strings.TrimSpace(t.Inner)
+	}
+	return html.EscapeString(strings.TrimSpace(t.Body))
+}
+
+type atomEntry struct {
+	ID        string `xml:"id"`
+	Title     text   `xml:"title"`
+	Summary   text   `xml:"summary"`
+	Content   text   `xml:"content"`
+	Published string `xml:"published"`
+	Updated   string `xml:"updated"`
+	Links     []struct {
+		Href string `xml:"href,attr"`
+		Rel  string `xml:"rel,attr"`
+	} `xml:"link"`
+	Authors []struct {
+		Name string `xml:"name"`
+		URI  string `xml:"uri"`
+	} `xml:"author"`
+	Categories []struct {
+		Term  string `xml:"term,attr"`
+		Label string `xml:"label,attr"`
+	} `xml:"category"`
+	Lang string `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+}
+
+type atom struct {
+	Title   text        `xml:"title"`
+	Lang    string      `xml:"http://www.w3.org/XML/1998/namespace lang,attr"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// parse reads an RSS 0.9x, 1.0, or 2.0, or Atom 1.0 document. Item IDs
+// are left to the caller.
+func parse(doc []byte) (parsed, error) {
+	d := newDecoder(doc)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("no root element")
+			}
+			return parsed{}, fmt.Errorf("parse feed: %w", err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case start.Name.Local == "rss" || start.Name.Local == "RDF":
+			var r rss
+			if err := d.DecodeElement(&r, &start); err != nil {
+				return parsed{}, fmt.Errorf("parse feed: %w", err)
+			}
+			return r.parsed(), nil
+		case start.Name.Local == "feed" && start.Name.Space == nsAtom:
+			var a atom
+			if err := d.DecodeElement(&a, &start); err != nil {
+				return parsed{}, fmt.Errorf("parse feed: %w", err)
+			}
+			return a.parsed(), nil
+		}
+		return parsed{}, fmt.Errorf("parse feed: unknown root element <%s>", start.Name.Local)
+	}
+}
+
+func (r rss) parsed() parsed {
+	p := parsed{title: strings.TrimSpace(r.Channel.Title), language: strings.TrimSpace(r.Channel.Language)}
+	for _, it := range append(r.Channel.Items, r.Items...) {
+		item := Item{
+			GUID:       strings.TrimSpace(it.GUID),
+			Title:      strings.TrimSpace(html.UnescapeString(it.Title)),
+			Summary:    strings.TrimSpace(it.Description),
+			Content:    strings.TrimSpace(it.Content),
+			Author:     strings.TrimSpace(first(it.Creator, it.Author)),
+			Categories: trimAll(append(it.Categories, it.Subjects...)),
+			Published:  parseTime(first(it.PubDate, it.Date)),
+		}
+		for _, l := range it.Links {
+			if item.Link == "" {
+				item.Link = strings.TrimSpace(l.Body)
+			}
+		}
+		p.items = append(p.items, item)
+	}
+	return p
+}
+
+func (a atom) parsed() parsed {
+	p := parsed{title: plain(a.Title), language: a.Lang}
+	for _, e := range a.Entries {
+		item := Item{
+			GUID:      strings.TrimSpace(e.ID),
+			Title:     plain(e.Title),
+			Summary:   e.Summary.html(),
+			Content:   e.Content.html(),
+			Published: parseTime(e.Published),
+			Updated:   parseTime(e.Updated),
+			Language:  e.Lang,
+		}
+		for _, l := range e.Links {
+			if l.Rel == "" || l.Rel == "alternate" {
+				item.Link = strings.TrimSpace(l.Href)
+				break
+			}
+		}
+		if len(e.Authors) > 0 {
+			item.Author, item.AuthorURL = strings.TrimSpace(e.Authors[0].Name), strings.TrimSpace(e.Authors[0].URI)
+		}
+		for _, c := range e.Categories {
+			item.Categories = append(item.Categories, first(c.Label, c.Term))
+		}
+		item.Categories = trimAll(item.Categories)
+		if item.Published.IsZero() {
+			item.Published = item.Updated
+		}
+		p.items = append(p.items, item)
+	}
+	return p
+}
+
+// plain returns a text construct as plain text, for titles.
+func plain(t text) string {
+	if t.Type == "html" || t.Type == "xhtml" {
+		return strings.TrimSpace(content.StripHTML(t.html()))
+	}
+	return strings.TrimSpace(t.Body)
+}
+
+// timeLayouts are the date formats found in feeds: RFC 822 and its common
+// variations in RSS, RFC 3339 in Atom and Dublin Core.
+var timeLayouts = []string{
+	time.RFC1123Z, time.RFC1123, time.RFC3339, time.RFC3339Nano,
+	"Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST",
+	"2 Jan 2006 15:04:05 -0700", "2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700", "Mon, 02 Jan 2006 15:04 MST",
+	time.RFC822Z, time.RFC822, "2006-01-02T15:04:05", "2006-01-02",
+}
+
+// parseTime parses a feed date, or returns the zero time.
+func parseTime(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func first(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func trimAll(values []string) []string {
+	var out []string
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// newDecoder returns a decoder for doc that accepts the HTML entities
+// feeds use without declaring them, and Latin-1 encoded documents.
+func newDecoder(doc []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(doc))
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	d.CharsetReader = func(charset string, r io.Reader) (io.Reader, error) {
+		switch strings.ToLower(charset) {
+		case "iso-8859-1", "latin1", "latin-1", "windows-1252", "us-ascii":
+			return latin1Reader(r)
+		}
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	}
+	return d
+}
+
+// latin1Reader decodes Latin-1 into UTF-8. Windows-1252 is read as
+// Latin-1, which differs only in punctuation.
+func latin1Reader(r io.Reader) (io.Reader, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		out = utf8.AppendRune(out, rune(c))
+	}
+	return bytes.NewReader(out), nil
+}
@@ -0,0 +1,117 @@
+package feed
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Item is an entry of a feed, as indexed by a Source.
+type Item struct {
+	// ID identifies the item across refreshes; it is derived from the
+	// feed's address and the item's GUID, or link if it has none, and is
+	// the item's topic ID
+	ID int64 `json:"id"`
+
+	// Feed is the address of the feed the item came from
+	Feed string `json:"feed"`
+
+	// FeedTitle is the title of the feed
+	FeedTitle string `json:"feed_title,omitempty"`
+
+	GUID       string    `json:"guid,omitempty"`
+	Title      string    `json:"title"`
+	Link       string    `json:"link,omitempty"`
+	Summary    string    `json:"summary,omitempty"` // HTML
+	Content    string    `json:"content,omitempty"` // HTML
+	Author     string    `json:"author,omitempty"`
+	AuthorURL  string    `json:"author_url,omitempty"`
+	Categories []string  `json:"categories,omitempty"`
+	Language   string    `json:"language,omitempty"`
+	Published  time.Time `json:"published,omitempty"`
+	Updated    time.Time `json:"updated,omitempty"`
+}
+
+// date returns when the item was last published or updated.
+func (it Item) date() time.Time {
+	if it.Updated.After(it.Published) {
+		return it.Updated
+	}
+	return it.Published
+}
+
+// Store holds the items a Source indexes. Put is called after each refresh
+// of a feed, with the items currently in it; items that dropped out of the
+// feed may be kept. Implementations must be safe for concurrent use.
+type Store interface {
+	// Put adds items, replacing those with the same ID
+	Put(items []Item) error
+
+	// Get returns the item with the ID, and whether it exists
+	Get(id int64) (Item, bool, error)
+
+	// Items returns every item, in any order
+	Items() ([]Item, error)
+}
+
+// MemoryStore is a Store keeping items in memory, up to a maximum; past
+// it, the least recently published items are evicted.
+type MemoryStore struct {
+	max int
+
+	mu    sync.RWMutex
+	items map[int64]Item
+}
+
+// NewMemoryStore returns a MemoryStore holding at most maxItems items, or
+// any number if maxItems is zero or less.
+func NewMemoryStore(maxItems int) *MemoryStore {
+	return &MemoryStore{max: maxItems, items: make(map[int64]Item)}
+}
+
+// Put adds items, evicting the oldest items past the maximum.
+func (s *MemoryStore) Put(items []Item) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, it := range items {
+		s.items[it.ID] = it
+	}
+	if s.max <= 0 || len(s.items) <= s.max {
+		return nil
+	}
+	all := make([]Item, 0, len(s.items))
+	for _, it := range s.items {
+		all = append(all, it)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].date().Before(all[j].date()) })
+	for _, it := range all[:len(all)-s.max] {
+		delete(s.items, it.ID)
+	}
+	return nil
+}
+
+// Get returns the item with the ID.
+func (s *MemoryStore) Get(id int64) (Item, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	it, ok := s.items[id]
+	return it, ok, nil
+}
+
+// Items returns every item.
+func (s *MemoryStore) Items() ([]Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	all := make([]Item, 0, len(s.items))
+	for _, it := range s.items {
+		all = append(all, it)
+	}
+	return all, nil
+}
+
+// Len returns the number of items held.
+func (s *MemoryStore) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.items)
+}
@@ -0,0 +1,475 @@
+// Package fsdocs is a data source answering from a directory of Markdown
+// and text files, such as the docs folder of a repository. Init reads the
+// files into a local search index; topics are the files ranked by BM25
+// against the question, and a topic's data items are the file's sections,
+// split at level 2 and 3 headings, each deep-linking to its heading:
+//
+//	ds := fsdocs.New(fsdocs.Config{
+//	    Dir:     "./docs",
+//	    BaseURL: "https://github.com/example/project/blob/main/docs/",
+//	})
+//	if err := ds.Init(); err != nil { ... }
+//	defer ds.Close(context.Background())
+//
+// A document's title is its front matter title, its first level 1
+// heading, or its file name. The directory is checked for changes every
+// Config.PollInterval, and files added, changed, or removed since are
+// indexed again; questions are answered from the previous index until
+// then.
+package fsdocs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultPollInterval = 5 * time.Second
+	DefaultMaxFileBytes = 5 << 20
+)
+
+// DefaultExtensions are the extensions of the files indexed by default.
+var DefaultExtensions = []string{".md", ".markdown", ".txt"}
+
+// Metadata keys set on topics and data items.
+const (
+	MetadataPath        = "path"        // the file's path, relative to the directory
+	MetadataDescription = "description" // the front matter description, if any
+	MetadataSection     = "section"     // the heading of the section, unset for the lead
+)
+
+// Config configures a Source.
+type Config struct {
+	// Dir is the directory of the documents
+	Dir string
+
+	// FS is the file system the documents are read from, such as an
+	// embed.FS
+	// Defaults to os.DirFS(Dir)
+	FS fs.FS
+
+	// BaseURL is the address documents' paths are resolved against, such
+	// as the address of the directory in a repository browser
+	// Defaults to file URLs under Dir, or bare paths if only FS is set
+	BaseURL string
+
+	// Extensions lists the extensions of the files indexed; files ending
+	// in ".txt" are read as plain text, others as Markdown
+	// Defaults to DefaultExtensions
+	Extensions []string
+
+	// Exclude lists path.Match patterns of files and directories left out,
+	// matched against both their path and their name. Names starting with
+	// a dot are always left out
+	// Optional
+	Exclude []string
+
+	// PollInterval is how often the directory is checked for changes; a
+	// negative interval checks only in Init and Reload
+	// Defaults to DefaultPollInterval
+	PollInterval time.Duration
+
+	// MaxFileBytes bounds the files read; larger files are skipped
+	// Defaults to DefaultMaxFileBytes
+	MaxFileBytes int64
+
+	// Site is reported as the Site of every topic and data item
+	// Optional
+	Site string
+}
+
+func (c Config) withDefaults() Config {
+	if c.FS == nil && c.Dir != "" {
+		c.FS = os.DirFS(c.Dir)
+	}
+	if len(c.Extensions) == 0 {
+		c.Extensions = DefaultExtensions
+	}
+	if c.PollInterval == 0 {
+		c.PollInterval = DefaultPollInterval
+	}
+	if c.MaxFileBytes <= 0 {
+		c.MaxFileBytes = DefaultMaxFileBytes
+	}
+	return c
+}
+
+// Source is a DataSource searching a directory of documents. Topic IDs
+// are derived from file paths, so they are stable across changes. A
+// Source is safe for concurrent use.
+type Source struct {
+	cfg  Config
+	base *url.URL // nil for bare paths
+
+	scanning sync.Mutex // held by Reload
+
+	mu      sync.RWMutex
+	index   *index
+	err     error // of the last scan
+	started bool
+	closed  bool
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+var (
+	_ datasource.DataSource    = (*Source)(nil)
+	_ datasource.HealthChecker = (*Source)(nil)
+	_ datasource.Closer        = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	s := &Source{cfg: cfg.withDefaults()}
+	switch {
+	case s.cfg.BaseURL != "":
+		s.base, _ = url.Parse(s.cfg.BaseURL)
+	case cfg.Dir != "":
+		if abs, err := filepath.Abs(cfg.Dir); err == nil {
+			s.base = &url.URL{Scheme: "file", Path: filepath.ToSlash(abs) + "/"}
+		}
+	}
+	return s
+}
+
+// Init indexes the directory, failing if it cannot be read, and starts
+// watching it for changes every PollInterval until Close.
+func (s *Source) Init() error {
+	if s.cfg.FS == nil {
+		return errors.New("fsdocs: no directory configured")
+	}
+	if s.cfg.BaseURL != "" && s.base == nil {
+		return fmt.Errorf("fsdocs: invalid base URL %q", s.cfg.BaseURL)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	if s.started || s.closed {
+		s.mu.Unlock()
+		cancel()
+		return nil
+	}
+	s.started, s.cancel = true, cancel
+	s.mu.Unlock()
+
+	if err := s.Reload(); err != nil && s.loaded() == nil {
+		s.mu.Lock()
+		s.started = false
+		s.mu.Unlock()
+		cancel()
+		return err
+	}
+	if s.cfg.PollInterval > 0 {
+		s.wg.Add(1)
+		go s.watch(ctx)
+	}
+	return nil
+}
+
+// watch reloads the directory every PollInterval until ctx is done.
+func (s *Source) watch(ctx context.Context) {
+	defer s.wg.Done()
+	t := time.NewTicker(s.cfg.PollInterval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			s.Reload()
+		}
+	}
+}
+
+// Close stops watching the directory, waiting for a reload in progress to
+// finish or ctx to be done.
+func (s *Source) Close(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Reload indexes the files added or changed since the last scan, and
+// drops those removed. Unchanged files are not read again. Files that
+// cannot be read are left out and their errors returned joined; if the
+// directory itself cannot be read, the index is kept.
+func (s *Source) Reload() error {
+	s.scanning.Lock()
+	defer s.scanning.Unlock()
+
+	prev := s.loaded()
+	var docs []*document
+	var errs []error
+	changed := prev == nil
+	err := fs.WalkDir(s.cfg.FS, ".", func(p string, e fs.DirEntry, err error) error {
+		if err != nil {
+			if p == "." {
+				return err
+			}
+			errs = append(errs, err)
+			return nil
+		}
+		if p != "." && s.excluded(p, e.Name()) {
+			if e.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if e.IsDir() || !s.indexable(e.Name()) {
+			return nil
+		}
+		info, err := e.Info()
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		if info.Size() > s.cfg.MaxFileBytes {
+			return nil
+		}
+		if prev != nil {
+			if d := prev.docs[docID(p)]; d != nil && d.path == p && d.modified.Equal(info.ModTime()) && d.size == info.Size() {
+				docs = append(docs, d)
+				return nil
+			}
+		}
+		b, err := fs.ReadFile(s.cfg.FS, p)
+		if err != nil {
+			errs = append(errs, err)
+			return nil
+		}
+		parse := parseMarkdown
+		if strings.EqualFold(path.Ext(p), ".txt") {
+			parse = parseText
+		}
+		docs = append(docs, newDocument(p, parse(string(b)), info.ModTime(), info.Size()))
+		changed = true
+		return nil
+	})
+	if err != nil {
+		err = fmt.Errorf("fsdocs: %w", err)
+		s.mu.Lock()
+		s.err = err
+		s.mu.Unlock()
+		return err
+	}
+	err = errors.Join(errs...)
+	if err != nil {
+		err = fmt.Errorf("fsdocs: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if changed || len(docs) != len(prev.docs) {
+		s.index = newIndex(docs)
+	}
+	s.err = err
+	return err
+}
+
+// excluded reports whether the file or directory at p is left out.
+func (s *Source) excluded(p, name string) bool {
+	if strings.HasPrefix(name, ".") {
+		return true
+	}
+	for _, pattern := range s.cfg.Exclude {
+		if ok, _ := path.Match(pattern, p); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// indexable reports whether a file with the name has an indexed
+// extension.
+func (s *Source) indexable(name string) bool {
+	ext := path.Ext(name)
+	for _, e := range s.cfg.Extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
+
+// loaded returns the current index, or nil before the first scan.
+func (s *Source) loaded() *index {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.index
+}
+
+func (s *Source) current() (*index, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	switch {
+	case s.closed:
+		return nil, fmt.Errorf("fsdocs: %w: source closed", datasource.ErrUnavailable)
+	case s.index == nil:
+		return nil, fmt.Errorf("fsdocs: %w: not loaded yet", datasource.ErrUnavailable)
+	}
+	return s.index, nil
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck reports the source unhealthy until the directory is
+// indexed, and degraded if the last scan failed, while questions are
+// answered from the previous index.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h := datasource.HealthStatus{State: datasource.Healthy}
+	switch {
+	case s.index == nil && s.err != nil:
+		h.State, h.Error = datasource.Unhealthy, s.err.Error()
+	case s.index == nil:
+		h.State, h.Error = datasource.Unhealthy, "fsdocs: not loaded yet"
+	case s.err != nil:
+		h.State, h.Error = datasource.Degraded, s.err.Error()
+	}
+	return h
+}
+
+// FetchTopics returns the documents matching the question, best match
+// first. A document's title counts more than its text. A topic's Score is
+// its BM25 score relative to the best match's, between 0 and 1.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	ix, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	query := terms(input.QuestionText)
+	if count <= 0 || len(query) == 0 {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	hits := ix.search(query)
+	topics := make([]datasource.DataSourceTopic, 0, len(hits))
+	for _, h := range hits {
+		topics = append(topics, s.topic(h.doc, h.score/hits[0].score))
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+func (s *Source) topic(d *document, score float64) datasource.DataSourceTopic {
+	t := datasource.DataSourceTopic{
+		Topic:     d.title,
+		SourceURL: s.address(d.path),
+		Site:      s.cfg.Site,
+		TopicID:   d.id,
+		Score:     score,
+		UpdatedAt: d.modified,
+	}
+	t = t.Structured(d.title, d.description, breadcrumb(d.path)...)
+	t.Metadata.Set(MetadataPath, d.path)
+	if d.description != "" {
+		t.Metadata.Set(MetadataDescription, d.description)
+	}
+	return t
+}
+
+// FetchData returns up to count sections of the document, lead first.
+// AnswerIDs number the sections from 0.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	ix, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := ix.docs[topicID]
+	if !ok {
+		return nil, fmt.Errorf("fsdocs: document %d: %w", topicID, datasource.ErrNotFound)
+	}
+	ct := datasource.ContentMarkdown
+	if strings.EqualFold(path.Ext(d.path), ".txt") {
+		ct = datasource.ContentPlainText
+	}
+	items := make([]datasource.DataSourceData, 0, min(max(count, 0), len(d.sections)))
+	for i, sec := range d.sections {
+		if len(items) >= count {
+			break
+		}
+		item := datasource.DataSourceData{
+			DataText:    sec.text,
+			ContentType: ct,
+			SourceURL:   s.address(d.path),
+			Site:        s.cfg.Site,
+			AnswerID:    int64(i),
+			Rank:        i + 1,
+			UpdatedAt:   d.modified,
+		}
+		item.Metadata.Set(MetadataPath, d.path)
+		if sec.heading != "" {
+			item.Metadata.Set(MetadataSection, sec.heading)
+		}
+		if sec.anchor != "" {
+			item = item.Anchored(sec.anchor)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// address returns the address of the file at p.
+func (s *Source) address(p string) string {
+	if s.base == nil {
+		return p
+	}
+	return s.base.ResolveReference(&url.URL{Path: p}).String()
+}
+
+// breadcrumb returns the directories of the file at p, outermost first.
+func breadcrumb(p string) []string {
+	dir := path.Dir(p)
+	if dir == "." {
+		return nil
+	}
+	return strings.Split(dir, "/")
+}
+
+// docID returns the topic ID of the file at p.
+func docID(p string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(p))
+	return int64(h.Sum64()>>1) | 1
+}
+
+// titleFromPath returns a title for a file without one: its name without
+// the extension, with dashes and underscores as spaces.
+func titleFromPath(p string) string {
+	name := strings.TrimSuffix(path.Base(p), path.Ext(p))
+	return strings.NewReplacer("-", " ", "_", " ").Replace(name)
+}
@@ -0,0 +1,191 @@
+package fsdocs_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/fsdocs"
+)
+
+var files = map[string]string{
+	"install.md": `---
+title: "Installing Acme"
+description: How to install Acme.
+---
+# Ignored heading
+
+Download the binary for your platform.
+
+## Linux
+
+Use the package manager to install Acme on Linux.
+
+` + "```sh\n## not a heading\napt install acme\n```" + `
+
+## Windows ##
+
+Run the installer.
+
+## Linux
+
+Older distributions.
+`,
+	"guide/deploy-guide.md": `Deploy Acme with acme deploy. Deployments roll out gradually.
+
+### Canary
+
+Canary deploys.
+`,
+	"notes.txt":          "Release notes: install fixes.\n",
+	"drafts/install.md":  "# Draft install\n",
+	".hidden/install.md": "# Hidden install\n",
+	"image.png":          "install",
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, body := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func newSource(t *testing.T, cfg fsdocs.Config) *fsdocs.Source {
+	t.Helper()
+	ds := fsdocs.New(cfg)
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ds.Close(context.Background()) })
+	return ds
+}
+
+func TestFetchTopicsAndData(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, files)
+	ds := newSource(t, fsdocs.Config{
+		Dir:          dir,
+		BaseURL:      "https://git.example.com/acme/blob/main/docs/",
+		Exclude:      []string{"drafts"},
+		PollInterval: -1,
+	})
+
+	topics, err := ds.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "install"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 {
+		t.Fatalf("got %d topics, want install.md and notes.txt: %+v", len(topics), topics)
+	}
+	install := topics[0]
+	if install.Topic != "Installing Acme" || install.SourceURL != "https://git.example.com/acme/blob/main/docs/install.md" ||
+		install.Score != 1 || install.Rank != 1 {
+		t.Errorf("first topic = %+v", install)
+	}
+	if install.Title != "Installing Acme" || install.BodyExcerpt != "How to install Acme." || install.Path != nil {
+		t.Errorf("structured fields = %q, %q, %q", install.Title, install.BodyExcerpt, install.Path)
+	}
+	if v, _ := install.Metadata.String(fsdocs.MetadataDescription); v != "How to install Acme." {
+		t.Errorf("description = %q", v)
+	}
+
+	data, err := ds.FetchData(10, install.TopicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct{ url, section string }{
+		{"https://git.example.com/acme/blob/main/docs/install.md", ""},
+		{"https://git.example.com/acme/blob/main/docs/install.md#linux", "Linux"},
+		{"https://git.example.com/acme/blob/main/docs/install.md#windows", "Windows"},
+		{"https://git.example.com/acme/blob/main/docs/install.md#linux-1", "Linux"},
+	}
+	if len(data) != len(want) {
+		t.Fatalf("got %d sections, want %d: %+v", len(data), len(want), data)
+	}
+	for i, w := range want {
+		section, _ := data[i].Metadata.String(fsdocs.MetadataSection)
+		if data[i].SourceURL != w.url || section != w.section || data[i].AnswerID != int64(i) {
+			t.Errorf("section %d = %s %q, want %s %q", i, data[i].SourceURL, section, w.url, w.section)
+		}
+	}
+	if data[1].DataText != "Use the package manager to install Acme on Linux.\n\n```sh\n## not a heading\napt install acme\n```" ||
+		data[1].ContentType != datasource.ContentMarkdown {
+		t.Errorf("Linux section = %q", data[1].DataText)
+	}
+
+	topics, _ = ds.FetchTopics(10, datasource.NewQuestionInput{QuestionText: "canary deploy"})
+	if len(topics) != 1 || topics[0].Topic != "deploy guide" || len(topics[0].Path) != 1 || topics[0].Path[0] != "guide" {
+		t.Errorf("deploy topics = %+v", topics)
+	}
+	if _, err := ds.FetchData(1, 42); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData(unknown) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestReload(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "# Alpha\n\nFirst.\n", "b.md": "# Beta\n\nSecond.\n"})
+	ds := newSource(t, fsdocs.Config{Dir: dir, PollInterval: -1})
+
+	writeFiles(t, dir, map[string]string{"a.md": "# Alpha\n\nRewritten.\n", "c.md": "# Gamma\n\nThird.\n"})
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "a.md"), later, later)
+	os.Remove(filepath.Join(dir, "b.md"))
+	if err := ds.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	for q, want := range map[string]int{"rewritten": 1, "first": 0, "second": 0, "third": 1} {
+		topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: q})
+		if err != nil || len(topics) != want {
+			t.Errorf("FetchTopics(%q) = %d topics, %v; want %d", q, len(topics), err, want)
+		}
+	}
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"a.md": "# Alpha\n"})
+	ds := newSource(t, fsdocs.Config{Dir: dir, PollInterval: 10 * time.Millisecond})
+
+	writeFiles(t, dir, map[string]string{"b.md": "# Beta\n\nWatched.\n"})
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "watched"})
+		if err == nil && len(topics) == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("new file not indexed: %v, %v", topics, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ds.Close(context.Background())
+	if _, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "watched"}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchTopics after Close = %v, want ErrUnavailable", err)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	if err := fsdocs.New(fsdocs.Config{}).Init(); err == nil {
+		t.Error("Init without a directory succeeded")
+	}
+	ds := fsdocs.New(fsdocs.Config{Dir: filepath.Join(t.TempDir(), "missing")})
+	if err := ds.Init(); err == nil {
+		t.Error("Init with a missing directory succeeded")
+	}
+	if h := ds.HealthCheck(); h.State != datasource.Unhealthy {
+		t.Errorf("health = %+v, want unhealthy", h)
+	}
+}
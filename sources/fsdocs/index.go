@@ -0,0 +1,167 @@
+package fsdocs
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/locus-search/datasource-sdk/similarity"
+)
+
+// BM25 parameters: term frequency saturation and length normalization.
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// titleWeight is how many times a word of a document's title counts.
+const titleWeight = 3
+
+// document is an indexed file.
+type document struct {
+	id          int64
+	path        string // slash-separated, relative to the root
+	title       string
+	description string
+	modified    time.Time
+	size        int64
+	sections    []section
+
+	terms  map[string]int // term frequencies, title words weighted
+	length int
+}
+
+// newDocument indexes the file at path.
+func newDocument(path string, p parsed, modified time.Time, size int64) *document {
+	d := &document{
+		id:          docID(path),
+		path:        path,
+		title:       p.title,
+		description: p.description,
+		modified:    modified,
+		size:        size,
+		sections:    p.sections,
+		terms:       make(map[string]int),
+	}
+	if d.title == "" {
+		d.title = titleFromPath(path)
+	}
+	for i := 0; i < titleWeight; i++ {
+		d.add(terms(d.title))
+	}
+	for _, s := range d.sections {
+		d.add(terms(s.heading))
+		d.add(terms(s.text))
+	}
+	return d
+}
+
+func (d *document) add(words []string) {
+	for _, w := range words {
+		d.terms[w]++
+	}
+	d.length += len(words)
+}
+
+// terms returns the words of s, lowercased, less single letters.
+func terms(s string) []string {
+	words := strings.Fields(similarity.Normalize(s))
+	out := words[:0]
+	for _, w := range words {
+		if utf8.RuneCountInString(w) > 1 {
+			out = append(out, w)
+		}
+	}
+	return out
+}
+
+// index is an inverted index of documents. It is built by a scan and not
+// modified after, so it can be searched concurrently.
+type index struct {
+	docs      map[int64]*document
+	postings  map[string]map[*document]int // term frequencies by term
+	avgLength float64
+}
+
+func newIndex(docs []*document) *index {
+	ix := &index{docs: make(map[int64]*document, len(docs)), postings: make(map[string]map[*document]int)}
+	total := 0
+	for _, d := range docs {
+		ix.docs[d.id] = d
+		total += d.length
+		for t, n := range d.terms {
+			if ix.postings[t] == nil {
+				ix.postings[t] = make(map[*document]int)
+			}
+			ix.postings[t][d] = n
+		}
+	}
+	if len(docs) > 0 {
+		ix.avgLength = float64(total) / float64(len(docs))
+	}
+	return ix
+}
+
+// hit is a document matching a search, with its BM25 score.
+type hit struct {
+	doc   *document
+	score float64
+}
+
+// search returns the documents having any of the terms, best BM25 score
+// first. Terms of four or more letters also match longer words they
+// begin, so "config" finds "configuration".
+func (ix *index) search(query []string) []hit {
+	n := float64(len(ix.docs))
+	scores := make(map[*document]float64)
+	seen := make(map[string]bool)
+	for _, t := range query {
+		if seen[t] {
+			continue
+		}
+		seen[t] = true
+		tfs := make(map[*document]float64)
+		for _, w := range ix.expand(t) {
+			for d, tf := range ix.postings[w] {
+				tfs[d] += float64(tf)
+			}
+		}
+		df := float64(len(tfs))
+		idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+		for d, tf := range tfs {
+			norm := 1 - bm25B + bm25B*float64(d.length)/ix.avgLength
+			scores[d] += idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		}
+	}
+	hits := make([]hit, 0, len(scores))
+	for d, s := range scores {
+		hits = append(hits, hit{d, s})
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].doc.path < hits[j].doc.path
+	})
+	return hits
+}
+
+// expand returns the indexed words a query term matches: itself, and for
+// terms of four or more letters, the words it begins.
+func (ix *index) expand(term string) []string {
+	var words []string
+	if ix.postings[term] != nil {
+		words = append(words, term)
+	}
+	if utf8.RuneCountInString(term) < 4 {
+		return words
+	}
+	for w := range ix.postings {
+		if w != term && strings.HasPrefix(w, term) {
+			words = append(words, w)
+		}
+	}
+	return words
+}
@@ -0,0 +1,140 @@
+package fsdocs
+
+import (
+	"strconv"
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// section is a part of a document: the lead before the first heading, or
+// a level 2 or 3 heading and the text up to the next.
+type section struct {
+	heading string // empty for the lead
+	anchor  datasource.Anchor
+	text    string
+}
+
+// parsed is the title and sections of a file.
+type parsed struct {
+	title       string
+	description string
+	sections    []section
+}
+
+// parseMarkdown splits a Markdown document into sections at its level 2
+// and 3 ATX headings, outside fenced code blocks. The title is the front
+// matter's title, or the first level 1 heading.
+func parseMarkdown(src string) parsed {
+	var p parsed
+	src = p.frontMatter(strings.ReplaceAll(src, "\r\n", "\n"))
+
+	var (
+		cur   section
+		lines []string
+		fence string // the open code fence, if any
+		seen  = make(map[datasource.Anchor]int)
+	)
+	flush := func() {
+		cur.text = strings.TrimSpace(strings.Join(lines, "\n"))
+		if cur.text != "" || cur.heading != "" {
+			p.sections = append(p.sections, cur)
+		}
+		lines = nil
+	}
+	for _, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			lines = append(lines, line)
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			lines = append(lines, line)
+			continue
+		}
+		level, heading := atxHeading(line)
+		switch {
+		case level == 1 && p.title == "":
+			p.title = heading
+		case level == 2 || level == 3:
+			flush()
+			cur = section{heading: heading, anchor: unique(seen, datasource.HeadingAnchor(heading))}
+		default:
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	return p
+}
+
+// frontMatter reads the title and description of a YAML front matter
+// block and returns the document after it.
+func (p *parsed) frontMatter(src string) string {
+	if !strings.HasPrefix(src, "---\n") {
+		return src
+	}
+	block, rest, ok := strings.Cut(src[4:], "\n---")
+	if !ok {
+		return src
+	}
+	for _, line := range strings.Split(block, "\n") {
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(key) {
+		case "title":
+			p.title = value
+		case "description":
+			p.description = value
+		}
+	}
+	_, rest, _ = strings.Cut(rest, "\n")
+	return rest
+}
+
+// atxHeading returns the level and text of a line that is an ATX heading,
+// such as "## Install ##", or 0.
+func atxHeading(line string) (int, string) {
+	if strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t") {
+		return 0, "" // indented code
+	}
+	line = strings.TrimSpace(line)
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 || level < len(line) && line[level] != ' ' && line[level] != '\t' {
+		return 0, ""
+	}
+	text := strings.TrimSpace(line[level:])
+	if t := strings.TrimRight(text, "#"); t == "" || strings.HasSuffix(t, " ") {
+		text = strings.TrimSpace(t)
+	}
+	return level, text
+}
+
+// unique returns the anchor, suffixed as GitHub does if it is already
+// taken: "setup", then "setup-1", "setup-2", and so on.
+func unique(seen map[datasource.Anchor]int, a datasource.Anchor) datasource.Anchor {
+	n := seen[a]
+	seen[a]++
+	if n == 0 {
+		return a
+	}
+	return a + datasource.Anchor("-"+strconv.Itoa(n))
+}
+
+// parseText returns a plain text file as one section.
+func parseText(src string) parsed {
+	var p parsed
+	if text := strings.TrimSpace(src); text != "" {
+		p.sections = []section{{text: text}}
+	}
+	return p
+}
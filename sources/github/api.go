@@ -0,0 +1,247 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// maxResponseBytes bounds response bodies read from the API.
+const maxResponseBytes = 16 << 20
+
+// now is replaced in tests.
+var now = time.Now
+
+// graphqlError is an error of a GraphQL response.
+type graphqlError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// pageInfo is the position of a page of a connection.
+type pageInfo struct {
+	HasNextPage bool   `json:"hasNextPage"`
+	EndCursor   string `json:"endCursor"`
+}
+
+// thread is an issue or discussion as the search returns it.
+type thread struct {
+	Typename   string    `json:"__typename"`
+	Number     int64     `json:"number"`
+	Title      string    `json:"title"`
+	URL        string    `json:"url"`
+	BodyText   string    `json:"bodyText"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	State      string    `json:"state"`  // issues
+	Closed     bool      `json:"closed"` // discussions
+	Repository struct {
+		NameWithOwner string `json:"nameWithOwner"`
+	} `json:"repository"`
+	Labels struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	} `json:"labels"`
+	Category *struct {
+		Name string `json:"name"`
+	} `json:"category"`
+	Comments  count   `json:"comments"`
+	Reactions count   `json:"reactions"`
+	Answer    *answer `json:"answer"`
+}
+
+type count struct {
+	TotalCount int `json:"totalCount"`
+}
+
+type answer struct {
+	DatabaseID int64 `json:"databaseId"`
+}
+
+// comment is a comment of an issue, or a top-level comment of a
+// discussion.
+type comment struct {
+	DatabaseID int64     `json:"databaseId"`
+	URL        string    `json:"url"`
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"createdAt"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+	Author     *struct {
+		Login string `json:"login"`
+		URL   string `json:"url"`
+	} `json:"author"`
+	Reactions   count `json:"reactions"`
+	IsMinimized bool  `json:"isMinimized"`
+}
+
+// threadFields are the fields of a thread the source reads.
+const threadFields = `number title url bodyText createdAt updatedAt
+repository { nameWithOwner } labels(first: 20) { nodes { name } }
+comments { totalCount } reactions { totalCount }`
+
+const searchQuery = `query($q: String!, $type: SearchType!, $first: Int!, $after: String) {
+  search(query: $q, type: $type, first: $first, after: $after) {
+    pageInfo { hasNextPage endCursor }
+    nodes {
+      __typename
+      ... on Issue { ` + threadFields + ` state }
+      ... on Discussion { ` + threadFields + ` closed category { name } answer { databaseId } }
+    }
+  }
+}`
+
+// commentsQuery returns the query of a page of the comments of an issue
+// or discussion, as named by field.
+func commentsQuery(field string) string {
+	extra := ""
+	if field == "discussion" {
+		extra = "answer { databaseId }"
+	}
+	return `query($owner: String!, $name: String!, $number: Int!, $after: String) {
+  repository(owner: $owner, name: $name) {
+    thread: ` + field + `(number: $number) {
+      ` + extra + `
+      comments(first: 100, after: $after) {
+        pageInfo { hasNextPage endCursor }
+        nodes { databaseId url body createdAt updatedAt author { login url } reactions { totalCount } isMinimized }
+      }
+    }
+  }
+}`
+}
+
+const rateLimitQuery = `query { rateLimit { limit remaining resetAt } }`
+
+const categoriesQuery = `query($owner: String!, $name: String!) {
+  repository(owner: $owner, name: $name) { id discussionCategories(first: 100) { nodes { id name } } }
+}`
+
+const createDiscussionMutation = `mutation($repo: ID!, $category: ID!, $title: String!, $body: String!) {
+  createDiscussion(input: {repositoryId: $repo, categoryId: $category, title: $title, body: $body}) {
+    discussion { __typename ` + threadFields + ` closed category { name } answer { databaseId } }
+  }
+}`
+
+// query sends a GraphQL query with its variables and decodes the data of
+// the response into into.
+func (s *Source) query(ctx context.Context, query string, vars map[string]any, into any) error {
+	if err := s.checkRateLimit(); err != nil {
+		return err
+	}
+	body, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return fmt.Errorf("github: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("github: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Authorization", "Bearer "+s.cfg.Token)
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("github: %w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	s.record(resp.Header)
+
+	if resp.StatusCode >= 300 {
+		return s.statusError(resp)
+	}
+	var r struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []graphqlError  `json:"errors"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&r); err != nil {
+		return fmt.Errorf("github: decode response: %w", err)
+	}
+	if len(r.Errors) > 0 {
+		return s.queryError(r.Errors[0])
+	}
+	if err := json.Unmarshal(r.Data, into); err != nil {
+		return fmt.Errorf("github: decode response: %w", err)
+	}
+	return nil
+}
+
+// checkRateLimit fails with ErrRateLimited while the rate limit is
+// exhausted, rather than spending a request the API would refuse.
+func (s *Source) checkRateLimit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if wait := s.reset.Sub(now()); s.limit > 0 && s.remaining == 0 && wait > 0 {
+		return &datasource.ErrRateLimited{RetryAfter: wait, Err: fmt.Errorf("github: rate limit of %d points exhausted", s.limit)}
+	}
+	return nil
+}
+
+// record notes the rate limit reported by the headers of a response.
+func (s *Source) record(h http.Header) {
+	limit, err1 := strconv.Atoi(h.Get("X-RateLimit-Limit"))
+	remaining, err2 := strconv.Atoi(h.Get("X-RateLimit-Remaining"))
+	reset, err3 := strconv.ParseInt(h.Get("X-RateLimit-Reset"), 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limit, s.remaining, s.reset = limit, remaining, time.Unix(reset, 0)
+}
+
+// statusError converts a failed response into an error wrapping the
+// matching SDK error. Both the primary rate limit, exhausted until the
+// reset time, and secondary rate limits, which ask to retry after a delay,
+// are answered with 403 or 429.
+func (s *Source) statusError(resp *http.Response) error {
+	var e struct {
+		Message string `json:"message"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&e)
+	if resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests {
+		msg := fmt.Errorf("github: %s: %s", resp.Status, e.Message)
+		if v := resp.Header.Get("Retry-After"); v != "" {
+			return &datasource.ErrRateLimited{RetryAfter: httpx.ParseRetryAfter(v), Err: msg}
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			s.mu.Lock()
+			wait := max(s.reset.Sub(now()), 0)
+			s.mu.Unlock()
+			return &datasource.ErrRateLimited{RetryAfter: wait, Err: msg}
+		}
+	}
+	if e.Message != "" {
+		return fmt.Errorf("github: %w: %s", httpx.StatusError(resp), e.Message)
+	}
+	return fmt.Errorf("github: %w", httpx.StatusError(resp))
+}
+
+// queryError converts an error of a GraphQL response into an error
+// wrapping the matching SDK error.
+func (s *Source) queryError(e graphqlError) error {
+	err := fmt.Errorf("github: %s", strings.TrimSpace(e.Type+" "+e.Message))
+	switch e.Type {
+	case "NOT_FOUND":
+		return fmt.Errorf("%w: %w", datasource.ErrNotFound, err)
+	case "FORBIDDEN":
+		return fmt.Errorf("%w: %w", datasource.ErrUnauthorized, err)
+	case "RATE_LIMITED":
+		s.mu.Lock()
+		wait := max(s.reset.Sub(now()), 0)
+		s.mu.Unlock()
+		return &datasource.ErrRateLimited{RetryAfter: wait, Err: err}
+	case "SERVICE_UNAVAILABLE":
+		return fmt.Errorf("%w: %w", datasource.ErrUnavailable, err)
+	}
+	return err
+}
@@ -0,0 +1,677 @@
+// Package github is a data source searching the issues and discussions of
+// a set of GitHub repositories through the GraphQL API. Topics are issues
+// and discussions, and data items are their comments, most reactions
+// first, with a discussion's accepted answer leading:
+//
+//	ds := github.New(github.Config{
+//	    Repos: []string{"acme/widgets", "acme/gadgets"},
+//	    Token: os.Getenv("GITHUB_TOKEN"),
+//	})
+//
+// Repositories are the Site of results, and Filters.Sites narrows the
+// configured Repos. The API's rate limit is tracked from its response
+// headers: while it is exhausted, requests fail with
+// datasource.ErrRateLimited until it resets, as do requests refused by
+// secondary rate limits; wrap the source with middleware.Retry to retry
+// them. Search results and comments are paged through as needed.
+//
+// With Config.PostCategory set, the source is datasource.Writable:
+// PostTopic opens a discussion in that category for questions the host
+// could not answer.
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultURL         = "https://api.github.com/graphql"
+	DefaultMaxComments = 500
+	DefaultTimeout     = 15 * time.Second
+)
+
+// Metadata keys set on topics and data items.
+const (
+	MetadataKind     = "kind"          // "issue" or "discussion"
+	MetadataState    = "state"         // "open" or "closed"
+	MetadataLabels   = "labels"        // the labels of the issue or discussion
+	MetadataCategory = "category"      // the category of a discussion
+	MetadataComments = "comment_count" // the number of comments
+	MetadataAnswered = "answered"      // whether a discussion has an accepted answer
+	MetadataAccepted = "accepted"      // set on the comment that is a discussion's answer
+)
+
+// Kind is a kind of thread searched.
+type Kind string
+
+// Kinds of threads.
+const (
+	KindIssue      Kind = "issue"
+	KindDiscussion Kind = "discussion"
+)
+
+// pageSize is the most nodes the API returns in one page of a connection.
+const pageSize = 100
+
+// maxPhraseBytes bounds the error line searched for as a phrase.
+const maxPhraseBytes = 120
+
+// maxExcerptChars bounds the body text kept as a topic's BodyExcerpt.
+const maxExcerptChars = 300
+
+// maxTitleChars bounds the titles of discussions opened by PostTopic.
+const maxTitleChars = 120
+
+// Topic IDs pack the index of the repository, above repoShift, the kind,
+// at discussionBit, and the number of the issue or discussion.
+const (
+	discussionBit = 1 << 32
+	repoShift     = 33
+)
+
+// Config configures a Source.
+type Config struct {
+	// Repos are the repositories searched, as "owner/name"
+	Repos []string
+
+	// Token is a personal access token or GitHub App installation token
+	// with read access to the repositories' issues and discussions
+	Token string
+
+	// Kinds are the kinds of threads searched
+	// Defaults to issues and discussions
+	Kinds []Kind
+
+	// Query holds search qualifiers added to every search, such as
+	// "is:closed" or "label:faq"
+	// Optional
+	Query string
+
+	// URL is the GraphQL endpoint, such as
+	// "https://github.example.com/api/graphql" for GitHub Enterprise Server
+	// Defaults to DefaultURL
+	URL string
+
+	// MaxComments bounds the comments read per thread, most recent ones
+	// being left out of longer threads
+	// Defaults to DefaultMaxComments
+	MaxComments int
+
+	// Timeout bounds each request, in addition to NewQuestionInput.Budget
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// HTTPClient makes the requests
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+
+	// PostCategory is the discussion category, such as "Q&A", in which
+	// PostTopic opens a discussion for each question posted; the token
+	// then needs write access to discussions
+	// Optional - empty refuses posts
+	PostCategory string
+
+	// PostRepo is the repository, one of Repos, in which questions are
+	// posted
+	// Defaults to the first of Repos
+	PostRepo string
+}
+
+func (c Config) withDefaults() Config {
+	if len(c.Kinds) == 0 {
+		c.Kinds = []Kind{KindIssue, KindDiscussion}
+	}
+	if c.URL == "" {
+		c.URL = DefaultURL
+	}
+	if c.MaxComments <= 0 {
+		c.MaxComments = DefaultMaxComments
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	if c.PostRepo == "" && len(c.Repos) > 0 {
+		c.PostRepo = c.Repos[0]
+	}
+	return c
+}
+
+// Source is a DataSource searching GitHub issues and discussions. A Source
+// is safe for concurrent use.
+type Source struct {
+	cfg Config
+
+	// the rate limit, as of the last response
+	mu               sync.Mutex
+	limit, remaining int
+	reset            time.Time
+
+	// the node IDs of Config.PostRepo and Config.PostCategory, once Init
+	// resolved them
+	postRepoID, postCategoryID string
+}
+
+var (
+	_ datasource.DataSource         = (*Source)(nil)
+	_ datasource.CapabilityReporter = (*Source)(nil)
+	_ datasource.HealthChecker      = (*Source)(nil)
+	_ datasource.Writable           = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults()}
+}
+
+// Init checks the configuration, and that the token can read the
+// repositories and, if posting is configured, that the post category
+// exists.
+func (s *Source) Init() error {
+	if len(s.cfg.Repos) == 0 {
+		return errors.New("github: no repositories configured")
+	}
+	if s.cfg.Token == "" {
+		return errors.New("github: no token configured")
+	}
+	for _, k := range s.cfg.Kinds {
+		if k != KindIssue && k != KindDiscussion {
+			return fmt.Errorf("github: unknown kind %q", k)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	for _, repo := range s.cfg.Repos {
+		owner, name, ok := strings.Cut(repo, "/")
+		if !ok || owner == "" || name == "" {
+			return fmt.Errorf("github: repository %q is not owner/name", repo)
+		}
+		var r struct{}
+		vars := map[string]any{"owner": owner, "name": name}
+		if err := s.query(ctx, `query($owner: String!, $name: String!) { repository(owner: $owner, name: $name) { id } }`, vars, &r); err != nil {
+			return err
+		}
+	}
+	if s.cfg.PostCategory != "" {
+		return s.resolvePostCategory(ctx)
+	}
+	return nil
+}
+
+// resolvePostCategory looks up the node IDs of Config.PostRepo and
+// Config.PostCategory, which creating a discussion requires.
+func (s *Source) resolvePostCategory(ctx context.Context) error {
+	found := false
+	for _, repo := range s.cfg.Repos {
+		found = found || strings.EqualFold(repo, s.cfg.PostRepo)
+	}
+	if !found {
+		return fmt.Errorf("github: post repository %q is not one of the repositories", s.cfg.PostRepo)
+	}
+	owner, name, _ := strings.Cut(s.cfg.PostRepo, "/")
+	var r struct {
+		Repository struct {
+			ID         string `json:"id"`
+			Categories struct {
+				Nodes []struct {
+					ID   string `json:"id"`
+					Name string `json:"name"`
+				} `json:"nodes"`
+			} `json:"discussionCategories"`
+		} `json:"repository"`
+	}
+	if err := s.query(ctx, categoriesQuery, map[string]any{"owner": owner, "name": name}, &r); err != nil {
+		return err
+	}
+	for _, c := range r.Repository.Categories.Nodes {
+		if strings.EqualFold(c.Name, s.cfg.PostCategory) {
+			s.mu.Lock()
+			s.postRepoID, s.postCategoryID = r.Repository.ID, c.ID
+			s.mu.Unlock()
+			return nil
+		}
+	}
+	return fmt.Errorf("github: %s has no discussion category %q", s.cfg.PostRepo, s.cfg.PostCategory)
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck queries the rate limit: the source is unhealthy if the API
+// cannot be reached, and degraded while the rate limit is exhausted or
+// below a tenth.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	var r struct{}
+	err := s.query(ctx, rateLimitQuery, nil, &r)
+	h := datasource.HealthStatus{State: datasource.Healthy, Latency: time.Since(start)}
+	remaining, limit, _ := s.RateLimit()
+	switch {
+	case datasource.IsRetryable(err):
+		h.State, h.Error = datasource.Degraded, err.Error()
+	case err != nil:
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	case limit > 0 && remaining*10 < limit:
+		h.State = datasource.Degraded
+		h.Error = fmt.Sprintf("github: %d of %d rate limit points left", remaining, limit)
+	}
+	return h
+}
+
+// Capabilities reports pagination, several sites if several repositories
+// are configured, and write-back if a post category is.
+func (s *Source) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{
+		Pagination: true,
+		MultiSite:  len(s.cfg.Repos) > 1,
+		WriteBack:  s.cfg.PostCategory != "",
+	}
+}
+
+// RateLimit returns the GraphQL rate limit as of the last response: the
+// points left, the points per hour, and when it resets. All are zero
+// before the first request.
+func (s *Source) RateLimit() (remaining, limit int, reset time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.remaining, s.limit, s.reset
+}
+
+// FetchTopics searches the issues and discussions of the repositories for
+// the question, and interleaves the results of each kind. A topic's Score
+// is its number of reactions.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if count <= 0 || input.SearchText() == "" {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	repos := s.repos(input.Filters.Sites)
+	if len(repos) == 0 {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	ctx, cancel := input.Budget.Context(context.Background())
+	defer cancel()
+
+	var results [][]datasource.DataSourceTopic
+	for _, kind := range s.cfg.Kinds {
+		topics, err := s.search(ctx, kind, s.searchQuery(kind, input, repos), count)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, topics)
+	}
+	var topics []datasource.DataSourceTopic
+	for i := 0; ; i++ {
+		added := false
+		for _, r := range results {
+			if i < len(r) {
+				topics, added = append(topics, r[i]), true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// search pages through the search results of the kind for q until it has
+// count topics or the results end.
+func (s *Source) search(ctx context.Context, kind Kind, q string, count int) ([]datasource.DataSourceTopic, error) {
+	searchType := "ISSUE"
+	if kind == KindDiscussion {
+		searchType = "DISCUSSION"
+	}
+	topics := []datasource.DataSourceTopic{}
+	vars := map[string]any{"q": q, "type": searchType}
+	for len(topics) < count {
+		vars["first"] = min(count-len(topics), pageSize)
+		var r struct {
+			Search struct {
+				PageInfo pageInfo `json:"pageInfo"`
+				Nodes    []thread `json:"nodes"`
+			} `json:"search"`
+		}
+		reqCtx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+		err := s.query(reqCtx, searchQuery, vars, &r)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range r.Search.Nodes {
+			if topic, ok := s.topic(t); ok {
+				topics = append(topics, topic)
+			}
+		}
+		if !r.Search.PageInfo.HasNextPage || len(r.Search.Nodes) == 0 {
+			break
+		}
+		vars["after"] = r.Search.PageInfo.EndCursor
+	}
+	return topics, nil
+}
+
+// FetchData returns up to count comments of the issue or discussion with
+// the topic ID: a discussion's answer first, then by most reactions, and
+// oldest first among equals. Hidden comments are left out. An item's
+// AnswerID is the comment's database ID, and its Score its number of
+// reactions.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if count <= 0 {
+		return []datasource.DataSourceData{}, nil
+	}
+	repoIndex, kind, number := int(topicID>>repoShift), KindIssue, topicID&(discussionBit-1)
+	if topicID&discussionBit != 0 {
+		kind = KindDiscussion
+	}
+	if topicID <= 0 || repoIndex >= len(s.cfg.Repos) {
+		return nil, fmt.Errorf("github: topic %d: %w", topicID, datasource.ErrNotFound)
+	}
+	repo := s.cfg.Repos[repoIndex]
+	comments, answerID, err := s.comments(repo, kind, number)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(comments, func(i, j int) bool {
+		a, b := comments[i], comments[j]
+		if (a.DatabaseID == answerID) != (b.DatabaseID == answerID) {
+			return a.DatabaseID == answerID
+		}
+		if a.Reactions.TotalCount != b.Reactions.TotalCount {
+			return a.Reactions.TotalCount > b.Reactions.TotalCount
+		}
+		return a.CreatedAt.Before(b.CreatedAt)
+	})
+	items := make([]datasource.DataSourceData, 0, min(count, len(comments)))
+	for i, c := range comments[:min(count, len(comments))] {
+		item := datasource.DataSourceData{
+			DataText:    c.Body,
+			ContentType: datasource.ContentMarkdown,
+			SourceURL:   c.URL,
+			Site:        repo,
+			AnswerID:    c.DatabaseID,
+			Score:       float64(c.Reactions.TotalCount),
+			Rank:        i + 1,
+			CreatedAt:   c.CreatedAt,
+			UpdatedAt:   c.UpdatedAt,
+		}
+		if c.Author != nil {
+			item.Author = &datasource.Author{Name: c.Author.Login, ProfileURL: c.Author.URL}
+		}
+		item.Metadata.Set(MetadataKind, string(kind))
+		if c.DatabaseID == answerID {
+			item.Metadata.Set(MetadataAccepted, true)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// comments pages through the visible comments of an issue or discussion,
+// up to MaxComments, and returns them with the database ID of the
+// discussion's answer, if any.
+func (s *Source) comments(repo string, kind Kind, number int64) ([]comment, int64, error) {
+	owner, name, _ := strings.Cut(repo, "/")
+	vars := map[string]any{"owner": owner, "name": name, "number": number}
+	var (
+		comments []comment
+		answerID int64
+		read     int
+	)
+	for read < s.cfg.MaxComments {
+		var r struct {
+			Repository struct {
+				Thread *struct {
+					Answer   *answer `json:"answer"`
+					Comments struct {
+						PageInfo pageInfo  `json:"pageInfo"`
+						Nodes    []comment `json:"nodes"`
+					} `json:"comments"`
+				} `json:"thread"`
+			} `json:"repository"`
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+		err := s.query(ctx, commentsQuery(string(kind)), vars, &r)
+		cancel()
+		if err != nil {
+			return nil, 0, err
+		}
+		t := r.Repository.Thread
+		if t == nil {
+			return nil, 0, fmt.Errorf("github: %s %s#%d: %w", kind, repo, number, datasource.ErrNotFound)
+		}
+		if t.Answer != nil {
+			answerID = t.Answer.DatabaseID
+		}
+		for _, c := range t.Comments.Nodes[:min(len(t.Comments.Nodes), s.cfg.MaxComments-read)] {
+			if !c.IsMinimized {
+				comments = append(comments, c)
+			}
+		}
+		read += len(t.Comments.Nodes)
+		if !t.Comments.PageInfo.HasNextPage || len(t.Comments.Nodes) == 0 {
+			break
+		}
+		vars["after"] = t.Comments.PageInfo.EndCursor
+	}
+	return comments, answerID, nil
+}
+
+// repos returns the configured repositories the Sites filter allows.
+func (s *Source) repos(sites []string) []string {
+	if len(sites) == 0 {
+		return s.cfg.Repos
+	}
+	var out []string
+	for _, repo := range s.cfg.Repos {
+		for _, site := range sites {
+			if strings.EqualFold(repo, site) {
+				out = append(out, repo)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// searchQuery returns the search query for threads of the kind answering
+// the question in the repositories, with the input's filters as
+// qualifiers. The error line of the first attachment that has one is
+// searched for as a phrase.
+func (s *Source) searchQuery(kind Kind, input datasource.NewQuestionInput, repos []string) string {
+	var parts []string
+	if q := strings.TrimSpace(input.QuestionText); q != "" {
+		parts = append(parts, q)
+	}
+	if lines := input.ErrorLines(); len(lines) > 0 {
+		parts = append(parts, `"`+phrase(lines[0])+`"`)
+	}
+	for _, repo := range repos {
+		parts = append(parts, "repo:"+repo)
+	}
+	if kind == KindIssue {
+		parts = append(parts, "is:issue")
+	}
+	f := input.Filters
+	for _, tag := range f.ExcludeTags {
+		parts = append(parts, `-label:"`+strings.ReplaceAll(tag, `"`, "")+`"`)
+	}
+	if !f.After.IsZero() {
+		parts = append(parts, "created:>="+f.After.UTC().Format(time.RFC3339))
+	}
+	if !f.Before.IsZero() {
+		parts = append(parts, "created:<"+f.Before.UTC().Format(time.RFC3339))
+	}
+	if f.Sort == datasource.SortRecency {
+		parts = append(parts, "sort:created-desc")
+	}
+	if s.cfg.Query != "" {
+		parts = append(parts, s.cfg.Query)
+	}
+	return strings.Join(parts, " ")
+}
+
+// phrase prepares an error line for an exact-match search: quotes are
+// dropped, and the line is cut at a word boundary to maxPhraseBytes, so
+// that it leaves room for the question within GitHub's query limit.
+func phrase(line string) string {
+	line = strings.Join(strings.Fields(strings.ReplaceAll(line, `"`, " ")), " ")
+	if len(line) <= maxPhraseBytes {
+		return line
+	}
+	if i := strings.LastIndexByte(line[:maxPhraseBytes+1], ' '); i > 0 {
+		return line[:i]
+	}
+	return strings.ToValidUTF8(line[:maxPhraseBytes], "")
+}
+
+// PostTopic opens a discussion for the question in Config.PostCategory of
+// Config.PostRepo, and returns it as a topic. The discussion's title is
+// the question's first line, and its body the question followed by its
+// attachments. PostTopic fails with errors.ErrUnsupported if no post
+// category is configured.
+func (s *Source) PostTopic(ctx context.Context, input datasource.NewQuestionInput) (datasource.DataSourceTopic, error) {
+	if s.cfg.PostCategory == "" {
+		return datasource.DataSourceTopic{}, fmt.Errorf("github: post: %w: no post category configured", errors.ErrUnsupported)
+	}
+	first, _, _ := strings.Cut(strings.TrimSpace(input.QuestionText), "\n")
+	title := excerpt(first, maxTitleChars)
+	if title == "" {
+		return datasource.DataSourceTopic{}, errors.New("github: post: empty question")
+	}
+	s.mu.Lock()
+	repoID, categoryID := s.postRepoID, s.postCategoryID
+	s.mu.Unlock()
+	if repoID == "" {
+		return datasource.DataSourceTopic{}, fmt.Errorf("github: post: %w: not initialized", datasource.ErrUnavailable)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	var r struct {
+		CreateDiscussion struct {
+			Discussion thread `json:"discussion"`
+		} `json:"createDiscussion"`
+	}
+	vars := map[string]any{"repo": repoID, "category": categoryID, "title": title, "body": postBody(input)}
+	if err := s.query(ctx, createDiscussionMutation, vars, &r); err != nil {
+		return datasource.DataSourceTopic{}, err
+	}
+	topic, ok := s.topic(r.CreateDiscussion.Discussion)
+	if !ok {
+		return datasource.DataSourceTopic{}, fmt.Errorf("github: post: unexpected discussion %q", r.CreateDiscussion.Discussion.URL)
+	}
+	return topic, nil
+}
+
+// postBody returns the Markdown body of a discussion for the question:
+// its text, then its attachments, code and logs fenced and images linked.
+func postBody(input datasource.NewQuestionInput) string {
+	var b strings.Builder
+	b.WriteString(strings.TrimSpace(input.QuestionText))
+	for _, a := range input.Attachments {
+		b.WriteString("\n\n")
+		if a.Kind == datasource.AttachmentImage {
+			alt := a.Name
+			if a.Text != "" {
+				alt = a.Text
+			}
+			if a.URL == "" {
+				b.WriteString(alt)
+				continue
+			}
+			fmt.Fprintf(&b, "![%s](%s)", alt, a.URL)
+			continue
+		}
+		if a.Name != "" {
+			fmt.Fprintf(&b, "`%s`:\n", a.Name)
+		}
+		fence := "```"
+		for strings.Contains(a.Text, fence) {
+			fence += "`"
+		}
+		fmt.Fprintf(&b, "%s%s\n%s\n%s", fence, a.Language, strings.TrimRight(a.Text, "\n"), fence)
+	}
+	return b.String()
+}
+
+// topic converts a search result into a topic, reporting false for
+// results outside the configured repositories.
+func (s *Source) topic(t thread) (datasource.DataSourceTopic, bool) {
+	repoIndex := -1
+	for i, repo := range s.cfg.Repos {
+		if strings.EqualFold(repo, t.Repository.NameWithOwner) {
+			repoIndex = i
+			break
+		}
+	}
+	if repoIndex < 0 || t.Number <= 0 || t.Number >= discussionBit {
+		return datasource.DataSourceTopic{}, false
+	}
+	id, kind, state := int64(repoIndex)<<repoShift|t.Number, KindIssue, strings.ToLower(t.State)
+	if t.Typename == "Discussion" {
+		id, kind, state = id|discussionBit, KindDiscussion, "open"
+		if t.Closed {
+			state = "closed"
+		}
+	}
+	topic := datasource.DataSourceTopic{
+		SourceURL: t.URL,
+		Site:      s.cfg.Repos[repoIndex],
+		TopicID:   id,
+		Score:     float64(t.Reactions.TotalCount),
+		CreatedAt: t.CreatedAt,
+		UpdatedAt: t.UpdatedAt,
+	}
+	topic = topic.Structured(t.Title, excerpt(t.BodyText, maxExcerptChars), s.cfg.Repos[repoIndex])
+	topic.Metadata.Set(MetadataKind, string(kind))
+	topic.Metadata.Set(MetadataState, state)
+	topic.Metadata.Set(MetadataComments, t.Comments.TotalCount)
+	if len(t.Labels.Nodes) > 0 {
+		labels := make([]string, len(t.Labels.Nodes))
+		for i, l := range t.Labels.Nodes {
+			labels[i] = l.Name
+		}
+		topic.Metadata.Set(MetadataLabels, labels)
+	}
+	if kind == KindDiscussion {
+		topic.Metadata.Set(MetadataAnswered, t.Answer != nil)
+		if t.Category != nil {
+			topic.Metadata.Set(MetadataCategory, t.Category.Name)
+		}
+	}
+	return topic, true
+}
+
+// excerpt returns the start of a text, at most limit characters cut at a
+// word boundary.
+func excerpt(text string, limit int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	r := []rune(text)
+	if len(r) <= limit {
+		return text
+	}
+	cut := string(r[:limit])
+	if i := strings.LastIndexByte(cut, ' '); i > 0 {
+		cut = cut[:i]
+	}
+	return cut + "…"
+}
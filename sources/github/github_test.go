@@ -0,0 +1,349 @@
+package github_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/github"
+)
+
+var issue = map[string]any{
+	"__typename": "Issue", "number": 12, "title": "Crash on startup", "url": "https://github.com/acme/widgets/issues/12",
+	"bodyText": "The widget crashes   when started.", "createdAt": "2026-03-01T10:00:00Z", "updatedAt": "2026-03-02T10:00:00Z",
+	"state": "OPEN", "repository": map[string]any{"nameWithOwner": "acme/widgets"},
+	"labels":   map[string]any{"nodes": []any{map[string]any{"name": "bug"}}},
+	"comments": map[string]any{"totalCount": 3}, "reactions": map[string]any{"totalCount": 5},
+}
+
+var discussion = map[string]any{
+	"__typename": "Discussion", "number": 7, "title": "How do I configure widgets?", "url": "https://github.com/acme/gadgets/discussions/7",
+	"bodyText": "Where is the config file?", "createdAt": "2026-02-01T10:00:00Z", "updatedAt": "2026-02-01T10:00:00Z",
+	"closed": false, "repository": map[string]any{"nameWithOwner": "acme/gadgets"},
+	"labels": map[string]any{"nodes": []any{}}, "category": map[string]any{"name": "Q&A"},
+	"comments": map[string]any{"totalCount": 2}, "reactions": map[string]any{"totalCount": 1},
+	"answer": map[string]any{"databaseId": 502},
+}
+
+func comment(id, reactions int, created string, minimized bool) map[string]any {
+	return map[string]any{
+		"databaseId": id, "url": fmt.Sprintf("https://github.com/acme/widgets/issues/12#issuecomment-%d", id),
+		"body": fmt.Sprintf("comment %d", id), "createdAt": created, "updatedAt": created,
+		"author": map[string]any{"login": "octocat", "url": "https://github.com/octocat"}, "reactions": map[string]any{"totalCount": reactions},
+		"isMinimized": minimized,
+	}
+}
+
+// api is a fake GraphQL API.
+type api struct {
+	remaining int
+	reset     time.Time
+
+	mu       sync.Mutex
+	searches []map[string]any
+	posts    []map[string]any
+	requests int
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.requests++
+	var req struct {
+		Query     string         `json:"query"`
+		Variables map[string]any `json:"variables"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+	w.Header().Set("X-RateLimit-Limit", "5000")
+	w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(a.remaining))
+	w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(a.reset.Unix(), 10))
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"message":"Bad credentials"}`)
+		return
+	}
+	if a.remaining == 0 {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"message":"API rate limit exceeded"}`)
+		return
+	}
+	v := req.Variables
+	var data any
+	switch {
+	case strings.Contains(req.Query, "rateLimit"):
+		data = map[string]any{"rateLimit": map[string]any{"limit": 5000, "remaining": a.remaining}}
+	case strings.Contains(req.Query, "search("):
+		a.searches = append(a.searches, v)
+		nodes, info := []any{}, map[string]any{"hasNextPage": false}
+		switch {
+		case v["type"] == "ISSUE" && v["after"] == nil:
+			nodes = []any{issue, map[string]any{"__typename": "Issue", "number": 1, "repository": map[string]any{"nameWithOwner": "other/repo"}}}
+			info = map[string]any{"hasNextPage": true, "endCursor": "c1"}
+		case v["type"] == "ISSUE":
+			nodes = []any{map[string]any{"__typename": "Issue", "number": 13, "title": "Second page", "repository": map[string]any{"nameWithOwner": "acme/widgets"}}}
+		case v["type"] == "DISCUSSION":
+			nodes = []any{discussion}
+		}
+		data = map[string]any{"search": map[string]any{"pageInfo": info, "nodes": nodes}}
+	case strings.Contains(req.Query, "thread: issue") && v["number"] == 12.0:
+		page := map[string]any{
+			"pageInfo": map[string]any{"hasNextPage": true, "endCursor": "p1"},
+			"nodes":    []any{comment(101, 1, "2026-03-01T11:00:00Z", false), comment(102, 9, "2026-03-01T12:00:00Z", false)},
+		}
+		if v["after"] == "p1" {
+			page = map[string]any{
+				"pageInfo": map[string]any{"hasNextPage": false},
+				"nodes":    []any{comment(103, 1, "2026-03-01T10:30:00Z", false), comment(104, 50, "2026-03-01T13:00:00Z", true)},
+			}
+		}
+		data = map[string]any{"repository": map[string]any{"thread": map[string]any{"comments": page}}}
+	case strings.Contains(req.Query, "thread: discussion") && v["number"] == 7.0:
+		data = map[string]any{"repository": map[string]any{"thread": map[string]any{
+			"answer": map[string]any{"databaseId": 502},
+			"comments": map[string]any{"pageInfo": map[string]any{"hasNextPage": false},
+				"nodes": []any{comment(501, 4, "2026-02-01T11:00:00Z", false), comment(502, 0, "2026-02-01T12:00:00Z", false)}},
+		}}}
+	case strings.Contains(req.Query, "thread:"):
+		json.NewEncoder(w).Encode(map[string]any{
+			"data":   map[string]any{"repository": map[string]any{"thread": nil}},
+			"errors": []any{map[string]any{"type": "NOT_FOUND", "message": "Could not resolve to an Issue."}},
+		})
+		return
+	case strings.Contains(req.Query, "discussionCategories"):
+		data = map[string]any{"repository": map[string]any{"id": "R_1", "discussionCategories": map[string]any{"nodes": []any{
+			map[string]any{"id": "DC_1", "name": "Announcements"}, map[string]any{"id": "DC_2", "name": "Q&A"},
+		}}}}
+	case strings.Contains(req.Query, "createDiscussion"):
+		a.posts = append(a.posts, v)
+		data = map[string]any{"createDiscussion": map[string]any{"discussion": map[string]any{
+			"__typename": "Discussion", "number": 8, "title": v["title"], "url": "https://github.com/acme/gadgets/discussions/8",
+			"bodyText": v["body"], "createdAt": "2026-03-05T10:00:00Z", "updatedAt": "2026-03-05T10:00:00Z",
+			"closed": false, "repository": map[string]any{"nameWithOwner": "acme/gadgets"},
+			"labels": map[string]any{"nodes": []any{}}, "category": map[string]any{"name": "Q&A"},
+			"comments": map[string]any{"totalCount": 0}, "reactions": map[string]any{"totalCount": 0},
+		}}}
+	case v["name"] == "missing":
+		json.NewEncoder(w).Encode(map[string]any{
+			"data":   map[string]any{"repository": nil},
+			"errors": []any{map[string]any{"type": "NOT_FOUND", "message": "Could not resolve to a Repository."}},
+		})
+		return
+	default:
+		data = map[string]any{"repository": map[string]any{"id": "R_1"}}
+	}
+	json.NewEncoder(w).Encode(map[string]any{"data": data})
+}
+
+func newSource(t *testing.T, a *api, repos ...string) *github.Source {
+	t.Helper()
+	srv := httptest.NewServer(a)
+	t.Cleanup(srv.Close)
+	ds := github.New(github.Config{Repos: repos, Token: "token", URL: srv.URL})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return ds
+}
+
+func TestFetchTopics(t *testing.T) {
+	a := &api{remaining: 4000, reset: time.Now().Add(time.Hour)}
+	ds := newSource(t, a, "acme/widgets", "acme/gadgets")
+	topics, err := ds.FetchTopics(2, datasource.NewQuestionInput{
+		QuestionText: "widget crash",
+		Filters:      datasource.Filters{ExcludeTags: []string{"wontfix"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 {
+		t.Fatalf("got %d topics, want 2: %+v", len(topics), topics)
+	}
+	got := topics[0]
+	if got.Title != "Crash on startup" || got.Topic != "Crash on startup" || got.Site != "acme/widgets" ||
+		got.BodyExcerpt != "The widget crashes when started." || got.Score != 5 || got.Rank != 1 {
+		t.Errorf("first topic = %+v", got)
+	}
+	if v, _ := got.Metadata.String(github.MetadataState); v != "open" {
+		t.Errorf("state = %q", v)
+	}
+	if got := topics[1]; got.Title != "How do I configure widgets?" || got.Site != "acme/gadgets" || got.Rank != 2 {
+		t.Errorf("second topic = %+v", got)
+	}
+	if v, _ := topics[1].Metadata.String(github.MetadataCategory); v != "Q&A" {
+		t.Errorf("category = %q", v)
+	}
+
+	if len(a.searches) != 3 {
+		t.Fatalf("got %d searches, want two pages of issues and one of discussions", len(a.searches))
+	}
+	if q := a.searches[0]["q"]; q != `widget crash repo:acme/widgets repo:acme/gadgets is:issue -label:"wontfix"` {
+		t.Errorf("issue query = %q", q)
+	}
+	if a.searches[1]["after"] != "c1" || a.searches[1]["first"] != 1.0 {
+		t.Errorf("second page = %v", a.searches[1])
+	}
+
+	topics, _ = ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "x", Filters: datasource.Filters{Sites: []string{"acme/gadgets"}}})
+	if len(topics) != 1 || topics[0].Site != "acme/gadgets" {
+		t.Errorf("topics of acme/gadgets = %+v", topics)
+	}
+	if q := a.searches[len(a.searches)-1]["q"]; q != "x repo:acme/gadgets" {
+		t.Errorf("discussion query = %q", q)
+	}
+
+	ds.FetchTopics(1, datasource.NewQuestionInput{
+		Attachments: []datasource.Attachment{{Kind: datasource.AttachmentLog, Text: "goroutine 1 [running]:\npanic: widget \"w1\" is nil\n\nmain.go:12 +0x1d"}},
+		Filters:     datasource.Filters{Sites: []string{"acme/gadgets"}},
+	})
+	if q := a.searches[len(a.searches)-1]["q"]; q != `"panic: widget w1 is nil" repo:acme/gadgets` {
+		t.Errorf("query for an attached stack trace = %q", q)
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	a := &api{remaining: 4000, reset: time.Now().Add(time.Hour)}
+	ds := newSource(t, a, "acme/widgets", "acme/gadgets")
+	topics, err := ds.FetchTopics(3, datasource.NewQuestionInput{QuestionText: "widgets"})
+	if err != nil || len(topics) != 3 {
+		t.Fatalf("FetchTopics = %+v, %v", topics, err)
+	}
+
+	data, err := ds.FetchData(10, topics[0].TopicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int64
+	for _, d := range data {
+		ids = append(ids, d.AnswerID)
+	}
+	if fmt.Sprint(ids) != "[102 103 101]" {
+		t.Errorf("comment order = %v, want most reactions, then oldest, hidden left out", ids)
+	}
+	if d := data[0]; d.Score != 9 || d.Rank != 1 || d.ContentType != datasource.ContentMarkdown || d.Site != "acme/widgets" ||
+		d.Author == nil || d.Author.Name != "octocat" || d.SourceURL != "https://github.com/acme/widgets/issues/12#issuecomment-102" {
+		t.Errorf("first comment = %+v", d)
+	}
+
+	data, err = ds.FetchData(10, topics[1].TopicID)
+	if err != nil || len(data) != 2 || data[0].AnswerID != 502 {
+		t.Fatalf("discussion comments = %+v, %v", data, err)
+	}
+	if accepted, _ := data[0].Metadata.Bool(github.MetadataAccepted); !accepted {
+		t.Error("answer not marked accepted")
+	}
+
+	if _, err := ds.FetchData(10, topics[2].TopicID); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData(deleted issue) = %v, want ErrNotFound", err)
+	}
+	if _, err := ds.FetchData(10, 99<<33|1); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData(unknown repo) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	a := &api{remaining: 100, reset: time.Now().Add(30 * time.Minute)}
+	ds := newSource(t, a, "acme/widgets")
+	if remaining, limit, _ := ds.RateLimit(); remaining != 100 || limit != 5000 {
+		t.Errorf("RateLimit = %d of %d", remaining, limit)
+	}
+	if h := ds.HealthCheck(); h.State != datasource.Degraded {
+		t.Errorf("health with 2%% left = %+v, want degraded", h)
+	}
+
+	a.mu.Lock()
+	a.remaining = 0
+	a.mu.Unlock()
+	_, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "crash"})
+	var limited *datasource.ErrRateLimited
+	if !errors.As(err, &limited) || limited.RetryAfter < 29*time.Minute {
+		t.Fatalf("FetchTopics when exhausted = %v, want ErrRateLimited until the reset", err)
+	}
+	requests := a.requests
+	if _, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "crash"}); !errors.As(err, &limited) {
+		t.Errorf("FetchTopics while exhausted = %v, want ErrRateLimited", err)
+	}
+	if a.requests != requests {
+		t.Error("request sent while the rate limit was exhausted")
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	if err := github.New(github.Config{Token: "token"}).Init(); err == nil {
+		t.Error("Init without repositories succeeded")
+	}
+	if err := github.New(github.Config{Repos: []string{"acme"}, Token: "token"}).Init(); err == nil {
+		t.Error("Init with a malformed repository succeeded")
+	}
+	srv := httptest.NewServer(&api{remaining: 10, reset: time.Now().Add(time.Hour)})
+	defer srv.Close()
+	if err := github.New(github.Config{Repos: []string{"acme/missing"}, Token: "token", URL: srv.URL}).Init(); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("Init(missing repository) = %v, want ErrNotFound", err)
+	}
+	if err := github.New(github.Config{Repos: []string{"acme/widgets"}, Token: "bad", URL: srv.URL}).Init(); !errors.Is(err, datasource.ErrUnauthorized) {
+		t.Errorf("Init(bad token) = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestPostTopic(t *testing.T) {
+	a := &api{remaining: 4000, reset: time.Now().Add(time.Hour)}
+	srv := httptest.NewServer(a)
+	defer srv.Close()
+	cfg := github.Config{Repos: []string{"acme/widgets", "acme/gadgets"}, Token: "token", URL: srv.URL, PostRepo: "acme/gadgets", PostCategory: "q&a"}
+	ds := github.New(cfg)
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if w, ok := datasource.WritableOf(ds); !ok || w != ds {
+		t.Fatal("source with a post category is not writable")
+	}
+
+	input := datasource.NewQuestionInput{
+		QuestionText: "Why does the widget panic on startup?\nIt started after upgrading.",
+		Attachments: []datasource.Attachment{
+			{Kind: datasource.AttachmentLog, Name: "stderr", Text: "panic: nil map\n"},
+			{Kind: datasource.AttachmentImage, Text: "settings page", URL: "https://img.example.com/1.png"},
+		},
+	}
+	topic, err := ds.PostTopic(context.Background(), input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if topic.TopicID != 1<<33|1<<32|8 || topic.SourceURL != "https://github.com/acme/gadgets/discussions/8" || topic.Site != "acme/gadgets" {
+		t.Errorf("topic = %+v", topic)
+	}
+	post := a.posts[0]
+	wantBody := "Why does the widget panic on startup?\nIt started after upgrading.\n\n`stderr`:\n```\npanic: nil map\n```\n\n![settings page](https://img.example.com/1.png)"
+	if post["repo"] != "R_1" || post["category"] != "DC_2" || post["title"] != "Why does the widget panic on startup?" || post["body"] != wantBody {
+		t.Errorf("posted %q", post)
+	}
+	if _, err := ds.PostTopic(context.Background(), datasource.NewQuestionInput{QuestionText: "  "}); err == nil {
+		t.Error("posted an empty question")
+	}
+
+	readOnly := newSource(t, a, "acme/widgets")
+	if _, ok := datasource.WritableOf(readOnly); ok {
+		t.Error("source without a post category is writable")
+	}
+	if _, err := readOnly.PostTopic(context.Background(), input); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("PostTopic without a post category = %v, want ErrUnsupported", err)
+	}
+
+	for _, bad := range []github.Config{
+		{Repos: cfg.Repos, Token: "token", URL: srv.URL, PostRepo: "acme/other", PostCategory: "Q&A"},
+		{Repos: cfg.Repos, Token: "token", URL: srv.URL, PostCategory: "Ideas"},
+	} {
+		if err := github.New(bad).Init(); err == nil {
+			t.Errorf("Init with post repository %q and category %q succeeded", bad.PostRepo, bad.PostCategory)
+		}
+	}
+}
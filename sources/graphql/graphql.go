@@ -0,0 +1,427 @@
+// Package graphql is a data source for any GraphQL API, described by
+// configuration: the operator supplies the search and fetch queries, and
+// maps fields of their results to the fields of topics and data items:
+//
+//	ds := graphql.New(graphql.Config{
+//	    URL: "https://kb.example.com/graphql",
+//	    SearchQuery: `query($question: String!, $count: Int!) {
+//	        search(text: $question, first: $count) { id title summary url }
+//	    }`,
+//	    FetchQuery: `query($topicId: ID!) {
+//	        article(id: $topicId) { paragraphs { body } }
+//	    }`,
+//	    Headers:     map[string]string{"Authorization": "Bearer {{.Credentials}}"},
+//	    TopicFields: restgeneric.Mapping{Items: "search", Excerpt: "summary"},
+//	    DataFields:  restgeneric.Mapping{Items: "article.paragraphs", Text: "body"},
+//	    Credentials: os.Getenv("KB_TOKEN"),
+//	})
+//
+// The queries receive the question through the variables they declare of
+// these names:
+//
+//	$question   the question text, with the error lines of its attachments
+//	$count      the number of results wanted
+//	$tags       the question's tags
+//	$tenantId   NewQuestionInput.TenantID
+//	$language   the first of the accepted languages, or null
+//	$languages  the accepted languages
+//	$topicId    the topic whose data items are fetched, in FetchQuery
+//
+// Mappings are those of restgeneric, with paths relative to the "data" of
+// responses. Errors in responses are reported with the SDK's errors where
+// their code says which applies, unless the response has partial data,
+// which is used.
+//
+// The Config has JSON field names, and FromSettings builds a Source from
+// them, so the source can be listed in a config descriptor:
+//
+//	loader := &config.Loader{Types: map[string]config.Constructor{"graphql": graphql.FromSettings}}
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+	"github.com/locus-search/datasource-sdk/internal/fieldmap"
+	"github.com/locus-search/datasource-sdk/sources/restgeneric"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultTimeout = 10 * time.Second
+)
+
+// maxResponseBytes bounds response bodies read from the API.
+const maxResponseBytes = 16 << 20
+
+// healthQuery is valid against any GraphQL schema.
+const healthQuery = `query { __typename }`
+
+// Config configures a Source.
+type Config struct {
+	// URL is the GraphQL endpoint
+	URL string `json:"url"`
+
+	// SearchQuery is the GraphQL document searching for the topics
+	// answering a question, best first
+	SearchQuery string `json:"search_query"`
+
+	// FetchQuery is the GraphQL document fetching the data items of a
+	// topic, best first
+	FetchQuery string `json:"fetch_query"`
+
+	// Variables are sent with both queries, such as the ID of a space to
+	// search; the question's variables take precedence over them
+	// Optional
+	Variables map[string]any `json:"variables,omitempty"`
+
+	// Headers are sent with every request; their values are templates
+	// reading {{.Credentials}}, such as "Bearer {{.Credentials}}"
+	// Optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// TopicFields maps the results of SearchQuery to topics
+	TopicFields restgeneric.Mapping `json:"topic_fields,omitempty"`
+
+	// DataFields maps the results of FetchQuery to data items
+	DataFields restgeneric.Mapping `json:"data_fields,omitempty"`
+
+	// ContentType is the format of data items' text
+	// Defaults to detecting it with content.Detect
+	ContentType datasource.ContentType `json:"content_type,omitempty"`
+
+	// Credentials is the secret headers read as {{.Credentials}}; it is
+	// never read from JSON
+	// Optional
+	Credentials string `json:"-"`
+
+	// Timeout bounds each request, in addition to NewQuestionInput.Budget
+	// Defaults to DefaultTimeout
+	Timeout time.Duration `json:"-"`
+
+	// HTTPClient makes the requests
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client `json:"-"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+// FromSettings returns a Source for the JSON form of a Config, with a
+// "timeout" given as a duration string such as "5s", and the credentials.
+// It has the signature of config.Constructor.
+func FromSettings(settings json.RawMessage, credentials string) (datasource.DataSource, error) {
+	var s struct {
+		Config
+		Timeout string `json:"timeout,omitempty"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(settings))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("graphql: settings: %w", err)
+	}
+	if s.Timeout != "" {
+		d, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("graphql: settings: timeout: %w", err)
+		}
+		s.Config.Timeout = d
+	}
+	s.Config.Credentials = credentials
+	return New(s.Config), nil
+}
+
+// Source is a DataSource querying a GraphQL API as its Config describes.
+// A Source is safe for concurrent use.
+type Source struct {
+	cfg Config
+
+	// Set by Init.
+	headers              map[string]*template.Template
+	searchVars, dataVars map[string]bool
+	topicFields          *fieldmap.Mapping
+	dataFields           *fieldmap.Mapping
+}
+
+var (
+	_ datasource.DataSource    = (*Source)(nil)
+	_ datasource.HealthChecker = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults()}
+}
+
+// Init compiles the headers and mappings, and checks that the endpoint
+// answers a trivial query.
+func (s *Source) Init() error {
+	if s.cfg.URL == "" || s.cfg.SearchQuery == "" || s.cfg.FetchQuery == "" {
+		return errors.New("graphql: URL, SearchQuery, and FetchQuery are required")
+	}
+	headers := make(map[string]*template.Template, len(s.cfg.Headers))
+	for k, v := range s.cfg.Headers {
+		t, err := template.New(k).Funcs(fieldmap.Funcs).Parse(v)
+		if err != nil {
+			return fmt.Errorf("graphql: header %s: %w", k, err)
+		}
+		headers[k] = t
+	}
+	topicFields, err := fieldmap.Compile("TopicFields", fieldmap.Spec(s.cfg.TopicFields))
+	if err != nil {
+		return fmt.Errorf("graphql: %w", err)
+	}
+	dataFields, err := fieldmap.Compile("DataFields", fieldmap.Spec(s.cfg.DataFields))
+	if err != nil {
+		return fmt.Errorf("graphql: %w", err)
+	}
+	s.headers, s.topicFields, s.dataFields = headers, topicFields, dataFields
+	s.searchVars, s.dataVars = declared(s.cfg.SearchQuery), declared(s.cfg.FetchQuery)
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	_, err = s.query(ctx, healthQuery, nil)
+	return err
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck sends a trivial query.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	if s.topicFields == nil {
+		return datasource.HealthStatus{State: datasource.Unhealthy, Error: "graphql: not initialized"}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	_, err := s.query(ctx, healthQuery, nil)
+	h := datasource.HealthStatus{State: datasource.Healthy, Latency: time.Since(start)}
+	if err != nil {
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	}
+	return h
+}
+
+// FetchTopics sends SearchQuery. Topics are ranked in the order of the
+// results, and narrowed by the input's accepted languages and filters.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if s.topicFields == nil {
+		return nil, errors.New("graphql: not initialized")
+	}
+	question := input.SearchText()
+	if count <= 0 || question == "" {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	ctx, cancel := input.Budget.Context(context.Background())
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	var language any
+	if len(input.AcceptLanguages) > 0 {
+		language = input.AcceptLanguages[0]
+	}
+	tags, languages := input.Tags, input.AcceptLanguages
+	if tags == nil {
+		tags = []string{}
+	}
+	if languages == nil {
+		languages = []string{}
+	}
+	vars := s.variables(s.searchVars, map[string]any{
+		"question":  question,
+		"count":     count,
+		"tags":      tags,
+		"tenantId":  input.TenantID,
+		"language":  language,
+		"languages": languages,
+	})
+	data, err := s.query(ctx, s.cfg.SearchQuery, vars)
+	if err != nil {
+		return nil, err
+	}
+	topics := []datasource.DataSourceTopic{}
+	for _, r := range s.topicFields.Results(data) {
+		t, err := s.topicFields.Topic(r)
+		if err != nil {
+			return nil, fmt.Errorf("graphql: SearchQuery: %w", err)
+		}
+		if datasource.AcceptsLanguage(input.AcceptLanguages, t.Language) {
+			topics = append(topics, t)
+		}
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// FetchData sends FetchQuery. Items are ranked in the order of the
+// results.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if s.dataFields == nil {
+		return nil, errors.New("graphql: not initialized")
+	}
+	if count <= 0 {
+		return []datasource.DataSourceData{}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	vars := s.variables(s.dataVars, map[string]any{"topicId": topicID, "count": count})
+	data, err := s.query(ctx, s.cfg.FetchQuery, vars)
+	if err != nil {
+		return nil, err
+	}
+	results := s.dataFields.Results(data)
+	items := make([]datasource.DataSourceData, 0, min(count, len(results)))
+	for i, r := range results[:min(count, len(results))] {
+		item := s.dataFields.Data(r, i, s.cfg.ContentType)
+		item.Rank = i + 1
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// variablePattern matches the variable definitions of a GraphQL document.
+var variablePattern = regexp.MustCompile(`\$([_A-Za-z][_0-9A-Za-z]*)\s*:`)
+
+// declared returns the names of the variables a document defines.
+func declared(document string) map[string]bool {
+	names := make(map[string]bool)
+	for _, m := range variablePattern.FindAllStringSubmatch(document, -1) {
+		names[m[1]] = true
+	}
+	return names
+}
+
+// variables returns Config.Variables with those of the question the
+// document declares.
+func (s *Source) variables(declared map[string]bool, question map[string]any) map[string]any {
+	vars := make(map[string]any, len(s.cfg.Variables)+len(question))
+	for k, v := range s.cfg.Variables {
+		vars[k] = v
+	}
+	for k, v := range question {
+		if declared[k] {
+			vars[k] = v
+		}
+	}
+	return vars
+}
+
+// gqlError is an error of a GraphQL response. Servers give its code as
+// extensions.code, or as type.
+type gqlError struct {
+	Message    string `json:"message"`
+	Type       string `json:"type"`
+	Extensions struct {
+		Code string `json:"code"`
+	} `json:"extensions"`
+}
+
+// query sends a GraphQL document with its variables and returns the data
+// of the response, decoded with numbers as json.Number. It fails with the
+// first error of the response if none of the fields queried has data.
+func (s *Source) query(ctx context.Context, document string, vars map[string]any) (any, error) {
+	body, err := json.Marshal(map[string]any{"query": document, "variables": vars})
+	if err != nil {
+		return nil, fmt.Errorf("graphql: encode variables: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("graphql: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Content-Type", "application/json")
+	for k, t := range s.headers {
+		v, err := fieldmap.Execute(t, struct{ Credentials string }{s.cfg.Credentials})
+		if err != nil {
+			return nil, fmt.Errorf("graphql: header %s: %w", k, err)
+		}
+		req.Header.Set(k, v)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("graphql: %w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var r struct {
+		Data   any        `json:"data"`
+		Errors []gqlError `json:"errors"`
+	}
+	dec := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes))
+	dec.UseNumber()
+	decodeErr := dec.Decode(&r)
+	switch {
+	// Servers following the GraphQL over HTTP spec answer invalid
+	// documents with 400 and errors that say more than the status.
+	case resp.StatusCode == http.StatusBadRequest && len(r.Errors) > 0:
+		return nil, queryError(r.Errors[0])
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("graphql: %w", httpx.StatusError(resp))
+	case decodeErr != nil:
+		return nil, fmt.Errorf("graphql: decode response: %w", decodeErr)
+	case len(r.Errors) > 0 && empty(r.Data):
+		return nil, queryError(r.Errors[0])
+	}
+	return r.Data, nil
+}
+
+// empty reports whether data has no value other than null, as when the
+// fields queried failed.
+func empty(data any) bool {
+	m, ok := data.(map[string]any)
+	if !ok {
+		return data == nil
+	}
+	for _, v := range m {
+		if v != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// queryError converts an error of a GraphQL response into an error
+// wrapping the matching SDK error.
+func queryError(e gqlError) error {
+	code := e.Extensions.Code
+	if code == "" {
+		code = e.Type
+	}
+	err := fmt.Errorf("graphql: %s", strings.TrimSpace(code+" "+e.Message))
+	switch strings.ToUpper(code) {
+	case "NOT_FOUND":
+		return fmt.Errorf("%w: %w", datasource.ErrNotFound, err)
+	case "UNAUTHENTICATED", "UNAUTHORIZED", "FORBIDDEN":
+		return fmt.Errorf("%w: %w", datasource.ErrUnauthorized, err)
+	case "RATE_LIMITED", "TOO_MANY_REQUESTS", "THROTTLED":
+		return &datasource.ErrRateLimited{Err: err}
+	case "SERVICE_UNAVAILABLE":
+		return fmt.Errorf("%w: %w", datasource.ErrUnavailable, err)
+	}
+	return err
+}
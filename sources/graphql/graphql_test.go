@@ -0,0 +1,222 @@
+package graphql_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/graphql"
+	"github.com/locus-search/datasource-sdk/sources/restgeneric"
+)
+
+const (
+	searchQuery = `query Search($question: String!, $count: Int!, $tags: [String!], $space: ID) {
+		search(text: $question, first: $count, tags: $tags, space: $space) {
+			nodes { id title summary url lang views }
+		}
+	}`
+	fetchQuery = `query($topicId: ID!) { article(id: $topicId) { paragraphs { body author { name } } } }`
+)
+
+// kb is a fake GraphQL knowledge base API.
+type kb struct {
+	mu       sync.Mutex
+	requests []request
+	headers  []http.Header
+	status   int
+}
+
+type request struct {
+	Query     string         `json:"query"`
+	Variables map[string]any `json:"variables"`
+}
+
+func (k *kb) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	var req request
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&req); err != nil || r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	k.requests = append(k.requests, req)
+	k.headers = append(k.headers, r.Header)
+	w.Header().Set("Content-Type", "application/json")
+	if k.status != 0 {
+		w.WriteHeader(k.status)
+		io.WriteString(w, `{"errors": [{"message": "slow down"}]}`)
+		return
+	}
+	switch {
+	case strings.Contains(req.Query, "__typename"):
+		io.WriteString(w, `{"data": {"__typename": "Query"}}`)
+	case strings.Contains(req.Query, "search("):
+		io.WriteString(w, `{"data": {"search": {"nodes": [
+			{"id": "12", "title": "VPN setup", "summary": "Connect to the VPN first.", "url": "https://kb.example.com/a/12", "lang": "en", "views": 120},
+			{"id": "13", "title": "VPN auf Deutsch", "lang": "de"},
+			{"id": "14", "title": "Proxy settings", "lang": "en"}
+		]}}}`)
+	case strings.Contains(req.Query, "article(") && req.Variables["topicId"] == json.Number("12"):
+		io.WriteString(w, `{"data": {"article": {"paragraphs": [
+			{"body": "Install the client.", "author": {"name": "Ada"}},
+			{"body": "# Troubleshooting\n\n- Restart it", "author": null}
+		]}}}`)
+	case strings.Contains(req.Query, "article("):
+		io.WriteString(w, `{"data": {"article": null}, "errors": [{"message": "no such article", "extensions": {"code": "NOT_FOUND"}}]}`)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+		io.WriteString(w, `{"errors": [{"message": "Cannot query field", "extensions": {"code": "GRAPHQL_VALIDATION_FAILED"}}]}`)
+	}
+}
+
+func newSource(t *testing.T, cfg graphql.Config) (*graphql.Source, *kb) {
+	t.Helper()
+	fake := &kb{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	cfg.URL = srv.URL
+	if cfg.SearchQuery == "" {
+		cfg.SearchQuery = searchQuery
+	}
+	if cfg.FetchQuery == "" {
+		cfg.FetchQuery = fetchQuery
+	}
+	ds := graphql.New(cfg)
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return ds, fake
+}
+
+func TestFetchTopics(t *testing.T) {
+	ds, fake := newSource(t, graphql.Config{
+		Variables:   map[string]any{"space": "IT"},
+		Headers:     map[string]string{"Authorization": "Bearer {{.Credentials}}"},
+		TopicFields: restgeneric.Mapping{Items: "search.nodes", Excerpt: "summary", Language: "lang", Metadata: map[string]string{"views": "views"}},
+		Credentials: "s3cret",
+	})
+	topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{
+		QuestionText:    "vpn",
+		Tags:            []string{"network"},
+		TenantID:        "acme",
+		AcceptLanguages: []string{"en"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 {
+		t.Fatalf("got %d topics, want the two that are not German: %+v", len(topics), topics)
+	}
+	got := topics[0]
+	if got.TopicID != 12 || got.Title != "VPN setup" || got.BodyExcerpt != "Connect to the VPN first." ||
+		got.SourceURL != "https://kb.example.com/a/12" || got.Rank != 1 {
+		t.Errorf("first topic = %+v", got)
+	}
+	if v, _ := got.Metadata.Int("views"); v != 120 {
+		t.Errorf("views = %d", v)
+	}
+	if topics[1].TopicID != 14 || topics[1].Rank != 2 {
+		t.Errorf("second topic = %+v", topics[1])
+	}
+
+	req := fake.requests[len(fake.requests)-1]
+	vars, _ := json.Marshal(req.Variables)
+	if want := `{"count":5,"question":"vpn","space":"IT","tags":["network"]}`; string(vars) != want {
+		t.Errorf("variables = %s, want only the declared ones: %s", vars, want)
+	}
+	if h := fake.headers[len(fake.headers)-1].Get("Authorization"); h != "Bearer s3cret" {
+		t.Errorf("Authorization = %q", h)
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	ds, _ := newSource(t, graphql.Config{
+		DataFields: restgeneric.Mapping{Items: "article.paragraphs", Text: "body", Author: "author.name"},
+	})
+	data, err := ds.FetchData(5, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 {
+		t.Fatalf("got %d items, want 2: %+v", len(data), data)
+	}
+	if d := data[0]; d.DataText != "Install the client." || d.Rank != 1 || d.Author == nil || d.Author.Name != "Ada" {
+		t.Errorf("first item = %+v", d)
+	}
+	if d := data[1]; d.AnswerID != 1 || d.ContentType != datasource.ContentMarkdown || d.Author != nil {
+		t.Errorf("second item = %+v", d)
+	}
+
+	if _, err := ds.FetchData(5, 404); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFromSettings(t *testing.T) {
+	fake := &kb{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	settings, _ := json.Marshal(map[string]any{
+		"url":          srv.URL,
+		"search_query": searchQuery,
+		"fetch_query":  fetchQuery,
+		"headers":      map[string]string{"X-Api-Key": "{{.Credentials}}"},
+		"topic_fields": map[string]any{"items": "$.search.nodes[*]"},
+		"timeout":      "2s",
+	})
+	ds, err := graphql.FromSettings(settings, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	topics, err := ds.FetchTopics(1, datasource.NewQuestionInput{QuestionText: "vpn"})
+	if err != nil || len(topics) != 1 || topics[0].TopicID != 12 {
+		t.Fatalf("FetchTopics = %+v, %v", topics, err)
+	}
+	if h := fake.headers[len(fake.headers)-1].Get("X-Api-Key"); h != "key" {
+		t.Errorf("X-Api-Key = %q", h)
+	}
+
+	if _, err := graphql.FromSettings(json.RawMessage(`{"url": "x", "serach_query": ""}`), ""); err == nil {
+		t.Error("FromSettings with an unknown field succeeded")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ds, fake := newSource(t, graphql.Config{SearchQuery: `query($question: String!) { serach(text: $question) { id } }`})
+	_, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q"})
+	if err == nil || !strings.Contains(err.Error(), "Cannot query field") {
+		t.Errorf("FetchTopics(invalid query) = %v", err)
+	}
+
+	fake.mu.Lock()
+	fake.status = http.StatusTooManyRequests
+	fake.mu.Unlock()
+	var rl *datasource.ErrRateLimited
+	if _, err := ds.FetchData(5, 12); !errors.As(err, &rl) {
+		t.Errorf("FetchData(rate limited) = %v, want ErrRateLimited", err)
+	}
+	if h := ds.HealthCheck(); h.State != datasource.Unhealthy {
+		t.Errorf("HealthCheck = %+v, want unhealthy", h)
+	}
+
+	for name, cfg := range map[string]graphql.Config{
+		"no queries": {URL: "http://localhost"},
+		"bad header": {URL: "http://localhost", SearchQuery: "q", FetchQuery: "q", Headers: map[string]string{"X": "{{.Credentials"}},
+		"bad path":   {URL: "http://localhost", SearchQuery: "q", FetchQuery: "q", TopicFields: restgeneric.Mapping{Title: "$[x]"}},
+	} {
+		if err := graphql.New(cfg).Init(); err == nil {
+			t.Errorf("Init with %s succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,489 @@
+// Package restgeneric is a data source for JSON APIs described entirely by
+// configuration, so a simple search API can be integrated without writing
+// a data source. The operator supplies the requests, as URL, header, and
+// body templates, and maps fields of the responses to the fields of
+// topics and data items with JSONPath or templates:
+//
+//	ds := restgeneric.New(restgeneric.Config{
+//	    Topics:  restgeneric.Request{URL: "https://kb.example.com/api/search?q={{query .Question}}&limit={{.Count}}"},
+//	    Data:    restgeneric.Request{URL: "https://kb.example.com/api/articles/{{.TopicID}}/paragraphs"},
+//	    Headers: map[string]string{"Authorization": "Bearer {{.Credentials}}"},
+//	    TopicFields: restgeneric.Mapping{
+//	        Items:   "$.results",
+//	        Excerpt: "$.highlight.body[0]",
+//	        URL:     "https://kb.example.com/a/{{.id}}",
+//	    },
+//	    DataFields:  restgeneric.Mapping{Items: "$.paragraphs[*]", Text: "body"},
+//	    Credentials: os.Getenv("KB_TOKEN"),
+//	})
+//
+// Templates use text/template with the fields of TemplateData and the
+// functions query and path (URL escaping), json, and join. A Mapping field
+// holding "{{" is a template executed with the result object; any other
+// is a JSONPath into the result, in which "$", ".name", "['name']",
+// "[index]", and "[*]" are supported, and a path without a leading "$"
+// is relative to the result, so "title" is "$.title".
+//
+// The Config has JSON field names, and FromSettings builds a Source from
+// them, so the source can be listed in a config descriptor:
+//
+//	loader := &config.Loader{Types: map[string]config.Constructor{"rest": restgeneric.FromSettings}}
+package restgeneric
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+	"github.com/locus-search/datasource-sdk/internal/fieldmap"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultID        = fieldmap.DefaultID
+	DefaultTitle     = fieldmap.DefaultTitle
+	DefaultExcerpt   = fieldmap.DefaultExcerpt
+	DefaultText      = fieldmap.DefaultText
+	DefaultURL       = fieldmap.DefaultURL
+	DefaultScore     = fieldmap.DefaultScore
+	DefaultSite      = fieldmap.DefaultSite
+	DefaultLanguage  = fieldmap.DefaultLanguage
+	DefaultCreatedAt = fieldmap.DefaultCreatedAt
+	DefaultUpdatedAt = fieldmap.DefaultUpdatedAt
+	DefaultAuthor    = fieldmap.DefaultAuthor
+	DefaultTimeout   = 10 * time.Second
+)
+
+// maxResponseBytes bounds response bodies read from the API.
+const maxResponseBytes = 16 << 20
+
+// Request describes an HTTP request. Its URL and Body are templates
+// executed with TemplateData.
+type Request struct {
+	// Method is the HTTP method
+	// Defaults to GET, or POST if Body is set
+	Method string `json:"method,omitempty"`
+
+	// URL is the address requested
+	URL string `json:"url"`
+
+	// Body is sent as the request's JSON body, such as
+	// `{"query": {{json .Question}}, "size": {{.Count}}}`
+	// Optional
+	Body string `json:"body,omitempty"`
+}
+
+// TemplateData is what the templates of requests and headers are executed
+// with.
+type TemplateData struct {
+	// Question is the question text, with the error lines of its
+	// attachments; see datasource.NewQuestionInput.SearchText
+	Question string
+
+	// Tags are the question's tags
+	Tags []string
+
+	// Count is the number of results wanted
+	Count int
+
+	// TopicID is the topic whose data items are fetched, in Config.Data
+	TopicID int64
+
+	// TenantID is NewQuestionInput.TenantID
+	TenantID string
+
+	// Language is the first of the accepted languages, or ""
+	Language string
+
+	// Credentials is Config.Credentials
+	Credentials string
+}
+
+// Mapping maps the fields of result objects to the fields of topics or
+// data items. Each field is a JSONPath into a result or, if it contains
+// "{{", a template executed with the result. Only the ID of topics is
+// required in results; the other fields are read when present.
+type Mapping struct {
+	// Items is the JSONPath of the results in a response, either an array
+	// or a path selecting several values with [*]
+	// Defaults to the whole response, an array of results or a single
+	// result
+	Items string `json:"items,omitempty"`
+
+	// ID is the integer ID of a topic or data item; data items without
+	// one are numbered by their position
+	// Defaults to DefaultID
+	ID string `json:"id,omitempty"`
+
+	// Title is a topic's title
+	// Defaults to DefaultTitle
+	Title string `json:"title,omitempty"`
+
+	// Excerpt is plain text from a topic's body
+	// Defaults to DefaultExcerpt
+	Excerpt string `json:"excerpt,omitempty"`
+
+	// Text is a data item's text
+	// Defaults to DefaultText
+	Text string `json:"text,omitempty"`
+
+	// URL is the address of a result
+	// Defaults to DefaultURL
+	URL string `json:"url,omitempty"`
+
+	// Score is the relevance score of a result
+	// Defaults to DefaultScore
+	Score string `json:"score,omitempty"`
+
+	// Site, Language, CreatedAt, UpdatedAt, and Author are the like-named
+	// fields; times are read from Unix seconds, or RFC 3339 or
+	// "2006-01-02 15:04:05" text
+	// Default to DefaultSite, DefaultLanguage, DefaultCreatedAt,
+	// DefaultUpdatedAt, and DefaultAuthor
+	Site      string `json:"site,omitempty"`
+	Language  string `json:"language,omitempty"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
+	Author    string `json:"author,omitempty"`
+
+	// Metadata maps metadata keys to the fields copied into the
+	// Metadata of results
+	// Optional
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Config configures a Source.
+type Config struct {
+	// Topics is the request searching for the topics answering a
+	// question, best first
+	Topics Request `json:"topics"`
+
+	// Data is the request fetching the data items of a topic, best first
+	Data Request `json:"data"`
+
+	// Health is the request HealthCheck sends, healthy on any 2xx status
+	// Optional - without it the source reports healthy without a request
+	Health Request `json:"health,omitempty"`
+
+	// Headers are sent with every request; their values are templates,
+	// such as "Bearer {{.Credentials}}"
+	// Optional
+	Headers map[string]string `json:"headers,omitempty"`
+
+	// TopicFields maps the results of Topics to topics
+	TopicFields Mapping `json:"topic_fields,omitempty"`
+
+	// DataFields maps the results of Data to data items
+	DataFields Mapping `json:"data_fields,omitempty"`
+
+	// ContentType is the format of data items' text
+	// Defaults to detecting it with content.Detect
+	ContentType datasource.ContentType `json:"content_type,omitempty"`
+
+	// Credentials is the secret templates read as {{.Credentials}}; it is
+	// never read from JSON
+	// Optional
+	Credentials string `json:"-"`
+
+	// Timeout bounds each request, in addition to NewQuestionInput.Budget
+	// Defaults to DefaultTimeout
+	Timeout time.Duration `json:"-"`
+
+	// HTTPClient makes the requests
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client `json:"-"`
+}
+
+func (c Config) withDefaults() Config {
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+// FromSettings returns a Source for the JSON form of a Config, with a
+// "timeout" given as a duration string such as "5s", and the credentials.
+// It has the signature of config.Constructor.
+func FromSettings(settings json.RawMessage, credentials string) (datasource.DataSource, error) {
+	var s struct {
+		Config
+		Timeout string `json:"timeout,omitempty"`
+	}
+	dec := json.NewDecoder(bytes.NewReader(settings))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, fmt.Errorf("restgeneric: settings: %w", err)
+	}
+	if s.Timeout != "" {
+		d, err := time.ParseDuration(s.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("restgeneric: settings: timeout: %w", err)
+		}
+		s.Config.Timeout = d
+	}
+	s.Config.Credentials = credentials
+	return New(s.Config), nil
+}
+
+// Source is a DataSource calling a JSON API as its Config describes. A
+// Source is safe for concurrent use.
+type Source struct {
+	cfg Config
+
+	// Set by Init.
+	topics, data, health *request
+	topicFields          *fieldmap.Mapping
+	dataFields           *fieldmap.Mapping
+}
+
+var (
+	_ datasource.DataSource    = (*Source)(nil)
+	_ datasource.HealthChecker = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults()}
+}
+
+// Init compiles the templates and mappings, and sends the Health request
+// if there is one.
+func (s *Source) Init() error {
+	if s.cfg.Topics.URL == "" || s.cfg.Data.URL == "" {
+		return errors.New("restgeneric: both Topics and Data URLs are required")
+	}
+	headers, err := compileHeaders(s.cfg.Headers)
+	if err != nil {
+		return err
+	}
+	if s.topics, err = compileRequest("Topics", s.cfg.Topics, headers); err != nil {
+		return err
+	}
+	if s.data, err = compileRequest("Data", s.cfg.Data, headers); err != nil {
+		return err
+	}
+	if s.cfg.Health.URL != "" {
+		if s.health, err = compileRequest("Health", s.cfg.Health, headers); err != nil {
+			return err
+		}
+	}
+	if s.topicFields, err = fieldmap.Compile("TopicFields", fieldmap.Spec(s.cfg.TopicFields)); err != nil {
+		return fmt.Errorf("restgeneric: %w", err)
+	}
+	if s.dataFields, err = fieldmap.Compile("DataFields", fieldmap.Spec(s.cfg.DataFields)); err != nil {
+		return fmt.Errorf("restgeneric: %w", err)
+	}
+	if s.health == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	_, err = s.do(ctx, s.health, s.templateData())
+	return err
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck sends the Health request.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	if s.topics == nil {
+		return datasource.HealthStatus{State: datasource.Unhealthy, Error: "restgeneric: not initialized"}
+	}
+	if s.health == nil {
+		return datasource.HealthStatus{State: datasource.Healthy}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	_, err := s.do(ctx, s.health, s.templateData())
+	h := datasource.HealthStatus{State: datasource.Healthy, Latency: time.Since(start)}
+	if err != nil {
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	}
+	return h
+}
+
+// FetchTopics sends the Topics request. Topics are ranked in the order of
+// the results, and narrowed by the input's accepted languages and
+// filters.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if s.topics == nil {
+		return nil, errors.New("restgeneric: not initialized")
+	}
+	question := input.SearchText()
+	if count <= 0 || question == "" {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	ctx, cancel := input.Budget.Context(context.Background())
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	data := s.templateData()
+	data.Question, data.Tags, data.Count, data.TenantID = question, input.Tags, count, input.TenantID
+	if len(input.AcceptLanguages) > 0 {
+		data.Language = input.AcceptLanguages[0]
+	}
+	resp, err := s.do(ctx, s.topics, data)
+	if err != nil {
+		return nil, err
+	}
+	topics := []datasource.DataSourceTopic{}
+	for _, r := range s.topicFields.Results(resp) {
+		t, err := s.topicFields.Topic(r)
+		if err != nil {
+			return nil, fmt.Errorf("restgeneric: Topics: %w", err)
+		}
+		if datasource.AcceptsLanguage(input.AcceptLanguages, t.Language) {
+			topics = append(topics, t)
+		}
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// FetchData sends the Data request. Items are ranked in the order of the
+// results.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if s.data == nil {
+		return nil, errors.New("restgeneric: not initialized")
+	}
+	if count <= 0 {
+		return []datasource.DataSourceData{}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	data := s.templateData()
+	data.TopicID, data.Count = topicID, count
+	resp, err := s.do(ctx, s.data, data)
+	if err != nil {
+		return nil, err
+	}
+	results := s.dataFields.Results(resp)
+	items := make([]datasource.DataSourceData, 0, min(count, len(results)))
+	for i, r := range results[:min(count, len(results))] {
+		item := s.dataFields.Data(r, i, s.cfg.ContentType)
+		item.Rank = i + 1
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func (s *Source) templateData() TemplateData {
+	return TemplateData{Credentials: s.cfg.Credentials}
+}
+
+// do sends the request executed with data and decodes its JSON response,
+// with numbers as json.Number.
+func (s *Source) do(ctx context.Context, r *request, data TemplateData) (any, error) {
+	req, err := r.build(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("restgeneric: %w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("restgeneric: %s: %w", r.name, httpx.StatusError(resp))
+	}
+	var v any
+	dec := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("restgeneric: %s: decode response: %w", r.name, err)
+	}
+	return v, nil
+}
+
+// request is a compiled Request.
+type request struct {
+	name      string
+	method    string
+	url, body *template.Template
+	headers   map[string]*template.Template
+}
+
+func compileRequest(name string, r Request, headers map[string]*template.Template) (*request, error) {
+	c := &request{name: name, method: r.Method, headers: headers}
+	var err error
+	if c.url, err = template.New("url").Funcs(fieldmap.Funcs).Parse(r.URL); err != nil {
+		return nil, fmt.Errorf("restgeneric: %s URL: %w", name, err)
+	}
+	if r.Body != "" {
+		if c.body, err = template.New("body").Funcs(fieldmap.Funcs).Parse(r.Body); err != nil {
+			return nil, fmt.Errorf("restgeneric: %s body: %w", name, err)
+		}
+	}
+	if c.method == "" {
+		c.method = http.MethodGet
+		if c.body != nil {
+			c.method = http.MethodPost
+		}
+	}
+	return c, nil
+}
+
+func compileHeaders(headers map[string]string) (map[string]*template.Template, error) {
+	out := make(map[string]*template.Template, len(headers))
+	for k, v := range headers {
+		t, err := template.New(k).Funcs(fieldmap.Funcs).Parse(v)
+		if err != nil {
+			return nil, fmt.Errorf("restgeneric: header %s: %w", k, err)
+		}
+		out[k] = t
+	}
+	return out, nil
+}
+
+// build executes the templates of the request with data.
+func (r *request) build(ctx context.Context, data TemplateData) (*http.Request, error) {
+	u, err := fieldmap.Execute(r.url, data)
+	if err != nil {
+		return nil, fmt.Errorf("restgeneric: %s URL: %w", r.name, err)
+	}
+	var body io.Reader
+	if r.body != nil {
+		b, err := fieldmap.Execute(r.body, data)
+		if err != nil {
+			return nil, fmt.Errorf("restgeneric: %s body: %w", r.name, err)
+		}
+		body = strings.NewReader(b)
+	}
+	req, err := http.NewRequestWithContext(ctx, r.method, u, body)
+	if err != nil {
+		return nil, fmt.Errorf("restgeneric: %s: %w", r.name, err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for k, t := range r.headers {
+		v, err := fieldmap.Execute(t, data)
+		if err != nil {
+			return nil, fmt.Errorf("restgeneric: header %s: %w", k, err)
+		}
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
@@ -0,0 +1,203 @@
+package restgeneric_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/restgeneric"
+)
+
+// kb is a fake knowledge base API.
+type kb struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	bodies   []string
+}
+
+func (k *kb) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	body, _ := io.ReadAll(r.Body)
+	k.requests = append(k.requests, r)
+	k.bodies = append(k.bodies, string(body))
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/health":
+		io.WriteString(w, `{"ok": true}`)
+	case "/search":
+		io.WriteString(w, `{"total": 3, "results": [
+			{"id": "12", "title": "VPN setup", "highlight": {"body": ["Connect to the VPN first."]},
+			 "score": 2.5, "created": "2026-01-02T03:04:05Z", "lang": "en", "views": 120, "team": {"name": "IT"}},
+			{"id": 13, "title": "VPN auf Deutsch", "score": 1.5, "lang": "de"},
+			{"id": 9007199254740993, "title": "Big ID", "score": "0.5", "created": 1767225600}
+		]}`)
+	case "/articles/12/paragraphs":
+		io.WriteString(w, `[
+			{"body": "Install the client.", "author": {"name": "Ada"}},
+			{"body": "# Troubleshooting\n\n- Restart it"}
+		]`)
+	case "/bad":
+		io.WriteString(w, `{"results": [{"title": "no id"}]}`)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newSource(t *testing.T, cfg restgeneric.Config) (*restgeneric.Source, *kb, string) {
+	t.Helper()
+	fake := &kb{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	for _, r := range []*restgeneric.Request{&cfg.Topics, &cfg.Data, &cfg.Health} {
+		if r.URL != "" {
+			r.URL = srv.URL + r.URL
+		}
+	}
+	ds := restgeneric.New(cfg)
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return ds, fake, srv.URL
+}
+
+func TestFetchTopics(t *testing.T) {
+	ds, fake, _ := newSource(t, restgeneric.Config{
+		Topics:  restgeneric.Request{URL: "/search?q={{query .Question}}&n={{.Count}}&tags={{join .Tags \",\"}}"},
+		Data:    restgeneric.Request{URL: "/articles/{{.TopicID}}/paragraphs"},
+		Headers: map[string]string{"Authorization": "Bearer {{.Credentials}}"},
+		TopicFields: restgeneric.Mapping{
+			Items:     "$.results",
+			Excerpt:   "$.highlight.body[0]",
+			URL:       "https://kb.example.com/a/{{.id}}",
+			CreatedAt: "created",
+			Language:  "lang",
+			Metadata:  map[string]string{"views": "views", "team": "$.team.name"},
+		},
+		Credentials: "s3cret",
+	})
+	topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{
+		QuestionText:    "vpn & proxy",
+		Tags:            []string{"network", "remote"},
+		AcceptLanguages: []string{"en"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 {
+		t.Fatalf("got %d topics, want the two that are not German: %+v", len(topics), topics)
+	}
+	got := topics[0]
+	if got.TopicID != 12 || got.Title != "VPN setup" || got.Topic != "VPN setup" || got.BodyExcerpt != "Connect to the VPN first." ||
+		got.SourceURL != "https://kb.example.com/a/12" || got.Score != 2.5 || got.Language != "en" ||
+		got.CreatedAt.Day() != 2 || got.Rank != 1 {
+		t.Errorf("first topic = %+v", got)
+	}
+	if v, _ := got.Metadata.Int("views"); v != 120 {
+		t.Errorf("views = %d", v)
+	}
+	if v, _ := got.Metadata.String("team"); v != "IT" {
+		t.Errorf("team = %q", v)
+	}
+	got = topics[1]
+	if got.TopicID != 9007199254740993 || got.Score != 0.5 || got.CreatedAt.Year() != 2026 || got.Rank != 2 {
+		t.Errorf("second topic = %+v", got)
+	}
+
+	r := fake.requests[0]
+	if q := r.URL.Query(); q.Get("q") != "vpn & proxy" || q.Get("n") != "5" || q.Get("tags") != "network,remote" {
+		t.Errorf("query = %v", q)
+	}
+	if r.Header.Get("Authorization") != "Bearer s3cret" {
+		t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	ds, _, _ := newSource(t, restgeneric.Config{
+		Topics:     restgeneric.Request{URL: "/search"},
+		Data:       restgeneric.Request{URL: "/articles/{{.TopicID}}/paragraphs"},
+		DataFields: restgeneric.Mapping{Text: "body", Author: "author.name", URL: "https://kb.example.com/a/12#p{{.body | len}}"},
+	})
+	data, err := ds.FetchData(5, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(data) != 2 {
+		t.Fatalf("got %d items, want 2: %+v", len(data), data)
+	}
+	if d := data[0]; d.DataText != "Install the client." || d.AnswerID != 0 || d.Rank != 1 ||
+		d.Author == nil || d.Author.Name != "Ada" || d.SourceURL != "https://kb.example.com/a/12#p19" {
+		t.Errorf("first item = %+v", d)
+	}
+	if d := data[1]; d.AnswerID != 1 || d.ContentType != datasource.ContentMarkdown || d.Author != nil {
+		t.Errorf("second item = %+v", d)
+	}
+
+	if _, err := ds.FetchData(5, 404); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData(missing) = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFromSettings(t *testing.T) {
+	fake := &kb{}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+	settings, _ := json.Marshal(map[string]any{
+		"topics":       map[string]any{"url": srv.URL + "/search", "body": `{"query": {{json .Question}}, "size": {{.Count}}}`},
+		"data":         map[string]any{"url": srv.URL + "/articles/{{.TopicID}}/paragraphs"},
+		"health":       map[string]any{"url": srv.URL + "/health"},
+		"headers":      map[string]string{"X-Api-Key": "{{.Credentials}}"},
+		"topic_fields": map[string]any{"items": "$.results[*]"},
+		"timeout":      "2s",
+	})
+	ds, err := restgeneric.FromSettings(settings, "key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	topics, err := ds.FetchTopics(1, datasource.NewQuestionInput{QuestionText: `say "hi"`})
+	if err != nil || len(topics) != 1 || topics[0].TopicID != 12 {
+		t.Fatalf("FetchTopics = %+v, %v", topics, err)
+	}
+	r := fake.requests[len(fake.requests)-1]
+	if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Api-Key") != "key" {
+		t.Errorf("request = %s %v", r.Method, r.Header)
+	}
+	if body := fake.bodies[len(fake.bodies)-1]; body != `{"query": "say \"hi\"", "size": 1}` {
+		t.Errorf("body = %s", body)
+	}
+
+	if _, err := restgeneric.FromSettings(json.RawMessage(`{"topics": {"url": "x"}, "tpoic_fields": {}}`), ""); err == nil {
+		t.Error("FromSettings with an unknown field succeeded")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ds, _, _ := newSource(t, restgeneric.Config{
+		Topics: restgeneric.Request{URL: "/bad"},
+		Data:   restgeneric.Request{URL: "/articles/{{.TopicID}}/paragraphs"},
+	})
+	if _, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q"}); err == nil || !strings.Contains(err.Error(), "integer ID") {
+		t.Errorf("FetchTopics(result without an ID) = %v", err)
+	}
+
+	for name, cfg := range map[string]restgeneric.Config{
+		"no URLs":       {},
+		"bad template":  {Topics: restgeneric.Request{URL: "{{.Question"}, Data: restgeneric.Request{URL: "x"}},
+		"bad path":      {Topics: restgeneric.Request{URL: "x"}, Data: restgeneric.Request{URL: "x"}, TopicFields: restgeneric.Mapping{Title: "$[x]"}},
+		"template item": {Topics: restgeneric.Request{URL: "x"}, Data: restgeneric.Request{URL: "x"}, DataFields: restgeneric.Mapping{Items: "{{.x}}"}},
+	} {
+		if err := restgeneric.New(cfg).Init(); err == nil {
+			t.Errorf("Init with %s succeeded", name)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package sqlds
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Placeholder is the bind parameter syntax of a database driver.
+type Placeholder int
+
+// Placeholder syntaxes, named after a database using each.
+const (
+	// Question binds "?", as MySQL and SQLite drivers expect
+	Question Placeholder = iota
+
+	// Dollar binds "$1", "$2", and so on, as PostgreSQL drivers expect
+	Dollar
+
+	// AtP binds "@p1", "@p2", and so on, as SQL Server drivers expect
+	AtP
+
+	// Colon binds ":1", ":2", and so on, as Oracle drivers expect
+	Colon
+)
+
+// marker returns the placeholder of the nth parameter, counting from 1.
+func (p Placeholder) marker(n int) string {
+	switch p {
+	case Dollar:
+		return "$" + strconv.Itoa(n)
+	case AtP:
+		return "@p" + strconv.Itoa(n)
+	case Colon:
+		return ":" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
+
+// statement is a query whose named parameters were replaced by the
+// driver's placeholders.
+type statement struct {
+	text   string
+	params []string // parameter names, in placeholder order
+}
+
+// parse replaces the :name parameters of query with placeholders, leaving
+// string literals, quoted identifiers, comments, and "::" casts alone. It
+// fails on parameters not in allowed.
+func parse(query string, p Placeholder, allowed ...string) (statement, error) {
+	var st statement
+	var b strings.Builder
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			end := closing(query, i+1, c)
+			b.WriteString(query[i:end])
+			i = end
+		case strings.HasPrefix(query[i:], "--"):
+			end := strings.IndexByte(query[i:], '\n')
+			if end < 0 {
+				end = len(query) - i
+			}
+			b.WriteString(query[i : i+end])
+			i += end
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				end = len(query) - i - 4
+			}
+			b.WriteString(query[i : i+end+4])
+			i += end + 4
+		case strings.HasPrefix(query[i:], "::"):
+			b.WriteString("::")
+			i += 2
+		case c == ':' && i+1 < len(query) && isNameStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && isNamePart(query[j]) {
+				j++
+			}
+			name := query[i+1 : j]
+			if !contains(allowed, name) {
+				return statement{}, fmt.Errorf("unknown parameter :%s (want one of :%s)", name, strings.Join(allowed, ", :"))
+			}
+			st.params = append(st.params, name)
+			b.WriteString(p.marker(len(st.params)))
+			i = j
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	st.text = b.String()
+	return st, nil
+}
+
+// closing returns the index after the quote closing the literal starting
+// at i. Doubled quotes inside the literal escape it.
+func closing(query string, i int, quote byte) int {
+	for i < len(query) {
+		if query[i] != quote {
+			i++
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == quote {
+			i += 2
+			continue
+		}
+		return i + 1
+	}
+	return len(query)
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isNamePart(c byte) bool {
+	return isNameStart(c) || '0' <= c && c <= '9'
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// args returns the arguments of the statement's placeholders.
+func (st statement) args(values map[string]any) []any {
+	args := make([]any, len(st.params))
+	for i, name := range st.params {
+		args[i] = values[name]
+	}
+	return args
+}
+
+// row is a result row by lowercased column name.
+type row map[string]any
+
+// readRows reads every row of rows.
+func readRows(rows *sql.Rows) ([]row, error) {
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var out []row
+	for rows.Next() {
+		values := make([]any, len(cols))
+		dest := make([]any, len(cols))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		r := make(row, len(cols))
+		for i, c := range cols {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			r[strings.ToLower(c)] = values[i]
+		}
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// str returns the column as a string, or "" if it is missing or NULL.
+func (r row) str(col string) string {
+	switch v := r[col].(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// int returns the column as an integer.
+func (r row) int(col string) (int64, bool) {
+	switch v := r[col].(type) {
+	case int64:
+		return v, true
+	case float64:
+		return int64(v), true
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// float returns the column as a number, or 0.
+func (r row) float(col string) float64 {
+	switch v := r[col].(type) {
+	case float64:
+		return v
+	case int64:
+		return float64(v)
+	case string:
+		f, _ := strconv.ParseFloat(strings.TrimSpace(v), 64)
+		return f
+	}
+	return 0
+}
+
+// timeLayouts are the layouts of times read from text columns.
+var timeLayouts = []string{time.RFC3339Nano, time.DateTime, time.DateOnly}
+
+// time returns the column as a time, or the zero time.
+func (r row) time(col string) time.Time {
+	switch v := r[col].(type) {
+	case time.Time:
+		return v
+	case int64:
+		return time.Unix(v, 0).UTC()
+	case string:
+		for _, layout := range timeLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(v)); err == nil {
+				return t
+			}
+		}
+	}
+	return time.Time{}
+}
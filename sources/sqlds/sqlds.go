@@ -0,0 +1,365 @@
+// Package sqlds is a data source running operator-supplied SQL queries
+// against any database/sql driver, so knowledge held in internal tables
+// can be searched without writing a data source:
+//
+//	db, err := sql.Open("pgx", os.Getenv("KB_DSN"))
+//	...
+//	ds := sqlds.New(sqlds.Config{
+//	    DB:          db,
+//	    Placeholder: sqlds.Dollar,
+//	    TopicsQuery: `SELECT id, title, 'https://kb.example.com/a/' || id AS url,
+//	                         ts_rank(search, q) AS score
+//	                  FROM articles, plainto_tsquery(:question) q
+//	                  WHERE search @@ q ORDER BY score DESC LIMIT :count`,
+//	    DataQuery:   `SELECT id, body AS text FROM paragraphs
+//	                  WHERE article_id = :topic_id ORDER BY position LIMIT :count`,
+//	})
+//
+// Queries name their parameters with a colon, as in :question, and the
+// source binds them with the driver's placeholder syntax. Rows are read
+// by column name (see Columns), so queries alias their columns to the
+// mapped names; other columns are copied into the Metadata of results.
+package sqlds
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultID        = "id"
+	DefaultTitle     = "title"
+	DefaultText      = "text"
+	DefaultURL       = "url"
+	DefaultScore     = "score"
+	DefaultSite      = "site"
+	DefaultLanguage  = "language"
+	DefaultCreatedAt = "created_at"
+	DefaultUpdatedAt = "updated_at"
+	DefaultAuthor    = "author"
+	DefaultTimeout   = 10 * time.Second
+)
+
+// Parameters of TopicsQuery.
+const (
+	ParamQuestion = "question"  // the question text
+	ParamPattern  = "pattern"   // the question text as a LIKE pattern, "%text%"
+	ParamCount    = "count"     // the number of results wanted
+	ParamTenantID = "tenant_id" // NewQuestionInput.TenantID
+	ParamAskedBy  = "asked_by"  // NewQuestionInput.AskedBy, or NULL
+	ParamLanguage = "language"  // the first accepted language, or ""
+)
+
+// Parameters of DataQuery, besides ParamCount.
+const (
+	ParamTopicID = "topic_id" // the topic's ID
+)
+
+// Columns names the result columns read into the fields of topics and
+// data items. Names are compared case-insensitively. Only the ID column
+// is required in results; the others are read when present.
+type Columns struct {
+	// ID is the integer column of the topic ID (TopicsQuery) or data
+	// item ID (DataQuery)
+	// Defaults to DefaultID
+	ID string
+
+	// Title is the column of a topic's title
+	// Defaults to DefaultTitle
+	Title string
+
+	// Text is the column of a data item's text
+	// Defaults to DefaultText
+	Text string
+
+	// URL is the column of the address of results
+	// Defaults to DefaultURL
+	URL string
+
+	// Score is the column of the relevance score of results
+	// Defaults to DefaultScore
+	Score string
+
+	// Site, Language, CreatedAt, UpdatedAt, and Author are the columns of
+	// the like-named fields; times are read from time values, Unix
+	// seconds, or RFC 3339 or "2006-01-02 15:04:05" text
+	// Default to DefaultSite, DefaultLanguage, DefaultCreatedAt,
+	// DefaultUpdatedAt, and DefaultAuthor
+	Site, Language, CreatedAt, UpdatedAt, Author string
+}
+
+func (c Columns) withDefaults() Columns {
+	for _, f := range []struct {
+		name *string
+		def  string
+	}{
+		{&c.ID, DefaultID},
+		{&c.Title, DefaultTitle},
+		{&c.Text, DefaultText},
+		{&c.URL, DefaultURL},
+		{&c.Score, DefaultScore},
+		{&c.Site, DefaultSite},
+		{&c.Language, DefaultLanguage},
+		{&c.CreatedAt, DefaultCreatedAt},
+		{&c.UpdatedAt, DefaultUpdatedAt},
+		{&c.Author, DefaultAuthor},
+	} {
+		if *f.name == "" {
+			*f.name = f.def
+		}
+		*f.name = strings.ToLower(*f.name)
+	}
+	return c
+}
+
+// mapped reports whether col is one of the mapped columns.
+func (c Columns) mapped(col string) bool {
+	switch col {
+	case c.ID, c.Title, c.Text, c.URL, c.Score, c.Site, c.Language, c.CreatedAt, c.UpdatedAt, c.Author:
+		return true
+	}
+	return false
+}
+
+// Config configures a Source.
+type Config struct {
+	// DB is the database to query, opened with the driver of the
+	// operator's choice
+	DB *sql.DB
+
+	// TopicsQuery returns the topics answering a question, best first. It
+	// may use the parameters ParamQuestion, ParamPattern, ParamCount,
+	// ParamTenantID, ParamAskedBy, and ParamLanguage
+	TopicsQuery string
+
+	// DataQuery returns the data items of a topic, best first. It may use
+	// the parameters ParamTopicID and ParamCount
+	DataQuery string
+
+	// Placeholder is the driver's bind parameter syntax
+	// Defaults to Question
+	Placeholder Placeholder
+
+	// Columns maps result columns to the fields of results
+	Columns Columns
+
+	// ContentType is the format of the text column
+	// Defaults to detecting it with content.Detect
+	ContentType datasource.ContentType
+
+	// Timeout bounds each query, in addition to NewQuestionInput.Budget
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+}
+
+func (c Config) withDefaults() Config {
+	c.Columns = c.Columns.withDefaults()
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	return c
+}
+
+// Source is a DataSource answering questions with SQL queries. A Source is
+// safe for concurrent use.
+type Source struct {
+	cfg          Config
+	topics, data statement // set by Init
+}
+
+var (
+	_ datasource.DataSource    = (*Source)(nil)
+	_ datasource.HealthChecker = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults()}
+}
+
+// Init checks the queries' parameters and that the database can be
+// reached.
+func (s *Source) Init() error {
+	if s.cfg.DB == nil {
+		return errors.New("sqlds: no database configured")
+	}
+	if s.cfg.TopicsQuery == "" || s.cfg.DataQuery == "" {
+		return errors.New("sqlds: both TopicsQuery and DataQuery are required")
+	}
+	var err error
+	s.topics, err = parse(s.cfg.TopicsQuery, s.cfg.Placeholder,
+		ParamQuestion, ParamPattern, ParamCount, ParamTenantID, ParamAskedBy, ParamLanguage)
+	if err != nil {
+		return fmt.Errorf("sqlds: TopicsQuery: %w", err)
+	}
+	s.data, err = parse(s.cfg.DataQuery, s.cfg.Placeholder, ParamTopicID, ParamCount)
+	if err != nil {
+		return fmt.Errorf("sqlds: DataQuery: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	if err := s.cfg.DB.PingContext(ctx); err != nil {
+		return fmt.Errorf("sqlds: %w: %w", datasource.ErrUnavailable, err)
+	}
+	return nil
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck pings the database.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	if s.cfg.DB == nil {
+		return datasource.HealthStatus{State: datasource.Unhealthy, Error: "sqlds: no database configured"}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := s.cfg.DB.PingContext(ctx)
+	h := datasource.HealthStatus{State: datasource.Healthy, Latency: time.Since(start)}
+	if err != nil {
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	}
+	return h
+}
+
+// FetchTopics runs TopicsQuery. Topics are ranked in the order of its
+// rows, and narrowed by the input's accepted languages and filters.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if count <= 0 || strings.TrimSpace(input.QuestionText) == "" {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	var askedBy any
+	if input.AskedBy != nil {
+		askedBy = *input.AskedBy
+	}
+	var language string
+	if len(input.AcceptLanguages) > 0 {
+		language = input.AcceptLanguages[0]
+	}
+	rows, err := s.query(input.Budget, s.topics, map[string]any{
+		ParamQuestion: input.QuestionText,
+		ParamPattern:  "%" + input.QuestionText + "%",
+		ParamCount:    count,
+		ParamTenantID: input.TenantID,
+		ParamAskedBy:  askedBy,
+		ParamLanguage: language,
+	})
+	if err != nil {
+		return nil, err
+	}
+	c := s.cfg.Columns
+	topics := make([]datasource.DataSourceTopic, 0, len(rows))
+	for _, r := range rows {
+		id, ok := r.int(c.ID)
+		if !ok {
+			return nil, fmt.Errorf("sqlds: TopicsQuery: row without an integer %s column", c.ID)
+		}
+		t := datasource.DataSourceTopic{
+			Topic:     r.str(c.Title),
+			SourceURL: r.str(c.URL),
+			Site:      r.str(c.Site),
+			TopicID:   id,
+			Score:     r.float(c.Score),
+			Metadata:  s.metadata(r),
+			Language:  r.str(c.Language),
+			CreatedAt: r.time(c.CreatedAt),
+			UpdatedAt: r.time(c.UpdatedAt),
+		}
+		if datasource.AcceptsLanguage(input.AcceptLanguages, t.Language) {
+			topics = append(topics, t)
+		}
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// FetchData runs DataQuery. Items are ranked in the order of its rows.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if count <= 0 {
+		return []datasource.DataSourceData{}, nil
+	}
+	rows, err := s.query(datasource.Budget{}, s.data, map[string]any{ParamTopicID: topicID, ParamCount: count})
+	if err != nil {
+		return nil, err
+	}
+	c := s.cfg.Columns
+	items := make([]datasource.DataSourceData, 0, min(count, len(rows)))
+	for _, r := range rows {
+		if len(items) >= count {
+			break
+		}
+		id, ok := r.int(c.ID)
+		if !ok {
+			return nil, fmt.Errorf("sqlds: DataQuery: row without an integer %s column", c.ID)
+		}
+		text := r.str(c.Text)
+		ct := s.cfg.ContentType
+		if ct == "" {
+			ct = content.Detect(text)
+		}
+		item := datasource.DataSourceData{
+			DataText:    text,
+			ContentType: ct,
+			SourceURL:   r.str(c.URL),
+			Site:        r.str(c.Site),
+			AnswerID:    id,
+			Score:       r.float(c.Score),
+			Rank:        len(items) + 1,
+			Metadata:    s.metadata(r),
+			Language:    r.str(c.Language),
+			CreatedAt:   r.time(c.CreatedAt),
+			UpdatedAt:   r.time(c.UpdatedAt),
+		}
+		if author := r.str(c.Author); author != "" {
+			item.Author = &datasource.Author{Name: author}
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// query runs the statement with the parameter values, within the budget
+// and Config.Timeout.
+func (s *Source) query(budget datasource.Budget, st statement, values map[string]any) ([]row, error) {
+	if s.cfg.DB == nil {
+		return nil, errors.New("sqlds: no database configured")
+	}
+	ctx, cancel := budget.Context(context.Background())
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	rows, err := s.cfg.DB.QueryContext(ctx, st.text, st.args(values)...)
+	if err != nil {
+		return nil, fmt.Errorf("sqlds: %w", err)
+	}
+	out, err := readRows(rows)
+	if err != nil {
+		return nil, fmt.Errorf("sqlds: %w", err)
+	}
+	return out, nil
+}
+
+// metadata returns the row's unmapped, non-NULL columns.
+func (s *Source) metadata(r row) datasource.Metadata {
+	var m datasource.Metadata
+	for col, v := range r {
+		if v != nil && !s.cfg.Columns.mapped(col) {
+			m.Set(col, v)
+		}
+	}
+	return m
+}
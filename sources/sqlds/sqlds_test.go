@@ -0,0 +1,222 @@
+package sqlds_test
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/sqlds"
+)
+
+// fakeDriver answers queries with the handler registered under the DSN.
+type fakeDriver struct{}
+
+// result is the answer to a query.
+type result struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+// handler answers a query with its arguments.
+type handler func(query string, args []driver.Value) (result, error)
+
+var (
+	handlersMu sync.Mutex
+	handlers   = map[string]handler{}
+)
+
+func init() { sql.Register("sqlds-fake", fakeDriver{}) }
+
+func (fakeDriver) Open(dsn string) (driver.Conn, error) {
+	handlersMu.Lock()
+	defer handlersMu.Unlock()
+	h, ok := handlers[dsn]
+	if !ok {
+		return nil, errors.New("no such database")
+	}
+	return conn{h}, nil
+}
+
+type conn struct{ h handler }
+
+func (c conn) Prepare(query string) (driver.Stmt, error) { return stmt{c.h, query}, nil }
+func (c conn) Close() error                              { return nil }
+func (c conn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type stmt struct {
+	h     handler
+	query string
+}
+
+func (s stmt) Close() error  { return nil }
+func (s stmt) NumInput() int { return -1 }
+func (s stmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s stmt) Query(args []driver.Value) (driver.Rows, error) {
+	r, err := s.h(s.query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &rows{result: r}, nil
+}
+
+type rows struct {
+	result
+	next int
+}
+
+func (r *rows) Columns() []string { return r.cols }
+func (r *rows) Close() error      { return nil }
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.next])
+	r.next++
+	return nil
+}
+
+// open returns a database answering queries with h.
+func open(t *testing.T, h handler) *sql.DB {
+	t.Helper()
+	handlersMu.Lock()
+	handlers[t.Name()] = h
+	handlersMu.Unlock()
+	db, err := sql.Open("sqlds-fake", t.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestFetchTopics(t *testing.T) {
+	var gotQuery string
+	var gotArgs []driver.Value
+	db := open(t, func(query string, args []driver.Value) (result, error) {
+		gotQuery, gotArgs = query, args
+		return result{
+			cols: []string{"ID", "Title", "url", "score", "language", "created_at", "category"},
+			rows: [][]driver.Value{
+				{int64(7), []byte("Reset a VPN token"), "https://kb.example.com/7", 2.5, "en", "2026-03-01 09:30:00", "network"},
+				{[]byte("8"), "Réinitialiser un jeton", "https://kb.example.com/8", 1.5, "fr", nil, nil},
+				{int64(9), "Token expiry", "https://kb.example.com/9", int64(1), nil, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), "security"},
+			},
+		}, nil
+	})
+	ds := sqlds.New(sqlds.Config{
+		DB:          db,
+		Placeholder: sqlds.Dollar,
+		TopicsQuery: `SELECT id, title, url, category, created_at::text, -- :not_a_param
+			'a:b' AS x FROM kb WHERE title ILIKE :pattern AND tenant = :tenant_id LIMIT :count`,
+		DataQuery: `SELECT 1`,
+	})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{
+		QuestionText:    "vpn token",
+		TenantID:        "acme",
+		AcceptLanguages: []string{"en"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `created_at::text, -- :not_a_param
+			'a:b' AS x FROM kb WHERE title ILIKE $1 AND tenant = $2 LIMIT $3`
+	if !strings.HasSuffix(gotQuery, want) {
+		t.Errorf("query = %q, want suffix %q", gotQuery, want)
+	}
+	if len(gotArgs) != 3 || gotArgs[0] != "%vpn token%" || gotArgs[1] != "acme" || gotArgs[2] != int64(5) {
+		t.Errorf("args = %v", gotArgs)
+	}
+
+	if len(topics) != 2 {
+		t.Fatalf("got %d topics, want the 2 not in French: %+v", len(topics), topics)
+	}
+	first := topics[0]
+	if first.TopicID != 7 || first.Topic != "Reset a VPN token" || first.Score != 2.5 || first.Rank != 1 ||
+		!first.CreatedAt.Equal(time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)) {
+		t.Errorf("first topic = %+v", first)
+	}
+	if v, _ := first.Metadata.String("category"); v != "network" {
+		t.Errorf("metadata = %v, want the unmapped category column", first.Metadata)
+	}
+	if topics[1].TopicID != 9 || topics[1].Rank != 2 || topics[1].CreatedAt.Year() != 2026 {
+		t.Errorf("second topic = %+v", topics[1])
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	var gotArgs []driver.Value
+	db := open(t, func(query string, args []driver.Value) (result, error) {
+		gotArgs = args
+		return result{
+			cols: []string{"id", "text", "author"},
+			rows: [][]driver.Value{
+				{int64(1), "# Steps\n\nOpen the portal.", "Ana"},
+				{int64(2), "Then sign in.", nil},
+			},
+		}, nil
+	})
+	ds := sqlds.New(sqlds.Config{
+		DB:          db,
+		TopicsQuery: `SELECT 1`,
+		DataQuery:   `SELECT id, body AS text FROM paragraphs WHERE article_id = :topic_id LIMIT :count`,
+	})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ds.FetchData(1, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(gotArgs) != 2 || gotArgs[0] != int64(7) || gotArgs[1] != int64(1) {
+		t.Errorf("args = %v", gotArgs)
+	}
+	if len(data) != 1 || data[0].AnswerID != 1 || data[0].ContentType != datasource.ContentMarkdown ||
+		data[0].Author == nil || data[0].Author.Name != "Ana" {
+		t.Errorf("data = %+v", data)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	db := open(t, func(string, []driver.Value) (result, error) { return result{}, nil })
+	tests := []struct {
+		name string
+		cfg  sqlds.Config
+		want string
+	}{
+		{"no database", sqlds.Config{TopicsQuery: "SELECT 1", DataQuery: "SELECT 1"}, "no database"},
+		{"no data query", sqlds.Config{DB: db, TopicsQuery: "SELECT 1"}, "DataQuery are required"},
+		{"unknown parameter", sqlds.Config{DB: db, TopicsQuery: "SELECT :topic_id", DataQuery: "SELECT 1"}, "unknown parameter :topic_id"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := sqlds.New(tt.cfg).Init(); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Init = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestQueryErrors(t *testing.T) {
+	db := open(t, func(string, []driver.Value) (result, error) {
+		return result{cols: []string{"title"}, rows: [][]driver.Value{{"no id"}}}, nil
+	})
+	ds := sqlds.New(sqlds.Config{DB: db, TopicsQuery: "SELECT title FROM kb", DataQuery: "SELECT 1"})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "q"}); err == nil || !strings.Contains(err.Error(), "integer id column") {
+		t.Errorf("FetchTopics = %v, want a missing ID error", err)
+	}
+}
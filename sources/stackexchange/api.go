@@ -0,0 +1,176 @@
+package stackexchange
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// maxResponseBytes bounds response bodies read from the API.
+const maxResponseBytes = 16 << 20
+
+// now is replaced in tests.
+var now = time.Now
+
+// wrapper is the common wrapper of every API response.
+type wrapper struct {
+	Items          json.RawMessage `json:"items"`
+	HasMore        bool            `json:"has_more"`
+	QuotaMax       int             `json:"quota_max"`
+	QuotaRemaining int             `json:"quota_remaining"`
+	Backoff        int             `json:"backoff"`
+	ErrorID        int             `json:"error_id"`
+	ErrorName      string          `json:"error_name"`
+	ErrorMessage   string          `json:"error_message"`
+}
+
+// get calls the API method at path, such as "search/excerpts", and decodes
+// the response's items into items, reporting whether more pages follow.
+// Backoff is tracked per route, which names the method independently of
+// the IDs in its path.
+func (s *Source) get(ctx context.Context, route, path string, params url.Values, items any) (bool, error) {
+	if err := s.wait(ctx, route); err != nil {
+		return false, err
+	}
+	if err := s.checkQuota(); err != nil {
+		return false, err
+	}
+	if s.cfg.Key != "" {
+		params.Set("key", s.cfg.Key)
+	}
+	if s.cfg.AccessToken != "" {
+		params.Set("access_token", s.cfg.AccessToken)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.cfg.BaseURL+"/"+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return false, fmt.Errorf("stackexchange: %w", err)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		var ue *url.Error
+		if errors.As(err, &ue) {
+			ue.URL = s.cfg.BaseURL + "/" + path // drop the key and token
+		}
+		return false, fmt.Errorf("stackexchange: %w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var w wrapper
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&w)
+	if decodeErr == nil {
+		s.record(route, w)
+	}
+	switch {
+	case decodeErr == nil && w.ErrorID != 0:
+		return false, s.apiError(w)
+	case resp.StatusCode >= 300:
+		return false, fmt.Errorf("stackexchange: %w", httpx.StatusError(resp))
+	case decodeErr != nil:
+		return false, fmt.Errorf("stackexchange: decode %s response: %w", route, decodeErr)
+	}
+	if len(w.Items) > 0 {
+		if err := json.Unmarshal(w.Items, items); err != nil {
+			return false, fmt.Errorf("stackexchange: decode %s items: %w", route, err)
+		}
+	}
+	return w.HasMore, nil
+}
+
+// wait waits out a backoff the API requested for route, up to
+// MaxBackoffWait; longer backoffs fail with ErrRateLimited.
+func (s *Source) wait(ctx context.Context, route string) error {
+	s.mu.Lock()
+	d := s.backoff[route].Sub(now())
+	s.mu.Unlock()
+	if d <= 0 {
+		return nil
+	}
+	limited := &datasource.ErrRateLimited{RetryAfter: d, Err: fmt.Errorf("stackexchange: backing off %s", route)}
+	if d > s.cfg.MaxBackoffWait {
+		return limited
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return limited
+	}
+}
+
+// checkQuota fails with ErrQuotaExceeded while the daily quota is down to
+// the reserve.
+func (s *Source) checkQuota() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if until := s.exhaustedUntil; now().Before(until) {
+		return fmt.Errorf("stackexchange: %w: %d of %d requests left until %s",
+			datasource.ErrQuotaExceeded, s.quotaRemaining, s.quotaMax, until.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// record notes the backoff and quota reported by a response.
+func (s *Source) record(route string, w wrapper) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := now()
+	if w.Backoff > 0 {
+		s.backoff[route] = t.Add(time.Duration(w.Backoff) * time.Second)
+	}
+	if w.QuotaMax > 0 {
+		s.quotaMax, s.quotaRemaining = w.QuotaMax, w.QuotaRemaining
+		if w.QuotaRemaining <= s.cfg.QuotaReserve {
+			s.exhaustedUntil = nextDay(t)
+		}
+	}
+}
+
+// nextDay returns the next UTC midnight, when the API resets quotas.
+func nextDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d+1, 0, 0, 0, 0, time.UTC)
+}
+
+// quotaWait matches the message of a throttle violation caused by an
+// exhausted quota rather than a burst of requests.
+var quotaWait = regexp.MustCompile(`more requests available in (\d+) seconds`)
+
+// apiError converts an API error response into an error wrapping the
+// matching SDK error.
+func (s *Source) apiError(w wrapper) error {
+	err := fmt.Errorf("stackexchange: %s (%d): %s", w.ErrorName, w.ErrorID, w.ErrorMessage)
+	switch w.ErrorID {
+	case 401, 402, 403, 405, 406:
+		return fmt.Errorf("%w: %w", datasource.ErrUnauthorized, err)
+	case 404:
+		return fmt.Errorf("%w: %w", datasource.ErrNotFound, err)
+	case 500, 503:
+		return fmt.Errorf("%w: %w", datasource.ErrUnavailable, err)
+	case 502:
+		var wait time.Duration
+		if m := quotaWait.FindStringSubmatch(w.ErrorMessage); m != nil {
+			secs, _ := strconv.Atoi(m[1])
+			wait = time.Duration(secs) * time.Second
+		}
+		if wait >= time.Hour {
+			s.mu.Lock()
+			s.exhaustedUntil = now().Add(wait)
+			s.mu.Unlock()
+			return fmt.Errorf("%w: %w", datasource.ErrQuotaExceeded, err)
+		}
+		return &datasource.ErrRateLimited{RetryAfter: max(wait, time.Duration(w.Backoff)*time.Second), Err: err}
+	}
+	return err
+}
@@ -0,0 +1,37 @@
+package stackexchange
+
+import "strings"
+
+// dotCom are the sites with .com domains of their own; others are
+// subdomains of stackexchange.com.
+var dotCom = map[string]bool{
+	"askubuntu": true, "serverfault": true, "stackapps": true,
+	"stackoverflow": true, "superuser": true,
+}
+
+// localized are the language prefixes of localized sites, such as
+// "ru.stackoverflow".
+var localized = map[string]bool{"es": true, "ja": true, "pt": true, "ru": true}
+
+// siteURL returns the address of the site with the API name site, such as
+// "https://serverfault.com" for "serverfault", "https://ru.stackoverflow.com"
+// for "ru.stackoverflow", and "https://math.stackexchange.com" for "math".
+func siteURL(site string) string {
+	base := site[strings.LastIndexByte(site, '.')+1:]
+	switch {
+	case strings.HasSuffix(site, ".net") || strings.HasSuffix(site, ".com"):
+		return "https://" + site
+	case dotCom[base]:
+		return "https://" + site + ".com"
+	default:
+		return "https://" + site + ".stackexchange.com"
+	}
+}
+
+// siteLanguage returns the language of the site's content.
+func siteLanguage(site string) string {
+	if prefix, _, ok := strings.Cut(site, "."); ok && localized[prefix] {
+		return prefix
+	}
+	return "en"
+}
@@ -0,0 +1,475 @@
+// Package stackexchange is a data source backed by the Stack Exchange API.
+// Topics are questions found with the search/excerpts method, matching
+// either the question or one of its answers, and data items are the
+// question's answers, accepted answer first:
+//
+//	ds := stackexchange.New(stackexchange.Config{
+//	    Sites: []string{"stackoverflow", "serverfault"},
+//	    Key:   os.Getenv("STACKEXCHANGE_KEY"),
+//	})
+//
+// The source honors the backoff field of API responses, waiting out short
+// backoffs and failing with datasource.ErrRateLimited on long ones, and
+// tracks the daily quota, failing with datasource.ErrQuotaExceeded once it
+// is down to Config.QuotaReserve. The API also limits each IP to 30
+// requests a second; wrap the source with middleware.RateLimit to stay
+// under it, and with quota.Track to pace background work against the
+// daily quota.
+//
+// Besides being usable as is, the package is meant as an example of a
+// complete source: it maps upstream errors to the SDK's taxonomy, bounds
+// its requests by NewQuestionInput.Budget, pushes Filters and tags down to
+// the API, and reports its capabilities and health.
+package stackexchange
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultBaseURL        = "https://api.stackexchange.com/2.3"
+	DefaultSite           = "stackoverflow"
+	DefaultTimeout        = 15 * time.Second
+	DefaultMaxBackoffWait = 5 * time.Second
+)
+
+// Metadata keys set on topics and data items.
+const (
+	MetadataExcerpt     = "excerpt"      // the matching passage, as plain text
+	MetadataTags        = "tags"         // the question's tags
+	MetadataAnswerCount = "answer_count" // the number of answers
+	MetadataAnswered    = "is_answered"  // whether an answer was upvoted or accepted
+	MetadataAccepted    = "accepted"     // whether the answer, or one of the question's, is accepted
+)
+
+// pageSize is the largest page the API returns.
+const pageSize = 100
+
+// siteShift is the bit position of the site index packed into topic IDs,
+// above any question ID.
+const siteShift = 40
+
+// Config configures a Source.
+type Config struct {
+	// Sites lists the API names of the sites to search, such as
+	// "stackoverflow", "superuser", or "math"
+	// Defaults to DefaultSite
+	Sites []string
+
+	// Key is the application key, which raises the daily quota from 300 to
+	// 10,000 requests
+	// Optional - keys are not secret
+	Key string
+
+	// AccessToken authenticates requests as a user, for private sites
+	// Optional - requires Key
+	AccessToken string
+
+	// QuotaReserve is the number of requests of the daily quota held back:
+	// once the API reports that few left, calls fail with
+	// datasource.ErrQuotaExceeded until the quota resets
+	QuotaReserve int
+
+	// MaxBackoffWait bounds how long a call waits out a backoff the API
+	// requested; calls facing longer backoffs fail with
+	// datasource.ErrRateLimited
+	// Defaults to DefaultMaxBackoffWait
+	MaxBackoffWait time.Duration
+
+	// Timeout bounds each call, in addition to NewQuestionInput.Budget
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// BaseURL is the API address
+	// Defaults to DefaultBaseURL
+	BaseURL string
+
+	// HTTPClient makes the requests
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func (c Config) withDefaults() Config {
+	if len(c.Sites) == 0 {
+		c.Sites = []string{DefaultSite}
+	}
+	if c.MaxBackoffWait <= 0 {
+		c.MaxBackoffWait = DefaultMaxBackoffWait
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.BaseURL == "" {
+		c.BaseURL = DefaultBaseURL
+	}
+	c.BaseURL = strings.TrimSuffix(c.BaseURL, "/")
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+// Source is a DataSource searching Stack Exchange sites. Topic IDs pack
+// the index of the question's site in Config.Sites above its question ID,
+// so they are unique across sites; with one site they are question IDs. A
+// Source is safe for concurrent use.
+type Source struct {
+	cfg Config
+
+	mu             sync.Mutex
+	backoff        map[string]time.Time // route -> when requests may resume
+	quotaMax       int
+	quotaRemaining int
+	exhaustedUntil time.Time
+}
+
+var (
+	_ datasource.DataSource         = (*Source)(nil)
+	_ datasource.CapabilityReporter = (*Source)(nil)
+	_ datasource.HealthChecker      = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults(), backoff: make(map[string]time.Time)}
+}
+
+// Init checks that every configured site exists and that the key and
+// access token, if any, are accepted.
+func (s *Source) Init() error {
+	if s.cfg.AccessToken != "" && s.cfg.Key == "" {
+		return errors.New("stackexchange: an access token requires a key")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	for _, site := range s.cfg.Sites {
+		if err := s.info(ctx, site); err != nil {
+			return fmt.Errorf("stackexchange: site %q: %w", site, err)
+		}
+	}
+	return nil
+}
+
+func (s *Source) info(ctx context.Context, site string) error {
+	var items []struct{}
+	_, err := s.get(ctx, "info", "info", url.Values{"site": {site}}, &items)
+	return err
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck reports the source unhealthy if the API cannot be reached,
+// and degraded while it is backing off or has less than a tenth of its
+// daily quota left.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	start := now()
+	err := s.info(ctx, s.cfg.Sites[0])
+	h := datasource.HealthStatus{State: datasource.Healthy, Latency: now().Sub(start)}
+	remaining, limit := s.Quota()
+	switch {
+	case errors.Is(err, datasource.ErrQuotaExceeded):
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	case datasource.IsRetryable(err):
+		h.State, h.Error = datasource.Degraded, err.Error()
+	case err != nil:
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	case limit > 0 && remaining*10 < limit:
+		h.State = datasource.Degraded
+		h.Error = fmt.Sprintf("stackexchange: %d of %d daily requests left", remaining, limit)
+	}
+	return h
+}
+
+// Capabilities reports pagination and tag filtering, and several sites if
+// more than one is configured.
+func (s *Source) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{
+		Pagination:   true,
+		TagFiltering: true,
+		MultiSite:    len(s.cfg.Sites) > 1,
+	}
+}
+
+// Quota returns the requests left of the daily quota and the quota, as
+// last reported by the API; both are zero before the first call.
+func (s *Source) Quota() (remaining, limit int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.quotaRemaining, s.quotaMax
+}
+
+// excerpt is an item of search/excerpts.
+type excerpt struct {
+	ItemType          string   `json:"item_type"`
+	QuestionID        int64    `json:"question_id"`
+	Title             string   `json:"title"`
+	Excerpt           string   `json:"excerpt"`
+	Score             int      `json:"score"`
+	Tags              []string `json:"tags"`
+	AnswerCount       int      `json:"answer_count"`
+	IsAnswered        bool     `json:"is_answered"`
+	HasAcceptedAnswer bool     `json:"has_accepted_answer"`
+	CreationDate      int64    `json:"creation_date"`
+	LastActivityDate  int64    `json:"last_activity_date"`
+}
+
+// FetchTopics searches every configured site that passes the input's
+// site filter and accepted languages, concurrently, and interleaves their
+// results by rank. A topic's Score is the votes of the post that matched,
+// the question or an answer. Results are returned from the sites that
+// answered if only some failed.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if count <= 0 || input.SearchText() == "" {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	ctx, cancel = input.Budget.Context(ctx)
+	defer cancel()
+
+	type result struct {
+		topics []datasource.DataSourceTopic
+		err    error
+	}
+	results := make([]result, len(s.cfg.Sites))
+	var wg sync.WaitGroup
+	for i, site := range s.cfg.Sites {
+		if !s.searches(site, input) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			topics, err := s.search(ctx, i, count, input)
+			results[i] = result{topics, err}
+		}(i)
+	}
+	wg.Wait()
+
+	var (
+		perSite [][]datasource.DataSourceTopic
+		errs    []error
+	)
+	for i, r := range results {
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("site %q: %w", s.cfg.Sites[i], r.err))
+		} else if r.topics != nil {
+			perSite = append(perSite, r.topics)
+		}
+	}
+	if len(perSite) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	topics := []datasource.DataSourceTopic{}
+	for rank := 0; len(topics) < count; rank++ {
+		added := false
+		for _, site := range perSite {
+			if rank < len(site) && len(topics) < count {
+				topics = append(topics, site[rank])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// searches reports whether FetchTopics searches site for input.
+func (s *Source) searches(site string, input datasource.NewQuestionInput) bool {
+	if sites := input.Filters.Sites; len(sites) > 0 {
+		found := false
+		for _, f := range sites {
+			found = found || strings.EqualFold(f, site)
+		}
+		if !found {
+			return false
+		}
+	}
+	return datasource.AcceptsLanguage(input.AcceptLanguages, siteLanguage(site))
+}
+
+// search returns up to count questions from the site at index i, paging
+// through results.
+func (s *Source) search(ctx context.Context, i, count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	site := s.cfg.Sites[i]
+	params := url.Values{"site": {site}, "q": {input.SearchText()}, "order": {"desc"}, "sort": {"relevance"}}
+	if len(input.Tags) > 0 {
+		params.Set("tagged", strings.Join(input.Tags, ";"))
+	}
+	f := input.Filters
+	if len(f.ExcludeTags) > 0 {
+		params.Set("nottagged", strings.Join(f.ExcludeTags, ";"))
+	}
+	if !f.After.IsZero() {
+		params.Set("fromdate", strconv.FormatInt(f.After.Unix(), 10))
+	}
+	if !f.Before.IsZero() {
+		params.Set("todate", strconv.FormatInt(f.Before.Unix(), 10))
+	}
+	switch f.Sort {
+	case datasource.SortRecency:
+		params.Set("sort", "creation")
+	case datasource.SortVotes:
+		params.Set("sort", "votes")
+	}
+
+	topics := []datasource.DataSourceTopic{}
+	seen := make(map[int64]bool)
+	for page := 1; len(topics) < count; page++ {
+		params.Set("page", strconv.Itoa(page))
+		params.Set("pagesize", strconv.Itoa(min(count-len(topics), pageSize)))
+		var items []excerpt
+		more, err := s.get(ctx, "search/excerpts", "search/excerpts", params, &items)
+		if err != nil {
+			if len(topics) > 0 && ctx.Err() != nil {
+				break // out of time; return the pages we have
+			}
+			return nil, err
+		}
+		for _, e := range items {
+			if seen[e.QuestionID] || len(topics) == count {
+				continue
+			}
+			seen[e.QuestionID] = true
+			if t := s.topic(i, e); len(f.Topics([]datasource.DataSourceTopic{t})) == 1 {
+				topics = append(topics, t)
+			}
+		}
+		if !more || len(items) == 0 {
+			break
+		}
+	}
+	return topics, nil
+}
+
+func (s *Source) topic(i int, e excerpt) datasource.DataSourceTopic {
+	site := s.cfg.Sites[i]
+	t := datasource.DataSourceTopic{
+		Topic:     html.UnescapeString(e.Title),
+		SourceURL: siteURL(site) + "/q/" + strconv.FormatInt(e.QuestionID, 10),
+		Site:      site,
+		TopicID:   int64(i)<<siteShift | e.QuestionID,
+		Score:     float64(e.Score),
+		Language:  siteLanguage(site),
+		CreatedAt: unix(e.CreationDate),
+		UpdatedAt: unix(e.LastActivityDate),
+	}
+	t = t.Structured(t.Topic, content.StripHTML(e.Excerpt))
+	if t.BodyExcerpt != "" {
+		t.Metadata.Set(MetadataExcerpt, t.BodyExcerpt)
+	}
+	if len(e.Tags) > 0 {
+		t.Metadata.Set(MetadataTags, e.Tags)
+	}
+	t.Metadata.Set(MetadataAnswerCount, e.AnswerCount)
+	t.Metadata.Set(MetadataAnswered, e.IsAnswered)
+	t.Metadata.Set(MetadataAccepted, e.HasAcceptedAnswer)
+	return t
+}
+
+// answer is an item of questions/{ids}/answers with the withbody filter.
+type answer struct {
+	AnswerID         int64  `json:"answer_id"`
+	Body             string `json:"body"`
+	Score            int    `json:"score"`
+	IsAccepted       bool   `json:"is_accepted"`
+	CreationDate     int64  `json:"creation_date"`
+	LastActivityDate int64  `json:"last_activity_date"`
+	LastEditDate     int64  `json:"last_edit_date"`
+	Owner            *struct {
+		DisplayName string  `json:"display_name"`
+		Link        string  `json:"link"`
+		Reputation  float64 `json:"reputation"`
+		UserType    string  `json:"user_type"`
+	} `json:"owner"`
+}
+
+// FetchData returns the answers to the question, as HTML, accepted answer
+// first and then by votes.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	i, question := int(topicID>>siteShift), topicID&(1<<siteShift-1)
+	if topicID <= 0 || i >= len(s.cfg.Sites) {
+		return nil, fmt.Errorf("stackexchange: topic %d: %w", topicID, datasource.ErrNotFound)
+	}
+	if count <= 0 {
+		return []datasource.DataSourceData{}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	site := s.cfg.Sites[i]
+	params := url.Values{"site": {site}, "order": {"desc"}, "sort": {"votes"}, "filter": {"withbody"}}
+	path := "questions/" + strconv.FormatInt(question, 10) + "/answers"
+	var answers []answer
+	for page := 1; len(answers) < count; page++ {
+		params.Set("page", strconv.Itoa(page))
+		params.Set("pagesize", strconv.Itoa(min(count-len(answers), pageSize)))
+		var items []answer
+		more, err := s.get(ctx, "questions/answers", path, params, &items)
+		if err != nil {
+			return nil, err
+		}
+		answers = append(answers, items...)
+		if !more || len(items) == 0 {
+			break
+		}
+	}
+	sort.SliceStable(answers, func(a, b int) bool { return answers[a].IsAccepted && !answers[b].IsAccepted })
+	answers = answers[:min(len(answers), count)]
+
+	items := make([]datasource.DataSourceData, len(answers))
+	for j, a := range answers {
+		d := datasource.DataSourceData{
+			DataText:    a.Body,
+			ContentType: datasource.ContentHTML,
+			SourceURL:   siteURL(site) + "/a/" + strconv.FormatInt(a.AnswerID, 10),
+			Site:        site,
+			AnswerID:    a.AnswerID,
+			Score:       float64(a.Score),
+			Rank:        j + 1,
+			Language:    siteLanguage(site),
+			CreatedAt:   unix(a.CreationDate),
+			UpdatedAt:   unix(max(a.LastEditDate, a.LastActivityDate)),
+		}
+		d.Metadata.Set(MetadataAccepted, a.IsAccepted)
+		if o := a.Owner; o != nil && o.UserType != "does_not_exist" {
+			d.Author = &datasource.Author{
+				Name:       html.UnescapeString(o.DisplayName),
+				ProfileURL: o.Link,
+				Reputation: o.Reputation,
+			}
+		}
+		items[j] = d
+	}
+	return items, nil
+}
+
+func unix(secs int64) time.Time {
+	if secs == 0 {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0).UTC()
+}
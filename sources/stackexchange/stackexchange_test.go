@@ -0,0 +1,247 @@
+package stackexchange_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/stackexchange"
+)
+
+// api is a fake Stack Exchange API. Handlers return the response wrapper
+// for a request; requests are recorded.
+type api struct {
+	mu       sync.Mutex
+	requests []url.URL
+	handle   func(path string, q url.Values) map[string]any
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.requests = append(a.requests, *r.URL)
+	a.mu.Unlock()
+	resp := a.handle(r.URL.Path, r.URL.Query())
+	if resp == nil {
+		resp = map[string]any{"items": []any{}}
+	}
+	if _, ok := resp["quota_max"]; !ok {
+		resp["quota_max"], resp["quota_remaining"] = 10000, 9000
+	}
+	if id, ok := resp["error_id"].(int); ok {
+		w.WriteHeader(id / 100 * 100)
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (a *api) calls(path string) []url.Values {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var qs []url.Values
+	for _, u := range a.requests {
+		if u.Path == path {
+			qs = append(qs, u.Query())
+		}
+	}
+	return qs
+}
+
+func newSource(t *testing.T, cfg stackexchange.Config, handle func(path string, q url.Values) map[string]any) (*stackexchange.Source, *api) {
+	t.Helper()
+	a := &api{handle: handle}
+	srv := httptest.NewServer(a)
+	t.Cleanup(srv.Close)
+	cfg.BaseURL = srv.URL
+	return stackexchange.New(cfg), a
+}
+
+func TestFetchTopics(t *testing.T) {
+	ds, a := newSource(t, stackexchange.Config{Sites: []string{"stackoverflow", "serverfault", "ru.stackoverflow"}, Key: "k"},
+		func(path string, q url.Values) map[string]any {
+			switch q.Get("site") {
+			case "stackoverflow":
+				return map[string]any{"items": []map[string]any{
+					{"item_type": "question", "question_id": 11, "title": "Why &quot;nil&quot; map?", "score": 7,
+						"excerpt": `assignment to <span class="highlight">nil</span> map`, "tags": []string{"go"}, "creation_date": 1700000000},
+					{"item_type": "answer", "question_id": 11, "answer_id": 99, "title": "Why &quot;nil&quot; map?", "score": 3},
+					{"item_type": "answer", "question_id": 12, "answer_id": 98, "title": "Second", "score": 2},
+				}}
+			case "serverfault":
+				return map[string]any{"items": []map[string]any{{"item_type": "question", "question_id": 11, "title": "Other site"}}}
+			}
+			t.Errorf("searched site %q", q.Get("site"))
+			return nil
+		})
+	topics, err := ds.FetchTopics(10, datasource.NewQuestionInput{
+		QuestionText:    "nil map",
+		Tags:            []string{"go", "maps"},
+		AcceptLanguages: []string{"en"},
+		Filters: datasource.Filters{
+			ExcludeTags: []string{"python"},
+			After:       time.Unix(1600000000, 0),
+			Sort:        datasource.SortVotes,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, tp := range topics {
+		got = append(got, tp.Site+":"+tp.Topic)
+	}
+	if want := []string{`stackoverflow:Why "nil" map?`, "serverfault:Other site", "stackoverflow:Second"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("topics = %q, want %q", got, want)
+	}
+	first := topics[0]
+	if first.TopicID != 11 || first.Rank != 1 || first.Score != 7 || first.SourceURL != "https://stackoverflow.com/q/11" || first.Language != "en" {
+		t.Errorf("first topic = %+v", first)
+	}
+	if ex, _ := first.Metadata.String(stackexchange.MetadataExcerpt); ex != "assignment to nil map" {
+		t.Errorf("excerpt = %q, want highlighting stripped", ex)
+	}
+	if !first.CreatedAt.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("CreatedAt = %v", first.CreatedAt)
+	}
+	if other := topics[1]; other.TopicID != 1<<40|11 || other.SourceURL != "https://serverfault.com/q/11" {
+		t.Errorf("second site's topic = %d %s, want the site index packed above the question ID", other.TopicID, other.SourceURL)
+	}
+
+	q := a.calls("/search/excerpts")[0]
+	for k, want := range map[string]string{
+		"q": "nil map", "tagged": "go;maps", "nottagged": "python", "fromdate": "1600000000",
+		"sort": "votes", "order": "desc", "key": "k", "pagesize": "10",
+	} {
+		if got := q.Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	ds, a := newSource(t, stackexchange.Config{Sites: []string{"stackoverflow", "math"}}, func(path string, q url.Values) map[string]any {
+		return map[string]any{"items": []map[string]any{
+			{"answer_id": 1, "body": "<p>most votes</p>", "score": 10, "owner": map[string]any{"display_name": "J&#246;rg", "reputation": 5000, "link": "https://math.stackexchange.com/users/1"}},
+			{"answer_id": 2, "body": "<p>accepted</p>", "score": 4, "is_accepted": true, "owner": map[string]any{"user_type": "does_not_exist", "display_name": "gone"}},
+			{"answer_id": 3, "body": "<p>third</p>", "score": 1},
+		}}
+	})
+	items, err := ds.FetchData(2, 1<<40|77)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].AnswerID != 2 || items[1].AnswerID != 1 {
+		t.Fatalf("items = %+v, want the accepted answer first, then by votes", items)
+	}
+	if accepted, _ := items[0].Metadata.Bool(stackexchange.MetadataAccepted); !accepted || items[0].Author != nil {
+		t.Errorf("accepted answer = %+v, want marked accepted without a deleted author", items[0])
+	}
+	d := items[1]
+	if d.ContentType != datasource.ContentHTML || d.SourceURL != "https://math.stackexchange.com/a/1" || d.Site != "math" || d.Rank != 2 {
+		t.Errorf("item = %+v", d)
+	}
+	if d.Author == nil || d.Author.Name != "Jörg" || d.Author.Reputation != 5000 {
+		t.Errorf("author = %+v", d.Author)
+	}
+	q := a.calls("/questions/77/answers")
+	if len(q) != 1 || q[0].Get("site") != "math" || q[0].Get("filter") != "withbody" {
+		t.Errorf("requests = %v", q)
+	}
+
+	if _, err := ds.FetchData(5, 5<<40|77); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("unknown site index: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	ds, a := newSource(t, stackexchange.Config{}, func(path string, q url.Values) map[string]any {
+		return map[string]any{"items": []any{}, "backoff": 30}
+	})
+	input := datasource.NewQuestionInput{QuestionText: "q"}
+	if _, err := ds.FetchTopics(5, input); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ds.FetchTopics(5, input)
+	if d, ok := datasource.RetryAfter(err); !ok || d < 25*time.Second {
+		t.Errorf("err = %v, want rate limited for the backoff", err)
+	}
+	if n := len(a.calls("/search/excerpts")); n != 1 {
+		t.Errorf("%d searches, want the second held back", n)
+	}
+	if _, err := ds.FetchData(5, 1); err != nil {
+		t.Errorf("FetchData: %v, want other methods unaffected", err)
+	}
+}
+
+func TestQuota(t *testing.T) {
+	ds, a := newSource(t, stackexchange.Config{QuotaReserve: 10}, func(path string, q url.Values) map[string]any {
+		return map[string]any{"items": []any{}, "quota_max": 300, "quota_remaining": 10}
+	})
+	if _, err := ds.FetchData(5, 1); err != nil {
+		t.Fatal(err)
+	}
+	if remaining, limit := ds.Quota(); remaining != 10 || limit != 300 {
+		t.Errorf("Quota() = %d, %d", remaining, limit)
+	}
+	if _, err := ds.FetchData(5, 1); !errors.Is(err, datasource.ErrQuotaExceeded) {
+		t.Errorf("err = %v, want ErrQuotaExceeded at the reserve", err)
+	}
+	if n := len(a.requests); n != 1 {
+		t.Errorf("%d requests, want none once the reserve is reached", n)
+	}
+	if h := ds.HealthCheck(); h.State != datasource.Unhealthy {
+		t.Errorf("health = %+v, want unhealthy", h)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		resp   map[string]any
+		target error
+	}{
+		{"throttled", map[string]any{"error_id": 502, "error_name": "throttle_violation", "error_message": "too many requests from this IP, more requests available in 30 seconds"}, nil},
+		{"quota", map[string]any{"error_id": 502, "error_name": "throttle_violation", "error_message": "too many requests from this IP, more requests available in 80000 seconds"}, datasource.ErrQuotaExceeded},
+		{"bad key", map[string]any{"error_id": 403, "error_name": "access_denied", "error_message": "denied"}, datasource.ErrUnauthorized},
+		{"down", map[string]any{"error_id": 503, "error_name": "temporarily_unavailable", "error_message": "down"}, datasource.ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ds, _ := newSource(t, stackexchange.Config{}, func(string, url.Values) map[string]any { return tt.resp })
+			_, err := ds.FetchData(5, 1)
+			if tt.target == nil {
+				if d, ok := datasource.RetryAfter(err); !ok || d != 30*time.Second {
+					t.Errorf("err = %v, want rate limited for 30s", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.target) {
+				t.Errorf("err = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	ds, a := newSource(t, stackexchange.Config{Sites: []string{"stackoverflow", "nosuchsite"}}, func(path string, q url.Values) map[string]any {
+		if q.Get("site") == "nosuchsite" {
+			return map[string]any{"error_id": 400, "error_name": "bad_parameter", "error_message": "No site found for name `nosuchsite`"}
+		}
+		return nil
+	})
+	if err := ds.Init(); err == nil || !strings.Contains(err.Error(), "nosuchsite") {
+		t.Errorf("Init() = %v, want the unknown site reported", err)
+	}
+	if n := len(a.calls("/info")); n != 2 {
+		t.Errorf("%d info calls, want one per site", n)
+	}
+	if err := stackexchange.New(stackexchange.Config{AccessToken: "t"}).Init(); err == nil {
+		t.Error("Init() with a token but no key succeeded")
+	}
+}
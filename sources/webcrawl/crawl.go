@@ -0,0 +1,346 @@
+package webcrawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// maxRobotsBytes bounds the robots.txt files read; RFC 9309 requires
+// crawlers to read at least 500 KiB.
+const maxRobotsBytes = 512 << 10
+
+// maxCrawlDelay caps the Crawl-delay honored, so a site cannot stall the
+// crawl indefinitely.
+const maxCrawlDelay = time.Minute
+
+// maxErrors bounds the page errors a failed crawl reports.
+const maxErrors = 5
+
+// maxRedirects bounds the redirects followed for a page.
+const maxRedirects = 10
+
+// skippedExtensions are file extensions of addresses not fetched, as they
+// are rarely HTML.
+var skippedExtensions = map[string]bool{
+	".pdf": true, ".zip": true, ".gz": true, ".tgz": true, ".tar": true,
+	".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".svg": true, ".webp": true, ".ico": true,
+	".mp3": true, ".mp4": true, ".webm": true, ".css": true, ".js": true, ".json": true, ".xml": true,
+	".woff": true, ".woff2": true, ".exe": true, ".dmg": true,
+}
+
+// errSkipped marks pages fetched but not indexed, such as non-HTML
+// documents.
+var errSkipped = errors.New("skipped")
+
+// crawler is the state of one crawl.
+type crawler struct {
+	s       *Source
+	robots  map[string]robots    // by scheme and host
+	last    map[string]time.Time // of the last request to each host
+	seen    map[string]bool      // addresses queued or fetched
+	indexed map[string]bool      // addresses of indexed pages
+	docs    []*document
+	errs    []error
+	stats   Stats
+}
+
+// link is an address queued for the crawl, and its distance from a seed.
+type link struct {
+	url   *url.URL
+	depth int
+}
+
+// crawl visits the seeds and the pages linked from them, breadth first.
+func (c *crawler) crawl(ctx context.Context, seeds []*url.URL) error {
+	var queue []link
+	for _, u := range seeds {
+		if key := u.String(); !c.seen[key] {
+			c.seen[key] = true
+			queue = append(queue, link{u, 0})
+		}
+	}
+	for len(queue) > 0 && len(c.docs) < c.s.cfg.MaxPages {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		l := queue[0]
+		queue = queue[1:]
+		next, err := c.visit(ctx, l)
+		switch {
+		case errors.Is(err, errSkipped):
+			c.stats.Skipped++
+		case err != nil:
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			c.stats.Failed++
+			if len(c.errs) < maxErrors {
+				c.errs = append(c.errs, fmt.Errorf("%s: %w", l.url, err))
+			}
+		}
+		queue = append(queue, next...)
+	}
+	return nil
+}
+
+// visit fetches and indexes the page at l, returning the links to follow
+// from it.
+func (c *crawler) visit(ctx context.Context, l link) ([]link, error) {
+	rb := c.robotsFor(ctx, l.url)
+	if !rb.allowed(requestPath(l.url)) {
+		return nil, errSkipped
+	}
+	c.wait(ctx, l.url.Host, rb.delay)
+	doc, final, modified, directives, err := c.fetch(ctx, l.url)
+	if err != nil {
+		return nil, err
+	}
+	if key := final.String(); key != l.url.String() {
+		if !c.s.inScope(final) {
+			return nil, errSkipped
+		}
+		c.seen[key] = true
+	}
+
+	p := parsePage(doc)
+	p.noindex = p.noindex || hasDirective(directives, "noindex")
+	p.nofollow = p.nofollow || hasDirective(directives, "nofollow")
+	base := final
+	if p.base != "" {
+		if b, err := final.Parse(p.base); err == nil {
+			base = b
+		}
+	}
+
+	var next []link
+	if !p.nofollow && (l.depth < c.s.cfg.MaxDepth) {
+		for _, href := range p.links {
+			u, err := base.Parse(href)
+			if err != nil {
+				continue
+			}
+			u = normalize(u)
+			if key := u.String(); !c.seen[key] && c.s.inScope(u) {
+				c.seen[key] = true
+				next = append(next, link{u, l.depth + 1})
+			}
+		}
+	}
+
+	if p.noindex {
+		return next, errSkipped
+	}
+	addr := final.String()
+	if p.canonical != "" {
+		if u, err := base.Parse(p.canonical); err == nil && c.s.inScope(normalize(u)) {
+			addr = normalize(u).String()
+		}
+	}
+	if c.indexed[addr] {
+		return next, errSkipped
+	}
+	d := newDocument(addr, p, modified)
+	if len(d.sections) == 0 {
+		return next, errSkipped
+	}
+	c.indexed[addr] = true
+	c.docs = append(c.docs, d)
+	c.stats.Pages++
+	return next, nil
+}
+
+// fetch gets the HTML page at u, returning it, its address after
+// redirects, when it was last modified, and its X-Robots-Tag directives.
+// Documents other than HTML fail with errSkipped.
+func (c *crawler) fetch(ctx context.Context, u *url.URL) (doc string, final *url.URL, modified time.Time, directives string, err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.s.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", nil, time.Time{}, "", err
+	}
+	req.Header.Set("User-Agent", c.s.cfg.UserAgent)
+	req.Header.Set("Accept", "text/html, application/xhtml+xml;q=0.9, */*;q=0.1")
+	client := *c.s.cfg.HTTPClient
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return c.checkRedirect(ctx, req, via)
+	}
+	resp, err := client.Do(req)
+	c.last[u.Host] = time.Now()
+	if errors.Is(err, errSkipped) {
+		return "", nil, time.Time{}, "", errSkipped
+	}
+	if err != nil {
+		return "", nil, time.Time{}, "", fmt.Errorf("%w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return "", nil, time.Time{}, "", httpx.StatusError(resp)
+	}
+	if mt, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mt != "text/html" && mt != "application/xhtml+xml" {
+		return "", nil, time.Time{}, "", errSkipped
+	}
+	doc, err = httpx.ReadText(resp, c.s.cfg.MaxPageBytes)
+	if err != nil {
+		return "", nil, time.Time{}, "", errSkipped
+	}
+	modified, _ = http.ParseTime(resp.Header.Get("Last-Modified"))
+	directives = strings.Join(resp.Header.Values("X-Robots-Tag"), ",")
+	return doc, normalize(resp.Request.URL), modified, directives, nil
+}
+
+// checkRedirect lets the crawl follow a redirect only to an address it
+// could visit itself: in scope and allowed by its host's robots.txt. Pages
+// redirecting elsewhere, such as to internal addresses, are skipped
+// before the target is requested.
+func (c *crawler) checkRedirect(ctx context.Context, req *http.Request, via []*http.Request) error {
+	if len(via) >= maxRedirects {
+		return fmt.Errorf("stopped after %d redirects", maxRedirects)
+	}
+	u := normalize(req.URL)
+	if !c.s.inScope(u) || !c.robotsFor(ctx, u).allowed(requestPath(u)) {
+		return errSkipped
+	}
+	return nil
+}
+
+// robotsFor returns the robots.txt rules of u's host, fetching them on
+// first use. A missing file allows everything; one that cannot be read
+// because the server fails allows nothing, as RFC 9309 requires.
+func (c *crawler) robotsFor(ctx context.Context, u *url.URL) robots {
+	origin := u.Scheme + "://" + u.Host
+	if rb, ok := c.robots[origin]; ok {
+		return rb
+	}
+	rb := c.fetchRobots(ctx, origin)
+	rb.delay = min(rb.delay, maxCrawlDelay)
+	c.robots[origin] = rb
+	return rb
+}
+
+func (c *crawler) fetchRobots(ctx context.Context, origin string) robots {
+	ctx, cancel := context.WithTimeout(ctx, c.s.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, origin+"/robots.txt", nil)
+	if err != nil {
+		return robots{deny: true}
+	}
+	req.Header.Set("User-Agent", c.s.cfg.UserAgent)
+	resp, err := c.s.cfg.HTTPClient.Do(req)
+	c.last[req.URL.Host] = time.Now()
+	if err != nil {
+		return robots{deny: true}
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 500:
+		return robots{deny: true}
+	case resp.StatusCode >= 400:
+		return robots{}
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsBytes))
+	if err != nil {
+		return robots{deny: true}
+	}
+	return parseRobots(string(body), productToken(c.s.cfg.UserAgent))
+}
+
+// wait sleeps until the host may be sent another request: Config.Delay,
+// or the robots.txt Crawl-delay if longer, after the last one.
+func (c *crawler) wait(ctx context.Context, host string, crawlDelay time.Duration) {
+	delay := max(c.s.cfg.Delay, crawlDelay)
+	last, ok := c.last[host]
+	if !ok || delay <= 0 {
+		return
+	}
+	t := time.NewTimer(time.Until(last.Add(delay)))
+	defer t.Stop()
+	select {
+	case <-t.C:
+	case <-ctx.Done():
+	}
+}
+
+// inScope reports whether the crawl may visit u: an http or https address
+// on one of the domains, under one of the path prefixes, and not of a
+// file type that is rarely HTML.
+func (s *Source) inScope(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	host := u.Hostname()
+	inDomain := false
+	for _, d := range s.domains {
+		if host == d || strings.HasSuffix(host, "."+d) {
+			inDomain = true
+			break
+		}
+	}
+	if !inDomain || skippedExtensions[strings.ToLower(path.Ext(u.Path))] {
+		return false
+	}
+	if len(s.cfg.PathPrefixes) == 0 {
+		return true
+	}
+	for _, p := range s.cfg.PathPrefixes {
+		if strings.HasPrefix(u.Path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalize returns u without its fragment and default port, with its
+// scheme and host lowercased and an empty path made "/", so that
+// addresses of the same page compare equal.
+func normalize(u *url.URL) *url.URL {
+	n := *u
+	n.Fragment, n.RawFragment = "", ""
+	n.Scheme = strings.ToLower(n.Scheme)
+	n.Host = strings.ToLower(n.Host)
+	if port := n.Port(); port == "80" && n.Scheme == "http" || port == "443" && n.Scheme == "https" {
+		n.Host = n.Hostname()
+	}
+	if n.Path == "" {
+		n.Path, n.RawPath = "/", ""
+	}
+	n.User = nil
+	return &n
+}
+
+// requestPath returns the path and query of u, as robots.txt rules match
+// them.
+func requestPath(u *url.URL) string {
+	if u.RawQuery != "" {
+		return u.EscapedPath() + "?" + u.RawQuery
+	}
+	return u.EscapedPath()
+}
+
+// productToken returns the product token of a User-Agent, such as
+// "my-crawler" for "my-crawler/1.0 (+https://example.com)".
+func productToken(userAgent string) string {
+	if f := strings.FieldsFunc(userAgent, func(r rune) bool { return r == '/' || r == ' ' }); len(f) > 0 {
+		return f[0]
+	}
+	return userAgent
+}
+
+// pageID derives a positive topic ID from a page's address.
+func pageID(addr string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(addr))
+	return int64(h.Sum64()>>1) | 1
+}
@@ -0,0 +1,365 @@
+package webcrawl
+
+import (
+	"html"
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// tag is a start or end tag of an HTML document.
+type tag struct {
+	name       string            // lowercase
+	attrs      map[string]string // names lowercase, values unescaped
+	closing    bool
+	start, end int // offsets of the tag in the document
+}
+
+// scan calls fn with the tags of doc in order until fn returns false.
+// Comments, doctypes, and the content of scripts and styles are skipped.
+func scan(doc string, fn func(t tag) bool) {
+	for i := 0; i < len(doc); {
+		j := strings.IndexByte(doc[i:], '<')
+		if j < 0 {
+			return
+		}
+		i += j
+		if strings.HasPrefix(doc[i:], "<!--") {
+			k := strings.Index(doc[i+4:], "-->")
+			if k < 0 {
+				return
+			}
+			i += 4 + k + 3
+			continue
+		}
+		t, n := parseTag(doc[i:])
+		if n == 0 {
+			i++
+			continue
+		}
+		t.start, t.end = i, i+n
+		if t.name != "" && !fn(t) {
+			return
+		}
+		i += n
+		if !t.closing && (t.name == "script" || t.name == "style") {
+			k := indexFold(doc[i:], "</"+t.name)
+			if k < 0 {
+				return
+			}
+			i += k
+		}
+	}
+}
+
+// parseTag parses the tag s begins with, returning its length, or 0 if s
+// does not begin with one. Declarations and processing instructions are
+// returned without a name.
+func parseTag(s string) (tag, int) {
+	if len(s) < 2 {
+		return tag{}, 0
+	}
+	if s[1] == '!' || s[1] == '?' {
+		k := strings.IndexByte(s, '>')
+		if k < 0 {
+			return tag{}, len(s)
+		}
+		return tag{}, k + 1
+	}
+	var t tag
+	i := 1
+	if s[1] == '/' {
+		t.closing = true
+		i++
+	}
+	j := i
+	for j < len(s) && isNameByte(s[j]) {
+		j++
+	}
+	if j == i || !isLetter(s[i]) {
+		return tag{}, 0
+	}
+	t.name = strings.ToLower(s[i:j])
+	t.attrs = make(map[string]string)
+	for i = j; i < len(s); {
+		switch c := s[i]; {
+		case c == '>':
+			return t, i + 1
+		case c == '/' || isSpace(c):
+			i++
+			continue
+		}
+		j = i
+		for j < len(s) && s[j] != '=' && s[j] != '>' && s[j] != '/' && !isSpace(s[j]) {
+			j++
+		}
+		name := strings.ToLower(s[i:j])
+		for j < len(s) && isSpace(s[j]) {
+			j++
+		}
+		if j >= len(s) || s[j] != '=' {
+			t.attrs[name] = ""
+			i = j
+			continue
+		}
+		j++
+		for j < len(s) && isSpace(s[j]) {
+			j++
+		}
+		var value string
+		if j < len(s) && (s[j] == '"' || s[j] == '\'') {
+			k := strings.IndexByte(s[j+1:], s[j])
+			if k < 0 {
+				return t, len(s)
+			}
+			value, i = s[j+1:j+1+k], j+k+2
+		} else {
+			i = j
+			for i < len(s) && s[i] != '>' && !isSpace(s[i]) {
+				i++
+			}
+			value = s[j:i]
+		}
+		if _, dup := t.attrs[name]; !dup {
+			t.attrs[name] = html.UnescapeString(value)
+		}
+	}
+	return t, len(s)
+}
+
+func isNameByte(c byte) bool {
+	return isLetter(c) || c >= '0' && c <= '9' || c == '-' || c == ':'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// indexFold is strings.Index ignoring ASCII case.
+func indexFold(s, substr string) int {
+	n := len(substr)
+	for i := 0; i+n <= len(s); i++ {
+		if strings.EqualFold(s[i:i+n], substr) {
+			return i
+		}
+	}
+	return -1
+}
+
+// page is what the crawler reads from an HTML document.
+type page struct {
+	title       string
+	description string
+	language    string
+	base        string   // the <base> address, if any
+	canonical   string   // the canonical address, if declared
+	links       []string // hrefs of links to follow, unresolved
+	noindex     bool
+	nofollow    bool
+	main        string // the HTML of the main content
+}
+
+// chromeTags are the elements left out of the main content when a page
+// does not mark it with <main> or <article>.
+var chromeTags = []string{"nav", "header", "footer", "aside"}
+
+// parsePage reads a page from doc. Its title is the document's <title>,
+// else its first <h1>. The main content is the page's <main> element, else
+// its <article> elements, else its body less navigation, headers, footers,
+// and sidebars.
+func parsePage(doc string) page {
+	var (
+		p                        page
+		title, h1, main, article = unset, unset, unset, unset
+		body                     = span{0, len(doc)}
+	)
+	scan(doc, func(t tag) bool {
+		if t.closing {
+			switch t.name {
+			case "title":
+				title.close(t.start)
+			case "h1":
+				h1.close(t.start)
+			case "main":
+				main.close(t.start)
+			case "article":
+				article.end = t.start // the last one
+			case "body":
+				body.end = t.start
+			}
+			return true
+		}
+		switch t.name {
+		case "html":
+			p.language = strings.TrimSpace(t.attrs["lang"])
+		case "title":
+			title.open(t.end)
+		case "h1":
+			h1.open(t.end)
+		case "main":
+			main.open(t.end)
+		case "article":
+			article.open(t.end)
+		case "body":
+			body.start = t.end
+		case "base":
+			if p.base == "" {
+				p.base = strings.TrimSpace(t.attrs["href"])
+			}
+		case "meta":
+			switch strings.ToLower(t.attrs["name"]) {
+			case "description":
+				p.description = strings.TrimSpace(t.attrs["content"])
+			case "robots":
+				p.noindex = p.noindex || hasDirective(t.attrs["content"], "noindex")
+				p.nofollow = p.nofollow || hasDirective(t.attrs["content"], "nofollow")
+			}
+		case "link":
+			if p.canonical == "" && hasDirective(t.attrs["rel"], "canonical") {
+				p.canonical = strings.TrimSpace(t.attrs["href"])
+			}
+		case "a":
+			if href := strings.TrimSpace(t.attrs["href"]); href != "" && !hasDirective(t.attrs["rel"], "nofollow") {
+				p.links = append(p.links, href)
+			}
+		}
+		return true
+	})
+
+	p.title = text(title.of(doc))
+	if p.title == "" {
+		p.title = text(h1.of(doc))
+	}
+	switch {
+	case main.ok():
+		p.main = main.of(doc)
+	case article.ok():
+		p.main = article.of(doc)
+	default:
+		p.main = cut(body.of(doc), chromeTags...)
+	}
+	return p
+}
+
+// span is the content of an element in a document.
+type span struct{ start, end int }
+
+// unset is a span not found yet.
+var unset = span{-1, -1}
+
+// open records the start of the first such element.
+func (s *span) open(at int) {
+	if s.start < 0 {
+		s.start = at
+	}
+}
+
+// close records the end of the first such element.
+func (s *span) close(at int) {
+	if s.start >= 0 && s.end < 0 {
+		s.end = at
+	}
+}
+
+func (s span) ok() bool { return s.start >= 0 && s.end > s.start }
+
+func (s span) of(doc string) string {
+	if !s.ok() {
+		return ""
+	}
+	return doc[s.start:s.end]
+}
+
+// cut removes the elements named from doc, with their content.
+func cut(doc string, names ...string) string {
+	for _, name := range names {
+		for {
+			var t tag
+			scan(doc, func(found tag) bool {
+				if found.name == name && !found.closing {
+					t = found
+					return false
+				}
+				return true
+			})
+			if t.name == "" {
+				break
+			}
+			end := len(doc)
+			if k := indexFold(doc[t.end:], "</"+name); k >= 0 {
+				end = t.end + k
+				if g := strings.IndexByte(doc[end:], '>'); g >= 0 {
+					end += g + 1
+				}
+			}
+			doc = doc[:t.start] + doc[end:]
+		}
+	}
+	return doc
+}
+
+// hasDirective reports whether the comma- or space-separated list has the
+// directive, ignoring case.
+func hasDirective(list, directive string) bool {
+	for _, d := range strings.FieldsFunc(list, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if strings.EqualFold(d, directive) {
+			return true
+		}
+	}
+	return false
+}
+
+// text returns an HTML fragment as a line of plain text.
+func text(fragment string) string {
+	return strings.Join(strings.Fields(content.StripHTML(fragment)), " ")
+}
+
+// section is a part of a page's main content, under a heading.
+type section struct {
+	heading string
+	anchor  datasource.Anchor // the heading's id, if it has one
+	html    string
+}
+
+// sections splits a page's main content at its <h2> and <h3> headings.
+// Content before the first heading is the lead, without a heading;
+// sections without text are dropped.
+func sections(main string) []section {
+	var (
+		out   []section
+		cur   section
+		from  int
+		inH   = -1 // offset of the open heading's content, or -1
+		hName string
+	)
+	flush := func(to int) {
+		cur.html = main[from:to]
+		if strings.TrimSpace(content.StripHTML(cur.html)) != "" {
+			out = append(out, cur)
+		}
+	}
+	scan(main, func(t tag) bool {
+		switch {
+		case !t.closing && (t.name == "h2" || t.name == "h3") && inH < 0:
+			flush(t.start)
+			cur = section{anchor: datasource.Anchor(t.attrs["id"])}
+			inH, hName = t.end, t.name
+		case t.closing && t.name == hName && inH >= 0:
+			cur.heading = text(main[inH:t.start])
+			from, inH = t.end, -1
+		case !t.closing && inH >= 0 && cur.anchor == "" && t.attrs["id"] != "":
+			cur.anchor = datasource.Anchor(t.attrs["id"]) // <h2><a id="...">
+		}
+		return true
+	})
+	if inH >= 0 {
+		from = len(main)
+	}
+	flush(len(main))
+	return out
+}
@@ -0,0 +1,131 @@
+package webcrawl
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// robots is the part of a site's robots.txt that applies to the crawler,
+// as specified by RFC 9309.
+type robots struct {
+	rules []rule
+	delay time.Duration // Crawl-delay, a common extension
+	deny  bool          // the file could not be read, so nothing is allowed
+}
+
+type rule struct {
+	allow   bool
+	pattern string
+}
+
+// group is a group of a robots.txt file: the rules for its user agents.
+type group struct {
+	agents []string
+	robots
+}
+
+// parseRobots reads the rules of a robots.txt file for the user agent with
+// the product token agent: those of the groups naming the longest token
+// agent begins with, merged, else those of the groups for "*".
+func parseRobots(body, agent string) robots {
+	var (
+		groups []*group
+		cur    *group
+	)
+	for _, line := range strings.Split(body, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "user-agent":
+			if cur == nil || len(cur.rules) > 0 || cur.delay > 0 {
+				cur = &group{}
+				groups = append(groups, cur)
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if cur != nil && value != "" {
+				cur.rules = append(cur.rules, rule{allow: key == "allow", pattern: value})
+			}
+		case "crawl-delay":
+			if cur != nil {
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					cur.delay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	agent = strings.ToLower(agent)
+	best := -1
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a != "*" && strings.HasPrefix(agent, a) {
+				best = max(best, len(a))
+			}
+		}
+	}
+	var r robots
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if best < 0 && a == "*" || best >= 0 && a != "*" && len(a) == best && strings.HasPrefix(agent, a) {
+				r.rules = append(r.rules, g.rules...)
+				r.delay = max(r.delay, g.delay)
+				break
+			}
+		}
+	}
+	return r
+}
+
+// allowed reports whether the rules allow the path, with its query. The
+// longest matching rule wins, and Allow wins ties; no match allows.
+func (r robots) allowed(path string) bool {
+	if r.deny {
+		return false
+	}
+	allow, longest := true, -1
+	for _, rl := range r.rules {
+		if !match(rl.pattern, path) {
+			continue
+		}
+		if n := len(rl.pattern); n > longest || n == longest && rl.allow {
+			allow, longest = rl.allow, n
+		}
+	}
+	return allow
+}
+
+// match reports whether a robots.txt path pattern matches path: patterns
+// match prefixes, '*' matches any characters, and a final '$' anchors the
+// pattern at the end.
+func match(pattern, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	parts := strings.Split(pattern, "*")
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+	if len(parts) == 1 {
+		return !anchored || rest == ""
+	}
+	last := parts[len(parts)-1]
+	for _, p := range parts[1 : len(parts)-1] {
+		i := strings.Index(rest, p)
+		if i < 0 {
+			return false
+		}
+		rest = rest[i+len(p):]
+	}
+	if anchored {
+		return strings.HasSuffix(rest, last)
+	}
+	return strings.Contains(rest, last)
+}
@@ -0,0 +1,74 @@
+package webcrawl
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRobots(t *testing.T) {
+	body := `User-agent: *
+Disallow: /
+
+User-agent: docs-bot
+User-agent: docs
+Disallow: /tmp/
+Disallow: /*.php$
+Allow: /tmp/keep
+Crawl-delay: 2.5
+
+User-agent: docs-bot
+Disallow: /drafts # merged with the group above
+`
+	rb := parseRobots(body, "docs-bot")
+	if rb.delay != 2500*time.Millisecond {
+		t.Errorf("delay = %s", rb.delay)
+	}
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{"/guide", true},
+		{"/tmp/x", false},
+		{"/tmp/keep/x", true},
+		{"/index.php", false},
+		{"/index.php?x=1", true},
+		{"/drafts/1", false},
+	}
+	for _, tt := range tests {
+		if got := rb.allowed(tt.path); got != tt.want {
+			t.Errorf("allowed(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	if parseRobots(body, "other").allowed("/guide") {
+		t.Error("other agents not given the * group")
+	}
+	if !parseRobots("", "docs-bot").allowed("/anything") {
+		t.Error("empty robots.txt disallows")
+	}
+	if (robots{deny: true}).allowed("/") {
+		t.Error("unreadable robots.txt allows")
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		pattern, path string
+		want          bool
+	}{
+		{"/fish", "/fish.html", true},
+		{"/fish", "/Fish", false},
+		{"/fish*", "/fishheads/yummy", true},
+		{"/*.php", "/folder/index.php5", true},
+		{"/*.php$", "/folder/index.php5", false},
+		{"/fish*.php", "/fish/a.php", true},
+		{"/fish*.php", "/fish/a.html", false},
+		{"/$", "/", true},
+		{"/$", "/a", false},
+	}
+	for _, tt := range tests {
+		if got := match(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("match(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
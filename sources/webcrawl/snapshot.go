@@ -0,0 +1,97 @@
+package webcrawl
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// snapshotVersion is the version of the snapshot format.
+const snapshotVersion = 1
+
+// snapshot is the JSON form of an index.
+type snapshot struct {
+	Version int            `json:"version"`
+	Stats   Stats          `json:"stats"`
+	Pages   []snapshotPage `json:"pages"`
+}
+
+type snapshotPage struct {
+	URL         string            `json:"url"`
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Language    string            `json:"language,omitempty"`
+	Modified    time.Time         `json:"modified,omitempty"`
+	Sections    []snapshotSection `json:"sections"`
+}
+
+type snapshotSection struct {
+	Heading string            `json:"heading,omitempty"`
+	Anchor  datasource.Anchor `json:"anchor,omitempty"`
+	HTML    string            `json:"html"`
+}
+
+// WriteSnapshot writes the index, with the statistics of the crawl that
+// built it, to w. It fails if the site has not been crawled.
+func (s *Source) WriteSnapshot(w io.Writer) error {
+	s.mu.RLock()
+	ix, stats := s.index, s.stats
+	s.mu.RUnlock()
+	if ix == nil {
+		return errors.New("webcrawl: write snapshot: not crawled yet")
+	}
+	snap := snapshot{Version: snapshotVersion, Stats: stats, Pages: make([]snapshotPage, 0, len(ix.docs))}
+	for _, d := range ix.docs {
+		p := snapshotPage{URL: d.url, Title: d.title, Description: d.description, Language: d.language, Modified: d.modified}
+		for _, sec := range d.sections {
+			p.Sections = append(p.Sections, snapshotSection{sec.heading, sec.anchor, sec.html})
+		}
+		snap.Pages = append(snap.Pages, p)
+	}
+	sort.Slice(snap.Pages, func(i, j int) bool { return snap.Pages[i].URL < snap.Pages[j].URL })
+	if err := json.NewEncoder(w).Encode(snap); err != nil {
+		return fmt.Errorf("webcrawl: write snapshot: %w", err)
+	}
+	return nil
+}
+
+// ReadSnapshot replaces the index with one written by WriteSnapshot, so
+// that Init does not crawl. Call Crawl to refresh it.
+func (s *Source) ReadSnapshot(r io.Reader) error {
+	var snap snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return fmt.Errorf("webcrawl: read snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return fmt.Errorf("webcrawl: read snapshot: unsupported version %d", snap.Version)
+	}
+	if len(snap.Pages) == 0 {
+		return errors.New("webcrawl: read snapshot: no pages")
+	}
+	docs := make([]*document, 0, len(snap.Pages))
+	for _, p := range snap.Pages {
+		d := &document{
+			id:          pageID(p.URL),
+			url:         p.URL,
+			title:       p.Title,
+			description: p.Description,
+			language:    p.Language,
+			modified:    p.Modified,
+		}
+		for _, sec := range p.Sections {
+			d.sections = append(d.sections, section{sec.Heading, sec.Anchor, sec.HTML})
+		}
+		d.index()
+		docs = append(docs, d)
+	}
+	ix := newIndex(docs)
+	s.mu.Lock()
+	s.index, s.stats = ix, snap.Stats
+	s.mu.Unlock()
+	return nil
+}
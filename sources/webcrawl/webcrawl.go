@@ -0,0 +1,372 @@
+// Package webcrawl is a data source answering from a website without an
+// API, such as a documentation site. Init crawls the site and builds a
+// local search index; topics are the indexed pages ranked by BM25 against
+// the question, and a topic's data items are the sections of the page's
+// main content, as Markdown, each deep-linking to its heading:
+//
+//	ds := webcrawl.New(webcrawl.Config{
+//	    Seeds:        []string{"https://docs.example.com/"},
+//	    PathPrefixes: []string{"/guide/", "/reference/"},
+//	    UserAgent:    "example-docs-bot/1.0 (+https://example.com/bot)",
+//	})
+//	if err := ds.Init(); err != nil { ... }
+//
+// The crawler is polite: it follows robots.txt, including Crawl-delay,
+// and robots meta tags and headers, waits Config.Delay between requests
+// to a host, stays within Config.Domains and Config.PathPrefixes, and
+// stops at Config.MaxDepth links from a seed and Config.MaxPages pages.
+// Call Crawl to rebuild the index, for instance on a schedule; questions
+// are answered from the previous index until it completes.
+//
+// WriteSnapshot exports the index, and ReadSnapshot loads an export before
+// Init, so that new replicas can answer from an index built ahead of time,
+// such as in CI, instead of crawling on start.
+package webcrawl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/content"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultMaxDepth     = 5
+	DefaultMaxPages     = 1000
+	DefaultDelay        = time.Second
+	DefaultUserAgent    = "locus-datasource-sdk (https://github.com/locus-search/datasource-sdk)"
+	DefaultMaxPageBytes = 5 << 20
+	DefaultTimeout      = 30 * time.Second
+)
+
+// Metadata keys set on topics and data items.
+const (
+	MetadataDescription = "description" // the page's meta description
+	MetadataSection     = "section"     // the heading of the section, unset for the lead
+)
+
+// Config configures a Source.
+type Config struct {
+	// Seeds lists the addresses the crawl starts from, such as the home
+	// page of a documentation site
+	Seeds []string
+
+	// Domains lists the hosts the crawl may visit, subdomains included
+	// Defaults to the hosts of Seeds
+	Domains []string
+
+	// PathPrefixes restricts the crawl to addresses whose path begins with
+	// one of them, such as "/docs/"
+	// Optional
+	PathPrefixes []string
+
+	// MaxDepth is how many links away from a seed the crawl goes; a
+	// negative depth crawls only the seeds
+	// Defaults to DefaultMaxDepth
+	MaxDepth int
+
+	// MaxPages bounds the pages indexed
+	// Defaults to DefaultMaxPages
+	MaxPages int
+
+	// Delay is the least time between requests to a host, lengthened by a
+	// robots.txt Crawl-delay; a negative delay waits only as robots.txt
+	// asks
+	// Defaults to DefaultDelay
+	Delay time.Duration
+
+	// UserAgent identifies the crawler to sites; its product token, such
+	// as "my-crawler" in "my-crawler/1.0", selects robots.txt rules
+	// Defaults to DefaultUserAgent
+	UserAgent string
+
+	// MaxPageBytes bounds the pages read; longer pages are skipped
+	// Defaults to DefaultMaxPageBytes
+	MaxPageBytes int64
+
+	// Timeout bounds each request of the crawl
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// HTTPClient makes the requests; its CheckRedirect is replaced for
+	// pages, which are redirected only to addresses in scope and allowed
+	// by robots.txt
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func (c Config) withDefaults() Config {
+	if c.MaxDepth == 0 {
+		c.MaxDepth = DefaultMaxDepth
+	}
+	if c.MaxPages <= 0 {
+		c.MaxPages = DefaultMaxPages
+	}
+	if c.Delay == 0 {
+		c.Delay = DefaultDelay
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = DefaultUserAgent
+	}
+	if c.MaxPageBytes <= 0 {
+		c.MaxPageBytes = DefaultMaxPageBytes
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+// Stats describes a crawl.
+type Stats struct {
+	// Pages is the number of pages indexed
+	Pages int `json:"pages"`
+
+	// Skipped is the number of pages not indexed by choice: disallowed by
+	// robots.txt or marked noindex, not HTML, too large, empty, or
+	// duplicates of indexed pages
+	Skipped int `json:"skipped"`
+
+	// Failed is the number of pages that could not be fetched
+	Failed int `json:"failed"`
+
+	// Start is when the crawl started
+	Start time.Time `json:"start"`
+
+	// Duration is how long the crawl took
+	Duration time.Duration `json:"duration"`
+}
+
+// Source is a DataSource searching a crawled website. Topic IDs are
+// derived from page addresses, so they are stable across crawls. A Source
+// is safe for concurrent use.
+type Source struct {
+	cfg     Config
+	domains []string
+
+	crawling sync.Mutex // held by Crawl
+
+	mu    sync.RWMutex
+	index *index
+	stats Stats
+}
+
+var (
+	_ datasource.DataSource         = (*Source)(nil)
+	_ datasource.CapabilityReporter = (*Source)(nil)
+	_ datasource.HealthChecker      = (*Source)(nil)
+	_ datasource.Snapshotter        = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	s := &Source{cfg: cfg.withDefaults(), domains: cfg.Domains}
+	if len(s.domains) == 0 {
+		for _, seed := range cfg.Seeds {
+			if u, err := url.Parse(seed); err == nil && u.Hostname() != "" {
+				s.domains = append(s.domains, u.Hostname())
+			}
+		}
+	}
+	return s
+}
+
+// Init crawls the site, failing if no page could be indexed, unless
+// ReadSnapshot has loaded an index.
+func (s *Source) Init() error {
+	s.mu.RLock()
+	loaded := s.index != nil
+	s.mu.RUnlock()
+	if loaded {
+		return nil
+	}
+	_, err := s.Crawl(context.Background())
+	return err
+}
+
+// Crawl crawls the site again and replaces the index with the pages
+// found. If no page could be indexed, or ctx is done first, the index is
+// kept and an error returned.
+func (s *Source) Crawl(ctx context.Context) (Stats, error) {
+	if len(s.cfg.Seeds) == 0 {
+		return Stats{}, errors.New("webcrawl: no seeds configured")
+	}
+	seeds := make([]*url.URL, 0, len(s.cfg.Seeds))
+	for _, seed := range s.cfg.Seeds {
+		u, err := url.Parse(seed)
+		if err != nil || !s.inScope(normalize(u)) {
+			return Stats{}, fmt.Errorf("webcrawl: seed %q is not an http or https address within the configured domains and paths", seed)
+		}
+		seeds = append(seeds, normalize(u))
+	}
+
+	s.crawling.Lock()
+	defer s.crawling.Unlock()
+	c := &crawler{
+		s:       s,
+		robots:  make(map[string]robots),
+		last:    make(map[string]time.Time),
+		seen:    make(map[string]bool),
+		indexed: make(map[string]bool),
+		stats:   Stats{Start: time.Now()},
+	}
+	err := c.crawl(ctx, seeds)
+	c.stats.Duration = time.Since(c.stats.Start)
+	switch {
+	case err != nil:
+		return c.stats, fmt.Errorf("webcrawl: %w", err)
+	case len(c.docs) == 0 && len(c.errs) > 0:
+		return c.stats, fmt.Errorf("webcrawl: no pages indexed: %w", errors.Join(c.errs...))
+	case len(c.docs) == 0:
+		return c.stats, fmt.Errorf("webcrawl: no pages indexed: %d skipped", c.stats.Skipped)
+	}
+
+	ix := newIndex(c.docs)
+	s.mu.Lock()
+	s.index, s.stats = ix, c.stats
+	s.mu.Unlock()
+	return c.stats, nil
+}
+
+// Stats returns the statistics of the crawl that built the index.
+func (s *Source) Stats() Stats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats
+}
+
+func (s *Source) current() (*index, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.index == nil {
+		return nil, fmt.Errorf("webcrawl: %w: not crawled yet", datasource.ErrUnavailable)
+	}
+	return s.index, nil
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck reports the source unhealthy until a crawl has built the
+// index, and degraded if more of the pages of that crawl failed than
+// were indexed. It does not contact the site.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	h := datasource.HealthStatus{State: datasource.Healthy}
+	switch {
+	case s.index == nil:
+		h.State, h.Error = datasource.Unhealthy, "webcrawl: not crawled yet"
+	case s.stats.Failed > s.stats.Pages:
+		h.State, h.Error = datasource.Degraded, fmt.Sprintf("webcrawl: %d pages failed, %d indexed", s.stats.Failed, s.stats.Pages)
+	}
+	return h
+}
+
+// Capabilities reports several sites if more than one domain is crawled.
+func (s *Source) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{MultiSite: len(s.domains) > 1}
+}
+
+// FetchTopics returns the pages matching the question, best match first.
+// A page's title counts more than its text. A topic's Score is its BM25
+// score relative to the best match's, between 0 and 1.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	ix, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	query := terms(input.SearchText())
+	if count <= 0 || len(query) == 0 {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	hits := ix.search(query)
+	topics := make([]datasource.DataSourceTopic, 0, len(hits))
+	for _, h := range hits {
+		if datasource.AcceptsLanguage(input.AcceptLanguages, h.doc.language) {
+			topics = append(topics, topic(h.doc, h.score/hits[0].score))
+		}
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// site returns the host of a page's address.
+func site(addr string) string {
+	if u, err := url.Parse(addr); err == nil {
+		return u.Host
+	}
+	return addr
+}
+
+func topic(d *document, score float64) datasource.DataSourceTopic {
+	t := datasource.DataSourceTopic{
+		Topic:     d.title,
+		SourceURL: d.url,
+		Site:      site(d.url),
+		TopicID:   d.id,
+		Score:     score,
+		Language:  d.language,
+		UpdatedAt: d.modified,
+	}
+	t = t.Structured(d.title, d.description)
+	if t.Topic == "" {
+		t.Topic = d.url
+	}
+	if d.description != "" {
+		t.Metadata.Set(MetadataDescription, d.description)
+	}
+	return t
+}
+
+// FetchData returns up to count sections of the page, lead first, as
+// Markdown. AnswerIDs number the sections from 0 for the lead.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	ix, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	d, ok := ix.docs[topicID]
+	if !ok {
+		return nil, fmt.Errorf("webcrawl: page %d: %w", topicID, datasource.ErrNotFound)
+	}
+	items := make([]datasource.DataSourceData, 0, min(max(count, 0), len(d.sections)))
+	for i, sec := range d.sections {
+		if len(items) >= count {
+			break
+		}
+		item := datasource.DataSourceData{
+			DataText:    content.HTMLToMarkdown(sec.html),
+			ContentType: datasource.ContentMarkdown,
+			SourceURL:   d.url,
+			Site:        site(d.url),
+			AnswerID:    int64(i),
+			Rank:        i + 1,
+			Language:    d.language,
+			UpdatedAt:   d.modified,
+		}
+		if sec.heading != "" {
+			item.Metadata.Set(MetadataSection, sec.heading)
+		}
+		if sec.anchor != "" {
+			item = item.Anchored(sec.anchor)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
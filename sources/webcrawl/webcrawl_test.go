@@ -0,0 +1,365 @@
+package webcrawl_test
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/webcrawl"
+)
+
+const robotsTxt = `# docs robots
+User-agent: *
+Disallow: /private/
+Allow: /private/public$
+
+User-agent: other-bot
+Disallow: /
+`
+
+var pages = map[string]string{
+	"/": `<!DOCTYPE html><html lang="en"><head><title>Acme Docs</title>
+<meta name="description" content="Documentation for Acme."></head>
+<body><nav><a href="/guide/install">Install</a> <a href="/guide/deploy#top">Deploy</a></nav>
+<main><h1>Welcome</h1><p>Acme is a deployment tool.</p>
+<p><a href="/private/secret">Secret</a> <a href="/private/public">Public</a>
+<a href="https://elsewhere.example/">Elsewhere</a> <a href="/files/manual.pdf">Manual</a>
+<a href="/about" rel="nofollow">About</a> <a href="/hidden">Hidden</a> <a href="/old">Old</a></p></main>
+<footer>Copyright Acme</footer></body></html>`,
+	"/guide/install": `<html lang="en"><head><title>Installing Acme</title></head><body>
+<header>Acme Docs</header>
+<p>Download the binary for your platform.</p>
+<h2 id="linux">Linux</h2><p>Use the package manager to install Acme on Linux.</p>
+<h2><a id="windows"></a>Windows</h2><p>Run the installer.</p>
+<h2 id="empty">Empty</h2>
+<footer>Deploy, deploy, deploy.</footer></body></html>`,
+	"/guide/deploy": `<html><head><title>Deploying</title></head><body><article>
+<p>Deploy Acme with <code>acme deploy</code>. Deployments roll out gradually.</p>
+<p><a href="/guide/deploy/advanced">Advanced</a></p></article></body></html>`,
+	"/guide/deploy/advanced": `<html><head><title>Advanced deployment</title></head><body><p>Canary deploys.</p></body></html>`,
+	"/private/secret":        `<html><head><title>Secret deploy plans</title></head><body><p>Deploy secrets.</p></body></html>`,
+	"/private/public":        `<html><head><title>Public notes</title></head><body><p>Notes anyone may read.</p></body></html>`,
+	"/about":                 `<html><head><title>About</title></head><body><p>About deploy.</p></body></html>`,
+	"/hidden": `<html><head><title>Hidden deploy page</title><meta name="robots" content="noindex"></head>
+<body><p>Not indexed.</p><a href="/hidden/child">Child</a></body></html>`,
+	"/hidden/child": `<html><head><title>Child of hidden</title></head><body><p>Reached through a noindex page.</p></body></html>`,
+	"/old":          `<html><head><title>Install (old)</title><link rel="canonical" href="/guide/install"></head><body><p>Duplicate.</p></body></html>`,
+}
+
+type site struct {
+	mu       sync.Mutex
+	requests []string
+	agents   map[string]bool
+}
+
+func (s *site) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests = append(s.requests, r.URL.Path)
+	s.agents[r.UserAgent()] = true
+	s.mu.Unlock()
+	if r.URL.Path == "/robots.txt" {
+		w.Write([]byte(robotsTxt))
+		return
+	}
+	doc, ok := pages[r.URL.Path]
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Last-Modified", "Mon, 02 Mar 2026 10:00:00 GMT")
+	w.Write([]byte(doc))
+}
+
+func (s *site) fetched(path string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range s.requests {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+func newSource(t *testing.T, cfg webcrawl.Config) (*webcrawl.Source, *site, string) {
+	t.Helper()
+	s := &site{agents: make(map[string]bool)}
+	srv := httptest.NewServer(s)
+	t.Cleanup(srv.Close)
+	cfg.Seeds = append(cfg.Seeds, srv.URL+"/")
+	if cfg.Delay == 0 {
+		cfg.Delay = -1
+	}
+	return webcrawl.New(cfg), s, srv.URL
+}
+
+func titles(topics []datasource.DataSourceTopic) string {
+	var s []string
+	for _, t := range topics {
+		s = append(s, t.Topic)
+	}
+	return strings.Join(s, "|")
+}
+
+func TestCrawl(t *testing.T) {
+	ds, site, _ := newSource(t, webcrawl.Config{})
+	if _, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "deploy"}); !errors.Is(err, datasource.ErrUnavailable) {
+		t.Errorf("FetchTopics before Init: err = %v, want ErrUnavailable", err)
+	}
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	stats := ds.Stats()
+	// Indexed: the home page, install, deploy, advanced, and public
+	// notes. Skipped: the noindex page and the duplicate of install.
+	if stats.Pages != 6 || stats.Skipped != 3 || stats.Failed != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+	for _, path := range []string{"/private/secret", "/about", "/files/manual.pdf"} {
+		if site.fetched(path) {
+			t.Errorf("%s fetched", path)
+		}
+	}
+	if !site.fetched("/hidden/child") {
+		t.Error("links of a noindex page not followed")
+	}
+	if !site.agents[webcrawl.DefaultUserAgent] || len(site.agents) != 1 {
+		t.Errorf("user agents = %v", site.agents)
+	}
+}
+
+func TestFetchTopics(t *testing.T) {
+	ds, _, base := newSource(t, webcrawl.Config{})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	topics, err := ds.FetchTopics(3, datasource.NewQuestionInput{QuestionText: "How do I deploy?"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The title counts most; text in navigation and footers is not indexed.
+	if got := titles(topics); !strings.HasPrefix(got, "Deploying|") || strings.Contains(got, "Installing") {
+		t.Fatalf("topics = %s", got)
+	}
+	first := topics[0]
+	if first.Score != 1 || first.Rank != 1 || first.SourceURL != base+"/guide/deploy" || first.TopicID <= 0 ||
+		!first.UpdatedAt.Equal(time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("first topic = %+v", first)
+	}
+
+	topics, _ = ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "acme", AcceptLanguages: []string{"de"}})
+	for _, tp := range topics {
+		if tp.Language != "" {
+			t.Errorf("topic %q in %q returned for German", tp.Topic, tp.Language)
+		}
+	}
+	if topics, _ := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "kubernetes"}); len(topics) != 0 {
+		t.Errorf("topics = %s, want none", titles(topics))
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	ds, _, base := newSource(t, webcrawl.Config{})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	topics, _ := ds.FetchTopics(1, datasource.NewQuestionInput{QuestionText: "installing linux"})
+	if len(topics) != 1 || topics[0].SourceURL != base+"/guide/install" {
+		t.Fatalf("topics = %+v", topics)
+	}
+
+	items, err := ds.FetchData(5, topics[0].TopicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("items = %+v, want the lead and two sections", items)
+	}
+	if items[0].DataText != "Download the binary for your platform." || items[0].SourceURL != base+"/guide/install" {
+		t.Errorf("lead = %+v", items[0])
+	}
+	if s, _ := items[1].Metadata.String(webcrawl.MetadataSection); s != "Linux" || items[1].SourceURL != base+"/guide/install#linux" ||
+		items[1].ContentType != datasource.ContentMarkdown || items[1].AnswerID != 1 {
+		t.Errorf("section = %+v", items[1])
+	}
+	if items[2].SourceURL != base+"/guide/install#windows" {
+		t.Errorf("anchor of a heading with a named link = %q", items[2].SourceURL)
+	}
+	if items, _ := ds.FetchData(1, topics[0].TopicID); len(items) != 1 {
+		t.Errorf("FetchData(1) = %d items", len(items))
+	}
+	if _, err := ds.FetchData(5, 12345); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("unknown page: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLimits(t *testing.T) {
+	ds, site, _ := newSource(t, webcrawl.Config{MaxDepth: 1, PathPrefixes: []string{"/", "/guide/"}})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if site.fetched("/guide/deploy/advanced") {
+		t.Error("page two links away fetched with MaxDepth 1")
+	}
+
+	ds, _, _ = newSource(t, webcrawl.Config{MaxPages: 2})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if stats := ds.Stats(); stats.Pages != 2 {
+		t.Errorf("%d pages indexed, want MaxPages", stats.Pages)
+	}
+
+	ds, site, _ = newSource(t, webcrawl.Config{MaxDepth: -1})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if site.fetched("/guide/install") {
+		t.Error("links followed with a negative MaxDepth")
+	}
+}
+
+func TestRobots(t *testing.T) {
+	ds, _, _ := newSource(t, webcrawl.Config{UserAgent: "Other-Bot/2.0"})
+	if err := ds.Init(); err == nil {
+		t.Error("Init() succeeded with every page disallowed")
+	}
+	if h := ds.HealthCheck(); h.State != datasource.Unhealthy {
+		t.Errorf("health = %+v, want unhealthy without an index", h)
+	}
+
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	if err := webcrawl.New(webcrawl.Config{Seeds: []string{down.URL}, Delay: -1}).Init(); err == nil {
+		t.Error("Init() succeeded with robots.txt unavailable")
+	}
+}
+
+func TestRedirects(t *testing.T) {
+	var internalHits atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		internalHits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(`<html><head><title>Internal</title></head><body><p>Metadata.</p></body></html>`))
+	}))
+	defer internal.Close()
+	// Another host name for the same machine, outside the crawl's scope.
+	elsewhere := strings.Replace(internal.URL, "127.0.0.1", "localhost", 1)
+
+	s := &site{agents: make(map[string]bool)}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/":
+			w.Header().Set("Content-Type", "text/html")
+			w.Write([]byte(`<html><head><title>Home</title></head><body><p>Links.</p>
+<a href="/out">Out</a> <a href="/in">In</a> <a href="/disallowed">Disallowed</a></body></html>`))
+		case "/out":
+			http.Redirect(w, r, elsewhere+"/latest/meta-data", http.StatusFound)
+		case "/in":
+			http.Redirect(w, r, "/guide/install", http.StatusFound)
+		case "/disallowed":
+			http.Redirect(w, r, "/private/secret", http.StatusMovedPermanently)
+		default:
+			s.ServeHTTP(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	ds := webcrawl.New(webcrawl.Config{Seeds: []string{srv.URL + "/"}, Delay: -1})
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if n := internalHits.Load(); n != 0 {
+		t.Errorf("redirect out of scope followed: %d requests", n)
+	}
+	if s.fetched("/private/secret") {
+		t.Error("redirect to a page robots.txt disallows followed")
+	}
+	if !s.fetched("/guide/install") {
+		t.Error("redirect within scope not followed")
+	}
+	if stats := ds.Stats(); stats.Pages != 2 || stats.Skipped != 2 || stats.Failed != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+}
+
+func TestPoliteness(t *testing.T) {
+	ds, _, _ := newSource(t, webcrawl.Config{Delay: 20 * time.Millisecond, MaxPages: 3})
+	start := time.Now()
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	// robots.txt and three pages: three waits.
+	if elapsed := time.Since(start); elapsed < 60*time.Millisecond {
+		t.Errorf("crawl took %s, want Delay between requests", elapsed)
+	}
+}
+
+func TestSeeds(t *testing.T) {
+	if err := webcrawl.New(webcrawl.Config{}).Init(); err == nil {
+		t.Error("Init() without seeds succeeded")
+	}
+	cfg := webcrawl.Config{Seeds: []string{"https://docs.example.com/"}, Domains: []string{"example.org"}}
+	if err := webcrawl.New(cfg).Init(); err == nil {
+		t.Error("Init() with a seed outside the domains succeeded")
+	}
+	if err := webcrawl.New(webcrawl.Config{Seeds: []string{"ftp://example.com/"}}).Init(); err == nil {
+		t.Error("Init() with an ftp seed succeeded")
+	}
+}
+
+func TestSnapshot(t *testing.T) {
+	ds, _, _ := newSource(t, webcrawl.Config{})
+	var buf bytes.Buffer
+	if err := ds.WriteSnapshot(&buf); err == nil {
+		t.Error("WriteSnapshot before crawling succeeded")
+	}
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ds.WriteSnapshot(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	// A replica restores the snapshot instead of crawling its site.
+	replica, site, _ := newSource(t, webcrawl.Config{})
+	if err := replica.ReadSnapshot(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if err := replica.Init(); err != nil {
+		t.Fatal(err)
+	}
+	if site.fetched("/") {
+		t.Error("Init crawled after ReadSnapshot")
+	}
+	q := datasource.NewQuestionInput{QuestionText: "deploy"}
+	want, _ := ds.FetchTopics(5, q)
+	got, err := replica.FetchTopics(5, q)
+	if err != nil || titles(got) != titles(want) {
+		t.Errorf("restored FetchTopics = %q, %v; want %q", titles(got), err, titles(want))
+	}
+	wantData, _ := ds.FetchData(5, want[0].TopicID)
+	gotData, _ := replica.FetchData(5, want[0].TopicID)
+	if len(gotData) != len(wantData) || gotData[0].DataText != wantData[0].DataText {
+		t.Errorf("restored FetchData = %+v, want %+v", gotData, wantData)
+	}
+	if got, want := replica.Stats(), ds.Stats(); got.Pages != want.Pages || !got.Start.Equal(want.Start) {
+		t.Errorf("restored Stats = %+v, want %+v", got, want)
+	}
+
+	if err := replica.ReadSnapshot(strings.NewReader(`{"version": 1, "pages": []}`)); err == nil {
+		t.Error("ReadSnapshot accepted a snapshot without pages")
+	}
+}
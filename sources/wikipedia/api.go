@@ -0,0 +1,133 @@
+package wikipedia
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// maxResponseBytes bounds response bodies read from the API.
+const maxResponseBytes = 16 << 20
+
+// response is the envelope of an action=query response in format version 2.
+type response struct {
+	Continue map[string]any  `json:"continue"`
+	Query    json.RawMessage `json:"query"`
+	Error    *struct {
+		Code string `json:"code"`
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// query calls the API of the site at index i with action=query and params,
+// and decodes the response's query object into into. It returns the
+// parameters continuing the query, or nil on its last batch.
+func (s *Source) query(ctx context.Context, i int, params url.Values, into any) (url.Values, error) {
+	endpoint := s.endpoint(s.cfg.Sites[i])
+	params.Set("action", "query")
+	params.Set("format", "json")
+	params.Set("formatversion", "2")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("wikipedia: %w", err)
+	}
+	req.Header.Set("User-Agent", s.cfg.UserAgent)
+	if s.cfg.AccessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+s.cfg.AccessToken)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("wikipedia: %w: %w", datasource.ErrUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	var r response
+	decodeErr := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&r)
+	switch {
+	case resp.StatusCode >= 300:
+		return nil, fmt.Errorf("wikipedia: %w", httpx.StatusError(resp))
+	case decodeErr != nil:
+		return nil, fmt.Errorf("wikipedia: decode response: %w", decodeErr)
+	case r.Error != nil:
+		return nil, apiError(resp, r.Error.Code, r.Error.Info)
+	}
+	if len(r.Query) > 0 {
+		if err := json.Unmarshal(r.Query, into); err != nil {
+			return nil, fmt.Errorf("wikipedia: decode query: %w", err)
+		}
+	}
+	if len(r.Continue) == 0 {
+		return nil, nil
+	}
+	next := url.Values{}
+	for k, v := range r.Continue {
+		next.Set(k, fmt.Sprint(v))
+	}
+	return next, nil
+}
+
+// apiError converts an API error response into an error wrapping the
+// matching SDK error. The API reports most errors with status 200.
+func apiError(resp *http.Response, code, info string) error {
+	err := fmt.Errorf("wikipedia: %s: %s", code, info)
+	switch {
+	case code == "ratelimited" || code == "maxlag":
+		return &datasource.ErrRateLimited{RetryAfter: httpx.ParseRetryAfter(resp.Header.Get("Retry-After")), Err: err}
+	case code == "readapidenied" || code == "permissiondenied" || strings.HasPrefix(code, "mwoauth-"):
+		return fmt.Errorf("%w: %w", datasource.ErrUnauthorized, err)
+	case code == "nosuchpageid" || code == "missingtitle":
+		return fmt.Errorf("%w: %w", datasource.ErrNotFound, err)
+	case strings.HasPrefix(code, "internal_api_error"):
+		return fmt.Errorf("%w: %w", datasource.ErrUnavailable, err)
+	}
+	return err
+}
+
+// endpoint returns the API address of site.
+func (s *Source) endpoint(site string) string {
+	if e, ok := s.cfg.Endpoints[site]; ok {
+		return e
+	}
+	return siteURL(site) + "/w/api.php"
+}
+
+// siteURL returns the address of the wiki named site: the Wikipedia
+// edition for a language code, such as "https://de.wikipedia.org" for
+// "de", or the host itself for a host name.
+func siteURL(site string) string {
+	if strings.Contains(site, ".") {
+		return "https://" + site
+	}
+	return "https://" + site + ".wikipedia.org"
+}
+
+// wikimedia are the domains of Wikimedia projects with language editions.
+var wikimedia = []string{
+	".wikipedia.org", ".wiktionary.org", ".wikibooks.org", ".wikinews.org",
+	".wikiquote.org", ".wikisource.org", ".wikiversity.org", ".wikivoyage.org",
+}
+
+// siteLanguage returns the language of the site's content, or "" if it is
+// not known.
+func siteLanguage(site string) string {
+	lang := site
+	if strings.Contains(site, ".") {
+		lang = ""
+		for _, d := range wikimedia {
+			if prefix, ok := strings.CutSuffix(site, d); ok && !strings.Contains(prefix, ".") {
+				lang = prefix
+			}
+		}
+	}
+	if lang == "simple" {
+		return "en"
+	}
+	return lang
+}
@@ -0,0 +1,82 @@
+package wikipedia
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// section is a section of an article's plain-text extract.
+type section struct {
+	number  int // as in action=parse&section=N; 0 is the lead
+	heading string
+	anchor  datasource.Anchor
+	text    string
+}
+
+// headingLine matches a heading of an extract in wiki section format, such
+// as "== History ==".
+var headingLine = regexp.MustCompile(`^(={2,6})\s*(.*?)\s*={2,6}$`)
+
+// sections splits an extract in wiki section format into the lead and the
+// text under each heading, leaving out sections with no text of their own
+// and those under a heading in skip, subsections included.
+func sections(extract string, skip []string) []section {
+	var (
+		out       []section
+		cur       section
+		body      []string
+		skipLevel int // level of the skipped heading being inside, or 0
+		anchors   = make(map[string]int)
+	)
+	flush := func() {
+		cur.text = strings.TrimSpace(strings.Join(body, "\n"))
+		if cur.text != "" && skipLevel == 0 {
+			out = append(out, cur)
+		}
+		body = body[:0]
+	}
+	number := 0
+	for _, line := range strings.Split(extract, "\n") {
+		m := headingLine.FindStringSubmatch(strings.TrimSpace(line))
+		if m == nil {
+			body = append(body, line)
+			continue
+		}
+		flush()
+		number++
+		level := len(m[1])
+		if skipLevel > 0 && level <= skipLevel {
+			skipLevel = 0
+		}
+		if skipLevel == 0 && skipped(m[2], skip) {
+			skipLevel = level
+		}
+		cur = section{number: number, heading: m[2], anchor: anchor(m[2], anchors)}
+	}
+	flush()
+	return out
+}
+
+// anchor returns the fragment MediaWiki gives heading: spaces become
+// underscores, and repeated headings get a suffix from "_2" on. seen counts
+// the headings so far.
+func anchor(heading string, seen map[string]int) datasource.Anchor {
+	a := strings.ReplaceAll(heading, " ", "_")
+	seen[a]++
+	if n := seen[a]; n > 1 {
+		a += "_" + strconv.Itoa(n)
+	}
+	return datasource.Anchor(a)
+}
+
+func skipped(heading string, skip []string) bool {
+	for _, s := range skip {
+		if strings.EqualFold(heading, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,420 @@
+// Package wikipedia is a data source backed by the MediaWiki Action API of
+// Wikipedia or any other MediaWiki wiki. Topics are articles found with
+// search, and data items are the sections of an article's plain-text
+// extract, lead section first, each deep-linking to its heading:
+//
+//	ds := wikipedia.New(wikipedia.Config{
+//	    Sites:     []string{"en", "de"},
+//	    UserAgent: "my-bot/1.0 (https://example.com/bot; bot@example.com)",
+//	})
+//
+// Sites name the language editions to search, and are the Site of
+// results. Wikimedia asks API clients to identify themselves with a
+// descriptive User-Agent; set Config.UserAgent to one with contact
+// details. Rate-limited and lagged requests fail with
+// datasource.ErrRateLimited; wrap the source with middleware.Retry to
+// retry them.
+package wikipedia
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultSite      = "en"
+	DefaultUserAgent = "locus-datasource-sdk (https://github.com/locus-search/datasource-sdk)"
+	DefaultTimeout   = 15 * time.Second
+)
+
+// DefaultSkipSections are the sections of English articles left out of
+// data items: they list links and citations rather than content.
+var DefaultSkipSections = []string{
+	"See also", "References", "External links", "Further reading", "Notes",
+	"Notes and references", "Citations", "Sources", "Bibliography", "Footnotes",
+}
+
+// Metadata keys set on topics and data items.
+const (
+	MetadataDescription = "description" // the article's short description
+	MetadataSection     = "section"     // the heading of the section, unset for the lead
+)
+
+// pageSize is the largest page of search results the API returns to
+// clients without the bot right.
+const pageSize = 50
+
+// siteShift is the bit position of the site index packed into topic IDs,
+// above any page ID.
+const siteShift = 40
+
+// Config configures a Source.
+type Config struct {
+	// Sites lists the wikis to search: language codes of Wikipedia
+	// editions, such as "en", "de", or "simple", or host names of other
+	// MediaWiki wikis, such as "en.wikivoyage.org"
+	// Defaults to DefaultSite
+	Sites []string
+
+	// Endpoints maps sites to their API address, for wikis not serving it
+	// at /w/api.php
+	// Optional
+	Endpoints map[string]string
+
+	// UserAgent identifies the client to the API, and should include
+	// contact details
+	// Defaults to DefaultUserAgent
+	UserAgent string
+
+	// AccessToken is an OAuth 2 access token, for private wikis or higher
+	// rate limits
+	// Optional
+	AccessToken string
+
+	// SkipSections lists the headings of sections, and their subsections,
+	// left out of data items; they match case-insensitively. Set it to an
+	// empty slice to keep every section
+	// Defaults to DefaultSkipSections
+	SkipSections []string
+
+	// Timeout bounds each call, in addition to NewQuestionInput.Budget
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// HTTPClient makes the requests
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func (c Config) withDefaults() Config {
+	if len(c.Sites) == 0 {
+		c.Sites = []string{DefaultSite}
+	}
+	if c.UserAgent == "" {
+		c.UserAgent = DefaultUserAgent
+	}
+	if c.SkipSections == nil {
+		c.SkipSections = DefaultSkipSections
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+// Source is a DataSource searching Wikipedia editions or other MediaWiki
+// wikis. Topic IDs pack the index of the article's site in Config.Sites
+// above its page ID, so they are unique across sites; with one site they
+// are page IDs. A Source is safe for concurrent use.
+type Source struct {
+	cfg Config
+}
+
+var (
+	_ datasource.DataSource         = (*Source)(nil)
+	_ datasource.CapabilityReporter = (*Source)(nil)
+	_ datasource.HealthChecker      = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults()}
+}
+
+// Init checks that the API of every configured site answers.
+func (s *Source) Init() error {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	for i, site := range s.cfg.Sites {
+		if err := s.siteinfo(ctx, i); err != nil {
+			return fmt.Errorf("wikipedia: site %q: %w", site, err)
+		}
+	}
+	return nil
+}
+
+func (s *Source) siteinfo(ctx context.Context, i int) error {
+	var q struct{}
+	_, err := s.query(ctx, i, url.Values{"meta": {"siteinfo"}}, &q)
+	return err
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck reports the source unhealthy if the first site's API cannot
+// be reached, and degraded while it is rate limiting or lagging.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := s.siteinfo(ctx, 0)
+	h := datasource.HealthStatus{State: datasource.Healthy, Latency: time.Since(start)}
+	switch {
+	case datasource.IsRetryable(err):
+		h.State, h.Error = datasource.Degraded, err.Error()
+	case err != nil:
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	}
+	return h
+}
+
+// Capabilities reports pagination, and several sites if more than one is
+// configured.
+func (s *Source) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{
+		Pagination: true,
+		MultiSite:  len(s.cfg.Sites) > 1,
+	}
+}
+
+// page is a page of a query with prop=info|revisions|description.
+type page struct {
+	PageID      int64  `json:"pageid"`
+	Title       string `json:"title"`
+	Index       int    `json:"index"`
+	FullURL     string `json:"fullurl"`
+	Description string `json:"description"`
+	Extract     string `json:"extract"`
+	Missing     bool   `json:"missing"`
+	Revisions   []struct {
+		Timestamp time.Time `json:"timestamp"`
+	} `json:"revisions"`
+}
+
+func (p page) updated() time.Time {
+	if len(p.Revisions) == 0 {
+		return time.Time{}
+	}
+	return p.Revisions[0].Timestamp
+}
+
+// FetchTopics searches every configured site that passes the input's
+// site filter and accepted languages, concurrently, and interleaves their
+// results by rank. Results are returned from the sites that answered if
+// only some failed.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if count <= 0 || strings.TrimSpace(input.QuestionText) == "" {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	ctx, cancel = input.Budget.Context(ctx)
+	defer cancel()
+
+	type result struct {
+		topics []datasource.DataSourceTopic
+		err    error
+	}
+	results := make([]result, len(s.cfg.Sites))
+	var wg sync.WaitGroup
+	for i, site := range s.cfg.Sites {
+		if !s.searches(site, input) {
+			continue
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			topics, err := s.search(ctx, i, count, input)
+			results[i] = result{topics, err}
+		}(i)
+	}
+	wg.Wait()
+
+	var (
+		perSite [][]datasource.DataSourceTopic
+		errs    []error
+	)
+	for i, r := range results {
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("site %q: %w", s.cfg.Sites[i], r.err))
+		} else if r.topics != nil {
+			perSite = append(perSite, r.topics)
+		}
+	}
+	if len(perSite) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	topics := []datasource.DataSourceTopic{}
+	for rank := 0; len(topics) < count; rank++ {
+		added := false
+		for _, site := range perSite {
+			if rank < len(site) && len(topics) < count {
+				topics = append(topics, site[rank])
+				added = true
+			}
+		}
+		if !added {
+			break
+		}
+	}
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// searches reports whether FetchTopics searches site for input.
+func (s *Source) searches(site string, input datasource.NewQuestionInput) bool {
+	if sites := input.Filters.Sites; len(sites) > 0 {
+		found := false
+		for _, f := range sites {
+			found = found || strings.EqualFold(f, site)
+		}
+		if !found {
+			return false
+		}
+	}
+	return datasource.AcceptsLanguage(input.AcceptLanguages, siteLanguage(site))
+}
+
+// search returns up to count articles from the site at index i, paging
+// through results. Filters the API cannot apply are applied to each page.
+func (s *Source) search(ctx context.Context, i, count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	params := url.Values{
+		"generator":    {"search"},
+		"gsrsearch":    {input.QuestionText},
+		"gsrnamespace": {"0"},
+		"prop":         {"info|revisions|description"},
+		"inprop":       {"url"},
+		"rvprop":       {"timestamp"},
+	}
+	f := input.Filters
+	if f.Sort == datasource.SortRecency {
+		params.Set("gsrsort", "last_edit_desc")
+	}
+
+	topics := []datasource.DataSourceTopic{}
+	for len(topics) < count {
+		params.Set("gsrlimit", strconv.Itoa(min(count-len(topics), pageSize)))
+		var q struct {
+			Pages []page `json:"pages"`
+		}
+		next, err := s.query(ctx, i, params, &q)
+		if err != nil {
+			if len(topics) > 0 && ctx.Err() != nil {
+				break // out of time; return the pages we have
+			}
+			return nil, err
+		}
+		sort.Slice(q.Pages, func(a, b int) bool { return q.Pages[a].Index < q.Pages[b].Index })
+		batch := make([]datasource.DataSourceTopic, 0, len(q.Pages))
+		for _, p := range q.Pages {
+			if !p.Missing {
+				batch = append(batch, s.topic(i, p))
+			}
+		}
+		for _, t := range f.Topics(batch) {
+			if len(topics) < count {
+				topics = append(topics, t)
+			}
+		}
+		if next == nil || len(q.Pages) == 0 {
+			break
+		}
+		for k := range next {
+			params.Set(k, next.Get(k))
+		}
+	}
+	return topics, nil
+}
+
+func (s *Source) topic(i int, p page) datasource.DataSourceTopic {
+	site := s.cfg.Sites[i]
+	t := datasource.DataSourceTopic{
+		Topic:     p.Title,
+		SourceURL: p.FullURL,
+		Site:      site,
+		TopicID:   int64(i)<<siteShift | p.PageID,
+		Language:  siteLanguage(site),
+		UpdatedAt: p.updated(),
+	}
+	if t.SourceURL == "" {
+		t.SourceURL = articleURL(site, p.Title)
+	}
+	t = t.Structured(p.Title, p.Description)
+	if p.Description != "" {
+		t.Metadata.Set(MetadataDescription, p.Description)
+	}
+	return t
+}
+
+// articleURL returns the conventional address of the article titled title.
+func articleURL(site, title string) string {
+	return siteURL(site) + "/wiki/" + url.PathEscape(strings.ReplaceAll(title, " ", "_"))
+}
+
+// FetchData returns the sections of the article as plain text, lead
+// section first and then in article order. Each item's AnswerID is its
+// section number, 0 for the lead, and its SourceURL links to its heading.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	i, pageID := int(topicID>>siteShift), topicID&(1<<siteShift-1)
+	if topicID <= 0 || i >= len(s.cfg.Sites) {
+		return nil, fmt.Errorf("wikipedia: topic %d: %w", topicID, datasource.ErrNotFound)
+	}
+	if count <= 0 {
+		return []datasource.DataSourceData{}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	site := s.cfg.Sites[i]
+	params := url.Values{
+		"pageids":         {strconv.FormatInt(pageID, 10)},
+		"prop":            {"extracts|info|revisions"},
+		"explaintext":     {"1"},
+		"exsectionformat": {"wiki"},
+		"inprop":          {"url"},
+		"rvprop":          {"timestamp"},
+	}
+	var q struct {
+		Pages []page `json:"pages"`
+	}
+	if _, err := s.query(ctx, i, params, &q); err != nil {
+		return nil, err
+	}
+	if len(q.Pages) == 0 || q.Pages[0].Missing {
+		return nil, fmt.Errorf("wikipedia: page %d on %q: %w", pageID, site, datasource.ErrNotFound)
+	}
+	p := q.Pages[0]
+	if p.FullURL == "" {
+		p.FullURL = articleURL(site, p.Title)
+	}
+
+	secs := sections(p.Extract, s.cfg.SkipSections)
+	secs = secs[:min(len(secs), count)]
+	items := make([]datasource.DataSourceData, len(secs))
+	for j, sec := range secs {
+		d := datasource.DataSourceData{
+			DataText:    sec.text,
+			ContentType: datasource.ContentPlainText,
+			SourceURL:   p.FullURL,
+			Site:        site,
+			AnswerID:    int64(sec.number),
+			Rank:        j + 1,
+			Language:    siteLanguage(site),
+			UpdatedAt:   p.updated(),
+		}
+		if sec.heading != "" {
+			d.Metadata.Set(MetadataSection, sec.heading)
+			d = d.Anchored(sec.anchor)
+		}
+		items[j] = d
+	}
+	return items, nil
+}
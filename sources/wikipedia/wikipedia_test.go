@@ -0,0 +1,282 @@
+package wikipedia_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/wikipedia"
+)
+
+// api is a fake MediaWiki API serving every site under /<site>/api.php.
+// Handlers return the response for a site and query; requests are
+// recorded.
+type api struct {
+	mu       sync.Mutex
+	requests []*http.Request
+	handle   func(site string, q url.Values) map[string]any
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	a.requests = append(a.requests, r)
+	a.mu.Unlock()
+	site := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/"), "/api.php")
+	resp := a.handle(site, r.URL.Query())
+	if resp == nil {
+		resp = map[string]any{"batchcomplete": true}
+	}
+	if _, ok := resp["error"]; ok {
+		w.Header().Set("Retry-After", "5")
+	}
+	json.NewEncoder(w).Encode(resp)
+}
+
+func (a *api) calls(site string) []url.Values {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	var qs []url.Values
+	for _, r := range a.requests {
+		if r.URL.Path == "/"+site+"/api.php" {
+			qs = append(qs, r.URL.Query())
+		}
+	}
+	return qs
+}
+
+func newSource(t *testing.T, cfg wikipedia.Config, handle func(site string, q url.Values) map[string]any) (*wikipedia.Source, *api) {
+	t.Helper()
+	a := &api{handle: handle}
+	srv := httptest.NewServer(a)
+	t.Cleanup(srv.Close)
+	if len(cfg.Sites) == 0 {
+		cfg.Sites = []string{wikipedia.DefaultSite}
+	}
+	cfg.Endpoints = make(map[string]string)
+	for _, site := range cfg.Sites {
+		cfg.Endpoints[site] = srv.URL + "/" + site + "/api.php"
+	}
+	return wikipedia.New(cfg), a
+}
+
+func pages(ps ...map[string]any) map[string]any {
+	return map[string]any{"query": map[string]any{"pages": ps}}
+}
+
+func TestFetchTopics(t *testing.T) {
+	ds, a := newSource(t, wikipedia.Config{Sites: []string{"en", "de", "fr"}}, func(site string, q url.Values) map[string]any {
+		switch site {
+		case "en":
+			if q.Get("gsroffset") == "" {
+				resp := pages(
+					map[string]any{"pageid": 2, "title": "Second", "index": 2, "fullurl": "https://en.wikipedia.org/wiki/Second"},
+					map[string]any{"pageid": 1, "title": "Go (programming language)", "index": 1, "fullurl": "https://en.wikipedia.org/wiki/Go_(programming_language)",
+						"description": "Programming language", "revisions": []map[string]any{{"timestamp": "2026-01-02T03:04:05Z"}}},
+				)
+				resp["continue"] = map[string]any{"gsroffset": 2, "continue": "gsroffset||"}
+				return resp
+			}
+			return pages(map[string]any{"pageid": 3, "title": "Third", "index": 3})
+		case "de":
+			return pages(map[string]any{"pageid": 1, "title": "Go (Programmiersprache)", "index": 1, "fullurl": "https://de.wikipedia.org/wiki/Go_(Programmiersprache)"})
+		}
+		t.Errorf("searched site %q", site)
+		return nil
+	})
+	topics, err := ds.FetchTopics(4, datasource.NewQuestionInput{
+		QuestionText:    "go language",
+		AcceptLanguages: []string{"en", "de"},
+		Filters:         datasource.Filters{Sort: datasource.SortRecency},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got []string
+	for _, tp := range topics {
+		got = append(got, tp.Site+":"+tp.Topic)
+	}
+	if want := []string{"en:Go (programming language)", "de:Go (Programmiersprache)", "en:Second", "en:Third"}; strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Fatalf("topics = %q, want %q", got, want)
+	}
+	first := topics[0]
+	if first.TopicID != 1 || first.Rank != 1 || first.Language != "en" || !first.UpdatedAt.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("first topic = %+v", first)
+	}
+	if d, _ := first.Metadata.String(wikipedia.MetadataDescription); d != "Programming language" {
+		t.Errorf("description = %q", d)
+	}
+	if de := topics[1]; de.TopicID != 1<<40|1 || de.Language != "de" {
+		t.Errorf("second site's topic = %+v, want the site index packed above the page ID", de)
+	}
+	if third := topics[3]; third.SourceURL != "https://en.wikipedia.org/wiki/Third" {
+		t.Errorf("SourceURL = %q, want the article path when the API gives none", third.SourceURL)
+	}
+
+	qs := a.calls("en")
+	if len(qs) != 2 || qs[1].Get("gsroffset") != "2" || qs[1].Get("gsrlimit") != "2" {
+		t.Fatalf("requests = %v, want the second page continued", qs)
+	}
+	for k, want := range map[string]string{
+		"action": "query", "generator": "search", "gsrsearch": "go language", "gsrnamespace": "0",
+		"gsrsort": "last_edit_desc", "formatversion": "2",
+	} {
+		if got := qs[0].Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+	if ua := a.requests[0].Header.Get("User-Agent"); ua != wikipedia.DefaultUserAgent {
+		t.Errorf("User-Agent = %q", ua)
+	}
+}
+
+const extract = `Go is a programming language.
+It was designed at Google.
+
+== History ==
+Go was announced in 2009.
+
+== Design ==
+
+=== Syntax ===
+Go's syntax is C-like.
+
+== History ==
+A second section named History.
+
+== References ==
+Cited works.
+
+=== Notes ===
+A note.`
+
+func TestFetchData(t *testing.T) {
+	ds, a := newSource(t, wikipedia.Config{Sites: []string{"en", "de"}}, func(site string, q url.Values) map[string]any {
+		return pages(map[string]any{"pageid": 7, "title": "Go", "fullurl": "https://de.wikipedia.org/wiki/Go",
+			"extract": extract, "revisions": []map[string]any{{"timestamp": "2026-01-02T03:04:05Z"}}})
+	})
+	items, err := ds.FetchData(10, 1<<40|7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type item struct {
+		section string
+		number  int64
+		url     string
+	}
+	var got []item
+	for _, d := range items {
+		s, _ := d.Metadata.String(wikipedia.MetadataSection)
+		got = append(got, item{s, d.AnswerID, d.SourceURL})
+	}
+	want := []item{
+		{"", 0, "https://de.wikipedia.org/wiki/Go"},
+		{"History", 1, "https://de.wikipedia.org/wiki/Go#History"},
+		{"Syntax", 3, "https://de.wikipedia.org/wiki/Go#Syntax"},
+		{"History", 4, "https://de.wikipedia.org/wiki/Go#History_2"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("items = %+v, want %+v", got, want)
+	}
+	for j := range want {
+		if got[j] != want[j] {
+			t.Errorf("item %d = %+v, want %+v", j, got[j], want[j])
+		}
+	}
+	lead := items[0]
+	if lead.DataText != "Go is a programming language.\nIt was designed at Google." || lead.ContentType != datasource.ContentPlainText ||
+		lead.Site != "de" || lead.Language != "de" || lead.Rank != 1 || lead.UpdatedAt.IsZero() {
+		t.Errorf("lead = %+v", lead)
+	}
+	q := a.calls("de")
+	if len(q) != 1 || q[0].Get("pageids") != "7" || q[0].Get("explaintext") != "1" || q[0].Get("exsectionformat") != "wiki" {
+		t.Errorf("requests = %v", q)
+	}
+
+	if items, err := ds.FetchData(2, 7); err != nil || len(items) != 2 {
+		t.Errorf("FetchData(2) = %d items, %v", len(items), err)
+	}
+	if _, err := ds.FetchData(5, 5<<40|7); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("unknown site index: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFetchDataMissing(t *testing.T) {
+	ds, _ := newSource(t, wikipedia.Config{}, func(string, url.Values) map[string]any {
+		return pages(map[string]any{"pageid": 7, "missing": true})
+	})
+	if _, err := ds.FetchData(5, 7); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestSkipSections(t *testing.T) {
+	ds, _ := newSource(t, wikipedia.Config{SkipSections: []string{}}, func(string, url.Values) map[string]any {
+		return pages(map[string]any{"pageid": 7, "title": "Go", "extract": extract})
+	})
+	items, err := ds.FetchData(10, 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(items); n != 6 {
+		t.Errorf("%d items, want every section with text", n)
+	}
+	if items[0].SourceURL != "https://en.wikipedia.org/wiki/Go" {
+		t.Errorf("SourceURL = %q, want the article path when the API gives none", items[0].SourceURL)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		code   string
+		target error
+	}{
+		{"ratelimited", nil},
+		{"maxlag", nil},
+		{"readapidenied", datasource.ErrUnauthorized},
+		{"nosuchpageid", datasource.ErrNotFound},
+		{"internal_api_error_DBQueryError", datasource.ErrUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			ds, _ := newSource(t, wikipedia.Config{}, func(string, url.Values) map[string]any {
+				return map[string]any{"error": map[string]any{"code": tt.code, "info": "failed"}}
+			})
+			_, err := ds.FetchData(5, 1)
+			if tt.target == nil {
+				if d, ok := datasource.RetryAfter(err); !ok || d != 5*time.Second {
+					t.Errorf("err = %v, want rate limited for 5s", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.target) {
+				t.Errorf("err = %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	ds, a := newSource(t, wikipedia.Config{Sites: []string{"en", "xx"}}, func(site string, q url.Values) map[string]any {
+		if site == "xx" {
+			return map[string]any{"error": map[string]any{"code": "readapidenied", "info": "private wiki"}}
+		}
+		return nil
+	})
+	if err := ds.Init(); err == nil || !strings.Contains(err.Error(), `"xx"`) {
+		t.Errorf("Init() = %v, want the failing site reported", err)
+	}
+	if q := a.calls("en"); len(q) != 1 || q[0].Get("meta") != "siteinfo" {
+		t.Errorf("requests = %v", q)
+	}
+	if h := ds.HealthCheck(); h.State != datasource.Healthy {
+		t.Errorf("health = %+v", h)
+	}
+}
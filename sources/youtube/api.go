@@ -0,0 +1,129 @@
+package youtube
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// maxResponseBytes bounds response bodies read from the APIs.
+const maxResponseBytes = 16 << 20
+
+// snippet is the snippet part of a search result, playlist item, or video.
+type snippet struct {
+	PublishedAt          time.Time `json:"publishedAt"`
+	ChannelID            string    `json:"channelId"`
+	ChannelTitle         string    `json:"channelTitle"`
+	Title                string    `json:"title"`
+	Description          string    `json:"description"`
+	Tags                 []string  `json:"tags"`
+	DefaultLanguage      string    `json:"defaultLanguage"`
+	DefaultAudioLanguage string    `json:"defaultAudioLanguage"`
+	ResourceID           struct {
+		VideoID string `json:"videoId"`
+	} `json:"resourceId"`
+}
+
+// video is a video as the videos endpoint returns it.
+type video struct {
+	ID             string  `json:"id"`
+	Snippet        snippet `json:"snippet"`
+	ContentDetails struct {
+		Duration string `json:"duration"`
+	} `json:"contentDetails"`
+	Statistics struct {
+		ViewCount string `json:"viewCount"`
+		LikeCount string `json:"likeCount"`
+	} `json:"statistics"`
+}
+
+// listResponse is a page of the items of a list endpoint.
+type listResponse[T any] struct {
+	NextPageToken string `json:"nextPageToken"`
+	Items         []T    `json:"items"`
+}
+
+// searchResult is an item of a search response.
+type searchResult struct {
+	ID struct {
+		VideoID string `json:"videoId"`
+	} `json:"id"`
+	Snippet snippet `json:"snippet"`
+}
+
+// playlistItem is an item of a playlistItems response.
+type playlistItem struct {
+	Snippet snippet `json:"snippet"`
+}
+
+// list sends a GET request to the endpoint of the Data API with the
+// params and decodes the JSON response into into.
+func (s *Source) list(ctx context.Context, endpoint string, params url.Values, into any) error {
+	if s.cfg.APIKey != "" {
+		params.Set("key", s.cfg.APIKey)
+	}
+	u := strings.TrimSuffix(s.cfg.URL, "/") + "/" + endpoint + "?" + params.Encode()
+	resp, err := s.get(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return apiError(resp)
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(into); err != nil {
+		return fmt.Errorf("youtube: decode response: %w", err)
+	}
+	return nil
+}
+
+func (s *Source) get(ctx context.Context, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("youtube: %w", err)
+	}
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("youtube: %w: %w", datasource.ErrUnavailable, err)
+	}
+	return resp, nil
+}
+
+// apiError converts an error response of the Data API into an error
+// wrapping the matching SDK error. The API reports exhausted daily quotas
+// and throttling alike with status 403, told apart by the reason.
+func apiError(resp *http.Response) error {
+	var e struct {
+		Error struct {
+			Message string `json:"message"`
+			Errors  []struct {
+				Reason string `json:"reason"`
+			} `json:"errors"`
+		} `json:"error"`
+	}
+	json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&e)
+	reason := ""
+	if len(e.Error.Errors) > 0 {
+		reason = e.Error.Errors[0].Reason
+	}
+	err := fmt.Errorf("youtube: %s", e.Error.Message)
+	switch reason {
+	case "quotaExceeded", "dailyLimitExceeded":
+		return fmt.Errorf("%w: %w", datasource.ErrQuotaExceeded, err)
+	case "rateLimitExceeded", "userRateLimitExceeded":
+		return &datasource.ErrRateLimited{RetryAfter: httpx.ParseRetryAfter(resp.Header.Get("Retry-After")), Err: err}
+	}
+	if e.Error.Message == "" {
+		return fmt.Errorf("youtube: %w", httpx.StatusError(resp))
+	}
+	return fmt.Errorf("youtube: %w: %s", httpx.StatusError(resp), e.Error.Message)
+}
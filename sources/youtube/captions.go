@@ -0,0 +1,163 @@
+package youtube
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/httpx"
+)
+
+// cue is a caption shown from start for dur.
+type cue struct {
+	start, dur time.Duration
+	text       string
+}
+
+// segment is a run of cues served as one data item.
+type segment struct {
+	start, end time.Duration
+	text       string
+}
+
+// captions returns the cues of the video's captions in the first of the
+// configured languages it has captions in, preferring captions uploaded
+// by the channel to automatic ones, and that language.
+func (s *Source) captions(ctx context.Context, videoID string) ([]cue, string, error) {
+	kinds := []string{""}
+	if !s.cfg.ManualCaptionsOnly {
+		kinds = append(kinds, "asr")
+	}
+	for _, kind := range kinds {
+		for _, lang := range s.cfg.Languages {
+			params := url.Values{"v": {videoID}, "lang": {lang}}
+			if kind != "" {
+				params.Set("kind", kind)
+			}
+			cues, err := s.track(ctx, params)
+			if err != nil {
+				return nil, "", err
+			}
+			if len(cues) > 0 {
+				return cues, lang, nil
+			}
+		}
+	}
+	return nil, "", fmt.Errorf("youtube: video %s has no captions in %s: %w",
+		videoID, strings.Join(s.cfg.Languages, ", "), datasource.ErrNotFound)
+}
+
+// track fetches a caption track in the timedtext XML format. A missing
+// track is empty.
+func (s *Source) track(ctx context.Context, params url.Values) ([]cue, error) {
+	resp, err := s.get(ctx, s.cfg.CaptionsURL+"?"+params.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("youtube: captions: %w", httpx.StatusError(resp))
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("youtube: captions: %w: %w", datasource.ErrUnavailable, err)
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return nil, nil
+	}
+	return parseTrack(body)
+}
+
+// parseTrack parses a caption track of <text start="1.2" dur="3.4">
+// elements. Their text is escaped again within the XML.
+func parseTrack(body []byte) ([]cue, error) {
+	var t struct {
+		Texts []struct {
+			Start float64 `xml:"start,attr"`
+			Dur   float64 `xml:"dur,attr"`
+			Text  string  `xml:",chardata"`
+		} `xml:"text"`
+	}
+	if err := xml.Unmarshal(body, &t); err != nil {
+		return nil, fmt.Errorf("youtube: decode captions: %w", err)
+	}
+	cues := make([]cue, 0, len(t.Texts))
+	for _, x := range t.Texts {
+		cues = append(cues, cue{
+			start: seconds(x.Start),
+			dur:   seconds(x.Dur),
+			text:  html.UnescapeString(x.Text),
+		})
+	}
+	return cues, nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+var (
+	// annotation matches descriptions of sounds, such as "[Music]" and
+	// "(applause)".
+	annotation = regexp.MustCompile(`\[[^\]]*\]|\([A-Za-z ]*\)`)
+
+	// speaker matches the ">>" marking a change of speaker in automatic
+	// captions.
+	speaker = regexp.MustCompile(`^\s*>>\s*`)
+)
+
+// cleanCue returns the spoken text of a cue on one line, without sound
+// annotations or speaker change markers.
+func cleanCue(text string) string {
+	text = annotation.ReplaceAllString(text, " ")
+	text = speaker.ReplaceAllString(text, "")
+	return strings.Join(strings.Fields(text), " ")
+}
+
+// segments groups cues into segments of about target length. A segment
+// ends at the first cue ending a sentence once it is target long, or at
+// half again target if no sentence ends before then. Cues with no spoken
+// text are skipped.
+func segments(cues []cue, target time.Duration) []segment {
+	var out []segment
+	var cur segment
+	var parts []string
+	for _, c := range cues {
+		text := cleanCue(c.text)
+		if text == "" {
+			continue
+		}
+		if len(parts) == 0 {
+			cur.start = c.start
+		}
+		parts = append(parts, text)
+		cur.end = max(cur.end, c.start+c.dur)
+		if length := cur.end - cur.start; length >= target && endsSentence(text) || length >= target*3/2 {
+			cur.text = strings.Join(parts, " ")
+			out = append(out, cur)
+			cur, parts = segment{}, nil
+		}
+	}
+	if len(parts) > 0 {
+		cur.text = strings.Join(parts, " ")
+		out = append(out, cur)
+	}
+	return out
+}
+
+func endsSentence(text string) bool {
+	text = strings.TrimRight(text, `"')’”`)
+	return strings.HasSuffix(text, ".") || strings.HasSuffix(text, "?") || strings.HasSuffix(text, "!")
+}
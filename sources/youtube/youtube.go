@@ -0,0 +1,572 @@
+// Package youtube is a data source backed by the YouTube Data API and the
+// captions of videos. Topics are the videos of a channel or playlist
+// matching the question, and data items are timestamped segments of a
+// video's transcript, deep-linking to the moment they are spoken:
+//
+//	ds := youtube.New(youtube.Config{
+//	    APIKey:    os.Getenv("YOUTUBE_API_KEY"),
+//	    ChannelID: "UC_x5XG1OV2P6uZZ5FSM9Ttw",
+//	    Languages: []string{"en", "de"},
+//	})
+//
+// Channels are searched with the API's search endpoint, at 100 units of
+// the daily quota per page of results; playlists are listed, cached for
+// PlaylistTTL, and matched against the question locally. Exhausting the
+// quota fails calls with datasource.ErrQuotaExceeded.
+package youtube
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/similarity"
+)
+
+// Default configuration values used when fields are zero.
+const (
+	DefaultURL               = "https://www.googleapis.com/youtube/v3"
+	DefaultCaptionsURL       = "https://www.youtube.com/api/timedtext"
+	DefaultSegmentLength     = time.Minute
+	DefaultMaxPlaylistVideos = 500
+	DefaultPlaylistTTL       = time.Hour
+	DefaultTimeout           = 15 * time.Second
+)
+
+// Metadata keys set on topics and data items.
+const (
+	MetadataChannel  = "channel"  // the title of the video's channel
+	MetadataViews    = "views"    // the video's view count
+	MetadataDuration = "duration" // the video's length in seconds
+	MetadataStart    = "start"    // the second a segment starts at
+	MetadataEnd      = "end"      // the second a segment ends at
+)
+
+// maxResults is the most items the API returns in one page.
+const maxResults = 50
+
+// maxExcerptChars bounds the description kept as a topic's BodyExcerpt.
+const maxExcerptChars = 300
+
+// Config configures a Source. Exactly one of ChannelID and PlaylistID is
+// set.
+type Config struct {
+	// APIKey authenticates to the Data API
+	// Optional if HTTPClient authenticates requests
+	APIKey string
+
+	// ChannelID is the ID of the channel searched, such as
+	// "UC_x5XG1OV2P6uZZ5FSM9Ttw"
+	ChannelID string
+
+	// PlaylistID is the ID of the playlist searched, such as
+	// "PLOU2XLYxmsIIM9h1Ybw2DuRw6o2fkNMeR"
+	PlaylistID string
+
+	// Languages are the languages of the captions served, in order of
+	// preference
+	// Defaults to English
+	Languages []string
+
+	// ManualCaptionsOnly serves only captions uploaded by the channel,
+	// not those YouTube generates by speech recognition
+	ManualCaptionsOnly bool
+
+	// SegmentLength is the length a transcript segment is cut at, at the
+	// end of a sentence
+	// Defaults to DefaultSegmentLength
+	SegmentLength time.Duration
+
+	// MaxPlaylistVideos bounds the videos of the playlist searched
+	// Defaults to DefaultMaxPlaylistVideos
+	MaxPlaylistVideos int
+
+	// PlaylistTTL is how long the videos of the playlist are cached
+	// Defaults to DefaultPlaylistTTL
+	PlaylistTTL time.Duration
+
+	// URL is the address of the Data API
+	// Defaults to DefaultURL
+	URL string
+
+	// CaptionsURL is the address captions are fetched from
+	// Defaults to DefaultCaptionsURL
+	CaptionsURL string
+
+	// Timeout bounds each call, in addition to NewQuestionInput.Budget
+	// Defaults to DefaultTimeout
+	Timeout time.Duration
+
+	// HTTPClient makes the requests
+	// Defaults to http.DefaultClient
+	HTTPClient *http.Client
+}
+
+func (c Config) withDefaults() Config {
+	if len(c.Languages) == 0 {
+		c.Languages = []string{"en"}
+	}
+	if c.SegmentLength <= 0 {
+		c.SegmentLength = DefaultSegmentLength
+	}
+	if c.MaxPlaylistVideos <= 0 {
+		c.MaxPlaylistVideos = DefaultMaxPlaylistVideos
+	}
+	if c.PlaylistTTL <= 0 {
+		c.PlaylistTTL = DefaultPlaylistTTL
+	}
+	if c.URL == "" {
+		c.URL = DefaultURL
+	}
+	if c.CaptionsURL == "" {
+		c.CaptionsURL = DefaultCaptionsURL
+	}
+	if c.Timeout <= 0 {
+		c.Timeout = DefaultTimeout
+	}
+	if c.HTTPClient == nil {
+		c.HTTPClient = http.DefaultClient
+	}
+	return c
+}
+
+// Source is a DataSource searching the videos of a YouTube channel or
+// playlist. A Source is safe for concurrent use.
+type Source struct {
+	cfg Config
+
+	mu       sync.Mutex
+	playlist []snippet // cached videos of the playlist
+	listed   time.Time
+}
+
+var (
+	_ datasource.DataSource         = (*Source)(nil)
+	_ datasource.CapabilityReporter = (*Source)(nil)
+	_ datasource.HealthChecker      = (*Source)(nil)
+)
+
+// New returns a Source for cfg.
+func New(cfg Config) *Source {
+	return &Source{cfg: cfg.withDefaults()}
+}
+
+// Init checks that the channel or playlist exists.
+func (s *Source) Init() error {
+	if (s.cfg.ChannelID == "") == (s.cfg.PlaylistID == "") {
+		return errors.New("youtube: exactly one of ChannelID and PlaylistID must be set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	return s.check(ctx)
+}
+
+func (s *Source) CheckAvailability() bool {
+	return s.HealthCheck().State != datasource.Unhealthy
+}
+
+// HealthCheck reports whether the channel or playlist can be read.
+func (s *Source) HealthCheck() datasource.HealthStatus {
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := s.check(ctx)
+	h := datasource.HealthStatus{State: datasource.Healthy, Latency: time.Since(start)}
+	if err != nil {
+		h.State, h.Error = datasource.Unhealthy, err.Error()
+	}
+	return h
+}
+
+// check fails unless the channel or playlist exists.
+func (s *Source) check(ctx context.Context) error {
+	endpoint, id := "channels", s.cfg.ChannelID
+	if s.cfg.PlaylistID != "" {
+		endpoint, id = "playlists", s.cfg.PlaylistID
+	}
+	var resp listResponse[struct{}]
+	if err := s.list(ctx, endpoint, url.Values{"part": {"id"}, "id": {id}}, &resp); err != nil {
+		return err
+	}
+	if len(resp.Items) == 0 {
+		return fmt.Errorf("youtube: %s %s: %w", strings.TrimSuffix(endpoint, "s"), id, datasource.ErrNotFound)
+	}
+	return nil
+}
+
+// Capabilities reports pagination.
+func (s *Source) Capabilities() datasource.Capabilities {
+	return datasource.Capabilities{Pagination: true}
+}
+
+// FetchTopics searches the videos for the question, best match first. A
+// topic's Score is its number of likes, and its Site the ID of its
+// channel.
+func (s *Source) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	if count <= 0 || strings.TrimSpace(input.QuestionText) == "" {
+		return []datasource.DataSourceTopic{}, nil
+	}
+	ctx, cancel := input.Budget.Context(context.Background())
+	defer cancel()
+	ctx, cancel = context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+
+	var ids []string
+	var err error
+	if s.cfg.ChannelID != "" {
+		ids, err = s.searchChannel(ctx, count, input)
+	} else {
+		ids, err = s.searchPlaylist(ctx, input.QuestionText)
+		ids = ids[:min(len(ids), count)]
+	}
+	if err != nil {
+		return nil, err
+	}
+	videos, err := s.videos(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	topics := make([]datasource.DataSourceTopic, 0, len(ids))
+	for _, id := range ids {
+		v, ok := videos[id]
+		if !ok {
+			continue
+		}
+		if t, ok := topic(v); ok && datasource.AcceptsLanguage(input.AcceptLanguages, t.Language) {
+			topics = append(topics, t)
+		}
+	}
+	topics = input.Filters.Topics(topics)
+	topics = topics[:min(len(topics), count)]
+	for i := range topics {
+		topics[i].Rank = i + 1
+	}
+	return topics, nil
+}
+
+// FetchData returns the transcript of the video with the topic ID in
+// segments of about Config.SegmentLength, in order. An item's AnswerID is
+// the index of its segment, and its SourceURL starts the video at the
+// segment.
+func (s *Source) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	if count <= 0 {
+		return []datasource.DataSourceData{}, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	id := videoID(topicID)
+	videos, err := s.videos(ctx, []string{id})
+	if err != nil {
+		return nil, err
+	}
+	v, ok := videos[id]
+	if !ok || (s.cfg.ChannelID != "" && v.Snippet.ChannelID != s.cfg.ChannelID) {
+		return nil, fmt.Errorf("youtube: video %s: %w", id, datasource.ErrNotFound)
+	}
+	cues, lang, err := s.captions(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	segs := segments(cues, s.cfg.SegmentLength)
+	items := make([]datasource.DataSourceData, 0, min(len(segs), count))
+	for i, seg := range segs[:min(len(segs), count)] {
+		var m datasource.Metadata
+		m.Set(MetadataStart, int(seg.start/time.Second))
+		m.Set(MetadataEnd, int((seg.end+time.Second-1)/time.Second))
+		items = append(items, datasource.DataSourceData{
+			DataText:    seg.text,
+			ContentType: datasource.ContentPlainText,
+			SourceURL:   watchURL(id, seg.start),
+			Site:        v.Snippet.ChannelID,
+			AnswerID:    int64(i),
+			Rank:        i + 1,
+			Metadata:    m,
+			Language:    lang,
+			CreatedAt:   v.Snippet.PublishedAt,
+		})
+	}
+	return items, nil
+}
+
+// searchChannel returns the IDs of the channel's videos matching the
+// question, best match first, with the input's filters as parameters.
+func (s *Source) searchChannel(ctx context.Context, count int, input datasource.NewQuestionInput) ([]string, error) {
+	params := url.Values{
+		"part":      {"snippet"},
+		"type":      {"video"},
+		"channelId": {s.cfg.ChannelID},
+		"q":         {input.QuestionText},
+		"order":     {"relevance"},
+	}
+	f := input.Filters
+	if f.Sort == datasource.SortRecency {
+		params.Set("order", "date")
+	}
+	if !f.After.IsZero() {
+		params.Set("publishedAfter", f.After.UTC().Format(time.RFC3339))
+	}
+	if !f.Before.IsZero() {
+		params.Set("publishedBefore", f.Before.UTC().Format(time.RFC3339))
+	}
+	if len(input.AcceptLanguages) > 0 {
+		lang, _, _ := strings.Cut(input.AcceptLanguages[0], "-")
+		params.Set("relevanceLanguage", lang)
+	}
+	var ids []string
+	for len(ids) < count {
+		params.Set("maxResults", strconv.Itoa(min(count-len(ids), maxResults)))
+		var resp listResponse[searchResult]
+		if err := s.list(ctx, "search", params, &resp); err != nil {
+			return nil, err
+		}
+		for _, r := range resp.Items {
+			if r.ID.VideoID != "" {
+				ids = append(ids, r.ID.VideoID)
+			}
+		}
+		if resp.NextPageToken == "" || len(resp.Items) == 0 {
+			break
+		}
+		params.Set("pageToken", resp.NextPageToken)
+	}
+	return ids, nil
+}
+
+// searchPlaylist returns the IDs of the playlist's videos whose title or
+// description contain words of the question, best match first. Each word
+// found weighs by its rarity among the videos, and double in the title.
+func (s *Source) searchPlaylist(ctx context.Context, question string) ([]string, error) {
+	videos, err := s.playlistVideos(ctx)
+	if err != nil {
+		return nil, err
+	}
+	type doc struct {
+		id          string
+		title, desc map[string]bool
+		score       float64
+	}
+	docs := make([]doc, len(videos))
+	freq := make(map[string]int)
+	for i, v := range videos {
+		docs[i] = doc{id: v.ResourceID.VideoID, title: words(v.Title), desc: words(v.Description)}
+		for w := range docs[i].title {
+			freq[w]++
+		}
+		for w := range docs[i].desc {
+			if !docs[i].title[w] {
+				freq[w]++
+			}
+		}
+	}
+	terms := words(question)
+	var matches []doc
+	for _, d := range docs {
+		for w := range terms {
+			idf := math.Log(float64(len(docs)+1) / float64(freq[w]+1))
+			if d.title[w] {
+				d.score += 2 * idf
+			}
+			if d.desc[w] {
+				d.score += idf
+			}
+		}
+		if d.score > 0 {
+			matches = append(matches, d)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool { return matches[i].score > matches[j].score })
+	ids := make([]string, len(matches))
+	for i, m := range matches {
+		ids[i] = m.id
+	}
+	return ids, nil
+}
+
+// playlistVideos returns the snippets of the playlist's videos, listing
+// them if the cached list is older than Config.PlaylistTTL.
+func (s *Source) playlistVideos(ctx context.Context) ([]snippet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.playlist != nil && time.Since(s.listed) < s.cfg.PlaylistTTL {
+		return s.playlist, nil
+	}
+	params := url.Values{"part": {"snippet"}, "playlistId": {s.cfg.PlaylistID}}
+	videos := []snippet{}
+	for len(videos) < s.cfg.MaxPlaylistVideos {
+		params.Set("maxResults", strconv.Itoa(min(s.cfg.MaxPlaylistVideos-len(videos), maxResults)))
+		var resp listResponse[playlistItem]
+		if err := s.list(ctx, "playlistItems", params, &resp); err != nil {
+			return nil, err
+		}
+		for _, item := range resp.Items {
+			if item.Snippet.ResourceID.VideoID != "" {
+				videos = append(videos, item.Snippet)
+			}
+		}
+		if resp.NextPageToken == "" || len(resp.Items) == 0 {
+			break
+		}
+		params.Set("pageToken", resp.NextPageToken)
+	}
+	s.playlist, s.listed = videos, time.Now()
+	return videos, nil
+}
+
+// videos returns the videos with the IDs, by ID. Videos that are private
+// or deleted are missing.
+func (s *Source) videos(ctx context.Context, ids []string) (map[string]video, error) {
+	out := make(map[string]video, len(ids))
+	for start := 0; start < len(ids); start += maxResults {
+		batch := ids[start:min(start+maxResults, len(ids))]
+		params := url.Values{
+			"part": {"snippet,contentDetails,statistics"},
+			"id":   {strings.Join(batch, ",")},
+		}
+		var resp listResponse[video]
+		if err := s.list(ctx, "videos", params, &resp); err != nil {
+			return nil, err
+		}
+		for _, v := range resp.Items {
+			out[v.ID] = v
+		}
+	}
+	return out, nil
+}
+
+// topic converts a video into a topic, reporting false if its ID is not
+// a valid video ID.
+func topic(v video) (datasource.DataSourceTopic, bool) {
+	id, ok := topicID(v.ID)
+	if !ok {
+		return datasource.DataSourceTopic{}, false
+	}
+	var m datasource.Metadata
+	if v.Snippet.ChannelTitle != "" {
+		m.Set(MetadataChannel, v.Snippet.ChannelTitle)
+	}
+	if n, err := strconv.ParseInt(v.Statistics.ViewCount, 10, 64); err == nil {
+		m.Set(MetadataViews, n)
+	}
+	if d, ok := parseDuration(v.ContentDetails.Duration); ok {
+		m.Set(MetadataDuration, int(d/time.Second))
+	}
+	likes, _ := strconv.ParseFloat(v.Statistics.LikeCount, 64)
+	lang := v.Snippet.DefaultAudioLanguage
+	if lang == "" {
+		lang = v.Snippet.DefaultLanguage
+	}
+	t := datasource.DataSourceTopic{
+		SourceURL: watchURL(v.ID, 0),
+		Site:      v.Snippet.ChannelID,
+		TopicID:   id,
+		Score:     likes,
+		Metadata:  m,
+		Language:  lang,
+		CreatedAt: v.Snippet.PublishedAt,
+	}
+	var path []string
+	if v.Snippet.ChannelTitle != "" {
+		path = []string{v.Snippet.ChannelTitle}
+	}
+	return t.Structured(v.Snippet.Title, excerpt(v.Snippet.Description), path...), true
+}
+
+// watchURL returns the address of the video, starting at start.
+func watchURL(id string, start time.Duration) string {
+	u := "https://www.youtube.com/watch?v=" + url.QueryEscape(id)
+	if s := int64(start / time.Second); s > 0 {
+		u += "&t=" + strconv.FormatInt(s, 10) + "s"
+	}
+	return u
+}
+
+// alphabet is the URL-safe base64 alphabet of video IDs.
+const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789-_"
+
+// topicID returns the topic ID of a video. Video IDs are 64-bit numbers
+// in eleven base64 digits, the last of which carries four bits.
+func topicID(videoID string) (int64, bool) {
+	if len(videoID) != 11 {
+		return 0, false
+	}
+	var n uint64
+	for i := 0; i < len(videoID); i++ {
+		d := strings.IndexByte(alphabet, videoID[i])
+		switch {
+		case d < 0:
+			return 0, false
+		case i < 10:
+			n = n<<6 | uint64(d)
+		case d&3 != 0:
+			return 0, false
+		default:
+			n = n<<4 | uint64(d>>2)
+		}
+	}
+	return int64(n), true
+}
+
+// videoID returns the ID of the video with the topic ID.
+func videoID(topicID int64) string {
+	n := uint64(topicID)
+	var b [11]byte
+	b[10] = alphabet[(n&0xf)<<2]
+	n >>= 4
+	for i := 9; i >= 0; i-- {
+		b[i] = alphabet[n&0x3f]
+		n >>= 6
+	}
+	return string(b[:])
+}
+
+// isoDuration matches ISO 8601 durations as the API reports them, such
+// as "PT1H2M3S".
+var isoDuration = regexp.MustCompile(`^P(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+func parseDuration(s string) (time.Duration, bool) {
+	m := isoDuration.FindStringSubmatch(s)
+	if m == nil {
+		return 0, false
+	}
+	var d time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute, time.Second} {
+		n, _ := strconv.Atoi(m[i+1])
+		d += time.Duration(n) * unit
+	}
+	return d, true
+}
+
+// words returns the set of normalized words of s, less single letters.
+func words(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, w := range strings.Fields(similarity.Normalize(s)) {
+		if utf8.RuneCountInString(w) > 1 {
+			set[w] = true
+		}
+	}
+	return set
+}
+
+// excerpt returns the start of a description, cut at a word boundary.
+func excerpt(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > maxExcerptChars {
+		s = string(r[:maxExcerptChars])
+		if i := strings.LastIndexByte(s, ' '); i > 0 {
+			s = s[:i]
+		}
+		s += "…"
+	}
+	return s
+}
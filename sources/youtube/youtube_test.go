@@ -0,0 +1,256 @@
+package youtube_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/sources/youtube"
+)
+
+const track = `<?xml version="1.0" encoding="utf-8" ?><transcript>` +
+	`<text start="0" dur="5">[Music]</text>` +
+	`<text start="5" dur="5">Welcome to the widget tutorial.</text>` +
+	`<text start="10" dur="10">&gt;&gt; Today we install</text>` +
+	`<text start="20" dur="12">the widget &amp;amp; it&amp;#39;s tools.</text>` +
+	`<text start="32" dur="8">First, download it</text>` +
+	`<text start="40" dur="5">from the site.</text>` +
+	`<text start="45" dur="15">Then run the installer</text>` +
+	`<text start="60" dur="15">and reboot</text>` +
+	`<text start="75" dur="20">when it asks</text>` +
+	`<text start="95" dur="1.5">Done!</text>` +
+	`</transcript>`
+
+var videos = map[string]map[string]any{
+	"dQw4w9WgXcQ": {
+		"id": "dQw4w9WgXcQ",
+		"snippet": map[string]any{
+			"publishedAt": "2026-01-02T03:04:05Z", "channelId": "UCwidgets", "channelTitle": "Widgets Inc",
+			"title": "Installing widgets", "description": "How to install\nthe widget.", "defaultAudioLanguage": "en",
+		},
+		"contentDetails": map[string]any{"duration": "PT1M37S"},
+		"statistics":     map[string]any{"viewCount": "1200", "likeCount": "42"},
+	},
+	"jNQXAC9IVRw": {
+		"id": "jNQXAC9IVRw",
+		"snippet": map[string]any{
+			"publishedAt": "2025-05-06T00:00:00Z", "channelId": "UCwidgets", "channelTitle": "Widgets Inc",
+			"title": "Widget FAQ", "description": "Answers about gadgets.", "defaultAudioLanguage": "en",
+		},
+		"contentDetails": map[string]any{"duration": "PT1H2M"},
+		"statistics":     map[string]any{"viewCount": "7", "likeCount": "1"},
+	},
+	"AAAAAAAAAAA": {
+		"id":      "AAAAAAAAAAA",
+		"snippet": map[string]any{"channelId": "UCother", "title": "Elsewhere"},
+	},
+}
+
+// api is a fake Data API and captions endpoint.
+type api struct {
+	mu       sync.Mutex
+	searches []url.Values
+	listed   int
+	captions []string
+	quota    bool
+}
+
+func (a *api) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	q := r.URL.Query()
+	if a.quota {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `{"error":{"code":403,"message":"quota used up","errors":[{"reason":"quotaExceeded"}]}}`)
+		return
+	}
+	items := []any{}
+	var next string
+	switch r.URL.Path {
+	case "/channels":
+		if q.Get("id") == "UCwidgets" {
+			items = append(items, map[string]any{"id": "UCwidgets"})
+		}
+	case "/playlists":
+		items = append(items, map[string]any{"id": q.Get("id")})
+	case "/search":
+		a.searches = append(a.searches, q)
+		items = append(items,
+			map[string]any{"id": map[string]any{"videoId": "dQw4w9WgXcQ"}},
+			map[string]any{"id": map[string]any{"kind": "youtube#channel"}},
+			map[string]any{"id": map[string]any{"videoId": "jNQXAC9IVRw"}})
+	case "/playlistItems":
+		a.listed++
+		if q.Get("pageToken") == "" {
+			next = "p2"
+			items = append(items, map[string]any{"snippet": map[string]any{"title": "Widget FAQ", "description": "Answers about gadgets.",
+				"resourceId": map[string]any{"videoId": "jNQXAC9IVRw"}}})
+		} else {
+			items = append(items, map[string]any{"snippet": map[string]any{"title": "Installing widgets", "description": "How to install the widget.",
+				"resourceId": map[string]any{"videoId": "dQw4w9WgXcQ"}}})
+		}
+	case "/videos":
+		for _, id := range strings.Split(q.Get("id"), ",") {
+			if v, ok := videos[id]; ok {
+				items = append(items, v)
+			}
+		}
+	case "/timedtext":
+		a.captions = append(a.captions, q.Get("lang")+"/"+q.Get("kind"))
+		switch {
+		case q.Get("v") != "dQw4w9WgXcQ":
+		case q.Get("lang") == "en" && q.Get("kind") == "":
+			w.WriteHeader(http.StatusNotFound)
+		case q.Get("lang") == "en":
+			io.WriteString(w, track)
+		}
+		return
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]any{"items": items, "nextPageToken": next})
+}
+
+func newSource(t *testing.T, cfg youtube.Config) (*youtube.Source, *api) {
+	t.Helper()
+	fake := &api{}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	cfg.APIKey = "key"
+	cfg.URL = srv.URL
+	cfg.CaptionsURL = srv.URL + "/timedtext"
+	ds := youtube.New(cfg)
+	if err := ds.Init(); err != nil {
+		t.Fatal(err)
+	}
+	return ds, fake
+}
+
+func TestFetchTopics(t *testing.T) {
+	ds, fake := newSource(t, youtube.Config{ChannelID: "UCwidgets"})
+	topics, err := ds.FetchTopics(2, datasource.NewQuestionInput{
+		QuestionText: "install widget",
+		Filters:      datasource.Filters{Sort: datasource.SortRecency},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 {
+		t.Fatalf("got %d topics, want 2: %+v", len(topics), topics)
+	}
+	got := topics[0]
+	if got.Title != "Installing widgets" || got.BodyExcerpt != "How to install the widget." ||
+		!reflect.DeepEqual(got.Path, []string{"Widgets Inc"}) || got.Site != "UCwidgets" ||
+		got.SourceURL != "https://www.youtube.com/watch?v=dQw4w9WgXcQ" || got.Score != 42 ||
+		got.Language != "en" || got.Rank != 1 || got.CreatedAt.Year() != 2026 {
+		t.Errorf("first topic = %+v", got)
+	}
+	if v, _ := got.Metadata.Int(youtube.MetadataDuration); v != 97 {
+		t.Errorf("duration = %d", v)
+	}
+	if v, _ := got.Metadata.Int(youtube.MetadataViews); v != 1200 {
+		t.Errorf("views = %d", v)
+	}
+	if topics[1].Title != "Widget FAQ" || topics[1].Rank != 2 {
+		t.Errorf("second topic = %+v", topics[1])
+	}
+
+	q := fake.searches[0]
+	if q.Get("channelId") != "UCwidgets" || q.Get("q") != "install widget" || q.Get("type") != "video" ||
+		q.Get("order") != "date" || q.Get("maxResults") != "2" || q.Get("key") != "key" {
+		t.Errorf("search parameters = %v", q)
+	}
+}
+
+func TestFetchTopicsPlaylist(t *testing.T) {
+	ds, fake := newSource(t, youtube.Config{PlaylistID: "PLwidgets"})
+	for i := 0; i < 2; i++ {
+		topics, err := ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "How do I install it?"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(topics) != 1 || topics[0].Title != "Installing widgets" {
+			t.Errorf("topics = %+v", topics)
+		}
+	}
+	if fake.listed != 2 {
+		t.Errorf("listed %d pages, want the playlist's two pages once", fake.listed)
+	}
+}
+
+func TestFetchData(t *testing.T) {
+	ds, fake := newSource(t, youtube.Config{ChannelID: "UCwidgets", Languages: []string{"de", "en"}, SegmentLength: 30 * time.Second})
+	topics, err := ds.FetchTopics(1, datasource.NewQuestionInput{QuestionText: "install"})
+	if err != nil || len(topics) != 1 {
+		t.Fatalf("FetchTopics = %+v, %v", topics, err)
+	}
+	data, err := ds.FetchData(10, topics[0].TopicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		url, text  string
+		start, end int64
+	}{
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=5s",
+			"Welcome to the widget tutorial. Today we install the widget & it's tools. First, download it from the site.", 5, 45},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=45s", "Then run the installer and reboot when it asks", 45, 95},
+		{"https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=95s", "Done!", 95, 97},
+	}
+	if len(data) != len(want) {
+		t.Fatalf("got %d items, want %d: %+v", len(data), len(want), data)
+	}
+	for i, w := range want {
+		d := data[i]
+		start, _ := d.Metadata.Int(youtube.MetadataStart)
+		end, _ := d.Metadata.Int(youtube.MetadataEnd)
+		if d.SourceURL != w.url || d.DataText != w.text || start != w.start || end != w.end ||
+			d.AnswerID != int64(i) || d.Language != "en" || d.ContentType != datasource.ContentPlainText {
+			t.Errorf("item %d = %s %q %d-%d, want %s %q %d-%d", i, d.SourceURL, d.DataText, start, end, w.url, w.text, w.start, w.end)
+		}
+	}
+	if want := []string{"de/", "en/", "de/asr", "en/asr"}; !reflect.DeepEqual(fake.captions, want) {
+		t.Errorf("caption requests = %q, want manual captions first", fake.captions)
+	}
+
+	if data, _ := ds.FetchData(2, topics[0].TopicID); len(data) != 2 {
+		t.Errorf("FetchData(2) returned %d items", len(data))
+	}
+}
+
+func TestFetchDataErrors(t *testing.T) {
+	ds, fake := newSource(t, youtube.Config{ChannelID: "UCwidgets", ManualCaptionsOnly: true})
+	topics, _ := ds.FetchTopics(1, datasource.NewQuestionInput{QuestionText: "install"})
+	if _, err := ds.FetchData(5, topics[0].TopicID); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData without manual captions = %v, want ErrNotFound", err)
+	}
+	if _, err := ds.FetchData(5, 0); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("FetchData(video of another channel) = %v, want ErrNotFound", err)
+	}
+
+	fake.quota = true
+	if _, err := ds.FetchTopics(1, datasource.NewQuestionInput{QuestionText: "install"}); !errors.Is(err, datasource.ErrQuotaExceeded) {
+		t.Errorf("FetchTopics past the quota = %v, want ErrQuotaExceeded", err)
+	}
+}
+
+func TestInitErrors(t *testing.T) {
+	if err := youtube.New(youtube.Config{ChannelID: "UCa", PlaylistID: "PLb"}).Init(); err == nil {
+		t.Error("Init with a channel and a playlist succeeded")
+	}
+	srv := httptest.NewServer(&api{})
+	defer srv.Close()
+	if err := youtube.New(youtube.Config{URL: srv.URL, ChannelID: "UCnope"}).Init(); !errors.Is(err, datasource.ErrNotFound) {
+		t.Errorf("Init(missing channel) = %v, want ErrNotFound", err)
+	}
+}
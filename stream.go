@@ -0,0 +1,53 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrStopStream can be returned by a StreamData callback to end the stream
+// early without reporting an error to the caller.
+var ErrStopStream = errors.New("datasource: stop stream")
+
+// DataStreamer is an optional interface for data sources whose data items
+// are very large or numerous (transcripts, books, long manuals). Instead of
+// buffering a whole FetchData result, the source delivers items one at a
+// time as they are produced, typically while still downloading.
+type DataStreamer interface {
+	// StreamData calls fn for each data item of topicID, up to count items,
+	// in the same order FetchData would return them. Returning an error from
+	// fn stops the stream and StreamData returns that error (or nil for
+	// ErrStopStream). Implementations must stop promptly when ctx is done.
+	StreamData(ctx context.Context, count int, topicID int64, fn func(DataSourceData) error) error
+}
+
+// StreamData delivers the data items of topicID to fn, streaming them if ds
+// implements DataStreamer and falling back to FetchData otherwise.
+func StreamData(ctx context.Context, ds DataSource, count int, topicID int64, fn func(DataSourceData) error) error {
+	var err error
+	if s, ok := ds.(DataStreamer); ok {
+		err = s.StreamData(ctx, count, topicID, fn)
+	} else {
+		err = fetchEach(ctx, ds, count, topicID, fn)
+	}
+	if errors.Is(err, ErrStopStream) {
+		return nil
+	}
+	return err
+}
+
+func fetchEach(ctx context.Context, ds DataSource, count int, topicID int64, fn func(DataSourceData) error) error {
+	items, err := ds.FetchData(count, topicID)
+	if err != nil {
+		return err
+	}
+	for _, d := range items {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package datasource_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+type streamingSource struct {
+	ExampleDataSource
+	streamed bool
+}
+
+func (s *streamingSource) StreamData(ctx context.Context, count int, topicID int64, fn func(datasource.DataSourceData) error) error {
+	s.streamed = true
+	for i := 0; i < count; i++ {
+		if err := fn(datasource.DataSourceData{AnswerID: int64(i)}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestStreamData(t *testing.T) {
+	s := &streamingSource{ExampleDataSource: ExampleDataSource{Name: "s"}}
+	var got []int64
+	err := datasource.StreamData(context.Background(), s, 10, 1, func(d datasource.DataSourceData) error {
+		got = append(got, d.AnswerID)
+		if len(got) == 3 {
+			return datasource.ErrStopStream
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("StreamData failed: %v", err)
+	}
+	if !s.streamed || len(got) != 3 {
+		t.Errorf("expected 3 streamed items, got %v (streamed=%v)", got, s.streamed)
+	}
+}
+
+func TestStreamDataFallsBackToFetchData(t *testing.T) {
+	ds := &ExampleDataSource{Name: "plain"}
+	var n int
+	if err := datasource.StreamData(context.Background(), ds, 3, 1, func(datasource.DataSourceData) error {
+		n++
+		return nil
+	}); err != nil || n != 1 {
+		t.Errorf("expected one item from FetchData, got %d, %v", n, err)
+	}
+
+	boom := errors.New("boom")
+	if err := datasource.StreamData(context.Background(), ds, 3, 1, func(datasource.DataSourceData) error {
+		return boom
+	}); !errors.Is(err, boom) {
+		t.Errorf("expected callback error, got %v", err)
+	}
+}
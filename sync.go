@@ -0,0 +1,98 @@
+package datasource
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrCursorExpired is returned by SyncSource.Changes when the source can
+// no longer resume from the cursor given, for example because its change
+// log was compacted. The host should sync again from the zero Cursor.
+var ErrCursorExpired = errors.New("datasource: sync cursor expired")
+
+// Cursor marks a position in a source's change feed. Its contents are
+// defined by the source and opaque to the host, which stores the Next
+// cursor of each batch it applies and passes it to the following call.
+// The zero Cursor is the start of the feed.
+type Cursor string
+
+// ChangeKind is the kind of a Change.
+type ChangeKind string
+
+// Kinds of change.
+const (
+	// ChangeUpsert means the topic was added or modified
+	ChangeUpsert ChangeKind = "upsert"
+
+	// ChangeDelete means the topic was removed
+	ChangeDelete ChangeKind = "delete"
+)
+
+// Change is a change to one topic of a source.
+type Change struct {
+	Kind ChangeKind `json:"kind"`
+
+	// Topic is the topic as it now is; for deletions only TopicID need be
+	// set
+	Topic DataSourceTopic `json:"topic"`
+
+	// Data are the topic's data items, so the host need not call FetchData
+	// to index them
+	// Optional - nil means the host should fetch them if it needs them
+	Data []DataSourceData `json:"data,omitempty"`
+
+	// ChangedAt is when the change happened upstream
+	// Optional
+	ChangedAt time.Time `json:"changed_at,omitempty"`
+}
+
+// ChangeBatch is a page of a source's change feed.
+type ChangeBatch struct {
+	// Changes are in the order they happened; a topic may change more than
+	// once in a batch, and the last change wins
+	Changes []Change `json:"changes"`
+
+	// Next is the cursor to pass to the following call. It equals the
+	// cursor given if there were no changes.
+	Next Cursor `json:"next"`
+
+	// More means further changes are available right away, so the host
+	// should call again without waiting for its next sync
+	More bool `json:"more,omitempty"`
+}
+
+// SyncSource is an optional interface for data sources that can feed the
+// host's own index incrementally, instead of being queried live for every
+// question. Changes returns the changes since a cursor; from the zero
+// Cursor it returns every topic as an upsert, so the host can build its
+// index from scratch. Implementations must stop promptly when ctx is done.
+type SyncSource interface {
+	Changes(ctx context.Context, since Cursor) (ChangeBatch, error)
+}
+
+// SyncSourceOf returns the first SyncSource in ds's decorator chain
+// (following Unwrap methods), and false if there is none.
+func SyncSourceOf(ds DataSource) (SyncSource, bool) {
+	return find[SyncSource](ds)
+}
+
+// Sync reads s's change feed from since until it has no more changes
+// available, calling apply for each batch, and returns the cursor to
+// resume from. If apply fails, Sync stops and returns the cursor before
+// that batch with the error, so no change is skipped.
+func Sync(ctx context.Context, s SyncSource, since Cursor, apply func(ChangeBatch) error) (Cursor, error) {
+	for {
+		batch, err := s.Changes(ctx, since)
+		if err != nil {
+			return since, err
+		}
+		if err := apply(batch); err != nil {
+			return since, err
+		}
+		if !batch.More || batch.Next == since {
+			return batch.Next, nil
+		}
+		since = batch.Next
+	}
+}
@@ -0,0 +1,76 @@
+package datasource_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// logSource serves a change log two changes per batch, with cursors
+// holding the position in the log.
+type logSource struct {
+	datasource.DataSource
+	log []datasource.Change
+}
+
+func (s *logSource) Changes(ctx context.Context, since datasource.Cursor) (datasource.ChangeBatch, error) {
+	pos := 0
+	if since != "" {
+		var err error
+		if pos, err = strconv.Atoi(string(since)); err != nil || pos > len(s.log) {
+			return datasource.ChangeBatch{}, datasource.ErrCursorExpired
+		}
+	}
+	end := min(pos+2, len(s.log))
+	return datasource.ChangeBatch{
+		Changes: s.log[pos:end],
+		Next:    datasource.Cursor(strconv.Itoa(end)),
+		More:    end < len(s.log),
+	}, nil
+}
+
+func TestSync(t *testing.T) {
+	src := &logSource{log: []datasource.Change{
+		{Kind: datasource.ChangeUpsert, Topic: datasource.DataSourceTopic{TopicID: 1}},
+		{Kind: datasource.ChangeUpsert, Topic: datasource.DataSourceTopic{TopicID: 2}},
+		{Kind: datasource.ChangeDelete, Topic: datasource.DataSourceTopic{TopicID: 1}},
+	}}
+	s, ok := datasource.SyncSourceOf(passthrough{src})
+	if !ok {
+		t.Fatal("SyncSourceOf did not find the source beneath the decorator")
+	}
+	if _, ok := datasource.SyncSourceOf(passthrough{&ExampleDataSource{}}); ok {
+		t.Error("SyncSourceOf found a source that cannot sync")
+	}
+
+	index := map[int64]bool{}
+	apply := func(b datasource.ChangeBatch) error {
+		for _, c := range b.Changes {
+			index[c.Topic.TopicID] = c.Kind == datasource.ChangeUpsert
+		}
+		return nil
+	}
+	cursor, err := datasource.Sync(context.Background(), s, "", apply)
+	if err != nil || cursor != "3" {
+		t.Fatalf("Sync = %q, %v; want cursor 3", cursor, err)
+	}
+	if index[1] || !index[2] {
+		t.Errorf("index %v, want only topic 2", index)
+	}
+
+	src.log = append(src.log, datasource.Change{Kind: datasource.ChangeUpsert, Topic: datasource.DataSourceTopic{TopicID: 3}})
+	boom := errors.New("boom")
+	cursor, err = datasource.Sync(context.Background(), s, cursor, func(datasource.ChangeBatch) error { return boom })
+	if !errors.Is(err, boom) || cursor != "3" {
+		t.Errorf("Sync with failing apply = %q, %v; want the cursor kept", cursor, err)
+	}
+	if cursor, err = datasource.Sync(context.Background(), s, cursor, apply); err != nil || cursor != "4" || !index[3] {
+		t.Errorf("Sync resumed = %q, %v; index %v", cursor, err, index)
+	}
+	if _, err := datasource.Sync(context.Background(), s, "9", apply); !errors.Is(err, datasource.ErrCursorExpired) {
+		t.Errorf("Sync from an unknown cursor = %v, want ErrCursorExpired", err)
+	}
+}
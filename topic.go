@@ -0,0 +1,54 @@
+package datasource
+
+// Structured returns a copy of t with the structured fields set: Title,
+// BodyExcerpt, and Path. Topic is set to the title if it is empty, so
+// hosts that read Topic alone still see it.
+func (t DataSourceTopic) Structured(title, bodyExcerpt string, path ...string) DataSourceTopic {
+	t.Title, t.BodyExcerpt, t.Path = title, bodyExcerpt, path
+	if t.Topic == "" {
+		t.Topic = title
+	}
+	return t
+}
+
+// DisplayTitle returns the topic's title: Title if set, or else Topic.
+func (t DataSourceTopic) DisplayTitle() string {
+	if t.Title != "" {
+		return t.Title
+	}
+	return t.Topic
+}
+
+// Body returns the text of the topic besides its title, for rankers
+// weighting title and body matches differently: BodyExcerpt if set, or
+// else Topic if it is more than the title. It returns "" for topics
+// without structured fields, whose Topic is taken as the title.
+func (t DataSourceTopic) Body() string {
+	if t.BodyExcerpt != "" {
+		return t.BodyExcerpt
+	}
+	if t.Title != "" && t.Topic != t.Title {
+		return t.Topic
+	}
+	return ""
+}
+
+// FillTopics returns topics with the Topic of those that have structured
+// fields but no Topic set: to the title, or else the body excerpt. The
+// slice is copied if any topic changes.
+func FillTopics(topics []DataSourceTopic) []DataSourceTopic {
+	out, copied := topics, false
+	for i, t := range topics {
+		if t.Topic != "" || t.Title == "" && t.BodyExcerpt == "" {
+			continue
+		}
+		if !copied {
+			out, copied = append([]DataSourceTopic(nil), topics...), true
+		}
+		out[i].Topic = t.Title
+		if out[i].Topic == "" {
+			out[i].Topic = t.BodyExcerpt
+		}
+	}
+	return out
+}
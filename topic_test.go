@@ -0,0 +1,90 @@
+package datasource_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+func TestStructured(t *testing.T) {
+	got := datasource.DataSourceTopic{TopicID: 1}.Structured("Install", "Download the binary.", "Docs", "Guides")
+	want := datasource.DataSourceTopic{
+		Topic: "Install", Title: "Install", BodyExcerpt: "Download the binary.",
+		Path: []string{"Docs", "Guides"}, TopicID: 1,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Structured = %+v, want %+v", got, want)
+	}
+	kept := datasource.DataSourceTopic{Topic: "Install: Download the binary."}.Structured("Install", "")
+	if kept.Topic != "Install: Download the binary." || kept.Body() != "Install: Download the binary." {
+		t.Errorf("Structured replaced Topic: %+v", kept)
+	}
+}
+
+func TestDisplayTitleAndBody(t *testing.T) {
+	tests := []struct {
+		topic       datasource.DataSourceTopic
+		title, body string
+	}{
+		{datasource.DataSourceTopic{Topic: "Legacy"}, "Legacy", ""},
+		{datasource.DataSourceTopic{Topic: "Install", Title: "Install"}, "Install", ""},
+		{datasource.DataSourceTopic{Topic: "Install", Title: "Install", BodyExcerpt: "Download."}, "Install", "Download."},
+	}
+	for _, tt := range tests {
+		if got := tt.topic.DisplayTitle(); got != tt.title {
+			t.Errorf("%+v: DisplayTitle = %q, want %q", tt.topic, got, tt.title)
+		}
+		if got := tt.topic.Body(); got != tt.body {
+			t.Errorf("%+v: Body = %q, want %q", tt.topic, got, tt.body)
+		}
+	}
+}
+
+func TestFillTopics(t *testing.T) {
+	topics := []datasource.DataSourceTopic{
+		{Topic: "Set", Title: "Other"},
+		{Title: "Titled"},
+		{BodyExcerpt: "Excerpt only"},
+		{TopicID: 4},
+	}
+	got := datasource.FillTopics(topics)
+	for i, want := range []string{"Set", "Titled", "Excerpt only", ""} {
+		if got[i].Topic != want {
+			t.Errorf("topic %d = %q, want %q", i, got[i].Topic, want)
+		}
+	}
+	if topics[1].Topic != "" {
+		t.Error("FillTopics modified its argument")
+	}
+	unchanged := topics[:1]
+	if got := datasource.FillTopics(unchanged); &got[0] != &unchanged[0] {
+		t.Error("FillTopics copied topics that needed no change")
+	}
+}
+
+func TestTopicTimesJSON(t *testing.T) {
+	b, err := json.Marshal(datasource.DataSourceTopic{Topic: "x", TopicID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(b), "_at") {
+		t.Errorf("zero times encoded: %s", b)
+	}
+
+	created := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	in := datasource.DataSourceTopic{Topic: "x", TopicID: 1, CreatedAt: created, Metadata: datasource.Metadata{"views": 3}}
+	if b, err = json.Marshal(in); err != nil {
+		t.Fatal(err)
+	}
+	var out datasource.DataSourceTopic
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.CreatedAt.Equal(created) || !out.UpdatedAt.IsZero() || out.Topic != "x" || out.Metadata["views"] != 3.0 || strings.Contains(string(b), "updated_at") {
+		t.Errorf("round trip of %s = %+v", b, out)
+	}
+}
@@ -0,0 +1,157 @@
+// Package tracing creates trace spans around data source calls.
+//
+// The package defines a minimal Tracer interface mirroring the subset of
+// the OpenTelemetry tracing API it needs, so the SDK does not import
+// OpenTelemetry. Hosts adapt their OTel tracer in a few lines:
+//
+//	type otelTracer struct{ t trace.Tracer }
+//
+//	func (o otelTracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+//	    ctx, span := o.t.Start(ctx, name)
+//	    return ctx, otelSpan{span}
+//	}
+//
+// where otelSpan converts tracing.Attribute values to attribute.KeyValue.
+package tracing
+
+import (
+	"context"
+	"strings"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// Attribute keys recorded on spans.
+const (
+	AttrSource      = "datasource.name"
+	AttrQuery       = "datasource.query"
+	AttrTags        = "datasource.tags"
+	AttrCount       = "datasource.count"
+	AttrTopicID     = "datasource.topic_id"
+	AttrResultCount = "datasource.result_count"
+	AttrTopicIDs    = "datasource.topic_ids"
+	AttrAvailable   = "datasource.available"
+	AttrErrorClass  = "error.class"
+)
+
+// Redacted replaces query text when Options.RedactQuery is set.
+const Redacted = "[redacted]"
+
+// Attribute is a key/value pair attached to a span. Value is one of string,
+// int64, bool, or []int64.
+type Attribute struct {
+	Key   string
+	Value any
+}
+
+// Tracer starts spans.
+type Tracer interface {
+	// Start creates a span named name as a child of any span in ctx.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span is an in-progress operation.
+type Span interface {
+	// SetAttributes records attributes on the span.
+	SetAttributes(attrs ...Attribute)
+
+	// RecordError marks the span as failed with err.
+	RecordError(err error)
+
+	// End completes the span.
+	End()
+}
+
+// Options configures Trace.
+type Options struct {
+	// Name identifies the data source in span attributes
+	Name string
+
+	// RedactQuery replaces question text and tags with Redacted, for
+	// deployments where questions may contain sensitive information
+	RedactQuery bool
+}
+
+// Trace returns a DataSource that wraps every call to ds in a span named
+// "datasource.<Method>", recording the query (unless redacted), count, topic
+// IDs, result counts, and error class as attributes.
+//
+// DataSource methods do not take a context, so spans are started from
+// context.Background() and become trace roots.
+func Trace(ds datasource.DataSource, tracer Tracer, opts Options) datasource.DataSource {
+	return &tracedSource{DataSource: ds, tracer: tracer, opts: opts}
+}
+
+type tracedSource struct {
+	datasource.DataSource
+	tracer Tracer
+	opts   Options
+}
+
+func (s *tracedSource) start(m datasource.Method, attrs ...Attribute) Span {
+	_, span := s.tracer.Start(context.Background(), "datasource."+string(m))
+	span.SetAttributes(append([]Attribute{{AttrSource, s.opts.Name}}, attrs...)...)
+	return span
+}
+
+func finish(span Span, err error, attrs ...Attribute) {
+	if err != nil {
+		attrs = append(attrs, Attribute{AttrErrorClass, datasource.ErrorClass(err)})
+		span.RecordError(err)
+	}
+	if len(attrs) > 0 {
+		span.SetAttributes(attrs...)
+	}
+	span.End()
+}
+
+func (s *tracedSource) Init() error {
+	span := s.start(datasource.MethodInit)
+	err := s.DataSource.Init()
+	finish(span, err)
+	return err
+}
+
+func (s *tracedSource) CheckAvailability() bool {
+	span := s.start(datasource.MethodCheckAvailability)
+	ok := s.DataSource.CheckAvailability()
+	finish(span, nil, Attribute{AttrAvailable, ok})
+	return ok
+}
+
+func (s *tracedSource) FetchTopics(count int, input datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	query, tags := input.QuestionText, strings.Join(input.Tags, ",")
+	if s.opts.RedactQuery {
+		query = Redacted
+		if tags != "" {
+			tags = Redacted
+		}
+	}
+	span := s.start(datasource.MethodFetchTopics,
+		Attribute{AttrQuery, query},
+		Attribute{AttrTags, tags},
+		Attribute{AttrCount, int64(count)},
+	)
+	topics, err := s.DataSource.FetchTopics(count, input)
+	ids := make([]int64, len(topics))
+	for i, t := range topics {
+		ids[i] = t.TopicID
+	}
+	finish(span, err, Attribute{AttrResultCount, int64(len(topics))}, Attribute{AttrTopicIDs, ids})
+	return topics, err
+}
+
+func (s *tracedSource) FetchData(count int, topicID int64) ([]datasource.DataSourceData, error) {
+	span := s.start(datasource.MethodFetchData,
+		Attribute{AttrTopicID, topicID},
+		Attribute{AttrCount, int64(count)},
+	)
+	data, err := s.DataSource.FetchData(count, topicID)
+	finish(span, err, Attribute{AttrResultCount, int64(len(data))})
+	return data, err
+}
+
+// Unwrap returns the wrapped data source.
+func (s *tracedSource) Unwrap() datasource.DataSource {
+	return s.DataSource
+}
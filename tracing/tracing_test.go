@@ -0,0 +1,108 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+	"github.com/locus-search/datasource-sdk/tracing"
+)
+
+type recordedSpan struct {
+	name  string
+	attrs map[string]any
+	err   error
+	ended bool
+}
+
+func (s *recordedSpan) SetAttributes(attrs ...tracing.Attribute) {
+	for _, a := range attrs {
+		s.attrs[a.Key] = a.Value
+	}
+}
+func (s *recordedSpan) RecordError(err error) { s.err = err }
+func (s *recordedSpan) End()                  { s.ended = true }
+
+type recordingTracer struct{ spans []*recordedSpan }
+
+func (t *recordingTracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	s := &recordedSpan{name: name, attrs: make(map[string]any)}
+	t.spans = append(t.spans, s)
+	return ctx, s
+}
+
+type fixedSource struct{ err error }
+
+func (s *fixedSource) Init() error             { return s.err }
+func (s *fixedSource) CheckAvailability() bool { return s.err == nil }
+func (s *fixedSource) FetchTopics(int, datasource.NewQuestionInput) ([]datasource.DataSourceTopic, error) {
+	return []datasource.DataSourceTopic{{TopicID: 4}, {TopicID: 9}}, nil
+}
+func (s *fixedSource) FetchData(int, int64) ([]datasource.DataSourceData, error) {
+	return nil, s.err
+}
+
+func TestTraceFetchTopics(t *testing.T) {
+	tr := &recordingTracer{}
+	ds := tracing.Trace(&fixedSource{}, tr, tracing.Options{Name: "wiki"})
+
+	ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "what is dns", Tags: []string{"net"}})
+
+	if len(tr.spans) != 1 {
+		t.Fatalf("expected 1 span, got %d", len(tr.spans))
+	}
+	s := tr.spans[0]
+	if s.name != "datasource.FetchTopics" || !s.ended {
+		t.Errorf("unexpected span %q (ended=%v)", s.name, s.ended)
+	}
+	want := map[string]any{
+		tracing.AttrSource:      "wiki",
+		tracing.AttrQuery:       "what is dns",
+		tracing.AttrTags:        "net",
+		tracing.AttrCount:       int64(5),
+		tracing.AttrResultCount: int64(2),
+	}
+	for k, v := range want {
+		if s.attrs[k] != v {
+			t.Errorf("attribute %s = %v, want %v", k, s.attrs[k], v)
+		}
+	}
+	if ids, _ := s.attrs[tracing.AttrTopicIDs].([]int64); len(ids) != 2 || ids[1] != 9 {
+		t.Errorf("unexpected topic IDs %v", s.attrs[tracing.AttrTopicIDs])
+	}
+}
+
+func TestTraceRedactsQuery(t *testing.T) {
+	tr := &recordingTracer{}
+	ds := tracing.Trace(&fixedSource{}, tr, tracing.Options{RedactQuery: true})
+
+	ds.FetchTopics(5, datasource.NewQuestionInput{QuestionText: "my SSN is 123", Tags: []string{"hr"}})
+
+	if q := tr.spans[0].attrs[tracing.AttrQuery]; q != tracing.Redacted {
+		t.Errorf("query not redacted: %v", q)
+	}
+	if tags := tr.spans[0].attrs[tracing.AttrTags]; tags != tracing.Redacted {
+		t.Errorf("tags not redacted: %v", tags)
+	}
+}
+
+func TestTraceRecordsErrors(t *testing.T) {
+	tr := &recordingTracer{}
+	boom := errors.New("boom")
+	ds := tracing.Trace(&fixedSource{err: datasource.ErrNotFound}, tr, tracing.Options{})
+
+	ds.FetchData(3, 42)
+	s := tr.spans[0]
+	if !errors.Is(s.err, datasource.ErrNotFound) || s.attrs[tracing.AttrErrorClass] != "not_found" {
+		t.Errorf("error not recorded: %v / %v", s.err, s.attrs[tracing.AttrErrorClass])
+	}
+	if s.attrs[tracing.AttrTopicID] != int64(42) {
+		t.Errorf("topic ID not recorded: %v", s.attrs[tracing.AttrTopicID])
+	}
+
+	tracing.Trace(&fixedSource{err: boom}, tr, tracing.Options{}).Init()
+	if last := tr.spans[len(tr.spans)-1]; last.name != "datasource.Init" || last.err != boom {
+		t.Errorf("unexpected Init span %+v", last)
+	}
+}
@@ -0,0 +1,29 @@
+package datasource
+
+import "context"
+
+// Writable is an optional interface for data sources that accept new
+// questions, such as an internal Q&A forum. When the host finds no good
+// answer to a question, it can post the question to such a source, so
+// people answer it there and later searches find the answer. PostTopic
+// creates a topic for the question and returns it, with its TopicID and
+// SourceURL set so the host can point the asker to it. Implementations
+// must stop promptly when ctx is done.
+//
+// Writable sources report Capabilities.WriteBack. A source that
+// implements Writable but is not configured to accept posts reports
+// WriteBack false, and PostTopic returns an error wrapping
+// errors.ErrUnsupported.
+type Writable interface {
+	PostTopic(ctx context.Context, input NewQuestionInput) (DataSourceTopic, error)
+}
+
+// WritableOf returns the first Writable in ds's decorator chain (following
+// Unwrap methods), and false if there is none or ds does not report
+// Capabilities.WriteBack.
+func WritableOf(ds DataSource) (Writable, bool) {
+	if !CapabilitiesOf(ds).WriteBack {
+		return nil, false
+	}
+	return find[Writable](ds)
+}
@@ -0,0 +1,38 @@
+package datasource_test
+
+import (
+	"context"
+	"testing"
+
+	datasource "github.com/locus-search/datasource-sdk"
+)
+
+// forumSource posts questions as new topics.
+type forumSource struct {
+	capableSource
+	posted []string
+}
+
+func (s *forumSource) PostTopic(_ context.Context, input datasource.NewQuestionInput) (datasource.DataSourceTopic, error) {
+	s.posted = append(s.posted, input.QuestionText)
+	return datasource.DataSourceTopic{Topic: input.QuestionText, TopicID: int64(len(s.posted))}, nil
+}
+
+func TestWritableOf(t *testing.T) {
+	forum := &forumSource{capableSource: capableSource{caps: datasource.Capabilities{WriteBack: true}}}
+	w, ok := datasource.WritableOf(passthrough{forum})
+	if !ok {
+		t.Fatal("WritableOf did not find the source beneath the decorator")
+	}
+	topic, err := w.PostTopic(context.Background(), datasource.NewQuestionInput{QuestionText: "How do I rotate the signing key?"})
+	if err != nil || topic.TopicID != 1 || len(forum.posted) != 1 {
+		t.Errorf("PostTopic = %+v, %v", topic, err)
+	}
+
+	if _, ok := datasource.WritableOf(&forumSource{}); ok {
+		t.Error("WritableOf found a source that does not report WriteBack")
+	}
+	if _, ok := datasource.WritableOf(&capableSource{caps: datasource.Capabilities{WriteBack: true}}); ok {
+		t.Error("WritableOf found a source that is not Writable")
+	}
+}